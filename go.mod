@@ -2,9 +2,16 @@ module github.com/0xcro3dile/localrag-go
 
 go 1.21
 
-require github.com/fsnotify/fsnotify v1.9.0
-
 require (
-	github.com/mattn/go-sqlite3 v1.14.32 // indirect
-	golang.org/x/sys v0.13.0 // indirect
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
+	github.com/mattn/go-sqlite3 v1.14.32
+	github.com/yalue/onnxruntime_go v1.26.0
+	go.etcd.io/bbolt v1.3.10
+	go.uber.org/goleak v1.3.0
+	golang.org/x/net v0.35.0
+	golang.org/x/text v0.22.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.30.0 // indirect
@@ -0,0 +1,88 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// statusError is a backend response other than 200 OK.
+type statusError struct {
+	backend string // Such as "Ollama"
+	status  int
+	body    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.backend, e.status, e.body)
+}
+
+// rejected reports whether err means the backend turned the texts down,
+// with a client error status or an unusable response, rather than being
+// unreachable or overloaded. Only then can other texts still succeed;
+// an outage that outlasted the retries, or an open circuit, fails the
+// whole batch instead of being retried one text at a time.
+func rejected(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, resilience.ErrCircuitOpen) {
+		return false
+	}
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return false
+	}
+	var serr *statusError
+	if errors.As(err, &serr) {
+		return serr.status >= http.StatusBadRequest && serr.status < http.StatusInternalServerError &&
+			!resilience.RetryableStatus(serr.status)
+	}
+	return true
+}
+
+// isolate embeds texts one request at a time after their batch was
+// rejected, so a bad input only costs itself. Texts rejected again are
+// reported in a *ports.BatchEmbedError with nil embeddings; an
+// unreachable backend fails the whole call.
+func isolate(ctx context.Context, texts []string, embedOne func(ctx context.Context, text string) ([]float32, error)) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	failed := make(map[int]error)
+	for i, text := range texts {
+		emb, err := embedOne(ctx, text)
+		if err != nil {
+			if !rejected(ctx, err) {
+				return nil, fmt.Errorf("embedding text %d: %w", i, err)
+			}
+			failed[i] = err
+			continue
+		}
+		embeddings[i] = emb
+	}
+	if len(failed) > 0 {
+		return embeddings, &ports.BatchEmbedError{Failed: failed}
+	}
+	return embeddings, nil
+}
+
+// embedOrIsolate sends texts as one request with embed and, if the
+// backend rejects it, sends them one at a time to single out the texts
+// it objects to.
+func embedOrIsolate(ctx context.Context, texts []string, embed func(ctx context.Context, texts []string) ([][]float32, error)) ([][]float32, error) {
+	embeddings, err := embed(ctx, texts)
+	if err == nil || !rejected(ctx, err) {
+		return embeddings, err
+	}
+	if len(texts) == 1 {
+		return [][]float32{nil}, &ports.BatchEmbedError{Failed: map[int]error{0: err}}
+	}
+	return isolate(ctx, texts, func(ctx context.Context, text string) ([]float32, error) {
+		embeddings, err := embed(ctx, []string{text})
+		if err != nil {
+			return nil, err
+		}
+		return embeddings[0], nil
+	})
+}
@@ -0,0 +1,56 @@
+package embedding
+
+import "strings"
+
+// Instructions are the prefixes an embedding model expects before
+// search queries and before the passages they are matched against.
+// Models trained with them retrieve noticeably worse without them.
+// Changing a corpus's passage prefix calls for re-ingesting it.
+type Instructions struct {
+	Query   string // e.g. "search_query: "
+	Passage string // e.g. "search_document: "
+}
+
+// bgeQueryInstruction is the query prefix of the English bge and
+// compatible models; their passages take none.
+const bgeQueryInstruction = "Represent this sentence for searching relevant passages: "
+
+// InstructionsFor returns the documented prefixes of well-known model
+// families by model name, such as "nomic-embed-text" or
+// "BAAI/bge-small-en-v1.5", and none for other models.
+func InstructionsFor(model string) Instructions {
+	name := strings.ToLower(model)
+	switch {
+	case strings.Contains(name, "nomic-embed"):
+		return Instructions{Query: "search_query: ", Passage: "search_document: "}
+	case strings.Contains(name, "e5-") || strings.HasSuffix(name, "e5"):
+		return Instructions{Query: "query: ", Passage: "passage: "}
+	case strings.Contains(name, "bge-m3"):
+		return Instructions{} // Multilingual bge needs no instruction
+	case strings.Contains(name, "bge-"), strings.Contains(name, "mxbai-embed-large"), strings.Contains(name, "snowflake-arctic-embed"):
+		return Instructions{Query: bgeQueryInstruction}
+	default:
+		return Instructions{}
+	}
+}
+
+// queries prefixes texts with the query instruction.
+func (in Instructions) queries(texts []string) []string {
+	return prefixed(in.Query, texts)
+}
+
+// passages prefixes texts with the passage instruction.
+func (in Instructions) passages(texts []string) []string {
+	return prefixed(in.Passage, texts)
+}
+
+func prefixed(prefix string, texts []string) []string {
+	if prefix == "" {
+		return texts
+	}
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = prefix + t
+	}
+	return out
+}
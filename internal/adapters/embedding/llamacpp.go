@@ -0,0 +1,189 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// LlamaCppAdapter implements ports.EmbeddingService against llama.cpp's
+// own server (llama-server --embedding) through its native /embedding
+// endpoint. Unlike /v1/embeddings, the native endpoint is served by
+// every server build, including ones that predate the OpenAI API.
+type LlamaCppAdapter struct {
+	baseURL      string
+	model        string
+	client       *http.Client
+	health       *resilience.Tracker
+	backoff      resilience.Backoff
+	instructions Instructions // Prefixes for EmbedQueries and EmbedPassages
+}
+
+// NewLlamaCppAdapter creates an embedding adapter for a llama.cpp server.
+// model is the server's --alias; it only matters to servers that host
+// several models, and is left out of requests when empty.
+func NewLlamaCppAdapter(baseURL, model string) *LlamaCppAdapter {
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	return &LlamaCppAdapter{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		client:  resilience.NewHTTPClient(60 * time.Second),
+		health:  resilience.NewTracker("llamacpp-embedding"),
+		backoff: resilience.DefaultBackoff,
+	}
+}
+
+// llamaCppEmbedRequest is the /embedding request format. EmbdNormalize 2
+// asks for unit-length (Euclidean) vectors, as /v1/embeddings returns.
+type llamaCppEmbedRequest struct {
+	Content       []string `json:"content"`
+	Model         string   `json:"model,omitempty"`
+	EmbdNormalize int      `json:"embd_normalize"`
+}
+
+// llamaCppEmbedding is one result of /embedding. Current servers send
+// the embedding as a list of rows, one per token unless the server
+// pools them; older ones send a single vector.
+type llamaCppEmbedding struct {
+	Index     int             `json:"index"`
+	Embedding json.RawMessage `json:"embedding"`
+}
+
+// Embed generates an embedding for a single text.
+func (a *LlamaCppAdapter) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := a.embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts in one request. If
+// the server rejects the request, texts are resent one at a time and
+// those rejected again are reported in a *ports.BatchEmbedError.
+func (a *LlamaCppAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedOrIsolate(ctx, texts, a.embed)
+}
+
+// embed sends one /embedding request.
+func (a *LlamaCppAdapter) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(llamaCppEmbedRequest{Content: texts, Model: a.model, EmbdNormalize: 2})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := resilience.DoHTTP(ctx, a.client, a.health, a.backoff, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/embedding", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("calling llama.cpp: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &statusError{backend: "llama.cpp", status: resp.StatusCode, body: string(bytes.TrimSpace(msg))}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	results, err := parseLlamaCppResults(body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if len(results) != len(texts) {
+		return nil, fmt.Errorf("llama.cpp returned %d embeddings for %d inputs", len(results), len(texts))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, r := range results {
+		if r.Index < 0 || r.Index >= len(texts) || embeddings[r.Index] != nil {
+			return nil, fmt.Errorf("llama.cpp returned an embedding for unexpected index %d", r.Index)
+		}
+		if embeddings[r.Index], err = r.vector(); err != nil {
+			return nil, fmt.Errorf("embedding %d: %w", i, err)
+		}
+	}
+	return embeddings, nil
+}
+
+// parseLlamaCppResults reads the response of any server version: a list
+// of results, or an object holding one embedding or a list of results.
+func parseLlamaCppResults(body []byte) ([]llamaCppEmbedding, error) {
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("[")) {
+		var results []llamaCppEmbedding
+		err := json.Unmarshal(body, &results)
+		return results, err
+	}
+
+	var legacy struct {
+		Embedding json.RawMessage     `json:"embedding"`
+		Results   []llamaCppEmbedding `json:"results"`
+	}
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	if legacy.Embedding != nil {
+		return []llamaCppEmbedding{{Embedding: legacy.Embedding}}, nil
+	}
+	for i := range legacy.Results {
+		legacy.Results[i].Index = i // Legacy results are in input order
+	}
+	return legacy.Results, nil
+}
+
+// vector returns the pooled embedding. Per-token rows mean the server
+// was started without pooling, which this adapter cannot use.
+func (e llamaCppEmbedding) vector() ([]float32, error) {
+	var vec []float32
+	if err := json.Unmarshal(e.Embedding, &vec); err == nil {
+		return vec, nil
+	}
+	var rows [][]float32
+	if err := json.Unmarshal(e.Embedding, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, fmt.Errorf("got %d per-token rows; start llama-server with --pooling mean (or cls/last)", len(rows))
+	}
+	return rows[0], nil
+}
+
+// SetInstructions sets the prefixes EmbedQueries and EmbedPassages put
+// before texts, such as InstructionsFor(model) for the served model.
+func (a *LlamaCppAdapter) SetInstructions(in Instructions) {
+	a.instructions = in
+}
+
+// EmbedQueries embeds search queries behind the query instruction.
+func (a *LlamaCppAdapter) EmbedQueries(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.queries(texts))
+}
+
+// EmbedPassages embeds passages behind the passage instruction.
+func (a *LlamaCppAdapter) EmbedPassages(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.passages(texts))
+}
+
+// Health reports the llama.cpp connection state.
+func (a *LlamaCppAdapter) Health() ports.BackendHealth {
+	return a.health.Health()
+}
@@ -0,0 +1,61 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLlamaCppAdapter_EmbedBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embedding" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var req llamaCppEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "nomic" || len(req.Content) != 2 {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		// Pooled rows, out of order on purpose
+		w.Write([]byte(`[{"index":1,"embedding":[[0,1]]},{"index":0,"embedding":[[1,0]]}]`))
+	}))
+	defer server.Close()
+
+	adapter := NewLlamaCppAdapter(server.URL, "nomic")
+	embs, err := adapter.EmbedBatch(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if len(embs) != 2 || embs[0][0] != 1 || embs[1][1] != 1 {
+		t.Errorf("embeddings not matched to inputs: %v", embs)
+	}
+}
+
+func TestLlamaCppAdapter_LegacyResponses(t *testing.T) {
+	for _, body := range []string{
+		`{"embedding":[0.5,0.25]}`,
+		`{"results":[{"embedding":[0.5,0.25]}]}`,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		emb, err := NewLlamaCppAdapter(server.URL, "").Embed(context.Background(), "text")
+		server.Close()
+		if err != nil || len(emb) != 2 || emb[0] != 0.5 {
+			t.Errorf("%s: got %v, %v", body, emb, err)
+		}
+	}
+}
+
+func TestLlamaCppAdapter_UnpooledEmbeddings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"index":0,"embedding":[[1,0],[0,1]]}]`))
+	}))
+	defer server.Close()
+
+	if _, err := NewLlamaCppAdapter(server.URL, "").Embed(context.Background(), "two tokens"); err == nil {
+		t.Error("should error when the server returns per-token embeddings")
+	}
+}
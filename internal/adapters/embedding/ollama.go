@@ -7,17 +7,32 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/ollama"
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 )
 
 // OllamaAdapter implements ports.EmbeddingService using Ollama API.
 type OllamaAdapter struct {
-	baseURL string
-	model   string
-	client  *http.Client
+	baseURL      string
+	model        string
+	client       *http.Client
+	health       *resilience.Tracker
+	backoff      resilience.Backoff
+	instructions Instructions // Prefixes for EmbedQueries and EmbedPassages
+	legacy       atomic.Bool  // Set once the server turns out to lack /api/embed
+	workers      atomic.Int32 // Requests EmbedBatch keeps in flight, see SetConcurrency
+	keep         string       // How long Ollama keeps the model loaded, see SetKeepAlive
 }
 
 // NewOllamaAdapter creates a new Ollama embedding adapter.
@@ -31,79 +46,285 @@ func NewOllamaAdapter(baseURL, model string) *OllamaAdapter {
 	return &OllamaAdapter{
 		baseURL: baseURL,
 		model:   model,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		client:  resilience.NewHTTPClient(60 * time.Second),
+		health:  resilience.NewTracker("ollama-embedding"),
+		backoff: resilience.DefaultBackoff,
+	}
+}
+
+// SetConcurrency sets how many embedding requests EmbedBatch keeps in
+// flight at once; below 2 it sends them one after another. Ollama runs
+// at most OLLAMA_NUM_PARALLEL requests per model at a time and queues
+// the rest, so more workers than that gain nothing. It is safe to call
+// while batches are embedding; those already started keep their workers.
+func (a *OllamaAdapter) SetConcurrency(workers int) {
+	a.workers.Store(int32(workers))
+}
+
+// SetKeepAlive sets how long Ollama keeps the embedding model in memory
+// after a request, instead of its default of five minutes
+// (OLLAMA_KEEP_ALIVE), so a query after a quiet spell does not wait for
+// the model to load again. A negative d keeps it loaded until Ollama
+// stops; 0 unloads it right after each request.
+func (a *OllamaAdapter) SetKeepAlive(d time.Duration) {
+	if d < 0 {
+		a.keep = "-1"
+		return
+	}
+	a.keep = d.String()
+}
+
+// Preload loads the embedding model into memory by embedding a short
+// text, so the first query does not wait for it.
+func (a *OllamaAdapter) Preload(ctx context.Context) error {
+	if _, err := a.embed(ctx, []string{"warm-up"}); err != nil {
+		return fmt.Errorf("loading %s: %w", a.model, err)
 	}
+	return nil
+}
+
+// SetRetry sets how embedding requests are retried when Ollama is
+// unreachable or answers 502, 503, 504 or 429. The default rides out a
+// restart of a few seconds; a long ingest may want more attempts and a
+// higher Max so a slow restart does not abort it.
+func (a *OllamaAdapter) SetRetry(b resilience.Backoff) {
+	a.backoff = b
+}
+
+// SetCircuitBreaker stops requests to Ollama after threshold failures
+// in a row and holds them back for cooldown before trying one again,
+// so concurrent EmbedBatch workers do not hammer a server that is
+// restarting. Held-back requests wait rather than fail while their
+// retry attempts last. A threshold of 0 removes the breaker.
+func (a *OllamaAdapter) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	if threshold <= 0 {
+		a.health.SetBreaker(nil)
+		return
+	}
+	a.health.SetBreaker(resilience.NewBreaker(threshold, cooldown))
+}
+
+// maxOllamaBatch caps the texts sent in one /api/embed request, so a
+// large ingest is a handful of requests of bounded size.
+const maxOllamaBatch = 128
+
+// ollamaBatchRequest is the /api/embed request format.
+type ollamaBatchRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+}
+
+// ollamaBatchResponse is the /api/embed response format, one embedding
+// per input in input order.
+type ollamaBatchResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
 }
 
-// ollamaEmbedRequest is the Ollama API request format.
+// ollamaEmbedRequest is the legacy /api/embeddings request format.
 type ollamaEmbedRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
+	Model     string `json:"model"`
+	Prompt    string `json:"prompt"`
+	KeepAlive string `json:"keep_alive,omitempty"`
 }
 
-// ollamaEmbedResponse is the Ollama API response format.
+// ollamaEmbedResponse is the legacy /api/embeddings response format.
 type ollamaEmbedResponse struct {
 	Embedding []float32 `json:"embedding"`
 }
 
 // Embed generates an embedding for a single text.
 func (a *OllamaAdapter) Embed(ctx context.Context, text string) ([]float32, error) {
-	log.Printf("[DEBUG] Embedding request to %s with model %s", a.baseURL, a.model)
-	
-	reqBody := ollamaEmbedRequest{
-		Model:  a.model,
-		Prompt: text,
+	embeddings, err := a.embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts, up to
+// maxOllamaBatch per /api/embed request, with as many requests in flight
+// as SetConcurrency allows. Embeddings are in input order. If Ollama
+// rejects a request, its texts are resent one at a time and those
+// rejected again are reported in a *ports.BatchEmbedError.
+func (a *OllamaAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	workers := max(int(a.workers.Load()), 1)
+	size := maxOllamaBatch
+	if workers > 1 {
+		// Spread small inputs over every worker
+		size = max(min(size, (len(texts)+workers-1)/workers), 1)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	embeddings := make([][]float32, len(texts))
+	failed := make(map[int]error)
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for start := 0; start < len(texts) && ctx.Err() == nil; start += size {
+		end := min(start+size, len(texts))
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(start, end int) {
+			defer func() { <-sem; wg.Done() }()
+			batch, err := embedOrIsolate(ctx, texts[start:end], a.embed)
+
+			mu.Lock()
+			defer mu.Unlock()
+			var batchErr *ports.BatchEmbedError
+			if errors.As(err, &batchErr) {
+				for i, cause := range batchErr.Failed {
+					failed[start+i] = cause
+				}
+			} else if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel() // No point embedding the rest
+				}
+				return
+			}
+			copy(embeddings[start:end], batch)
+		}(start, end)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(failed) > 0 {
+		return embeddings, &ports.BatchEmbedError{Failed: failed}
+	}
+	return embeddings, nil
+}
+
+// embed sends one /api/embed request, or one legacy /api/embeddings
+// request per text to Ollama versions before 0.3 that lack it.
+func (a *OllamaAdapter) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if a.legacy.Load() {
+		return a.embedLegacy(ctx, texts)
 	}
+	log.Printf("[DEBUG] Embedding %d texts at %s with model %s", len(texts), a.baseURL, a.model)
 
-	jsonData, err := json.Marshal(reqBody)
+	var embedResp ollamaBatchResponse
+	err := a.post(ctx, "/api/embed", ollamaBatchRequest{Model: a.model, Input: texts, KeepAlive: a.keep}, &embedResp)
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.missingRoute() {
+		a.legacy.Store(true)
+		log.Printf("[INFO] Ollama at %s has no /api/embed; embedding one text per request", a.baseURL)
+		return a.embedLegacy(ctx, texts)
+	}
 	if err != nil {
-		log.Printf("[ERROR] Marshal error: %v", err)
-		return nil, fmt.Errorf("marshaling request: %w", err)
+		return nil, err
+	}
+	if len(embedResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("Ollama returned %d embeddings for %d inputs", len(embedResp.Embeddings), len(texts))
 	}
+	return embedResp.Embeddings, nil
+}
+
+// embedLegacy embeds texts through /api/embeddings, which takes one
+// text per request.
+func (a *OllamaAdapter) embedLegacy(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		var embedResp ollamaEmbedResponse
+		if err := a.post(ctx, "/api/embeddings", ollamaEmbedRequest{Model: a.model, Prompt: text, KeepAlive: a.keep}, &embedResp); err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedResp.Embedding
+	}
+	return embeddings, nil
+}
+
+// missingRoute reports whether the server has no such endpoint. Ollama
+// also answers 404 for unknown models, but with a JSON error body.
+func (e *statusError) missingRoute() bool {
+	return e.status == http.StatusNotFound && !strings.HasPrefix(e.body, "{")
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/api/embeddings", bytes.NewReader(jsonData))
+// post sends body as JSON to an Ollama endpoint and decodes the response
+// into out. Statuses other than 200 OK are a *statusError.
+func (a *OllamaAdapter) post(ctx context.Context, path string, body, out interface{}) error {
+	jsonData, err := json.Marshal(body)
 	if err != nil {
-		log.Printf("[ERROR] Request create error: %v", err)
-		return nil, fmt.Errorf("creating request: %w", err)
+		return fmt.Errorf("marshaling request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	log.Printf("[DEBUG] Calling Ollama at %s/api/embeddings...", a.baseURL)
-	resp, err := a.client.Do(req)
+	resp, err := resilience.DoHTTP(ctx, a.client, a.health, a.backoff, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+path, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		log.Printf("[ERROR] Ollama call error: %v", err)
-		return nil, fmt.Errorf("calling Ollama: %w", err)
+		return fmt.Errorf("calling Ollama: %w", err)
 	}
 	defer resp.Body.Close()
 
-	log.Printf("[DEBUG] Ollama responded with status %d", resp.StatusCode)
-	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Ollama returned status %d", resp.StatusCode)
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{backend: "Ollama", status: resp.StatusCode, body: string(bytes.TrimSpace(msg))}
 	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// SetInstructions sets the prefixes EmbedQueries and EmbedPassages put
+// before texts, such as InstructionsFor(model) for the served model.
+func (a *OllamaAdapter) SetInstructions(in Instructions) {
+	a.instructions = in
+}
 
-	var embedResp ollamaEmbedResponse
-	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
-		log.Printf("[ERROR] Decode error: %v", err)
-		return nil, fmt.Errorf("decoding response: %w", err)
+// EmbedQueries embeds search queries behind the query instruction.
+func (a *OllamaAdapter) EmbedQueries(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.queries(texts))
+}
+
+// EmbedPassages embeds passages behind the passage instruction.
+func (a *OllamaAdapter) EmbedPassages(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.passages(texts))
+}
+
+// Models lists the models installed on the Ollama server, sorted.
+func (a *OllamaAdapter) Models(ctx context.Context) ([]string, error) {
+	models, err := ollama.Tags(ctx, a.client, a.baseURL)
+	if err != nil {
+		return nil, err
 	}
+	return ollama.Names(models), nil
+}
 
-	log.Printf("[OK] Got embedding with %d dimensions", len(embedResp.Embedding))
-	return embedResp.Embedding, nil
+// Model returns the configured model with its tag, as Ollama lists it.
+func (a *OllamaAdapter) Model() string {
+	return ollama.Canonical(a.model)
 }
 
-// EmbedBatch generates embeddings for multiple texts.
-// Currently calls Embed sequentially - can be parallelized if needed.
-func (a *OllamaAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
-	embeddings := make([][]float32, len(texts))
-	for i, text := range texts {
-		emb, err := a.Embed(ctx, text)
-		if err != nil {
-			return nil, fmt.Errorf("embedding text %d: %w", i, err)
-		}
-		embeddings[i] = emb
+// ModelInstalled reports whether the embedding model is installed on the
+// Ollama server.
+func (a *OllamaAdapter) ModelInstalled(ctx context.Context) (bool, error) {
+	models, err := ollama.Tags(ctx, a.client, a.baseURL)
+	if err != nil {
+		return false, err
 	}
-	return embeddings, nil
+	return ollama.Installed(models, a.model), nil
+}
+
+// PullModel downloads the embedding model to the Ollama server.
+func (a *OllamaAdapter) PullModel(ctx context.Context, progress func(ports.PullProgress)) error {
+	return ollama.Pull(ctx, ollama.NoTimeout(a.client), a.baseURL, a.model, progress)
+}
+
+// Health reports the Ollama connection state.
+func (a *OllamaAdapter) Health() ports.BackendHealth {
+	return a.health.Health()
 }
@@ -3,19 +3,27 @@ package embedding
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 )
 
 func TestOllamaAdapter_Embed(t *testing.T) {
 	// Mock Ollama server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/api/embeddings" {
+		if r.URL.Path != "/api/embed" {
 			t.Errorf("unexpected path: %s", r.URL.Path)
 		}
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"embedding": []float32{0.1, 0.2, 0.3},
+			"embeddings": [][]float32{{0.1, 0.2, 0.3}},
 		})
 	}))
 	defer server.Close()
@@ -35,21 +43,103 @@ func TestOllamaAdapter_EmbedBatch(t *testing.T) {
 	callCount := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		callCount++
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"embedding": []float32{float32(callCount) * 0.1},
-		})
+		var req ollamaBatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		embeddings := make([][]float32, len(req.Input))
+		for i := range embeddings {
+			embeddings[i] = []float32{float32(callCount)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
 	}))
 	defer server.Close()
 
 	adapter := NewOllamaAdapter(server.URL, "test-model")
-	texts := []string{"a", "b", "c"}
+	texts := make([]string, maxOllamaBatch+2)
 	results, err := adapter.EmbedBatch(context.Background(), texts)
 
 	if err != nil {
 		t.Fatalf("batch failed: %v", err)
 	}
-	if len(results) != 3 {
-		t.Errorf("expected 3 results, got %d", len(results))
+	if len(results) != len(texts) || callCount != 2 {
+		t.Errorf("expected %d results from 2 requests, got %d from %d", len(texts), len(results), callCount)
+	}
+	if results[maxOllamaBatch][0] != 2 {
+		t.Errorf("second request's embeddings out of place: %v", results[maxOllamaBatch])
+	}
+}
+
+func TestOllamaAdapter_ConcurrentEmbedBatch(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		var req ollamaBatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		embeddings := make([][]float32, len(req.Input))
+		for i, text := range req.Input {
+			v, _ := strconv.Atoi(text)
+			embeddings[i] = []float32{float32(v)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaAdapter(server.URL, "test-model")
+	adapter.SetConcurrency(4)
+	texts := make([]string, 10)
+	for i := range texts {
+		texts[i] = strconv.Itoa(i)
+	}
+	results, err := adapter.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	for i, emb := range results {
+		if emb[0] != float32(i) {
+			t.Fatalf("embedding %d out of order: %v", i, results)
+		}
+	}
+	if p := peak.Load(); p < 2 || p > 4 {
+		t.Errorf("expected 2-4 requests in flight, peaked at %d", p)
+	}
+}
+
+func TestOllamaAdapter_LegacyEndpoint(t *testing.T) {
+	legacyCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/embed" {
+			http.NotFound(w, r) // Ollama before 0.3
+			return
+		}
+		legacyCalls++
+		json.NewEncoder(w).Encode(map[string]interface{}{"embedding": []float32{0.1}})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaAdapter(server.URL, "test-model")
+	results, err := adapter.EmbedBatch(context.Background(), []string{"a", "b"})
+	if err != nil || len(results) != 2 || legacyCalls != 2 {
+		t.Fatalf("expected a fallback to /api/embeddings, got %v, %v after %d calls", results, err, legacyCalls)
+	}
+	if !adapter.legacy.Load() {
+		t.Error("the fallback should be remembered")
+	}
+}
+
+func TestOllamaAdapter_UnknownModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model \"missing\" not found, try pulling it first"}`))
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaAdapter(server.URL, "missing")
+	if _, err := adapter.Embed(context.Background(), "text"); err == nil || adapter.legacy.Load() {
+		t.Errorf("an unknown model should fail without a legacy fallback, got %v", err)
 	}
 }
 
@@ -67,6 +157,87 @@ func TestOllamaAdapter_ServerError(t *testing.T) {
 	}
 }
 
+func TestOllamaAdapter_RidesOutRestart(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 3 {
+			w.WriteHeader(http.StatusServiceUnavailable) // Restarting
+			return
+		}
+		var req ollamaBatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		embeddings := make([][]float32, len(req.Input))
+		for i := range embeddings {
+			embeddings[i] = []float32{0.1}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaAdapter(server.URL, "test-model")
+	adapter.SetRetry(resilience.Backoff{Initial: time.Millisecond, Max: 2 * time.Millisecond, Attempts: 20})
+	adapter.SetCircuitBreaker(2, 5*time.Millisecond)
+	adapter.SetConcurrency(4)
+
+	results, err := adapter.EmbedBatch(context.Background(), []string{"a", "b", "c", "d"})
+	if err != nil || len(results) != 4 {
+		t.Fatalf("expected the batch to survive the restart, got %v", err)
+	}
+	if h := adapter.Health(); !h.Healthy || h.CircuitOpen {
+		t.Errorf("breaker should close once Ollama is back: %+v", h)
+	}
+}
+
+func TestOllamaAdapter_Instructions(t *testing.T) {
+	var inputs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaBatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		inputs = append(inputs, req.Input...)
+		embeddings := make([][]float32, len(req.Input))
+		for i := range embeddings {
+			embeddings[i] = []float32{0.1}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaAdapter(server.URL, "nomic-embed-text")
+	adapter.SetInstructions(InstructionsFor("nomic-embed-text"))
+	ctx := context.Background()
+	if _, err := adapter.EmbedQueries(ctx, []string{"what is rag?"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.EmbedPassages(ctx, []string{"RAG retrieves context."}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.Embed(ctx, "as is"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"search_query: what is rag?", "search_document: RAG retrieves context.", "as is"}
+	if strings.Join(inputs, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", inputs, want)
+	}
+}
+
+func TestInstructionsFor(t *testing.T) {
+	tests := []struct {
+		model string
+		want  Instructions
+	}{
+		{"nomic-embed-text:latest", Instructions{Query: "search_query: ", Passage: "search_document: "}},
+		{"intfloat/multilingual-e5-large", Instructions{Query: "query: ", Passage: "passage: "}},
+		{"BAAI/bge-small-en-v1.5", Instructions{Query: bgeQueryInstruction}},
+		{"bge-m3", Instructions{}},
+		{"all-minilm", Instructions{}},
+	}
+	for _, tt := range tests {
+		if got := InstructionsFor(tt.model); got != tt.want {
+			t.Errorf("InstructionsFor(%q) = %+v, want %+v", tt.model, got, tt.want)
+		}
+	}
+}
+
 func TestOllamaAdapter_DefaultValues(t *testing.T) {
 	adapter := NewOllamaAdapter("", "")
 	if adapter.baseURL != "http://localhost:11434" {
@@ -76,3 +247,85 @@ func TestOllamaAdapter_DefaultValues(t *testing.T) {
 		t.Error("should default to nomic-embed-text")
 	}
 }
+
+func TestOllamaAdapter_EmbedBatchReportsFailedTexts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaBatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		embeddings := make([][]float32, len(req.Input))
+		for i, text := range req.Input {
+			if text == "bad" {
+				http.Error(w, "bad input", http.StatusBadRequest)
+				return
+			}
+			embeddings[i] = []float32{0.1}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaAdapter(server.URL, "test-model")
+	results, err := adapter.EmbedBatch(context.Background(), []string{"a", "bad", "c"})
+	var batchErr *ports.BatchEmbedError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[1] == nil {
+		t.Fatalf("expected text 1 to fail alone, got %v", err)
+	}
+	if results[0] == nil || results[1] != nil || results[2] == nil {
+		t.Errorf("other texts should still embed: %v", results)
+	}
+}
+
+func TestOllamaAdapter_OutageFailsWholeBatch(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaAdapter(server.URL, "test-model")
+	adapter.SetRetry(resilience.Backoff{Initial: time.Millisecond, Max: 2 * time.Millisecond, Attempts: 2})
+	_, err := adapter.EmbedBatch(context.Background(), []string{"a", "b", "c", "d"})
+	var batchErr *ports.BatchEmbedError
+	if err == nil || errors.As(err, &batchErr) {
+		t.Fatalf("an outage should fail the whole batch, got %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected the batch to be retried as one request, got %d requests", n)
+	}
+
+	// Once the breaker opens, the batch is refused without being split
+	calls.Store(0)
+	adapter.SetRetry(resilience.Backoff{Attempts: 1})
+	adapter.SetCircuitBreaker(1, time.Hour)
+	adapter.EmbedBatch(context.Background(), []string{"a"})
+	_, err = adapter.EmbedBatch(context.Background(), []string{"a", "b", "c", "d"})
+	if !errors.Is(err, resilience.ErrCircuitOpen) || errors.As(err, &batchErr) {
+		t.Fatalf("expected an open circuit for the whole batch, got %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected only the request that opened the breaker, got %d requests", n)
+	}
+}
+
+func TestOllamaAdapter_KeepAliveAndPreload(t *testing.T) {
+	var got []ollamaBatchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaBatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		got = append(got, req)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"embeddings": [][]float32{{0.1, 0.2, 0.3}},
+		})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaAdapter(server.URL, "test-model")
+	adapter.SetKeepAlive(time.Hour)
+	if err := adapter.Preload(context.Background()); err != nil {
+		t.Fatalf("preload failed: %v", err)
+	}
+	if len(got) != 1 || got[0].KeepAlive != "1h0m0s" || len(got[0].Input) != 1 {
+		t.Errorf("unexpected preload request: %+v", got)
+	}
+}
@@ -0,0 +1,197 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrONNXUnavailable is returned by NewONNXAdapter in builds without the
+// onnx tag.
+var ErrONNXUnavailable = errors.New("ONNX embeddings need a build with -tags onnx")
+
+// ONNXPooling selects how token states are combined into one embedding.
+type ONNXPooling int
+
+const (
+	// PoolMean averages the token states, as sentence-transformers
+	// models such as all-MiniLM-L6-v2 expect.
+	PoolMean ONNXPooling = iota
+	// PoolCLS takes the [CLS] token's state, as BGE models expect.
+	PoolCLS
+)
+
+const (
+	defaultONNXMaxTokens = 256
+	defaultONNXBatchSize = 16
+)
+
+// ONNXOptions configures an in-process ONNX embedding model.
+type ONNXOptions struct {
+	ModelPath   string // Exported BERT-style encoder, e.g. model.onnx
+	VocabPath   string // WordPiece vocab.txt shipped with the model
+	LibraryPath string // onnxruntime shared library; empty uses the platform default
+	MaxTokens   int    // Longer texts are truncated; 0 means 256
+	BatchSize   int    // Texts per inference run; 0 means 16
+	Pooling     ONNXPooling
+	Cased       bool // Keep case, for cased vocabularies
+
+	// Prefixes for EmbedQueries and EmbedPassages, e.g.
+	// InstructionsFor("bge-small-en-v1.5")
+	Instructions Instructions
+}
+
+// ONNXAdapter implements ports.EmbeddingService by running a small
+// sentence embedding model such as all-MiniLM-L6-v2 or bge-small-en
+// in-process with onnxruntime, so no embedding server is needed.
+// Embeddings are L2-normalized.
+type ONNXAdapter struct {
+	opts      ONNXOptions
+	tokenizer *wordPieceTokenizer
+	session   onnxSession
+}
+
+// onnxSession runs the encoder over a padded batch of token IDs and
+// returns the last hidden state, batch x seqLen x dim, flattened.
+type onnxSession interface {
+	run(ids, mask, types []int64, batch, seqLen int) (hidden []float32, dim int, err error)
+	close() error
+}
+
+// NewONNXAdapter loads the model and its vocabulary; Close releases
+// them. Builds without the onnx tag return ErrONNXUnavailable.
+func NewONNXAdapter(opts ONNXOptions) (*ONNXAdapter, error) {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = defaultONNXMaxTokens
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultONNXBatchSize
+	}
+	session, err := openONNXSession(opts)
+	if err != nil {
+		return nil, err
+	}
+	tokenizer, err := loadWordPiece(opts.VocabPath, !opts.Cased)
+	if err != nil {
+		session.close()
+		return nil, err
+	}
+	return &ONNXAdapter{opts: opts, tokenizer: tokenizer, session: session}, nil
+}
+
+// Embed generates an embedding for a single text.
+func (a *ONNXAdapter) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := a.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts, running at most
+// BatchSize texts through the model at a time.
+func (a *ONNXAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += a.opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+a.opts.BatchSize, len(texts))
+		batch, err := a.embed(texts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("embedding texts %d-%d: %w", start, end-1, err)
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
+
+// embed tokenizes texts, pads them to the longest, and pools the
+// model's token states into one normalized vector per text.
+func (a *ONNXAdapter) embed(texts []string) ([][]float32, error) {
+	tokens := make([][]int64, len(texts))
+	seqLen := 0
+	for i, text := range texts {
+		tokens[i] = a.tokenizer.encode(text, a.opts.MaxTokens)
+		seqLen = max(seqLen, len(tokens[i]))
+	}
+
+	ids := make([]int64, len(texts)*seqLen)
+	mask := make([]int64, len(texts)*seqLen)
+	types := make([]int64, len(texts)*seqLen)
+	for i, toks := range tokens {
+		row := i * seqLen
+		for j := 0; j < seqLen; j++ {
+			if j < len(toks) {
+				ids[row+j] = toks[j]
+				mask[row+j] = 1
+			} else {
+				ids[row+j] = a.tokenizer.pad
+			}
+		}
+	}
+
+	hidden, dim, err := a.session.run(ids, mask, types, len(texts), seqLen)
+	if err != nil {
+		return nil, err
+	}
+	if dim == 0 || len(hidden) != len(texts)*seqLen*dim {
+		return nil, fmt.Errorf("model returned %d values for %d texts of %d tokens", len(hidden), len(texts), seqLen)
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i := range texts {
+		embeddings[i] = pool(hidden[i*seqLen*dim:(i+1)*seqLen*dim], mask[i*seqLen:(i+1)*seqLen], dim, a.opts.Pooling)
+	}
+	return embeddings, nil
+}
+
+// pool combines one text's token states into a unit-length vector.
+func pool(states []float32, mask []int64, dim int, pooling ONNXPooling) []float32 {
+	out := make([]float32, dim)
+	if pooling == PoolCLS {
+		copy(out, states[:dim])
+	} else {
+		var tokens float32
+		for t, m := range mask {
+			if m == 0 {
+				continue
+			}
+			tokens++
+			for d := 0; d < dim; d++ {
+				out[d] += states[t*dim+d]
+			}
+		}
+		for d := range out {
+			out[d] /= tokens
+		}
+	}
+
+	var norm float64
+	for _, v := range out {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for d := range out {
+			out[d] *= scale
+		}
+	}
+	return out
+}
+
+// EmbedQueries embeds search queries behind the query instruction.
+func (a *ONNXAdapter) EmbedQueries(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.opts.Instructions.queries(texts))
+}
+
+// EmbedPassages embeds passages behind the passage instruction.
+func (a *ONNXAdapter) EmbedPassages(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.opts.Instructions.passages(texts))
+}
+
+// Close releases the model.
+func (a *ONNXAdapter) Close() error {
+	return a.session.close()
+}
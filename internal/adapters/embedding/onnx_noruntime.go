@@ -0,0 +1,8 @@
+//go:build !onnx
+
+package embedding
+
+// openONNXSession fails in builds without onnxruntime.
+func openONNXSession(opts ONNXOptions) (onnxSession, error) {
+	return nil, ErrONNXUnavailable
+}
@@ -0,0 +1,15 @@
+//go:build !onnx
+
+package embedding
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewONNXAdapter_Unavailable(t *testing.T) {
+	_, err := NewONNXAdapter(ONNXOptions{ModelPath: "model.onnx", VocabPath: "vocab.txt"})
+	if !errors.Is(err, ErrONNXUnavailable) {
+		t.Errorf("expected ErrONNXUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+//go:build onnx
+
+package embedding
+
+import (
+	"fmt"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// onnxruntime has one environment per process, bound to the shared
+// library the first adapter names.
+var ortEnv struct {
+	once sync.Once
+	err  error
+}
+
+// ortSession runs a model through onnxruntime_go.
+type ortSession struct {
+	session *ort.DynamicAdvancedSession
+	inputs  []string // Of input_ids, attention_mask and token_type_ids, those the model takes
+}
+
+func openONNXSession(opts ONNXOptions) (onnxSession, error) {
+	ortEnv.once.Do(func() {
+		if opts.LibraryPath != "" {
+			ort.SetSharedLibraryPath(opts.LibraryPath)
+		}
+		ortEnv.err = ort.InitializeEnvironment()
+	})
+	if ortEnv.err != nil {
+		return nil, fmt.Errorf("initializing onnxruntime: %w", ortEnv.err)
+	}
+
+	inputInfo, outputInfo, err := ort.GetInputOutputInfo(opts.ModelPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading model: %w", err)
+	}
+	if len(outputInfo) == 0 {
+		return nil, fmt.Errorf("model %s has no outputs", opts.ModelPath)
+	}
+	var inputs []string
+	for _, info := range inputInfo {
+		switch info.Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+			inputs = append(inputs, info.Name)
+		default:
+			return nil, fmt.Errorf("model takes unsupported input %q", info.Name)
+		}
+	}
+
+	// The first output is the last hidden state in both Optimum and
+	// sentence-transformers exports
+	session, err := ort.NewDynamicAdvancedSession(opts.ModelPath, inputs, []string{outputInfo[0].Name}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading model: %w", err)
+	}
+	return &ortSession{session: session, inputs: inputs}, nil
+}
+
+func (s *ortSession) run(ids, mask, types []int64, batch, seqLen int) ([]float32, int, error) {
+	shape := ort.NewShape(int64(batch), int64(seqLen))
+	data := map[string][]int64{"input_ids": ids, "attention_mask": mask, "token_type_ids": types}
+	inputs := make([]ort.Value, len(s.inputs))
+	for i, name := range s.inputs {
+		tensor, err := ort.NewTensor(shape, data[name])
+		if err != nil {
+			return nil, 0, fmt.Errorf("creating %s tensor: %w", name, err)
+		}
+		defer tensor.Destroy()
+		inputs[i] = tensor
+	}
+
+	// A nil output is allocated by onnxruntime with the shape it computes
+	outputs := []ort.Value{nil}
+	if err := s.session.Run(inputs, outputs); err != nil {
+		return nil, 0, fmt.Errorf("running model: %w", err)
+	}
+	defer outputs[0].Destroy()
+
+	hidden, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, 0, fmt.Errorf("model output is not a float32 tensor")
+	}
+	dims := hidden.GetShape()
+	if len(dims) != 3 {
+		return nil, 0, fmt.Errorf("model output has shape %v, want batch x tokens x dim", dims)
+	}
+	// The tensor's memory belongs to onnxruntime; copy before destroying it
+	return append([]float32(nil), hidden.GetData()...), int(dims[2]), nil
+}
+
+func (s *ortSession) close() error {
+	return s.session.Destroy()
+}
@@ -0,0 +1,88 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeONNXSession returns, for each token, a state of [id, 1].
+type fakeONNXSession struct {
+	batches []int
+}
+
+func (f *fakeONNXSession) run(ids, mask, types []int64, batch, seqLen int) ([]float32, int, error) {
+	f.batches = append(f.batches, batch)
+	hidden := make([]float32, 0, len(ids)*2)
+	for _, id := range ids {
+		hidden = append(hidden, float32(id), 1)
+	}
+	return hidden, 2, nil
+}
+
+func (f *fakeONNXSession) close() error { return nil }
+
+func testVocab(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "vocab.txt")
+	vocab := []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "hello", "world", "un", "##believ", "##able", ",", "!"}
+	if err := os.WriteFile(path, []byte(strings.Join(vocab, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWordPiece_Encode(t *testing.T) {
+	tok, err := loadWordPiece(testVocab(t), true)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	got := tok.encode("Hello, UNBELIEVABLE world!  xyz", 64)
+	want := []int64{2, 4, 9, 6, 7, 8, 5, 10, 1, 3}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	// Truncation keeps [CLS] and [SEP]
+	if got := tok.encode("hello world hello world", 4); len(got) != 4 || got[0] != 2 || got[3] != 3 {
+		t.Errorf("unexpected truncation: %v", got)
+	}
+}
+
+func TestONNXAdapter_EmbedBatch(t *testing.T) {
+	tok, _ := loadWordPiece(testVocab(t), true)
+	session := &fakeONNXSession{}
+	adapter := &ONNXAdapter{
+		opts:      ONNXOptions{MaxTokens: 16, BatchSize: 2},
+		tokenizer: tok,
+		session:   session,
+	}
+
+	embs, err := adapter.EmbedBatch(context.Background(), []string{"hello", "hello world", "world"})
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if len(session.batches) != 2 || session.batches[0] != 2 || session.batches[1] != 1 {
+		t.Errorf("unexpected batches: %v", session.batches)
+	}
+
+	// "hello" pools [CLS]=2, hello=4, [SEP]=3 to [3, 1]; padding is ignored
+	want := []float32{3 / float32(math.Sqrt(10)), 1 / float32(math.Sqrt(10))}
+	if len(embs) != 3 || math.Abs(float64(embs[0][0]-want[0])) > 1e-6 || math.Abs(float64(embs[0][1]-want[1])) > 1e-6 {
+		t.Errorf("unexpected embedding %v, want %v", embs[0], want)
+	}
+
+	adapter.opts.Pooling = PoolCLS
+	emb, _ := adapter.Embed(context.Background(), "world")
+	if math.Abs(float64(emb[0])-2/math.Sqrt(5)) > 1e-6 {
+		t.Errorf("CLS pooling should use the first token, got %v", emb)
+	}
+}
@@ -0,0 +1,224 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// OpenAICompatAdapter implements ports.EmbeddingService for local runtimes
+// that serve the OpenAI /v1/embeddings API, such as llamafile and LM Studio.
+// GPT4All's local server has no embeddings endpoint, so GPT4All users
+// pair its LLM adapter with one of the other embedding adapters.
+type OpenAICompatAdapter struct {
+	name         string // Runtime name for errors and health reports
+	baseURL      string
+	model        string
+	client       *http.Client
+	health       *resilience.Tracker
+	backoff      resilience.Backoff
+	instructions Instructions // Prefixes for EmbedQueries and EmbedPassages
+	typedModels  bool         // Server lists model types at /api/v0/models (LM Studio)
+}
+
+// NewLlamafileAdapter creates an embedding adapter for a llamafile server
+// started with --embedding.
+func NewLlamafileAdapter(baseURL, model string) *OpenAICompatAdapter {
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	if model == "" {
+		model = "LLaMA_CPP"
+	}
+	return newOpenAICompatAdapter("llamafile", baseURL, model)
+}
+
+// NewLMStudioAdapter creates an embedding adapter for LM Studio's local
+// server. model must name an embedding model loaded in LM Studio, such
+// as "text-embedding-nomic-embed-text-v1.5"; Models lists them.
+func NewLMStudioAdapter(baseURL, model string) *OpenAICompatAdapter {
+	if baseURL == "" {
+		baseURL = "http://localhost:1234"
+	}
+	a := newOpenAICompatAdapter("LM Studio", baseURL, model)
+	a.typedModels = true
+	return a
+}
+
+func newOpenAICompatAdapter(name, baseURL, model string) *OpenAICompatAdapter {
+	return &OpenAICompatAdapter{
+		name:    name,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		client:  resilience.NewHTTPClient(60 * time.Second),
+		health:  resilience.NewTracker(strings.ReplaceAll(strings.ToLower(name), " ", "") + "-embedding"),
+		backoff: resilience.DefaultBackoff,
+	}
+}
+
+// embeddingsRequest is the OpenAI embeddings request format.
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// embeddingsResponse is the OpenAI embeddings response format.
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed generates an embedding for a single text.
+func (a *OpenAICompatAdapter) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := a.embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts in one request. If
+// the server rejects the request, texts are resent one at a time and
+// those rejected again are reported in a *ports.BatchEmbedError.
+func (a *OpenAICompatAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedOrIsolate(ctx, texts, a.embed)
+}
+
+// embed sends one /v1/embeddings request.
+func (a *OpenAICompatAdapter) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(embeddingsRequest{Model: a.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := resilience.DoHTTP(ctx, a.client, a.health, a.backoff, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/v1/embeddings", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("calling %s: %w", a.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &statusError{backend: a.name, status: resp.StatusCode, body: string(bytes.TrimSpace(msg))}
+	}
+
+	var embResp embeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", a.name, len(embResp.Data), len(texts))
+	}
+
+	// The API does not promise response order; index ties results to inputs
+	sort.Slice(embResp.Data, func(i, j int) bool { return embResp.Data[i].Index < embResp.Data[j].Index })
+	embeddings := make([][]float32, len(texts))
+	for i, d := range embResp.Data {
+		embeddings[i] = d.Embedding
+	}
+	return embeddings, nil
+}
+
+// Models lists the models the server offers for embedding, sorted. LM
+// Studio reports each model's type, so only its embedding models are
+// listed; other runtimes list every model they serve.
+func (a *OpenAICompatAdapter) Models(ctx context.Context) ([]string, error) {
+	if a.typedModels {
+		var typed struct {
+			Data []struct {
+				ID   string `json:"id"`
+				Type string `json:"type"`
+			} `json:"data"`
+		}
+		// Older LM Studio versions lack the typed listing
+		if err := a.getJSON(ctx, "/api/v0/models", &typed); err == nil {
+			models := []string{}
+			for _, m := range typed.Data {
+				if m.Type == "embeddings" {
+					models = append(models, m.ID)
+				}
+			}
+			sort.Strings(models)
+			return models, nil
+		}
+	}
+
+	var listing struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := a.getJSON(ctx, "/v1/models", &listing); err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(listing.Data))
+	for _, m := range listing.Data {
+		models = append(models, m.ID)
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// Model returns the configured model.
+func (a *OpenAICompatAdapter) Model() string {
+	return a.model
+}
+
+// getJSON decodes the response to a GET of path.
+func (a *OpenAICompatAdapter) getJSON(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling %s: %w", a.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d for %s", a.name, resp.StatusCode, path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// SetInstructions sets the prefixes EmbedQueries and EmbedPassages put
+// before texts, such as InstructionsFor(model) for the served model.
+func (a *OpenAICompatAdapter) SetInstructions(in Instructions) {
+	a.instructions = in
+}
+
+// EmbedQueries embeds search queries behind the query instruction.
+func (a *OpenAICompatAdapter) EmbedQueries(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.queries(texts))
+}
+
+// EmbedPassages embeds passages behind the passage instruction.
+func (a *OpenAICompatAdapter) EmbedPassages(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.passages(texts))
+}
+
+// Health reports the backend connection state.
+func (a *OpenAICompatAdapter) Health() ports.BackendHealth {
+	return a.health.Health()
+}
@@ -0,0 +1,133 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+func TestLlamafileAdapter_EmbedBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		// Out of order on purpose
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	adapter := NewLlamafileAdapter(server.URL, "")
+	embs, err := adapter.EmbedBatch(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if len(embs) != 2 || embs[0][0] != 1 || embs[1][1] != 1 {
+		t.Errorf("embeddings not matched to inputs: %v", embs)
+	}
+}
+
+func TestLlamafileAdapter_CountMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	adapter := NewLlamafileAdapter(server.URL, "")
+	if _, err := adapter.Embed(context.Background(), "test"); err == nil {
+		t.Error("should error when the server returns no embeddings")
+	}
+}
+
+func TestLlamafileAdapter_EmbedBatchIsolatesRejectedText(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, text := range req.Input {
+			if text == "bad" {
+				http.Error(w, "input too long", http.StatusBadRequest)
+				return
+			}
+		}
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	adapter := NewLlamafileAdapter(server.URL, "")
+	embs, err := adapter.EmbedBatch(context.Background(), []string{"good", "bad", "fine"})
+	var batchErr *ports.BatchEmbedError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a BatchEmbedError, got %v", err)
+	}
+	if len(batchErr.Failed) != 1 || batchErr.Failed[1] == nil {
+		t.Errorf("only text 1 should fail, got %v", batchErr.Failed)
+	}
+	if len(embs) != 3 || embs[0] == nil || embs[1] != nil || embs[2] == nil {
+		t.Errorf("unexpected embeddings: %v", embs)
+	}
+	if requests != 4 {
+		t.Errorf("expected the batch then one request per text, got %d requests", requests)
+	}
+}
+
+func TestLlamafileAdapter_EmbedBatchUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	adapter := NewLlamafileAdapter(server.URL, "")
+	adapter.backoff.Attempts = 1
+	_, err := adapter.EmbedBatch(context.Background(), []string{"a", "b"})
+	var batchErr *ports.BatchEmbedError
+	if err == nil || errors.As(err, &batchErr) {
+		t.Errorf("an unreachable server should fail the whole batch, got %v", err)
+	}
+}
+
+func TestLMStudioAdapter_Models(t *testing.T) {
+	typed := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v0/models" && typed:
+			w.Write([]byte(`{"data":[{"id":"qwen2.5-7b-instruct","type":"llm"},{"id":"text-embedding-bge-m3","type":"embeddings"}]}`))
+		case r.URL.Path == "/v1/models":
+			w.Write([]byte(`{"data":[{"id":"qwen2.5-7b-instruct"},{"id":"text-embedding-bge-m3"}]}`))
+		case r.URL.Path == "/v1/embeddings":
+			var req embeddingsRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Model != "text-embedding-bge-m3" {
+				t.Errorf("unexpected model %q", req.Model)
+			}
+			w.Write([]byte(`{"data":[{"index":0,"embedding":[0.5,0.5]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	adapter := NewLMStudioAdapter(server.URL, "text-embedding-bge-m3")
+	ctx := context.Background()
+	models, err := adapter.Models(ctx)
+	if err != nil || len(models) != 1 || models[0] != "text-embedding-bge-m3" {
+		t.Fatalf("expected only the embedding model, got %v, %v", models, err)
+	}
+	if emb, err := adapter.Embed(ctx, "hello"); err != nil || len(emb) != 2 {
+		t.Errorf("embed failed: %v, %v", emb, err)
+	}
+
+	// Versions without the typed listing fall back to every model
+	typed = false
+	if models, err := adapter.Models(ctx); err != nil || len(models) != 2 {
+		t.Errorf("expected every model, got %v, %v", models, err)
+	}
+	if h := adapter.Health(); h.Name != "lmstudio-embedding" {
+		t.Errorf("unexpected health name %q", h.Name)
+	}
+}
@@ -0,0 +1,170 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// defaultSidecarBatchSize bounds the texts sent per sidecar request, so
+// one request stays well inside the HTTP client timeout on a CPU.
+const defaultSidecarBatchSize = 32
+
+// SentenceTransformersAdapter implements ports.EmbeddingService against
+// the /embed endpoint of the Python sidecar (python/pdf_service.py),
+// which runs sentence-transformers. It serves models that Ollama does
+// not package yet, loading each from Hugging Face on first use.
+type SentenceTransformersAdapter struct {
+	serviceURL   string
+	model        string // Empty for the sidecar's EMBEDDING_MODEL
+	batchSize    int
+	client       *http.Client
+	health       *resilience.Tracker
+	backoff      resilience.Backoff
+	instructions Instructions // Prefixes for EmbedQueries and EmbedPassages
+}
+
+// NewSentenceTransformersAdapter creates an adapter for the sidecar at
+// serviceURL, the same service as the PDF parser. An empty model uses
+// the sidecar's default; batchSize <= 0 sends 32 texts per request.
+func NewSentenceTransformersAdapter(serviceURL, model string, batchSize int) *SentenceTransformersAdapter {
+	if serviceURL == "" {
+		serviceURL = "http://localhost:8081"
+	}
+	if batchSize <= 0 {
+		batchSize = defaultSidecarBatchSize
+	}
+	return &SentenceTransformersAdapter{
+		serviceURL: serviceURL,
+		model:      model,
+		batchSize:  batchSize,
+		client:     resilience.NewHTTPClient(120 * time.Second), // First use downloads the model
+		health:     resilience.NewTracker("sentence-transformers-embedding"),
+		backoff:    resilience.DefaultBackoff,
+	}
+}
+
+// sidecarEmbedRequest is the sidecar /embed request format.
+type sidecarEmbedRequest struct {
+	Texts []string `json:"texts"`
+	Model string   `json:"model,omitempty"`
+}
+
+// sidecarEmbedResponse is the sidecar /embed response format.
+type sidecarEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	Model      string      `json:"model"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Embed generates an embedding for a single text.
+func (a *SentenceTransformersAdapter) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := a.embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts, sending at most
+// batchSize texts per request. Texts of a rejected request are resent
+// one at a time; those rejected again are reported in a
+// *ports.BatchEmbedError.
+func (a *SentenceTransformersAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	failed := make(map[int]error)
+	for start := 0; start < len(texts); start += a.batchSize {
+		end := start + a.batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := embedOrIsolate(ctx, texts[start:end], a.embed)
+		var batchErr *ports.BatchEmbedError
+		if errors.As(err, &batchErr) {
+			for i, cause := range batchErr.Failed {
+				failed[start+i] = cause
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("embedding texts %d-%d: %w", start, end-1, err)
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	if len(failed) > 0 {
+		return embeddings, &ports.BatchEmbedError{Failed: failed}
+	}
+	return embeddings, nil
+}
+
+// embed sends one /embed request.
+func (a *SentenceTransformersAdapter) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(sidecarEmbedRequest{Texts: texts, Model: a.model})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := resilience.DoHTTP(ctx, a.client, a.health, a.backoff, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.serviceURL+"/embed", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("calling embedding sidecar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	var result sidecarEmbedResponse
+	err = json.Unmarshal(body, &result)
+	if resp.StatusCode != http.StatusOK {
+		msg := string(bytes.TrimSpace(body))
+		if result.Error != "" {
+			msg = result.Error
+		}
+		return nil, &statusError{backend: "sidecar", status: resp.StatusCode, body: msg}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("sidecar embedding error: %s", result.Error)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("sidecar returned %d embeddings for %d inputs", len(result.Embeddings), len(texts))
+	}
+	return result.Embeddings, nil
+}
+
+// SetInstructions sets the prefixes EmbedQueries and EmbedPassages put
+// before texts, such as InstructionsFor(model) for the served model.
+func (a *SentenceTransformersAdapter) SetInstructions(in Instructions) {
+	a.instructions = in
+}
+
+// EmbedQueries embeds search queries behind the query instruction.
+func (a *SentenceTransformersAdapter) EmbedQueries(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.queries(texts))
+}
+
+// EmbedPassages embeds passages behind the passage instruction.
+func (a *SentenceTransformersAdapter) EmbedPassages(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.passages(texts))
+}
+
+// Health reports the sidecar connection state.
+func (a *SentenceTransformersAdapter) Health() ports.BackendHealth {
+	return a.health.Health()
+}
@@ -0,0 +1,58 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSentenceTransformersAdapter_EmbedBatch(t *testing.T) {
+	var requests []sidecarEmbedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embed" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var req sidecarEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+
+		out := sidecarEmbedResponse{Model: req.Model}
+		for _, text := range req.Texts {
+			out.Embeddings = append(out.Embeddings, []float32{float32(len(text)), 0.5})
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer server.Close()
+
+	adapter := NewSentenceTransformersAdapter(server.URL, "BAAI/bge-m3", 2)
+	texts := []string{"a", "bb", "ccc"}
+	embs, err := adapter.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	for i, e := range embs {
+		if int(e[0]) != len(texts[i]) {
+			t.Errorf("embedding %d out of order: %v", i, e)
+		}
+	}
+	if len(requests) != 2 || len(requests[0].Texts) != 2 || requests[0].Model != "BAAI/bge-m3" {
+		t.Errorf("unexpected requests: %+v", requests)
+	}
+}
+
+func TestSentenceTransformersAdapter_SidecarError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]string{"error": "sentence-transformers not installed"})
+	}))
+	defer server.Close()
+
+	adapter := NewSentenceTransformersAdapter(server.URL, "", 0)
+	_, err := adapter.Embed(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("expected sidecar error, got %v", err)
+	}
+}
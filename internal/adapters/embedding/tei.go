@@ -0,0 +1,150 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// defaultTEIBatchSize matches TEI's default --max-client-batch-size.
+const defaultTEIBatchSize = 32
+
+// TEIAdapter implements ports.EmbeddingService against a Hugging Face
+// text-embeddings-inference server. Unlike Ollama, TEI embeds a whole
+// batch per request, which matters when ingesting large corpora on a GPU.
+type TEIAdapter struct {
+	baseURL      string
+	batchSize    int
+	client       *http.Client
+	health       *resilience.Tracker
+	backoff      resilience.Backoff
+	instructions Instructions // Prefixes for EmbedQueries and EmbedPassages
+}
+
+// NewTEIAdapter creates a TEI embedding adapter. The model is chosen when
+// the TEI server starts, so it is not configured here. batchSize <= 0
+// uses TEI's default client batch limit.
+func NewTEIAdapter(baseURL string, batchSize int) *TEIAdapter {
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	if batchSize <= 0 {
+		batchSize = defaultTEIBatchSize
+	}
+	return &TEIAdapter{
+		baseURL:   baseURL,
+		batchSize: batchSize,
+		client:    resilience.NewHTTPClient(60 * time.Second),
+		health:    resilience.NewTracker("tei-embedding"),
+		backoff:   resilience.DefaultBackoff,
+	}
+}
+
+// teiEmbedRequest is the TEI /embed request format.
+type teiEmbedRequest struct {
+	Inputs   []string `json:"inputs"`
+	Truncate bool     `json:"truncate"`
+}
+
+// Embed generates an embedding for a single text.
+func (a *TEIAdapter) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := a.embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts, sending at most
+// batchSize texts per request. Texts of a rejected request are resent
+// one at a time; those rejected again are reported in a
+// *ports.BatchEmbedError.
+func (a *TEIAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	failed := make(map[int]error)
+	for start := 0; start < len(texts); start += a.batchSize {
+		end := start + a.batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := embedOrIsolate(ctx, texts[start:end], a.embed)
+		var batchErr *ports.BatchEmbedError
+		if errors.As(err, &batchErr) {
+			for i, cause := range batchErr.Failed {
+				failed[start+i] = cause
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("embedding texts %d-%d: %w", start, end-1, err)
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	if len(failed) > 0 {
+		return embeddings, &ports.BatchEmbedError{Failed: failed}
+	}
+	return embeddings, nil
+}
+
+// embed sends one /embed request. Inputs longer than the model's
+// context are truncated by the server rather than rejected.
+func (a *TEIAdapter) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(teiEmbedRequest{Inputs: texts, Truncate: true})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := resilience.DoHTTP(ctx, a.client, a.health, a.backoff, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/embed", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("calling TEI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &statusError{backend: "TEI", status: resp.StatusCode, body: string(bytes.TrimSpace(msg))}
+	}
+
+	var embeddings [][]float32
+	if err := json.NewDecoder(resp.Body).Decode(&embeddings); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("TEI returned %d embeddings for %d inputs", len(embeddings), len(texts))
+	}
+	return embeddings, nil
+}
+
+// SetInstructions sets the prefixes EmbedQueries and EmbedPassages put
+// before texts, such as InstructionsFor(model) for the served model.
+func (a *TEIAdapter) SetInstructions(in Instructions) {
+	a.instructions = in
+}
+
+// EmbedQueries embeds search queries behind the query instruction.
+func (a *TEIAdapter) EmbedQueries(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.queries(texts))
+}
+
+// EmbedPassages embeds passages behind the passage instruction.
+func (a *TEIAdapter) EmbedPassages(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.EmbedBatch(ctx, a.instructions.passages(texts))
+}
+
+// Health reports the TEI connection state.
+func (a *TEIAdapter) Health() ports.BackendHealth {
+	return a.health.Health()
+}
@@ -0,0 +1,78 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newFakeTEI(t *testing.T, requests *[]int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embed" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var req teiEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		*requests = append(*requests, len(req.Inputs))
+
+		out := make([][]float32, len(req.Inputs))
+		for i, text := range req.Inputs {
+			out[i] = []float32{float32(len(text)), 0.5}
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTEIAdapter_Embed(t *testing.T) {
+	var requests []int
+	server := newFakeTEI(t, &requests)
+
+	adapter := NewTEIAdapter(server.URL, 0)
+	emb, err := adapter.Embed(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("embed failed: %v", err)
+	}
+	if len(emb) != 2 || emb[0] != 3 {
+		t.Errorf("unexpected embedding: %v", emb)
+	}
+}
+
+func TestTEIAdapter_EmbedBatchSplitsRequests(t *testing.T) {
+	var requests []int
+	server := newFakeTEI(t, &requests)
+
+	adapter := NewTEIAdapter(server.URL, 2)
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	embs, err := adapter.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if len(embs) != 5 {
+		t.Fatalf("expected 5 embeddings, got %d", len(embs))
+	}
+	for i, e := range embs {
+		if int(e[0]) != len(texts[i]) {
+			t.Errorf("embedding %d out of order: %v", i, e)
+		}
+	}
+	if len(requests) != 3 || requests[0] != 2 || requests[2] != 1 {
+		t.Errorf("expected batches of 2,2,1, got %v", requests)
+	}
+}
+
+func TestTEIAdapter_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte(`{"error":"batch size 64 > maximum allowed batch size 32"}`))
+	}))
+	defer server.Close()
+
+	adapter := NewTEIAdapter(server.URL, 0)
+	if _, err := adapter.Embed(context.Background(), "test"); err == nil {
+		t.Error("should error on 413")
+	}
+}
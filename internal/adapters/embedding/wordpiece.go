@@ -0,0 +1,132 @@
+package embedding
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// maxWordPieceChars is the longest word WordPiece splits; longer words
+// become [UNK], as in BERT's reference tokenizer.
+const maxWordPieceChars = 100
+
+// wordPieceTokenizer is BERT's tokenizer, as used by MiniLM and BGE
+// models: split on whitespace and punctuation, then greedily into the
+// longest vocabulary pieces, continuation pieces prefixed with "##".
+type wordPieceTokenizer struct {
+	vocab     map[string]int64
+	lowercase bool
+	cls, sep  int64
+	unk, pad  int64
+}
+
+// loadWordPiece reads a vocab.txt with one token per line, the line
+// number being its ID.
+func loadWordPiece(path string, lowercase bool) (*wordPieceTokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	vocab := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for id := int64(0); scanner.Scan(); id++ {
+		vocab[strings.TrimRight(scanner.Text(), "\r")] = id
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading vocabulary: %w", err)
+	}
+	return newWordPiece(vocab, lowercase)
+}
+
+func newWordPiece(vocab map[string]int64, lowercase bool) (*wordPieceTokenizer, error) {
+	t := &wordPieceTokenizer{vocab: vocab, lowercase: lowercase}
+	for token, id := range map[string]*int64{"[CLS]": &t.cls, "[SEP]": &t.sep, "[UNK]": &t.unk, "[PAD]": &t.pad} {
+		v, ok := vocab[token]
+		if !ok {
+			return nil, fmt.Errorf("vocabulary has no %s token", token)
+		}
+		*id = v
+	}
+	return t, nil
+}
+
+// encode returns the token IDs of text between [CLS] and [SEP], cut to
+// at most maxTokens in total.
+func (t *wordPieceTokenizer) encode(text string, maxTokens int) []int64 {
+	ids := []int64{t.cls}
+	for _, word := range t.words(text) {
+		for _, id := range t.pieces(word) {
+			if len(ids) == maxTokens-1 {
+				return append(ids, t.sep)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return append(ids, t.sep)
+}
+
+// words splits text on whitespace, and around punctuation and CJK
+// characters, which form words of their own.
+func (t *wordPieceTokenizer) words(text string) []string {
+	if t.lowercase {
+		text = strings.ToLower(text)
+	}
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			flush()
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.Is(unicode.Han, r):
+			flush()
+			words = append(words, string(r))
+		case unicode.Is(unicode.Mn, r) && t.lowercase:
+			// Uncased vocabularies are accent-free. Only combining marks
+			// are dropped; precomposed letters such as é are kept
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// pieces splits a word into the longest vocabulary pieces, or [UNK] if
+// some part of it has none.
+func (t *wordPieceTokenizer) pieces(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordPieceChars {
+		return []int64{t.unk}
+	}
+	var ids []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		found := false
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if id, ok := t.vocab[piece]; ok {
+				ids = append(ids, id)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return []int64{t.unk}
+		}
+		start = end
+	}
+	return ids
+}
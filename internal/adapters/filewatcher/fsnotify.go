@@ -4,6 +4,7 @@ package filewatcher
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 
 	"github.com/fsnotify/fsnotify"
@@ -39,6 +40,16 @@ func (w *FSNotifyWatcher) Watch(ctx context.Context, dir string) (<-chan ports.F
 		return nil, err
 	}
 
+	// Remember subdirectories: once removed they can no longer be stat'ed
+	dirs := make(map[string]bool)
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs[filepath.Join(dir, e.Name())] = true
+			}
+		}
+	}
+
 	events := make(chan ports.FileEvent, 100)
 
 	go func() {
@@ -51,13 +62,16 @@ func (w *FSNotifyWatcher) Watch(ctx context.Context, dir string) (<-chan ports.F
 				if !ok {
 					return
 				}
-				// Filter by extension
-				if !w.isWatchedExtension(event.Name) {
-					continue
-				}
-
 				var op ports.FileOperation
 				switch {
+				case event.Op&fsnotify.Create == fsnotify.Create && isDir(event.Name):
+					dirs[event.Name] = true
+					continue
+				case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && dirs[event.Name]:
+					delete(dirs, event.Name)
+					op = ports.DirectoryDeleted
+				case !w.isWatchedExtension(event.Name):
+					continue // Filter by extension
 				case event.Op&fsnotify.Create == fsnotify.Create:
 					op = ports.FileCreated
 				case event.Op&fsnotify.Write == fsnotify.Write:
@@ -90,6 +104,12 @@ func (w *FSNotifyWatcher) Stop() error {
 	return w.watcher.Close()
 }
 
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
 // isWatchedExtension checks if the file has a watched extension.
 func (w *FSNotifyWatcher) isWatchedExtension(path string) bool {
 	ext := filepath.Ext(path)
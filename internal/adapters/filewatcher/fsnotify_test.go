@@ -2,12 +2,14 @@ package filewatcher
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	"go.uber.org/goleak"
 )
 
 func TestFSNotifyWatcher_Creation(t *testing.T) {
@@ -58,6 +60,47 @@ func TestFSNotifyWatcher_WatchDirectory(t *testing.T) {
 	}
 }
 
+func TestFSNotifyWatcher_ReportsRemovedDirectory(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "watcher-test-*")
+	defer os.RemoveAll(dir)
+	existing := filepath.Join(dir, "existing")
+	os.Mkdir(existing, 0755)
+
+	watcher, _ := NewFSNotifyWatcher([]string{".txt"})
+	defer watcher.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	events, err := watcher.Watch(ctx, dir)
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+
+	// Directories present at start and created later are both tracked
+	created := filepath.Join(dir, "created")
+	os.Mkdir(created, 0755)
+	time.Sleep(100 * time.Millisecond)
+	os.Remove(existing)
+	os.Remove(created)
+
+	removed := map[string]bool{}
+	for len(removed) < 2 {
+		select {
+		case event := <-events:
+			if event.Operation != ports.DirectoryDeleted {
+				t.Fatalf("expected directory removal, got %+v", event)
+			}
+			removed[event.Path] = true
+		case <-ctx.Done():
+			t.Fatalf("timeout waiting for removals, got %v", removed)
+		}
+	}
+	if !removed[existing] || !removed[created] {
+		t.Errorf("unexpected removals: %v", removed)
+	}
+}
+
 func TestFSNotifyWatcher_FiltersByExtension(t *testing.T) {
 	dir, _ := os.MkdirTemp("", "watcher-test-*")
 	defer os.RemoveAll(dir)
@@ -88,3 +131,23 @@ func TestFSNotifyWatcher_Stop(t *testing.T) {
 		t.Errorf("stop failed: %v", err)
 	}
 }
+
+func TestFSNotifyWatcher_AbandonedConsumerDoesNotLeak(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	dir := t.TempDir()
+	watcher, _ := NewFSNotifyWatcher([]string{".txt"})
+	defer watcher.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := watcher.Watch(ctx, dir); err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+
+	// Overflow the event buffer without ever reading it
+	for i := 0; i < 150; i++ {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.txt", i)), []byte("hi"), 0644)
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+}
@@ -11,14 +11,21 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/0xcro3dile/localrag-go/internal/adapters/ollama"
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
 	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 )
 
 // OllamaLLMAdapter implements ports.LLMService using Ollama API.
 type OllamaLLMAdapter struct {
-	baseURL string
-	model   string
-	client  *http.Client
+	baseURL  string
+	model    string
+	defaults ports.GenerateOptions // Generation settings of every request, see SetOptions
+	keep     string                // How long Ollama keeps the model loaded, see SetKeepAlive
+	client   *http.Client
+	health   *resilience.Tracker
+	backoff  resilience.Backoff
 }
 
 // NewOllamaLLMAdapter creates a new Ollama LLM adapter.
@@ -32,90 +39,151 @@ func NewOllamaLLMAdapter(baseURL, model string) *OllamaLLMAdapter {
 	return &OllamaLLMAdapter{
 		baseURL: baseURL,
 		model:   model,
-		client: &http.Client{
-			Timeout: 300 * time.Second, // Longer timeout for streaming
-		},
+		client:  resilience.NewHTTPClient(300 * time.Second), // Longer timeout for streaming
+		health:  resilience.NewTracker("ollama-llm"),
+		backoff: resilience.DefaultBackoff,
 	}
 }
 
-// ollamaGenerateRequest is the Ollama generate API request.
-type ollamaGenerateRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+// SetOptions sets the generation settings sent with every request, such
+// as a low temperature and a fixed seed for factual answers. Settings a
+// request passes in ports.GenerateOptions take precedence; unset ones
+// leave the model's own defaults, from its Modelfile.
+func (a *OllamaLLMAdapter) SetOptions(opts ports.GenerateOptions) {
+	a.defaults = opts
 }
 
-// ollamaGenerateResponse is the Ollama generate API response.
-type ollamaGenerateResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
+// SetKeepAlive sets how long Ollama keeps the model in memory after a
+// request, instead of its default of five minutes (OLLAMA_KEEP_ALIVE),
+// so a question after a quiet spell does not wait for the model to load
+// again. A negative d keeps it loaded until Ollama stops; 0 unloads it
+// right after each answer, freeing the memory on small machines.
+func (a *OllamaLLMAdapter) SetKeepAlive(d time.Duration) {
+	a.keep = keepAlive(d)
 }
 
-// Generate produces a response given a prompt and context.
-func (a *OllamaLLMAdapter) Generate(ctx context.Context, prompt string, context []string) (string, error) {
-	reqBody := ollamaGenerateRequest{
-		Model:  a.model,
-		Prompt: prompt,
-		Stream: false,
+// keepAlive formats d for Ollama's keep_alive field.
+func keepAlive(d time.Duration) string {
+	if d < 0 {
+		return "-1"
 	}
+	return d.String()
+}
 
-	jsonData, err := json.Marshal(reqBody)
+// Preload loads the model into memory, as Ollama does for a chat
+// request without messages, so the first question does not wait for it.
+func (a *OllamaLLMAdapter) Preload(ctx context.Context) error {
+	jsonData, err := json.Marshal(ollamaChatRequest{Model: a.model, Messages: []ollamaChatMessage{}, KeepAlive: a.keep})
 	if err != nil {
-		return "", fmt.Errorf("marshaling request: %w", err)
+		return fmt.Errorf("marshaling request: %w", err)
 	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/api/generate", bytes.NewReader(jsonData))
+	resp, err := a.post(ctx, "/api/chat", jsonData)
 	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+		return fmt.Errorf("loading %s: %w", a.model, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("loading %s: Ollama returned status %d", a.model, resp.StatusCode)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return nil
+}
 
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("calling Ollama: %w", err)
+// ollamaChatRequest is the Ollama chat API request.
+type ollamaChatRequest struct {
+	Model     string              `json:"model"`
+	Messages  []ollamaChatMessage `json:"messages"`
+	Stream    bool                `json:"stream"`
+	Options   *ollamaOptions      `json:"options,omitempty"`
+	KeepAlive string              `json:"keep_alive,omitempty"`
+}
+
+type ollamaChatMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // Base64, for multimodal models
+}
+
+// ollamaOptions are per-request model parameters.
+type ollamaOptions struct {
+	Temperature   *float64 `json:"temperature,omitempty"`
+	Seed          *int64   `json:"seed,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	NumCtx        *int     `json:"num_ctx,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	Stop          []string `json:"stop,omitempty"`
+	NumPredict    *int     `json:"num_predict,omitempty"`
+}
+
+// ollamaOptionsFor returns the options field for opts, nil when none is set.
+func ollamaOptionsFor(opts ports.GenerateOptions) *ollamaOptions {
+	opts.Model = "" // Sent outside the options
+	if opts.IsZero() {
+		return nil
 	}
-	defer resp.Body.Close()
+	return &ollamaOptions{
+		Temperature:   opts.Temperature,
+		Seed:          opts.Seed,
+		TopP:          opts.TopP,
+		TopK:          opts.TopK,
+		NumCtx:        opts.NumCtx,
+		RepeatPenalty: opts.RepeatPenalty,
+		Stop:          opts.Stop,
+		NumPredict:    opts.NumPredict,
+	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Ollama returned status %d", resp.StatusCode)
+// ollamaChatResponse is the Ollama chat API response, or one line of a
+// streamed response.
+type ollamaChatResponse struct {
+	Message ollamaChatMessage `json:"message"`
+	Done    bool              `json:"done"`
+}
+
+// Generate produces a response given a prompt and context.
+func (a *OllamaLLMAdapter) Generate(ctx context.Context, prompt string, context []string) (string, error) {
+	return a.GenerateWith(ctx, prompt, context, ports.GenerateOptions{})
+}
+
+// GenerateWith is Generate with per-request generation settings.
+func (a *OllamaLLMAdapter) GenerateWith(ctx context.Context, prompt string, context []string, opts ports.GenerateOptions) (string, error) {
+	return a.Chat(ctx, userPrompt(prompt), opts)
+}
+
+// Chat answers the last of messages via Ollama's /api/chat, so the
+// system message and earlier turns reach the model's chat template as
+// such.
+func (a *OllamaLLMAdapter) Chat(ctx context.Context, messages []entities.ChatMessage, opts ports.GenerateOptions) (string, error) {
+	resp, err := a.chat(ctx, messages, false, opts)
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
 
-	var genResp ollamaGenerateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
+	var chatResp ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
 		return "", fmt.Errorf("decoding response: %w", err)
 	}
 
-	return genResp.Response, nil
+	return chatResp.Message.Content, nil
 }
 
 // GenerateStream produces a real streaming response via Ollama's streaming API.
 // Returns a channel of StreamTokens for real-time UI updates.
 func (a *OllamaLLMAdapter) GenerateStream(ctx context.Context, prompt string, context []string) (<-chan ports.StreamToken, error) {
-	reqBody := ollamaGenerateRequest{
-		Model:  a.model,
-		Prompt: prompt,
-		Stream: true, // Enable streaming
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
-	}
+	return a.GenerateStreamWith(ctx, prompt, context, ports.GenerateOptions{})
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/api/generate", bytes.NewReader(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
+// GenerateStreamWith is GenerateStream with per-request generation settings.
+func (a *OllamaLLMAdapter) GenerateStreamWith(ctx context.Context, prompt string, context []string, opts ports.GenerateOptions) (<-chan ports.StreamToken, error) {
+	return a.ChatStream(ctx, userPrompt(prompt), opts)
+}
 
-	resp, err := a.client.Do(req)
+// ChatStream is Chat streamed token by token.
+func (a *OllamaLLMAdapter) ChatStream(ctx context.Context, messages []entities.ChatMessage, opts ports.GenerateOptions) (<-chan ports.StreamToken, error) {
+	resp, err := a.chat(ctx, messages, true, opts)
 	if err != nil {
-		return nil, fmt.Errorf("calling Ollama: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("Ollama returned status %d", resp.StatusCode)
+		return nil, err
 	}
 
 	ch := make(chan ports.StreamToken, 100)
@@ -128,7 +196,7 @@ func (a *OllamaLLMAdapter) GenerateStream(ctx context.Context, prompt string, co
 		for scanner.Scan() {
 			select {
 			case <-ctx.Done():
-				ch <- ports.StreamToken{Done: true, Error: ctx.Err()}
+				abortStream(ctx, ch)
 				return
 			default:
 			}
@@ -138,26 +206,111 @@ func (a *OllamaLLMAdapter) GenerateStream(ctx context.Context, prompt string, co
 				continue
 			}
 
-			var chunk ollamaGenerateResponse
+			var chunk ollamaChatResponse
 			if err := json.Unmarshal(line, &chunk); err != nil {
 				continue // Skip malformed lines
 			}
 
-			ch <- ports.StreamToken{
-				Content: chunk.Response,
+			delivered := sendToken(ctx, ch, ports.StreamToken{
+				Content: chunk.Message.Content,
 				Done:    chunk.Done,
-			}
+			})
 
-			if chunk.Done {
+			if !delivered || chunk.Done {
 				return
 			}
 		}
 
 		if err := scanner.Err(); err != nil {
-			ch <- ports.StreamToken{Done: true, Error: err}
+			sendToken(ctx, ch, ports.StreamToken{Done: true, Error: err})
 		}
 	}()
 
 	return ch, nil
 }
 
+// chat posts a chat request, applying opts over the configuration. The
+// caller owns the body of a successful response.
+func (a *OllamaLLMAdapter) chat(ctx context.Context, messages []entities.ChatMessage, stream bool, opts ports.GenerateOptions) (*http.Response, error) {
+	opts = opts.Or(a.defaults)
+	req := ollamaChatRequest{
+		Model:     a.model,
+		Messages:  make([]ollamaChatMessage, len(messages)),
+		Stream:    stream,
+		Options:   ollamaOptionsFor(opts),
+		KeepAlive: a.keep,
+	}
+	for i, m := range messages {
+		req.Messages[i] = ollamaChatMessage{Role: m.Role, Content: m.Content}
+	}
+	if opts.Model != "" {
+		req.Model = opts.Model
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := a.post(ctx, "/api/chat", jsonData)
+	if err != nil {
+		return nil, fmt.Errorf("calling Ollama: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Ollama returned status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// userPrompt wraps a single-turn prompt as a chat.
+func userPrompt(prompt string) []entities.ChatMessage {
+	return []entities.ChatMessage{{Role: "user", Content: prompt}}
+}
+
+// post sends a JSON request to Ollama, retrying while it is unreachable
+// or still loading. The caller owns the response body.
+func (a *OllamaLLMAdapter) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	return resilience.DoHTTP(ctx, a.client, a.health, a.backoff, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+// Models lists the models installed on the Ollama server, sorted.
+func (a *OllamaLLMAdapter) Models(ctx context.Context) ([]string, error) {
+	models, err := ollama.Tags(ctx, a.client, a.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return ollama.Names(models), nil
+}
+
+// Model returns the configured model with its tag, as Ollama lists it.
+func (a *OllamaLLMAdapter) Model() string {
+	return ollama.Canonical(a.model)
+}
+
+// ModelInstalled reports whether the LLM model is installed on the
+// Ollama server.
+func (a *OllamaLLMAdapter) ModelInstalled(ctx context.Context) (bool, error) {
+	models, err := ollama.Tags(ctx, a.client, a.baseURL)
+	if err != nil {
+		return false, err
+	}
+	return ollama.Installed(models, a.model), nil
+}
+
+// PullModel downloads the LLM model to the Ollama server.
+func (a *OllamaLLMAdapter) PullModel(ctx context.Context, progress func(ports.PullProgress)) error {
+	return ollama.Pull(ctx, ollama.NoTimeout(a.client), a.baseURL, a.model, progress)
+}
+
+// Health reports the Ollama connection state.
+func (a *OllamaLLMAdapter) Health() ports.BackendHealth {
+	return a.health.Health()
+}
@@ -6,16 +6,21 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 )
 
 func TestOllamaLLM_Generate(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/api/generate" {
+		if r.URL.Path != "/api/chat" {
 			t.Errorf("unexpected path: %s", r.URL.Path)
 		}
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"response": "Hello there!",
-			"done":     true,
+			"message": map[string]string{"role": "assistant", "content": "Hello there!"},
+			"done":    true,
 		})
 	}))
 	defer server.Close()
@@ -34,9 +39,9 @@ func TestOllamaLLM_Generate(t *testing.T) {
 func TestOllamaLLM_GenerateStream(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Streaming response - newline delimited JSON
-		w.Write([]byte(`{"response":"Hello","done":false}` + "\n"))
-		w.Write([]byte(`{"response":" world","done":false}` + "\n"))
-		w.Write([]byte(`{"response":"!","done":true}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"Hello"},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":" world"},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"!"},"done":true}` + "\n"))
 	}))
 	defer server.Close()
 
@@ -83,3 +88,167 @@ func TestOllamaLLM_DefaultValues(t *testing.T) {
 		t.Error("should default to llama3.2")
 	}
 }
+
+func TestOllamaLLM_RetriesWhileLoading(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"content": "ready"}, "done": true})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaLLMAdapter(server.URL, "test")
+	adapter.backoff = resilience.Backoff{Initial: time.Millisecond, Max: time.Millisecond, Attempts: 3}
+
+	resp, err := adapter.Generate(context.Background(), "Hi", nil)
+	if err != nil || resp != "ready" {
+		t.Fatalf("expected retry to succeed, got %q, %v", resp, err)
+	}
+	if h := adapter.Health(); !h.Healthy || h.TotalFailures != 1 {
+		t.Errorf("unexpected health: %+v", h)
+	}
+}
+
+func TestOllamaLLM_GenerateWithOverrides(t *testing.T) {
+	var got ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"content": "ok"}, "done": true})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaLLMAdapter(server.URL, "test-model")
+	temp := 0.7
+	if _, err := adapter.GenerateWith(context.Background(), "Hi", nil, ports.GenerateOptions{Model: "other", Temperature: &temp}); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if got.Model != "other" {
+		t.Errorf("model = %q, want other", got.Model)
+	}
+	if got.Options == nil || got.Options.Temperature == nil || *got.Options.Temperature != 0.7 {
+		t.Errorf("temperature not sent: %+v", got.Options)
+	}
+
+	seed := int64(42)
+	if _, err := adapter.GenerateWith(context.Background(), "Hi", nil, ports.GenerateOptions{Seed: &seed}); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if got.Options == nil || got.Options.Seed == nil || *got.Options.Seed != 42 {
+		t.Errorf("seed not sent: %+v", got.Options)
+	}
+
+	got = ollamaChatRequest{}
+	if _, err := adapter.Generate(context.Background(), "Hi", nil); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if got.Model != "test-model" || got.Options != nil {
+		t.Errorf("defaults not restored: %+v", got)
+	}
+
+	// Configured options apply to every request, under the request's own
+	zero, topP, topK, numCtx := 0.0, 0.9, 40, 8192
+	adapter.SetOptions(ports.GenerateOptions{Temperature: &zero, TopP: &topP, TopK: &topK, NumCtx: &numCtx})
+	if _, err := adapter.GenerateWith(context.Background(), "Hi", nil, ports.GenerateOptions{Temperature: &temp}); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	o := got.Options
+	if o == nil || *o.Temperature != 0.7 || *o.TopP != 0.9 || *o.TopK != 40 || *o.NumCtx != 8192 || o.RepeatPenalty != nil {
+		t.Errorf("options not merged: %+v", o)
+	}
+
+	numPredict := 256
+	if _, err := adapter.GenerateWith(context.Background(), "Hi", nil, ports.GenerateOptions{Stop: []string{"\nQuestion:"}, NumPredict: &numPredict}); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if o = got.Options; len(o.Stop) != 1 || o.Stop[0] != "\nQuestion:" || o.NumPredict == nil || *o.NumPredict != 256 {
+		t.Errorf("stop and num_predict not sent: %+v", o)
+	}
+}
+
+func TestOllamaLLM_Chat(t *testing.T) {
+	var got ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"content": "Paris"}, "done": true})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaLLMAdapter(server.URL, "test")
+	messages := []entities.ChatMessage{
+		{Role: "system", Content: "Answer from the context."},
+		{Role: "user", Content: "Capital of France?"},
+		{Role: "assistant", Content: "Paris."},
+		{Role: "user", Content: "And its river?"},
+	}
+	if _, err := adapter.Chat(context.Background(), messages, ports.GenerateOptions{}); err != nil {
+		t.Fatalf("chat failed: %v", err)
+	}
+	if len(got.Messages) != 4 || got.Messages[0].Role != "system" || got.Messages[3].Content != "And its river?" {
+		t.Errorf("messages not passed through: %+v", got.Messages)
+	}
+}
+
+func TestOllamaLLM_KeepAliveAndPreload(t *testing.T) {
+	var requests []ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"content": "ok"}, "done": true})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaLLMAdapter(server.URL, "test")
+	if _, err := adapter.Generate(context.Background(), "Hi", nil); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	adapter.SetKeepAlive(-1)
+	if err := adapter.Preload(context.Background()); err != nil {
+		t.Fatalf("preload failed: %v", err)
+	}
+	adapter.SetKeepAlive(30 * time.Minute)
+	if _, err := adapter.Generate(context.Background(), "Hi", nil); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	if requests[0].KeepAlive != "" {
+		t.Errorf("keep_alive sent without being set: %q", requests[0].KeepAlive)
+	}
+	if requests[1].KeepAlive != "-1" || len(requests[1].Messages) != 0 || requests[1].Model != "test" {
+		t.Errorf("unexpected preload request: %+v", requests[1])
+	}
+	if requests[2].KeepAlive != "30m0s" {
+		t.Errorf("keep_alive = %q, want 30m0s", requests[2].KeepAlive)
+	}
+}
+
+func TestOllamaLLM_Models(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"models": []map[string]string{{"name": "qwen2.5:7b"}, {"name": "llama3.2:latest"}},
+		})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaLLMAdapter(server.URL, "llama3.2")
+	models, err := adapter.Models(context.Background())
+	if err != nil {
+		t.Fatalf("Models: %v", err)
+	}
+	if len(models) != 2 || models[0] != "llama3.2:latest" || adapter.Model() != "llama3.2:latest" {
+		t.Errorf("unexpected models %v, selected %q", models, adapter.Model())
+	}
+	if installed, err := adapter.ModelInstalled(context.Background()); err != nil || !installed {
+		t.Errorf("expected llama3.2 installed, got %v, %v", installed, err)
+	}
+}
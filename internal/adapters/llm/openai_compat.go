@@ -0,0 +1,279 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// OpenAICompatAdapter implements ports.LLMService for local runtimes that
+// serve the OpenAI chat completions API, such as llamafile and GPT4All.
+type OpenAICompatAdapter struct {
+	name     string // Runtime name for errors and health reports
+	baseURL  string
+	model    string
+	defaults ports.GenerateOptions // Generation settings of every request, see SetOptions
+	client   *http.Client
+	health   *resilience.Tracker
+	backoff  resilience.Backoff
+}
+
+// NewLlamafileAdapter creates an adapter for a llamafile server.
+// llamafile serves the single model it was started with, so model may
+// be empty.
+func NewLlamafileAdapter(baseURL, model string) *OpenAICompatAdapter {
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	if model == "" {
+		model = "LLaMA_CPP"
+	}
+	return newOpenAICompatAdapter("llamafile", baseURL, model)
+}
+
+// NewGPT4AllAdapter creates an adapter for the GPT4All desktop app's
+// local API server. model must name a model installed in GPT4All.
+func NewGPT4AllAdapter(baseURL, model string) *OpenAICompatAdapter {
+	if baseURL == "" {
+		baseURL = "http://localhost:4891"
+	}
+	return newOpenAICompatAdapter("GPT4All", baseURL, model)
+}
+
+func newOpenAICompatAdapter(name, baseURL, model string) *OpenAICompatAdapter {
+	return &OpenAICompatAdapter{
+		name:    name,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		client:  resilience.NewHTTPClient(300 * time.Second), // Longer timeout for streaming
+		health:  resilience.NewTracker(strings.ToLower(name) + "-llm"),
+		backoff: resilience.DefaultBackoff,
+	}
+}
+
+// SetOptions sets the generation settings sent with every request.
+// Settings a request passes in ports.GenerateOptions take precedence.
+// NumCtx is ignored: these servers fix the context window at startup.
+func (a *OpenAICompatAdapter) SetOptions(opts ports.GenerateOptions) {
+	a.defaults = opts
+}
+
+// chatRequest is the OpenAI chat completions request. top_k and
+// repeat_penalty are llama.cpp extensions that other servers ignore;
+// max_tokens carries num_predict.
+type chatRequest struct {
+	Model         string        `json:"model"`
+	Messages      []chatMessage `json:"messages"`
+	Stream        bool          `json:"stream"`
+	Temperature   *float64      `json:"temperature,omitempty"`
+	Seed          *int64        `json:"seed,omitempty"`
+	TopP          *float64      `json:"top_p,omitempty"`
+	TopK          *int          `json:"top_k,omitempty"`
+	RepeatPenalty *float64      `json:"repeat_penalty,omitempty"`
+	Stop          []string      `json:"stop,omitempty"`
+	MaxTokens     *int          `json:"max_tokens,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatResponse covers both full responses (message) and stream chunks (delta).
+type chatResponse struct {
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		Delta        chatMessage `json:"delta"`
+		FinishReason *string     `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// Generate produces a response given a prompt and context.
+func (a *OpenAICompatAdapter) Generate(ctx context.Context, prompt string, context []string) (string, error) {
+	return a.GenerateWith(ctx, prompt, context, ports.GenerateOptions{})
+}
+
+// GenerateWith is Generate with per-request generation settings.
+func (a *OpenAICompatAdapter) GenerateWith(ctx context.Context, prompt string, context []string, opts ports.GenerateOptions) (string, error) {
+	return a.Chat(ctx, userPrompt(prompt), opts)
+}
+
+// Chat answers the last of messages, passing the system message and
+// earlier turns as chat messages.
+func (a *OpenAICompatAdapter) Chat(ctx context.Context, messages []entities.ChatMessage, opts ports.GenerateOptions) (string, error) {
+	resp, err := a.chat(ctx, messages, false, opts)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var chatResp chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("%s returned no choices", a.name)
+	}
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+// GenerateStream produces a streaming response from server-sent events.
+func (a *OpenAICompatAdapter) GenerateStream(ctx context.Context, prompt string, context []string) (<-chan ports.StreamToken, error) {
+	return a.GenerateStreamWith(ctx, prompt, context, ports.GenerateOptions{})
+}
+
+// GenerateStreamWith is GenerateStream with per-request generation settings.
+func (a *OpenAICompatAdapter) GenerateStreamWith(ctx context.Context, prompt string, context []string, opts ports.GenerateOptions) (<-chan ports.StreamToken, error) {
+	return a.ChatStream(ctx, userPrompt(prompt), opts)
+}
+
+// ChatStream is Chat streamed from server-sent events.
+func (a *OpenAICompatAdapter) ChatStream(ctx context.Context, messages []entities.ChatMessage, opts ports.GenerateOptions) (<-chan ports.StreamToken, error) {
+	resp, err := a.chat(ctx, messages, true, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ports.StreamToken, 100)
+
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			select {
+			case <-ctx.Done():
+				abortStream(ctx, ch)
+				return
+			default:
+			}
+
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue // Blank separators and comments
+			}
+			if data == "[DONE]" {
+				sendToken(ctx, ch, ports.StreamToken{Done: true})
+				return
+			}
+
+			var chunk chatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil || len(chunk.Choices) == 0 {
+				continue // Skip malformed events
+			}
+			choice := chunk.Choices[0]
+			done := choice.FinishReason != nil && *choice.FinishReason != ""
+			if !sendToken(ctx, ch, ports.StreamToken{Content: choice.Delta.Content, Done: done}) || done {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			sendToken(ctx, ch, ports.StreamToken{Done: true, Error: err})
+			return
+		}
+		sendToken(ctx, ch, ports.StreamToken{Done: true})
+	}()
+
+	return ch, nil
+}
+
+// chat posts a chat completion, applying opts over the configuration.
+// The caller owns the body of a successful response.
+func (a *OpenAICompatAdapter) chat(ctx context.Context, messages []entities.ChatMessage, stream bool, opts ports.GenerateOptions) (*http.Response, error) {
+	opts = opts.Or(a.defaults)
+	model := a.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+	chatMessages := make([]chatMessage, len(messages))
+	for i, m := range messages {
+		chatMessages[i] = chatMessage{Role: m.Role, Content: m.Content}
+	}
+	jsonData, err := json.Marshal(chatRequest{
+		Model:         model,
+		Messages:      chatMessages,
+		Stream:        stream,
+		Temperature:   opts.Temperature,
+		Seed:          opts.Seed,
+		TopP:          opts.TopP,
+		TopK:          opts.TopK,
+		RepeatPenalty: opts.RepeatPenalty,
+		Stop:          opts.Stop,
+		MaxTokens:     opts.NumPredict,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := resilience.DoHTTP(ctx, a.client, a.health, a.backoff, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/v1/chat/completions", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("calling %s: %w", a.name, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned status %d: %s", a.name, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+// Models lists the models the server serves, sorted.
+func (a *OpenAICompatAdapter) Models(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.baseURL+"/v1/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling %s: %w", a.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d for /v1/models", a.name, resp.StatusCode)
+	}
+	var listing struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	models := make([]string, 0, len(listing.Data))
+	for _, m := range listing.Data {
+		models = append(models, m.ID)
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// Model returns the configured model.
+func (a *OpenAICompatAdapter) Model() string {
+	return a.model
+}
+
+// Health reports the backend connection state.
+func (a *OpenAICompatAdapter) Health() ports.BackendHealth {
+	return a.health.Health()
+}
@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAICompat_Generate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var req chatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "Llama 3 8B Instruct" || len(req.Messages) != 1 || req.Messages[0].Content != "Hi" {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Hello there!"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	adapter := NewGPT4AllAdapter(server.URL, "Llama 3 8B Instruct")
+	resp, err := adapter.Generate(context.Background(), "Hi", nil)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if resp != "Hello there!" {
+		t.Errorf("unexpected response: %s", resp)
+	}
+}
+
+func TestOpenAICompat_GenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"},\"finish_reason\":null}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\" world\"},\"finish_reason\":null}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	adapter := NewLlamafileAdapter(server.URL, "")
+	ch, err := adapter.GenerateStream(context.Background(), "test", nil)
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	var text string
+	var done bool
+	for token := range ch {
+		text += token.Content
+		done = token.Done
+	}
+	if text != "Hello world" || !done {
+		t.Errorf("unexpected stream: %q, done=%v", text, done)
+	}
+}
+
+func TestOpenAICompat_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model not found"}`))
+	}))
+	defer server.Close()
+
+	adapter := NewGPT4AllAdapter(server.URL, "missing")
+	if _, err := adapter.Generate(context.Background(), "Hi", nil); err == nil {
+		t.Error("should error on 404")
+	}
+}
+
+func TestOpenAICompat_Models(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]string{{"id": "qwen2.5"}, {"id": "llama3.2"}},
+		})
+	}))
+	defer server.Close()
+
+	adapter := NewGPT4AllAdapter(server.URL, "llama3.2")
+	models, err := adapter.Models(context.Background())
+	if err != nil {
+		t.Fatalf("Models: %v", err)
+	}
+	if len(models) != 2 || models[0] != "llama3.2" || models[1] != "qwen2.5" || adapter.Model() != "llama3.2" {
+		t.Errorf("unexpected models %v, selected %q", models, adapter.Model())
+	}
+}
@@ -0,0 +1,28 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// sendToken delivers tok unless ctx ends first, so a consumer that stops
+// reading cannot strand the goroutine producing the stream. It reports
+// whether tok was delivered.
+func sendToken(ctx context.Context, ch chan<- ports.StreamToken, tok ports.StreamToken) bool {
+	select {
+	case ch <- tok:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// abortStream reports ctx's error to the consumer if the buffer has
+// room, without waiting on a consumer that may be gone.
+func abortStream(ctx context.Context, ch chan<- ports.StreamToken) {
+	select {
+	case ch <- ports.StreamToken{Done: true, Error: ctx.Err()}:
+	default:
+	}
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	"go.uber.org/goleak"
+)
+
+// Streams longer than the token buffer used to strand the producer on
+// a send once the consumer cancelled and walked away.
+func TestGenerateStream_AbandonedConsumerDoesNotLeak(t *testing.T) {
+	const tokens = 500
+
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < tokens; i++ {
+			fmt.Fprintf(w, "{\"message\":{\"content\":\"t%d \"},\"done\":false}\n", i)
+		}
+		w.Write([]byte(`{"message":{"content":""},"done":true}` + "\n"))
+	}))
+	defer ollama.Close()
+
+	openai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < tokens; i++ {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":\"t%d \"},\"finish_reason\":null}]}\n\n", i)
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer openai.Close()
+
+	for name, adapter := range map[string]ports.LLMService{
+		"ollama":    NewOllamaLLMAdapter(ollama.URL, "llama3"),
+		"llamafile": NewLlamafileAdapter(openai.URL, ""),
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			ch, err := adapter.GenerateStream(ctx, "test", nil)
+			if err != nil {
+				t.Fatalf("stream failed: %v", err)
+			}
+			<-ch
+			cancel() // Walk away without draining
+		})
+	}
+}
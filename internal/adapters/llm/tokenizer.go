@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// tokenizeCacheSize bounds the counts a LlamaCppTokenizer remembers.
+// Prompts repeat the same instructions and often the same passages, so
+// most counts are found here instead of asking the server again.
+const tokenizeCacheSize = 4096
+
+// LlamaCppTokenizer implements ports.Tokenizer with the /tokenize
+// endpoint of llama.cpp's server, which llamafile serves too, so
+// context budgets count tokens with the loaded model's own vocabulary.
+type LlamaCppTokenizer struct {
+	baseURL string
+	client  *http.Client
+	health  *resilience.Tracker
+
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+// NewLlamaCppTokenizer creates a tokenizer for the model served at
+// baseURL.
+func NewLlamaCppTokenizer(baseURL string) *LlamaCppTokenizer {
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	return &LlamaCppTokenizer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  resilience.NewHTTPClient(10 * time.Second),
+		health:  resilience.NewTracker("llamacpp-tokenizer"),
+		cache:   make(map[string]int),
+	}
+}
+
+// tokenizeRequest is the /tokenize request format.
+type tokenizeRequest struct {
+	Content string `json:"content"`
+}
+
+// tokenizeResponse is the /tokenize response; only the count matters.
+type tokenizeResponse struct {
+	Tokens []json.RawMessage `json:"tokens"`
+}
+
+// CountTokens returns the number of tokens the server's model reads text
+// as, special tokens excluded. When the server cannot be reached it
+// estimates a token per three bytes, which overcounts most text so a
+// prompt still fits.
+func (t *LlamaCppTokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	t.mu.Lock()
+	n, ok := t.cache[text]
+	t.mu.Unlock()
+	if ok {
+		return n
+	}
+
+	n, err := t.tokenize(context.Background(), text)
+	if t.health.Record(err) != nil {
+		return (len(text) + 2) / 3
+	}
+	t.mu.Lock()
+	if len(t.cache) >= tokenizeCacheSize {
+		t.cache = make(map[string]int)
+	}
+	t.cache[text] = n
+	t.mu.Unlock()
+	return n
+}
+
+// tokenize sends one /tokenize request.
+func (t *LlamaCppTokenizer) tokenize(ctx context.Context, text string) (int, error) {
+	jsonData, err := json.Marshal(tokenizeRequest{Content: text})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/tokenize", bytes.NewReader(jsonData))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("tokenizing: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("llama.cpp returned status %d", resp.StatusCode)
+	}
+
+	var result tokenizeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decoding response: %w", err)
+	}
+	return len(result.Tokens), nil
+}
+
+// Health reports the tokenizer's connection state.
+func (t *LlamaCppTokenizer) Health() ports.BackendHealth {
+	return t.health.Health()
+}
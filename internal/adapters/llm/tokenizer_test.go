@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLlamaCppTokenizer_CountTokens(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/tokenize" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var req tokenizeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		tokens := make([]int, len(strings.Fields(req.Content)))
+		json.NewEncoder(w).Encode(map[string]interface{}{"tokens": tokens})
+	}))
+	defer server.Close()
+
+	tok := NewLlamaCppTokenizer(server.URL)
+	if n := tok.CountTokens("one two three"); n != 3 {
+		t.Errorf("expected 3 tokens, got %d", n)
+	}
+	if n := tok.CountTokens("one two three"); n != 3 || calls.Load() != 1 {
+		t.Errorf("repeated text should be counted from the cache, got %d after %d calls", n, calls.Load())
+	}
+	if n := tok.CountTokens(""); n != 0 || calls.Load() != 1 {
+		t.Errorf("empty text should count 0 without a request, got %d", n)
+	}
+}
+
+func TestLlamaCppTokenizer_FallsBackWhenDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tok := NewLlamaCppTokenizer(server.URL)
+	if n := tok.CountTokens("twelve bytes"); n != 4 {
+		t.Errorf("expected a token per three bytes, got %d", n)
+	}
+	if h := tok.Health(); h.Healthy {
+		t.Errorf("tokenizer should report the failure: %+v", h)
+	}
+}
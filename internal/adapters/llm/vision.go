@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// defaultDescribePrompt asks for a description that answers questions
+// about the image, including the text and numbers it shows.
+const defaultDescribePrompt = "Describe this image for a search index. " +
+	"If it is a diagram, chart or table, explain what it shows, its parts and how they relate, " +
+	"and transcribe its labels, values and any text. Answer in plain prose, without preamble."
+
+// OllamaVisionAdapter implements ports.ImageDescriber with a multimodal
+// Ollama model such as llava, so images and figures are ingested as
+// descriptions. It is an OllamaLLMAdapter for that model too.
+type OllamaVisionAdapter struct {
+	*OllamaLLMAdapter
+	prompt string
+}
+
+// NewOllamaVisionAdapter creates an image describer using model, llava
+// when empty.
+func NewOllamaVisionAdapter(baseURL, model string) *OllamaVisionAdapter {
+	if model == "" {
+		model = "llava"
+	}
+	return &OllamaVisionAdapter{OllamaLLMAdapter: NewOllamaLLMAdapter(baseURL, model), prompt: defaultDescribePrompt}
+}
+
+// SetPrompt sets the instruction sent with each image, such as one
+// asking for a description in another language.
+func (a *OllamaVisionAdapter) SetPrompt(prompt string) {
+	if prompt != "" {
+		a.prompt = prompt
+	}
+}
+
+// Describe asks the model to describe the image in data.
+func (a *OllamaVisionAdapter) Describe(ctx context.Context, data []byte, filename string) (string, error) {
+	jsonData, err := json.Marshal(ollamaChatRequest{
+		Model: a.model,
+		Messages: []ollamaChatMessage{{
+			Role:    "user",
+			Content: a.prompt,
+			Images:  []string{base64.StdEncoding.EncodeToString(data)},
+		}},
+		Options:   ollamaOptionsFor(a.defaults),
+		KeepAlive: a.keep,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := a.post(ctx, "/api/chat", jsonData)
+	if err != nil {
+		return "", fmt.Errorf("describing %s: %w", filename, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("describing %s: Ollama returned status %d", filename, resp.StatusCode)
+	}
+	var chatResp ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	return strings.TrimSpace(chatResp.Message.Content), nil
+}
+
+// Tool names the model for provenance.
+func (a *OllamaVisionAdapter) Tool() string {
+	return a.Model()
+}
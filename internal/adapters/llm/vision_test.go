@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOllamaVision_Describe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "llava" || len(req.Messages) != 1 || len(req.Messages[0].Images) != 1 || req.Messages[0].Images[0] != "cG5n" {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]string{"role": "assistant", "content": " A bar chart of sales by quarter.\n"},
+			"done":    true,
+		})
+	}))
+	defer server.Close()
+
+	adapter := NewOllamaVisionAdapter(server.URL, "")
+	description, err := adapter.Describe(context.Background(), []byte("png"), "chart.png")
+	if err != nil {
+		t.Fatalf("describe failed: %v", err)
+	}
+	if description != "A bar chart of sales by quarter." {
+		t.Errorf("unexpected description: %q", description)
+	}
+	if adapter.Tool() != "llava:latest" {
+		t.Errorf("unexpected tool: %s", adapter.Tool())
+	}
+}
@@ -0,0 +1,98 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// minKanaShare is the share of the non-ASCII characters of text decoded
+// as Shift-JIS that must be kana for it to be taken as Japanese. Kana
+// fill much of any Japanese prose, while Latin text misread as
+// Shift-JIS turns into kanji and half-width katakana instead.
+const minKanaShare = 0.2
+
+// lookupEncoding returns the encoding with a WHATWG name or label, such
+// as "windows-1251" or "shift_jis".
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown text encoding %q", name)
+	}
+	return enc, nil
+}
+
+// decodeText returns a text file's content as UTF-8. A byte order mark
+// decides between UTF-8 and UTF-16; other valid UTF-8 is taken as is.
+// Otherwise the file is decoded with legacy, when set, or else as
+// Shift-JIS when that reads as Japanese, or as Windows-1252, which
+// covers Latin-1.
+func decodeText(data []byte, legacy encoding.Encoding) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return string(bytes.ToValidUTF8(data[3:], []byte("�")))
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return decodeWith(xunicode.UTF16(xunicode.LittleEndian, xunicode.ExpectBOM), data)
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return decodeWith(xunicode.UTF16(xunicode.BigEndian, xunicode.ExpectBOM), data)
+	case utf8.Valid(data):
+		return string(data)
+	case legacy != nil:
+		return decodeWith(legacy, data)
+	}
+	if text, ok := shiftJIS(data); ok {
+		return text
+	}
+	return decodeWith(charmap.Windows1252, data)
+}
+
+// shiftJIS decodes data as Shift-JIS, reporting whether it decoded
+// cleanly and reads as Japanese.
+func shiftJIS(data []byte) (string, bool) {
+	text, err := japanese.ShiftJIS.NewDecoder().String(string(data))
+	if err != nil {
+		return "", false
+	}
+	kana, other := 0, 0
+	for _, r := range text {
+		switch {
+		case r == utf8.RuneError:
+			return "", false
+		case r < utf8.RuneSelf:
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana) && r < 0xff00: // Full-width kana
+			kana++
+		default:
+			other++
+		}
+	}
+	return text, kana > 0 && float64(kana) >= minKanaShare*float64(kana+other)
+}
+
+// decodeCharset converts text in charset, such as one named by a
+// Content-Type, to UTF-8. Unknown charsets keep their valid UTF-8.
+func decodeCharset(charset string, data []byte) string {
+	if strings.EqualFold(charset, "latin-1") {
+		charset = "latin1"
+	}
+	if enc, err := htmlindex.Get(charset); err == nil && enc != xunicode.UTF8 {
+		return decodeWith(enc, data)
+	}
+	return string(bytes.ToValidUTF8(data, []byte("�")))
+}
+
+// decodeWith decodes data with enc, replacing bytes it cannot map.
+func decodeWith(enc encoding.Encoding, data []byte) string {
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(bytes.ToValidUTF8(data, []byte("�")))
+	}
+	return string(text)
+}
@@ -0,0 +1,57 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestTextLoader_LegacyEncodings(t *testing.T) {
+	sjis, err := japanese.ShiftJIS.NewEncoder().String("こんにちは、世界。テストです。")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"windows-1252": {[]byte("\x93Caf\xe9\x94 \x96 5\x80"), "“Café” – 5€"},
+		"latin-1":      {[]byte("Stra\xdfe, \xe0 la cr\xe8me"), "Straße, à la crème"},
+		"shift-jis":    {[]byte(sjis), "こんにちは、世界。テストです。"},
+		"utf-16":       {[]byte{0xff, 0xfe, 'O', 0, 'K', 0, 0xe9, 0}, "OKé"},
+		"utf-8 bom":    {[]byte("\xef\xbb\xbfna\xc3\xafve"), "naïve"},
+	} {
+		path := filepath.Join(t.TempDir(), "legacy.txt")
+		os.WriteFile(path, tc.data, 0644)
+		doc, err := NewTextLoader().Load(context.Background(), path)
+		if err != nil {
+			t.Fatalf("%s: load failed: %v", name, err)
+		}
+		if doc.Content != tc.want {
+			t.Errorf("%s: got %q, want %q", name, doc.Content, tc.want)
+		}
+	}
+}
+
+func TestMultiLoader_SetTextEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "letter.txt")
+	os.WriteFile(path, []byte("\xcf\xf0\xe8\xe2\xe5\xf2"), 0644) // "Привет" in Windows-1251
+
+	m := NewMultiLoader()
+	if err := m.SetTextEncoding("windows-1251"); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := m.Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Content != "Привет" {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+	if err := m.SetTextEncoding("klingon"); err == nil {
+		t.Error("expected an error for an unknown encoding")
+	}
+}
@@ -0,0 +1,192 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// codeSectionSize is the size in bytes up to which adjacent top-level
+// blocks of a source file, such as small functions, share a section.
+// Larger blocks are split at the declarations they contain, such as
+// the methods of a class.
+const codeSectionSize = 1500
+
+// codeLanguages names the language of each source file extension.
+var codeLanguages = map[string]string{
+	".go": "Go", ".py": "Python", ".js": "JavaScript", ".mjs": "JavaScript",
+	".cjs": "JavaScript", ".jsx": "JavaScript", ".ts": "TypeScript",
+	".tsx": "TypeScript", ".java": "Java", ".kt": "Kotlin", ".rs": "Rust",
+	".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++", ".hpp": "C++",
+	".cs": "C#", ".rb": "Ruby", ".php": "PHP", ".swift": "Swift",
+	".scala": "Scala", ".sh": "Shell",
+}
+
+// CodeLoader loads source files for "chat with my repo". Files are
+// split at top-level declarations, and each section is labeled with the
+// file's path, its line range and the language, such as
+// "File: internal/server.go, lines 40-85 (Go)", so every chunk says
+// where it comes from.
+type CodeLoader struct {
+	root string
+}
+
+// NewCodeLoader creates a source code loader that labels files by their
+// path relative to root, such as the repository's directory, or by the
+// path as given when root is empty.
+func NewCodeLoader(root string) *CodeLoader {
+	return &CodeLoader{root: root}
+}
+
+// Load reads a source file as sections of whole declarations.
+func (l *CodeLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := path
+	if l.root != "" {
+		if rel, err := filepath.Rel(l.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+	}
+	name = filepath.ToSlash(name)
+	lang := codeLanguages[strings.ToLower(filepath.Ext(path))]
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	var sections []entities.Section
+	for _, b := range mergeBlocks(lines, codeBlocks(lines, 0, len(lines))) {
+		first, last := b.start, b.end-1
+		for first < last && strings.TrimSpace(lines[first]) == "" {
+			first++
+		}
+		for last > first && strings.TrimSpace(lines[last]) == "" {
+			last--
+		}
+		content := strings.Join(lines[first:last+1], "\n")
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		label := fmt.Sprintf("File: %s, lines %d-%d", name, first+1, last+1)
+		if lang != "" {
+			label += " (" + lang + ")"
+		}
+		sections = append(sections, entities.Section{Label: label, Content: content})
+	}
+
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      filepath.Base(path),
+		Path:      path,
+		Content:   entities.JoinSections(sections),
+		Sections:  sections,
+		CreatedAt: info.ModTime(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns file extensions this loader handles.
+func (l *CodeLoader) SupportedExtensions() []string {
+	exts := make([]string, 0, len(codeLanguages))
+	for ext := range codeLanguages {
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// lineBlock is the range of lines [start, end).
+type lineBlock struct {
+	start, end int
+}
+
+// size is the number of bytes of a block's lines.
+func (b lineBlock) size(lines []string) int {
+	n := 0
+	for _, line := range lines[b.start:b.end] {
+		n += len(line) + 1
+	}
+	return n
+}
+
+// codeBlocks splits lines [start, end) into declarations. A declaration
+// starts at a line after a blank one, indented as little as any line
+// within the block, so comments and decorators above it stay with it.
+// Blocks larger than codeSectionSize are split again one level deeper,
+// as with the methods of a class, as long as they have such lines.
+func codeBlocks(lines []string, start, end int) []lineBlock {
+	whole := lineBlock{start, end}
+	if whole.size(lines) <= codeSectionSize {
+		return []lineBlock{whole}
+	}
+
+	// The least indentation within the block, past its first line and
+	// ignoring closing brackets, is its level of declarations
+	level := -1
+	for i := start + 1; i < end; i++ {
+		if indent, ok := declarationIndent(lines[i]); ok && (level < 0 || indent < level) {
+			level = indent
+		}
+	}
+	var starts []int
+	for i := start + 1; i < end; i++ {
+		if indent, ok := declarationIndent(lines[i]); ok && indent == level && strings.TrimSpace(lines[i-1]) == "" {
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) == 0 {
+		return []lineBlock{whole}
+	}
+
+	var blocks []lineBlock
+	from := start
+	for _, s := range append(starts, end) {
+		blocks = append(blocks, codeBlocks(lines, from, s)...)
+		from = s
+	}
+	return blocks
+}
+
+// declarationIndent returns the indentation of a line that could start
+// a declaration: not blank, nor closing a bracket. Tabs count as four
+// spaces.
+func declarationIndent(line string) (int, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if trimmed == "" || strings.ContainsRune("})]", rune(trimmed[0])) {
+		return 0, false
+	}
+	indent := 0
+	for _, r := range line[:len(line)-len(trimmed)] {
+		if r == '\t' {
+			indent += 4
+		} else {
+			indent++
+		}
+	}
+	return indent, true
+}
+
+// mergeBlocks joins adjacent blocks while they fit in codeSectionSize
+// together, so small declarations share a section.
+func mergeBlocks(lines []string, blocks []lineBlock) []lineBlock {
+	var out []lineBlock
+	for _, b := range blocks {
+		if n := len(out); n > 0 {
+			if merged := (lineBlock{out[n-1].start, b.end}); merged.size(lines) <= codeSectionSize {
+				out[n-1] = merged
+				continue
+			}
+		}
+		out = append(out, b)
+	}
+	return out
+}
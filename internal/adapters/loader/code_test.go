@@ -0,0 +1,52 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeLoader_Load(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "pkg", "server.go")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	body := strings.Repeat("\t// padding to make the function large enough to split\n", 30)
+	src := "package pkg\n\nimport \"fmt\"\n\n// Serve serves.\nfunc Serve() {\n" + body + "}\n\nfunc helper() {\n\tfmt.Println()\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := NewCodeLoader(root).Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	var labels []string
+	for _, s := range doc.Sections {
+		labels = append(labels, s.Label)
+	}
+	want := []string{
+		"File: pkg/server.go, lines 1-3 (Go)",
+		"File: pkg/server.go, lines 5-37 (Go)",
+		"File: pkg/server.go, lines 39-41 (Go)",
+	}
+	if strings.Join(labels, "|") != strings.Join(want, "|") {
+		t.Fatalf("labels = %q, want %q", labels, want)
+	}
+	if !strings.HasPrefix(doc.Sections[1].Content, "// Serve serves.\nfunc Serve() {") || !strings.HasSuffix(doc.Sections[1].Content, "\n}") {
+		t.Errorf("expected the doc comment with its function: %q", doc.Sections[1].Content)
+	}
+}
+
+func TestCodeBlocks_SplitsClassMethods(t *testing.T) {
+	method := func(name string) string {
+		return "    def " + name + "(self):\n" + strings.Repeat("        x = 1  # a long enough line of method body\n", 25)
+	}
+	src := "class Big:\n" + method("first") + "\n" + method("second")
+	lines := strings.Split(src, "\n")
+	blocks := codeBlocks(lines, 0, len(lines))
+	if len(blocks) != 2 || blocks[1].start != 28 || strings.TrimSpace(lines[blocks[1].start]) != "def second(self):" {
+		t.Errorf("expected a block per method, got %+v", blocks)
+	}
+}
@@ -0,0 +1,387 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+const (
+	// defaultCrawlPages bounds a crawl whose limits name no page count.
+	defaultCrawlPages = 1000
+
+	// defaultCrawlDelay is the pause between two requests to a host
+	// when neither the limits nor the host's robots.txt set one.
+	defaultCrawlDelay = time.Second
+
+	// maxSitemaps bounds the sitemaps read through sitemap indexes.
+	maxSitemaps = 100
+)
+
+// crawlSkippedExts are extensions of links that are never pages, so a
+// crawl does not fetch them.
+var crawlSkippedExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true,
+	".webp": true, ".ico": true, ".css": true, ".js": true, ".json": true,
+	".zip": true, ".gz": true, ".tgz": true, ".tar": true, ".mp3": true,
+	".mp4": true, ".webm": true, ".woff": true, ".woff2": true, ".ttf": true,
+	".exe": true, ".dmg": true,
+}
+
+// Crawler fetches the pages of a website for ingestion, one document
+// per page, read as URLLoader reads them. It follows links breadth
+// first, or starts from the pages a sitemap lists, fetching one page at
+// a time, pausing between requests to a host and keeping out of the
+// paths its robots.txt disallows.
+type Crawler struct {
+	pages *URLLoader
+}
+
+// NewCrawler creates a crawler that fetches pages with client, or with
+// a 30-second timeout when client is nil.
+func NewCrawler(client *http.Client) *Crawler {
+	return &Crawler{pages: NewURLLoader(client)}
+}
+
+// Crawl fetches the pages reachable from start, or listed by it when
+// it is a sitemap, such as https://example.com/sitemap.xml. Pages that
+// fail to load are kept with their error, so ingestion reports them;
+// links to content that is not text, such as images, are dropped.
+func (c *Crawler) Crawl(ctx context.Context, start string, limits ports.CrawlLimits) (ports.DocumentLoader, []string, error) {
+	u, err := url.Parse(start)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, nil, fmt.Errorf("not an http(s) URL: %q", start)
+	}
+	u.Fragment, u.RawFragment = "", ""
+
+	cr := &crawl{
+		pages:   c.pages,
+		limits:  limits,
+		robots:  make(map[string]*robotsRules),
+		fetched: make(map[string]time.Time),
+		seen:    make(map[string]bool),
+		found:   make(crawledPages),
+	}
+	if cr.limits.MaxPages <= 0 {
+		cr.limits.MaxPages = defaultCrawlPages
+	}
+	if cr.limits.Delay <= 0 {
+		cr.limits.Delay = defaultCrawlDelay
+	}
+	if len(cr.limits.Domains) == 0 {
+		cr.limits.Domains = []string{u.Hostname()}
+	}
+
+	seeds := []*url.URL{u}
+	sitemap := strings.HasSuffix(strings.ToLower(u.Path), ".xml")
+	if sitemap {
+		if seeds, err = cr.sitemap(ctx, u); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := cr.run(ctx, seeds); err != nil {
+		return nil, nil, err
+	}
+	if len(cr.order) == 0 {
+		return nil, nil, fmt.Errorf("no pages to crawl at %s", u)
+	}
+	if !sitemap && len(cr.order) == 1 && cr.found[cr.order[0]].err != nil {
+		// Nothing to crawl past a start page that fails
+		return nil, nil, cr.found[cr.order[0]].err
+	}
+	return cr.found, cr.order, nil
+}
+
+// crawl is the state of one run of a Crawler.
+type crawl struct {
+	pages   *URLLoader
+	limits  ports.CrawlLimits
+	robots  map[string]*robotsRules // By host
+	fetched map[string]time.Time    // Time of the last request, by host
+	seen    map[string]bool         // URLs queued or fetched
+	found   crawledPages
+	order   []string // URLs of found, in the order they were fetched
+}
+
+// crawlTarget is a URL to fetch and its distance from the start pages.
+type crawlTarget struct {
+	u     *url.URL
+	depth int
+}
+
+// run fetches the seeds and the pages they link to, breadth first.
+func (cr *crawl) run(ctx context.Context, seeds []*url.URL) error {
+	var queue []crawlTarget
+	for _, u := range seeds {
+		if cr.visit(u) {
+			queue = append(queue, crawlTarget{u: u})
+		}
+	}
+	for len(queue) > 0 && len(cr.order) < cr.limits.MaxPages {
+		t := queue[0]
+		queue = queue[1:]
+		if _, ok := cr.found[t.u.String()]; ok {
+			continue // Reached by a redirect already
+		}
+		if !cr.robotsFor(ctx, t.u).allowed(t.u.RequestURI()) {
+			continue
+		}
+		if err := cr.wait(ctx, t.u.Host); err != nil {
+			return err
+		}
+
+		doc, links, err := cr.pages.fetch(ctx, t.u)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if errors.Is(err, errUnsupportedContent) {
+			continue
+		}
+		key := t.u.String()
+		if err == nil {
+			if key = doc.Path; cr.found[key].doc != nil {
+				continue // A redirect led to a page fetched already
+			}
+			cr.seen[key] = true
+		}
+		cr.found[key] = crawledPage{doc: doc, err: err}
+		cr.order = append(cr.order, key)
+
+		if t.depth < cr.limits.MaxDepth {
+			for _, link := range links {
+				if cr.visit(link) {
+					queue = append(queue, crawlTarget{u: link, depth: t.depth + 1})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// visit reports whether u is a page within the crawl's domains not yet
+// seen, and marks it seen.
+func (cr *crawl) visit(u *url.URL) bool {
+	if (u.Scheme != "http" && u.Scheme != "https") || crawlSkippedExts[strings.ToLower(path.Ext(u.Path))] {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	inDomain := false
+	for _, d := range cr.limits.Domains {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			inDomain = true
+			break
+		}
+	}
+	if !inDomain || cr.seen[u.String()] {
+		return false
+	}
+	cr.seen[u.String()] = true
+	return true
+}
+
+// wait pauses until the crawl delay, or the host's longer Crawl-delay,
+// has passed since the last request to host.
+func (cr *crawl) wait(ctx context.Context, host string) error {
+	delay := cr.limits.Delay
+	if r := cr.robots[host]; r != nil && r.delay > delay {
+		delay = r.delay
+	}
+	if last, ok := cr.fetched[host]; ok {
+		select {
+		case <-time.After(time.Until(last.Add(delay))):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	cr.fetched[host] = time.Now()
+	return nil
+}
+
+// robotsFor returns the rules of u's host, fetching its robots.txt the
+// first time. A host without one, or failing to serve it, allows all.
+func (cr *crawl) robotsFor(ctx context.Context, u *url.URL) *robotsRules {
+	if r, ok := cr.robots[u.Host]; ok {
+		return r
+	}
+	rules := &robotsRules{}
+	if err := cr.wait(ctx, u.Host); err == nil {
+		robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+		if _, data, err := cr.pages.get(ctx, robotsURL, "text/plain"); err == nil {
+			rules = parseRobots(data)
+		}
+	}
+	cr.robots[u.Host] = rules
+	return rules
+}
+
+// sitemap returns the page URLs a sitemap lists, reading the sitemaps
+// a sitemap index lists in turn.
+func (cr *crawl) sitemap(ctx context.Context, u *url.URL) ([]*url.URL, error) {
+	var pages []*url.URL
+	queue := []*url.URL{u}
+	for read := 0; len(queue) > 0 && read < maxSitemaps; read++ {
+		sm := queue[0]
+		queue = queue[1:]
+		if err := cr.wait(ctx, sm.Host); err != nil {
+			return nil, err
+		}
+		_, data, err := cr.pages.get(ctx, sm, "application/xml,text/xml")
+		if err != nil {
+			if sm == u {
+				return nil, err
+			}
+			continue // A missing sitemap of an index leaves the rest
+		}
+		var doc struct {
+			Pages    []string `xml:"url>loc"`
+			Sitemaps []string `xml:"sitemap>loc"`
+		}
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing sitemap %s: %w", sm, err)
+		}
+		for _, loc := range doc.Sitemaps {
+			if next, err := sm.Parse(strings.TrimSpace(loc)); err == nil {
+				queue = append(queue, next)
+			}
+		}
+		for _, loc := range doc.Pages {
+			if page, err := sm.Parse(strings.TrimSpace(loc)); err == nil {
+				page.Fragment, page.RawFragment = "", ""
+				pages = append(pages, page)
+			}
+		}
+	}
+	return pages, nil
+}
+
+// crawledPage is a fetched page, or the error fetching it.
+type crawledPage struct {
+	doc *entities.Document
+	err error
+}
+
+// crawledPages serves the pages of a crawl by URL.
+type crawledPages map[string]crawledPage
+
+func (p crawledPages) Load(ctx context.Context, path string) (*entities.Document, error) {
+	page, ok := p[path]
+	if !ok {
+		return nil, fmt.Errorf("%s was not crawled", path)
+	}
+	return page.doc, page.err
+}
+
+// SupportedExtensions returns no extensions, as pages are URLs.
+func (p crawledPages) SupportedExtensions() []string {
+	return nil
+}
+
+// robotsRules are the rules a site's robots.txt sets for crawlers: the
+// group naming LocalRAG, or else the one for all crawlers.
+type robotsRules struct {
+	rules []robotsRule
+	delay time.Duration
+}
+
+// robotsRule allows or disallows the paths matching a pattern.
+type robotsRule struct {
+	allow   bool
+	length  int // Of the pattern; the longest matching pattern wins
+	pattern *regexp.Regexp
+}
+
+// allowed reports whether a crawler may fetch the escaped path, with
+// its query.
+func (r *robotsRules) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	allow, length := true, -1
+	for _, rule := range r.rules {
+		// Allow wins ties, as in RFC 9309
+		if rule.pattern.MatchString(path) && (rule.length > length || (rule.length == length && rule.allow)) {
+			allow, length = rule.allow, rule.length
+		}
+	}
+	return allow
+}
+
+// parseRobots reads a robots.txt file. Patterns match path prefixes,
+// with "*" matching any characters and a final "$" the end of the path.
+func parseRobots(data []byte) *robotsRules {
+	groups := map[string]*robotsRules{}
+	var agents []string
+	inRules := false
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if inRules {
+				// A user-agent line after rules starts a new group
+				agents, inRules = nil, false
+			}
+			agent := strings.ToLower(value)
+			if groups[agent] == nil {
+				groups[agent] = &robotsRules{}
+			}
+			agents = append(agents, agent)
+			continue
+		}
+		inRules = true
+		for _, agent := range agents {
+			g := groups[agent]
+			switch key {
+			case "allow", "disallow":
+				if value == "" {
+					continue // An empty Disallow allows all
+				}
+				g.rules = append(g.rules, robotsRule{allow: key == "allow", length: len(value), pattern: robotsPattern(value)})
+			case "crawl-delay":
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					g.delay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	for _, agent := range []string{"localrag-go", "localrag", "*"} {
+		if g := groups[agent]; g != nil {
+			return g
+		}
+	}
+	return &robotsRules{}
+}
+
+// robotsPattern compiles a robots.txt path pattern.
+func robotsPattern(p string) *regexp.Regexp {
+	end := strings.HasSuffix(p, "$")
+	p = strings.TrimSuffix(p, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*")
+	if end {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
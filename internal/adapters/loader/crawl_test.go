@@ -0,0 +1,117 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+func crawlSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	pages := map[string]string{
+		"/":        `<main><p>Home</p><a href="/a#top">A</a> <a href="private/x">Private</a> <a href="/logo.png">Logo</a> <a href="https://example.com/">Elsewhere</a> <a href="/missing">Gone</a></main>`,
+		"/a":       `<title>Page A</title><main><p>About A.</p><a href="/b">B</a> <a href="/old">Home again</a></main>`,
+		"/b":       `<main><p>About B.</p></main>`,
+		"/private": `<main><p>Secret</p></main>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case "/sitemap.xml":
+			w.Write([]byte(`<?xml version="1.0"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>/b</loc></url><url><loc>/a</loc></url></urlset>`))
+		case "/old":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			page, ok := pages[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCrawler_Links(t *testing.T) {
+	srv := crawlSite(t)
+	pages, urls, err := NewCrawler(nil).Crawl(context.Background(), srv.URL+"/", ports.CrawlLimits{MaxDepth: 1, Delay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	// /b is two links away, /private disallowed, and the rest off site or not pages
+	if want := []string{srv.URL + "/", srv.URL + "/a", srv.URL + "/missing"}; !reflect.DeepEqual(urls, want) {
+		t.Fatalf("expected %v, got %v", want, urls)
+	}
+	doc, err := pages.Load(context.Background(), srv.URL+"/a")
+	if err != nil || doc.Name != "Page A" || doc.Path != srv.URL+"/a" {
+		t.Errorf("unexpected page: %+v, %v", doc, err)
+	}
+	if _, err := pages.Load(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("expected the missing page's error")
+	}
+
+	// Deeper, the redirect back home is not fetched twice
+	_, urls, _ = NewCrawler(nil).Crawl(context.Background(), srv.URL+"/", ports.CrawlLimits{MaxDepth: 2, Delay: time.Millisecond})
+	if want := []string{srv.URL + "/", srv.URL + "/a", srv.URL + "/missing", srv.URL + "/b"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("expected %v, got %v", want, urls)
+	}
+
+	_, urls, _ = NewCrawler(nil).Crawl(context.Background(), srv.URL+"/", ports.CrawlLimits{MaxDepth: 2, MaxPages: 2, Delay: time.Millisecond})
+	if len(urls) != 2 {
+		t.Errorf("expected 2 pages at most, got %v", urls)
+	}
+}
+
+func TestCrawler_Sitemap(t *testing.T) {
+	srv := crawlSite(t)
+	_, urls, err := NewCrawler(nil).Crawl(context.Background(), srv.URL+"/sitemap.xml", ports.CrawlLimits{Delay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	if want := []string{srv.URL + "/b", srv.URL + "/a"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("expected the sitemap's pages, got %v", urls)
+	}
+}
+
+func TestCrawler_StartFails(t *testing.T) {
+	srv := crawlSite(t)
+	if _, _, err := NewCrawler(nil).Crawl(context.Background(), srv.URL+"/missing", ports.CrawlLimits{Delay: time.Millisecond}); err == nil {
+		t.Error("expected the start page's error")
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	rules := parseRobots([]byte(`# Rules
+User-agent: Googlebot
+Disallow: /
+
+User-agent: *
+Disallow: /docs/drafts
+Allow: /docs/drafts/public
+Disallow: /*.pdf$
+Crawl-delay: 2.5
+`))
+	for path, want := range map[string]bool{
+		"/docs/intro":           true,
+		"/docs/drafts/next":     false,
+		"/docs/drafts/public/a": true,
+		"/files/guide.pdf":      false,
+		"/files/guide.pdf?x=1":  true,
+	} {
+		if got := rules.allowed(path); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if rules.delay != 2500*time.Millisecond {
+		t.Errorf("unexpected crawl delay %v", rules.delay)
+	}
+}
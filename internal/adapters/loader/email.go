@@ -0,0 +1,329 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"golang.org/x/net/html"
+)
+
+// EmailLoader loads email: single messages (.eml) and mailboxes (.mbox)
+// as exported by mail clients. Each message is its own section, labeled
+// with its subject, and starts with its From, To, Date and Subject
+// headers, so answers cite the message and its sender.
+type EmailLoader struct{}
+
+// NewEmailLoader creates an email loader.
+func NewEmailLoader() *EmailLoader {
+	return &EmailLoader{}
+}
+
+// Load reads the messages of an email file. A single message's headers
+// also become the document's metadata fields "from", "to", "date" (RFC
+// 3339) and "subject", so queries can filter by them; a mailbox holds
+// many messages and has none.
+func (l *EmailLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := [][]byte{data}
+	mailbox := strings.EqualFold(filepath.Ext(path), ".mbox")
+	if mailbox {
+		raw = splitMbox(data)
+	}
+	var sections []entities.Section
+	var metadata map[string]string
+	for i, r := range raw {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, err := parseEmail(r)
+		if err != nil {
+			if mailbox {
+				continue // Skip a damaged message rather than the mailbox
+			}
+			return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+		}
+		label := "Email"
+		if msg.subject != "" {
+			label += ": " + msg.subject
+		} else if mailbox {
+			label += fmt.Sprintf(" %d", i+1)
+		}
+		sections = append(sections, entities.Section{Label: label, Content: msg.text()})
+		if !mailbox {
+			metadata = msg.metadata()
+		}
+	}
+
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      filepath.Base(path),
+		Path:      path,
+		Content:   entities.JoinSections(sections),
+		Metadata:  metadata,
+		Sections:  sections,
+		CreatedAt: info.ModTime(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns file extensions this loader handles.
+func (l *EmailLoader) SupportedExtensions() []string {
+	return []string{".eml", ".mbox"}
+}
+
+// mboxFrom matches a line quoted because it would otherwise start a
+// message, as in mboxrd.
+var mboxFrom = regexp.MustCompile(`^>+From `)
+
+// splitMbox splits a mailbox into its messages. Each starts with a
+// "From " line at the start of the file or after a blank line; lines
+// quoted as ">From " in a message lose one ">".
+func splitMbox(data []byte) [][]byte {
+	var messages [][]byte
+	var current *bytes.Buffer
+	blank := true
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if blank && strings.HasPrefix(line, "From ") {
+			if current != nil {
+				messages = append(messages, current.Bytes())
+			}
+			current = new(bytes.Buffer)
+			blank = false
+			continue
+		}
+		blank = line == ""
+		if current == nil {
+			continue // Text before the first message
+		}
+		if mboxFrom.MatchString(line) {
+			line = line[1:]
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	if current != nil {
+		messages = append(messages, current.Bytes())
+	}
+	return messages
+}
+
+// email is the text of a parsed message.
+type email struct {
+	from, to, subject string
+	date              time.Time
+	body              string
+}
+
+// parseEmail reads a message's headers and its readable body.
+func parseEmail(data []byte) (email, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return email{}, err
+	}
+	e := email{
+		from:    addressHeader(msg.Header, "From"),
+		to:      addressHeader(msg.Header, "To"),
+		subject: decodeHeader(msg.Header.Get("Subject")),
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		e.date = date
+	}
+	e.body, err = partText(msg.Header, msg.Body)
+	if err != nil {
+		return email{}, err
+	}
+	return e, nil
+}
+
+// text renders the message as its headers followed by its body.
+func (e email) text() string {
+	var sb strings.Builder
+	for _, h := range []struct{ name, value string }{
+		{"From", e.from}, {"To", e.to}, {"Date", e.dateText()}, {"Subject", e.subject},
+	} {
+		if h.value != "" {
+			sb.WriteString(h.name + ": " + h.value + "\n")
+		}
+	}
+	return strings.TrimSpace(sb.String() + "\n" + e.body)
+}
+
+// dateText formats the message's date, empty when it has none.
+func (e email) dateText() string {
+	if e.date.IsZero() {
+		return ""
+	}
+	return e.date.Format("2006-01-02 15:04 -0700")
+}
+
+// metadata returns the message's headers as document metadata.
+func (e email) metadata() map[string]string {
+	m := make(map[string]string, 4)
+	if e.from != "" {
+		m["from"] = e.from
+	}
+	if e.to != "" {
+		m["to"] = e.to
+	}
+	if !e.date.IsZero() {
+		m["date"] = e.date.UTC().Format(time.RFC3339)
+	}
+	if e.subject != "" {
+		m["subject"] = e.subject
+	}
+	return m
+}
+
+// headerDecoder decodes MIME encoded words, such as
+// "=?utf-8?q?Caf=C3=A9?=", in the charsets decodeCharset knows.
+var headerDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(decodeCharset(charset, data)), nil
+	},
+}
+
+// decodeHeader decodes the encoded words of a header value, keeping it
+// as is when it cannot.
+func decodeHeader(value string) string {
+	if decoded, err := headerDecoder.DecodeHeader(value); err == nil {
+		value = decoded
+	}
+	return collapseSpace(value)
+}
+
+// addressHeader renders an address list header as "Name <address>"
+// entries, or its decoded text when it does not parse.
+func addressHeader(h mail.Header, key string) string {
+	value := h.Get(key)
+	if value == "" {
+		return ""
+	}
+	parser := mail.AddressParser{WordDecoder: headerDecoder}
+	list, err := parser.ParseList(value)
+	if err != nil {
+		return decodeHeader(value)
+	}
+	out := make([]string, len(list))
+	for i, a := range list {
+		if a.Name == "" {
+			out[i] = a.Address
+		} else {
+			out[i] = a.Name + " <" + a.Address + ">"
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// partHeader is the part of a MIME header partText reads.
+type partHeader interface {
+	Get(key string) string
+}
+
+// partText returns the readable text of a MIME part: plain text as is,
+// HTML converted to Markdown, the preferred alternative of
+// multipart/alternative and the text parts of other multiparts in
+// order. Attachments are named but not read.
+func partText(h partHeader, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil // RFC 2045's default
+	}
+	if disposition, dparams, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil && disposition == "attachment" {
+		if name := decodeHeader(dparams["filename"]); name != "" {
+			return "[Attachment: " + name + "]", nil
+		}
+		return "", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		var texts, types []string
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			text, err := partText(part.Header, part)
+			if err != nil {
+				return "", err
+			}
+			if text == "" {
+				continue
+			}
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			texts = append(texts, text)
+			types = append(types, partType)
+		}
+		if mediaType == "multipart/alternative" && len(texts) > 0 {
+			for i, t := range types {
+				if t == "text/plain" {
+					return texts[i], nil
+				}
+			}
+			return texts[0], nil
+		}
+		return strings.Join(texts, "\n\n"), nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" && mediaType != "message/rfc822" {
+		return "", nil
+	}
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	switch mediaType {
+	case "message/rfc822":
+		msg, err := parseEmail(data)
+		if err != nil {
+			return "", nil
+		}
+		return "---\n" + msg.text(), nil
+	case "text/html":
+		root, err := html.Parse(strings.NewReader(decodeCharset(params["charset"], data)))
+		if err != nil {
+			return "", err
+		}
+		text, _ := chapterMarkdown(root)
+		return text, nil
+	}
+	return strings.TrimSpace(strings.ReplaceAll(decodeCharset(params["charset"], data), "\r\n", "\n")), nil
+}
@@ -0,0 +1,80 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmailLoader_EML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.eml")
+	os.WriteFile(path, []byte("From: =?utf-8?q?Ren=C3=A9e_Roe?= <renee@example.com>\r\n"+
+		"To: Sam <sam@example.com>, ops@example.com\r\n"+
+		"Date: Tue, 1 Oct 2024 09:30:00 +0200\r\n"+
+		"Subject: Q3 budget\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: multipart/mixed; boundary=outer\r\n\r\n"+
+		"--outer\r\n"+
+		"Content-Type: multipart/alternative; boundary=inner\r\n\r\n"+
+		"--inner\r\n"+
+		"Content-Type: text/html; charset=utf-8\r\n\r\n"+
+		"<p>HTML version</p>\r\n"+
+		"--inner\r\n"+
+		"Content-Type: text/plain; charset=iso-8859-1\r\n"+
+		"Content-Transfer-Encoding: quoted-printable\r\n\r\n"+
+		"The budget is approved, caf=E9 included.\r\n"+
+		"--inner--\r\n"+
+		"--outer\r\n"+
+		"Content-Type: application/pdf\r\n"+
+		"Content-Disposition: attachment; filename=\"budget.pdf\"\r\n"+
+		"Content-Transfer-Encoding: base64\r\n\r\n"+
+		"JVBERi0xLjQ=\r\n"+
+		"--outer--\r\n"), 0644)
+
+	doc, err := NewEmailLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(doc.Sections) != 1 || doc.Sections[0].Label != "Email: Q3 budget" {
+		t.Fatalf("unexpected sections: %+v", doc.Sections)
+	}
+	want := "From: Renée Roe <renee@example.com>\n" +
+		"To: Sam <sam@example.com>, ops@example.com\n" +
+		"Date: 2024-10-01 09:30 +0200\n" +
+		"Subject: Q3 budget\n\n" +
+		"The budget is approved, café included.\n\n" +
+		"[Attachment: budget.pdf]"
+	if doc.Sections[0].Content != want {
+		t.Errorf("unexpected content: %q", doc.Sections[0].Content)
+	}
+	if doc.Metadata["from"] != "Renée Roe <renee@example.com>" || doc.Metadata["subject"] != "Q3 budget" || doc.Metadata["date"] != "2024-10-01T07:30:00Z" {
+		t.Errorf("unexpected metadata: %v", doc.Metadata)
+	}
+}
+
+func TestEmailLoader_Mbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.mbox")
+	os.WriteFile(path, []byte("From alice@example.com Mon Jan  1 00:00:00 2024\n"+
+		"From: alice@example.com\nSubject: Lunch\n\nNoon?\n>From the kitchen: soup.\n\n"+
+		"From bob@example.com Mon Jan  1 01:00:00 2024\n"+
+		"From: bob@example.com\nContent-Type: text/html\n\n<p>Sure, <b>noon</b>.</p>\n"), 0644)
+
+	doc, err := NewEmailLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(doc.Sections) != 2 {
+		t.Fatalf("expected 2 messages, got %+v", doc.Sections)
+	}
+	if first := doc.Sections[0]; first.Label != "Email: Lunch" || !strings.HasSuffix(first.Content, "Noon?\nFrom the kitchen: soup.") {
+		t.Errorf("unexpected first message: %+v", first)
+	}
+	if second := doc.Sections[1]; second.Label != "Email 2" || second.Content != "From: bob@example.com\n\nSure, **noon**." {
+		t.Errorf("unexpected second message: %+v", second)
+	}
+	if doc.Metadata != nil {
+		t.Errorf("mailbox should have no metadata, got %v", doc.Metadata)
+	}
+}
@@ -0,0 +1,305 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EPUBLoader loads ebooks (.epub), one section per chapter labeled with
+// its title, so answers cite books by chapter.
+type EPUBLoader struct{}
+
+// NewEPUBLoader creates an ebook loader.
+func NewEPUBLoader() *EPUBLoader {
+	return &EPUBLoader{}
+}
+
+// Load reads the chapters of a book in reading order, converting each
+// to Markdown. A chapter is titled by the book's table of contents, or
+// by its first heading; a file with neither, such as the second half
+// of a chapter split in two, joins the chapter before it.
+func (l *EPUBLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening book: %w", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	book, err := readPackage(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []entities.Section
+	for _, chapter := range book.spine {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := readPart(files, chapter)
+		if err != nil {
+			if errors.As(err, new(errMissingPart)) {
+				continue
+			}
+			return nil, err
+		}
+		root, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", chapter, err)
+		}
+		text, heading := chapterMarkdown(root)
+		if text == "" {
+			continue
+		}
+
+		title := book.titles[chapter]
+		if title == "" {
+			title = heading
+		}
+		if title == "" && len(sections) > 0 {
+			last := &sections[len(sections)-1]
+			last.Content += "\n\n" + text
+			continue
+		}
+		label := ""
+		if title != "" {
+			label = "Chapter: " + title
+		}
+		sections = append(sections, entities.Section{Label: label, Content: text})
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      filepath.Base(path),
+		Path:      path,
+		Content:   entities.JoinSections(sections),
+		Sections:  sections,
+		CreatedAt: info.ModTime(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns file extensions this loader handles.
+func (l *EPUBLoader) SupportedExtensions() []string {
+	return []string{".epub"}
+}
+
+// epubPackage is what a book's package document tells: its content
+// files in reading order, and their titles in the table of contents.
+type epubPackage struct {
+	spine  []string          // Archive paths of the content files
+	titles map[string]string // Title of each content file, by archive path
+}
+
+// readPackage finds the package document through
+// META-INF/container.xml and reads its spine and table of contents,
+// from the EPUB 3 navigation document or else the EPUB 2 NCX.
+func readPackage(files map[string]*zip.File) (*epubPackage, error) {
+	var container struct {
+		Rootfiles []struct {
+			Path string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := decodePart(files, "META-INF/container.xml", &container); err != nil {
+		if errors.As(err, new(errMissingPart)) {
+			return nil, fmt.Errorf("not an EPUB book: %w", err)
+		}
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("not an EPUB book: no package document")
+	}
+	opfPath := container.Rootfiles[0].Path
+
+	var opf struct {
+		Items []struct {
+			ID         string `xml:"id,attr"`
+			Href       string `xml:"href,attr"`
+			Properties string `xml:"properties,attr"`
+		} `xml:"manifest>item"`
+		Spine struct {
+			Toc   string `xml:"toc,attr"`
+			Items []struct {
+				IDRef string `xml:"idref,attr"`
+			} `xml:"itemref"`
+		} `xml:"spine"`
+	}
+	if err := decodePart(files, opfPath, &opf); err != nil {
+		return nil, err
+	}
+
+	book := &epubPackage{titles: make(map[string]string)}
+	hrefs := make(map[string]string, len(opf.Items))
+	var nav string
+	for _, item := range opf.Items {
+		hrefs[item.ID] = resolveHref(opfPath, item.Href)
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			nav = hrefs[item.ID]
+		}
+	}
+	ncx := hrefs[opf.Spine.Toc]
+	for _, ref := range opf.Spine.Items {
+		if href, ok := hrefs[ref.IDRef]; ok {
+			book.spine = append(book.spine, href)
+		}
+	}
+
+	var entries []tocEntry
+	var err error
+	switch {
+	case nav != "":
+		entries, err = navEntries(files, nav)
+	case ncx != "":
+		entries, err = ncxEntries(files, ncx)
+	}
+	if err != nil && !errors.As(err, new(errMissingPart)) {
+		return nil, err
+	}
+	for _, e := range entries {
+		// A chapter keeps the first entry pointing into it
+		if _, ok := book.titles[e.path]; !ok && e.title != "" {
+			book.titles[e.path] = e.title
+		}
+	}
+	return book, nil
+}
+
+// tocEntry is an entry of a book's table of contents.
+type tocEntry struct {
+	title string
+	path  string // Archive path of the content file, without a fragment
+}
+
+// navEntries reads the "toc" list of an EPUB 3 navigation document.
+func navEntries(files map[string]*zip.File, name string) ([]tocEntry, error) {
+	data, err := readPart(files, name)
+	if err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	toc := findFirst(root, func(n *html.Node) bool {
+		return n.DataAtom == atom.Nav && getAttr(n, "epub:type") == "toc"
+	})
+	if toc == nil {
+		return nil, nil
+	}
+	var entries []tocEntry
+	for _, a := range findAll(toc, func(n *html.Node) bool { return n.DataAtom == atom.A }) {
+		if href := getAttr(a, "href"); href != "" {
+			entries = append(entries, tocEntry{title: collapseSpace(textContent(a)), path: resolveHref(name, href)})
+		}
+	}
+	return entries, nil
+}
+
+// navPoint is an entry of an EPUB 2 NCX table of contents.
+type navPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []navPoint `xml:"navPoint"`
+}
+
+// ncxEntries reads the navigation map of an EPUB 2 NCX file, depth
+// first.
+func ncxEntries(files map[string]*zip.File, name string) ([]tocEntry, error) {
+	var ncx struct {
+		Points []navPoint `xml:"navMap>navPoint"`
+	}
+	if err := decodePart(files, name, &ncx); err != nil {
+		return nil, err
+	}
+	var entries []tocEntry
+	var walk func([]navPoint)
+	walk = func(points []navPoint) {
+		for _, p := range points {
+			if p.Content.Src != "" {
+				entries = append(entries, tocEntry{title: collapseSpace(p.Label), path: resolveHref(name, p.Content.Src)})
+			}
+			walk(p.Children)
+		}
+	}
+	walk(ncx.Points)
+	return entries, nil
+}
+
+// resolveHref resolves a link in the part base to an archive path,
+// dropping its fragment.
+func resolveHref(base, href string) string {
+	if i := strings.IndexByte(href, '#'); i >= 0 {
+		href = href[:i]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Clean(path.Join(path.Dir(base), href))
+}
+
+// readPart returns the content of a part of the archive.
+func readPart(files map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, errMissingPart(name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// chapterMarkdown renders the body of a chapter as Markdown, and
+// returns its first heading. Unlike a web page, a chapter is all
+// content, so only tags that never hold text are dropped.
+func chapterMarkdown(root *html.Node) (text, heading string) {
+	body := findFirst(root, func(n *html.Node) bool { return n.DataAtom == atom.Body })
+	if body == nil {
+		return "", ""
+	}
+	removeTags(body, boilerplateTags)
+	if h := findFirst(body, func(n *html.Node) bool {
+		return n.DataAtom == atom.H1 || n.DataAtom == atom.H2 || n.DataAtom == atom.H3
+	}); h != nil {
+		heading = collapseSpace(textContent(h))
+	}
+	return renderMarkdown(body), heading
+}
+
+// removeTags detaches the elements with the given tags under n.
+func removeTags(n *html.Node, tags map[atom.Atom]bool) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && tags[c.DataAtom] {
+			n.RemoveChild(c)
+		} else {
+			removeTags(c, tags)
+		}
+		c = next
+	}
+}
@@ -0,0 +1,84 @@
+package loader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func epubContainer(opf string) string {
+	return `<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="` + opf + `" media-type="application/oebps-package+xml"/></rootfiles></container>`
+}
+
+func xhtml(body string) string {
+	return `<?xml version="1.0" encoding="utf-8"?><html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><head><title>Book</title><style>p { margin: 0 }</style></head><body>` + body + `</body></html>`
+}
+
+func TestEPUBLoader_EPUB3(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.epub")
+	writeZip(t, path, map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": epubContainer("OEBPS/content.opf"),
+		"OEBPS/content.opf": `<package xmlns="http://www.idpf.org/2007/opf" version="3.0"><manifest>` +
+			`<item id="nav" href="nav.xhtml" properties="nav" media-type="application/xhtml+xml"/>` +
+			`<item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="c1b" href="text/chapter1b.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="c2" href="text/chapter2.xhtml" media-type="application/xhtml+xml"/>` +
+			`</manifest><spine><itemref idref="nav"/><itemref idref="c1"/><itemref idref="c1b"/><itemref idref="c2"/></spine></package>`,
+		"OEBPS/nav.xhtml": xhtml(`<nav epub:type="toc"><ol><li><a href="text/chapter%201.xhtml#start">The  Storm</a></li>` +
+			`<li><a href="text/chapter2.xhtml">Landfall</a><ol><li><a href="text/chapter2.xhtml#s2">The Beach</a></li></ol></li></ol></nav>`),
+		"OEBPS/text/chapter 1.xhtml": xhtml(`<h1 id="start">I. Storm</h1><p>The wind rose at dusk.</p>`),
+		"OEBPS/text/chapter1b.xhtml": xhtml(`<p>By midnight the mast had <em>snapped</em>.</p>`),
+		"OEBPS/text/chapter2.xhtml":  xhtml(`<p>They reached the shore at dawn.</p><script>x()</script>`),
+	})
+
+	doc, err := NewEPUBLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(doc.Sections) != 2 {
+		t.Fatalf("expected 2 chapters, got %+v", doc.Sections)
+	}
+	first, second := doc.Sections[0], doc.Sections[1]
+	if first.Label != "Chapter: The Storm" || first.Content != "# I. Storm\n\nThe wind rose at dusk.\n\nBy midnight the mast had *snapped*." {
+		t.Errorf("unexpected first chapter: %+v", first)
+	}
+	if second.Label != "Chapter: Landfall" || second.Content != "They reached the shore at dawn." {
+		t.Errorf("unexpected second chapter: %+v", second)
+	}
+}
+
+func TestEPUBLoader_EPUB2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.epub")
+	writeZip(t, path, map[string]string{
+		"META-INF/container.xml": epubContainer("content.opf"),
+		"content.opf": `<package xmlns="http://www.idpf.org/2007/opf" version="2.0"><manifest>` +
+			`<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>` +
+			`<item id="title" href="title.html" media-type="application/xhtml+xml"/>` +
+			`<item id="c1" href="c1.html" media-type="application/xhtml+xml"/>` +
+			`</manifest><spine toc="ncx"><itemref idref="title"/><itemref idref="c1"/></spine></package>`,
+		"toc.ncx": `<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/"><navMap>` +
+			`<navPoint id="p1"><navLabel><text>Chapter One</text></navLabel><content src="c1.html"/></navPoint></navMap></ncx>`,
+		"title.html": xhtml(`<h2>A Sea Tale</h2>`),
+		"c1.html":    xhtml(`<p>Call me Ishmael.</p>`),
+	})
+
+	doc, err := NewEPUBLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(doc.Sections) != 2 || doc.Sections[0].Label != "Chapter: A Sea Tale" || doc.Sections[1].Label != "Chapter: Chapter One" {
+		t.Fatalf("unexpected chapters: %+v", doc.Sections)
+	}
+	if want := "[Chapter: A Sea Tale]\n## A Sea Tale\n\n[Chapter: Chapter One]\nCall me Ishmael."; doc.Content != want {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+}
+
+func TestEPUBLoader_NotABook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.epub")
+	writeZip(t, path, map[string]string{"readme.txt": "hello"})
+	if _, err := NewEPUBLoader().Load(context.Background(), path); err == nil {
+		t.Error("expected an error")
+	}
+}
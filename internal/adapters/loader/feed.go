@@ -0,0 +1,267 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	"golang.org/x/net/html"
+)
+
+// FeedLoader reads syndication feeds (RSS 2.0, RSS 1.0 and Atom), one
+// document per entry, so subscribed blogs and newsletters are ingested
+// as they post.
+type FeedLoader struct {
+	pages *URLLoader
+}
+
+// NewFeedLoader creates a feed reader that fetches feeds with client,
+// or with a 30-second timeout when client is nil.
+func NewFeedLoader(client *http.Client) *FeedLoader {
+	return &FeedLoader{pages: NewURLLoader(client)}
+}
+
+// Fetch reads the feed at feedURL. Each entry becomes a document cited
+// by its link, or by the feed's URL and the entry's id when it has
+// none, and named by its title. Its content, or else its summary, is
+// converted to Markdown under the title, the author and the date. The
+// metadata fields "feed", "author" and "published" (RFC 3339) are set
+// where the feed gives them.
+func (l *FeedLoader) Fetch(ctx context.Context, feedURL string) (ports.DocumentLoader, []string, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, nil, fmt.Errorf("not an http(s) URL: %q", feedURL)
+	}
+	resp, data, err := l.pages.get(ctx, u, "application/rss+xml,application/atom+xml,application/rdf+xml,application/xml;q=0.9,text/xml;q=0.9")
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := parseFeed(data, resp.Request.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading feed %s: %w", feedURL, err)
+	}
+
+	found := make(crawledPages, len(entries))
+	var paths []string
+	for _, doc := range entries {
+		if _, ok := found[doc.Path]; ok {
+			continue // Listed twice
+		}
+		found[doc.Path] = crawledPage{doc: doc}
+		paths = append(paths, doc.Path)
+	}
+	return found, paths, nil
+}
+
+// feedXML holds the parts of RSS and Atom feeds that are read. RSS 2.0
+// lists items in its channel, RSS 1.0 beside it, Atom lists entries.
+type feedXML struct {
+	XMLName xml.Name
+	Title   string `xml:"title"`
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	ID        string   `xml:"id"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Authors   []string `xml:"author>name"`
+	Content   atomText `xml:"content"`
+	Summary   atomText `xml:"summary"`
+}
+
+// atomText is an Atom text construct: plain text, escaped HTML, or
+// inline XHTML.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the construct as HTML.
+func (t atomText) html() string {
+	switch t.Type {
+	case "xhtml":
+		return t.Inner
+	case "html", "text/html":
+		return t.Text
+	}
+	return html.EscapeString(t.Text)
+}
+
+// feedEntry is an entry of any kind of feed.
+type feedEntry struct {
+	title, link, id, author, date, body string
+}
+
+// parseFeed reads the entries of a feed fetched from base.
+func parseFeed(data []byte, base *url.URL) ([]*entities.Document, error) {
+	var feed feedXML
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(decodeCharset(charset, data)), nil
+	}
+	if err := dec.Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	title := feed.Title
+	var entries []feedEntry
+	switch strings.ToLower(feed.XMLName.Local) {
+	case "rss", "rdf":
+		if title == "" {
+			title = feed.Channel.Title
+		}
+		for _, it := range append(feed.Channel.Items, feed.Items...) {
+			e := feedEntry{title: it.Title, link: it.Link, id: it.GUID, author: it.Author, date: it.PubDate, body: it.Content}
+			if e.author == "" {
+				e.author = it.Creator
+			}
+			if e.date == "" {
+				e.date = it.Date
+			}
+			if e.body == "" {
+				e.body = it.Description
+			}
+			entries = append(entries, e)
+		}
+	case "feed":
+		for _, it := range feed.Entries {
+			e := feedEntry{title: it.Title, id: it.ID, date: it.Published, body: it.Content.html()}
+			for _, link := range it.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					e.link = link.Href
+					break
+				}
+			}
+			if len(it.Authors) > 0 {
+				e.author = strings.Join(it.Authors, ", ")
+			}
+			if e.date == "" {
+				e.date = it.Updated
+			}
+			if strings.TrimSpace(e.body) == "" {
+				e.body = it.Summary.html()
+			}
+			entries = append(entries, e)
+		}
+	default:
+		return nil, fmt.Errorf("not an RSS or Atom feed: <%s>", feed.XMLName.Local)
+	}
+
+	docs := make([]*entities.Document, 0, len(entries))
+	for _, e := range entries {
+		if doc := e.document(base, collapseSpace(title)); doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// document renders an entry of the feed titled feedTitle as a document,
+// or returns nil when it has neither a link nor an id to cite it by.
+func (e feedEntry) document(base *url.URL, feedTitle string) *entities.Document {
+	path := ""
+	if link, err := base.Parse(strings.TrimSpace(e.link)); err == nil && e.link != "" {
+		path = link.String()
+	} else if id := strings.TrimSpace(e.id); id != "" {
+		path = base.String() + "#" + url.PathEscape(id)
+	}
+	if path == "" {
+		return nil
+	}
+
+	title := collapseSpace(e.title)
+	if title == "" {
+		title = path
+	}
+	metadata := make(map[string]string, 3)
+	if feedTitle != "" {
+		metadata["feed"] = feedTitle
+	}
+	var byline []string
+	if author := collapseSpace(e.author); author != "" {
+		metadata["author"] = author
+		byline = append(byline, author)
+	}
+	posted := time.Now()
+	if t, ok := parseFeedDate(e.date); ok {
+		posted = t
+		metadata["published"] = t.UTC().Format(time.RFC3339)
+		byline = append(byline, t.Format("2006-01-02"))
+	}
+
+	content := "# " + title
+	if len(byline) > 0 {
+		content += "\n\n" + strings.Join(byline, ", ")
+	}
+	if root, err := html.Parse(strings.NewReader(e.body)); err == nil {
+		if text, _ := chapterMarkdown(root); text != "" {
+			content += "\n\n" + text
+		}
+	}
+
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      title,
+		Path:      path,
+		Content:   content,
+		Metadata:  metadata,
+		CreatedAt: posted,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// feedDateLayouts are the date formats feeds use: RFC 822 in RSS, with
+// its common variants, and RFC 3339 in Atom and Dublin Core.
+var feedDateLayouts = []string{
+	time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST", "2 Jan 2006 15:04:05 -0700",
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02",
+}
+
+// parseFeedDate reads a feed's date.
+func parseFeedDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
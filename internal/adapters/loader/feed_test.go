@@ -0,0 +1,84 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeedLoader_RSS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><title>Caf` + "\xe9" + ` Notes</title>
+<item><title>Release 2.0</title><link>/posts/2-0</link><dc:creator>Ana</dc:creator>
+<pubDate>Tue, 01 Oct 2024 09:30:00 +0000</pubDate><description>Short summary</description>
+<content:encoded><![CDATA[<p>Version <b>2.0</b> is out.</p><script>x()</script>]]></content:encoded></item>
+<item><title>No link</title><guid isPermaLink="false">id 7</guid><description>&lt;p&gt;Escaped &amp;amp; fine&lt;/p&gt;</description></item>
+<item><title>Nothing to cite</title></item>
+</channel></rss>`))
+	}))
+	defer srv.Close()
+
+	entries, paths, err := NewFeedLoader(nil).Fetch(context.Background(), srv.URL+"/feed.xml")
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != srv.URL+"/posts/2-0" || paths[1] != srv.URL+"/feed.xml#id%207" {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+	doc, err := entries.Load(context.Background(), paths[0])
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Name != "Release 2.0" || doc.Content != "# Release 2.0\n\nAna, 2024-10-01\n\nVersion **2.0** is out." {
+		t.Errorf("unexpected entry: %q, %q", doc.Name, doc.Content)
+	}
+	if doc.Metadata["feed"] != "Café Notes" || doc.Metadata["author"] != "Ana" || doc.Metadata["published"] != "2024-10-01T09:30:00Z" {
+		t.Errorf("unexpected metadata: %v", doc.Metadata)
+	}
+	if doc, _ := entries.Load(context.Background(), paths[1]); doc.Content != "# No link\n\nEscaped & fine" {
+		t.Errorf("unexpected entry: %q", doc.Content)
+	}
+}
+
+func TestFeedLoader_Atom(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Dev Blog</title>
+<entry><title>Tracing</title><link rel="self" href="https://blog.example.com/api/1"/><link href="https://blog.example.com/tracing"/>
+<id>urn:1</id><updated>2024-03-05T10:00:00Z</updated><author><name>Bo</name></author>
+<summary>Summary only</summary>
+<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Spans <em>nest</em>.</p></div></content></entry>
+<entry><title>Plain</title><link href="https://blog.example.com/plain"/><summary type="text">a &lt; b</summary></entry>
+</feed>`))
+	}))
+	defer srv.Close()
+
+	entries, paths, err := NewFeedLoader(nil).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != "https://blog.example.com/tracing" {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+	doc, _ := entries.Load(context.Background(), paths[0])
+	if doc.Content != "# Tracing\n\nBo, 2024-03-05\n\nSpans *nest*." || doc.Metadata["feed"] != "Dev Blog" {
+		t.Errorf("unexpected entry: %q, %v", doc.Content, doc.Metadata)
+	}
+	if doc, _ := entries.Load(context.Background(), paths[1]); doc.Content != "# Plain\n\na < b" {
+		t.Errorf("unexpected entry: %q", doc.Content)
+	}
+}
+
+func TestFeedLoader_NotAFeed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>Hello</body></html>`))
+	}))
+	defer srv.Close()
+	if _, _, err := NewFeedLoader(nil).Fetch(context.Background(), srv.URL); err == nil {
+		t.Error("an HTML page is not a feed")
+	}
+}
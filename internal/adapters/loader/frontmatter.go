@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontmatter is the part of a Markdown file's YAML frontmatter that is
+// read.
+type frontmatter struct {
+	Title string    `yaml:"title"`
+	Tags  yaml.Node `yaml:"tags"`
+	Date  yaml.Node `yaml:"date"`
+}
+
+// splitFrontmatter splits the YAML frontmatter, between lines of "---"
+// at the very start, from the text of a Markdown file. It returns the
+// metadata fields read from it: "title", "tags", joined by ", ", and
+// "date", as a date or an RFC 3339 timestamp, with the time of that
+// date. Text without frontmatter, or with frontmatter that is not valid
+// YAML, is returned whole.
+func splitFrontmatter(text string) (map[string]string, time.Time, string) {
+	lines := strings.SplitAfter(strings.TrimPrefix(text, "\ufeff"), "\n")
+	if strings.TrimRight(lines[0], "\r\n") != "---" {
+		return nil, time.Time{}, text
+	}
+	end := 0
+	for i := 1; i < len(lines) && end == 0; i++ {
+		if line := strings.TrimRight(lines[i], "\r\n"); line == "---" || line == "..." {
+			end = i
+		}
+	}
+	if end == 0 {
+		return nil, time.Time{}, text // Never closed
+	}
+
+	var fm frontmatter
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end], "")), &fm); err != nil {
+		return nil, time.Time{}, text
+	}
+	metadata := make(map[string]string, 3)
+	if title := collapseSpace(fm.Title); title != "" {
+		metadata["title"] = title
+	}
+	if tags := frontmatterList(&fm.Tags); len(tags) > 0 {
+		metadata["tags"] = strings.Join(tags, ", ")
+	}
+	date, ok := frontmatterDate(&fm.Date)
+	if ok {
+		metadata["date"] = date.Format(time.RFC3339)
+		if date.Equal(date.Truncate(24 * time.Hour)) {
+			metadata["date"] = date.Format("2006-01-02")
+		}
+	}
+	return metadata, date, strings.TrimLeft(strings.Join(lines[end+1:], ""), "\r\n")
+}
+
+// frontmatterList reads a list of tags, written as a YAML sequence or
+// as one comma-separated string.
+func frontmatterList(n *yaml.Node) []string {
+	var values []string
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			if item.Kind == yaml.ScalarNode {
+				values = append(values, item.Value)
+			}
+		}
+	case yaml.ScalarNode:
+		values = strings.Split(n.Value, ",")
+	}
+	var tags []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			tags = append(tags, v)
+		}
+	}
+	return tags
+}
+
+// frontmatterDate reads a date, as 2006-01-02, or a timestamp, in UTC.
+func frontmatterDate(n *yaml.Node) (time.Time, bool) {
+	if n.Kind != yaml.ScalarNode || n.Value == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse("2006-01-02", strings.TrimSpace(n.Value)); err == nil {
+		return t, true
+	}
+	var t time.Time
+	if err := n.Decode(&t); err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
@@ -0,0 +1,208 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// GitLoader reads Git repositories for ingestion with the git binary.
+// Local repositories are read in place; others are cloned into a cache
+// directory, and pulled when checked out again. Files are loaded from
+// the working tree by type, as MultiLoader does, with source files
+// labeled relative to the repository.
+type GitLoader struct {
+	cloneDir string
+}
+
+// NewGitLoader creates a Git repository reader that clones remote
+// repositories under cloneDir, or under the system's temporary
+// directory when cloneDir is empty.
+func NewGitLoader(cloneDir string) *GitLoader {
+	if cloneDir == "" {
+		cloneDir = filepath.Join(os.TempDir(), "localrag-git")
+	}
+	return &GitLoader{cloneDir: cloneDir}
+}
+
+// Checkout opens the repository at source and lists its tracked text
+// files. Each file is loaded with the metadata fields "commit", the
+// full hash of HEAD, and "path", relative to the repository, and every
+// section's label ends with the short commit, such as "File: main.go,
+// lines 1-40 (Go), commit 3f2a9c1", so each chunk names the version it
+// was read from.
+func (l *GitLoader) Checkout(ctx context.Context, source string) (ports.GitCheckout, error) {
+	root, err := l.worktree(ctx, source)
+	if err != nil {
+		return ports.GitCheckout{}, err
+	}
+	head, err := git(ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		return ports.GitCheckout{}, err
+	}
+	co := ports.GitCheckout{Root: root, Head: strings.TrimSpace(string(head))}
+
+	// git grep -I lists the tracked files holding text, skipping binaries
+	out, err := git(ctx, root, "grep", "-I", "-z", "--name-only", "-e", "")
+	if err != nil && !isExit(err, 1) { // 1 means no file matched
+		return ports.GitCheckout{}, err
+	}
+	for _, rel := range splitNUL(out) {
+		co.Paths = append(co.Paths, filepath.Join(root, filepath.FromSlash(rel)))
+	}
+	sort.Strings(co.Paths)
+
+	files := NewMultiLoader()
+	files.SetCodeRoot(root)
+	co.Loader = gitFiles{files: files, root: root, head: co.Head}
+	return co, nil
+}
+
+// Changes lists the text files changed between since and the checkout's
+// HEAD, with git diff, and the files deleted.
+func (l *GitLoader) Changes(ctx context.Context, co ports.GitCheckout, since string) (changed, removed []string, ok bool, err error) {
+	if _, err := git(ctx, co.Root, "merge-base", "--is-ancestor", since, co.Head); err != nil {
+		return nil, nil, false, nil
+	}
+	out, err := git(ctx, co.Root, "diff", "-z", "--name-status", "--no-renames", since, co.Head)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	text := make(map[string]bool, len(co.Paths))
+	for _, path := range co.Paths {
+		text[path] = true
+	}
+	fields := splitNUL(out)
+	for i := 0; i+1 < len(fields); i += 2 {
+		path := filepath.Join(co.Root, filepath.FromSlash(fields[i+1]))
+		switch {
+		case fields[i] == "D":
+			removed = append(removed, path)
+		case text[path]:
+			changed = append(changed, path)
+		}
+	}
+	return changed, removed, true, nil
+}
+
+// worktree returns the top directory of a local repository, or clones
+// or pulls a remote one into the cache and returns its directory.
+func (l *GitLoader) worktree(ctx context.Context, source string) (string, error) {
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		top, err := git(ctx, source, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return "", err
+		}
+		return filepath.Clean(strings.TrimSpace(string(top))), nil
+	}
+	if !strings.Contains(source, "://") && !strings.Contains(source, "@") {
+		return "", fmt.Errorf("%q is neither a directory nor a repository URL", source)
+	}
+
+	hash := sha256.Sum256([]byte(source))
+	dir := filepath.Join(l.cloneDir, hex.EncodeToString(hash[:8]))
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		_, err := git(ctx, dir, "pull", "--quiet", "--ff-only")
+		return dir, err
+	}
+	if err := os.MkdirAll(l.cloneDir, 0o755); err != nil {
+		return "", err
+	}
+	if _, err := git(ctx, l.cloneDir, "clone", "--quiet", "--", source, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// git runs a git command in dir and returns its output, or an error
+// with what git said.
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// isExit reports whether err is a command exiting with code.
+func isExit(err error, code int) bool {
+	var exit *exec.ExitError
+	return errors.As(err, &exit) && exit.ExitCode() == code
+}
+
+// splitNUL splits git's -z output into its fields.
+func splitNUL(out []byte) []string {
+	var fields []string
+	for _, f := range strings.Split(string(out), "\x00") {
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// gitFiles loads the files of a checkout, labeled with their path and
+// commit.
+type gitFiles struct {
+	files *MultiLoader
+	root  string
+	head  string
+}
+
+func (g gitFiles) Load(ctx context.Context, path string) (*entities.Document, error) {
+	doc, err := g.files.Load(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(g.root, path)
+	if err != nil {
+		return nil, err
+	}
+	rel = filepath.ToSlash(rel)
+
+	short := g.head
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if len(doc.Sections) == 0 {
+		doc.Sections = []entities.Section{{Label: "File: " + rel, Content: doc.Content}}
+	}
+	for i := range doc.Sections {
+		if doc.Sections[i].Label == "" {
+			doc.Sections[i].Label = "File: " + rel
+		}
+		doc.Sections[i].Label += ", commit " + short
+	}
+	doc.Content = entities.JoinSections(doc.Sections)
+
+	if doc.Metadata == nil {
+		doc.Metadata = make(map[string]string, 2)
+	}
+	doc.Metadata["commit"] = g.head
+	doc.Metadata["path"] = rel
+	return doc, nil
+}
+
+// SupportedExtensions returns the extensions of the loaders files are
+// read with.
+func (g gitFiles) SupportedExtensions() []string {
+	return g.files.SupportedExtensions()
+}
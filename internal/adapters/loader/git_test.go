@@ -0,0 +1,78 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit runs git in dir, failing the test when it fails.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestGitLoader_CheckoutAndChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Tool\n\nDoes things."), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "logo.png"), []byte{0x89, 'P', 'N', 'G', 0, 0, 1}, 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("untracked"), 0644)
+	runGit(t, dir, "add", "README.md", "main.go", "logo.png")
+	runGit(t, dir, "commit", "-q", "-m", "first")
+	first := runGit(t, dir, "rev-parse", "HEAD")
+
+	g := NewGitLoader(t.TempDir())
+	co, err := g.Checkout(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("checkout failed: %v", err)
+	}
+	root, _ := filepath.EvalSymlinks(dir)
+	if co.Head != first || len(co.Paths) != 2 || co.Paths[0] != filepath.Join(root, "README.md") || co.Paths[1] != filepath.Join(root, "main.go") {
+		t.Fatalf("unexpected checkout: %+v", co)
+	}
+
+	doc, err := co.Loader.Load(context.Background(), co.Paths[1])
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if want := "File: main.go, lines 1-3 (Go), commit " + first[:7]; len(doc.Sections) != 1 || doc.Sections[0].Label != want {
+		t.Errorf("unexpected sections: %+v", doc.Sections)
+	}
+	if doc.Metadata["commit"] != first || doc.Metadata["path"] != "main.go" {
+		t.Errorf("unexpected metadata: %v", doc.Metadata)
+	}
+	doc, _ = co.Loader.Load(context.Background(), co.Paths[0])
+	if !strings.HasPrefix(doc.Content, "[File: README.md, commit "+first[:7]+"]\n# Tool") {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() { run() }\n"), 0644)
+	runGit(t, dir, "rm", "-q", "README.md")
+	runGit(t, dir, "commit", "-q", "-am", "second")
+	if co, err = g.Checkout(context.Background(), dir); err != nil {
+		t.Fatalf("checkout failed: %v", err)
+	}
+	changed, removed, ok, err := g.Changes(context.Background(), co, first)
+	if err != nil || !ok {
+		t.Fatalf("changes failed: %v, %v", ok, err)
+	}
+	if len(changed) != 1 || changed[0] != filepath.Join(root, "main.go") || len(removed) != 1 || removed[0] != filepath.Join(root, "README.md") {
+		t.Errorf("unexpected changes: %v, %v", changed, removed)
+	}
+	if _, _, ok, _ := g.Changes(context.Background(), co, strings.Repeat("0", 40)); ok {
+		t.Error("a commit outside the history should ask for a full ingest")
+	}
+}
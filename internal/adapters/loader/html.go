@@ -0,0 +1,490 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLLoader loads web pages (.html, .htm). Like a browser's reader
+// view, it drops scripts, navigation, sidebars, ads and other
+// boilerplate, keeps the page's main content and converts it to
+// Markdown, so headings, lists, links and tables survive chunking.
+type HTMLLoader struct{}
+
+// NewHTMLLoader creates a web page loader.
+func NewHTMLLoader() *HTMLLoader {
+	return &HTMLLoader{}
+}
+
+// Load reads a web page as Markdown, starting with its title as a
+// heading. Pages are expected to be UTF-8.
+func (l *HTMLLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	root, err := html.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      filepath.Base(path),
+		Path:      path,
+		Content:   htmlToMarkdown(root),
+		CreatedAt: info.ModTime(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns file extensions this loader handles.
+func (l *HTMLLoader) SupportedExtensions() []string {
+	return []string{".html", ".htm"}
+}
+
+// htmlToMarkdown renders the main content of a parsed page as Markdown.
+func htmlToMarkdown(root *html.Node) string {
+	title := pageTitle(root)
+	removeBoilerplate(root)
+	content := mainContent(root)
+	if content == nil {
+		return title
+	}
+
+	md := renderMarkdown(content)
+	if title != "" && !strings.HasPrefix(md, "# ") {
+		md = strings.TrimSpace("# " + title + "\n\n" + md)
+	}
+	return md
+}
+
+// pageTitle returns the text of the page's <title>.
+func pageTitle(root *html.Node) string {
+	if n := findFirst(root, func(n *html.Node) bool { return n.DataAtom == atom.Title }); n != nil {
+		return collapseSpace(textContent(n))
+	}
+	return ""
+}
+
+// boilerplateTags never hold a page's main content.
+var boilerplateTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Head: true, atom.Nav: true, atom.Aside: true, atom.Form: true,
+	atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Canvas: true,
+	atom.Svg: true, atom.Math: true, atom.Dialog: true,
+}
+
+// boilerplateRoles are ARIA landmarks around the main content.
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true,
+	"search": true, "dialog": true, "alert": true, "menu": true, "menubar": true,
+}
+
+// unlikelyClass and likelyClass match the class and id of elements that
+// usually hold boilerplate, and of those that usually hold content, as
+// in Mozilla's Readability.
+var (
+	unlikelyClass = regexp.MustCompile(`(?i)(^|[-_ ])(ad|ads|advert|banner|breadcrumbs?|combx|comments?|community|consent|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|newsletter|outbrain|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|taboola|tags|toolbar|tweet|widget)([-_ ]|$)`)
+	likelyClass   = regexp.MustCompile(`(?i)article|body|column|content|main|post|story|entry|text`)
+)
+
+// removeBoilerplate detaches elements that are not part of the content:
+// non-text tags, navigation landmarks, hidden elements, page headers
+// and footers outside an article, and elements whose class or id names
+// boilerplate.
+func removeBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == html.CommentNode:
+			n.RemoveChild(c)
+		case c.Type == html.ElementNode && isBoilerplate(c):
+			n.RemoveChild(c)
+		default:
+			removeBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+// isBoilerplate reports whether an element is left out of the content.
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateTags[n.DataAtom] || boilerplateRoles[getAttr(n, "role")] {
+		return true
+	}
+	if _, hidden := attrValue(n, "hidden"); hidden || getAttr(n, "aria-hidden") == "true" {
+		return true
+	}
+	if style := strings.ReplaceAll(strings.ToLower(getAttr(n, "style")), " ", ""); strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+	if (n.DataAtom == atom.Header || n.DataAtom == atom.Footer) && !insideArticle(n) {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Body, atom.Main, atom.Article, atom.A:
+		return false
+	}
+	names := getAttr(n, "class") + " " + getAttr(n, "id")
+	return unlikelyClass.MatchString(names) && !likelyClass.MatchString(names)
+}
+
+// insideArticle reports whether n is within an <article> or <main>.
+func insideArticle(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Article || p.DataAtom == atom.Main || getAttr(p, "role") == "main" {
+			return true
+		}
+	}
+	return false
+}
+
+// mainContent picks the element holding the page's content: its only
+// <main> or <article>, or else the element whose paragraphs score
+// highest, favoring long text with commas over lists of links. It falls
+// back to <body>.
+func mainContent(root *html.Node) *html.Node {
+	for _, match := range []func(*html.Node) bool{
+		func(n *html.Node) bool { return n.DataAtom == atom.Main || getAttr(n, "role") == "main" },
+		func(n *html.Node) bool { return n.DataAtom == atom.Article },
+	} {
+		if nodes := findAll(root, match); len(nodes) == 1 && textLength(nodes[0]) > 0 {
+			return nodes[0]
+		}
+	}
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node // In document order, so ties pick the first
+	add := func(n *html.Node, score float64) {
+		if _, ok := scores[n]; !ok {
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+	for _, p := range findAll(root, func(n *html.Node) bool {
+		return n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td || n.DataAtom == atom.Blockquote
+	}) {
+		text := collapseSpace(textContent(p))
+		if len(text) < 25 || p.Parent == nil {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		add(p.Parent, score)
+		if p.Parent.Parent != nil {
+			add(p.Parent.Parent, score/2)
+		}
+	}
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range candidates {
+		if score := scores[n] * (1 - linkDensity(n)); score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return findFirst(root, func(n *html.Node) bool { return n.DataAtom == atom.Body })
+}
+
+// linkDensity is the share of an element's text inside links.
+func linkDensity(n *html.Node) float64 {
+	total := textLength(n)
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	for _, a := range findAll(n, func(n *html.Node) bool { return n.DataAtom == atom.A }) {
+		links += textLength(a)
+	}
+	return float64(links) / float64(total)
+}
+
+// renderMarkdown renders the children of an element as a Markdown
+// document.
+func renderMarkdown(n *html.Node) string {
+	return strings.ReplaceAll(normalizeMarkdown(renderBlock(n)), indentMark, " ")
+}
+
+// renderBlock renders the children of a block element as Markdown.
+// Blocks are separated by blank lines, which normalizeMarkdown tidies.
+func renderBlock(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(renderNode(c))
+	}
+	return sb.String()
+}
+
+// renderNode renders a node and its children as Markdown.
+func renderNode(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spaceRun.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return renderBlock(n)
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := collapseSpace(renderBlock(n))
+		if text == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return "\n\n" + strings.Repeat("#", level) + " " + text + "\n\n"
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header, atom.Footer,
+		atom.Figure, atom.Figcaption, atom.Details, atom.Summary, atom.Address:
+		return "\n\n" + strings.TrimSpace(renderBlock(n)) + "\n\n"
+	case atom.Br:
+		return "\n"
+	case atom.Hr:
+		return "\n\n---\n\n"
+	case atom.Ul, atom.Ol:
+		return "\n\n" + renderList(n) + "\n\n"
+	case atom.Dl:
+		return "\n\n" + renderDefinitions(n) + "\n\n"
+	case atom.Blockquote:
+		inner := normalizeMarkdown(renderBlock(n))
+		if inner == "" {
+			return ""
+		}
+		return "\n\n> " + strings.ReplaceAll(inner, "\n", "\n> ") + "\n\n"
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		if code == "" {
+			return ""
+		}
+		return "\n\n```\n" + code + "\n```\n\n"
+	case atom.Table:
+		return "\n\n" + renderTable(n) + "\n\n"
+	case atom.Strong, atom.B:
+		return wrapInline(renderBlock(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(renderBlock(n), "*")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return wrapInline(textContent(n), "`")
+	case atom.A:
+		text := collapseSpace(renderBlock(n))
+		href := strings.TrimSpace(getAttr(n, "href"))
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return text
+		}
+		return "[" + text + "](" + href + ")"
+	case atom.Img:
+		return collapseSpace(getAttr(n, "alt"))
+	}
+	return renderBlock(n)
+}
+
+// wrapInline wraps inline text in a Markdown marker, keeping the spaces
+// around it outside.
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(spaceRun.ReplaceAllString(text, " "))
+	if trimmed == "" {
+		return text
+	}
+	lead, trail := "", ""
+	if strings.HasPrefix(text, " ") {
+		lead = " "
+	}
+	if strings.HasSuffix(text, " ") {
+		trail = " "
+	}
+	return lead + marker + trimmed + marker + trail
+}
+
+// renderList renders the items of a list, numbering those of an <ol>
+// and indenting nested lists under their item.
+func renderList(n *html.Node) string {
+	var items []string
+	number := 1
+	if start, err := strconv.Atoi(getAttr(n, "start")); err == nil {
+		number = start
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		text := normalizeMarkdown(renderBlock(c))
+		if text == "" {
+			continue
+		}
+		lines := strings.Split(text, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = strings.Repeat(indentMark, len(marker)) + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+// renderDefinitions renders a definition list as terms in bold, each
+// followed by its definitions.
+func renderDefinitions(n *html.Node) string {
+	var lines []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		text := collapseSpace(renderBlock(c))
+		if text == "" {
+			continue
+		}
+		switch c.DataAtom {
+		case atom.Dt:
+			lines = append(lines, "**"+text+"**")
+		case atom.Dd:
+			lines = append(lines, ": "+text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderTable renders a table as a Markdown table, with the first row
+// as its header.
+func renderTable(n *html.Node) string {
+	var rows [][]string
+	width := 0
+	for _, tr := range findAll(n, func(n *html.Node) bool { return n.DataAtom == atom.Tr }) {
+		var cells []string
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+				cells = append(cells, strings.ReplaceAll(collapseSpace(renderBlock(c)), "|", `\|`))
+			}
+		}
+		if !emptyRow(cells) {
+			rows = append(rows, cells)
+			width = max(width, len(cells))
+		}
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString(strings.Repeat("| --- ", width) + "|\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+var (
+	spaceRun = regexp.MustCompile(`\s+`)
+	blankRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// indentMark indents the lines of list items until the page is
+// rendered, so normalizeMarkdown can trim stray spaces from the start of
+// lines without losing the nesting. Parsed HTML text never holds NUL.
+const indentMark = "\x00"
+
+// normalizeMarkdown trims the spaces around lines outside code blocks
+// and collapses runs of blank lines.
+func normalizeMarkdown(md string) string {
+	lines := strings.Split(md, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimLeft(line, " "+indentMark), "```") {
+			inCode = !inCode
+			lines[i] = strings.TrimSpace(line)
+			continue
+		}
+		if !inCode {
+			lines[i] = strings.TrimSpace(spaceRun.ReplaceAllString(line, " "))
+		}
+	}
+	return strings.TrimSpace(blankRun.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// collapseSpace joins the words of s with single spaces.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// textContent returns the text within a node.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+// textLength is the length of the words within a node.
+func textLength(n *html.Node) int {
+	return len(collapseSpace(textContent(n)))
+}
+
+// getAttr returns the value of an attribute, or "" when it is absent.
+func getAttr(n *html.Node, key string) string {
+	v, _ := attrValue(n, key)
+	return v
+}
+
+// attrValue returns the value of an attribute and whether it is set.
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// findFirst returns the first node in document order matching match.
+func findFirst(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns the nodes matching match in document order.
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var out []*html.Node
+	if match(n) {
+		out = append(out, n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		out = append(out, findAll(c, match)...)
+	}
+	return out
+}
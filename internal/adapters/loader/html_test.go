@@ -0,0 +1,73 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLLoader_Load(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html><head><title>Release  notes</title><style>body { color: red }</style><script>track()</script></head>
+<body>
+<header><a href="/">Home</a> <a href="/blog">Blog</a></header>
+<nav><ul><li><a href="/docs">Docs</a></li></ul></nav>
+<div class="sidebar-widget">Popular posts</div>
+<div id="content">
+  <h1>Version 2.0</h1>
+  <p>This release brings <strong>faster search</strong>, a new
+     <a href="https://example.com/ui">web UI</a> and <code>--verbose</code> logging.</p>
+  <div class="ad-banner">Buy now!</div>
+  <h2>Changes</h2>
+  <ol><li>Hybrid search<ul><li>BM25 fusion</li></ul></li><li>Streaming answers</li></ol>
+  <pre>make build
+./localrag</pre>
+  <table><tr><th>Model</th><th>Dims</th></tr><tr><td>nomic-embed-text</td><td>768</td></tr></table>
+  <p style="display: none">Hidden text</p>
+</div>
+<footer>Copyright 2024</footer>
+</body></html>`
+	path := filepath.Join(t.TempDir(), "notes.html")
+	if err := os.WriteFile(path, []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := NewHTMLLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	want := "# Version 2.0\n\n" +
+		"This release brings **faster search**, a new [web UI](https://example.com/ui) and `--verbose` logging.\n\n" +
+		"## Changes\n\n" +
+		"1. Hybrid search\n\n   - BM25 fusion\n2. Streaming answers\n\n" +
+		"```\nmake build\n./localrag\n```\n\n" +
+		"| Model | Dims |\n| --- | --- |\n| nomic-embed-text | 768 |"
+	if doc.Content != want {
+		t.Errorf("unexpected content:\n%s", doc.Content)
+	}
+	for _, boilerplate := range []string{"track", "Home", "Docs", "Popular", "Buy now", "Hidden", "Copyright"} {
+		if strings.Contains(doc.Content, boilerplate) {
+			t.Errorf("content kept boilerplate %q", boilerplate)
+		}
+	}
+}
+
+func TestHTMLLoader_PrefersArticle(t *testing.T) {
+	page := `<html><head><title>Post</title></head><body><div class="menu"><p>Links, links, links, and more links here</p></div>
+<article><p>The only article on the page.</p></article>
+<div><p>Unrelated teaser, with commas, many commas, and more commas.</p></div></body></html>`
+	path := filepath.Join(t.TempDir(), "post.htm")
+	if err := os.WriteFile(path, []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := NewHTMLLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Content != "# Post\n\nThe only article on the page." {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+}
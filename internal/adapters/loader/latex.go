@@ -0,0 +1,669 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// maxInputDepth bounds how deeply \input and \include files nest, so a
+// file that includes itself does not loop.
+const maxInputDepth = 16
+
+// LaTeXLoader loads LaTeX sources (.tex) as text: commands are
+// stripped, \input and \include files are read in place, and each
+// section becomes its own section of the document, labeled with the
+// titles it is nested in, such as "Section: Methods > Data".
+type LaTeXLoader struct{}
+
+// NewLaTeXLoader creates a LaTeX loader.
+func NewLaTeXLoader() *LaTeXLoader {
+	return &LaTeXLoader{}
+}
+
+// Load reads a LaTeX file and the files it includes. Of a complete
+// document only the body is read, under its \title; formatting
+// commands keep their text, while references, labels, figures' image
+// files and layout commands are dropped. Citations keep their keys, as
+// in "[knuth84]". Math is kept as written, between $ signs, and
+// verbatim blocks as code.
+func (l *LaTeXLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	src, err := readTeX(path, filepath.Dir(path), 0, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	body := src
+	if begin := strings.Index(src, `\begin{document}`); begin >= 0 {
+		body = src[begin+len(`\begin{document}`):]
+		if end := strings.Index(body, `\end{document}`); end >= 0 {
+			body = body[:end]
+		}
+	}
+	c := &texConverter{}
+	c.write(&c.text, body, true)
+	c.flush()
+	sections := c.sections
+	if title := texTitle(src); title != "" {
+		if len(sections) == 0 || sections[0].Label != "" {
+			sections = append([]entities.Section{{}}, sections...)
+		}
+		sections[0].Content = strings.TrimSpace("# " + title + "\n\n" + sections[0].Content)
+	}
+
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      filepath.Base(path),
+		Path:      path,
+		Content:   entities.JoinSections(sections),
+		Sections:  sections,
+		CreatedAt: info.ModTime(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns file extensions this loader handles.
+func (l *LaTeXLoader) SupportedExtensions() []string {
+	return []string{".tex"}
+}
+
+// texInput matches the commands that read another file in place.
+var texInput = regexp.MustCompile(`\\(?:input|include|subfile)\s*\{([^}]*)\}`)
+
+// readTeX reads the file at path without its comments, with the files
+// it inputs read in place. Names are resolved against root, the main
+// file's directory, as LaTeX does; missing files and loops are left
+// out.
+func readTeX(path, root string, depth int, open map[string]bool) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if open[abs] || depth > maxInputDepth {
+		return "", nil
+	}
+	open[abs] = true
+	defer delete(open, abs)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	src := stripTeXComments(string(data))
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range texInput.FindAllStringSubmatchIndex(src, -1) {
+		sb.WriteString(src[last:m[0]])
+		last = m[1]
+		name := strings.TrimSpace(src[m[2]:m[3]])
+		if name == "" {
+			continue
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(root, filepath.FromSlash(name))
+		}
+		candidates := []string{name}
+		if filepath.Ext(name) != ".tex" {
+			candidates = []string{name + ".tex", name}
+		}
+		for _, candidate := range candidates {
+			text, err := readTeX(candidate, root, depth+1, open)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString("\n" + text + "\n")
+			break
+		}
+	}
+	sb.WriteString(src[last:])
+	return sb.String(), nil
+}
+
+// texVerbatimBegin matches the start of an environment whose lines are
+// taken as they are, comments included.
+var texVerbatimBegin = regexp.MustCompile(`\\begin\{(verbatim\*?|Verbatim|lstlisting|minted)\}`)
+
+// stripTeXComments removes comments, from an unescaped % to the end of
+// the line and its line break, as TeX does, except in verbatim
+// environments.
+func stripTeXComments(src string) string {
+	var sb strings.Builder
+	verbatim := ""
+	for _, line := range strings.SplitAfter(src, "\n") {
+		if verbatim != "" {
+			sb.WriteString(line)
+			if strings.Contains(line, `\end{`+verbatim+`}`) {
+				verbatim = ""
+			}
+			continue
+		}
+		if i := texCommentIndex(line); i >= 0 {
+			line = line[:i]
+		}
+		if m := texVerbatimBegin.FindStringSubmatchIndex(line); m != nil && !strings.Contains(line[m[1]:], `\end{`+line[m[2]:m[3]]+`}`) {
+			verbatim = line[m[2]:m[3]]
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// texCommentIndex returns the index of the % starting a comment in
+// line, or -1.
+func texCommentIndex(line string) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // Skip the escaped character, such as \%
+		case '%':
+			return i
+		}
+	}
+	return -1
+}
+
+// texTitleCommand matches \title, but not commands such as \titleformat.
+var texTitleCommand = regexp.MustCompile(`\\title\b`)
+
+// texTitle returns the text of a document's \title.
+func texTitle(src string) string {
+	loc := texTitleCommand.FindStringIndex(src)
+	if loc == nil {
+		return ""
+	}
+	_, i, _ := texOptArg(src, loc[1])
+	title, _ := texArg(src, i)
+	return collapseSpace((&texConverter{}).inline(title))
+}
+
+// texLevels are the sectioning commands, outermost first.
+var texLevels = map[string]int{"part": 0, "chapter": 1, "section": 2, "subsection": 3, "subsubsection": 4}
+
+// texDropArgs are commands whose arguments are not text, by how many
+// they take.
+var texDropArgs = map[string]int{
+	"label": 1, "ref": 1, "eqref": 1, "pageref": 1, "autoref": 1, "cref": 1, "Cref": 1, "nameref": 1,
+	"usepackage": 1, "RequirePackage": 1, "documentclass": 1, "bibliographystyle": 1, "bibliography": 1,
+	"addbibresource": 1, "nocite": 1, "includegraphics": 1, "graphicspath": 1, "vspace": 1, "hspace": 1,
+	"setlength": 2, "addtolength": 2, "setcounter": 2, "addtocounter": 2, "pagestyle": 1,
+	"thispagestyle": 1, "pagenumbering": 1, "hypersetup": 1, "linespread": 1, "color": 1, "textcolor": 1,
+	"definecolor": 3, "fontsize": 2, "title": 1, "author": 1, "date": 1, "thanks": 1, "input": 1,
+	"include": 1, "includeonly": 1, "newtheorem": 2, "newcounter": 1,
+}
+
+// texSymbols are commands that stand for text.
+var texSymbols = map[string]string{
+	"ldots": "…", "dots": "…", "textellipsis": "…", "LaTeX": "LaTeX", "LaTeXe": "LaTeX2e", "TeX": "TeX",
+	"textendash": "–", "textemdash": "—", "S": "§", "P": "¶", "copyright": "©", "textregistered": "®",
+	"texttrademark": "™", "ss": "ß", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ", "o": "ø", "O": "Ø",
+	"aa": "å", "AA": "Å", "l": "ł", "L": "Ł", "i": "i", "j": "j", "euro": "€", "pounds": "£",
+	"textdegree": "°", "textbackslash": `\`, "textasciitilde": "~", "textbar": "|", "textless": "<",
+	"textgreater": ">", "quad": " ", "qquad": " ", "newline": "\n", "linebreak": "\n", "par": "\n\n",
+}
+
+// texAccents are the letters accent commands apply to, and the accented
+// letters they make.
+var texAccents = map[string][2]string{
+	"'":  {"aeiouyAEIOUYcnszCNSZ", "áéíóúýÁÉÍÓÚÝćńśźĆŃŚŹ"},
+	"`":  {"aeiouAEIOU", "àèìòùÀÈÌÒÙ"},
+	"^":  {"aeiouAEIOU", "âêîôûÂÊÎÔÛ"},
+	"\"": {"aeiouyAEIOU", "äëïöüÿÄËÏÖÜ"},
+	"~":  {"anoANO", "ãñõÃÑÕ"},
+	"c":  {"cCsS", "çÇşŞ"},
+	"v":  {"cszCSZrnRN", "čšžČŠŽřňŘŇ"},
+	"=":  {}, ".": {}, "u": {}, "H": {},
+}
+
+// Environments read specially: verbatim ones as code, math ones as
+// written, skipped ones not at all, and the rest as text after the
+// arguments they take, by how many.
+var (
+	texVerbatimEnvs = map[string]bool{"verbatim": true, "Verbatim": true, "lstlisting": true, "minted": true}
+	texMathEnvs     = map[string]bool{"equation": true, "align": true, "gather": true, "multline": true, "eqnarray": true, "displaymath": true, "math": true, "flalign": true}
+	texSkipEnvs     = map[string]bool{"comment": true, "tikzpicture": true, "pgfpicture": true}
+	texEnvArgs      = map[string]int{"tabular": 1, "tabularx": 2, "tabulary": 2, "array": 1, "longtable": 1, "minipage": 1, "multicols": 1, "wrapfigure": 2, "subfigure": 1}
+)
+
+// texConverter turns LaTeX into text, collecting a section per heading.
+type texConverter struct {
+	sections []entities.Section
+	heads    [5]string // Titles of the enclosing part, chapter, section, subsection and subsubsection
+	label    string
+	text     strings.Builder
+}
+
+// flush ends the current section, dropping it when it holds no text,
+// as with a section whose text is all in its subsections.
+func (c *texConverter) flush() {
+	if content := tidyTeX(c.text.String()); content != "" {
+		c.sections = append(c.sections, entities.Section{Label: c.label, Content: content})
+	}
+	c.text.Reset()
+}
+
+// heading starts a section at level.
+func (c *texConverter) heading(level int, title string) {
+	c.flush()
+	c.heads[level] = title
+	for i := level + 1; i < len(c.heads); i++ {
+		c.heads[i] = ""
+	}
+	c.label = c.headLabel()
+}
+
+// headLabel labels text with the headings it is under.
+func (c *texConverter) headLabel() string {
+	var titles []string
+	for _, h := range c.heads {
+		if h != "" {
+			titles = append(titles, h)
+		}
+	}
+	if len(titles) == 0 {
+		return ""
+	}
+	return "Section: " + strings.Join(titles, " > ")
+}
+
+// inline converts LaTeX within a paragraph, such as a title.
+func (c *texConverter) inline(s string) string {
+	var sb strings.Builder
+	c.write(&sb, s, false)
+	return sb.String()
+}
+
+// write converts s into out. Headings start sections only at the top,
+// when out is the section's text.
+func (c *texConverter) write(out *strings.Builder, s string, top bool) {
+	for i := 0; i < len(s); {
+		switch ch := s[i]; {
+		case ch == '\\':
+			name, next := texCommandName(s, i+1)
+			i = c.command(out, s, name, next, top)
+		case ch == '{':
+			end := texMatchBrace(s, i)
+			c.write(out, s[i+1:end], top)
+			i = end + 1
+		case ch == '}':
+			i++ // Unbalanced
+		case strings.HasPrefix(s[i:], "$$"):
+			end := strings.Index(s[i+2:], "$$")
+			if end < 0 {
+				end = len(s) - i - 2
+			}
+			out.WriteString("\n\n$$" + strings.TrimSpace(s[i+2:i+2+end]) + "$$\n\n")
+			i += 2 + end + 2
+		case ch == '$':
+			end := i + 1
+			for end < len(s) && s[end] != '$' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end, len(s))
+			out.WriteString("$" + s[i+1:end] + "$")
+			i = end + 1
+		case ch == '~':
+			out.WriteByte(' ')
+			i++
+		case ch == '&':
+			out.WriteString(" | ")
+			i++
+		case strings.HasPrefix(s[i:], "``"), strings.HasPrefix(s[i:], "''"):
+			out.WriteByte('"')
+			i += 2
+		case strings.HasPrefix(s[i:], "---"):
+			out.WriteString("—")
+			i += 3
+		case strings.HasPrefix(s[i:], "--"):
+			out.WriteString("–")
+			i += 2
+		default:
+			out.WriteByte(ch)
+			i++
+		}
+	}
+}
+
+// command converts the command name, whose arguments start at i, and
+// returns where the text after it starts.
+func (c *texConverter) command(out *strings.Builder, s, name string, i int, top bool) int {
+	base := strings.TrimSuffix(name, "*")
+	if level, ok := texLevels[base]; ok {
+		_, i, _ = texOptArg(s, i)
+		title, i := texArg(s, i)
+		title = collapseSpace(c.inline(title))
+		if top {
+			c.heading(level, title)
+		} else {
+			out.WriteString(title)
+		}
+		return i
+	}
+	if n, ok := texDropArgs[base]; ok {
+		for ; n > 0; n-- {
+			_, i, _ = texOptArg(s, i)
+			_, i = texArg(s, i)
+		}
+		return i
+	}
+	if symbol, ok := texSymbols[name]; ok {
+		out.WriteString(symbol)
+		return i
+	}
+	if accent, ok := texAccents[name]; ok {
+		letter, next := texArg(s, i)
+		letter = c.inline(letter)
+		if k := strings.Index(accent[0], letter); k >= 0 && len(letter) == 1 {
+			letter = string([]rune(accent[1])[k])
+		}
+		out.WriteString(letter)
+		return next
+	}
+
+	switch {
+	case name == "":
+		return i
+	case name == "begin":
+		env, next := texArg(s, i)
+		return c.environment(out, s, strings.TrimSpace(env), next, top)
+	case name == "end":
+		_, i = texArg(s, i)
+		out.WriteByte('\n')
+	case name == `\`:
+		out.WriteByte('\n')
+		if i < len(s) && s[i] == '*' {
+			i++
+		}
+		_, i, _ = texOptArg(s, i)
+	case name == "item":
+		label, next, ok := texOptArg(s, i)
+		out.WriteString("\n- ")
+		if ok {
+			out.WriteString(collapseSpace(c.inline(label)) + ": ")
+		}
+		return next
+	case name == "bibitem":
+		_, i, _ = texOptArg(s, i)
+		key, next := texArg(s, i)
+		out.WriteString("\n\n[" + strings.TrimSpace(key) + "] ")
+		return next
+	case base == "paragraph" || base == "subparagraph":
+		_, i, _ = texOptArg(s, i)
+		title, next := texArg(s, i)
+		out.WriteString("\n\n**" + collapseSpace(c.inline(title)) + "** ")
+		return next
+	case strings.HasPrefix(base, "cite") || strings.HasSuffix(base, "cite"):
+		_, i, _ = texOptArg(s, i)
+		_, i, _ = texOptArg(s, i)
+		keys, next := texArg(s, i)
+		var list []string
+		for _, k := range strings.Split(keys, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				list = append(list, k)
+			}
+		}
+		out.WriteString("[" + strings.Join(list, ", ") + "]")
+		return next
+	case base == "footnote":
+		_, i, _ = texOptArg(s, i)
+		note, next := texArg(s, i)
+		out.WriteString(" (" + strings.TrimSpace(c.inline(note)) + ")")
+		return next
+	case base == "caption":
+		_, i, _ = texOptArg(s, i)
+		caption, next := texArg(s, i)
+		out.WriteString("\n\n" + strings.TrimSpace(c.inline(caption)) + "\n\n")
+		return next
+	case name == "href":
+		_, i = texArg(s, i)
+		text, next := texArg(s, i)
+		c.write(out, text, false)
+		return next
+	case name == "url":
+		u, next := texArg(s, i)
+		out.WriteString(u)
+		return next
+	case base == "verb":
+		if i >= len(s) {
+			return i
+		}
+		end := strings.IndexByte(s[i+1:], s[i])
+		if end < 0 {
+			return len(s)
+		}
+		out.WriteString("`" + s[i+1:i+1+end] + "`")
+		return i + 1 + end + 1
+	case base == "newcommand" || base == "renewcommand" || base == "providecommand" || base == "DeclareMathOperator":
+		_, i = texArg(s, i)
+		_, i, _ = texOptArg(s, i)
+		_, i, _ = texOptArg(s, i)
+		_, i = texArg(s, i)
+	case base == "newenvironment" || base == "renewenvironment":
+		_, i = texArg(s, i)
+		_, i, _ = texOptArg(s, i)
+		_, i, _ = texOptArg(s, i)
+		_, i = texArg(s, i)
+		_, i = texArg(s, i)
+	case name == "def" || name == "gdef" || name == "edef":
+		if open := strings.IndexByte(s[i:], '{'); open >= 0 {
+			i = texMatchBrace(s, i+open) + 1
+		}
+	case name == "(" || name == "[":
+		closing, delim := `\)`, "$"
+		if name == "[" {
+			closing, delim = `\]`, "$$"
+		}
+		end := strings.Index(s[i:], closing)
+		if end < 0 {
+			end = len(s) - i
+		}
+		math := delim + strings.TrimSpace(s[i:i+end]) + delim
+		if delim == "$$" {
+			math = "\n\n" + math + "\n\n"
+		}
+		out.WriteString(math)
+		return min(i+end+2, len(s))
+	case len(name) == 1 && strings.Contains(`&%$#_{}`, name):
+		out.WriteString(name) // Escaped
+	case len(name) == 1 && strings.Contains(`,;: `, name):
+		out.WriteByte(' ')
+	default:
+		// Formatting and unknown commands: their arguments are text,
+		// and the groups after them are read as such
+		if i < len(s) && s[i] == '[' {
+			_, i, _ = texOptArg(s, i)
+		}
+	}
+	return i
+}
+
+// environment converts the environment env, whose content starts at i,
+// and returns where the text after its end starts.
+func (c *texConverter) environment(out *strings.Builder, s, env string, i int, top bool) int {
+	end, after := texFindEnd(s, i, env)
+	inner := s[i:end]
+	base := strings.TrimSuffix(env, "*")
+	switch {
+	case texVerbatimEnvs[base]:
+		if base == "lstlisting" || base == "minted" {
+			_, j, _ := texOptArg(inner, 0)
+			if base == "minted" {
+				_, j = texArg(inner, j)
+			}
+			inner = inner[j:]
+		}
+		out.WriteString("\n\n```\n" + strings.Trim(inner, "\r\n") + "\n```\n\n")
+	case texMathEnvs[base]:
+		out.WriteString("\n\n$$" + strings.TrimSpace(inner) + "$$\n\n")
+	case texSkipEnvs[base]:
+	case base == "abstract" && top:
+		c.flush()
+		c.label = "Abstract"
+		c.write(out, inner, false)
+		c.flush()
+		c.label = c.headLabel()
+	default:
+		j := 0
+		if n := texEnvArgs[base]; n > 0 || strings.HasPrefix(strings.TrimLeft(inner, " \t"), "[") {
+			_, j, _ = texOptArg(inner, 0)
+			for ; n > 0; n-- {
+				_, j = texArg(inner, j)
+			}
+		}
+		out.WriteByte('\n')
+		c.write(out, inner[j:], top)
+		out.WriteByte('\n')
+	}
+	return after
+}
+
+// texFindEnd returns where the content of environment env, starting at
+// i, ends, and where the text after its \end starts, counting nested
+// environments of the same name.
+func texFindEnd(s string, i int, env string) (end, after int) {
+	begin, finish := `\begin{`+env+`}`, `\end{`+env+`}`
+	depth := 1
+	for j := i; j < len(s); {
+		next := strings.Index(s[j:], finish)
+		if next < 0 {
+			break
+		}
+		if nested := strings.Index(s[j:], begin); nested >= 0 && nested < next {
+			depth++
+			j += nested + len(begin)
+			continue
+		}
+		if depth--; depth == 0 {
+			return j + next, j + next + len(finish)
+		}
+		j += next + len(finish)
+	}
+	return len(s), len(s)
+}
+
+// texCommandName reads the name of the command after a backslash at
+// i-1: a run of letters with an optional star, or a single other
+// character. Spaces after a name of letters are skipped, as TeX does,
+// up to one line break.
+func texCommandName(s string, i int) (string, int) {
+	if i >= len(s) {
+		return "", i
+	}
+	j := i
+	for j < len(s) && (s[j] >= 'a' && s[j] <= 'z' || s[j] >= 'A' && s[j] <= 'Z') {
+		j++
+	}
+	if j == i {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		return s[i : i+size], i + size
+	}
+	if j < len(s) && s[j] == '*' {
+		j++
+	}
+	name := s[i:j]
+	for j < len(s) && (s[j] == ' ' || s[j] == '\t') {
+		j++
+	}
+	if j < len(s) && s[j] == '\n' {
+		j++
+	}
+	return name, j
+}
+
+// texArg reads the argument starting at i, after spaces: a group,
+// without its braces, a command, or a single character.
+func texArg(s string, i int) (string, int) {
+	for i < len(s) && strings.ContainsRune(" \t\r\n", rune(s[i])) {
+		i++
+	}
+	switch {
+	case i >= len(s):
+		return "", i
+	case s[i] == '{':
+		end := texMatchBrace(s, i)
+		return s[i+1 : end], min(end+1, len(s))
+	case s[i] == '\\':
+		_, next := texCommandName(s, i+1)
+		return strings.TrimRight(s[i:next], " \t\n"), next
+	}
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return s[i : i+size], i + size
+}
+
+// texOptArg reads the optional argument in brackets starting at i,
+// after spaces, if there is one.
+func texOptArg(s string, i int) (string, int, bool) {
+	j := i
+	for j < len(s) && strings.ContainsRune(" \t\r\n", rune(s[j])) {
+		j++
+	}
+	if j >= len(s) || s[j] != '[' {
+		return "", i, false
+	}
+	for k := j + 1; k < len(s); k++ {
+		switch s[k] {
+		case '{':
+			k = texMatchBrace(s, k)
+		case ']':
+			return s[j+1 : k], k + 1, true
+		}
+	}
+	return s[j+1:], len(s), true
+}
+
+// texMatchBrace returns the index of the brace closing the group that
+// opens at i, or len(s) when it is not closed.
+func texMatchBrace(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++ // Skip escaped braces
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(s)
+}
+
+// texBlankLines matches the blank lines between paragraphs.
+var texBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// tidyTeX collapses the spaces within lines of converted text, outside
+// code blocks, and the blank lines between paragraphs.
+func tidyTeX(text string) string {
+	lines := strings.Split(text, "\n")
+	code := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			code = !code
+			continue
+		}
+		if !code {
+			lines[i] = collapseSpace(line)
+		}
+	}
+	return strings.TrimSpace(texBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,67 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestLaTeXLoader_Load(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "chapters"), 0755)
+	os.WriteFile(filepath.Join(dir, "paper.tex"), []byte(`\documentclass[11pt]{article}
+\usepackage{amsmath} % math
+\title{Fast Indexes for \emph{Local} Search}
+\begin{document}
+\maketitle
+\begin{abstract}
+We study caching.% joined
+Results hold.
+\end{abstract}
+\section{Introduction}\label{sec:intro}
+Retrieval is \textbf{fast}~\cite{knuth84, lamport94}.
+50\% faster---really\footnote{Measured on a laptop.}.
+\input{chapters/methods}
+\include{chapters/missing}
+\begin{figure}[htbp]
+\centering
+\includegraphics[width=\linewidth]{plot.pdf}
+\caption{Latency by size.}
+\end{figure}
+\end{document}
+Not read.
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "chapters", "methods.tex"), []byte(`\section{Methods}
+\subsection*{Data}
+We use $x^2$ and Caf\'e na\"{\i}ve data.
+\begin{equation}
+E = mc^2
+\end{equation}
+\begin{verbatim}
+  keep   % this
+\end{verbatim}
+\input{methods}
+`), 0644)
+
+	doc, err := NewLaTeXLoader().Load(context.Background(), filepath.Join(dir, "paper.tex"))
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	want := []entities.Section{
+		{Content: "# Fast Indexes for Local Search"},
+		{Label: "Abstract", Content: "We study caching.Results hold."},
+		{Label: "Section: Introduction", Content: "Retrieval is fast [knuth84, lamport94].\n50% faster—really (Measured on a laptop.)."},
+		{Label: "Section: Methods > Data", Content: "We use $x^2$ and Café naïve data.\n\n$$E = mc^2$$\n\n```\n  keep   % this\n```\n\nLatency by size."},
+	}
+	if len(doc.Sections) != len(want) {
+		t.Fatalf("unexpected sections: %+v", doc.Sections)
+	}
+	for i := range want {
+		if doc.Sections[i].Label != want[i].Label || doc.Sections[i].Content != want[i].Content {
+			t.Errorf("section %d = %+v, want %+v", i, doc.Sections[i], want[i])
+		}
+	}
+}
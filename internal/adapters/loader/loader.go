@@ -2,31 +2,41 @@
 package loader
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"io"
-	httpPkg "net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/0xcro3dile/localrag-go/internal/adapters/parser"
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	"golang.org/x/text/encoding"
 )
 
-// TextLoader loads plain text documents (.txt, .md).
-type TextLoader struct{}
+// TextLoader loads plain text documents (.txt, .md), transcoding legacy
+// encodings to UTF-8.
+type TextLoader struct {
+	legacy encoding.Encoding // Encoding of files that are not UTF-8; detected when nil
+}
 
 // NewTextLoader creates a new text document loader.
 func NewTextLoader() *TextLoader {
 	return &TextLoader{}
 }
 
-// Load reads a text document from the given path.
+// Load reads a text document from the given path. Files that are not
+// UTF-8 are transcoded: UTF-16 with a byte order mark, Shift-JIS when
+// the text reads as Japanese, and Windows-1252, a superset of Latin-1,
+// otherwise. The YAML frontmatter
+// of a Markdown file is read rather than indexed: its title names the
+// document, so answers cite it by title, and its title, tags and date
+// become the metadata fields "title", "tags" and "date".
 func (l *TextLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -44,14 +54,28 @@ func (l *TextLoader) Load(ctx context.Context, path string) (*entities.Document,
 		return nil, err
 	}
 
-	return &entities.Document{
+	text := decodeText(content, l.legacy)
+	doc := &entities.Document{
 		ID:        generateDocID(path),
 		Name:      filepath.Base(path),
 		Path:      path,
-		Content:   string(content),
+		Content:   text,
 		CreatedAt: info.ModTime(),
 		UpdatedAt: time.Now(),
-	}, nil
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".md" || ext == ".markdown" {
+		metadata, date, text := splitFrontmatter(doc.Content)
+		if len(metadata) > 0 {
+			doc.Content, doc.Metadata = text, metadata
+		}
+		if title := metadata["title"]; title != "" {
+			doc.Name = title // Cited by its title
+		}
+		if !date.IsZero() {
+			doc.CreatedAt = date
+		}
+	}
+	return doc, nil
 }
 
 // SupportedExtensions returns file extensions this loader handles.
@@ -59,22 +83,47 @@ func (l *TextLoader) SupportedExtensions() []string {
 	return []string{".txt", ".md", ".markdown"}
 }
 
-// PDFLoader loads PDF documents via Python service.
+// ocrMinText is the number of characters, spaces aside, below which a
+// PDF's text layer is taken to be missing, as in a scan, and its pages
+// are read by OCR instead. Scans often carry a little text, such as a
+// stamped page number.
+const ocrMinText = 32
+
+// PDFLoader loads PDF documents with a ports.DocumentParser, falling
+// back to OCR for scans when it has a ports.OCREngine, and describing
+// their figures when it has a ports.ImageDescriber.
 type PDFLoader struct {
-	serviceURL string
+	parser    ports.DocumentParser
+	ocr       ports.OCREngine      // Reads scanned PDFs; nil leaves them empty
+	describer ports.ImageDescriber // Describes figures; nil leaves them out
+	figures   figureExtractor      // Finds the figures to describe
+}
+
+// figureExtractor finds the figures embedded in a PDF, such as
+// parser.PDFImages.
+type figureExtractor interface {
+	Extract(ctx context.Context, data []byte, filename string) ([]parser.PDFFigure, error)
 }
 
-// NewPDFLoader creates a PDF loader that calls Python service.
+// NewPDFLoader creates a PDF loader that extracts text in pure Go, so
+// PDFs load without the Python service.
 func NewPDFLoader() *PDFLoader {
-	return &PDFLoader{serviceURL: "http://localhost:8081"}
+	return &PDFLoader{parser: parser.NewNativePDFParser()}
 }
 
-// NewPDFLoaderWithURL creates a PDF loader with custom service URL.
+// NewPDFLoaderWithURL creates a PDF loader that calls the Python service
+// at url, which extracts complex layouts such as tables more faithfully.
 func NewPDFLoaderWithURL(url string) *PDFLoader {
-	return &PDFLoader{serviceURL: url}
+	return &PDFLoader{parser: parser.NewPythonPDFParser(url)}
 }
 
-// Load reads a PDF via Python service.
+// NewPDFLoaderWithParser creates a PDF loader that extracts text with p.
+func NewPDFLoaderWithParser(p ports.DocumentParser) *PDFLoader {
+	return &PDFLoader{parser: p}
+}
+
+// Load reads a PDF and extracts its text, page by page, so its chunks
+// cite the page they come from. Text read by OCR is not paged.
 func (l *PDFLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
 	// Read PDF file
 	data, err := os.ReadFile(path)
@@ -82,11 +131,24 @@ func (l *PDFLoader) Load(ctx context.Context, path string) (*entities.Document,
 		return nil, err
 	}
 
-	// Call Python service
-	text, err := l.parsePDF(ctx, data)
+	pages, err := l.parser.Parse(ctx, data, filepath.Base(path))
+	sections := pageSections(pages)
+	text := entities.JoinSections(sections)
+	var provenance entities.Provenance
+	// A scan has no text layer to speak of; read its pages by OCR
+	if l.ocr != nil && (err != nil || visibleChars(text) < ocrMinText) {
+		scanned, ocrErr := l.ocr.Recognize(ctx, data, filepath.Base(path))
+		switch {
+		case ocrErr == nil && visibleChars(scanned) > visibleChars(text):
+			text, sections, err = scanned, nil, nil
+			provenance = entities.Provenance{{Kind: entities.TransformOCR, Tool: l.ocr.Tool()}}
+		case ocrErr != nil && err == nil && visibleChars(text) == 0:
+			err = ocrErr
+		}
+	}
 	if err != nil {
 		// Fallback: return empty doc with error note
-		text = "[PDF parsing failed: " + err.Error() + "]"
+		text, sections = "[PDF parsing failed: "+err.Error()+"]", nil
 	}
 
 	info, _ := os.Stat(path)
@@ -95,48 +157,154 @@ func (l *PDFLoader) Load(ctx context.Context, path string) (*entities.Document,
 		modTime = info.ModTime()
 	}
 
-	return &entities.Document{
-		ID:        generateDocID(path),
-		Name:      filepath.Base(path),
-		Path:      path,
-		Content:   text,
-		CreatedAt: modTime,
-		UpdatedAt: time.Now(),
-	}, nil
+	doc := &entities.Document{
+		ID:         generateDocID(path),
+		Name:       filepath.Base(path),
+		Path:       path,
+		Content:    text,
+		Provenance: provenance,
+		Sections:   sections,
+		CreatedAt:  modTime,
+		UpdatedAt:  time.Now(),
+	}
+	// A scan's embedded images are its pages, read by OCR already
+	if l.describer != nil && l.figures != nil && len(provenance) == 0 {
+		l.describeFigures(ctx, doc, data)
+	}
+	return doc, nil
 }
 
-// parsePDF calls Python service for extraction.
-func (l *PDFLoader) parsePDF(ctx context.Context, data []byte) (string, error) {
-	req, err := httpPkg.NewRequestWithContext(ctx, "POST", l.serviceURL+"/parse", bytes.NewReader(data))
+// describeFigures appends a description of each figure of the PDF in
+// data to doc, as a section labeled with its page, such as "Figure on
+// page 3". Figures that cannot be extracted or described are left out,
+// so the text is ingested regardless.
+func (l *PDFLoader) describeFigures(ctx context.Context, doc *entities.Document, data []byte) {
+	figures, err := l.figures.Extract(ctx, data, doc.Name)
 	if err != nil {
-		return "", err
+		return
+	}
+	sections := doc.Sections
+	if sections == nil {
+		sections = []entities.Section{{Content: doc.Content}}
+	}
+	pages := len(sections)
+	for i, figure := range figures {
+		description, err := l.describer.Describe(ctx, figure.Data, fmt.Sprintf("%s, figure %d", doc.Name, i+1))
+		if err != nil || strings.TrimSpace(description) == "" {
+			continue
+		}
+		sections = append(sections, entities.Section{Label: fmt.Sprintf("Figure on page %d", figure.Page), Content: description, Page: figure.Page})
+	}
+	if len(sections) == pages {
+		return
 	}
-	req.Header.Set("Content-Type", "application/octet-stream")
+	doc.Sections = sections
+	doc.Content = entities.JoinSections(sections)
+	doc.Provenance = append(doc.Provenance, entities.Transformation{Kind: entities.TransformCaptioning, Tool: l.describer.Tool()})
+}
 
-	client := &httpPkg.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+// SupportedExtensions returns file extensions.
+func (l *PDFLoader) SupportedExtensions() []string {
+	return []string{".pdf"}
+}
+
+// pageSections returns the pages of a PDF that hold text as unlabeled
+// sections numbered from 1. A lone page is left unnumbered, as parsers
+// that cannot tell pages apart return all the text as one.
+func pageSections(pages []string) []entities.Section {
+	var sections []entities.Section
+	for i, page := range pages {
+		if page = strings.TrimSpace(page); page == "" {
+			continue
+		}
+		section := entities.Section{Content: page}
+		if len(pages) > 1 {
+			section.Page = i + 1
+		}
+		sections = append(sections, section)
 	}
-	defer resp.Body.Close()
+	return sections
+}
 
-	var result struct {
-		Text  string `json:"text"`
-		Error string `json:"error,omitempty"`
+// visibleChars counts the characters of text, spaces aside.
+func visibleChars(text string) int {
+	n := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			n++
+		}
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	return n
+}
+
+// ImageLoader loads images: scanned pages and photos of documents by
+// OCR, and diagrams, charts and photos by their description.
+type ImageLoader struct {
+	ocr       ports.OCREngine      // Reads the image's text; nil skips OCR
+	describer ports.ImageDescriber // Describes the image; nil skips descriptions
+}
+
+// NewImageLoader creates an image loader that reads text with ocr.
+func NewImageLoader(ocr ports.OCREngine) *ImageLoader {
+	return &ImageLoader{ocr: ocr}
+}
+
+// Load recognizes the text of an image. With a describer, the document
+// holds the image's description under "Image: <name>", followed by its
+// text, if any, under "Text in <name>", and records captioning as its
+// provenance.
+func (l *ImageLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
 	}
+	name := filepath.Base(path)
 
-	if result.Error != "" {
-		return "", fmt.Errorf("pdf parse: %s", result.Error)
+	var sections []entities.Section
+	var provenance entities.Provenance
+	if l.describer != nil {
+		description, err := l.describer.Describe(ctx, data, name)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, entities.Section{Label: "Image: " + name, Content: description})
+		provenance = entities.Provenance{{Kind: entities.TransformCaptioning, Tool: l.describer.Tool()}}
+	}
+	var text string
+	if l.ocr != nil {
+		if text, err = l.ocr.Recognize(ctx, data, name); err != nil {
+			return nil, err
+		}
+		if l.describer == nil { // Provenance is a chain; the description leads
+			provenance = entities.Provenance{{Kind: entities.TransformOCR, Tool: l.ocr.Tool()}}
+		}
+	}
+	if len(sections) > 0 {
+		if strings.TrimSpace(text) != "" {
+			sections = append(sections, entities.Section{Label: "Text in " + name, Content: text})
+		}
+		text = entities.JoinSections(sections)
 	}
-	return result.Text, nil
+
+	return &entities.Document{
+		ID:         generateDocID(path),
+		Name:       name,
+		Path:       path,
+		Content:    text,
+		Provenance: provenance,
+		Sections:   sections,
+		CreatedAt:  info.ModTime(),
+		UpdatedAt:  time.Now(),
+	}, nil
 }
 
-// SupportedExtensions returns file extensions.
-func (l *PDFLoader) SupportedExtensions() []string {
-	return []string{".pdf"}
+// SupportedExtensions returns file extensions this loader handles.
+func (l *ImageLoader) SupportedExtensions() []string {
+	return []string{".png", ".jpg", ".jpeg", ".tif", ".tiff"}
 }
 
 // MultiLoader combines multiple loaders.
@@ -146,14 +314,125 @@ type MultiLoader struct {
 
 // NewMultiLoader creates a loader that handles multiple file types.
 func NewMultiLoader() *MultiLoader {
-	return &MultiLoader{
+	m := &MultiLoader{
 		loaders: map[string]interface{ Load(context.Context, string) (*entities.Document, error) }{
 			".txt":      NewTextLoader(),
 			".md":       NewTextLoader(),
 			".markdown": NewTextLoader(),
 			".pdf":      NewPDFLoader(),
+			".pptx":     NewPPTXLoader(),
+			".csv":      NewSpreadsheetLoader(),
+			".tsv":      NewSpreadsheetLoader(),
+			".xlsx":     NewSpreadsheetLoader(),
+			".html":     NewHTMLLoader(),
+			".htm":      NewHTMLLoader(),
+			".epub":     NewEPUBLoader(),
+			".eml":      NewEmailLoader(),
+			".mbox":     NewEmailLoader(),
+			".tex":      NewLaTeXLoader(),
+			".json":     NewStructuredLoader(StructuredOptions{}),
+			".jsonl":    NewStructuredLoader(StructuredOptions{}),
+			".ndjson":   NewStructuredLoader(StructuredOptions{}),
+			".yaml":     NewStructuredLoader(StructuredOptions{}),
+			".yml":      NewStructuredLoader(StructuredOptions{}),
 		},
 	}
+	m.SetCodeRoot("")
+	return m
+}
+
+// SetPDFParser sets how PDFs are parsed, such as with
+// parser.NewPythonPDFParser for the Python service. They are parsed in
+// pure Go by default.
+func (m *MultiLoader) SetPDFParser(p ports.DocumentParser) {
+	l := m.pdfLoader()
+	l.parser = p
+	m.loaders[".pdf"] = &l
+}
+
+// SetOCR reads scanned PDFs, whose pages hold no text layer, and images
+// (.png, .jpg, .jpeg, .tif, .tiff) with ocr, recording OCR in their
+// provenance. Without it scans load empty and images are not loaded.
+func (m *MultiLoader) SetOCR(ocr ports.OCREngine) {
+	pdf := m.pdfLoader()
+	pdf.ocr = ocr
+	m.loaders[".pdf"] = &pdf
+	images := m.imageLoader()
+	images.ocr = ocr
+	m.setImageLoader(&images)
+}
+
+// SetImageDescriber describes images (.png, .jpg, .jpeg, .tif, .tiff)
+// and the figures embedded in PDFs with d, such as a multimodal Ollama
+// model, so diagrams and charts are searchable by what they show.
+// Figures are extracted with poppler's pdfimages; images smaller than
+// 100 pixels a side, such as logos, are skipped. Descriptions record
+// captioning in the document's provenance.
+func (m *MultiLoader) SetImageDescriber(d ports.ImageDescriber) {
+	pdf := m.pdfLoader()
+	pdf.describer, pdf.figures = d, parser.NewPDFImages("")
+	m.loaders[".pdf"] = &pdf
+	images := m.imageLoader()
+	images.describer = d
+	m.setImageLoader(&images)
+}
+
+// pdfLoader returns a copy of the PDF loader's configuration.
+func (m *MultiLoader) pdfLoader() PDFLoader {
+	if pdf, ok := m.loaders[".pdf"].(*PDFLoader); ok {
+		return *pdf
+	}
+	return *NewPDFLoader()
+}
+
+// imageLoader returns a copy of the image loader's configuration.
+func (m *MultiLoader) imageLoader() ImageLoader {
+	if images, ok := m.loaders[".png"].(*ImageLoader); ok {
+		return *images
+	}
+	return ImageLoader{}
+}
+
+// setImageLoader loads every image type with l.
+func (m *MultiLoader) setImageLoader(l *ImageLoader) {
+	for _, ext := range l.SupportedExtensions() {
+		m.loaders[ext] = l
+	}
+}
+
+// SetTextEncoding sets the encoding of text and Markdown files that are
+// not UTF-8, such as "windows-1251" or "euc-kr", by its WHATWG name,
+// for legacy files in code pages the loader does not detect.
+func (m *MultiLoader) SetTextEncoding(name string) error {
+	enc, err := lookupEncoding(name)
+	if err != nil {
+		return err
+	}
+	l := &TextLoader{legacy: enc}
+	for _, ext := range l.SupportedExtensions() {
+		m.loaders[ext] = l
+	}
+	return nil
+}
+
+// SetStructuredOptions sets which fields of JSON, JSON Lines and YAML
+// records are indexed, such as only "title" and "body". Every field is
+// indexed by default.
+func (m *MultiLoader) SetStructuredOptions(opts StructuredOptions) {
+	l := NewStructuredLoader(opts)
+	for _, ext := range l.SupportedExtensions() {
+		m.loaders[ext] = l
+	}
+}
+
+// SetCodeRoot sets the directory source files are labeled relative to,
+// such as the root of a repository. Their paths are used as given by
+// default.
+func (m *MultiLoader) SetCodeRoot(root string) {
+	l := NewCodeLoader(root)
+	for _, ext := range l.SupportedExtensions() {
+		m.loaders[ext] = l
+	}
 }
 
 // Load dispatches to the appropriate loader based on extension.
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/parser"
 )
 
 func TestTextLoader_LoadTxtFile(t *testing.T) {
@@ -48,6 +50,33 @@ func TestTextLoader_SupportedExtensions(t *testing.T) {
 	}
 }
 
+func TestTextLoader_Frontmatter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "post.md")
+	os.WriteFile(path, []byte("---\ntitle: Release  Notes\ntags: [go, databases]\ndate: 2024-03-05\ndraft: true\n---\n\n# Notes\n"), 0644)
+
+	doc, err := NewTextLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Name != "Release Notes" || doc.Content != "# Notes\n" {
+		t.Errorf("unexpected document: %q, %q", doc.Name, doc.Content)
+	}
+	if doc.Metadata["title"] != "Release Notes" || doc.Metadata["tags"] != "go, databases" || doc.Metadata["date"] != "2024-03-05" || len(doc.Metadata) != 3 {
+		t.Errorf("unexpected metadata: %v", doc.Metadata)
+	}
+	if doc.CreatedAt.Format("2006-01-02") != "2024-03-05" {
+		t.Errorf("unexpected date: %v", doc.CreatedAt)
+	}
+
+	// A rule at the start of a text file is no frontmatter
+	path = filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("---\ntitle: x\n---\n"), 0644)
+	if doc, _ := NewTextLoader().Load(context.Background(), path); doc.Metadata != nil || doc.Name != "notes.txt" {
+		t.Errorf("text file read as frontmatter: %v", doc.Metadata)
+	}
+}
+
 func TestMultiLoader_DispatchByExtension(t *testing.T) {
 	dir, _ := os.MkdirTemp("", "loader-test-*")
 	defer os.RemoveAll(dir)
@@ -88,3 +117,175 @@ func TestLoader_NonexistentFile(t *testing.T) {
 		t.Error("should error on nonexistent file")
 	}
 }
+
+// stubParser returns its text as the one page of every document, or its
+// pages when set.
+type stubParser struct {
+	text  string
+	pages []string
+}
+
+func (p stubParser) Parse(ctx context.Context, data []byte, filename string) ([]string, error) {
+	if p.pages != nil {
+		return p.pages, nil
+	}
+	return []string{p.text + " from " + filename}, nil
+}
+
+func (p stubParser) SupportedFormats() []string { return []string{"pdf"} }
+
+func TestMultiLoader_SetPDFParser(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4"), 0644)
+
+	m := NewMultiLoader()
+	m.SetPDFParser(stubParser{text: "parsed"})
+	doc, err := m.Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Content != "parsed from report.pdf" {
+		t.Errorf("unexpected content: %s", doc.Content)
+	}
+}
+
+func TestPDFLoader_Pages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4"), 0644)
+
+	l := &PDFLoader{parser: stubParser{pages: []string{"Summary", " ", "Findings"}}}
+	doc, err := l.Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Content != "Summary\n\nFindings" {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+	if len(doc.Sections) != 2 || doc.Sections[0].Page != 1 || doc.Sections[1].Page != 3 || doc.Sections[1].Label != "" {
+		t.Errorf("unexpected sections: %+v", doc.Sections)
+	}
+}
+
+func TestMultiLoader_SetStructuredOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.json")
+	os.WriteFile(path, []byte(`[{"title": "Plan", "secret": "hunter2"}]`), 0644)
+
+	m := NewMultiLoader()
+	m.SetStructuredOptions(StructuredOptions{Fields: []string{"title"}})
+	doc, err := m.Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Content != "[Record 1]\ntitle: Plan" {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+}
+
+// stubOCR recognizes the same text in every document.
+type stubOCR struct{ text string }
+
+func (o stubOCR) Recognize(ctx context.Context, data []byte, filename string) (string, error) {
+	return o.text + " in " + filename, nil
+}
+
+func (o stubOCR) Tool() string { return "stub" }
+
+func TestMultiLoader_SetOCR(t *testing.T) {
+	dir := t.TempDir()
+	scan := filepath.Join(dir, "scan.pdf")
+	os.WriteFile(scan, []byte("%PDF-1.4"), 0644)
+	photo := filepath.Join(dir, "receipt.jpg")
+	os.WriteFile(photo, []byte{0xff, 0xd8}, 0644)
+
+	m := NewMultiLoader()
+	m.SetPDFParser(stubParser{text: "3"}) // Little more than a page number
+	m.SetOCR(stubOCR{text: "Recognized invoice text"})
+	for path, want := range map[string]string{
+		scan:  "Recognized invoice text in scan.pdf",
+		photo: "Recognized invoice text in receipt.jpg",
+	} {
+		doc, err := m.Load(context.Background(), path)
+		if err != nil {
+			t.Fatalf("load failed: %v", err)
+		}
+		if doc.Content != want {
+			t.Errorf("unexpected content: %q", doc.Content)
+		}
+		if doc.Provenance.String() != "ocr (stub)" {
+			t.Errorf("unexpected provenance: %q", doc.Provenance)
+		}
+	}
+}
+
+func TestPDFLoader_TextLayerSkipsOCR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4"), 0644)
+
+	l := &PDFLoader{parser: stubParser{text: "A report with a full text layer"}, ocr: stubOCR{text: "OCR"}}
+	doc, err := l.Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Content != "A report with a full text layer from report.pdf" || doc.Provenance.MachineDerived() {
+		t.Errorf("unexpected document: %q, %q", doc.Content, doc.Provenance)
+	}
+}
+
+// stubDescriber describes every image the same way.
+type stubDescriber struct{}
+
+func (stubDescriber) Describe(ctx context.Context, data []byte, filename string) (string, error) {
+	return "A diagram in " + filename, nil
+}
+
+func (stubDescriber) Tool() string { return "llava" }
+
+// stubFigures finds a figure on each of its pages.
+type stubFigures []int
+
+func (f stubFigures) Extract(ctx context.Context, data []byte, filename string) ([]parser.PDFFigure, error) {
+	var figures []parser.PDFFigure
+	for _, page := range f {
+		figures = append(figures, parser.PDFFigure{Page: page, Data: []byte("png")})
+	}
+	return figures, nil
+}
+
+func TestMultiLoader_SetImageDescriber(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "architecture.png")
+	os.WriteFile(path, []byte{0x89, 'P', 'N', 'G'}, 0644)
+
+	m := NewMultiLoader()
+	m.SetOCR(stubOCR{text: "API"})
+	m.SetImageDescriber(stubDescriber{})
+	doc, err := m.Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	want := "[Image: architecture.png]\nA diagram in architecture.png\n\n[Text in architecture.png]\nAPI in architecture.png"
+	if doc.Content != want {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+	if doc.Provenance.String() != "captioning (llava)" {
+		t.Errorf("unexpected provenance: %q", doc.Provenance)
+	}
+}
+
+func TestPDFLoader_DescribesFigures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paper.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4"), 0644)
+
+	l := &PDFLoader{parser: stubParser{text: "Results"}, describer: stubDescriber{}, figures: stubFigures{2, 5}}
+	doc, err := l.Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	want := "Results from paper.pdf\n\n[Figure on page 2]\nA diagram in paper.pdf, figure 1\n\n[Figure on page 5]\nA diagram in paper.pdf, figure 2"
+	if doc.Content != want || len(doc.Sections) != 3 {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+	if doc.Provenance.String() != "captioning (llava)" {
+		t.Errorf("unexpected provenance: %q", doc.Provenance)
+	}
+}
@@ -0,0 +1,80 @@
+package loader
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Office Open XML files (.pptx, .xlsx) are zip archives of XML parts
+// linked by relationship parts; these helpers read them. EPUB books are
+// zip archives of XML parts too, and share decodePart.
+
+// relTarget is the part a relationship points to.
+type relTarget struct {
+	kind string // Relationship type URI
+	path string // Part name within the archive
+}
+
+// partRels reads the relationships of a part, keyed by ID, with their
+// targets resolved against the part's directory.
+func partRels(files map[string]*zip.File, part string) (map[string]relTarget, error) {
+	dir, base := path.Split(part)
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(files, dir+"_rels/"+base+".rels", &rels); err != nil {
+		if errors.As(err, new(errMissingPart)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	out := make(map[string]relTarget, len(rels.Rels))
+	for _, r := range rels.Rels {
+		target := path.Clean(path.Join(dir, r.Target))
+		if strings.HasPrefix(r.Target, "/") {
+			target = strings.TrimPrefix(r.Target, "/")
+		}
+		out[r.ID] = relTarget{kind: r.Type, path: target}
+	}
+	return out, nil
+}
+
+// errMissingPart reports a part absent from the archive.
+type errMissingPart string
+
+func (e errMissingPart) Error() string { return "missing part " + string(e) }
+
+// decodePart decodes the XML part name into v.
+func decodePart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return errMissingPart(name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return nil
+}
+
+// attr returns the value of the attribute local of an element.
+func attr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
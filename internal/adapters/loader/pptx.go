@@ -0,0 +1,239 @@
+package loader
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// PPTXLoader loads PowerPoint presentations (.pptx), one section per
+// slide with its title, text and speaker notes, so answers cite slides
+// by number.
+type PPTXLoader struct{}
+
+// NewPPTXLoader creates a PowerPoint loader.
+func NewPPTXLoader() *PPTXLoader {
+	return &PPTXLoader{}
+}
+
+// Load reads the slides of a presentation in order. Each becomes a
+// section labeled "Slide N: title", holding its other text a paragraph
+// per line, then its speaker notes. Slides without any text are left
+// out.
+func (l *PPTXLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening presentation: %w", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	slides, err := slideOrder(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []entities.Section
+	for i, slide := range slides {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		section, ok, err := readSlide(files, slide, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			sections = append(sections, section)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      filepath.Base(path),
+		Path:      path,
+		Content:   entities.JoinSections(sections),
+		Sections:  sections,
+		CreatedAt: info.ModTime(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns file extensions this loader handles.
+func (l *PPTXLoader) SupportedExtensions() []string {
+	return []string{".pptx"}
+}
+
+// slideOrder lists the slide parts in presentation order, from the
+// slide list in ppt/presentation.xml, or by number when it is missing.
+func slideOrder(files map[string]*zip.File) ([]string, error) {
+	var pres struct {
+		Slides []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := decodePart(files, "ppt/presentation.xml", &pres); err == nil && len(pres.Slides) > 0 {
+		rels, err := partRels(files, "ppt/presentation.xml")
+		if err != nil {
+			return nil, err
+		}
+		slides := make([]string, 0, len(pres.Slides))
+		for _, s := range pres.Slides {
+			if target, ok := rels[s.RID]; ok {
+				slides = append(slides, target.path)
+			}
+		}
+		return slides, nil
+	}
+
+	var slides []string
+	for name := range files {
+		if strings.HasPrefix(name, "ppt/slides/slide") && strings.HasSuffix(name, ".xml") {
+			slides = append(slides, name)
+		}
+	}
+	if len(slides) == 0 {
+		return nil, fmt.Errorf("not a PowerPoint presentation: no slides")
+	}
+	number := func(name string) int {
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "ppt/slides/slide"), ".xml"))
+		return n
+	}
+	sort.Slice(slides, func(i, j int) bool { return number(slides[i]) < number(slides[j]) })
+	return slides, nil
+}
+
+// readSlide extracts the title, text and speaker notes of a slide. It
+// reports whether the slide has any.
+func readSlide(files map[string]*zip.File, slide string, number int) (entities.Section, bool, error) {
+	paragraphs, err := slideText(files, slide)
+	if err != nil {
+		return entities.Section{}, false, err
+	}
+	var title string
+	var body []string
+	for _, p := range paragraphs {
+		switch {
+		case p.placeholder == "title" || p.placeholder == "ctrTitle":
+			title = strings.TrimSpace(title + " " + p.text)
+		case p.placeholder == "sldNum" || p.placeholder == "dt" || p.placeholder == "ftr":
+		default:
+			body = append(body, p.text)
+		}
+	}
+
+	// Speaker notes live in a notes part the slide links to
+	rels, err := partRels(files, slide)
+	if err != nil {
+		return entities.Section{}, false, err
+	}
+	var notes []string
+	for _, rel := range rels {
+		if !strings.HasSuffix(rel.kind, "/notesSlide") {
+			continue
+		}
+		paragraphs, err := slideText(files, rel.path)
+		if err != nil {
+			return entities.Section{}, false, err
+		}
+		for _, p := range paragraphs {
+			if p.placeholder == "body" {
+				notes = append(notes, p.text)
+			}
+		}
+	}
+	if len(notes) > 0 {
+		body = append(body, "Speaker notes: "+strings.Join(notes, " "))
+	}
+
+	label := "Slide " + strconv.Itoa(number)
+	if title != "" {
+		label += ": " + title
+	}
+	return entities.Section{Label: label, Content: strings.Join(body, "\n")}, title != "" || len(body) > 0, nil
+}
+
+// slideParagraph is a paragraph of text on a slide or notes page, with
+// the placeholder type of its shape, such as "title" or "body".
+type slideParagraph struct {
+	placeholder string
+	text        string
+}
+
+// slideText returns the non-empty paragraphs of a slide or notes part,
+// in document order, including those of tables and groups.
+func slideText(files map[string]*zip.File, name string) ([]slideParagraph, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	var paragraphs []slideParagraph
+	var placeholder string
+	var text strings.Builder
+	inText := false
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return paragraphs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				placeholder = ""
+			case "ph":
+				placeholder = attr(t, "type")
+				if placeholder == "" {
+					placeholder = "body" // The default placeholder type
+				}
+			case "p":
+				text.Reset()
+			case "t":
+				inText = true
+			case "br":
+				text.WriteString(" ")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if s := strings.Join(strings.Fields(text.String()), " "); s != "" {
+					paragraphs = append(paragraphs, slideParagraph{placeholder: placeholder, text: s})
+				}
+			case "sp":
+				placeholder = ""
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	pptxNS   = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	relsNS   = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+	relSlide = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+	relNotes = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide"
+)
+
+// pptxShape is a shape with a placeholder type and its paragraphs.
+func pptxShape(placeholder string, paragraphs ...string) string {
+	ph := ""
+	if placeholder != "" {
+		ph = `<p:ph type="` + placeholder + `"/>`
+	}
+	var body strings.Builder
+	for _, p := range paragraphs {
+		body.WriteString(`<a:p><a:r><a:t>` + p + `</a:t></a:r></a:p>`)
+	}
+	return `<p:sp><p:nvSpPr><p:cNvPr id="2" name="s"/><p:cNvSpPr/><p:nvPr>` + ph + `</p:nvPr></p:nvSpPr><p:txBody>` + body.String() + `</p:txBody></p:sp>`
+}
+
+func writeZip(t *testing.T, path string, parts map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestPPTXLoader_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deck.pptx")
+	slide := func(shapes ...string) string {
+		return `<p:sld ` + pptxNS + `><p:cSld><p:spTree>` + strings.Join(shapes, "") + `</p:spTree></p:cSld></p:sld>`
+	}
+	// slide2.xml is shown first, slide3.xml is empty
+	writeZip(t, path, map[string]string{
+		"ppt/presentation.xml": `<p:presentation ` + pptxNS + `><p:sldIdLst><p:sldId id="256" r:id="rId2"/><p:sldId id="257" r:id="rId1"/><p:sldId id="258" r:id="rId3"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships ` + relsNS + `>` +
+			`<Relationship Id="rId1" Type="` + relSlide + `" Target="slides/slide1.xml"/>` +
+			`<Relationship Id="rId2" Type="` + relSlide + `" Target="slides/slide2.xml"/>` +
+			`<Relationship Id="rId3" Type="` + relSlide + `" Target="slides/slide3.xml"/></Relationships>`,
+		"ppt/slides/slide1.xml":            slide(pptxShape("title", "Roadmap"), pptxShape("", "Ship v2 in Q3", "Hire two engineers"), pptxShape("sldNum", "2")),
+		"ppt/slides/slide2.xml":            slide(pptxShape("ctrTitle", "Quarterly Review"), pptxShape("subTitle", "Finance team")),
+		"ppt/slides/slide3.xml":            slide(),
+		"ppt/slides/_rels/slide1.xml.rels": `<Relationships ` + relsNS + `><Relationship Id="rId2" Type="` + relNotes + `" Target="../notesSlides/notesSlide1.xml"/></Relationships>`,
+		"ppt/notesSlides/notesSlide1.xml":  `<p:notes ` + pptxNS + `><p:cSld><p:spTree>` + pptxShape("sldImg") + pptxShape("body", "Mention the hiring freeze.") + pptxShape("sldNum", "2") + `</p:spTree></p:cSld></p:notes>`,
+	})
+
+	doc, err := NewPPTXLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(doc.Sections) != 2 {
+		t.Fatalf("expected 2 slides with text, got %+v", doc.Sections)
+	}
+	if doc.Sections[0].Label != "Slide 1: Quarterly Review" || doc.Sections[0].Content != "Finance team" {
+		t.Errorf("unexpected first slide: %+v", doc.Sections[0])
+	}
+	want := "Ship v2 in Q3\nHire two engineers\nSpeaker notes: Mention the hiring freeze."
+	if doc.Sections[1].Label != "Slide 2: Roadmap" || doc.Sections[1].Content != want {
+		t.Errorf("unexpected second slide: %+v", doc.Sections[1])
+	}
+	if !strings.HasPrefix(doc.Content, "[Slide 1: Quarterly Review]\nFinance team\n\n[Slide 2: Roadmap]") {
+		t.Errorf("unexpected content: %q", doc.Content)
+	}
+}
+
+func TestPPTXLoader_NotAPresentation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.pptx")
+	os.WriteFile(path, []byte("not a zip"), 0644)
+	if _, err := NewPPTXLoader().Load(context.Background(), path); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,517 @@
+package loader
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// SpreadsheetLoader loads tabular files (.csv, .tsv, .xlsx). Each row
+// becomes a textual record naming its columns, such as
+// "Row 4: SKU: A-113; Name: Widget; Stock: 12", using the first
+// non-empty row as the headers, and chunks hold whole rows.
+type SpreadsheetLoader struct{}
+
+// NewSpreadsheetLoader creates a spreadsheet loader.
+func NewSpreadsheetLoader() *SpreadsheetLoader {
+	return &SpreadsheetLoader{}
+}
+
+// Load reads a CSV or TSV file as one section of records, or an Excel
+// workbook as one section per sheet, labeled "Sheet: name". Row numbers
+// are those a spreadsheet shows, counting the header row as 1, or for
+// delimited files the line each row starts on.
+func (l *SpreadsheetLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	var sections []entities.Section
+	var err error
+	if strings.ToLower(filepath.Ext(path)) == ".xlsx" {
+		sections, err = loadWorkbook(ctx, path)
+	} else {
+		sections, err = loadDelimited(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      filepath.Base(path),
+		Path:      path,
+		Content:   entities.JoinSections(sections),
+		Sections:  sections,
+		CreatedAt: info.ModTime(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns file extensions this loader handles.
+func (l *SpreadsheetLoader) SupportedExtensions() []string {
+	return []string{".csv", ".tsv", ".xlsx"}
+}
+
+// sheetRow is a row of cells with its 1-based row number.
+type sheetRow struct {
+	number int
+	cells  []string
+}
+
+// tableSection renders rows as a section of records, one per line, each
+// value named by its column header. Columns without a header are named
+// by their letter. It reports whether any row has a value.
+func tableSection(label string, rows []sheetRow) (entities.Section, bool) {
+	var headers []string
+	var lines []string
+	for _, row := range rows {
+		if headers == nil {
+			if !emptyRow(row.cells) {
+				headers = row.cells
+			}
+			continue
+		}
+		var fields []string
+		for i, cell := range row.cells {
+			if cell == "" {
+				continue
+			}
+			name := ""
+			if i < len(headers) {
+				name = headers[i]
+			}
+			if name == "" {
+				name = "Column " + columnName(i)
+			}
+			fields = append(fields, name+": "+cell)
+		}
+		if len(fields) > 0 {
+			lines = append(lines, "Row "+strconv.Itoa(row.number)+": "+strings.Join(fields, "; "))
+		}
+	}
+	if len(lines) == 0 && headers != nil {
+		// A header row alone still tells what the table is about
+		lines = append(lines, "Columns: "+strings.Join(nonEmpty(headers), "; "))
+	}
+	return entities.Section{Label: label, Content: strings.Join(lines, "\n"), Records: true}, len(lines) > 0
+}
+
+// emptyRow reports whether a row has no values.
+func emptyRow(cells []string) bool {
+	for _, c := range cells {
+		if c != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// nonEmpty returns the non-empty values.
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// cleanCell collapses whitespace, including line breaks within a cell,
+// so each record stays on one line.
+func cleanCell(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// columnName returns the spreadsheet letter of the 0-based column i,
+// such as "A" or "AB".
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// loadDelimited reads a CSV or TSV file. The delimiter of a .csv file is
+// whichever of comma, semicolon or tab its first line uses most, as
+// exports from European locales separate with semicolons.
+func loadDelimited(path string) ([]entities.Section, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if bom, _ := br.Peek(3); string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+	comma := '\t'
+	if strings.ToLower(filepath.Ext(path)) != ".tsv" {
+		first, _ := br.Peek(4096)
+		if i := strings.IndexByte(string(first), '\n'); i >= 0 {
+			first = first[:i]
+		}
+		comma = ','
+		for _, c := range []rune{';', '\t'} {
+			if strings.Count(string(first), string(c)) > strings.Count(string(first), string(comma)) {
+				comma = c
+			}
+		}
+	}
+
+	r := csv.NewReader(br)
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	var rows []sheetRow
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+		}
+		for i := range record {
+			record[i] = cleanCell(record[i])
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, sheetRow{number: line, cells: record})
+	}
+
+	section, ok := tableSection("", rows)
+	if !ok {
+		return nil, nil
+	}
+	return []entities.Section{section}, nil
+}
+
+// loadWorkbook reads the sheets of an Excel workbook in tab order.
+func loadWorkbook(ctx context.Context, path string) ([]entities.Section, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening workbook: %w", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var workbook struct {
+		Props struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(files, "xl/workbook.xml", &workbook); err != nil {
+		if errors.As(err, new(errMissingPart)) {
+			return nil, fmt.Errorf("not an Excel workbook: %w", err)
+		}
+		return nil, err
+	}
+	rels, err := partRels(files, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	strs, err := sharedStrings(files)
+	if err != nil {
+		return nil, err
+	}
+	styles, err := dateStyles(files)
+	if err != nil {
+		return nil, err
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if p := workbook.Props.Date1904; p == "1" || p == "true" {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var sections []entities.Section
+	for _, sheet := range workbook.Sheets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		target, ok := rels[sheet.RID]
+		if !ok {
+			continue
+		}
+		rows, err := sheetRows(files, target.path, strs, styles, epoch)
+		if err != nil {
+			return nil, err
+		}
+		if section, ok := tableSection("Sheet: "+sheet.Name, rows); ok {
+			sections = append(sections, section)
+		}
+	}
+	return sections, nil
+}
+
+// sharedStrings reads the workbook's string table, which cells of type
+// "s" index into.
+func sharedStrings(files map[string]*zip.File) ([]string, error) {
+	const name = "xl/sharedStrings.xml"
+	f, ok := files[name]
+	if !ok {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	var strs []string
+	var text strings.Builder
+	inText, inPhonetic := false, false
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				text.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				inPhonetic = true // Reading aids for East Asian text, not part of the value
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, cleanCell(text.String()))
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText && !inPhonetic {
+				text.Write(t)
+			}
+		}
+	}
+}
+
+// dateStyles reports which cell styles, by index, format numbers as
+// dates or times, since a workbook stores those as day counts.
+func dateStyles(files map[string]*zip.File) (map[int]bool, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		Xfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodePart(files, "xl/styles.xml", &styles); err != nil {
+		if errors.As(err, new(errMissingPart)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	dateFormats := make(map[int]bool)
+	for _, id := range []int{14, 15, 16, 17, 18, 19, 20, 21, 22, 45, 46, 47} {
+		dateFormats[id] = true // Built-in date and time formats
+	}
+	for _, f := range styles.NumFmts {
+		dateFormats[f.ID] = isDateFormat(f.Code)
+	}
+	out := make(map[int]bool)
+	for i, xf := range styles.Xfs {
+		if dateFormats[xf.NumFmtID] {
+			out[i] = true
+		}
+	}
+	return out, nil
+}
+
+// isDateFormat reports whether a number format code shows a date or
+// time, ignoring quoted text and bracketed colors or locales.
+func isDateFormat(code string) bool {
+	var sb strings.Builder
+	quoted, bracketed := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '[':
+			bracketed = true
+		case r == ']':
+			bracketed = false
+		case !bracketed:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.ContainsAny(sb.String(), "ydh") || strings.Contains(sb.String(), "ss")
+}
+
+// sheetRows reads the rows of a worksheet part, placing each cell in its
+// column by its reference, such as "C7".
+func sheetRows(files map[string]*zip.File, name string, strs []string, dates map[int]bool, epoch time.Time) ([]sheetRow, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	var rows []sheetRow
+	var row *sheetRow
+	var kind, value string
+	var style, col int
+	var text strings.Builder
+	inValue, inText := false, false
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				number := len(rows) + 1
+				if n, err := strconv.Atoi(attr(t, "r")); err == nil {
+					number = n
+				}
+				rows = append(rows, sheetRow{number: number})
+				row = &rows[len(rows)-1]
+				col = -1
+			case "c":
+				kind, value = attr(t, "t"), ""
+				style, _ = strconv.Atoi(attr(t, "s"))
+				text.Reset()
+				if c, ok := columnIndex(attr(t, "r")); ok {
+					col = c
+				} else {
+					col++
+				}
+			case "v":
+				inValue = true
+			case "t":
+				inText = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v":
+				inValue = false
+			case "t":
+				inText = false
+			case "c":
+				if row == nil || col < 0 {
+					continue
+				}
+				if kind == "inlineStr" {
+					value = text.String()
+				}
+				cell := cellValue(kind, value, strs, dates[style], epoch)
+				if cell == "" {
+					continue
+				}
+				for len(row.cells) <= col {
+					row.cells = append(row.cells, "")
+				}
+				row.cells[col] = cell
+			}
+		case xml.CharData:
+			switch {
+			case inValue:
+				value += string(t)
+			case inText:
+				text.Write(t)
+			}
+		}
+	}
+}
+
+// columnIndex returns the 0-based column of a cell reference such as
+// "AB12".
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+// cellValue renders a cell's stored value as text.
+func cellValue(kind, value string, strs []string, date bool, epoch time.Time) string {
+	switch kind {
+	case "s":
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 || i >= len(strs) {
+			return ""
+		}
+		return strs[i]
+	case "b":
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "inlineStr", "str", "e":
+		return cleanCell(value)
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return cleanCell(value)
+	}
+	if date {
+		days, frac := math.Modf(n)
+		t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(math.Round(frac*86400)) * time.Second)
+		switch {
+		case frac == 0:
+			return t.Format("2006-01-02")
+		case days == 0:
+			return t.Format("15:04:05")
+		default:
+			return t.Format("2006-01-02 15:04:05")
+		}
+	}
+	// Workbooks store binary floats such as 0.30000000000000004; show
+	// the 15 significant digits a spreadsheet does
+	n, _ = strconv.ParseFloat(strconv.FormatFloat(n, 'g', 15, 64), 64)
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
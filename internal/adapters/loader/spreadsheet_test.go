@@ -0,0 +1,73 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpreadsheetLoader_CSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.csv")
+	data := "\xef\xbb\xbfSKU;Name;Stock;\n\nA-113;Widget;12;\nB-7;\"Gear,\nlarge\";;spare\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := NewSpreadsheetLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(doc.Sections) != 1 || !doc.Sections[0].Records {
+		t.Fatalf("expected one section of records, got %+v", doc.Sections)
+	}
+	want := "Row 3: SKU: A-113; Name: Widget; Stock: 12\nRow 4: SKU: B-7; Name: Gear, large; Column D: spare"
+	if doc.Sections[0].Content != want {
+		t.Errorf("unexpected records:\n%s", doc.Sections[0].Content)
+	}
+}
+
+func TestSpreadsheetLoader_XLSX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.xlsx")
+	sheet := func(rows string) string {
+		return `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + rows + `</sheetData></worksheet>`
+	}
+	writeZip(t, path, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Orders" sheetId="1" r:id="rId2"/><sheet name="Empty" sheetId="2" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships ` + relsNS + `>` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Customer</t></si><si><t>Date</t></si><si><t>Total</t></si>` +
+			`<si><r><t>Acme </t></r><r><t>Corp</t></r><rPh><t>アクメ</t></rPh></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts><numFmt numFmtId="164" formatCode="[$-409]d\-mmm\-yy"/><numFmt numFmtId="165" formatCode="&quot;$&quot;#,##0.00"/></numFmts>` +
+			`<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>3</v></c><c r="B2" s="1"><v>45292</v></c><c r="C2" s="2"><v>0.30000000000000004</v></c><c r="E2" t="b"><v>1</v></c></row>` +
+			`<row r="5"><c r="A5" t="inlineStr"><is><t>Globex</t></is></c><c r="C5"><v>1200</v></c></row>`),
+		"xl/worksheets/sheet2.xml": sheet(``),
+	})
+
+	doc, err := NewSpreadsheetLoader().Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(doc.Sections) != 1 {
+		t.Fatalf("expected only the sheet with rows, got %+v", doc.Sections)
+	}
+	if doc.Sections[0].Label != "Sheet: Orders" {
+		t.Errorf("unexpected label: %q", doc.Sections[0].Label)
+	}
+	want := "Row 2: Customer: Acme Corp; Date: 2024-01-01; Total: 0.3; Column E: TRUE\nRow 5: Customer: Globex; Total: 1200"
+	if doc.Sections[0].Content != want {
+		t.Errorf("unexpected records:\n%s", doc.Sections[0].Content)
+	}
+}
+
+func TestSpreadsheetLoader_NotAWorkbook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.xlsx")
+	writeZip(t, path, map[string]string{"word/document.xml": "<w:document/>"})
+	if _, err := NewSpreadsheetLoader().Load(context.Background(), path); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,375 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"gopkg.in/yaml.v3"
+)
+
+// StructuredOptions chooses what a StructuredLoader indexes.
+type StructuredOptions struct {
+	// Fields are the dotted paths of the fields to index, such as
+	// "title", "body" or "author.name". Empty indexes every field.
+	Fields []string
+	// Records is the dotted path of the array holding the records, such
+	// as "data.items". Empty takes a top-level array's elements, or else
+	// the whole file, as the records. JSON Lines files hold one record
+	// per line.
+	Records string
+}
+
+// StructuredLoader loads structured data (.json, .jsonl, .ndjson, .yaml,
+// .yml), flattening each record into lines of "field: value", so an
+// export such as a ticket dump becomes one section per record.
+type StructuredLoader struct {
+	opts StructuredOptions
+}
+
+// NewStructuredLoader creates a structured data loader.
+func NewStructuredLoader(opts StructuredOptions) *StructuredLoader {
+	return &StructuredLoader{opts: opts}
+}
+
+// Load reads the records of a file, each becoming a section labeled
+// "Record N", N counting from 1, or the line number in JSON Lines
+// files. Chunks never span two records. Records without any of the
+// chosen fields are left out.
+func (l *StructuredLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []structuredRecord
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		records, err = l.jsonLines(ctx, data)
+	case ".yaml", ".yml":
+		records, err = l.yamlRecords(data)
+	default:
+		var v interface{}
+		if v, err = decodeJSON(json.NewDecoder(bytes.NewReader(data))); err == nil {
+			records, err = l.records(v)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+	}
+
+	var sections []entities.Section
+	for _, r := range records {
+		if text := l.flatten(r.value); text != "" {
+			sections = append(sections, entities.Section{Label: "Record " + strconv.Itoa(r.number), Content: text})
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.Document{
+		ID:        generateDocID(path),
+		Name:      filepath.Base(path),
+		Path:      path,
+		Content:   entities.JoinSections(sections),
+		Sections:  sections,
+		CreatedAt: info.ModTime(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns file extensions this loader handles.
+func (l *StructuredLoader) SupportedExtensions() []string {
+	return []string{".json", ".jsonl", ".ndjson", ".yaml", ".yml"}
+}
+
+// Decoded values are kept in document order: objects as []objectField,
+// arrays as []interface{}, and scalars as strings, json.Number, bools
+// or nil.
+type objectField struct {
+	key   string
+	value interface{}
+}
+
+// structuredRecord is a record with its number.
+type structuredRecord struct {
+	number int
+	value  interface{}
+}
+
+// jsonLines reads one record per non-blank line.
+func (l *StructuredLoader) jsonLines(ctx context.Context, data []byte) ([]structuredRecord, error) {
+	var records []structuredRecord
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; sc.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		v, err := decodeJSON(json.NewDecoder(bytes.NewReader(sc.Bytes())))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, structuredRecord{number: line, value: v})
+	}
+	return records, sc.Err()
+}
+
+// yamlRecords reads the records of each document of a YAML stream.
+func (l *StructuredLoader) yamlRecords(data []byte) ([]structuredRecord, error) {
+	var records []structuredRecord
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		docRecords, err := l.records(yamlValue(&doc))
+		if err != nil {
+			return nil, err
+		}
+		offset := len(records)
+		for _, r := range docRecords {
+			r.number += offset
+			records = append(records, r)
+		}
+	}
+}
+
+// records picks the records of a decoded file: the elements of the
+// array at opts.Records, or of a top-level array, or the value itself.
+func (l *StructuredLoader) records(v interface{}) ([]structuredRecord, error) {
+	if l.opts.Records != "" {
+		var ok bool
+		if v, ok = lookupPath(v, l.opts.Records); !ok {
+			return nil, fmt.Errorf("no records at %q", l.opts.Records)
+		}
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		if l.opts.Records != "" {
+			return nil, fmt.Errorf("%q is not an array", l.opts.Records)
+		}
+		items = []interface{}{v}
+	}
+	records := make([]structuredRecord, len(items))
+	for i, item := range items {
+		records[i] = structuredRecord{number: i + 1, value: item}
+	}
+	return records, nil
+}
+
+// flatten renders the chosen fields of a record as "path: value" lines.
+func (l *StructuredLoader) flatten(record interface{}) string {
+	var fields []flatField
+	if len(l.opts.Fields) == 0 {
+		fields = flattenValue("", record, nil)
+	}
+	for _, path := range l.opts.Fields {
+		if v, ok := lookupPath(record, path); ok {
+			fields = flattenValue(path, v, fields)
+		}
+	}
+	lines := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f.path == "" {
+			lines = append(lines, f.value)
+		} else {
+			lines = append(lines, f.path+": "+f.value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// flatField is a scalar value with the dotted path leading to it.
+type flatField struct {
+	path  string
+	value string
+}
+
+// flattenValue appends the scalars of v under the dotted path prefix.
+// Nested objects extend the path; arrays join the values each path
+// takes across their elements with commas, as in "tags: go, rag".
+func flattenValue(prefix string, v interface{}, out []flatField) []flatField {
+	switch v := v.(type) {
+	case []objectField:
+		for _, f := range v {
+			out = flattenValue(joinPath(prefix, f.key), f.value, out)
+		}
+		return out
+	case []interface{}:
+		var paths []string // In first-seen order
+		values := make(map[string][]string)
+		for _, item := range v {
+			for _, f := range flattenValue("", item, nil) {
+				if _, ok := values[f.path]; !ok {
+					paths = append(paths, f.path)
+				}
+				values[f.path] = append(values[f.path], f.value)
+			}
+		}
+		for _, path := range paths {
+			out = append(out, flatField{path: joinPath(prefix, path), value: strings.Join(values[path], ", ")})
+		}
+		return out
+	case nil:
+		return out
+	}
+	if value := strings.TrimSpace(scalarString(v)); value != "" {
+		out = append(out, flatField{path: prefix, value: value})
+	}
+	return out
+}
+
+// scalarString renders a scalar value.
+func scalarString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// joinPath joins dotted path segments, either of which may be empty.
+func joinPath(prefix, key string) string {
+	switch {
+	case prefix == "":
+		return key
+	case key == "":
+		return prefix
+	}
+	return prefix + "." + key
+}
+
+// lookupPath returns the value at a dotted path of object keys.
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		fields, ok := v.([]objectField)
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for _, f := range fields {
+			if f.key == key {
+				v, found = f.value, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// decodeJSON decodes one JSON value, keeping object keys in order.
+func decodeJSON(dec *json.Decoder) (interface{}, error) {
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONValue(dec, tok)
+}
+
+// decodeJSONValue decodes the value starting with tok.
+func decodeJSONValue(dec *json.Decoder, tok json.Token) (interface{}, error) {
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	var fields []objectField
+	var items []interface{}
+	for dec.More() {
+		var key string
+		if delim == '{' {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ = keyTok.(string)
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeJSONValue(dec, tok)
+		if err != nil {
+			return nil, err
+		}
+		if delim == '{' {
+			fields = append(fields, objectField{key: key, value: value})
+		} else {
+			items = append(items, value)
+		}
+	}
+	if _, err := dec.Token(); err != nil { // The closing delimiter
+		return nil, err
+	}
+	if delim == '{' {
+		return fields, nil
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	return items, nil
+}
+
+// yamlValue converts a YAML node to the ordered form decodeJSON
+// returns, following aliases and merge keys.
+func yamlValue(n *yaml.Node) interface{} {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return yamlValue(n.Content[0])
+	case yaml.MappingNode:
+		fields := make([]objectField, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], yamlValue(n.Content[i+1])
+			if merged, ok := value.([]objectField); ok && key.Tag == "!!merge" {
+				// A merge key, as in "<<: *defaults", inlines the fields
+				fields = append(fields, merged...)
+				continue
+			}
+			fields = append(fields, objectField{key: key.Value, value: value})
+		}
+		return fields
+	case yaml.SequenceNode:
+		items := make([]interface{}, 0, len(n.Content))
+		for _, c := range n.Content {
+			items = append(items, yamlValue(c))
+		}
+		return items
+	case yaml.AliasNode:
+		if n.Alias != nil {
+			return yamlValue(n.Alias)
+		}
+		return nil
+	}
+	if n.Tag == "!!null" {
+		return nil
+	}
+	return n.Value
+}
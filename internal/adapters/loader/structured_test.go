@@ -0,0 +1,87 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func loadStructured(t *testing.T, name, data string, opts StructuredOptions) []entities.Section {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewStructuredLoader(opts).Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	return doc.Sections
+}
+
+func TestStructuredLoader_JSONLFields(t *testing.T) {
+	data := `{"id": 1, "title": "Login fails", "body": "Users see a 500 error.", "author": {"name": "Ann", "team": "web"}}
+
+{"id": 2, "body": "", "tags": ["sso", "auth"]}
+{"id": 3, "title": "Slow search", "author": {"name": "Bo"}}
+`
+	sections := loadStructured(t, "tickets.jsonl", data, StructuredOptions{Fields: []string{"title", "body", "author.name"}})
+	if len(sections) != 2 {
+		t.Fatalf("expected the records with chosen fields, got %+v", sections)
+	}
+	if sections[0].Label != "Record 1" || sections[0].Content != "title: Login fails\nbody: Users see a 500 error.\nauthor.name: Ann" {
+		t.Errorf("unexpected first record: %+v", sections[0])
+	}
+	if sections[1].Label != "Record 4" || sections[1].Content != "title: Slow search\nauthor.name: Bo" {
+		t.Errorf("unexpected second record: %+v", sections[1])
+	}
+}
+
+func TestStructuredLoader_JSONRecordsPath(t *testing.T) {
+	data := `{"data": {"items": [
+		{"sku": "A-113", "stock": 12, "active": true, "tags": ["metal", "small"], "sizes": [{"w": 1}, {"w": 2}]},
+		{"sku": "B-7", "stock": null}
+	]}}`
+	sections := loadStructured(t, "export.json", data, StructuredOptions{Records: "data.items"})
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 records, got %+v", sections)
+	}
+	if want := "sku: A-113\nstock: 12\nactive: true\ntags: metal, small\nsizes.w: 1, 2"; sections[0].Content != want {
+		t.Errorf("unexpected first record:\n%s", sections[0].Content)
+	}
+	if sections[1].Content != "sku: B-7" {
+		t.Errorf("unexpected second record: %q", sections[1].Content)
+	}
+}
+
+func TestStructuredLoader_YAML(t *testing.T) {
+	data := `defaults: &defaults
+  owner: platform
+services:
+  - name: api
+    <<: *defaults
+  - name: worker
+    owner: data
+`
+	sections := loadStructured(t, "services.yaml", data, StructuredOptions{Records: "services", Fields: []string{"name", "owner"}})
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 records, got %+v", sections)
+	}
+	if sections[0].Content != "name: api\nowner: platform" {
+		t.Errorf("unexpected first record: %q", sections[0].Content)
+	}
+	if sections[1].Content != "name: worker\nowner: data" {
+		t.Errorf("unexpected second record: %q", sections[1].Content)
+	}
+}
+
+func TestStructuredLoader_Malformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.jsonl")
+	os.WriteFile(path, []byte("{\"ok\": 1}\n{broken\n"), 0644)
+	if _, err := NewStructuredLoader(StructuredOptions{}).Load(context.Background(), path); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,198 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/parser"
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// maxFetchBytes bounds the size of a fetched page.
+	maxFetchBytes = 20 << 20
+
+	// fetchUserAgent identifies LocalRAG to the sites it fetches.
+	fetchUserAgent = "localrag-go (+https://github.com/0xcro3dile/localrag-go)"
+)
+
+// URLLoader fetches web pages over HTTP(S). HTML pages keep their main
+// content as Markdown, as with HTMLLoader; plain text, Markdown and PDF
+// responses are loaded too. The page's final URL, after redirects,
+// becomes the document's path, so answers cite it.
+type URLLoader struct {
+	client *http.Client
+	videos ports.DocumentLoader // Loads YouTube videos; nil when unset
+}
+
+// NewURLLoader creates a loader that fetches pages with client, or with
+// a 30-second timeout when client is nil.
+func NewURLLoader(client *http.Client) *URLLoader {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &URLLoader{client: client}
+}
+
+// Load fetches the page at rawURL. The document is named by the page's
+// title, or else the last segment of its URL.
+func (l *URLLoader) Load(ctx context.Context, rawURL string) (*entities.Document, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("not an http(s) URL: %q", rawURL)
+	}
+	if _, ok := YouTubeVideoID(rawURL); ok && l.videos != nil {
+		return l.videos.Load(ctx, rawURL)
+	}
+	doc, _, err := l.fetch(ctx, u)
+	return doc, err
+}
+
+// SetVideoLoader loads YouTube video URLs with videos, such as a
+// YouTubeLoader, so a talk is ingested as its transcript rather than
+// its watch page.
+func (l *URLLoader) SetVideoLoader(videos ports.DocumentLoader) {
+	l.videos = videos
+}
+
+// fetch loads the page at u, and returns the links of an HTML page,
+// resolved against its URL.
+func (l *URLLoader) fetch(ctx context.Context, u *url.URL) (*entities.Document, []*url.URL, error) {
+	resp, data, err := l.get(ctx, u, "text/html,application/xhtml+xml,text/plain;q=0.9,text/markdown;q=0.9,application/pdf;q=0.8")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	final := resp.Request.URL
+	page, err := pageContent(ctx, final, resp.Header.Get("Content-Type"), data)
+	if err != nil {
+		return nil, nil, err
+	}
+	title := page.title
+	if title == "" {
+		title = path.Base(final.Path)
+		if title == "/" || title == "." {
+			title = final.Host
+		}
+	}
+
+	modified := time.Now()
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		modified = t
+	}
+	return &entities.Document{
+		ID:        generateDocID(final.String()),
+		Name:      title,
+		Path:      final.String(),
+		Content:   page.content,
+		Sections:  page.sections,
+		CreatedAt: modified,
+		UpdatedAt: time.Now(),
+	}, page.links, nil
+}
+
+// get fetches u, returning the response and body of a successful
+// request. The response's Request holds the URL after redirects.
+func (l *URLLoader) get(ctx context.Context, u *url.URL, accept string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	req.Header.Set("Accept", accept)
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("fetching %s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", u, err)
+	}
+	if len(data) > maxFetchBytes {
+		return nil, nil, fmt.Errorf("fetching %s: larger than %d MB", u, maxFetchBytes>>20)
+	}
+	return resp, data, nil
+}
+
+// SupportedExtensions returns no extensions: any URL is fetched, and
+// its content type decides how it is read.
+func (l *URLLoader) SupportedExtensions() []string {
+	return nil
+}
+
+// webPage is the text of a fetched page, with the links of HTML pages.
+type webPage struct {
+	title    string
+	content  string
+	sections []entities.Section // Pages of a PDF, so chunks cite them
+	links    []*url.URL
+}
+
+// errUnsupportedContent reports a page of a content type that holds no
+// text to load, such as an image.
+var errUnsupportedContent = errors.New("unsupported content type")
+
+// pageContent extracts the title and text of a fetched page by its
+// content type, or by sniffing when the server names none.
+func pageContent(ctx context.Context, u *url.URL, contentType string, data []byte) (webPage, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		root, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			return webPage{}, fmt.Errorf("parsing %s: %w", u, err)
+		}
+		// Links come first, as boilerplate such as navigation goes
+		links := pageLinks(root, u)
+		return webPage{title: pageTitle(root), content: htmlToMarkdown(root), links: links}, nil
+	case "text/plain", "text/markdown", "text/x-markdown":
+		return webPage{content: string(data)}, nil
+	case "application/pdf":
+		pages, err := parser.NewNativePDFParser().Parse(ctx, data, path.Base(u.Path))
+		sections := pageSections(pages)
+		return webPage{content: entities.JoinSections(sections), sections: sections}, err
+	}
+	return webPage{}, fmt.Errorf("%s: %w %q", u, errUnsupportedContent, mediaType)
+}
+
+// pageLinks returns the targets of a page's links, resolved against its
+// <base> or else its URL, without fragments.
+func pageLinks(root *html.Node, u *url.URL) []*url.URL {
+	base := u
+	if b := findFirst(root, func(n *html.Node) bool { return n.DataAtom == atom.Base }); b != nil {
+		if href, err := u.Parse(getAttr(b, "href")); err == nil {
+			base = href
+		}
+	}
+	var links []*url.URL
+	for _, a := range findAll(root, func(n *html.Node) bool { return n.DataAtom == atom.A }) {
+		href := strings.TrimSpace(getAttr(a, "href"))
+		if href == "" || strings.HasPrefix(href, "#") {
+			continue
+		}
+		if link, err := base.Parse(href); err == nil {
+			link.Fragment, link.RawFragment = "", ""
+			links = append(links, link)
+		}
+	}
+	return links
+}
@@ -0,0 +1,64 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestURLLoader_Page(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/guide", http.StatusMovedPermanently)
+		case "/guide":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.Write([]byte(`<html><head><title>Setup Guide</title></head><body>
+				<nav><a href="/">Home</a></nav>
+				<main><h2>Install</h2><p>Run the installer.</p></main>
+				<footer>Copyright</footer></body></html>`))
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("plain notes"))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	l := NewURLLoader(nil)
+	ctx := context.Background()
+
+	doc, err := l.Load(ctx, srv.URL+"/old")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Path != srv.URL+"/guide" || doc.Name != "Setup Guide" {
+		t.Errorf("expected the redirected URL and the page title, got %q, %q", doc.Path, doc.Name)
+	}
+	if !strings.Contains(doc.Content, "## Install\n\nRun the installer.") || strings.Contains(doc.Content, "Copyright") {
+		t.Errorf("expected the main content only, got:\n%s", doc.Content)
+	}
+	if doc.CreatedAt.Year() != 2006 {
+		t.Errorf("expected the Last-Modified time, got %v", doc.CreatedAt)
+	}
+
+	doc, err = l.Load(ctx, srv.URL+"/notes.txt")
+	if err != nil || doc.Content != "plain notes" || doc.Name != "notes.txt" {
+		t.Errorf("unexpected text page: %+v, %v", doc, err)
+	}
+
+	for _, path := range []string{"/missing", "/logo.png"} {
+		if _, err := l.Load(ctx, srv.URL+path); err == nil {
+			t.Errorf("expected an error for %s", path)
+		}
+	}
+	if _, err := l.Load(ctx, "file:///etc/passwd"); err == nil {
+		t.Error("expected non-http URLs to be refused")
+	}
+}
@@ -0,0 +1,345 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// transcriptWindow is the stretch of a video each transcript section
+// covers, so chunks cite a nearby timestamp.
+const transcriptWindow = 2 * time.Minute
+
+// YouTubeOptions configures YouTubeLoader. Zero values take the
+// defaults.
+type YouTubeOptions struct {
+	// Languages are the caption languages to prefer, in order, such as
+	// "en" or "de"; English by default. Captions written by people are
+	// preferred over automatic ones in the same language.
+	Languages []string
+
+	// Transcriber transcribes the audio of videos without captions, or
+	// of every video when Local is set; such videos fail without one.
+	Transcriber ports.Transcriber
+	Local       bool
+
+	// YTDLP is the path of yt-dlp, which downloads the audio to
+	// transcribe; found on PATH by default.
+	YTDLP string
+}
+
+// YouTubeLoader loads the transcript of a YouTube video, so recorded
+// talks can be searched and cited by the minute. The video's captions
+// are fetched from YouTube, or its audio is downloaded with yt-dlp and
+// transcribed locally.
+type YouTubeLoader struct {
+	pages    *URLLoader
+	opts     YouTubeOptions
+	watchURL string // Page of a video, without its ID
+}
+
+// NewYouTubeLoader creates a transcript loader that fetches with client,
+// or with a 30-second timeout when client is nil.
+func NewYouTubeLoader(client *http.Client, opts YouTubeOptions) *YouTubeLoader {
+	if len(opts.Languages) == 0 {
+		opts.Languages = []string{"en"}
+	}
+	if opts.YTDLP == "" {
+		opts.YTDLP = "yt-dlp"
+	}
+	return &YouTubeLoader{pages: NewURLLoader(client), opts: opts, watchURL: "https://www.youtube.com/watch?v="}
+}
+
+// youTubeID matches a video ID.
+var youTubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// YouTubeVideoID returns the ID of the video a YouTube URL points to,
+// such as youtube.com/watch?v=ID, youtu.be/ID or youtube.com/shorts/ID.
+func YouTubeVideoID(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	var id string
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		switch {
+		case parts[0] == "watch":
+			id = u.Query().Get("v")
+		case len(parts) == 2 && (parts[0] == "shorts" || parts[0] == "embed" || parts[0] == "live" || parts[0] == "v"):
+			id = parts[1]
+		}
+	}
+	return id, youTubeID.MatchString(id)
+}
+
+// Load reads the transcript of the video at rawURL. The document is
+// named by the video's title and cited by its watch URL. Each section
+// covers about two minutes and is labeled with when it starts, such as
+// "Video at 12:30", so answers can point into the talk. The metadata
+// fields "channel" and "video_id" are set, and automatic captions and
+// local transcripts are recorded in the provenance.
+func (l *YouTubeLoader) Load(ctx context.Context, rawURL string) (*entities.Document, error) {
+	id, ok := YouTubeVideoID(rawURL)
+	if !ok {
+		return nil, fmt.Errorf("not a YouTube video URL: %q", rawURL)
+	}
+	watch := l.watchURL + id
+	video, err := l.video(ctx, watch)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []ports.TranscriptSegment
+	var provenance entities.Provenance
+	track, found := video.track(l.opts.Languages)
+	switch {
+	case found && !l.opts.Local:
+		if segments, err = l.captions(ctx, track.BaseURL); err != nil {
+			return nil, err
+		}
+		if track.Kind == "asr" {
+			provenance = entities.Provenance{{Kind: entities.TransformTranscription, Tool: "YouTube automatic captions", Detail: track.LanguageCode}}
+		}
+	case l.opts.Transcriber != nil:
+		if segments, err = l.transcribe(ctx, watch); err != nil {
+			return nil, err
+		}
+		provenance = entities.Provenance{{Kind: entities.TransformTranscription, Tool: l.opts.Transcriber.Tool()}}
+	default:
+		return nil, fmt.Errorf("video %s has no captions, and no transcriber is set", id)
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("video %s: empty transcript", id)
+	}
+
+	title := collapseSpace(video.Details.Title)
+	if title == "" {
+		title = id
+	}
+	metadata := map[string]string{"video_id": id}
+	if channel := collapseSpace(video.Details.Author); channel != "" {
+		metadata["channel"] = channel
+	}
+	sections := transcriptSections(segments)
+	published := time.Now()
+	if t, err := time.Parse("2006-01-02", video.Microformat.Renderer.PublishDate); err == nil {
+		published = t
+	}
+	return &entities.Document{
+		ID:         generateDocID(watch),
+		Name:       title,
+		Path:       watch,
+		Content:    entities.JoinSections(sections),
+		Metadata:   metadata,
+		Provenance: provenance,
+		Sections:   sections,
+		CreatedAt:  published,
+		UpdatedAt:  time.Now(),
+	}, nil
+}
+
+// SupportedExtensions returns no extensions: videos are loaded by URL.
+func (l *YouTubeLoader) SupportedExtensions() []string {
+	return nil
+}
+
+// playerResponse is the part of a watch page's player data that is
+// read.
+type playerResponse struct {
+	Details struct {
+		Title  string `json:"title"`
+		Author string `json:"author"`
+	} `json:"videoDetails"`
+	Microformat struct {
+		Renderer struct {
+			PublishDate string `json:"publishDate"`
+		} `json:"playerMicroformatRenderer"`
+	} `json:"microformat"`
+	Captions struct {
+		Renderer struct {
+			Tracks []captionTrack `json:"captionTracks"`
+		} `json:"playerCaptionsTracklistRenderer"`
+	} `json:"captions"`
+	Playability struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	} `json:"playabilityStatus"`
+}
+
+type captionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	Kind         string `json:"kind"` // "asr" for automatic captions
+}
+
+// track picks the caption track to read: the first of languages that
+// has one, preferring captions written by people.
+func (p playerResponse) track(languages []string) (captionTrack, bool) {
+	for _, lang := range languages {
+		var auto *captionTrack
+		for i, t := range p.Captions.Renderer.Tracks {
+			if t.LanguageCode != lang && !strings.HasPrefix(t.LanguageCode, lang+"-") {
+				continue
+			}
+			if t.Kind != "asr" {
+				return t, true
+			}
+			if auto == nil {
+				auto = &p.Captions.Renderer.Tracks[i]
+			}
+		}
+		if auto != nil {
+			return *auto, true
+		}
+	}
+	return captionTrack{}, false
+}
+
+// playerMarker precedes the player data in a watch page.
+const playerMarker = "ytInitialPlayerResponse = "
+
+// video fetches the watch page at watch and reads its player data.
+func (l *YouTubeLoader) video(ctx context.Context, watch string) (playerResponse, error) {
+	u, err := url.Parse(watch)
+	if err != nil {
+		return playerResponse{}, err
+	}
+	_, data, err := l.pages.get(ctx, u, "text/html")
+	if err != nil {
+		return playerResponse{}, err
+	}
+	start := bytes.Index(data, []byte(playerMarker))
+	if start < 0 {
+		return playerResponse{}, fmt.Errorf("reading %s: no player data in the page", watch)
+	}
+	var video playerResponse
+	// Decode reads the object and ignores the script after it
+	if err := json.NewDecoder(bytes.NewReader(data[start+len(playerMarker):])).Decode(&video); err != nil {
+		return playerResponse{}, fmt.Errorf("reading %s: %w", watch, err)
+	}
+	if status := video.Playability.Status; status != "" && status != "OK" {
+		return playerResponse{}, fmt.Errorf("video %s is unavailable: %s", watch, video.Playability.Reason)
+	}
+	return video, nil
+}
+
+// captionEvents is a caption track in YouTube's json3 format.
+type captionEvents struct {
+	Events []struct {
+		Start    int64 `json:"tStartMs"`
+		Duration int64 `json:"dDurationMs"`
+		Segs     []struct {
+			Text string `json:"utf8"`
+		} `json:"segs"`
+	} `json:"events"`
+}
+
+// captions fetches the caption track at baseURL.
+func (l *YouTubeLoader) captions(ctx context.Context, baseURL string) ([]ports.TranscriptSegment, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("fmt", "json3")
+	u.RawQuery = q.Encode()
+	_, data, err := l.pages.get(ctx, u, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	var track captionEvents
+	if err := json.Unmarshal(data, &track); err != nil {
+		return nil, fmt.Errorf("reading captions: %w", err)
+	}
+
+	var segments []ports.TranscriptSegment
+	for _, e := range track.Events {
+		var text strings.Builder
+		for _, s := range e.Segs {
+			text.WriteString(s.Text)
+		}
+		if t := collapseSpace(text.String()); t != "" {
+			start := time.Duration(e.Start) * time.Millisecond
+			segments = append(segments, ports.TranscriptSegment{
+				Start: start,
+				End:   start + time.Duration(e.Duration)*time.Millisecond,
+				Text:  t,
+			})
+		}
+	}
+	return segments, nil
+}
+
+// transcribe downloads the audio of the video at watch with yt-dlp and
+// transcribes it.
+func (l *YouTubeLoader) transcribe(ctx context.Context, watch string) ([]ports.TranscriptSegment, error) {
+	dir, err := os.MkdirTemp("", "localrag-youtube-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.CommandContext(ctx, l.opts.YTDLP, "--quiet", "--no-playlist", "-f", "bestaudio", "-o", filepath.Join(dir, "audio.%(ext)s"), "--", watch)
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("downloading %s: %w: %s", watch, err, strings.TrimSpace(output.String()))
+	}
+	audio, err := filepath.Glob(filepath.Join(dir, "audio.*"))
+	if err != nil || len(audio) == 0 {
+		return nil, fmt.Errorf("downloading %s: yt-dlp wrote no audio", watch)
+	}
+	return l.opts.Transcriber.Transcribe(ctx, audio[0])
+}
+
+// transcriptSections groups segments into sections of about
+// transcriptWindow, each labeled with its start.
+func transcriptSections(segments []ports.TranscriptSegment) []entities.Section {
+	var sections []entities.Section
+	var text []string
+	var start time.Duration
+	flush := func() {
+		if len(text) > 0 {
+			sections = append(sections, entities.Section{Label: "Video at " + timestamp(start), Content: strings.Join(text, " ")})
+			text = nil
+		}
+	}
+	for _, s := range segments {
+		if len(text) > 0 && s.Start-start >= transcriptWindow {
+			flush()
+		}
+		if len(text) == 0 {
+			start = s.Start
+		}
+		text = append(text, s.Text)
+	}
+	flush()
+	return sections
+}
+
+// timestamp formats an offset into a video as m:ss, or h:mm:ss.
+func timestamp(d time.Duration) string {
+	s := int(d / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,101 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestYouTubeVideoID(t *testing.T) {
+	for rawURL, want := range map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42": "dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?si=x":                "dQw4w9WgXcQ",
+		"https://m.youtube.com/shorts/dQw4w9WgXcQ":         "dQw4w9WgXcQ",
+		"https://www.youtube.com/embed/dQw4w9WgXcQ":        "dQw4w9WgXcQ",
+		"https://www.youtube.com/@channel":                 "",
+		"https://example.com/watch?v=dQw4w9WgXcQ":          "",
+	} {
+		if id, ok := YouTubeVideoID(rawURL); ok != (want != "") || (ok && id != want) {
+			t.Errorf("YouTubeVideoID(%q) = %q, %v; want %q", rawURL, id, ok, want)
+		}
+	}
+}
+
+func TestYouTubeLoader_Captions(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			fmt.Fprintf(w, `<html><script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"OK"},
+"videoDetails":{"title":"Scaling  Postgres","author":"DB Conf"},
+"microformat":{"playerMicroformatRenderer":{"publishDate":"2024-05-02"}},
+"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":[
+{"baseUrl":"%[1]s/asr?lang=en","languageCode":"en","kind":"asr"},
+{"baseUrl":"%[1]s/manual?lang=en","languageCode":"en-GB"}]}}};var meta = {};</script></html>`, srv.URL)
+		case "/manual":
+			if r.URL.Query().Get("fmt") != "json3" {
+				http.Error(w, "want json3", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"events":[
+{"tStartMs":0,"dDurationMs":4000,"segs":[{"utf8":"Welcome to"},{"utf8":" the talk."}]},
+{"tStartMs":4000,"dDurationMs":1000},
+{"tStartMs":65000,"dDurationMs":3000,"segs":[{"utf8":"Indexes\nmatter."}]},
+{"tStartMs":3725000,"dDurationMs":2000,"segs":[{"utf8":"Questions?"}]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	l := NewYouTubeLoader(nil, YouTubeOptions{})
+	l.watchURL = srv.URL + "/watch?v="
+	doc, err := l.Load(context.Background(), "https://youtu.be/dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if doc.Name != "Scaling Postgres" || doc.Path != srv.URL+"/watch?v=dQw4w9WgXcQ" {
+		t.Errorf("unexpected document: %q at %q", doc.Name, doc.Path)
+	}
+	want := []entities.Section{
+		{Label: "Video at 0:00", Content: "Welcome to the talk. Indexes matter."},
+		{Label: "Video at 1:02:05", Content: "Questions?"},
+	}
+	if len(doc.Sections) != len(want) {
+		t.Fatalf("unexpected sections: %+v", doc.Sections)
+	}
+	for i := range want {
+		if doc.Sections[i].Label != want[i].Label || doc.Sections[i].Content != want[i].Content {
+			t.Errorf("section %d = %+v, want %+v", i, doc.Sections[i], want[i])
+		}
+	}
+	if doc.Metadata["channel"] != "DB Conf" || doc.Metadata["video_id"] != "dQw4w9WgXcQ" {
+		t.Errorf("unexpected metadata: %v", doc.Metadata)
+	}
+	if len(doc.Provenance) != 0 {
+		t.Errorf("captions written by people recorded as %v", doc.Provenance)
+	}
+	if !doc.CreatedAt.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date: %v", doc.CreatedAt)
+	}
+}
+
+func TestYouTubeLoader_NoCaptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"title":"Silent"}};</script>`))
+	}))
+	defer srv.Close()
+
+	l := NewYouTubeLoader(nil, YouTubeOptions{})
+	l.watchURL = srv.URL + "/watch?v="
+	_, err := l.Load(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	if err == nil || !strings.Contains(err.Error(), "no transcriber") {
+		t.Errorf("expected an error for a video without captions, got %v", err)
+	}
+}
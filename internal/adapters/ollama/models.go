@@ -0,0 +1,147 @@
+// Package ollama holds the Ollama model management calls shared by the
+// LLM and embedding adapters: listing installed models and pulling
+// missing ones.
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// errIncomplete reports a pull stream that ended before "success".
+var errIncomplete = errors.New("Ollama ended the download before it completed")
+
+// Model is a model installed on an Ollama server.
+type Model struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"` // Bytes on disk
+}
+
+// Tags lists the models installed on the Ollama server at baseURL.
+func Tags(ctx context.Context, client *http.Client, baseURL string) ([]Model, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Ollama returned status %d", resp.StatusCode)
+	}
+	var tags struct {
+		Models []Model `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return tags.Models, nil
+}
+
+// Names returns the names of models, sorted.
+func Names(models []Model) []string {
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Installed reports whether model is among models. A model named
+// without a tag is the one tagged "latest", as Ollama resolves it.
+func Installed(models []Model, model string) bool {
+	want := Canonical(model)
+	for _, m := range models {
+		if Canonical(m.Name) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Canonical returns model with its tag, "latest" when it has none.
+func Canonical(model string) string {
+	name := model
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if !strings.Contains(name, ":") {
+		return model + ":latest"
+	}
+	return model
+}
+
+// pullStatus is a line of Ollama's streamed pull response.
+type pullStatus struct {
+	Status    string `json:"status"`
+	Completed int64  `json:"completed"`
+	Total     int64  `json:"total"`
+	Error     string `json:"error"`
+}
+
+// Pull downloads model to the Ollama server at baseURL, calling
+// progress, when not nil, with each status Ollama streams. It returns
+// once the model is installed. Downloads take minutes, so client should
+// have no timeout; ctx bounds the pull instead.
+func Pull(ctx context.Context, client *http.Client, baseURL, model string, progress func(ports.PullProgress)) error {
+	body, err := json.Marshal(map[string]interface{}{"model": model, "stream": true})
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pulling %s: Ollama returned status %d: %s", model, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var st pullStatus
+		if err := json.Unmarshal(scanner.Bytes(), &st); err != nil {
+			continue // Skip malformed lines
+		}
+		if st.Error != "" {
+			return fmt.Errorf("pulling %s: %s", model, st.Error)
+		}
+		if progress != nil {
+			progress(ports.PullProgress{Model: model, Status: st.Status, Completed: st.Completed, Total: st.Total})
+		}
+		if st.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pulling %s: %w", model, err)
+	}
+	return fmt.Errorf("pulling %s: %w", model, errIncomplete)
+}
+
+// NoTimeout returns a copy of client without its overall timeout, for
+// pulls, keeping its transport.
+func NoTimeout(client *http.Client) *http.Client {
+	c := *client
+	c.Timeout = 0
+	return &c
+}
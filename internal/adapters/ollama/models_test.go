@@ -0,0 +1,89 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+func TestInstalled(t *testing.T) {
+	models := []Model{{Name: "llama3.2:latest"}, {Name: "nomic-embed-text:v1.5"}, {Name: "registry.local:5000/team/phi3:latest"}}
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"llama3.2", true},
+		{"llama3.2:latest", true},
+		{"llama3.2:1b", false},
+		{"nomic-embed-text", false},
+		{"nomic-embed-text:v1.5", true},
+		{"registry.local:5000/team/phi3", true},
+		{"mistral", false},
+	}
+	for _, tt := range tests {
+		if got := Installed(models, tt.model); got != tt.want {
+			t.Errorf("Installed(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"models": []map[string]interface{}{{"name": "llama3.2:latest", "size": 2019393189}},
+		})
+	}))
+	defer server.Close()
+
+	models, err := Tags(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("Tags: %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "llama3.2:latest" || models[0].Size != 2019393189 {
+		t.Errorf("unexpected models: %+v", models)
+	}
+}
+
+func TestPull(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "missing" {
+			fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+			fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":100,"completed":40}`)
+		fmt.Fprintln(w, `{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":100,"completed":100}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer server.Close()
+
+	var steps []ports.PullProgress
+	err := Pull(context.Background(), server.Client(), server.URL, "llama3.2", func(p ports.PullProgress) {
+		steps = append(steps, p)
+	})
+	if err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	if len(steps) != 4 || steps[1].Completed != 40 || steps[1].Total != 100 || steps[3].Status != "success" || steps[0].Model != "llama3.2" {
+		t.Errorf("unexpected progress: %+v", steps)
+	}
+
+	err = Pull(context.Background(), server.Client(), server.URL, "missing", nil)
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("expected Ollama's error, got %v", err)
+	}
+}
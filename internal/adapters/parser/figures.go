@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// minFigureSide is the width and height in pixels below which an
+	// embedded image is taken for an icon, logo or rule rather than a
+	// figure.
+	minFigureSide = 100
+
+	// maxFigures bounds the figures taken from one PDF, so a scanned
+	// book does not send every page to the model.
+	maxFigures = 32
+)
+
+// PDFFigure is an image embedded in a PDF, as PNG.
+type PDFFigure struct {
+	Page int
+	Data []byte
+}
+
+// PDFImages extracts the figures embedded in PDFs with poppler's
+// pdfimages, so they can be described.
+type PDFImages struct {
+	binary string
+}
+
+// NewPDFImages creates a figure extractor that runs the pdfimages at
+// binary, or the one on PATH when binary is empty.
+func NewPDFImages(binary string) *PDFImages {
+	if binary == "" {
+		binary = "pdfimages"
+	}
+	return &PDFImages{binary: binary}
+}
+
+// Extract returns the figures of a PDF in page order, leaving out
+// images smaller than 100 pixels a side and any beyond the first 32.
+func (p *PDFImages) Extract(ctx context.Context, data []byte, filename string) ([]PDFFigure, error) {
+	dir, err := os.MkdirTemp("", "localrag-figures-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, p.binary, "-png", "-p", input, filepath.Join(dir, "img"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("extracting figures of %s: %w: %s", filename, err, strings.TrimSpace(string(out)))
+	}
+	// Images are named img-PPP-NNN.png by page and number, zero padded,
+	// so they sort in order
+	images, err := filepath.Glob(filepath.Join(dir, "img-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(images)
+
+	var figures []PDFFigure
+	for _, path := range images {
+		if len(figures) == maxFigures {
+			break
+		}
+		image, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(image))
+		if err != nil || config.Width < minFigureSide || config.Height < minFigureSide {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(filepath.Base(path), ".png"), "-")
+		page, _ := strconv.Atoi(parts[1])
+		figures = append(figures, PDFFigure{Page: page, Data: image})
+	}
+	return figures, nil
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writePNG writes a blank PNG of the given size.
+func writePNG(t *testing.T, path string, width, height int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPDFImages_Extract(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "chart.png"), 400, 300)
+	writePNG(t, filepath.Join(dir, "logo.png"), 32, 32)
+	// Fake pdfimages: a logo and a chart on page 3, a chart on page 12
+	pdfimages := writeScript(t, dir, "pdfimages", `cp "`+dir+`/chart.png" "$4-012-002.png"
+cp "`+dir+`/logo.png" "$4-003-000.png"
+cp "`+dir+`/chart.png" "$4-003-001.png"`)
+
+	figures, err := NewPDFImages(pdfimages).Extract(context.Background(), []byte("%PDF"), "paper.pdf")
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+	if len(figures) != 2 || figures[0].Page != 3 || figures[1].Page != 12 {
+		t.Errorf("unexpected figures: %+v", figures)
+	}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// NativePDFParser implements ports.DocumentParser in pure Go, so PDFs
+// are parsed without the Python service. It reads the text layer only:
+// scanned pages yield nothing, and complex layouts such as tables may
+// lose their structure, where the Python service does better.
+type NativePDFParser struct{}
+
+// NewNativePDFParser creates a pure Go PDF parser.
+func NewNativePDFParser() *NativePDFParser {
+	return &NativePDFParser{}
+}
+
+// Parse extracts the text of each page.
+func (p *NativePDFParser) Parse(ctx context.Context, data []byte, filename string) (pages []string, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("parsing %s: malformed PDF: %v", filename, r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+
+	pages = make([]string, reader.NumPage())
+	fonts := make(map[string]*pdf.Font) // Shared so each font's charmap is parsed once
+	for i := 1; i <= reader.NumPage(); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				f := page.Font(name)
+				fonts[name] = &f
+			}
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s page %d: %w", filename, i, err)
+		}
+		pages[i-1] = strings.TrimSpace(text)
+	}
+	return pages, nil
+}
+
+// SupportedFormats returns formats this parser handles.
+func (p *NativePDFParser) SupportedFormats() []string {
+	return []string{"pdf"}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a minimal PDF with one page per text, set in Helvetica.
+func buildPDF(pages ...string) []byte {
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	)
+	for i, text := range pages {
+		content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestNativePDFParser_Parse(t *testing.T) {
+	parser := NewNativePDFParser()
+	pages, err := parser.Parse(context.Background(), buildPDF("Hello from Go", "Second page"), "test.pdf")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(pages) != 2 || pages[0] != "Hello from Go" || pages[1] != "Second page" {
+		t.Errorf("unexpected pages: %q", pages)
+	}
+}
+
+func TestNativePDFParser_Malformed(t *testing.T) {
+	parser := NewNativePDFParser()
+	for _, data := range [][]byte{[]byte("not a pdf"), buildPDF("Hello")[:200]} {
+		if _, err := parser.Parse(context.Background(), data, "bad.pdf"); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
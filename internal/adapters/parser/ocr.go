@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TesseractOptions configures OCR with the tesseract binary. Zero
+// values take the defaults.
+type TesseractOptions struct {
+	Languages string // Tesseract languages, such as "eng+deu"; "eng" by default
+	DPI       int    // Resolution PDF pages are rendered at; 300 by default
+	Binary    string // Path of tesseract; found on PATH by default
+	PDFToPPM  string // Path of poppler's pdftoppm, which renders PDF pages; found on PATH by default
+}
+
+// TesseractOCR implements ports.OCREngine with the tesseract binary.
+// PDF pages are rendered to images with poppler's pdftoppm first.
+type TesseractOCR struct {
+	opts TesseractOptions
+}
+
+// NewTesseractOCR creates an OCR engine that runs tesseract.
+func NewTesseractOCR(opts TesseractOptions) *TesseractOCR {
+	if opts.Languages == "" {
+		opts.Languages = "eng"
+	}
+	if opts.DPI <= 0 {
+		opts.DPI = 300
+	}
+	if opts.Binary == "" {
+		opts.Binary = "tesseract"
+	}
+	if opts.PDFToPPM == "" {
+		opts.PDFToPPM = "pdftoppm"
+	}
+	return &TesseractOCR{opts: opts}
+}
+
+// Recognize returns the text of an image, or of each page of a PDF
+// separated by a blank line.
+func (o *TesseractOCR) Recognize(ctx context.Context, data []byte, filename string) (string, error) {
+	if !strings.EqualFold(filepath.Ext(filename), ".pdf") {
+		return o.recognizeImage(ctx, data, filename)
+	}
+
+	dir, err := os.MkdirTemp("", "localrag-ocr-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.CommandContext(ctx, o.opts.PDFToPPM, "-r", strconv.Itoa(o.opts.DPI), "-png", "-", filepath.Join(dir, "page"))
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("rendering %s: %w: %s", filename, err, strings.TrimSpace(string(out)))
+	}
+	// Pages are named page-1.png, or page-01.png and so on, padded to
+	// the same width, so they sort in order
+	pages, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(pages)
+
+	var texts []string
+	for i, page := range pages {
+		image, err := os.ReadFile(page)
+		if err != nil {
+			return "", err
+		}
+		text, err := o.recognizeImage(ctx, image, fmt.Sprintf("%s page %d", filename, i+1))
+		if err != nil {
+			return "", err
+		}
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// recognizeImage runs tesseract on one image.
+func (o *TesseractOCR) recognizeImage(ctx context.Context, image []byte, name string) (string, error) {
+	cmd := exec.CommandContext(ctx, o.opts.Binary, "stdin", "stdout", "-l", o.opts.Languages)
+	cmd.Stdin = bytes.NewReader(image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("OCR of %s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Tool names the engine for provenance.
+func (o *TesseractOCR) Tool() string {
+	return "tesseract"
+}
+
+// PythonOCR implements ports.OCREngine with the Python service's /ocr
+// endpoint, which runs pytesseract and renders PDFs with pdf2image.
+type PythonOCR struct {
+	serviceURL string
+	client     *http.Client
+}
+
+// NewPythonOCR creates an OCR engine that calls the Python service.
+func NewPythonOCR(serviceURL string) *PythonOCR {
+	if serviceURL == "" {
+		serviceURL = "http://localhost:8081"
+	}
+	return &PythonOCR{
+		serviceURL: serviceURL,
+		client: &http.Client{
+			Timeout: 5 * time.Minute, // OCR takes seconds per page
+		},
+	}
+}
+
+// Recognize sends the document to the service and returns its text.
+func (o *PythonOCR) Recognize(ctx context.Context, data []byte, filename string) (string, error) {
+	endpoint := o.serviceURL + "/ocr?filename=" + url.QueryEscape(filename)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calling OCR service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading response: %w", err)
+	}
+
+	var result parseResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("OCR error: %s", result.Error)
+	}
+	return result.Text, nil
+}
+
+// Tool names the engine for provenance.
+func (o *PythonOCR) Tool() string {
+	return "pytesseract"
+}
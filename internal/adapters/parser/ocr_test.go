@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPythonOCR_Recognize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ocr" || r.URL.Query().Get("filename") != "scan 1.pdf" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"text": "Scanned text", "pages": 1})
+	}))
+	defer server.Close()
+
+	text, err := NewPythonOCR(server.URL).Recognize(context.Background(), []byte("%PDF"), "scan 1.pdf")
+	if err != nil {
+		t.Fatalf("OCR failed: %v", err)
+	}
+	if text != "Scanned text" {
+		t.Errorf("unexpected text: %q", text)
+	}
+}
+
+// writeScript writes an executable shell script.
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTesseractOCR_PDFPages(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	// Fake binaries: pdftoppm writes three pages, tesseract echoes its input
+	pdftoppm := writeScript(t, dir, "pdftoppm", `for i in 01 02 10; do printf "page $i" > "$5-$i.png"; done`)
+	tesseract := writeScript(t, dir, "tesseract", `[ "$4" = "deu" ] || exit 1; cat; echo`)
+
+	ocr := NewTesseractOCR(TesseractOptions{Languages: "deu", Binary: tesseract, PDFToPPM: pdftoppm})
+	text, err := ocr.Recognize(context.Background(), []byte("%PDF"), "scan.pdf")
+	if err != nil {
+		t.Fatalf("OCR failed: %v", err)
+	}
+	if text != "page 01\n\npage 02\n\npage 10" {
+		t.Errorf("unexpected text: %q", text)
+	}
+
+	text, err = ocr.Recognize(context.Background(), []byte("photo"), "receipt.png")
+	if err != nil || text != "photo" {
+		t.Errorf("unexpected image text: %q, %v", text, err)
+	}
+}
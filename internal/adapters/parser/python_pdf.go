@@ -1,6 +1,8 @@
 // Package parser provides document parsing adapters.
 // Clean Architecture: Adapter implementing ports.DocumentParser.
-// Calls external Python service for PDF extraction.
+// PDFs are parsed in pure Go, or by an external Python service for
+// higher-fidelity extraction. Scanned pages and images are read by OCR,
+// with tesseract or the Python service.
 package parser
 
 import (
@@ -39,41 +41,47 @@ func NewPythonPDFParser(serviceURL string) *PythonPDFParser {
 
 // parseResponse is the Python service response format.
 type parseResponse struct {
-	Text    string `json:"text"`
-	Pages   int    `json:"pages"`
-	Library string `json:"library,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Text      string   `json:"text"`
+	Pages     int      `json:"pages"`
+	PageTexts []string `json:"page_texts,omitempty"` // Text of each page; older services send Text only
+	Library   string   `json:"library,omitempty"`
+	Error     string   `json:"error,omitempty"`
 }
 
-// Parse extracts text from PDF bytes via Python service.
-func (p *PythonPDFParser) Parse(ctx context.Context, data []byte, filename string) (string, error) {
+// Parse extracts the text of each page from PDF bytes via Python
+// service. Services that predate per-page text return the whole text as
+// one page.
+func (p *PythonPDFParser) Parse(ctx context.Context, data []byte, filename string) ([]string, error) {
 	req, err := http.NewRequestWithContext(ctx, "POST", p.serviceURL+"/parse", bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/octet-stream")
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("calling PDF service: %w", err)
+		return nil, fmt.Errorf("calling PDF service: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("reading response: %w", err)
+		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
 	var result parseResponse
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("decoding response: %w", err)
+		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
 	if result.Error != "" {
-		return "", fmt.Errorf("PDF parse error: %s", result.Error)
+		return nil, fmt.Errorf("PDF parse error: %s", result.Error)
 	}
 
-	return result.Text, nil
+	if result.PageTexts == nil {
+		return []string{result.Text}, nil
+	}
+	return result.PageTexts, nil
 }
 
 // SupportedFormats returns formats this parser handles.
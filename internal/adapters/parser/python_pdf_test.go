@@ -22,13 +22,32 @@ func TestPythonPDFParser_Parse(t *testing.T) {
 	defer server.Close()
 
 	parser := NewPythonPDFParser(server.URL)
-	text, err := parser.Parse(context.Background(), []byte("fake pdf"), "test.pdf")
+	pages, err := parser.Parse(context.Background(), []byte("fake pdf"), "test.pdf")
 
 	if err != nil {
 		t.Fatalf("parse failed: %v", err)
 	}
-	if text != "Hello from PDF" {
-		t.Errorf("unexpected text: %s", text)
+	if len(pages) != 1 || pages[0] != "Hello from PDF" {
+		t.Errorf("unexpected pages: %q", pages)
+	}
+}
+
+func TestPythonPDFParser_PageTexts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"text":       "Intro\n\nResults",
+			"pages":      3,
+			"page_texts": []string{"Intro", "", "Results"},
+		})
+	}))
+	defer server.Close()
+
+	pages, err := NewPythonPDFParser(server.URL).Parse(context.Background(), []byte("fake pdf"), "test.pdf")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(pages) != 3 || pages[0] != "Intro" || pages[1] != "" || pages[2] != "Results" {
+		t.Errorf("unexpected pages: %q", pages)
 	}
 }
 
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// WhisperOptions configures transcription with whisper.cpp. Model is
+// required; other zero values take the defaults.
+type WhisperOptions struct {
+	Model    string // Path of the ggml model file, such as ggml-base.en.bin
+	Language string // Spoken language, such as "de"; detected when "auto"; "en" by default
+	Binary   string // Path of whisper.cpp's CLI; whisper-cli on PATH by default
+	FFmpeg   string // Path of ffmpeg, which converts audio for whisper.cpp; found on PATH by default
+}
+
+// WhisperTranscriber implements ports.Transcriber with whisper.cpp, so
+// speech is transcribed locally. Audio is converted to the 16 kHz mono
+// WAV whisper.cpp reads with ffmpeg first.
+type WhisperTranscriber struct {
+	opts WhisperOptions
+}
+
+// NewWhisperTranscriber creates a transcriber that runs whisper.cpp.
+func NewWhisperTranscriber(opts WhisperOptions) *WhisperTranscriber {
+	if opts.Language == "" {
+		opts.Language = "en"
+	}
+	if opts.Binary == "" {
+		opts.Binary = "whisper-cli"
+	}
+	if opts.FFmpeg == "" {
+		opts.FFmpeg = "ffmpeg"
+	}
+	return &WhisperTranscriber{opts: opts}
+}
+
+// whisperOutput is the part of whisper.cpp's JSON output (-oj) that is
+// read. Offsets are in milliseconds.
+type whisperOutput struct {
+	Transcription []struct {
+		Offsets struct {
+			From int64 `json:"from"`
+			To   int64 `json:"to"`
+		} `json:"offsets"`
+		Text string `json:"text"`
+	} `json:"transcription"`
+}
+
+// Transcribe converts the file at path and transcribes it.
+func (w *WhisperTranscriber) Transcribe(ctx context.Context, path string) ([]ports.TranscriptSegment, error) {
+	if w.opts.Model == "" {
+		return nil, fmt.Errorf("transcribing %s: no whisper model set", filepath.Base(path))
+	}
+	dir, err := os.MkdirTemp("", "localrag-whisper-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	wav := filepath.Join(dir, "audio.wav")
+	if err := run(ctx, w.opts.FFmpeg, "-nostdin", "-loglevel", "error", "-i", path, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", wav); err != nil {
+		return nil, fmt.Errorf("converting %s: %w", filepath.Base(path), err)
+	}
+	out := filepath.Join(dir, "transcript")
+	if err := run(ctx, w.opts.Binary, "-m", w.opts.Model, "-l", w.opts.Language, "-f", wav, "-oj", "-of", out, "-np"); err != nil {
+		return nil, fmt.Errorf("transcribing %s: %w", filepath.Base(path), err)
+	}
+	data, err := os.ReadFile(out + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("transcribing %s: %w", filepath.Base(path), err)
+	}
+
+	var result whisperOutput
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("decoding transcript: %w", err)
+	}
+	segments := make([]ports.TranscriptSegment, 0, len(result.Transcription))
+	for _, s := range result.Transcription {
+		if text := strings.TrimSpace(s.Text); text != "" {
+			segments = append(segments, ports.TranscriptSegment{
+				Start: time.Duration(s.Offsets.From) * time.Millisecond,
+				End:   time.Duration(s.Offsets.To) * time.Millisecond,
+				Text:  text,
+			})
+		}
+	}
+	return segments, nil
+}
+
+// Tool names the engine for provenance.
+func (w *WhisperTranscriber) Tool() string {
+	return "whisper.cpp"
+}
+
+// run runs a command, returning an error with what it printed when it
+// fails.
+func run(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWhisperTranscriber_Transcribe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	// Fake binaries: ffmpeg copies the audio, whisper-cli writes a transcript
+	ffmpeg := writeScript(t, dir, "ffmpeg", `eval last=\${$#}; cp "$5" "$last"`)
+	whisper := writeScript(t, dir, "whisper-cli", `[ "$4" = "de" ] || exit 1
+cat > "$9.json" <<'EOF'
+{"transcription":[
+{"offsets":{"from":0,"to":2500},"text":" Hallo zusammen."},
+{"offsets":{"from":2500,"to":3000},"text":" "},
+{"offsets":{"from":3000,"to":7250},"text":" Heute geht es um Indizes."}]}
+EOF`)
+	audio := filepath.Join(dir, "talk.m4a")
+	if err := os.WriteFile(audio, []byte("audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWhisperTranscriber(WhisperOptions{Model: "ggml-base.bin", Language: "de", Binary: whisper, FFmpeg: ffmpeg})
+	segments, err := w.Transcribe(context.Background(), audio)
+	if err != nil {
+		t.Fatalf("transcription failed: %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("unexpected segments: %+v", segments)
+	}
+	if s := segments[1]; s.Start != 3*time.Second || s.End != 7250*time.Millisecond || s.Text != "Heute geht es um Indizes." {
+		t.Errorf("unexpected segment: %+v", s)
+	}
+}
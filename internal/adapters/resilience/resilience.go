@@ -0,0 +1,322 @@
+// Package resilience provides retry, health tracking, circuit breaking
+// and pooled HTTP clients shared by adapters that talk to remote backends.
+// Transient failures (a restarting Ollama or Redis) are retried with
+// jittered backoff instead of surfacing to the user.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// Backoff configures exponential retry with full jitter.
+type Backoff struct {
+	Initial  time.Duration // Upper bound of the first delay
+	Max      time.Duration // Cap on any single delay
+	Attempts int           // Total tries, including the first
+}
+
+// DefaultBackoff rides out a backend restart of a few seconds.
+var DefaultBackoff = Backoff{Initial: 200 * time.Millisecond, Max: 5 * time.Second, Attempts: 5}
+
+// Delay returns a random wait before retry number attempt (starting at 1),
+// drawn from [0, min(Max, Initial*2^(attempt-1))).
+func (b Backoff) Delay(attempt int) time.Duration {
+	ceiling := b.Initial
+	for i := 1; i < attempt && ceiling < b.Max; i++ {
+		ceiling *= 2
+	}
+	if ceiling > b.Max {
+		ceiling = b.Max
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// permanentError marks a failure that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Retry returns it immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// retryAfterError marks a failure that should not be retried before a
+// given wait, such as a call refused by an open Breaker.
+type retryAfterError struct {
+	err  error
+	wait time.Duration
+}
+
+func (e retryAfterError) Error() string { return e.err.Error() }
+func (e retryAfterError) Unwrap() error { return e.err }
+
+// Retry calls fn until it succeeds, returns a Permanent error, the
+// attempts run out or ctx is done. The last error is returned unwrapped.
+func Retry(ctx context.Context, b Backoff, fn func() error) error {
+	attempts := b.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		var perm permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		delay := b.Delay(attempt)
+		var after retryAfterError
+		if errors.As(err, &after) {
+			err = after.err
+			delay = max(delay, after.wait)
+		}
+		if err == nil || attempt >= attempts {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// ErrCircuitOpen is returned for calls refused without being sent
+// because the backend's Breaker is open.
+var ErrCircuitOpen = errors.New("circuit open: backend keeps failing")
+
+// Breaker stops sending calls to a backend that keeps failing, so a
+// restarting server is not flooded with requests from every worker.
+// After Threshold consecutive failures it opens and refuses calls for
+// Cooldown; then it lets one trial call through, closing on its success
+// and reopening on its failure. Other callers wait out the trial too.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu         sync.Mutex
+	failures   int
+	openUntil  time.Time
+	trialUntil time.Time // A trial is under way until then
+}
+
+// NewBreaker creates a closed breaker. A threshold below 1 is taken as 1.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: max(threshold, 1), Cooldown: cooldown}
+}
+
+// Allow reports how long a caller must wait before calling the backend;
+// zero means the call may go ahead. Once the cooldown is over the first
+// caller is allowed through as the trial. A trial whose outcome is never
+// recorded, because its caller gave up, expires after another cooldown.
+func (b *Breaker) Allow() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.Threshold {
+		return 0
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return b.openUntil.Sub(now)
+	}
+	if now.Before(b.trialUntil) {
+		return b.trialUntil.Sub(now)
+	}
+	b.trialUntil = now.Add(b.Cooldown)
+	return 0
+}
+
+// Success records a successful call, closing the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.trialUntil = time.Time{}
+}
+
+// Failure records a failed call, opening the breaker once Threshold
+// failures have happened in a row.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.Threshold {
+		b.openUntil = time.Now().Add(b.Cooldown)
+		b.trialUntil = time.Time{}
+	}
+}
+
+// Open reports whether the breaker is refusing calls or trying one.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.Threshold
+}
+
+// Tracker records the outcome of calls to one backend.
+type Tracker struct {
+	mu      sync.Mutex
+	health  ports.BackendHealth
+	breaker *Breaker
+}
+
+// NewTracker creates a tracker for the named backend. A backend counts
+// as healthy until its first failure.
+func NewTracker(name string) *Tracker {
+	return &Tracker{health: ports.BackendHealth{Name: name, Healthy: true}}
+}
+
+// SetBreaker makes the tracker feed call outcomes to b, and DoHTTP hold
+// back calls while b is open. A nil b removes the breaker.
+func (t *Tracker) SetBreaker(b *Breaker) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.breaker = b
+}
+
+// allow reports how long the tracker's breaker, if any, holds calls back.
+func (t *Tracker) allow() time.Duration {
+	t.mu.Lock()
+	b := t.breaker
+	t.mu.Unlock()
+	if b == nil {
+		return 0
+	}
+	return b.Allow()
+}
+
+// Success records a successful call.
+func (t *Tracker) Success() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.breaker != nil {
+		t.breaker.Success()
+	}
+	t.health.Healthy = true
+	t.health.ConsecutiveFailures = 0
+	t.health.LastSuccess = time.Now()
+}
+
+// Failure records a failed call.
+func (t *Tracker) Failure(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.breaker != nil {
+		t.breaker.Failure()
+	}
+	t.health.Healthy = false
+	t.health.ConsecutiveFailures++
+	t.health.TotalFailures++
+	t.health.LastFailure = time.Now()
+	if err != nil {
+		t.health.LastError = err.Error()
+	}
+}
+
+// Reconnect records that a new connection replaced a broken one.
+func (t *Tracker) Reconnect() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.health.Reconnects++
+}
+
+// Health returns a snapshot of the backend's state.
+func (t *Tracker) Health() ports.BackendHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.health
+	h.CircuitOpen = t.breaker != nil && t.breaker.Open()
+	return h
+}
+
+// Record updates the tracker from the result of a call and passes err through.
+func (t *Tracker) Record(err error) error {
+	if err != nil {
+		t.Failure(err)
+	} else {
+		t.Success()
+	}
+	return err
+}
+
+// NewHTTPClient returns a client whose transport keeps idle connections
+// to a single backend host alive between requests.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 32
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// RetryableStatus reports whether an HTTP status suggests the backend is
+// restarting or overloaded rather than rejecting the request.
+func RetryableStatus(code int) bool {
+	return code == http.StatusBadGateway ||
+		code == http.StatusServiceUnavailable ||
+		code == http.StatusGatewayTimeout ||
+		code == http.StatusTooManyRequests
+}
+
+// DoHTTP sends the request built by newRequest, retrying transport errors
+// and RetryableStatus responses with backoff. newRequest is called once
+// per attempt so request bodies can be replayed. Any other response, or
+// the last retryable one, is returned for the caller to interpret.
+// While the tracker's breaker is open, attempts wait for it instead of
+// sending, and fail with ErrCircuitOpen if the attempts run out first.
+func DoHTTP(ctx context.Context, client *http.Client, t *Tracker, b Backoff, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	var resp *http.Response
+	err := Retry(ctx, b, func() error {
+		if resp != nil {
+			resp.Body.Close()
+			resp = nil
+		}
+		if wait := t.allow(); wait > 0 {
+			return retryAfterError{err: ErrCircuitOpen, wait: wait}
+		}
+		req, err := newRequest()
+		if err != nil {
+			return Permanent(err)
+		}
+		r, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Permanent(err) // Caller gave up; not the backend's fault
+			}
+			t.Failure(err)
+			return err
+		}
+		resp = r
+		if RetryableStatus(r.StatusCode) {
+			err := fmt.Errorf("status %d", r.StatusCode)
+			t.Failure(err)
+			return err
+		}
+		t.Success()
+		return nil
+	})
+	if resp != nil {
+		return resp, nil
+	}
+	return nil, err
+}
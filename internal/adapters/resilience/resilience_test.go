@@ -0,0 +1,182 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var fast = Backoff{Initial: time.Millisecond, Max: 2 * time.Millisecond, Attempts: 4}
+
+func TestBackoff_DelayIsCapped(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Millisecond, Max: 40 * time.Millisecond}
+	for attempt := 1; attempt < 10; attempt++ {
+		if d := b.Delay(attempt); d < 0 || d >= 40*time.Millisecond {
+			t.Errorf("attempt %d: delay %v out of range", attempt, d)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), fast, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on third call, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	cause := errors.New("bad request")
+	err = Retry(context.Background(), fast, func() error {
+		calls++
+		return Permanent(cause)
+	})
+	if err != cause || calls != 1 {
+		t.Errorf("permanent error should stop retries, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	Retry(context.Background(), fast, func() error {
+		calls++
+		return errors.New("down")
+	})
+	if calls != fast.Attempts {
+		t.Errorf("expected %d attempts, got %d", fast.Attempts, calls)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker("test")
+	if !tr.Health().Healthy {
+		t.Error("new tracker should be healthy")
+	}
+	tr.Failure(errors.New("refused"))
+	tr.Failure(errors.New("refused"))
+	h := tr.Health()
+	if h.Healthy || h.ConsecutiveFailures != 2 || h.LastError != "refused" {
+		t.Errorf("unexpected health after failures: %+v", h)
+	}
+	tr.Success()
+	h = tr.Health()
+	if !h.Healthy || h.ConsecutiveFailures != 0 || h.TotalFailures != 2 {
+		t.Errorf("unexpected health after recovery: %+v", h)
+	}
+}
+
+func TestDoHTTP_RetriesUnavailable(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tr := NewTracker("test")
+	resp, err := DoHTTP(context.Background(), server.Client(), tr, fast, func() (*http.Request, error) {
+		return http.NewRequest("GET", server.URL, nil)
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 3 {
+		t.Errorf("expected 200 after 3 calls, got %d after %d", resp.StatusCode, calls)
+	}
+	if h := tr.Health(); !h.Healthy || h.TotalFailures != 2 {
+		t.Errorf("unexpected health: %+v", h)
+	}
+}
+
+func TestDoHTTP_ConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	tr := NewTracker("test")
+	_, err := DoHTTP(context.Background(), http.DefaultClient, tr, fast, func() (*http.Request, error) {
+		return http.NewRequest("GET", url, nil)
+	})
+	if err == nil {
+		t.Fatal("expected error for closed server")
+	}
+	if h := tr.Health(); h.Healthy || h.ConsecutiveFailures != fast.Attempts {
+		t.Errorf("unexpected health: %+v", h)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	b := NewBreaker(2, 20*time.Millisecond)
+	b.Failure()
+	if b.Open() || b.Allow() != 0 {
+		t.Fatal("breaker should stay closed below the threshold")
+	}
+	b.Failure()
+	if !b.Open() || b.Allow() <= 0 {
+		t.Fatal("breaker should open at the threshold")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if b.Allow() != 0 {
+		t.Fatal("a trial call should be allowed after the cooldown")
+	}
+	if b.Allow() <= 0 {
+		t.Error("only one trial call should be allowed")
+	}
+	b.Failure()
+	if b.Allow() <= 0 {
+		t.Error("a failed trial should reopen the breaker")
+	}
+
+	b.Success()
+	if b.Open() || b.Allow() != 0 {
+		t.Error("a success should close the breaker")
+	}
+}
+
+func TestDoHTTP_CircuitBreaker(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tr := NewTracker("test")
+	tr.SetBreaker(NewBreaker(2, 10*time.Millisecond))
+	newRequest := func() (*http.Request, error) { return http.NewRequest("GET", server.URL, nil) }
+
+	// Two failures open the breaker, which holds back the third attempt
+	_, err := DoHTTP(context.Background(), http.DefaultClient, tr, Backoff{Attempts: 3}, newRequest)
+	if !errors.Is(err, ErrCircuitOpen) || calls != 2 {
+		t.Fatalf("expected ErrCircuitOpen after 2 calls, got %v after %d", err, calls)
+	}
+	if !tr.Health().CircuitOpen {
+		t.Error("health should report the open circuit")
+	}
+
+	// Waiting out the cooldown lets trial calls through until one succeeds
+	resp, err := DoHTTP(context.Background(), http.DefaultClient, tr, Backoff{Attempts: 10}, newRequest)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected success once the backend recovers, got %v", err)
+	}
+	resp.Body.Close()
+	if calls != 4 || tr.Health().CircuitOpen {
+		t.Errorf("expected one failed and one successful trial, got %d calls, health %+v", calls, tr.Health())
+	}
+}
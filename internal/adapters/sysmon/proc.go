@@ -0,0 +1,148 @@
+// Package sysmon samples the host's CPU and memory use for the resource
+// guardrails, so a small box can shed load before it starts swapping.
+package sysmon
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// firstWindow is how long the first sample watches the CPU, as there is
+// no earlier one to compare with.
+const firstWindow = 200 * time.Millisecond
+
+// ProcMonitor implements ports.ResourceMonitor from the Linux /proc
+// filesystem. Other systems have no /proc, so Usage fails there.
+type ProcMonitor struct {
+	root string
+
+	mu         sync.Mutex
+	busy, idle uint64 // CPU jiffies at the previous sample
+	sampled    bool
+}
+
+// NewProcMonitor creates a monitor reading /proc.
+func NewProcMonitor() *ProcMonitor {
+	return &ProcMonitor{root: "/proc"}
+}
+
+// Usage returns the CPU use since the previous call and the memory in
+// use now. Memory the kernel can reclaim, such as the page cache,
+// counts as free.
+func (m *ProcMonitor) Usage(ctx context.Context) (ports.ResourceUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	busy, idle, err := m.cpuTimes()
+	if err != nil {
+		return ports.ResourceUsage{}, err
+	}
+	if !m.sampled {
+		timer := time.NewTimer(firstWindow)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ports.ResourceUsage{}, ctx.Err()
+		}
+		m.busy, m.idle = busy, idle
+		if busy, idle, err = m.cpuTimes(); err != nil {
+			return ports.ResourceUsage{}, err
+		}
+	}
+
+	var usage ports.ResourceUsage
+	if total := (busy - m.busy) + (idle - m.idle); total > 0 {
+		usage.CPU = float64(busy-m.busy) / float64(total)
+	}
+	m.busy, m.idle, m.sampled = busy, idle, true
+
+	if usage.Memory, err = m.memoryUsed(); err != nil {
+		return ports.ResourceUsage{}, err
+	}
+	return usage, nil
+}
+
+// cpuTimes reads the jiffies all CPUs spent busy and idle since boot
+// from the first line of /proc/stat.
+func (m *ProcMonitor) cpuTimes() (busy, idle uint64, err error) {
+	f, err := os.Open(filepath.Join(m.root, "stat"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading CPU times: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		return 0, 0, fmt.Errorf("reading CPU times: empty %s", f.Name())
+	}
+	fields := strings.Fields(sc.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("reading CPU times: unexpected line %q", sc.Text())
+	}
+	// user nice system idle iowait irq softirq steal; guest time is
+	// already counted in user and nice
+	for i, field := range fields[1:] {
+		if i >= 8 {
+			break
+		}
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("reading CPU times: %w", err)
+		}
+		if i == 3 || i == 4 {
+			idle += n
+		} else {
+			busy += n
+		}
+	}
+	return busy, idle, nil
+}
+
+// memoryUsed reads the fraction of memory in use from /proc/meminfo.
+func (m *ProcMonitor) memoryUsed() (float64, error) {
+	f, err := os.Open(filepath.Join(m.root, "meminfo"))
+	if err != nil {
+		return 0, fmt.Errorf("reading memory use: %w", err)
+	}
+	defer f.Close()
+
+	var total, available uint64
+	var haveAvailable bool
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = n
+		case "MemAvailable:":
+			available, haveAvailable = n, true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("reading memory use: %w", err)
+	}
+	if total == 0 || !haveAvailable {
+		return 0, fmt.Errorf("reading memory use: no MemTotal or MemAvailable in %s", f.Name())
+	}
+	if available > total {
+		available = total
+	}
+	return float64(total-available) / float64(total), nil
+}
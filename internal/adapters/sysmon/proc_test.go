@@ -0,0 +1,54 @@
+package sysmon
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProc(t *testing.T, dir, stat string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcMonitor_Usage(t *testing.T) {
+	dir := t.TempDir()
+	meminfo := "MemTotal:       16000000 kB\nMemFree:         1000000 kB\nMemAvailable:    4000000 kB\nCached:          3000000 kB\n"
+	if err := os.WriteFile(filepath.Join(dir, "meminfo"), []byte(meminfo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeProc(t, dir, "cpu  100 0 100 800 0 0 0 0 0 0\ncpu0 100 0 100 800 0 0 0 0 0 0\n")
+
+	m := &ProcMonitor{root: dir}
+	first, err := m.Usage(context.Background())
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if first.CPU != 0 {
+		t.Errorf("first CPU = %v, want 0 with unchanged times", first.CPU)
+	}
+	if math.Abs(first.Memory-0.75) > 1e-9 {
+		t.Errorf("Memory = %v, want 0.75", first.Memory)
+	}
+
+	// 300 busy and 100 idle jiffies since the previous sample
+	writeProc(t, dir, "cpu  300 0 200 850 50 0 0 0 0 0\n")
+	second, err := m.Usage(context.Background())
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if math.Abs(second.CPU-0.75) > 1e-9 {
+		t.Errorf("CPU = %v, want 0.75", second.CPU)
+	}
+}
+
+func TestProcMonitor_MissingProc(t *testing.T) {
+	m := &ProcMonitor{root: filepath.Join(t.TempDir(), "missing")}
+	if _, err := m.Usage(context.Background()); err == nil {
+		t.Error("expected an error without /proc")
+	}
+}
@@ -0,0 +1,773 @@
+// Package vectordb provides vector store adapters.
+// Clean Architecture: Adapter implementing ports.VectorStore.
+// BoltStore provides persistent storage in pure Go (no CGO required).
+package vectordb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	chunksBucket      = []byte("chunks")      // chunkID -> boltChunk
+	docsBucket        = []byte("docs")        // docID -> nested bucket of chunkIDs
+	documentsBucket   = []byte("documents")   // docID -> boltDocument
+	collectionsBucket = []byte("collections") // name -> nested chunks/docs/documents buckets
+	modelsBucket      = []byte("models")      // collection name -> boltModel
+)
+
+// BoltStore implements ports.VectorStore with bbolt-based persistence.
+// Unlike LanceDBStore it needs no C toolchain, so it cross-compiles
+// cleanly for ARM NAS devices and Windows with CGO_ENABLED=0.
+type BoltStore struct {
+	db         *bolt.DB
+	dataPath   string
+	collection string // Empty for the default collection
+}
+
+// boltChunk is the on-disk representation of a chunk.
+type boltChunk struct {
+	ID         string    `json:"id"`
+	DocumentID string    `json:"document_id"`
+	Content    string    `json:"content"`
+	Index      int       `json:"index"`
+	Embedding  []float32 `json:"embedding"`
+	Hash       string    `json:"hash,omitempty"`
+	Page       int       `json:"page,omitempty"`
+}
+
+// boltDocument is the on-disk registry entry for a document.
+type boltDocument struct {
+	Name       string               `json:"name"`
+	Path       string               `json:"path"`
+	IngestedAt time.Time            `json:"ingested_at"`
+	Metadata   map[string]string    `json:"metadata,omitempty"`
+	Provenance []transformationJSON `json:"provenance,omitempty"`
+}
+
+// boltModel is the on-disk record of a collection's embedding model.
+type boltModel struct {
+	Name      string `json:"name"`
+	Dimension int    `json:"dimension"`
+}
+
+// boltBuckets groups the per-collection buckets.
+type boltBuckets struct {
+	chunks    *bolt.Bucket
+	docs      *bolt.Bucket
+	documents *bolt.Bucket
+}
+
+// NewBoltStore creates a new persistent vector store backed by a bbolt file.
+func NewBoltStore(dataPath string) (*BoltStore, error) {
+	if dataPath == "" {
+		dataPath = "./data"
+	}
+
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return nil, fmt.Errorf("creating data directory: %w", err)
+	}
+
+	dbPath := filepath.Join(dataPath, "vectors.bolt")
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	store := &BoltStore{
+		db:       db,
+		dataPath: dataPath,
+	}
+
+	if err := store.initBuckets(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing buckets: %w", err)
+	}
+
+	return store, nil
+}
+
+// initBuckets creates the top-level buckets.
+func (s *BoltStore) initBuckets() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{chunksBucket, docsBucket, documentsBucket, collectionsBucket, modelsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Collection returns a store scoped to the named collection.
+// The default collection keeps using the top-level buckets, so files
+// written before collections existed remain readable.
+func (s *BoltStore) Collection(name string) ports.VectorStore {
+	if name == entities.DefaultCollection {
+		name = ""
+	}
+	return &BoltStore{db: s.db, dataPath: s.dataPath, collection: name}
+}
+
+// Collections lists collections that hold data.
+func (s *BoltStore) Collections(ctx context.Context) ([]string, error) {
+	names := []string{entities.DefaultCollection}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(collectionsBucket).ForEachBucket(func(k []byte) error {
+			chunksB := tx.Bucket(collectionsBucket).Bucket(k).Bucket(chunksBucket)
+			if chunksB != nil && chunksB.Stats().KeyN > 0 {
+				names = append(names, string(k))
+			}
+			return nil
+		})
+	})
+	return names, err
+}
+
+// swapBucket is a top-level bucket holding one collection's data while
+// SwapCollections moves the other's; it exists only inside that
+// transaction.
+var swapBucket = []byte("swap")
+
+// SwapCollections exchanges the contents of two collections in one
+// transaction, parking one in a temporary bucket while the other is
+// copied over it.
+func (s *BoltStore) SwapCollections(ctx context.Context, a, b string) error {
+	if a == entities.DefaultCollection {
+		a = ""
+	}
+	if b == entities.DefaultCollection {
+		b = ""
+	}
+	if a == b {
+		return nil
+	}
+	x := &BoltStore{db: s.db, dataPath: s.dataPath, collection: a}
+	y := &BoltStore{db: s.db, dataPath: s.dataPath, collection: b}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		parked, err := tx.CreateBucket(swapBucket)
+		if err != nil {
+			return fmt.Errorf("creating swap bucket: %w", err)
+		}
+		held := &boltBuckets{}
+		for _, nb := range []struct {
+			name []byte
+			dst  **bolt.Bucket
+		}{{chunksBucket, &held.chunks}, {docsBucket, &held.docs}, {documentsBucket, &held.documents}} {
+			if *nb.dst, err = parked.CreateBucket(nb.name); err != nil {
+				return err
+			}
+		}
+
+		// x -> held, y -> x, held -> y
+		if err := moveBuckets(tx, held, x); err != nil {
+			return err
+		}
+		xb, err := x.buckets(tx)
+		if err != nil {
+			return err
+		}
+		if err := moveBuckets(tx, xb, y); err != nil {
+			return err
+		}
+		yb, err := y.buckets(tx)
+		if err != nil {
+			return err
+		}
+		if err := copyBuckets(yb, held); err != nil {
+			return fmt.Errorf("swapping collections: %w", err)
+		}
+		if err := tx.DeleteBucket(swapBucket); err != nil {
+			return err
+		}
+
+		models := tx.Bucket(modelsBucket)
+		xm := append([]byte(nil), models.Get(x.modelKey())...)
+		ym := append([]byte(nil), models.Get(y.modelKey())...)
+		for _, m := range []struct {
+			key   []byte
+			model []byte
+		}{{x.modelKey(), ym}, {y.modelKey(), xm}} {
+			if len(m.model) == 0 {
+				err = models.Delete(m.key)
+			} else {
+				err = models.Put(m.key, m.model)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// moveBuckets copies src's collection into dst and empties it.
+func moveBuckets(tx *bolt.Tx, dst *boltBuckets, src *BoltStore) error {
+	b, err := src.buckets(tx)
+	if err != nil {
+		return err
+	}
+	if err := copyBuckets(dst, b); err != nil {
+		return fmt.Errorf("swapping collections: %w", err)
+	}
+	return src.dropBuckets(tx)
+}
+
+// copyBuckets copies a collection's buckets into another's empty ones.
+func copyBuckets(dst, src *boltBuckets) error {
+	for _, pair := range [][2]*bolt.Bucket{{dst.chunks, src.chunks}, {dst.docs, src.docs}, {dst.documents, src.documents}} {
+		if err := copyBucket(pair[0], pair[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buckets resolves the buckets for this collection.
+// In read-only transactions a missing collection yields nil.
+func (s *BoltStore) buckets(tx *bolt.Tx) (*boltBuckets, error) {
+	if s.collection == "" {
+		return &boltBuckets{
+			chunks:    tx.Bucket(chunksBucket),
+			docs:      tx.Bucket(docsBucket),
+			documents: tx.Bucket(documentsBucket),
+		}, nil
+	}
+
+	parent := tx.Bucket(collectionsBucket)
+	if !tx.Writable() {
+		col := parent.Bucket([]byte(s.collection))
+		if col == nil {
+			return nil, nil
+		}
+		return &boltBuckets{
+			chunks:    col.Bucket(chunksBucket),
+			docs:      col.Bucket(docsBucket),
+			documents: col.Bucket(documentsBucket),
+		}, nil
+	}
+
+	col, err := parent.CreateBucketIfNotExists([]byte(s.collection))
+	if err != nil {
+		return nil, fmt.Errorf("creating collection: %w", err)
+	}
+	b := &boltBuckets{}
+	for _, nb := range []struct {
+		name []byte
+		dst  **bolt.Bucket
+	}{{chunksBucket, &b.chunks}, {docsBucket, &b.docs}, {documentsBucket, &b.documents}} {
+		if *nb.dst, err = col.CreateBucketIfNotExists(nb.name); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Store saves chunks with their embeddings.
+func (s *BoltStore) Store(ctx context.Context, chunks []entities.Chunk) error {
+	return s.Upsert(ctx, chunks, ports.ConflictVersion)
+}
+
+// Upsert is Store with an explicit policy for chunks already stored.
+func (s *BoltStore) Upsert(ctx context.Context, chunks []entities.Chunk, policy ports.ConflictPolicy) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := s.buckets(tx)
+		if err != nil {
+			return err
+		}
+		if err := checkChunkDimensions(dimension(b), chunks); err != nil {
+			return err
+		}
+
+		for _, chunk := range chunks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if old := b.chunks.Get([]byte(chunk.ID)); old != nil {
+				if keepStored(policy, chunkHash(old), chunk) {
+					continue
+				}
+				if err := unlinkChunk(b, chunkDocument(old), chunk); err != nil {
+					return fmt.Errorf("moving chunk: %w", err)
+				}
+			}
+
+			data, err := json.Marshal(boltChunk{
+				ID:         chunk.ID,
+				DocumentID: chunk.DocumentID,
+				Content:    chunk.Content,
+				Index:      chunk.Index,
+				Embedding:  chunk.Embedding,
+				Hash:       chunk.Hash,
+				Page:       chunk.Page,
+			})
+			if err != nil {
+				return fmt.Errorf("encoding chunk: %w", err)
+			}
+			if err := b.chunks.Put([]byte(chunk.ID), data); err != nil {
+				return fmt.Errorf("inserting chunk: %w", err)
+			}
+
+			docB, err := b.docs.CreateBucketIfNotExists([]byte(chunk.DocumentID))
+			if err != nil {
+				return fmt.Errorf("creating document bucket: %w", err)
+			}
+			if err := docB.Put([]byte(chunk.ID), nil); err != nil {
+				return fmt.Errorf("indexing chunk: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ChunkHashes returns the content hash of each stored chunk of a document.
+func (s *BoltStore) ChunkHashes(ctx context.Context, documentID string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b, err := s.buckets(tx)
+		if err != nil || b == nil {
+			return err
+		}
+		docB := b.docs.Bucket([]byte(documentID))
+		if docB == nil {
+			return nil
+		}
+		return docB.ForEach(func(k, _ []byte) error {
+			if data := b.chunks.Get(k); data != nil {
+				hashes[string(k)] = chunkHash(data)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading chunk hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// DocumentChunks returns a document's stored chunks in order, without
+// their embeddings.
+func (s *BoltStore) DocumentChunks(ctx context.Context, documentID string) ([]entities.Chunk, error) {
+	var chunks []entities.Chunk
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b, err := s.buckets(tx)
+		if err != nil || b == nil {
+			return err
+		}
+		docB := b.docs.Bucket([]byte(documentID))
+		if docB == nil {
+			return nil
+		}
+		return docB.ForEach(func(k, _ []byte) error {
+			data := b.chunks.Get(k)
+			if data == nil {
+				return nil
+			}
+			var rec boltChunk
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return fmt.Errorf("decoding chunk %s: %w", k, err)
+			}
+			chunks = append(chunks, entities.Chunk{
+				ID:         rec.ID,
+				DocumentID: rec.DocumentID,
+				Content:    rec.Content,
+				Index:      rec.Index,
+				Hash:       rec.Hash,
+				Page:       rec.Page,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading chunks: %w", err)
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Index < chunks[j].Index })
+	return chunks, nil
+}
+
+// chunkHash extracts the content hash from an encoded boltChunk.
+func chunkHash(data []byte) string {
+	var rec struct {
+		Hash string `json:"hash"`
+	}
+	json.Unmarshal(data, &rec)
+	return rec.Hash
+}
+
+// chunkDocument returns the document ID of an encoded boltChunk.
+func chunkDocument(data []byte) string {
+	var rec struct {
+		DocumentID string `json:"document_id"`
+	}
+	json.Unmarshal(data, &rec)
+	return rec.DocumentID
+}
+
+// unlinkChunk removes chunk from the index of documentID when it is
+// moving to another document, dropping a document left without chunks.
+func unlinkChunk(b *boltBuckets, documentID string, chunk entities.Chunk) error {
+	if documentID == chunk.DocumentID {
+		return nil
+	}
+	docB := b.docs.Bucket([]byte(documentID))
+	if docB == nil {
+		return nil
+	}
+	if err := docB.Delete([]byte(chunk.ID)); err != nil {
+		return err
+	}
+	if k, _ := docB.Cursor().First(); k != nil {
+		return nil
+	}
+	return deleteDocument(b, documentID)
+}
+
+// Search finds the most similar chunks to a query embedding.
+func (s *BoltStore) Search(ctx context.Context, embedding []float32, topK int) ([]entities.QueryResult, error) {
+	return s.search(ctx, embedding, topK, nil)
+}
+
+// SearchDocuments is Search restricted to chunks of the given documents.
+// It reads only those documents' chunks via the docs index.
+func (s *BoltStore) SearchDocuments(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	if len(documentIDs) == 0 {
+		return nil, nil
+	}
+	return s.search(ctx, embedding, topK, documentIDs)
+}
+
+// search ranks the collection's chunks, only those of documentIDs when
+// it is non-nil.
+func (s *BoltStore) search(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	type scored struct {
+		chunk entities.Chunk
+		score float64
+		doc   string
+	}
+
+	var results []scored
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b, err := s.buckets(tx)
+		if err != nil || b == nil {
+			return err
+		}
+		if err := checkQueryDimension(dimension(b), len(embedding)); err != nil {
+			return err
+		}
+
+		// Brute force scan, same as LanceDBStore
+		score := func(v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var rec boltChunk
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return nil // Skip corrupted records
+			}
+
+			chunk := entities.Chunk{
+				ID:         rec.ID,
+				DocumentID: rec.DocumentID,
+				Content:    rec.Content,
+				Index:      rec.Index,
+				Embedding:  rec.Embedding,
+				Page:       rec.Page,
+			}
+			results = append(results, scored{
+				chunk: chunk,
+				score: cosineSimilarity(embedding, chunk.Embedding),
+				doc:   documentName(b, chunk.DocumentID),
+			})
+			return nil
+		}
+
+		if documentIDs == nil {
+			return b.chunks.ForEach(func(k, v []byte) error { return score(v) })
+		}
+		seen := make(map[string]bool, len(documentIDs))
+		for _, id := range documentIDs {
+			docB := b.docs.Bucket([]byte(id))
+			if docB == nil || seen[id] {
+				continue
+			}
+			seen[id] = true
+			err := docB.ForEach(func(k, _ []byte) error {
+				if v := b.chunks.Get(k); v != nil {
+					return score(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrDimensionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning chunks: %w", err)
+	}
+
+	// Sort by score descending
+	sort.Slice(results, func(i, j int) bool {
+		return ranksBefore(results[i].score, results[j].score, results[i].chunk.ID, results[j].chunk.ID)
+	})
+
+	// Take top K
+	if len(results) > topK {
+		results = results[:topK]
+	}
+
+	// Convert to QueryResult
+	queryResults := make([]entities.QueryResult, len(results))
+	for i, r := range results {
+		queryResults[i] = entities.QueryResult{
+			Chunk:     r.chunk,
+			Score:     r.score,
+			SourceDoc: r.doc,
+		}
+	}
+
+	return queryResults, nil
+}
+
+// dimension returns the width of the collection's stored embeddings,
+// or 0 when it is empty.
+func dimension(b *boltBuckets) int {
+	_, v := b.chunks.Cursor().First()
+	if v == nil {
+		return 0
+	}
+	var rec boltChunk
+	if err := json.Unmarshal(v, &rec); err != nil {
+		return 0
+	}
+	return len(rec.Embedding)
+}
+
+// Delete removes all chunks for a document.
+func (s *BoltStore) Delete(ctx context.Context, documentID string) error {
+	return s.DeleteMany(ctx, []string{documentID})
+}
+
+// DeleteMany removes all chunks of several documents in one transaction.
+func (s *BoltStore) DeleteMany(ctx context.Context, documentIDs []string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := s.buckets(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range documentIDs {
+			if err := deleteDocument(b, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deleteDocument removes a document's chunks and registry entry.
+func deleteDocument(b *boltBuckets, documentID string) error {
+	if err := b.documents.Delete([]byte(documentID)); err != nil {
+		return err
+	}
+	docB := b.docs.Bucket([]byte(documentID))
+	if docB == nil {
+		return nil
+	}
+
+	if err := docB.ForEach(func(k, _ []byte) error {
+		return b.chunks.Delete(k)
+	}); err != nil {
+		return err
+	}
+	return b.docs.DeleteBucket([]byte(documentID))
+}
+
+// Clear removes all data from the store.
+func (s *BoltStore) Clear(ctx context.Context) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(modelsBucket).Delete(s.modelKey()); err != nil {
+			return err
+		}
+		return s.dropBuckets(tx)
+	})
+}
+
+// dropBuckets empties the collection's buckets. A named collection's are
+// recreated by its next write.
+func (s *BoltStore) dropBuckets(tx *bolt.Tx) error {
+	if s.collection != "" {
+		err := tx.Bucket(collectionsBucket).DeleteBucket([]byte(s.collection))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	}
+
+	for _, name := range [][]byte{chunksBucket, docsBucket, documentsBucket} {
+		if err := tx.DeleteBucket(name); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmbeddingModel returns the model recorded for the collection.
+func (s *BoltStore) EmbeddingModel(ctx context.Context) (ports.EmbeddingModel, error) {
+	var model boltModel
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(modelsBucket).Get(s.modelKey())
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &model)
+	})
+	if err != nil {
+		return ports.EmbeddingModel{}, fmt.Errorf("reading embedding model: %w", err)
+	}
+	return ports.EmbeddingModel{Name: model.Name, Dimension: model.Dimension}, nil
+}
+
+// RecordEmbeddingModel records the model the collection is embedded with.
+func (s *BoltStore) RecordEmbeddingModel(ctx context.Context, model ports.EmbeddingModel) error {
+	data, err := json.Marshal(boltModel{Name: model.Name, Dimension: model.Dimension})
+	if err != nil {
+		return fmt.Errorf("encoding embedding model: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(modelsBucket).Put(s.modelKey(), data)
+	})
+}
+
+// modelKey is the collection's key in modelsBucket.
+func (s *BoltStore) modelKey() []byte {
+	if s.collection == "" {
+		return []byte(entities.DefaultCollection)
+	}
+	return []byte(s.collection)
+}
+
+// Close closes the database file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// ChunkCount returns the number of stored chunks.
+func (s *BoltStore) ChunkCount(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b, err := s.buckets(tx)
+		if err != nil || b == nil {
+			return err
+		}
+		count = b.chunks.Stats().KeyN
+		return nil
+	})
+	return count, err
+}
+
+// Stats reports the collection's contents. SizeBytes is the size of the
+// whole database file.
+func (s *BoltStore) Stats(ctx context.Context) (ports.StoreStats, error) {
+	var stats ports.StoreStats
+	err := s.db.View(func(tx *bolt.Tx) error {
+		stats.SizeBytes = tx.Size()
+		b, err := s.buckets(tx)
+		if err != nil || b == nil {
+			return err
+		}
+		stats.Chunks = b.chunks.Stats().KeyN
+		stats.Dimension = dimension(b)
+		return b.docs.ForEachBucket(func(k []byte) error {
+			stats.Documents++
+			return nil
+		})
+	})
+	if err != nil {
+		return stats, fmt.Errorf("reading stats: %w", err)
+	}
+	return stats, nil
+}
+
+// RegisterDocument records or updates a document's metadata.
+func (s *BoltStore) RegisterDocument(ctx context.Context, info entities.DocumentInfo) error {
+	data, err := json.Marshal(boltDocument{Name: info.Name, Path: info.Path, IngestedAt: info.IngestedAt, Metadata: info.Metadata, Provenance: provenanceToJSON(info.Provenance)})
+	if err != nil {
+		return fmt.Errorf("encoding document: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := s.buckets(tx)
+		if err != nil {
+			return err
+		}
+		return b.documents.Put([]byte(info.ID), data)
+	})
+}
+
+// ListDocuments returns the documents that have stored chunks and match
+// filter, one page at a time.
+func (s *BoltStore) ListDocuments(ctx context.Context, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error) {
+	var docs []entities.DocumentInfo
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b, err := s.buckets(tx)
+		if err != nil || b == nil {
+			return err
+		}
+		return b.docs.ForEachBucket(func(k []byte) error {
+			info := entities.DocumentInfo{
+				ID:         string(k),
+				Name:       string(k),
+				ChunkCount: b.docs.Bucket(k).Stats().KeyN,
+			}
+			if b.documents == nil {
+				docs = append(docs, info) // Collection predates the registry
+				return nil
+			}
+			var rec boltDocument
+			if v := b.documents.Get(k); v != nil && json.Unmarshal(v, &rec) == nil {
+				info.Name = rec.Name
+				info.Path = rec.Path
+				info.IngestedAt = rec.IngestedAt
+				info.Metadata = rec.Metadata
+				info.Provenance = provenanceFromJSON(rec.Provenance)
+			}
+			docs = append(docs, info)
+			return nil
+		})
+	})
+	if err != nil {
+		return entities.DocumentPage{}, err
+	}
+	return pageDocuments(docs, filter, page), nil
+}
+
+// documentName returns the registered name for citations, falling back to the ID.
+func documentName(b *boltBuckets, documentID string) string {
+	if b.documents == nil {
+		return documentID
+	}
+	var rec boltDocument
+	if v := b.documents.Get([]byte(documentID)); v != nil && json.Unmarshal(v, &rec) == nil && rec.Name != "" {
+		return rec.Name
+	}
+	return documentID
+}
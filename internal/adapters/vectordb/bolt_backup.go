@@ -0,0 +1,108 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Backup writes a consistent snapshot of the whole file (all
+// collections) to path. It runs in a read transaction, so searches
+// and writes continue meanwhile.
+func (s *BoltStore) Backup(ctx context.Context, path string) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		if err := tx.CopyFile(path, 0600); err != nil {
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+		return nil
+	})
+}
+
+// Restore replaces the store contents with a snapshot written by Backup.
+// The snapshot is copied in a single transaction, so a failed restore
+// leaves the current data untouched.
+func (s *BoltStore) Restore(ctx context.Context, path string) error {
+	snap, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true, Timeout: 1 * time.Second})
+	if err != nil {
+		return fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer snap.Close()
+
+	return snap.View(func(src *bolt.Tx) error {
+		if src.Bucket(chunksBucket) == nil {
+			return fmt.Errorf("%s is not a LocalRAG snapshot", path)
+		}
+		return s.db.Update(func(dst *bolt.Tx) error {
+			var names [][]byte
+			dst.ForEach(func(name []byte, _ *bolt.Bucket) error {
+				names = append(names, append([]byte(nil), name...))
+				return nil
+			})
+			for _, name := range names {
+				if err := dst.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+
+			err := src.ForEach(func(name []byte, b *bolt.Bucket) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				nb, err := dst.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+				return copyBucket(nb, b)
+			})
+			if err != nil {
+				return fmt.Errorf("copying snapshot: %w", err)
+			}
+
+			// Snapshots from older versions may lack newer buckets
+			for _, name := range [][]byte{chunksBucket, docsBucket, documentsBucket, collectionsBucket, modelsBucket} {
+				if _, err := dst.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// OpenSnapshot opens a snapshot written by Backup read-only, in place.
+func (s *BoltStore) OpenSnapshot(ctx context.Context, path string) (ports.SnapshotStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true, Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening snapshot: %w", err)
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{chunksBucket, docsBucket, documentsBucket, collectionsBucket} {
+			if tx.Bucket(name) == nil {
+				return fmt.Errorf("%s is not a LocalRAG snapshot", path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// copyBucket recursively copies keys and nested buckets from src to dst.
+func copyBucket(dst, src *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(nested, src.Bucket(k))
+	})
+}
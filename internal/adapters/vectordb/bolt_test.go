@@ -0,0 +1,330 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestBoltStore_StoreAndSearch(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewBoltStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	chunks := []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "hello", Embedding: []float32{1.0, 0.0, 0.0}},
+		{ID: "c2", DocumentID: "doc1", Content: "world", Embedding: []float32{0.0, 1.0, 0.0}},
+	}
+
+	if err := store.Store(ctx, chunks); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	results, err := store.Search(ctx, []float32{1.0, 0.0, 0.0}, 2)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Errorf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Chunk.ID != "c1" {
+		t.Error("c1 should be top result")
+	}
+}
+
+func TestBoltStore_Delete(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "test", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc2", Content: "keep", Embedding: []float32{0, 1, 0}},
+	})
+
+	if err := store.Delete(ctx, "doc1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	results, _ := store.Search(ctx, []float32{1, 0, 0}, 10)
+	if len(results) != 1 || results[0].Chunk.ID != "c2" {
+		t.Errorf("only c2 should remain, got %v", results)
+	}
+}
+
+func TestBoltStore_Clear(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc1", Embedding: []float32{0, 1, 0}},
+	})
+
+	store.Clear(ctx)
+
+	count, _ := store.ChunkCount(ctx)
+	if count != 0 {
+		t.Errorf("expected 0 chunks after clear, got %d", count)
+	}
+}
+
+func TestBoltStore_PersistsAcrossReopen(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	store, _ := NewBoltStore(dir)
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "persisted", Embedding: []float32{1, 0, 0}},
+	})
+	store.Close()
+
+	reopened, err := NewBoltStore(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+
+	results, _ := reopened.Search(ctx, []float32{1, 0, 0}, 1)
+	if len(results) != 1 || results[0].Chunk.Content != "persisted" {
+		t.Error("chunk should survive reopen")
+	}
+}
+
+func TestBoltStore_Collections(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	work := store.Collection("work")
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "personal", Embedding: []float32{1, 0, 0}}})
+	work.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "work", Embedding: []float32{1, 0, 0}}})
+
+	results, _ := work.Search(ctx, []float32{1, 0, 0}, 10)
+	if len(results) != 1 || results[0].Chunk.Content != "work" {
+		t.Errorf("work collection should only see its own chunk, got %v", results)
+	}
+
+	names, _ := store.Collections(ctx)
+	if len(names) != 2 || names[1] != "work" {
+		t.Errorf("unexpected collections: %v", names)
+	}
+
+	// Searching a collection that was never written is not an error
+	results, err := store.Collection("empty").Search(ctx, []float32{1, 0, 0}, 10)
+	if err != nil || len(results) != 0 {
+		t.Errorf("empty collection search: %v, %v", results, err)
+	}
+
+	work.Clear(ctx)
+	count, _ := store.ChunkCount(ctx)
+	if count != 1 {
+		t.Errorf("clearing work should keep default chunk, got %d", count)
+	}
+}
+
+func TestBoltStore_ListDocuments(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc1", Embedding: []float32{0, 1, 0}},
+	})
+	store.RegisterDocument(ctx, entities.DocumentInfo{ID: "doc1", Name: "notes.txt", Path: "/docs/notes.txt"})
+
+	page, err := store.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+	docs := page.Documents
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Name != "notes.txt" || docs[0].ChunkCount != 2 {
+		t.Errorf("unexpected documents: %+v", docs)
+	}
+
+	results, _ := store.Search(ctx, []float32{1, 0, 0}, 1)
+	if results[0].SourceDoc != "notes.txt" {
+		t.Errorf("expected source notes.txt, got %s", results[0].SourceDoc)
+	}
+
+	store.Delete(ctx, "doc1")
+	if page, _ := store.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{}); page.Total != 0 {
+		t.Error("deleted document should not be listed")
+	}
+}
+
+func TestBoltStore_ListDocumentsFilterAndPage(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testListDocuments(t, store)
+}
+
+func TestBoltStore_EmbeddingModel(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testEmbeddingModel(t, store)
+}
+
+func TestBoltStore_SwapCollections(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testSwapCollections(t, store)
+}
+
+func TestBoltStore_DimensionMismatch(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}}})
+
+	err := store.Store(ctx, []entities.Chunk{{ID: "c2", DocumentID: "doc2", Embedding: []float32{1, 0}}})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("expected ErrDimensionMismatch on store, got %v", err)
+	}
+	if _, err := store.Search(ctx, []float32{1, 0}, 1); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("expected ErrDimensionMismatch on search, got %v", err)
+	}
+}
+
+func TestBoltStore_BackupAndRestore(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(filepath.Join(dir, "data"))
+	defer store.Close()
+
+	ctx := context.Background()
+	work := store.Collection("work")
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "snapshotted", Embedding: []float32{1, 0, 0}}})
+	work.Store(ctx, []entities.Chunk{{ID: "w1", DocumentID: "doc2", Content: "work", Embedding: []float32{1, 0, 0}}})
+
+	snapshot := filepath.Join(dir, "index.snapshot")
+	if err := store.Backup(ctx, snapshot); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+
+	store.Clear(ctx)
+	work.Clear(ctx)
+	store.Store(ctx, []entities.Chunk{{ID: "c9", DocumentID: "doc9", Content: "newer", Embedding: []float32{1, 0, 0}}})
+
+	if err := store.Restore(ctx, snapshot); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	results, _ := store.Search(ctx, []float32{1, 0, 0}, 10)
+	if len(results) != 1 || results[0].Chunk.Content != "snapshotted" {
+		t.Errorf("default collection not restored: %v", results)
+	}
+	if results, _ := work.Search(ctx, []float32{1, 0, 0}, 10); len(results) != 1 {
+		t.Errorf("work collection not restored: %v", results)
+	}
+
+	// Delete still works on restored nested buckets
+	if err := store.Delete(ctx, "doc1"); err != nil {
+		t.Errorf("delete after restore: %v", err)
+	}
+}
+
+func TestBoltStore_OpenSnapshot(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(filepath.Join(dir, "data"))
+	defer store.Close()
+	testOpenSnapshot(t, store, filepath.Join(dir, "index.snapshot"))
+}
+
+func TestBoltStore_SkipsUnchangedChunks(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testSkipsUnchangedChunks(t, store)
+}
+
+func TestBoltStore_SearchDocuments(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testSearchDocuments(t, store)
+}
+
+func TestBoltStore_DeleteMany(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testDeleteMany(t, store)
+}
+
+func TestBoltStore_Upsert(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testUpsert(t, store)
+}
+
+func TestBoltStore_ChunkPages(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testChunkPages(t, store)
+}
+
+func TestBoltStore_Stats(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	if stats := testStats(t, store); stats.SizeBytes <= 0 {
+		t.Errorf("expected database size, got %d", stats.SizeBytes)
+	}
+}
@@ -0,0 +1,118 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	"github.com/0xcro3dile/localrag-go/internal/domain/usecases"
+)
+
+// wordEmbedder embeds text as a bag of hashed words, enough for the
+// store to rank something without an embedding backend.
+type wordEmbedder struct{}
+
+func (wordEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	emb := make([]float32, 16)
+	h := fnv.New32a()
+	for _, b := range []byte(text) {
+		if b == ' ' {
+			emb[h.Sum32()%16]++
+			h.Reset()
+			continue
+		}
+		h.Write([]byte{b})
+	}
+	emb[h.Sum32()%16]++
+	return emb, nil
+}
+
+func (e wordEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	embs := make([][]float32, len(texts))
+	for i, text := range texts {
+		embs[i], _ = e.Embed(ctx, text)
+	}
+	return embs, nil
+}
+
+// testConcurrentIngestAndQuery runs ingest, delete and search workloads
+// against one store at once; run it with -race.
+func testConcurrentIngestAndQuery(t *testing.T, store ports.VectorStore) {
+	t.Helper()
+	ingest := usecases.NewIngestUseCase(wordEmbedder{}, store, 40, 0)
+	query := usecases.NewQueryUseCase(wordEmbedder{}, store, nil, 3)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 3; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 30; i++ {
+				doc := &entities.Document{
+					ID:      fmt.Sprintf("notes-%d-%d", w, i%4),
+					Name:    fmt.Sprintf("notes-%d.md", i),
+					Content: fmt.Sprintf("meeting %d notes about release planning and budget review %d", i, w),
+				}
+				if err := ingest.Ingest(ctx, doc); err != nil {
+					t.Errorf("ingest failed: %v", err)
+					return
+				}
+				if i%6 == 5 {
+					if err := ingest.Delete(ctx, doc.ID); err != nil {
+						t.Errorf("delete failed: %v", err)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 3; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 30; i++ {
+				if _, err := query.Search(ctx, "release planning"); err != nil {
+					t.Errorf("search failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	results, err := query.Search(ctx, "budget review")
+	if err != nil || len(results) == 0 {
+		t.Errorf("expected results after the workload, got %v, %v", results, err)
+	}
+}
+
+func TestInMemoryStore_ConcurrentIngestAndQuery(t *testing.T) {
+	testConcurrentIngestAndQuery(t, NewInMemoryStore())
+}
+
+func TestLanceDBStore_ConcurrentIngestAndQuery(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewLanceDBStoreWithOptions(dir, LanceDBOptions{DisableNativeIndex: true, HotCache: true})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+	testConcurrentIngestAndQuery(t, store)
+}
+
+func TestBoltStore_ConcurrentIngestAndQuery(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bolt-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewBoltStore(dir)
+	defer store.Close()
+	testConcurrentIngestAndQuery(t, store)
+}
@@ -0,0 +1,66 @@
+package vectordb
+
+import (
+	"sort"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// pageDocuments filters, orders and pages a collection's full document
+// listing, for stores whose backend cannot do it in the query.
+func pageDocuments(docs []entities.DocumentInfo, filter entities.DocumentFilter, page entities.Page) entities.DocumentPage {
+	matched := make([]entities.DocumentInfo, 0, len(docs))
+	for _, doc := range docs {
+		if filter.Matches(doc) {
+			matched = append(matched, doc)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Name != matched[j].Name {
+			return matched[i].Name < matched[j].Name
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	result := entities.DocumentPage{Total: len(matched)}
+	start := min(max(page.Offset, 0), len(matched))
+	end := len(matched)
+	if page.Limit > 0 {
+		end = min(start+page.Limit, end)
+	}
+	result.Documents = matched[start:end]
+	return result
+}
+
+// transformationJSON is the stored form of a step of a document's
+// provenance.
+type transformationJSON struct {
+	Kind   string `json:"kind"`
+	Tool   string `json:"tool,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// provenanceToJSON converts a provenance chain to its stored form, nil
+// when it is empty.
+func provenanceToJSON(p entities.Provenance) []transformationJSON {
+	if len(p) == 0 {
+		return nil
+	}
+	out := make([]transformationJSON, len(p))
+	for i, t := range p {
+		out[i] = transformationJSON{Kind: t.Kind, Tool: t.Tool, Detail: t.Detail}
+	}
+	return out
+}
+
+// provenanceFromJSON converts a stored provenance chain back.
+func provenanceFromJSON(in []transformationJSON) entities.Provenance {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(entities.Provenance, len(in))
+	for i, t := range in {
+		out[i] = entities.Transformation{Kind: t.Kind, Tool: t.Tool, Detail: t.Detail}
+	}
+	return out
+}
@@ -6,15 +6,19 @@ package vectordb
 import (
 	"context"
 	"database/sql"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
 
@@ -22,13 +26,28 @@ import (
 // This is a simplified LanceDB-like implementation using SQLite for portability.
 // For production, swap with actual LanceDB Go bindings when available.
 type LanceDBStore struct {
-	mu       sync.RWMutex
-	db       *sql.DB
-	dataPath string
+	writeMu      *sync.Mutex   // Serializes writers; shared by all collection views
+	mu           *sync.RWMutex // Guards hybridAlpha and vec; never held across queries
+	db           *sql.DB
+	dataPath     string
+	collection   string
+	ftsEnabled   bool         // FTS5 keyword index available (see lancedb_hybrid.go)
+	hybridAlpha  float64      // Vector weight in HybridSearch
+	quantization Quantization // Encoding for new embeddings (see lancedb_quant.go)
+	normalize    bool         // Store unit-length embeddings (see lancedb_norm.go)
+	vec          *vecIndex    // sqlite-vec KNN index (see lancedb_vec.go)
+	cache        *hotCache    // Decoded rows for brute-force search (see lancedb_cache.go)
 }
 
-// NewLanceDBStore creates a new persistent vector store.
+// NewLanceDBStore creates a new persistent vector store, using sqlite-vec
+// for Search when the extension can be found.
 func NewLanceDBStore(dataPath string) (*LanceDBStore, error) {
+	return NewLanceDBStoreWithOptions(dataPath, LanceDBOptions{VecExtension: DefaultVecExtension})
+}
+
+// NewLanceDBStoreWithOptions creates a persistent vector store with
+// explicit control over optional SQLite extensions.
+func NewLanceDBStoreWithOptions(dataPath string, opts LanceDBOptions) (*LanceDBStore, error) {
 	if dataPath == "" {
 		dataPath = "./data"
 	}
@@ -38,15 +57,25 @@ func NewLanceDBStore(dataPath string) (*LanceDBStore, error) {
 		return nil, fmt.Errorf("creating data directory: %w", err)
 	}
 
+	// WAL lets searches read a consistent snapshot while ingestion
+	// writes; the busy timeout covers checkpoints and writer handoff.
 	dbPath := filepath.Join(dataPath, "vectors.db")
-	db, err := sql.Open("sqlite3", dbPath)
+	dsn := dbPath + "?_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
+	db, err := sql.Open(sqliteDriver(opts.VecExtension), dsn)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
 
 	store := &LanceDBStore{
-		db:       db,
-		dataPath: dataPath,
+		writeMu:      &sync.Mutex{},
+		mu:           &sync.RWMutex{},
+		db:           db,
+		dataPath:     dataPath,
+		collection:   entities.DefaultCollection,
+		hybridAlpha:  defaultHybridAlpha,
+		quantization: opts.Quantization,
+		normalize:    opts.Normalize,
+		cache:        &hotCache{enabled: opts.HotCache},
 	}
 
 	if err := store.initSchema(); err != nil {
@@ -54,31 +83,340 @@ func NewLanceDBStore(dataPath string) (*LanceDBStore, error) {
 		return nil, fmt.Errorf("initializing schema: %w", err)
 	}
 
+	store.initFTS()
+	store.initVec(opts.DisableNativeIndex)
+
 	return store, nil
 }
 
-// initSchema creates the necessary tables.
+// initSchema creates the necessary tables and upgrades older databases.
 func (s *LanceDBStore) initSchema() error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS chunks (
-		id TEXT PRIMARY KEY,
+		id TEXT NOT NULL,
+		collection TEXT NOT NULL DEFAULT 'default',
 		document_id TEXT NOT NULL,
 		content TEXT NOT NULL,
 		chunk_index INTEGER NOT NULL,
 		embedding BLOB NOT NULL,
+		encoding INTEGER NOT NULL DEFAULT 0,
+		content_hash TEXT NOT NULL DEFAULT '',
+		norm REAL NOT NULL DEFAULT 0,
+		page INTEGER NOT NULL DEFAULT 0,
 		source_doc TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (collection, id)
+	);
+	CREATE TABLE IF NOT EXISTS documents (
+		id TEXT NOT NULL,
+		collection TEXT NOT NULL DEFAULT 'default',
+		name TEXT NOT NULL,
+		path TEXT,
+		ingested_at DATETIME NOT NULL,
+		metadata TEXT NOT NULL DEFAULT '{}',
+		provenance TEXT NOT NULL DEFAULT '[]',
+		PRIMARY KEY (collection, id)
+	);
+	CREATE TABLE IF NOT EXISTS collection_models (
+		collection TEXT PRIMARY KEY,
+		model TEXT NOT NULL,
+		dimension INTEGER NOT NULL
 	);
-	CREATE INDEX IF NOT EXISTS idx_document_id ON chunks(document_id);
 	`
-	_, err := s.db.Exec(schema)
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	if err := s.migrate(); err != nil {
+		return fmt.Errorf("migrating schema: %w", err)
+	}
+
+	_, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_collection_document ON chunks(collection, document_id)`)
 	return err
 }
 
+// schemaVersion is stored in SQLite's user_version pragma.
+// Version 1: embeddings are packed little-endian float32 blobs (was JSON).
+// Version 2: chunks carry a collection; primary key is (collection, id).
+// Version 3: chunks record their embedding encoding (float32 or int8).
+// Version 4: chunks record a content hash for incremental re-ingestion.
+// Version 5: documents record user-defined metadata as a JSON object.
+// Version 6: documents record their provenance as a JSON array.
+// Version 7: chunks record their embedding's L2 norm (0 if unknown).
+// Version 8: chunks record the page of their source they come from (0 if unknown).
+const schemaVersion = 8
+
+// migrate upgrades databases written by older versions in place.
+func (s *LanceDBStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version >= schemaVersion {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if version < 1 {
+		if err := migrateJSONEmbeddings(tx); err != nil {
+			return err
+		}
+	}
+	if version < 2 {
+		if err := migrateCollections(tx); err != nil {
+			return err
+		}
+	}
+	if version < 3 {
+		if err := addColumn(tx, "chunks", "encoding", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+	if version < 4 {
+		if err := addColumn(tx, "chunks", "content_hash", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	if version < 5 {
+		if err := addColumn(tx, "documents", "metadata", "TEXT NOT NULL DEFAULT '{}'"); err != nil {
+			return err
+		}
+	}
+	if version < 6 {
+		if err := addColumn(tx, "documents", "provenance", "TEXT NOT NULL DEFAULT '[]'"); err != nil {
+			return err
+		}
+	}
+	if version < 7 {
+		if err := addColumn(tx, "chunks", "norm", "REAL NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+	if version < 8 {
+		if err := addColumn(tx, "chunks", "page", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		return fmt.Errorf("setting schema version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// migrateJSONEmbeddings re-encodes legacy JSON embeddings as binary blobs.
+func migrateJSONEmbeddings(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, embedding FROM chunks")
+	if err != nil {
+		return fmt.Errorf("querying chunks: %w", err)
+	}
+	converted := make(map[string][]byte)
+	for rows.Next() {
+		var id string
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning row: %w", err)
+		}
+		var embedding []float32
+		if err := json.Unmarshal(raw, &embedding); err != nil {
+			continue // Skip corrupted embeddings
+		}
+		converted[id] = encodeEmbedding(embedding)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating rows: %w", err)
+	}
+
+	for id, blob := range converted {
+		if _, err := tx.Exec("UPDATE chunks SET embedding = ? WHERE id = ?", blob, id); err != nil {
+			return fmt.Errorf("updating chunk %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// migrateCollections rebuilds a pre-collection chunks table, placing
+// existing rows in the default collection. SQLite cannot alter a
+// primary key in place, so the table is copied.
+func migrateCollections(tx *sql.Tx) error {
+	var hasColumn int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('chunks') WHERE name = 'collection'`).Scan(&hasColumn)
+	if err != nil {
+		return fmt.Errorf("inspecting chunks table: %w", err)
+	}
+	if hasColumn > 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE chunks_v2 (
+			id TEXT NOT NULL,
+			collection TEXT NOT NULL DEFAULT 'default',
+			document_id TEXT NOT NULL,
+			content TEXT NOT NULL,
+			chunk_index INTEGER NOT NULL,
+			embedding BLOB NOT NULL,
+			source_doc TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (collection, id)
+		);
+		INSERT INTO chunks_v2 (id, document_id, content, chunk_index, embedding, source_doc, created_at)
+			SELECT id, document_id, content, chunk_index, embedding, source_doc, created_at FROM chunks;
+		DROP TABLE chunks;
+		ALTER TABLE chunks_v2 RENAME TO chunks;
+		DROP TABLE IF EXISTS chunks_fts;
+	`)
+	if err != nil {
+		return fmt.Errorf("adding collection column: %w", err)
+	}
+	return nil
+}
+
+// addColumn adds a column to a table unless a fresh schema already has
+// it. Existing rows take the column default.
+func addColumn(tx *sql.Tx, table, name, definition string) error {
+	var hasColumn int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, name).Scan(&hasColumn)
+	if err != nil {
+		return fmt.Errorf("inspecting %s table: %w", table, err)
+	}
+	if hasColumn > 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, name, definition)); err != nil {
+		return fmt.Errorf("adding %s column: %w", name, err)
+	}
+	return nil
+}
+
+// Collection returns a store scoped to the named collection.
+func (s *LanceDBStore) Collection(name string) ports.VectorStore {
+	if name == "" {
+		name = entities.DefaultCollection
+	}
+	view := *s
+	view.collection = name
+	return &view
+}
+
+// Collections lists collections that hold data.
+func (s *LanceDBStore) Collections(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT collection FROM chunks WHERE collection != ? ORDER BY collection
+	`, entities.DefaultCollection)
+	if err != nil {
+		return nil, fmt.Errorf("querying collections: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{entities.DefaultCollection}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// swapPlaceholder holds one collection's rows while SwapCollections
+// renames the other; no collection can be named with a NUL byte.
+const swapPlaceholder = "\x00swap"
+
+// SwapCollections exchanges the contents of two collections in one
+// transaction. Rows are renamed through a placeholder, as the two
+// collections may hold the same chunk and document IDs. sqlite-vec
+// partitions by collection, so their vectors are re-indexed under the
+// new names.
+func (s *LanceDBStore) SwapCollections(ctx context.Context, a, b string) error {
+	if a == "" {
+		a = entities.DefaultCollection
+	}
+	if b == "" {
+		b = entities.DefaultCollection
+	}
+	if a == b {
+		return nil
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	defer s.cache.invalidate(a)
+	defer s.cache.invalidate(b)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	indexed := s.vec.dims > 0
+	if indexed {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TEMP TABLE swap_vec AS
+			SELECT rowid AS id, embedding FROM chunks_vec
+			WHERE rowid IN (SELECT rowid FROM chunks WHERE collection IN (?, ?))
+		`, a, b)
+		if err != nil {
+			return fmt.Errorf("updating vector index: %w", err)
+		}
+		if err := s.unindexWhere(ctx, tx, "collection IN (?, ?)", a, b); err != nil {
+			return err
+		}
+	}
+
+	tables := []string{"chunks", "documents", "collection_models"}
+	if s.ftsEnabled {
+		tables = append(tables, "chunks_fts")
+	}
+	for _, table := range tables {
+		for _, rename := range [][2]string{{a, swapPlaceholder}, {b, a}, {swapPlaceholder, b}} {
+			_, err := tx.ExecContext(ctx, "UPDATE "+table+" SET collection = ? WHERE collection = ?", rename[1], rename[0])
+			if err != nil {
+				return fmt.Errorf("swapping %s: %w", table, err)
+			}
+		}
+	}
+
+	if indexed {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO chunks_vec (rowid, collection, embedding)
+			SELECT v.id, c.collection, v.embedding FROM temp.swap_vec v JOIN chunks c ON c.rowid = v.id
+		`)
+		if err != nil {
+			return fmt.Errorf("updating vector index: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DROP TABLE temp.swap_vec"); err != nil {
+			return fmt.Errorf("updating vector index: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
 // Store saves chunks with their embeddings.
 func (s *LanceDBStore) Store(ctx context.Context, chunks []entities.Chunk) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.Upsert(ctx, chunks, ports.ConflictVersion)
+}
+
+// Upsert is Store with an explicit policy for chunks already stored.
+func (s *LanceDBStore) Upsert(ctx context.Context, chunks []entities.Chunk, policy ports.ConflictPolicy) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	defer s.cache.invalidate(s.collection)
+
+	// Writers are serialized, so the index width can only change here
+	s.mu.RLock()
+	vec := *s.vec
+	s.mu.RUnlock()
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -86,9 +424,17 @@ func (s *LanceDBStore) Store(ctx context.Context, chunks []entities.Chunk) error
 	}
 	defer tx.Rollback()
 
+	dims, err := s.dimension(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := checkChunkDimensions(dims, chunks); err != nil {
+		return err
+	}
+
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO chunks (id, document_id, content, chunk_index, embedding, source_doc)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT OR REPLACE INTO chunks (id, collection, document_id, content, chunk_index, embedding, encoding, content_hash, norm, page, source_doc)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("preparing statement: %w", err)
@@ -96,73 +442,259 @@ func (s *LanceDBStore) Store(ctx context.Context, chunks []entities.Chunk) error
 	defer stmt.Close()
 
 	for _, chunk := range chunks {
-		embeddingJSON, err := json.Marshal(chunk.Embedding)
-		if err != nil {
-			return fmt.Errorf("encoding embedding: %w", err)
+		if policy != ports.ConflictReplace {
+			var stored string
+			err := tx.QueryRowContext(ctx, "SELECT content_hash FROM chunks WHERE collection = ? AND id = ?", s.collection, chunk.ID).Scan(&stored)
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("reading chunk hash: %w", err)
+			}
+			if err == nil && keepStored(policy, stored, chunk) {
+				continue
+			}
+		}
+
+		if err := s.unindexChunk(ctx, tx, vec, chunk.ID); err != nil {
+			return err
 		}
 
-		_, err = stmt.ExecContext(ctx,
+		embedding, norm := s.prepare(chunk.Embedding)
+		blob, encoding := s.encode(embedding)
+		res, err := stmt.ExecContext(ctx,
 			chunk.ID,
+			s.collection,
 			chunk.DocumentID,
 			chunk.Content,
 			chunk.Index,
-			embeddingJSON,
+			blob,
+			encoding,
+			chunk.Hash,
+			norm,
+			chunk.Page,
 			chunk.DocumentID, // source_doc
 		)
 		if err != nil {
 			return fmt.Errorf("inserting chunk: %w", err)
 		}
+
+		if vec.available {
+			rowid, err := res.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("inserting chunk: %w", err)
+			}
+			if err := s.indexChunk(ctx, tx, &vec, rowid, embedding); err != nil {
+				return err
+			}
+		}
+
+		if s.ftsEnabled {
+			_, err := tx.ExecContext(ctx,
+				"DELETE FROM chunks_fts WHERE chunk_id = ? AND collection = ?", chunk.ID, s.collection)
+			if err != nil {
+				return fmt.Errorf("updating keyword index: %w", err)
+			}
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO chunks_fts (chunk_id, collection, content) VALUES (?, ?, ?)", chunk.ID, s.collection, chunk.Content)
+			if err != nil {
+				return fmt.Errorf("updating keyword index: %w", err)
+			}
+		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Publish a newly created vector index only once it is committed
+	s.mu.Lock()
+	s.vec.dims = vec.dims
+	s.mu.Unlock()
+	return nil
 }
 
 // Search finds the most similar chunks to a query embedding.
 func (s *LanceDBStore) Search(ctx context.Context, embedding []float32, topK int) ([]entities.QueryResult, error) {
+	return s.search(ctx, embedding, topK, nil)
+}
+
+// SearchDocuments is Search restricted to chunks of the given documents.
+// The sqlite-vec index is partitioned by collection only, so scoped
+// searches always scan.
+func (s *LanceDBStore) SearchDocuments(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	if len(documentIDs) == 0 {
+		return nil, nil
+	}
+	return s.search(ctx, embedding, topK, documentIDs)
+}
+
+// search ranks the collection's chunks, only those of documentIDs when
+// it is non-nil.
+func (s *LanceDBStore) search(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	vec := *s.vec
+	s.mu.RUnlock()
 
-	// Load all chunks and compute similarity (brute force for MVP)
-	// For production, use FAISS or actual LanceDB with ANN indexing
+	dims, err := s.dimension(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkQueryDimension(dims, len(embedding)); err != nil {
+		return nil, err
+	}
+	embedding = s.prepareQuery(embedding)
+
+	if documentIDs == nil {
+		if results, ok, err := s.nativeSearch(ctx, vec, embedding, topK); ok {
+			return results, err
+		}
+	}
+
+	// Without sqlite-vec, compute similarity over the collection's rows
+	rows, err := s.candidates(ctx, documentIDs)
+	if err != nil {
+		return nil, err
+	}
+	return rankRows(rows, embedding, topK), nil
+}
+
+// candidates returns the rows brute-force search ranks: the collection's
+// chunks, only those of documentIDs when it is non-nil. With the hot
+// cache enabled the whole collection is read once and filtered in memory.
+func (s *LanceDBStore) candidates(ctx context.Context, documentIDs []string) ([]cachedRow, error) {
+	rows, gen, ok := s.cache.get(s.collection)
+	if !ok {
+		if !s.cache.isEnabled() {
+			return s.loadRows(ctx, documentIDs)
+		}
+		var err error
+		if rows, err = s.loadRows(ctx, nil); err != nil {
+			return nil, err
+		}
+		s.cache.put(s.collection, gen, rows)
+	}
+	if documentIDs == nil {
+		return rows, nil
+	}
+
+	allowed := make(map[string]bool, len(documentIDs))
+	for _, id := range documentIDs {
+		allowed[id] = true
+	}
+	var scoped []cachedRow
+	for _, r := range rows {
+		if allowed[r.chunk.DocumentID] {
+			scoped = append(scoped, r)
+		}
+	}
+	return scoped, nil
+}
+
+// loadRows reads and decodes the collection's chunks, only those of
+// documentIDs when it is non-nil. Quantized embeddings are kept as codes.
+func (s *LanceDBStore) loadRows(ctx context.Context, documentIDs []string) ([]cachedRow, error) {
+	where := "c.collection = ?"
+	args := []interface{}{s.collection}
+	if documentIDs != nil {
+		where += " AND c.document_id IN (?" + strings.Repeat(", ?", len(documentIDs)-1) + ")"
+		for _, id := range documentIDs {
+			args = append(args, id)
+		}
+	}
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, document_id, content, chunk_index, embedding, source_doc
-		FROM chunks
-	`)
+		SELECT c.id, c.document_id, c.content, c.chunk_index, c.page, c.embedding, c.encoding, c.norm, COALESCE(d.name, c.source_doc)
+		FROM chunks c
+		LEFT JOIN documents d ON d.collection = c.collection AND d.id = c.document_id
+		WHERE `+where, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying chunks: %w", err)
 	}
 	defer rows.Close()
 
-	type scored struct {
-		chunk entities.Chunk
-		score float64
-		doc   string
-	}
-
-	var results []scored
+	var loaded []cachedRow
 	for rows.Next() {
-		var chunk entities.Chunk
-		var embeddingJSON []byte
-		var sourceDoc string
+		var r cachedRow
+		var embeddingBlob []byte
+		var encoding int
 
-		err := rows.Scan(&chunk.ID, &chunk.DocumentID, &chunk.Content, &chunk.Index, &embeddingJSON, &sourceDoc)
+		err := rows.Scan(&r.chunk.ID, &r.chunk.DocumentID, &r.chunk.Content, &r.chunk.Index, &r.chunk.Page, &embeddingBlob, &encoding, &r.norm, &r.doc)
 		if err != nil {
 			return nil, fmt.Errorf("scanning row: %w", err)
 		}
 
-		if err := json.Unmarshal(embeddingJSON, &chunk.Embedding); err != nil {
+		if encoding == encodingInt8 {
+			r.codes = embeddingBlob
+		} else if r.chunk.Embedding, err = decodeStored(embeddingBlob, encoding); err != nil {
 			continue // Skip corrupted embeddings
 		}
+		if r.norm == 0 && r.codes == nil {
+			r.norm = vectorNorm(r.chunk.Embedding) // Stored before norms were kept
+		}
+		loaded = append(loaded, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying chunks: %w", err)
+	}
+	return loaded, nil
+}
+
+// rankRows returns the topK rows most similar to embedding. Quantized
+// rows are ranked on their codes first and the best candidates rescored
+// at full precision.
+func rankRows(rows []cachedRow, embedding []float32, topK int) []entities.QueryResult {
+	type scored struct {
+		chunk     entities.Chunk
+		score     float64
+		doc       string
+		quantized []byte // int8 codes awaiting rescoring; score is approximate
+	}
+
+	results := make([]scored, 0, len(rows))
+	queryNorm := vectorNorm(embedding)
+	if math.Abs(queryNorm-1) < 1e-6 {
+		queryNorm = 1 // Normalized query: score unit rows by dot product alone
+	}
+	var queryCodes []byte // Query quantized on first int8 row
+	for _, r := range rows {
+		if r.codes != nil {
+			if queryCodes == nil {
+				queryCodes = encodeInt8(embedding)
+			}
+			score := int8Cosine(queryCodes, r.codes)
+			results = append(results, scored{chunk: r.chunk, score: score, doc: r.doc, quantized: r.codes})
+			continue
+		}
 
-		score := cosineSimilarity(embedding, chunk.Embedding)
-		results = append(results, scored{chunk: chunk, score: score, doc: sourceDoc})
+		score := normScore(embedding, r.chunk.Embedding, queryNorm, r.norm)
+		results = append(results, scored{chunk: r.chunk, score: score, doc: r.doc})
 	}
 
 	// Sort by score descending
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].score > results[j].score
+		return ranksBefore(results[i].score, results[j].score, results[i].chunk.ID, results[j].chunk.ID)
 	})
 
+	// Rescore the best quantized candidates against the full-precision query
+	if queryCodes != nil {
+		if len(results) > topK*rescoreFactor {
+			results = results[:topK*rescoreFactor]
+		}
+		kept := results[:0]
+		for _, r := range results {
+			if r.quantized != nil {
+				var err error
+				r.chunk.Embedding, err = decodeInt8(r.quantized)
+				if err != nil {
+					continue // Skip corrupted embeddings
+				}
+				r.score = cosineSimilarity(embedding, r.chunk.Embedding)
+			}
+			kept = append(kept, r)
+		}
+		results = kept
+		sort.Slice(results, func(i, j int) bool {
+			return ranksBefore(results[i].score, results[j].score, results[i].chunk.ID, results[j].chunk.ID)
+		})
+	}
+
 	// Take top K
 	if len(results) > topK {
 		results = results[:topK]
@@ -177,26 +709,266 @@ func (s *LanceDBStore) Search(ctx context.Context, embedding []float32, topK int
 			SourceDoc: r.doc,
 		}
 	}
+	return queryResults
+}
+
+// ChunkHashes returns the content hash of each stored chunk of a document.
+func (s *LanceDBStore) ChunkHashes(ctx context.Context, documentID string) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, content_hash FROM chunks WHERE collection = ? AND document_id = ?", s.collection, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("querying chunk hashes: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		hashes[id] = hash
+	}
+	return hashes, rows.Err()
+}
 
-	return queryResults, nil
+// DocumentChunks returns a document's stored chunks in order, without
+// their embeddings.
+func (s *LanceDBStore) DocumentChunks(ctx context.Context, documentID string) ([]entities.Chunk, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, content, chunk_index, content_hash, page FROM chunks
+		WHERE collection = ? AND document_id = ? ORDER BY chunk_index
+	`, s.collection, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("querying chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []entities.Chunk
+	for rows.Next() {
+		chunk := entities.Chunk{DocumentID: documentID}
+		if err := rows.Scan(&chunk.ID, &chunk.Content, &chunk.Index, &chunk.Hash, &chunk.Page); err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// dimension returns the embedding width of the collection's stored
+// chunks, or 0 when it is empty.
+func (s *LanceDBStore) dimension(ctx context.Context, q queryer) (int, error) {
+	var bytes, encoding int
+	err := q.QueryRowContext(ctx, "SELECT length(embedding), encoding FROM chunks WHERE collection = ? LIMIT 1", s.collection).Scan(&bytes, &encoding)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading embedding dimension: %w", err)
+	}
+	return storedDimension(bytes, encoding), nil
 }
 
 // Delete removes all chunks for a document.
 func (s *LanceDBStore) Delete(ctx context.Context, documentID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.DeleteMany(ctx, []string{documentID})
+}
 
-	_, err := s.db.ExecContext(ctx, "DELETE FROM chunks WHERE document_id = ?", documentID)
-	return err
+// DeleteMany removes all chunks of several documents in one transaction.
+func (s *LanceDBStore) DeleteMany(ctx context.Context, documentIDs []string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	defer s.cache.invalidate(s.collection)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range documentIDs {
+		if err := s.deleteDocument(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// deleteDocument removes a document's chunks, index entries and registry
+// row inside tx.
+func (s *LanceDBStore) deleteDocument(ctx context.Context, tx *sql.Tx, documentID string) error {
+	if s.ftsEnabled {
+		_, err := tx.ExecContext(ctx,
+			"DELETE FROM chunks_fts WHERE collection = ? AND chunk_id IN (SELECT id FROM chunks WHERE collection = ? AND document_id = ?)",
+			s.collection, s.collection, documentID)
+		if err != nil {
+			return fmt.Errorf("updating keyword index: %w", err)
+		}
+	}
+
+	if err := s.unindexWhere(ctx, tx, "collection = ? AND document_id = ?", s.collection, documentID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM documents WHERE collection = ? AND id = ?", s.collection, documentID); err != nil {
+		return fmt.Errorf("deleting document: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM chunks WHERE collection = ? AND document_id = ?", s.collection, documentID); err != nil {
+		return fmt.Errorf("deleting chunks: %w", err)
+	}
+	return nil
 }
 
 // Clear removes all data from the store.
 func (s *LanceDBStore) Clear(ctx context.Context) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	defer s.cache.invalidate(s.collection)
 
-	_, err := s.db.ExecContext(ctx, "DELETE FROM chunks")
-	return err
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if s.ftsEnabled {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM chunks_fts WHERE collection = ?", s.collection); err != nil {
+			return fmt.Errorf("clearing keyword index: %w", err)
+		}
+	}
+
+	if err := s.unindexWhere(ctx, tx, "collection = ?", s.collection); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM documents WHERE collection = ?", s.collection); err != nil {
+		return fmt.Errorf("clearing documents: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM chunks WHERE collection = ?", s.collection); err != nil {
+		return fmt.Errorf("clearing chunks: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM collection_models WHERE collection = ?", s.collection); err != nil {
+		return fmt.Errorf("clearing embedding model: %w", err)
+	}
+	return tx.Commit()
+}
+
+// EmbeddingModel returns the model recorded for the collection.
+func (s *LanceDBStore) EmbeddingModel(ctx context.Context) (ports.EmbeddingModel, error) {
+	var model ports.EmbeddingModel
+	err := s.db.QueryRowContext(ctx, "SELECT model, dimension FROM collection_models WHERE collection = ?",
+		s.collection).Scan(&model.Name, &model.Dimension)
+	if err == sql.ErrNoRows {
+		return ports.EmbeddingModel{}, nil
+	}
+	if err != nil {
+		return model, fmt.Errorf("reading embedding model: %w", err)
+	}
+	return model, nil
+}
+
+// RecordEmbeddingModel records the model the collection is embedded with.
+func (s *LanceDBStore) RecordEmbeddingModel(ctx context.Context, model ports.EmbeddingModel) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO collection_models (collection, model, dimension) VALUES (?, ?, ?)",
+		s.collection, model.Name, model.Dimension)
+	if err != nil {
+		return fmt.Errorf("recording embedding model: %w", err)
+	}
+	return nil
+}
+
+// RegisterDocument records or updates a document's metadata.
+func (s *LanceDBStore) RegisterDocument(ctx context.Context, info entities.DocumentInfo) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	defer s.cache.invalidate(s.collection)
+
+	metadata, err := json.Marshal(info.Metadata)
+	if err != nil {
+		return fmt.Errorf("encoding metadata: %w", err)
+	}
+	provenance, err := json.Marshal(provenanceToJSON(info.Provenance))
+	if err != nil {
+		return fmt.Errorf("encoding provenance: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO documents (id, collection, name, path, ingested_at, metadata, provenance)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, info.ID, s.collection, info.Name, info.Path, info.IngestedAt, string(metadata), string(provenance))
+	if err != nil {
+		return fmt.Errorf("registering document: %w", err)
+	}
+	return nil
+}
+
+// ListDocuments returns the documents that have stored chunks and match
+// filter, one page at a time. Chunk counts come from the chunks table,
+// so they reflect what is actually searchable rather than what was
+// registered.
+func (s *LanceDBStore) ListDocuments(ctx context.Context, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error) {
+	where := "c.collection = ?"
+	args := []interface{}{s.collection}
+	if len(filter.IDs) > 0 {
+		where += " AND c.document_id IN (?" + strings.Repeat(", ?", len(filter.IDs)-1) + ")"
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+	if cond, condArgs, _ := filterSQL(filter.Expr); cond != "" {
+		where += " AND " + cond
+		args = append(args, condArgs...)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.document_id, d.name, d.path, d.ingested_at, d.metadata, d.provenance, COUNT(*)
+		FROM chunks c
+		LEFT JOIN documents d ON d.collection = c.collection AND d.id = c.document_id
+		WHERE `+where+`
+		GROUP BY c.document_id
+	`, args...)
+	if err != nil {
+		return entities.DocumentPage{}, fmt.Errorf("querying documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []entities.DocumentInfo
+	for rows.Next() {
+		var info entities.DocumentInfo
+		var name, path, metadata, provenance sql.NullString
+		var ingestedAt sql.NullTime
+		if err := rows.Scan(&info.ID, &name, &path, &ingestedAt, &metadata, &provenance, &info.ChunkCount); err != nil {
+			return entities.DocumentPage{}, fmt.Errorf("scanning row: %w", err)
+		}
+		info.Name = info.ID
+		if name.Valid {
+			info.Name = name.String
+		}
+		info.Path = path.String
+		info.IngestedAt = ingestedAt.Time
+		if metadata.Valid {
+			json.Unmarshal([]byte(metadata.String), &info.Metadata)
+		}
+		if provenance.Valid {
+			var steps []transformationJSON
+			json.Unmarshal([]byte(provenance.String), &steps)
+			info.Provenance = provenanceFromJSON(steps)
+		}
+		docs = append(docs, info)
+	}
+	if err := rows.Err(); err != nil {
+		return entities.DocumentPage{}, err
+	}
+	return pageDocuments(docs, filter, page), nil
 }
 
 // Close closes the database connection.
@@ -204,13 +976,145 @@ func (s *LanceDBStore) Close() error {
 	return s.db.Close()
 }
 
+// OpenSnapshot restores a snapshot written by Backup into a temporary
+// database, migrated to the current schema, which Close deletes.
+func (s *LanceDBStore) OpenSnapshot(ctx context.Context, path string) (ports.SnapshotStore, error) {
+	dir, err := os.MkdirTemp("", "localrag-snapshot-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	snap, err := NewLanceDBStoreWithOptions(dir, LanceDBOptions{DisableNativeIndex: true})
+	if err == nil {
+		if err = snap.Restore(ctx, path); err != nil {
+			snap.Close()
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return lanceDBSnapshot{snap}, nil
+}
+
+// lanceDBSnapshot is a LanceDBStore opened by OpenSnapshot.
+type lanceDBSnapshot struct {
+	*LanceDBStore
+}
+
+// Close closes the database and deletes its directory.
+func (s lanceDBSnapshot) Close() error {
+	err := s.LanceDBStore.Close()
+	os.RemoveAll(s.dataPath)
+	return err
+}
+
 // ChunkCount returns the number of stored chunks.
 func (s *LanceDBStore) ChunkCount(ctx context.Context) (int, error) {
 	var count int
-	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunks").Scan(&count)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunks WHERE collection = ?", s.collection).Scan(&count)
 	return count, err
 }
 
+// Stats reports the collection's contents. SizeBytes covers the whole
+// database file and its write-ahead log.
+func (s *LanceDBStore) Stats(ctx context.Context) (ports.StoreStats, error) {
+	var stats ports.StoreStats
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*), COUNT(DISTINCT document_id) FROM chunks WHERE collection = ?",
+		s.collection).Scan(&stats.Chunks, &stats.Documents)
+	if err != nil {
+		return stats, fmt.Errorf("counting chunks: %w", err)
+	}
+	if stats.Dimension, err = s.dimension(ctx, s.db); err != nil {
+		return stats, err
+	}
+
+	dbPath := filepath.Join(s.dataPath, "vectors.db")
+	for _, name := range []string{dbPath, dbPath + "-wal"} {
+		if fi, err := os.Stat(name); err == nil {
+			stats.SizeBytes += fi.Size()
+		}
+	}
+	return stats, nil
+}
+
+// encodeEmbedding packs a vector as little-endian float32 values.
+func encodeEmbedding(embedding []float32) []byte {
+	buf := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// decodeEmbedding unpacks a blob written by encodeEmbedding.
+func decodeEmbedding(blob []byte) ([]float32, error) {
+	if len(blob)%4 != 0 {
+		return nil, fmt.Errorf("embedding blob length %d is not a multiple of 4", len(blob))
+	}
+	embedding := make([]float32, len(blob)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[4*i:]))
+	}
+	return embedding, nil
+}
+
+// ErrDimensionMismatch is returned when an embedding's width differs from
+// the vectors already stored in a collection, typically after switching
+// embedding models without re-ingesting.
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// checkChunkDimensions verifies every chunk has the collection's width.
+// want is 0 for an empty collection, in which case the batch sets it.
+func checkChunkDimensions(want int, chunks []entities.Chunk) error {
+	for _, chunk := range chunks {
+		if want == 0 {
+			want = len(chunk.Embedding)
+		}
+		if len(chunk.Embedding) != want {
+			return fmt.Errorf("%w: chunk %s has %d dimensions, collection holds %d",
+				ErrDimensionMismatch, chunk.ID, len(chunk.Embedding), want)
+		}
+	}
+	return nil
+}
+
+// unchanged reports whether chunk can skip a rewrite because the stored
+// copy has the same content hash. Chunks without a hash always rewrite.
+func unchanged(storedHash string, chunk entities.Chunk) bool {
+	return chunk.Hash != "" && storedHash == chunk.Hash
+}
+
+// keepStored reports whether policy keeps the stored copy of chunk
+// instead of writing it.
+func keepStored(policy ports.ConflictPolicy, storedHash string, chunk entities.Chunk) bool {
+	switch policy {
+	case ports.ConflictReplace:
+		return false
+	case ports.ConflictSkip:
+		return true
+	}
+	return unchanged(storedHash, chunk)
+}
+
+// checkQueryDimension verifies a query embedding matches the stored width.
+func checkQueryDimension(want, got int) error {
+	if want != 0 && got != want {
+		return fmt.Errorf("%w: query has %d dimensions, collection holds %d; re-ingest after changing embedding models",
+			ErrDimensionMismatch, got, want)
+	}
+	return nil
+}
+
+// ranksBefore orders search results by score descending, breaking ties
+// by chunk ID so equal scores rank the same way on every search.
+func ranksBefore(scoreA, scoreB float64, idA, idB string) bool {
+	if scoreA != scoreB {
+		return scoreA > scoreB
+	}
+	return idA < idB
+}
+
 // cosineSimilarity calculates cosine similarity between two vectors.
 func cosineSimilarity(a, b []float32) float64 {
 	if len(a) != len(b) || len(a) == 0 {
@@ -0,0 +1,129 @@
+//go:build cgo
+
+package vectordb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup writes a consistent snapshot of the whole database (all
+// collections) to path using SQLite's online backup API. Searches
+// continue while it runs; writes wait for it to finish.
+func (s *LanceDBStore) Backup(ctx context.Context, path string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("replacing backup file: %w", err)
+	}
+	return s.withRawConn(ctx, func(live *sqlite3.SQLiteConn) error {
+		file, err := openSQLiteFile(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return copyDatabase(file, live)
+	})
+}
+
+// Restore replaces the database contents with a snapshot written by
+// Backup. Older snapshots are migrated to the current schema.
+func (s *LanceDBStore) Restore(ctx context.Context, path string) error {
+	if err := checkSnapshot(path); err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	defer s.cache.invalidateAll()
+
+	err := s.withRawConn(ctx, func(live *sqlite3.SQLiteConn) error {
+		file, err := openSQLiteFile(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return copyDatabase(live, file)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.initSchema(); err != nil {
+		return fmt.Errorf("migrating restored database: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.initFTS()
+	s.initVec(!s.vec.enabled)
+	return nil
+}
+
+// withRawConn runs fn on a dedicated driver connection from the pool.
+func (s *LanceDBStore) withRawConn(ctx context.Context, fn func(*sqlite3.SQLiteConn) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc interface{}) error {
+		live, ok := dc.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", dc)
+		}
+		return fn(live)
+	})
+}
+
+// openSQLiteFile opens a standalone connection outside the pool.
+func openSQLiteFile(path string) (*sqlite3.SQLiteConn, error) {
+	conn, err := (&sqlite3.SQLiteDriver{}).Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return conn.(*sqlite3.SQLiteConn), nil
+}
+
+// copyDatabase copies every page of src's main database into dst.
+func copyDatabase(dst, src *sqlite3.SQLiteConn) error {
+	backup, err := dst.Backup("main", src, "main")
+	if err != nil {
+		return fmt.Errorf("starting backup: %w", err)
+	}
+	for {
+		done, err := backup.Step(-1)
+		if err != nil {
+			backup.Finish()
+			return fmt.Errorf("copying pages: %w", err)
+		}
+		if done {
+			break
+		}
+	}
+	return backup.Finish()
+}
+
+// checkSnapshot refuses files that are not LocalRAG databases, so a
+// wrong upload cannot wipe the store.
+func checkSnapshot(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer db.Close()
+
+	var tables int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'chunks'`).Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("%s is not a LocalRAG snapshot", path)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !cgo
+
+package vectordb
+
+import (
+	"context"
+	"errors"
+)
+
+// errBackupNeedsCgo is returned by LanceDBStore snapshots in CGO-free
+// builds, where go-sqlite3 has no backup API.
+var errBackupNeedsCgo = errors.New("LanceDB backup requires a cgo build")
+
+// Backup is unavailable without cgo.
+func (s *LanceDBStore) Backup(ctx context.Context, path string) error {
+	return errBackupNeedsCgo
+}
+
+// Restore is unavailable without cgo.
+func (s *LanceDBStore) Restore(ctx context.Context, path string) error {
+	return errBackupNeedsCgo
+}
@@ -0,0 +1,76 @@
+//go:build cgo
+
+package vectordb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestLanceDBStore_BackupAndRestore(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(filepath.Join(dir, "data"))
+	defer store.Close()
+
+	ctx := context.Background()
+	work := store.Collection("work")
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "snapshotted", Embedding: []float32{1, 0, 0}}})
+	work.Store(ctx, []entities.Chunk{{ID: "w1", DocumentID: "doc2", Content: "work", Embedding: []float32{1, 0, 0}}})
+
+	snapshot := filepath.Join(dir, "index.snapshot")
+	if err := store.Backup(ctx, snapshot); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+
+	// Re-ingest after the snapshot
+	store.Clear(ctx)
+	work.Clear(ctx)
+	store.Store(ctx, []entities.Chunk{{ID: "c9", DocumentID: "doc9", Content: "newer", Embedding: []float32{1, 0, 0}}})
+
+	if err := store.Restore(ctx, snapshot); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	results, err := store.Search(ctx, []float32{1, 0, 0}, 10)
+	if err != nil || len(results) != 1 || results[0].Chunk.Content != "snapshotted" {
+		t.Errorf("default collection not restored: %v, %v", results, err)
+	}
+	if results, _ := work.Search(ctx, []float32{1, 0, 0}, 10); len(results) != 1 {
+		t.Errorf("work collection not restored: %v", results)
+	}
+}
+
+func TestLanceDBStore_OpenSnapshot(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(filepath.Join(dir, "data"))
+	defer store.Close()
+	testOpenSnapshot(t, store, filepath.Join(dir, "index.snapshot"))
+}
+
+func TestLanceDBStore_RestoreRejectsForeignFile(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(filepath.Join(dir, "data"))
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}}})
+
+	bogus := filepath.Join(dir, "bogus")
+	os.WriteFile(bogus, []byte("not a database"), 0600)
+	if err := store.Restore(ctx, bogus); err == nil {
+		t.Error("restoring a non-snapshot should fail")
+	}
+	if count, _ := store.ChunkCount(ctx); count != 1 {
+		t.Errorf("failed restore should keep data, got %d chunks", count)
+	}
+}
@@ -0,0 +1,130 @@
+package vectordb
+
+import (
+	"sync"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// hotCacheCollections bounds how many collections the hot cache holds;
+// the least recently searched one is evicted first.
+const hotCacheCollections = 8
+
+// hotCache keeps the decoded rows of recently searched collections, so
+// brute-force searches skip reading and decoding every embedding. It is
+// shared by all collection views of one database and filled lazily by
+// the first search of a collection; writes drop the collection's rows.
+//
+// A write bumps gen after it commits, and rows read before that are only
+// installed if gen is unchanged, so a search racing a write can never
+// cache the pre-write rows.
+type hotCache struct {
+	mu      sync.Mutex
+	enabled bool
+	gen     uint64
+	entries map[string][]cachedRow // collection -> rows
+	order   []string               // Collections, least recently searched first
+}
+
+// cachedRow is a chunk as ranked by brute-force search.
+type cachedRow struct {
+	chunk entities.Chunk // Embedding is set for float32 rows
+	doc   string         // Source name for citations
+	codes []byte         // int8 codes of quantized rows, decoded only when rescored
+	norm  float64        // L2 norm of Embedding; 1 for normalized rows
+}
+
+// get returns the cached rows of a collection and the generation to pass
+// to put when they are missing.
+func (c *hotCache) get(collection string) (rows []cachedRow, gen uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.enabled {
+		return nil, c.gen, false
+	}
+	rows, ok = c.entries[collection]
+	if ok {
+		c.touch(collection)
+	}
+	return rows, c.gen, ok
+}
+
+// put caches rows read at generation gen, unless a write has since
+// invalidated them.
+func (c *hotCache) put(collection string, gen uint64, rows []cachedRow) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.enabled || c.gen != gen {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string][]cachedRow)
+	}
+	if _, ok := c.entries[collection]; !ok && len(c.order) >= hotCacheCollections {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[collection] = rows
+	c.touch(collection)
+}
+
+// touch marks a collection as most recently searched. Caller must hold c.mu.
+func (c *hotCache) touch(collection string) {
+	for i, name := range c.order {
+		if name == collection {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, collection)
+}
+
+// invalidate drops a collection's rows after a write.
+func (c *hotCache) invalidate(collection string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	delete(c.entries, collection)
+	for i, name := range c.order {
+		if name == collection {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// invalidateAll drops every collection's rows, as after a restore.
+func (c *hotCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	c.entries = nil
+	c.order = nil
+}
+
+// isEnabled reports whether searches fill and use the cache.
+func (c *hotCache) isEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled
+}
+
+// setEnabled turns the cache on or off; turning it off frees its rows.
+func (c *hotCache) setEnabled(enabled bool) {
+	c.mu.Lock()
+	c.enabled = enabled
+	c.mu.Unlock()
+	if !enabled {
+		c.invalidateAll()
+	}
+}
+
+// SetHotCache toggles the in-memory cache of decoded rows used by
+// brute-force search. See LanceDBOptions.HotCache.
+func (s *LanceDBStore) SetHotCache(enabled bool) {
+	s.cache.setEnabled(enabled)
+}
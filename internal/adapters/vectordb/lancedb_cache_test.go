@@ -0,0 +1,93 @@
+package vectordb
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestLanceDBStore_HotCache(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewLanceDBStoreWithOptions(dir, LanceDBOptions{DisableNativeIndex: true, HotCache: true})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "one", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc2", Content: "two", Embedding: []float32{0, 1, 0}},
+	})
+
+	results, err := store.Search(ctx, []float32{1, 0, 0}, 5)
+	if err != nil || len(results) != 2 || results[0].Chunk.ID != "c1" {
+		t.Fatalf("unexpected results: %+v, %v", results, err)
+	}
+	if _, _, ok := store.cache.get(entities.DefaultCollection); !ok {
+		t.Fatal("search should fill the cache")
+	}
+
+	// Rows deleted behind the cache's back prove later searches use it
+	store.db.Exec("DELETE FROM chunks WHERE id = 'c2'")
+	if results, _ := store.Search(ctx, []float32{1, 0, 0}, 5); len(results) != 2 {
+		t.Errorf("cached search should not re-read rows, got %d results", len(results))
+	}
+	if results, _ := store.SearchDocuments(ctx, []float32{1, 0, 0}, 5, []string{"doc2"}); len(results) != 1 || results[0].Chunk.ID != "c2" {
+		t.Errorf("scoped search should filter cached rows, got %+v", results)
+	}
+
+	// Each write drops the collection's rows
+	store.Store(ctx, []entities.Chunk{{ID: "c3", DocumentID: "doc3", Content: "three", Embedding: []float32{0, 0, 1}}})
+	results, _ = store.Search(ctx, []float32{0, 0, 1}, 5)
+	if len(results) != 2 || results[0].Chunk.ID != "c3" {
+		t.Errorf("store should invalidate the cache, got %+v", results)
+	}
+
+	store.RegisterDocument(ctx, entities.DocumentInfo{ID: "doc3", Name: "three.md"})
+	if results, _ := store.Search(ctx, []float32{0, 0, 1}, 1); len(results) != 1 || results[0].SourceDoc != "three.md" {
+		t.Errorf("registering should invalidate the cache, got %+v", results)
+	}
+
+	store.Delete(ctx, "doc3")
+	if results, _ := store.Search(ctx, []float32{0, 0, 1}, 5); len(results) != 1 {
+		t.Errorf("delete should invalidate the cache, got %+v", results)
+	}
+
+	// Other collections are cached separately
+	work := store.Collection("work")
+	work.Store(ctx, []entities.Chunk{{ID: "w1", DocumentID: "doc1", Content: "work", Embedding: []float32{1, 0, 0}}})
+	if results, _ := work.Search(ctx, []float32{1, 0, 0}, 5); len(results) != 1 || results[0].Chunk.ID != "w1" {
+		t.Errorf("unexpected work results: %+v", results)
+	}
+
+	store.SetHotCache(false)
+	if _, _, ok := store.cache.get(entities.DefaultCollection); ok {
+		t.Error("disabling should drop cached rows")
+	}
+}
+
+func TestHotCache_RejectsRowsReadBeforeWrite(t *testing.T) {
+	c := &hotCache{enabled: true}
+	_, gen, _ := c.get("default")
+	c.invalidate("default") // A write commits while the rows are read
+	c.put("default", gen, []cachedRow{{chunk: entities.Chunk{ID: "stale"}}})
+	if _, _, ok := c.get("default"); ok {
+		t.Error("rows read before a write should not be cached")
+	}
+
+	for i := 0; i < hotCacheCollections+1; i++ {
+		_, gen, _ := c.get(string(rune('a' + i)))
+		c.put(string(rune('a'+i)), gen, nil)
+	}
+	if len(c.entries) != hotCacheCollections {
+		t.Errorf("cache holds %d collections, want %d", len(c.entries), hotCacheCollections)
+	}
+	if _, _, ok := c.get("a"); ok {
+		t.Error("least recently searched collection should be evicted")
+	}
+}
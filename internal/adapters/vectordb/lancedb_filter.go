@@ -0,0 +1,93 @@
+package vectordb
+
+import (
+	"strings"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// filterSQL translates a filter expression into a condition on the
+// chunks c LEFT JOIN documents d rows ListDocuments reads, so SQLite
+// skips documents that cannot match. Terms with no exact SQL form, such
+// as ingest times and ordered comparisons of typed metadata, are
+// dropped from conjunctions, which only widens the condition;
+// pageDocuments still applies the whole filter. cond is empty when
+// nothing narrower than every row can be expressed, and exact reports
+// whether cond matches the same documents as expr.
+func filterSQL(expr entities.FilterExpr) (cond string, args []interface{}, exact bool) {
+	switch e := expr.(type) {
+	case entities.FilterAnd:
+		var parts []string
+		exact = true
+		for _, x := range e {
+			c, a, ok := filterSQL(x)
+			exact = exact && ok
+			if c != "" {
+				parts = append(parts, c)
+				args = append(args, a...)
+			}
+		}
+		if len(parts) == 0 {
+			return "", nil, false
+		}
+		return "(" + strings.Join(parts, " AND ") + ")", args, exact
+
+	case entities.FilterOr:
+		parts := make([]string, len(e))
+		exact = true
+		for i, x := range e {
+			c, a, ok := filterSQL(x)
+			if c == "" {
+				return "", nil, false // One operand may match anything
+			}
+			exact = exact && ok
+			parts[i] = c
+			args = append(args, a...)
+		}
+		return "(" + strings.Join(parts, " OR ") + ")", args, exact
+
+	case entities.FilterNot:
+		c, a, ok := filterSQL(e.Expr)
+		if !ok {
+			return "", nil, false // Negating a wider condition would narrow it too far
+		}
+		return "NOT " + c, a, true
+
+	case entities.FilterTerm:
+		pattern := globPattern(e.Value)
+		switch e.Field {
+		case entities.FilterFieldDoc:
+			return "(COALESCE(d.name, c.document_id) GLOB ? OR c.document_id GLOB ?)", []interface{}{pattern, pattern}, true
+		case entities.FilterFieldPath:
+			return "(COALESCE(d.path, '') GLOB ?)", []interface{}{pattern}, true
+		case entities.FilterFieldIngested:
+			return "", nil, false
+		}
+		if e.Op != entities.FilterEqual || (e.Type != entities.FieldString && e.Type != entities.FieldEnum) || !plainField(e.Field) {
+			return "", nil, false
+		}
+		value := `json_extract(COALESCE(d.metadata, '{}'), '$.` + e.Field + `')`
+		if strings.Contains(e.Value, "*") {
+			return "COALESCE(" + value + " GLOB ?, 0)", []interface{}{pattern}, true
+		}
+		return "COALESCE(" + value + " = ?, 0)", []interface{}{e.Value}, true
+	}
+	return "", nil, false
+}
+
+// globPattern turns a filter pattern, where only * is special, into an
+// SQLite GLOB pattern.
+func globPattern(pattern string) string {
+	return strings.NewReplacer("[", "[[]", "?", "[?]").Replace(pattern)
+}
+
+// plainField reports whether a metadata field name can be spliced into a
+// JSON path as is.
+func plainField(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return name != ""
+}
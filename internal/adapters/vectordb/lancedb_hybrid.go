@@ -0,0 +1,210 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// defaultHybridAlpha weights vector similarity against BM25 in HybridSearch.
+const defaultHybridAlpha = 0.5
+
+// initFTS creates the FTS5 keyword index next to the chunks table.
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag;
+// without it the store keeps working and HybridSearch degrades to Search.
+func (s *LanceDBStore) initFTS() {
+	var exists int
+	s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'chunks_fts'`).Scan(&exists)
+
+	_, err := s.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS chunks_fts USING fts5(chunk_id UNINDEXED, collection UNINDEXED, content)`)
+	if err != nil {
+		return
+	}
+	s.ftsEnabled = true
+
+	// Backfill rows stored before the index existed
+	if exists == 0 {
+		s.db.Exec(`INSERT INTO chunks_fts (chunk_id, collection, content) SELECT id, collection, content FROM chunks`)
+	}
+}
+
+// SetHybridWeight sets the vector weight (0..1) used by HybridSearch.
+// The keyword score receives the remaining 1-alpha.
+func (s *LanceDBStore) SetHybridWeight(alpha float64) {
+	if alpha < 0 {
+		alpha = 0
+	}
+	if alpha > 1 {
+		alpha = 1
+	}
+	s.mu.Lock()
+	s.hybridAlpha = alpha
+	s.mu.Unlock()
+}
+
+// HybridSearch fuses BM25 keyword scores with cosine similarity.
+// Both score lists are scaled to 0..1 before the weighted sum, so exact
+// identifiers and rare terms can surface even when their embedding is weak.
+func (s *LanceDBStore) HybridSearch(ctx context.Context, query string, embedding []float32, topK int) ([]entities.QueryResult, error) {
+	s.mu.RLock()
+	enabled, alpha := s.ftsEnabled, s.hybridAlpha
+	s.mu.RUnlock()
+
+	match := ftsMatchExpr(query)
+	if !enabled || match == "" {
+		return s.Search(ctx, embedding, topK)
+	}
+
+	// Oversample both retrievers so fusion has candidates to reorder
+	pool := topK * 4
+	vectorHits, err := s.Search(ctx, embedding, pool)
+	if err != nil {
+		return nil, err
+	}
+	keywordHits, err := s.keywordSearch(ctx, match, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	type fused struct {
+		result  entities.QueryResult
+		vector  float64
+		keyword float64
+	}
+	byID := make(map[string]*fused)
+	for _, r := range vectorHits {
+		byID[r.Chunk.ID] = &fused{result: r, vector: r.Score}
+	}
+
+	var maxKeyword float64
+	for _, h := range keywordHits {
+		if h.score > maxKeyword {
+			maxKeyword = h.score
+		}
+		f, ok := byID[h.result.Chunk.ID]
+		if !ok {
+			f = &fused{
+				result: h.result,
+				vector: cosineSimilarity(embedding, h.result.Chunk.Embedding),
+			}
+			byID[h.result.Chunk.ID] = f
+		}
+		f.keyword = h.score
+	}
+
+	results := make([]entities.QueryResult, 0, len(byID))
+	for _, f := range byID {
+		vector := f.vector
+		if vector < 0 {
+			vector = 0
+		}
+		keyword := 0.0
+		if maxKeyword > 0 {
+			keyword = f.keyword / maxKeyword
+		}
+		r := f.result
+		r.Score = alpha*vector + (1-alpha)*keyword
+		results = append(results, r)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return ranksBefore(results[i].Score, results[j].Score, results[i].Chunk.ID, results[j].Chunk.ID)
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// KeywordSearch ranks chunks by BM25 alone. It returns no results when
+// the FTS5 index is unavailable or query has no searchable words.
+func (s *LanceDBStore) KeywordSearch(ctx context.Context, query string, topK int) ([]entities.QueryResult, error) {
+	s.mu.RLock()
+	enabled := s.ftsEnabled
+	s.mu.RUnlock()
+
+	match := ftsMatchExpr(query)
+	if !enabled || match == "" {
+		return nil, nil
+	}
+	hits, err := s.keywordSearch(ctx, match, topK)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]entities.QueryResult, len(hits))
+	for i, h := range hits {
+		results[i] = h.result
+		if hits[0].score > 0 {
+			results[i].Score = h.score / hits[0].score
+		}
+	}
+	return results, nil
+}
+
+// keywordHit is a BM25-scored chunk (higher is better).
+type keywordHit struct {
+	result entities.QueryResult
+	score  float64
+}
+
+// keywordSearch runs an FTS5 MATCH and returns chunks ranked by BM25.
+func (s *LanceDBStore) keywordSearch(ctx context.Context, match string, limit int) ([]keywordHit, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.id, c.document_id, c.content, c.chunk_index, c.page, c.embedding, c.encoding, COALESCE(d.name, c.source_doc), bm25(chunks_fts)
+		FROM chunks_fts
+		JOIN chunks c ON c.id = chunks_fts.chunk_id AND c.collection = chunks_fts.collection
+		LEFT JOIN documents d ON d.collection = c.collection AND d.id = c.document_id
+		WHERE chunks_fts MATCH ? AND chunks_fts.collection = ?
+		ORDER BY bm25(chunks_fts)
+		LIMIT ?
+	`, match, s.collection, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying keyword index: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []keywordHit
+	for rows.Next() {
+		var chunk entities.Chunk
+		var embeddingBlob []byte
+		var encoding int
+		var sourceDoc string
+		var rank float64
+
+		err := rows.Scan(&chunk.ID, &chunk.DocumentID, &chunk.Content, &chunk.Index, &chunk.Page, &embeddingBlob, &encoding, &sourceDoc, &rank)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+
+		chunk.Embedding, err = decodeStored(embeddingBlob, encoding)
+		if err != nil {
+			continue // Skip corrupted embeddings
+		}
+
+		// SQLite's bm25() is negative; more negative means more relevant
+		hits = append(hits, keywordHit{
+			result: entities.QueryResult{Chunk: chunk, SourceDoc: sourceDoc},
+			score:  -rank,
+		})
+	}
+	return hits, rows.Err()
+}
+
+// ftsMatchExpr turns free text into a safe FTS5 query: each word is
+// quoted (so operators and punctuation in user input are inert) and
+// terms are OR-ed together, letting BM25 do the ranking.
+func ftsMatchExpr(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, `"`+w+`"`)
+	}
+	return strings.Join(terms, " OR ")
+}
@@ -0,0 +1,82 @@
+package vectordb
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestFTSMatchExpr(t *testing.T) {
+	got := ftsMatchExpr(`error ERR_42: "disk" AND full?`)
+	want := `"error" OR "ERR_42" OR "disk" OR "AND" OR "full"`
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if ftsMatchExpr("?!") != "" {
+		t.Error("punctuation-only query should produce empty match")
+	}
+}
+
+func TestLanceDBStore_HybridSearchFindsIdentifier(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	if !store.ftsEnabled {
+		t.Skip("FTS5 not compiled in; run with -tags sqlite_fts5")
+	}
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "general notes about the system", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc2", Content: "set KAFKA_BROKER_TIMEOUT to 30s", Embedding: []float32{0, 1, 0}},
+	})
+
+	// Embedding favors c1, but the keyword only appears in c2
+	store.SetHybridWeight(0.3)
+	results, err := store.HybridSearch(ctx, "KAFKA_BROKER_TIMEOUT", []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("hybrid search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].Chunk.ID != "c2" {
+		t.Errorf("c2 should rank first on exact identifier, got %v", results)
+	}
+
+	// Lexical-only search needs no embedding; the best hit scores 1
+	lexical, err := store.KeywordSearch(ctx, "KAFKA_BROKER_TIMEOUT", 2)
+	if err != nil || len(lexical) != 1 || lexical[0].Chunk.ID != "c2" || lexical[0].Score != 1 {
+		t.Errorf("keyword search: %+v, %v", lexical, err)
+	}
+
+	// Deleting the document must drop it from the keyword index too
+	store.Delete(ctx, "doc2")
+	hits, _ := store.keywordSearch(ctx, ftsMatchExpr("KAFKA_BROKER_TIMEOUT"), 10)
+	if len(hits) != 0 {
+		t.Error("keyword index should be cleaned on delete")
+	}
+}
+
+func TestLanceDBStore_HybridSearchFallsBack(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "hello", Embedding: []float32{1, 0, 0}},
+	})
+
+	// Punctuation-only queries skip the keyword index entirely
+	results, err := store.HybridSearch(ctx, "??", []float32{1, 0, 0}, 1)
+	if err != nil {
+		t.Fatalf("hybrid search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Chunk.ID != "c1" {
+		t.Error("should fall back to vector search")
+	}
+}
@@ -0,0 +1,206 @@
+package vectordb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// Maintain cleans up and compacts the whole database (all collections):
+// registry rows and keyword index entries without chunks are dropped,
+// the keyword index is optimized, the file is VACUUMed and the vector
+// index is rebuilt. Writes wait for it to finish; searches continue and
+// use brute force while the vector index is being rebuilt.
+func (s *LanceDBStore) Maintain(ctx context.Context) (ports.MaintenanceReport, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	var report ports.MaintenanceReport
+	before, err := s.fileSize(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	s.mu.RLock()
+	vec := *s.vec
+	s.mu.RUnlock()
+
+	// VACUUM may renumber chunk rowids, which key the vector index, so
+	// searches stop using it here and it is rebuilt after compaction
+	s.setVecDims(0)
+	if err := s.removeOrphans(ctx, vec, &report); err != nil {
+		s.setVecDims(vec.dims)
+		return report, err
+	}
+
+	_, vacuumErr := s.db.ExecContext(ctx, "VACUUM")
+	if vec.dims > 0 {
+		// Rebuild even if VACUUM failed or ctx ended, so the index isn't lost
+		if err := s.rebuildVec(context.WithoutCancel(ctx), vec.dims); err != nil {
+			return report, err
+		}
+		report.IndexesRebuilt = append(report.IndexesRebuilt, "vector")
+	}
+	if vacuumErr != nil {
+		return report, fmt.Errorf("vacuuming database: %w", vacuumErr)
+	}
+
+	// Fold the WAL back into the main file so the saving is visible
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return report, fmt.Errorf("checkpointing: %w", err)
+	}
+	after, err := s.fileSize(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.ReclaimedBytes = before - after
+	return report, nil
+}
+
+// removeOrphans deletes registry rows and index entries that no longer
+// match a chunk, and drops the vector index ahead of VACUUM.
+func (s *LanceDBStore) removeOrphans(ctx context.Context, vec vecIndex, report *ports.MaintenanceReport) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM documents WHERE NOT EXISTS (
+			SELECT 1 FROM chunks c WHERE c.collection = documents.collection AND c.document_id = documents.id
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("pruning documents: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	report.OrphansRemoved += int(n)
+
+	if s.ftsEnabled {
+		orphans, err := countAndDelete(ctx, tx, `chunks_fts WHERE NOT EXISTS (
+			SELECT 1 FROM chunks c WHERE c.id = chunks_fts.chunk_id AND c.collection = chunks_fts.collection
+		)`)
+		if err != nil {
+			return fmt.Errorf("pruning keyword index: %w", err)
+		}
+		report.OrphansRemoved += orphans
+		if _, err := tx.ExecContext(ctx, "INSERT INTO chunks_fts (chunks_fts) VALUES ('optimize')"); err != nil {
+			return fmt.Errorf("optimizing keyword index: %w", err)
+		}
+		report.IndexesRebuilt = append(report.IndexesRebuilt, "keyword")
+	}
+
+	if vec.dims > 0 {
+		orphans, err := countAndDelete(ctx, tx, "chunks_vec WHERE rowid NOT IN (SELECT rowid FROM chunks)")
+		if err != nil {
+			return fmt.Errorf("pruning vector index: %w", err)
+		}
+		report.OrphansRemoved += orphans
+		if _, err := tx.ExecContext(ctx, "DROP TABLE chunks_vec"); err != nil {
+			return fmt.Errorf("dropping vector index: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// countAndDelete deletes the rows selected by "<table> WHERE ..." and
+// returns how many there were. Virtual tables don't reliably report
+// affected rows, so they are counted first.
+func countAndDelete(ctx context.Context, tx *sql.Tx, from string) (int, error) {
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+from).Scan(&n); err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM "+from)
+	return n, err
+}
+
+// rebuildVec recreates the vector index from the chunks table. Quantized
+// rows are indexed from their decoded approximation, since the original
+// vectors are gone.
+func (s *LanceDBStore) rebuildVec(ctx context.Context, dims int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := createVecTable(ctx, tx, dims); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chunks_vec (rowid, collection, embedding)
+		SELECT rowid, collection, embedding FROM chunks WHERE encoding = ? AND length(embedding) = ?
+	`, encodingFloat32, dims*4)
+	if err != nil {
+		return fmt.Errorf("rebuilding vector index: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT rowid, collection, embedding FROM chunks WHERE encoding = ? AND length(embedding) = ?",
+		encodingInt8, dims+4)
+	if err != nil {
+		return fmt.Errorf("rebuilding vector index: %w", err)
+	}
+	type quantized struct {
+		rowid      int64
+		collection string
+		blob       []byte
+	}
+	var pending []quantized
+	for rows.Next() {
+		var q quantized
+		if err := rows.Scan(&q.rowid, &q.collection, &q.blob); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning row: %w", err)
+		}
+		pending = append(pending, q)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rebuilding vector index: %w", err)
+	}
+	for _, q := range pending {
+		embedding, err := decodeInt8(q.blob)
+		if err != nil {
+			continue // Skip corrupted embeddings
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO chunks_vec (rowid, collection, embedding) VALUES (?, ?, ?)",
+			q.rowid, q.collection, encodeEmbedding(embedding))
+		if err != nil {
+			return fmt.Errorf("rebuilding vector index: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.setVecDims(dims)
+	return nil
+}
+
+// setVecDims publishes the vector index width; 0 hides the index from Search.
+func (s *LanceDBStore) setVecDims(dims int) {
+	s.mu.Lock()
+	s.vec.dims = dims
+	s.mu.Unlock()
+}
+
+// fileSize returns the size of the main database file in bytes.
+func (s *LanceDBStore) fileSize(ctx context.Context) (int64, error) {
+	var pages, pageSize int64
+	if err := s.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, fmt.Errorf("reading page count: %w", err)
+	}
+	if err := s.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("reading page size: %w", err)
+	}
+	return pages * pageSize, nil
+}
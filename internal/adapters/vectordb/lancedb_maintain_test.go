@@ -0,0 +1,67 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestLanceDBStore_Maintain(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewLanceDBStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	var chunks []entities.Chunk
+	for i := 0; i < 200; i++ {
+		chunks = append(chunks, entities.Chunk{
+			ID: fmt.Sprintf("c%d", i), DocumentID: fmt.Sprintf("doc%d", i%2),
+			Content: strings.Repeat("filler text ", 100), Embedding: []float32{float32(i), 1, 0},
+		})
+	}
+	store.Store(ctx, chunks)
+	store.RegisterDocument(ctx, entities.DocumentInfo{ID: "doc0", Name: "kept", IngestedAt: time.Now()})
+	store.RegisterDocument(ctx, entities.DocumentInfo{ID: "never-stored", Name: "orphan", IngestedAt: time.Now()})
+	store.Delete(ctx, "doc1")
+	wantOrphans := 1
+	if store.ftsEnabled {
+		store.db.Exec("INSERT INTO chunks_fts (chunk_id, collection, content) VALUES ('gone', 'default', 'stale')")
+		wantOrphans++
+	}
+
+	report, err := store.Maintain(ctx)
+	if err != nil {
+		t.Fatalf("maintain failed: %v", err)
+	}
+	if report.OrphansRemoved != wantOrphans {
+		t.Errorf("expected %d orphans removed, got %+v", wantOrphans, report)
+	}
+	if report.ReclaimedBytes <= 0 {
+		t.Errorf("expected space reclaimed after deleting half the chunks, got %+v", report)
+	}
+
+	var registered int
+	store.db.QueryRow("SELECT COUNT(*) FROM documents").Scan(&registered)
+	if registered != 1 {
+		t.Errorf("expected 1 registry row left, got %d", registered)
+	}
+
+	// Remaining data is intact and searchable
+	results, err := store.Search(ctx, []float32{198, 1, 0}, 1)
+	if err != nil || len(results) != 1 || results[0].SourceDoc != "kept" {
+		t.Errorf("search after maintenance: %+v, %v", results, err)
+	}
+	if store.vec.available && store.vec.dims != 3 {
+		t.Errorf("vector index not rebuilt: %+v", *store.vec)
+	}
+}
@@ -0,0 +1,71 @@
+package vectordb
+
+import "math"
+
+// prepare returns the embedding to store for a chunk and its L2 norm,
+// which is kept in chunks.norm so brute-force search scores a row with
+// one dot product. With normalization on the embedding is scaled to unit
+// length first, and its norm is 1.
+func (s *LanceDBStore) prepare(embedding []float32) ([]float32, float64) {
+	if s.normalize {
+		unit := normalized(embedding)
+		if unit != nil {
+			return unit, 1
+		}
+	}
+	return embedding, vectorNorm(embedding)
+}
+
+// prepareQuery scales a query to unit length when the store normalizes,
+// so it is compared with stored vectors by dot product alone.
+func (s *LanceDBStore) prepareQuery(embedding []float32) []float32 {
+	if s.normalize {
+		if unit := normalized(embedding); unit != nil {
+			return unit
+		}
+	}
+	return embedding
+}
+
+// vectorNorm returns the L2 norm of v.
+func vectorNorm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
+
+// normalized returns a unit-length copy of v, or nil for a zero vector.
+func normalized(v []float32) []float32 {
+	norm := vectorNorm(v)
+	if norm == 0 {
+		return nil
+	}
+	unit := make([]float32, len(v))
+	for i, x := range v {
+		unit[i] = float32(float64(x) / norm)
+	}
+	return unit
+}
+
+// dotProduct returns the dot product of two vectors of equal width.
+func dotProduct(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// normScore is cosine similarity from a dot product and precomputed
+// norms; with both vectors unit length it is the dot product itself.
+func normScore(a, b []float32, normA, normB float64) float64 {
+	if len(a) != len(b) || len(a) == 0 || normA == 0 || normB == 0 {
+		return 0
+	}
+	if normA == 1 && normB == 1 {
+		return dotProduct(a, b)
+	}
+	return dotProduct(a, b) / (normA * normB)
+}
@@ -0,0 +1,108 @@
+package vectordb
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestNormScore(t *testing.T) {
+	a := []float32{3, 4, 0}
+	b := []float32{0, 4, 3}
+	want := cosineSimilarity(a, b)
+	if got := normScore(a, b, vectorNorm(a), vectorNorm(b)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("normScore = %f, want %f", got, want)
+	}
+	ua, ub := normalized(a), normalized(b)
+	if got := normScore(ua, ub, 1, 1); math.Abs(got-want) > 1e-6 {
+		t.Errorf("unit normScore = %f, want %f", got, want)
+	}
+	if normalized([]float32{0, 0}) != nil {
+		t.Error("zero vector should not normalize")
+	}
+	if got := normScore(a, []float32{0, 0, 0}, 5, 0); got != 0 {
+		t.Errorf("score against zero vector = %f, want 0", got)
+	}
+}
+
+func TestLanceDBStore_Normalize(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewLanceDBStoreWithOptions(dir, LanceDBOptions{Normalize: true})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	chunks := []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "a", Embedding: []float32{3, 4, 0}},
+		{ID: "c2", DocumentID: "doc1", Content: "b", Embedding: []float32{0, 10, 0}},
+	}
+	if err := store.Store(ctx, chunks); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	var norm float64
+	store.db.QueryRow("SELECT norm FROM chunks WHERE id = 'c1'").Scan(&norm)
+	if norm != 1 {
+		t.Errorf("stored norm = %f, want 1", norm)
+	}
+
+	results, err := store.Search(ctx, []float32{6, 8, 0}, 2)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Chunk.ID != "c1" {
+		t.Fatalf("expected c1 first, got %+v", results)
+	}
+	if math.Abs(results[0].Score-1) > 1e-6 || math.Abs(results[1].Score-0.8) > 1e-6 {
+		t.Errorf("scores = %f, %f; want 1, 0.8", results[0].Score, results[1].Score)
+	}
+	if n := vectorNorm(results[1].Chunk.Embedding); math.Abs(n-1) > 1e-6 {
+		t.Errorf("returned embedding norm = %f, want 1", n)
+	}
+}
+
+func TestLanceDBStore_StoredNorms(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewLanceDBStoreWithOptions(dir, LanceDBOptions{DisableNativeIndex: true})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	err = store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "a", Embedding: []float32{3, 4}},
+		{ID: "c2", DocumentID: "doc1", Content: "b", Embedding: []float32{0, 2}},
+	})
+	if err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	var norm float64
+	store.db.QueryRow("SELECT norm FROM chunks WHERE id = 'c1'").Scan(&norm)
+	if norm != 5 {
+		t.Errorf("stored norm = %f, want 5", norm)
+	}
+
+	// Rows stored before norms were kept have theirs computed on load
+	store.db.Exec("UPDATE chunks SET norm = 0 WHERE id = 'c2'")
+	results, err := store.Search(ctx, []float32{0, 1}, 2)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Chunk.ID != "c2" || math.Abs(results[0].Score-1) > 1e-9 {
+		t.Fatalf("expected c2 first with score 1, got %+v", results)
+	}
+	if math.Abs(results[1].Score-0.8) > 1e-9 {
+		t.Errorf("c1 score = %f, want 0.8", results[1].Score)
+	}
+}
@@ -0,0 +1,111 @@
+package vectordb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Quantization selects how LanceDBStore encodes embeddings it stores.
+// Existing rows keep their encoding, so the setting can change between
+// runs without re-ingesting.
+type Quantization int
+
+const (
+	// QuantizeNone stores full-precision float32 vectors.
+	QuantizeNone Quantization = iota
+	// QuantizeInt8 stores one signed byte per dimension plus a per-vector
+	// scale, about 4x smaller. Search ranks on the codes and rescores the
+	// best candidates against the full-precision query.
+	QuantizeInt8
+)
+
+// Values of the chunks.encoding column.
+const (
+	encodingFloat32 = 0
+	encodingInt8    = 1
+)
+
+// rescoreFactor widens the candidate pool taken from the quantized scan
+// before exact rescoring, recovering most of the recall lost to rounding.
+const rescoreFactor = 4
+
+// encode packs an embedding using the store's quantization.
+func (s *LanceDBStore) encode(embedding []float32) (blob []byte, encoding int) {
+	if s.quantization == QuantizeInt8 {
+		return encodeInt8(embedding), encodingInt8
+	}
+	return encodeEmbedding(embedding), encodingFloat32
+}
+
+// decodeStored unpacks a chunks.embedding blob of the given encoding.
+func decodeStored(blob []byte, encoding int) ([]float32, error) {
+	switch encoding {
+	case encodingFloat32:
+		return decodeEmbedding(blob)
+	case encodingInt8:
+		return decodeInt8(blob)
+	default:
+		return nil, fmt.Errorf("unknown embedding encoding %d", encoding)
+	}
+}
+
+// storedDimension returns the vector width of a blob of n bytes.
+func storedDimension(n, encoding int) int {
+	if encoding == encodingInt8 {
+		return n - 4
+	}
+	return n / 4
+}
+
+// encodeInt8 writes a little-endian float32 scale followed by one code
+// per dimension, with code = round(v / scale) and scale = max|v| / 127.
+func encodeInt8(embedding []float32) []byte {
+	var maxAbs float64
+	for _, v := range embedding {
+		maxAbs = math.Max(maxAbs, math.Abs(float64(v)))
+	}
+	scale := float32(maxAbs / 127)
+
+	buf := make([]byte, 4+len(embedding))
+	binary.LittleEndian.PutUint32(buf, math.Float32bits(scale))
+	if scale == 0 {
+		return buf
+	}
+	for i, v := range embedding {
+		buf[4+i] = byte(int8(math.Round(float64(v / scale))))
+	}
+	return buf
+}
+
+// decodeInt8 reconstructs an approximate vector from encodeInt8 output.
+func decodeInt8(blob []byte) ([]float32, error) {
+	if len(blob) < 4 {
+		return nil, fmt.Errorf("quantized embedding blob length %d is too short", len(blob))
+	}
+	scale := math.Float32frombits(binary.LittleEndian.Uint32(blob))
+	embedding := make([]float32, len(blob)-4)
+	for i := range embedding {
+		embedding[i] = float32(int8(blob[4+i])) * scale
+	}
+	return embedding, nil
+}
+
+// int8Cosine approximates cosine similarity between two encodeInt8
+// blobs using integer arithmetic on the codes; scales cancel out.
+func int8Cosine(a, b []byte) float64 {
+	if len(a) != len(b) || len(a) <= 4 {
+		return 0
+	}
+	var dot, normA, normB int64
+	for i := 4; i < len(a); i++ {
+		x, y := int64(int8(a[i])), int64(int8(b[i]))
+		dot += x * y
+		normA += x * x
+		normB += y * y
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float64(dot) / math.Sqrt(float64(normA)*float64(normB))
+}
@@ -0,0 +1,119 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestInt8RoundTrip(t *testing.T) {
+	original := []float32{0.5, -1.0, 0.25, 0, 0.999}
+	blob := encodeInt8(original)
+	if len(blob) != 4+len(original) {
+		t.Fatalf("blob length = %d, want %d", len(blob), 4+len(original))
+	}
+	decoded, err := decodeInt8(blob)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	for i := range original {
+		if math.Abs(float64(decoded[i]-original[i])) > 1.0/127 {
+			t.Errorf("dim %d: got %f, want %f", i, decoded[i], original[i])
+		}
+	}
+
+	if zero, _ := decodeInt8(encodeInt8(make([]float32, 3))); len(zero) != 3 || zero[0] != 0 {
+		t.Errorf("zero vector round trip: %v", zero)
+	}
+}
+
+func TestLanceDBStore_Int8Quantization(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewLanceDBStoreWithOptions(dir, LanceDBOptions{Quantization: QuantizeInt8})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	randomVec := func() []float32 {
+		v := make([]float32, 64)
+		for i := range v {
+			v[i] = float32(rng.NormFloat64())
+		}
+		return v
+	}
+
+	ctx := context.Background()
+	var chunks []entities.Chunk
+	for i := 0; i < 200; i++ {
+		chunks = append(chunks, entities.Chunk{ID: fmt.Sprintf("c%d", i), DocumentID: "doc1", Content: "x", Embedding: randomVec()})
+	}
+	if err := store.Store(ctx, chunks); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	var bytes int
+	store.db.QueryRow("SELECT length(embedding) FROM chunks LIMIT 1").Scan(&bytes)
+	if bytes != 4+64 {
+		t.Errorf("stored %d bytes per embedding, want %d", bytes, 4+64)
+	}
+
+	// Each stored vector must still be its own nearest neighbour
+	for _, c := range chunks[:20] {
+		results, err := store.Search(ctx, c.Embedding, 3)
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		if len(results) != 3 || results[0].Chunk.ID != c.ID {
+			t.Fatalf("expected %s first, got %+v", c.ID, results)
+		}
+		if results[0].Score < 0.99 {
+			t.Errorf("rescored self-similarity %f, want ~1", results[0].Score)
+		}
+		if len(results[0].Chunk.Embedding) != 64 {
+			t.Errorf("result embedding has %d dims", len(results[0].Chunk.Embedding))
+		}
+	}
+
+	// Dimension checks account for the quantized layout
+	if _, err := store.Search(ctx, []float32{1, 0}, 1); err == nil {
+		t.Error("expected dimension mismatch")
+	}
+}
+
+func TestLanceDBStore_MixedEncodings(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	store, _ := NewLanceDBStore(dir)
+	store.Store(ctx, []entities.Chunk{
+		{ID: "float", DocumentID: "doc1", Content: "a", Embedding: []float32{1, 0, 0}},
+	})
+	store.Close()
+
+	// Turning quantization on later keeps existing rows readable
+	store, err := NewLanceDBStoreWithOptions(dir, LanceDBOptions{Quantization: QuantizeInt8})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer store.Close()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "int8", DocumentID: "doc2", Content: "b", Embedding: []float32{0, 1, 0}},
+	})
+
+	for query, want := range map[string][]float32{"float": {1, 0, 0}, "int8": {0, 1, 0}} {
+		results, err := store.Search(ctx, want, 2)
+		if err != nil || len(results) != 2 || results[0].Chunk.ID != query {
+			t.Errorf("query for %s: %v, %v", query, results, err)
+		}
+	}
+}
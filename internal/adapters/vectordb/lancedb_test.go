@@ -2,10 +2,15 @@ package vectordb
 
 import (
 	"context"
+	"errors"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/filter"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 )
 
 func TestLanceDBStore_StoreAndSearch(t *testing.T) {
@@ -106,3 +111,743 @@ func TestLanceDBStore_CosineSimilarity(t *testing.T) {
 		t.Errorf("orthogonal vectors should have score 0.0, got %f", diff)
 	}
 }
+
+func TestLanceDBStore_EmbeddingRoundTrip(t *testing.T) {
+	in := []float32{0.5, -1.25, 3.0e-7, 0}
+	out, err := decodeEmbedding(encodeEmbedding(in))
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("expected %d dims, got %d", len(in), len(out))
+	}
+	for i := range in {
+		if in[i] != out[i] {
+			t.Errorf("dim %d: expected %v, got %v", i, in[i], out[i])
+		}
+	}
+
+	if _, err := decodeEmbedding([]byte{1, 2, 3}); err == nil {
+		t.Error("should reject truncated blob")
+	}
+}
+
+func TestLanceDBStore_MigratesJSONEmbeddings(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	// Simulate a database written before the binary format
+	store, _ := NewLanceDBStore(dir)
+	store.db.Exec(`INSERT INTO chunks (id, document_id, content, chunk_index, embedding, source_doc)
+		VALUES ('legacy', 'doc1', 'old', 0, '[1,0,0]', 'doc1')`)
+	store.db.Exec("PRAGMA user_version = 0")
+	store.Close()
+
+	store, err := NewLanceDBStore(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer store.Close()
+
+	results, _ := store.Search(context.Background(), []float32{1, 0, 0}, 1)
+	if len(results) != 1 || results[0].Chunk.ID != "legacy" {
+		t.Fatal("legacy chunk should be searchable after migration")
+	}
+	if results[0].Score < 0.99 {
+		t.Errorf("expected score ~1.0, got %f", results[0].Score)
+	}
+}
+
+func TestLanceDBStore_Collections(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	work := store.Collection("work")
+
+	// Same chunk ID in two collections must not collide
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "personal", Embedding: []float32{1, 0, 0}}})
+	work.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "work", Embedding: []float32{1, 0, 0}}})
+
+	results, _ := work.Search(ctx, []float32{1, 0, 0}, 10)
+	if len(results) != 1 || results[0].Chunk.Content != "work" {
+		t.Errorf("work collection should only see its own chunk, got %v", results)
+	}
+
+	names, _ := store.Collections(ctx)
+	if len(names) != 2 || names[0] != entities.DefaultCollection || names[1] != "work" {
+		t.Errorf("unexpected collections: %v", names)
+	}
+
+	work.Clear(ctx)
+	count, _ := store.ChunkCount(ctx)
+	if count != 1 {
+		t.Errorf("clearing work should keep default chunk, got %d", count)
+	}
+}
+
+func TestLanceDBStore_MigratesToCollections(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	// Create a version 1 database by hand
+	store, _ := NewLanceDBStore(dir)
+	store.db.Exec(`DROP TABLE chunks`)
+	store.db.Exec(`CREATE TABLE chunks (
+		id TEXT PRIMARY KEY, document_id TEXT NOT NULL, content TEXT NOT NULL,
+		chunk_index INTEGER NOT NULL, embedding BLOB NOT NULL, source_doc TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	store.db.Exec(`INSERT INTO chunks (id, document_id, content, chunk_index, embedding, source_doc)
+		VALUES ('old', 'doc1', 'kept', 0, ?, 'doc1')`, encodeEmbedding([]float32{1, 0, 0}))
+	store.db.Exec("PRAGMA user_version = 1")
+	store.Close()
+
+	store, err := NewLanceDBStore(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer store.Close()
+
+	results, _ := store.Search(context.Background(), []float32{1, 0, 0}, 1)
+	if len(results) != 1 || results[0].Chunk.ID != "old" {
+		t.Error("existing chunks should move to the default collection")
+	}
+}
+
+func TestLanceDBStore_ListDocuments(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	ingested := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc1", Content: "b", Embedding: []float32{0, 1, 0}},
+	})
+	store.RegisterDocument(ctx, entities.DocumentInfo{ID: "doc1", Name: "handbook.md", Path: "/docs/handbook.md", IngestedAt: ingested})
+
+	page, err := store.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+	docs := page.Documents
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(docs))
+	}
+	d := docs[0]
+	if d.Name != "handbook.md" || d.Path != "/docs/handbook.md" || d.ChunkCount != 2 || !d.IngestedAt.Equal(ingested) {
+		t.Errorf("unexpected document info: %+v", d)
+	}
+
+	// Search results cite the registered name
+	results, _ := store.Search(ctx, []float32{1, 0, 0}, 1)
+	if results[0].SourceDoc != "handbook.md" {
+		t.Errorf("expected source handbook.md, got %s", results[0].SourceDoc)
+	}
+
+	store.Delete(ctx, "doc1")
+	page, _ = store.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+	docs = page.Documents
+	if len(docs) != 0 {
+		t.Error("deleted document should not be listed")
+	}
+}
+
+func TestLanceDBStore_ListDocumentsFilterAndPage(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	testListDocuments(t, store)
+}
+
+func TestLanceDBStore_EmbeddingModel(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	testEmbeddingModel(t, store)
+}
+
+func TestLanceDBStore_SwapCollections(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	testSwapCollections(t, store)
+}
+
+func TestLanceDBStore_DimensionMismatch(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}}})
+
+	err := store.Store(ctx, []entities.Chunk{{ID: "c2", DocumentID: "doc2", Embedding: []float32{1, 0}}})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("expected ErrDimensionMismatch on store, got %v", err)
+	}
+	if _, err := store.Search(ctx, []float32{1, 0}, 1); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("expected ErrDimensionMismatch on search, got %v", err)
+	}
+
+	// Collections are checked independently
+	if err := store.Collection("other").Store(ctx, []entities.Chunk{{ID: "c2", DocumentID: "doc2", Embedding: []float32{1, 0}}}); err != nil {
+		t.Errorf("other collection should accept its own dimension: %v", err)
+	}
+}
+
+func TestLanceDBStore_ReadsDuringWrite(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewLanceDBStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	var mode string
+	if err := store.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatalf("reading journal mode: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "hello", Embedding: []float32{1, 0, 0}},
+	})
+
+	// Simulate a long ingestion holding the writer lock and an open transaction
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	tx, err := store.db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM chunks"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		results, err := store.Search(ctx, []float32{1, 0, 0}, 1)
+		if err == nil && len(results) != 1 {
+			err = errors.New("uncommitted delete visible to reader")
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("search during write: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("search blocked behind writer")
+	}
+}
+
+func TestLanceDBStore_SkipsUnchangedChunks(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	testSkipsUnchangedChunks(t, store)
+}
+
+// testSkipsUnchangedChunks checks that a chunk re-stored with the same
+// hash is left alone, and that a changed hash overwrites it.
+func testSkipsUnchangedChunks(t *testing.T, store interface {
+	ports.VectorStore
+	ports.ChunkHasher
+}) {
+	t.Helper()
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "a", Hash: "h1", Embedding: []float32{1, 0, 0}},
+	})
+
+	hashes, err := store.ChunkHashes(ctx, "doc1")
+	if err != nil || hashes["c1"] != "h1" {
+		t.Fatalf("ChunkHashes = %v, %v", hashes, err)
+	}
+
+	// Same hash: the original embedding survives
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "a", Hash: "h1", Embedding: []float32{0, 1, 0}},
+	})
+	results, _ := store.Search(ctx, []float32{1, 0, 0}, 1)
+	if len(results) != 1 || results[0].Score < 0.99 {
+		t.Errorf("unchanged chunk was rewritten: %+v", results)
+	}
+
+	// New hash: the chunk is replaced
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "b", Hash: "h2", Embedding: []float32{0, 1, 0}},
+	})
+	results, _ = store.Search(ctx, []float32{0, 1, 0}, 1)
+	if len(results) != 1 || results[0].Chunk.Content != "b" || results[0].Score < 0.99 {
+		t.Errorf("changed chunk was not rewritten: %+v", results)
+	}
+	if hashes, _ := store.ChunkHashes(ctx, "doc1"); hashes["c1"] != "h2" {
+		t.Errorf("hash not updated: %v", hashes)
+	}
+}
+
+func TestLanceDBStore_SearchDocuments(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	testSearchDocuments(t, store)
+}
+
+func TestLanceDBStore_DeleteMany(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	testDeleteMany(t, store)
+}
+
+func TestLanceDBStore_Upsert(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	testUpsert(t, store)
+}
+
+func TestLanceDBStore_ChunkPages(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	testChunkPages(t, store)
+}
+
+func TestLanceDBStore_Stats(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	if stats := testStats(t, store); stats.SizeBytes <= 0 {
+		t.Errorf("expected database size, got %d", stats.SizeBytes)
+	}
+}
+
+// testSearchDocuments checks that document-scoped search ignores better
+// matches from other documents.
+func testSearchDocuments(t *testing.T, store interface {
+	ports.VectorStore
+	ports.DocumentSearcher
+}) {
+	t.Helper()
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "a1", DocumentID: "handbook", Content: "a1", Embedding: []float32{0.6, 0.8, 0}},
+		{ID: "b1", DocumentID: "notes", Content: "b1", Embedding: []float32{1, 0, 0}},
+		{ID: "c1", DocumentID: "memo", Content: "c1", Embedding: []float32{0, 0, 1}},
+	})
+
+	results, err := store.SearchDocuments(ctx, []float32{1, 0, 0}, 5, []string{"handbook"})
+	if err != nil {
+		t.Fatalf("scoped search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Chunk.ID != "a1" {
+		t.Errorf("expected only a1, got %+v", results)
+	}
+
+	results, _ = store.SearchDocuments(ctx, []float32{1, 0, 0}, 5, []string{"handbook", "memo"})
+	if len(results) != 2 || results[0].Chunk.ID != "a1" {
+		t.Errorf("expected a1 then c1, got %+v", results)
+	}
+}
+
+// testStats checks Stats on an empty and a populated store and returns
+// the populated stats for adapter-specific checks.
+func testStats(t *testing.T, store ports.VectorStore) ports.StoreStats {
+	t.Helper()
+	ctx := context.Background()
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("stats on empty store failed: %v", err)
+	}
+	if stats.Chunks != 0 || stats.Documents != 0 || stats.Dimension != 0 {
+		t.Errorf("empty store should report no contents, got %+v", stats)
+	}
+
+	store.Store(ctx, []entities.Chunk{
+		{ID: "a1", DocumentID: "handbook", Content: "a1", Embedding: []float32{0.6, 0.8, 0}},
+		{ID: "a2", DocumentID: "handbook", Content: "a2", Embedding: []float32{1, 0, 0}},
+		{ID: "b1", DocumentID: "notes", Content: "b1", Embedding: []float32{0, 0, 1}},
+	})
+	stats, err = store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if stats.Chunks != 3 || stats.Documents != 2 || stats.Dimension != 3 {
+		t.Errorf("expected 3 chunks, 2 documents, dimension 3, got %+v", stats)
+	}
+	return stats
+}
+
+// testChunkPages checks that search results keep the page their chunk
+// comes from.
+func testChunkPages(t *testing.T, store ports.VectorStore) {
+	t.Helper()
+	ctx := context.Background()
+	err := store.Store(ctx, []entities.Chunk{
+		{ID: "r1", DocumentID: "report", Content: "Costs fell", Embedding: []float32{1, 0, 0}, Page: 12},
+		{ID: "n1", DocumentID: "notes", Content: "Unpaged", Embedding: []float32{0, 1, 0}},
+	})
+	if err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	results, err := store.Search(ctx, []float32{1, 0.1, 0}, 2)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("expected both chunks, got %+v, %v", results, err)
+	}
+	if results[0].Chunk.Page != 12 || results[1].Chunk.Page != 0 {
+		t.Errorf("unexpected pages: %d, %d", results[0].Chunk.Page, results[1].Chunk.Page)
+	}
+}
+
+// testDeleteMany checks that DeleteMany removes exactly the named documents.
+func testDeleteMany(t *testing.T, store interface {
+	ports.VectorStore
+	ports.BatchDeleter
+}) {
+	t.Helper()
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "a1", DocumentID: "handbook", Content: "a1", Embedding: []float32{1, 0, 0}},
+		{ID: "a2", DocumentID: "handbook", Content: "a2", Embedding: []float32{0.9, 0.1, 0}},
+		{ID: "b1", DocumentID: "notes", Content: "b1", Embedding: []float32{0, 1, 0}},
+		{ID: "c1", DocumentID: "memo", Content: "c1", Embedding: []float32{0, 0, 1}},
+	})
+
+	if err := store.DeleteMany(ctx, []string{"handbook", "memo", "unknown"}); err != nil {
+		t.Fatalf("delete many failed: %v", err)
+	}
+	results, err := store.Search(ctx, []float32{1, 0, 0}, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Chunk.ID != "b1" {
+		t.Errorf("only b1 should remain, got %+v", results)
+	}
+	if err := store.DeleteMany(ctx, nil); err != nil {
+		t.Errorf("deleting nothing should succeed, got %v", err)
+	}
+}
+
+// testUpsert checks each conflict policy, and that a replaced chunk
+// moves to its new document.
+func testUpsert(t *testing.T, store interface {
+	ports.VectorStore
+	ports.Upserter
+}) {
+	t.Helper()
+	ctx := context.Background()
+	chunk := func(doc, content, hash string) []entities.Chunk {
+		return []entities.Chunk{{ID: "c1", DocumentID: doc, Content: content, Hash: hash, Embedding: []float32{1, 0, 0}}}
+	}
+	expect := func(step, doc, content string) {
+		t.Helper()
+		results, err := store.Search(ctx, []float32{1, 0, 0}, 10)
+		if err != nil {
+			t.Fatalf("%s: search failed: %v", step, err)
+		}
+		if len(results) != 1 || results[0].Chunk.DocumentID != doc || results[0].Chunk.Content != content {
+			t.Errorf("%s: want %s/%q, got %+v", step, doc, content, results)
+		}
+	}
+
+	if err := store.Store(ctx, chunk("a", "v1", "h1")); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	if err := store.Upsert(ctx, chunk("a", "v2", "h2"), ports.ConflictSkip); err != nil {
+		t.Fatalf("skip failed: %v", err)
+	}
+	expect("skip", "a", "v1")
+
+	store.Upsert(ctx, chunk("a", "v1 reformatted", "h1"), ports.ConflictVersion)
+	expect("same version", "a", "v1")
+	store.Upsert(ctx, chunk("a", "v2", "h2"), ports.ConflictVersion)
+	expect("new version", "a", "v2")
+
+	store.Upsert(ctx, chunk("b", "v3", "h2"), ports.ConflictReplace)
+	expect("replace", "b", "v3")
+
+	// The chunk left document a, so deleting a must not remove it
+	store.Delete(ctx, "a")
+	expect("delete old document", "b", "v3")
+	store.Delete(ctx, "b")
+	if results, _ := store.Search(ctx, []float32{1, 0, 0}, 10); len(results) != 0 {
+		t.Errorf("deleting the new document should remove the chunk, got %+v", results)
+	}
+}
+
+// testListDocuments checks filtering, ordering and paging of the
+// document listing, and that only documents with chunks are listed.
+func parseFilter(t *testing.T, expr string) entities.FilterExpr {
+	t.Helper()
+	e, err := filter.Parse(expr)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", expr, err)
+	}
+	return e
+}
+
+func testListDocuments(t *testing.T, store interface {
+	ports.VectorStore
+	ports.DocumentRegistry
+}) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.Store(ctx, []entities.Chunk{
+		{ID: "a1", DocumentID: "doc-a", Content: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "a2", DocumentID: "doc-a", Content: "a", Embedding: []float32{0, 1, 0}},
+		{ID: "b1", DocumentID: "doc-b", Content: "b", Embedding: []float32{0, 0, 1}},
+		{ID: "c1", DocumentID: "doc-c", Content: "c", Embedding: []float32{1, 1, 0}},
+		{ID: "d1", DocumentID: "doc-d", Content: "d", Embedding: []float32{0, 1, 1}},
+	})
+	for _, info := range []entities.DocumentInfo{
+		{ID: "doc-a", Name: "handbook.md", Path: "/docs/handbook.md", IngestedAt: base, Metadata: map[string]string{"year": "2023"},
+			Provenance: entities.Provenance{{Kind: entities.TransformOCR, Tool: "tesseract"}, {Kind: entities.TransformTranslation, Detail: "de to en"}}},
+		{ID: "doc-b", Name: "notes.txt", Path: "/notes/notes.txt", IngestedAt: base.Add(time.Hour), Metadata: map[string]string{"year": "2024"}},
+		{ID: "doc-c", Name: "Roadmap.md", Path: "/docs/roadmap.md", IngestedAt: base.Add(2 * time.Hour)},
+		{ID: "ghost", Name: "ghost.md", Path: "/docs/ghost.md", IngestedAt: base},
+	} {
+		if err := store.RegisterDocument(ctx, info); err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+	}
+
+	ids := func(page entities.DocumentPage) []string {
+		var ids []string
+		for _, doc := range page.Documents {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+	cases := []struct {
+		name   string
+		filter entities.DocumentFilter
+		page   entities.Page
+		want   []string
+		total  int
+	}{
+		{"all", entities.DocumentFilter{}, entities.Page{}, []string{"doc-c", "doc-d", "doc-a", "doc-b"}, 4},
+		{"ids", entities.DocumentFilter{IDs: []string{"doc-b", "ghost", "doc-d"}}, entities.Page{}, []string{"doc-d", "doc-b"}, 2},
+		{"query", entities.DocumentFilter{Query: "ROAD"}, entities.Page{}, []string{"doc-c"}, 1},
+		{"path prefix", entities.DocumentFilter{PathPrefix: "/docs/"}, entities.Page{}, []string{"doc-c", "doc-a"}, 2},
+		{"ingested after", entities.DocumentFilter{IngestedAfter: base.Add(time.Hour)}, entities.Page{}, []string{"doc-c", "doc-b"}, 2},
+		{"ingested before", entities.DocumentFilter{IngestedBefore: base.Add(time.Hour)}, entities.Page{}, []string{"doc-a"}, 1},
+		{"metadata", entities.DocumentFilter{Metadata: []entities.MetadataCondition{{Field: "year", Min: "2024", Type: entities.FieldNumber}}}, entities.Page{}, []string{"doc-b"}, 1},
+		{"expression", entities.DocumentFilter{Expr: parseFilter(t, `doc:*.md NOT path:/docs/road*`)}, entities.Page{}, []string{"doc-a"}, 1},
+		{"expression or", entities.DocumentFilter{Expr: parseFilter(t, `year:2024 OR doc:doc-d`)}, entities.Page{}, []string{"doc-d", "doc-b"}, 2},
+		{"expression not", entities.DocumentFilter{Expr: parseFilter(t, `NOT year:2023`)}, entities.Page{}, []string{"doc-c", "doc-d", "doc-b"}, 3},
+		{"expression time", entities.DocumentFilter{Expr: parseFilter(t, `ingested>=2024-05-01 path:/docs/*`)}, entities.Page{}, []string{"doc-c", "doc-a"}, 2},
+		{"page", entities.DocumentFilter{}, entities.Page{Limit: 2, Offset: 1}, []string{"doc-d", "doc-a"}, 4},
+		{"past the end", entities.DocumentFilter{}, entities.Page{Offset: 10}, nil, 4},
+	}
+	for _, tc := range cases {
+		page, err := store.ListDocuments(ctx, tc.filter, tc.page)
+		if err != nil {
+			t.Fatalf("%s: list failed: %v", tc.name, err)
+		}
+		if got := ids(page); page.Total != tc.total || strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: got %v of %d, want %v of %d", tc.name, got, page.Total, tc.want, tc.total)
+		}
+	}
+
+	page, _ := store.ListDocuments(ctx, entities.DocumentFilter{Query: "handbook"}, entities.Page{})
+	if len(page.Documents) != 1 {
+		t.Fatalf("expected the handbook, got %+v", page.Documents)
+	}
+	if d := page.Documents[0]; d.Name != "handbook.md" || d.Path != "/docs/handbook.md" || d.ChunkCount != 2 || !d.IngestedAt.Equal(base) {
+		t.Errorf("unexpected document info: %+v", d)
+	}
+	if p := page.Documents[0].Provenance.String(); p != "ocr (tesseract) -> translation (de to en)" {
+		t.Errorf("provenance not kept, got %q", p)
+	}
+	if d, _ := store.ListDocuments(ctx, entities.DocumentFilter{Query: "doc-d"}, entities.Page{}); d.Total != 1 || d.Documents[0].Name != "doc-d" {
+		t.Errorf("unregistered document should be named by ID, got %+v", d.Documents)
+	}
+
+	store.Delete(ctx, "doc-a")
+	if page, _ := store.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{}); page.Total != 3 {
+		t.Errorf("deleted document should not be listed, got %v", ids(page))
+	}
+}
+
+func testEmbeddingModel(t *testing.T, store interface {
+	ports.CollectionStore
+	ports.ModelRecorder
+}) {
+	t.Helper()
+	ctx := context.Background()
+	if model, err := store.EmbeddingModel(ctx); err != nil || model != (ports.EmbeddingModel{}) {
+		t.Fatalf("expected no recorded model, got %+v, %v", model, err)
+	}
+	want := ports.EmbeddingModel{Name: "nomic-embed-text", Dimension: 768}
+	if err := store.RecordEmbeddingModel(ctx, want); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	if model, err := store.EmbeddingModel(ctx); err != nil || model != want {
+		t.Errorf("expected %+v, got %+v, %v", want, model, err)
+	}
+
+	// Collections record their own model
+	other := store.Collection("other").(ports.ModelRecorder)
+	if model, _ := other.EmbeddingModel(ctx); model != (ports.EmbeddingModel{}) {
+		t.Errorf("expected no model for another collection, got %+v", model)
+	}
+	bge := ports.EmbeddingModel{Name: "bge-m3", Dimension: 1024}
+	other.RecordEmbeddingModel(ctx, bge)
+	if model, _ := other.EmbeddingModel(ctx); model != bge {
+		t.Errorf("expected %+v, got %+v", bge, model)
+	}
+	if model, _ := store.EmbeddingModel(ctx); model != want {
+		t.Errorf("recording another collection's model changed the default's to %+v", model)
+	}
+
+	if err := store.Clear(ctx); err != nil {
+		t.Fatalf("clear failed: %v", err)
+	}
+	if model, _ := store.EmbeddingModel(ctx); model != (ports.EmbeddingModel{}) {
+		t.Errorf("expected Clear to forget the model, got %+v", model)
+	}
+}
+
+func testSwapCollections(t *testing.T, store interface {
+	ports.CollectionStore
+	ports.CollectionSwapper
+}) {
+	t.Helper()
+	ctx := context.Background()
+	next := store.Collection("next")
+
+	// Both collections hold doc1, with different text and widths
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c2", DocumentID: "doc1", Content: "old second", Index: 1, Hash: "h2", Embedding: []float32{0, 1}},
+		{ID: "c1", DocumentID: "doc1", Content: "old first", Index: 0, Hash: "h1", Embedding: []float32{1, 0}},
+	})
+	store.(ports.DocumentRegistry).RegisterDocument(ctx, entities.DocumentInfo{ID: "doc1", Name: "old.txt"})
+	store.(ports.ModelRecorder).RecordEmbeddingModel(ctx, ports.EmbeddingModel{Name: "small", Dimension: 2})
+	next.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "new first", Index: 0, Embedding: []float32{1, 0, 0}},
+	})
+	next.(ports.DocumentRegistry).RegisterDocument(ctx, entities.DocumentInfo{ID: "doc1", Name: "new.txt"})
+	next.(ports.ModelRecorder).RecordEmbeddingModel(ctx, ports.EmbeddingModel{Name: "large", Dimension: 3})
+
+	chunks, err := store.(ports.ChunkReader).DocumentChunks(ctx, "doc1")
+	if err != nil || len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %v, %v", chunks, err)
+	}
+	if chunks[0].ID != "c1" || chunks[0].Content != "old first" || chunks[0].Hash != "h1" || chunks[1].Index != 1 {
+		t.Errorf("expected chunks in index order with content and hash, got %+v", chunks)
+	}
+	if chunks[0].Embedding != nil {
+		t.Errorf("expected no embeddings, got %v", chunks[0].Embedding)
+	}
+	if chunks, _ := store.(ports.ChunkReader).DocumentChunks(ctx, "missing"); len(chunks) != 0 {
+		t.Errorf("expected no chunks for a missing document, got %v", chunks)
+	}
+
+	if err := store.SwapCollections(ctx, entities.DefaultCollection, "next"); err != nil {
+		t.Fatalf("swap failed: %v", err)
+	}
+	results, err := store.Search(ctx, []float32{1, 0, 0}, 5)
+	if err != nil || len(results) != 1 || results[0].Chunk.Content != "new first" || results[0].SourceDoc != "new.txt" {
+		t.Errorf("expected the default collection to hold the new chunks, got %+v, %v", results, err)
+	}
+	if model, _ := store.(ports.ModelRecorder).EmbeddingModel(ctx); model.Name != "large" {
+		t.Errorf("expected the model to move with the chunks, got %+v", model)
+	}
+	results, err = next.Search(ctx, []float32{1, 0}, 5)
+	if err != nil || len(results) != 2 || results[0].SourceDoc != "old.txt" {
+		t.Errorf("expected the other collection to hold the old chunks, got %+v, %v", results, err)
+	}
+	if model, _ := next.(ports.ModelRecorder).EmbeddingModel(ctx); model.Name != "small" {
+		t.Errorf("expected the old model in the other collection, got %+v", model)
+	}
+
+	// Swapping with an empty collection moves everything across
+	if err := store.SwapCollections(ctx, "next", "empty"); err != nil {
+		t.Fatalf("swap failed: %v", err)
+	}
+	if stats, _ := next.Stats(ctx); stats.Chunks != 0 {
+		t.Errorf("expected next to be empty, got %+v", stats)
+	}
+	if stats, _ := store.Collection("empty").Stats(ctx); stats.Chunks != 2 {
+		t.Errorf("expected the chunks to move to empty, got %+v", stats)
+	}
+	if model, _ := next.(ports.ModelRecorder).EmbeddingModel(ctx); model != (ports.EmbeddingModel{}) {
+		t.Errorf("expected no model in the emptied collection, got %+v", model)
+	}
+}
+
+func testOpenSnapshot(t *testing.T, store interface {
+	ports.CollectionStore
+	ports.Snapshotter
+	ports.SnapshotReader
+}, snapshot string) {
+	t.Helper()
+	ctx := context.Background()
+	work := store.Collection("work")
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "snapshotted", Embedding: []float32{1, 0, 0}}})
+	work.Store(ctx, []entities.Chunk{{ID: "w1", DocumentID: "doc2", Content: "work", Embedding: []float32{1, 0, 0}}})
+	if err := store.Backup(ctx, snapshot); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	store.Clear(ctx)
+	store.Store(ctx, []entities.Chunk{{ID: "c9", DocumentID: "doc9", Content: "newer", Embedding: []float32{1, 0, 0}}})
+
+	snap, err := store.OpenSnapshot(ctx, snapshot)
+	if err != nil {
+		t.Fatalf("open snapshot failed: %v", err)
+	}
+	defer snap.Close()
+	chunks, err := snap.(ports.ChunkReader).DocumentChunks(ctx, "doc1")
+	if err != nil || len(chunks) != 1 || chunks[0].Content != "snapshotted" {
+		t.Errorf("expected the snapshotted chunk, got %v, %v", chunks, err)
+	}
+	names, _ := snap.(ports.CollectionStore).Collections(ctx)
+	if len(names) != 2 || names[1] != "work" {
+		t.Errorf("expected the snapshot's collections, got %v", names)
+	}
+	page, err := snap.(ports.CollectionStore).Collection("work").(ports.DocumentRegistry).ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+	if err != nil || page.Total != 1 {
+		t.Errorf("expected one document in the snapshot's work collection, got %+v, %v", page, err)
+	}
+	if results, _ := store.Search(ctx, []float32{1, 0, 0}, 10); len(results) != 1 || results[0].Chunk.Content != "newer" {
+		t.Errorf("opening a snapshot changed the live store: %v", results)
+	}
+}
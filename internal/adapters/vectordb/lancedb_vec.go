@@ -0,0 +1,258 @@
+package vectordb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/mattn/go-sqlite3"
+)
+
+// DefaultVecExtension is the sqlite-vec loadable extension name. SQLite
+// resolves it through the platform library path and adds the suffix.
+const DefaultVecExtension = "vec0"
+
+// LanceDBOptions tunes optional SQLite features of LanceDBStore.
+type LanceDBOptions struct {
+	// VecExtension is the sqlite-vec extension to load on each connection.
+	// Empty skips loading; a statically linked sqlite-vec is still detected.
+	VecExtension string
+	// DisableNativeIndex keeps Search on the Go brute-force path even
+	// when sqlite-vec is available.
+	DisableNativeIndex bool
+	// Quantization encodes newly stored embeddings compactly. The vec0
+	// index keeps its own float32 copy, so the saving is largest with
+	// the native index disabled or unavailable.
+	Quantization Quantization
+	// HotCache keeps the decoded rows of recently searched collections in
+	// memory, so brute-force searches (native index unavailable, disabled
+	// or document-scoped) don't re-read and re-decode every embedding.
+	// Costs about the embeddings' stored size in memory.
+	HotCache bool
+	// Normalize scales embeddings to unit length before storing them and
+	// queries before searching, so brute-force search scores by dot
+	// product. Suits models trained for normalized vectors; scores are
+	// unchanged, as cosine similarity ignores length.
+	Normalize bool
+}
+
+// vecIndex tracks the sqlite-vec KNN index. It is shared by all
+// collection views of one database and guarded by LanceDBStore.mu.
+type vecIndex struct {
+	available bool // vec0 module present
+	enabled   bool // use it for Search
+	dims      int  // embedding width; 0 until the table exists
+}
+
+var vecDrivers sync.Map // extension path -> registered driver name
+
+// extensionLoader is satisfied by cgo builds of go-sqlite3; the CGO-free
+// stub has no LoadExtension.
+type extensionLoader interface {
+	LoadExtension(lib, entry string) error
+}
+
+// sqliteDriver returns a database/sql driver name that loads the given
+// extension on connect. Load failures are ignored so a missing library
+// leaves an ordinary SQLite connection.
+func sqliteDriver(extension string) string {
+	if extension == "" {
+		return "sqlite3"
+	}
+	name := "sqlite3_vec_" + extension
+	if _, loaded := vecDrivers.LoadOrStore(extension, name); !loaded {
+		sql.Register(name, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				if loader, ok := interface{}(conn).(extensionLoader); ok {
+					loader.LoadExtension(extension, "sqlite3_vec_init")
+				}
+				return nil
+			},
+		})
+	}
+	return name
+}
+
+// initVec detects sqlite-vec and opens or backfills the KNN index.
+// It resets the shared state in place so existing views see the result.
+func (s *LanceDBStore) initVec(disabled bool) {
+	if s.vec == nil {
+		s.vec = &vecIndex{}
+	}
+	*s.vec = vecIndex{enabled: !disabled}
+
+	var version string
+	if err := s.db.QueryRow("SELECT vec_version()").Scan(&version); err != nil {
+		return
+	}
+	s.vec.available = true
+
+	var ddl string
+	err := s.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'chunks_vec'`).Scan(&ddl)
+	if err == nil {
+		if i := strings.Index(ddl, "float["); i >= 0 {
+			fmt.Sscanf(ddl[i:], "float[%d]", &s.vec.dims)
+		}
+		return
+	}
+
+	// Index rows stored before sqlite-vec was installed. Quantized rows
+	// are only indexed from the original vector at Store time.
+	var dims int
+	err = s.db.QueryRow("SELECT length(embedding) / 4 FROM chunks WHERE encoding = ? LIMIT 1", encodingFloat32).Scan(&dims)
+	if err != nil || dims == 0 {
+		return
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+	if err := createVecTable(context.Background(), tx, dims); err != nil {
+		return
+	}
+	_, err = tx.Exec(`
+		INSERT INTO chunks_vec (rowid, collection, embedding)
+		SELECT rowid, collection, embedding FROM chunks WHERE encoding = ? AND length(embedding) = ?
+	`, encodingFloat32, dims*4)
+	if err != nil {
+		return
+	}
+	if tx.Commit() == nil {
+		s.vec.dims = dims
+	}
+}
+
+// createVecTable creates the vec0 table for embeddings of the given width.
+// Collections are a partition key, so KNN queries never cross collections.
+func createVecTable(ctx context.Context, tx *sql.Tx, dims int) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE VIRTUAL TABLE chunks_vec USING vec0(
+			collection text partition key,
+			embedding float[%d] distance_metric=cosine
+		)
+	`, dims))
+	if err != nil {
+		return fmt.Errorf("creating vector index: %w", err)
+	}
+	return nil
+}
+
+// SetNativeIndex toggles sqlite-vec for Search. The index is kept up to
+// date either way, so re-enabling needs no rebuild.
+func (s *LanceDBStore) SetNativeIndex(enabled bool) {
+	s.mu.Lock()
+	s.vec.enabled = enabled
+	s.mu.Unlock()
+}
+
+// NativeIndex reports whether Search runs on sqlite-vec.
+func (s *LanceDBStore) NativeIndex() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.vec.available && s.vec.enabled
+}
+
+// unindexChunk removes a chunk's vector before it is replaced.
+// Must be called inside the Store transaction, before the chunk row changes.
+func (s *LanceDBStore) unindexChunk(ctx context.Context, tx *sql.Tx, vec vecIndex, chunkID string) error {
+	if vec.dims == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM chunks_vec WHERE rowid = (SELECT rowid FROM chunks WHERE collection = ? AND id = ?)
+	`, s.collection, chunkID)
+	if err != nil {
+		return fmt.Errorf("updating vector index: %w", err)
+	}
+	return nil
+}
+
+// indexChunk adds a freshly stored chunk to the KNN index, creating the
+// index on first use and recording its width in vec. Embeddings of a
+// different width stay out of the index and are only reachable through
+// brute-force Search.
+func (s *LanceDBStore) indexChunk(ctx context.Context, tx *sql.Tx, vec *vecIndex, rowid int64, embedding []float32) error {
+	if !vec.available || len(embedding) == 0 {
+		return nil
+	}
+	if vec.dims == 0 {
+		if err := createVecTable(ctx, tx, len(embedding)); err != nil {
+			return err
+		}
+		vec.dims = len(embedding)
+	}
+	if len(embedding) != vec.dims {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO chunks_vec (rowid, collection, embedding) VALUES (?, ?, ?)",
+		rowid, s.collection, encodeEmbedding(embedding))
+	if err != nil {
+		return fmt.Errorf("updating vector index: %w", err)
+	}
+	return nil
+}
+
+// unindexWhere removes vectors for chunks matching a chunks-table filter.
+// Caller must hold writeMu, which keeps the index width stable.
+func (s *LanceDBStore) unindexWhere(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) error {
+	if s.vec.dims == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM chunks_vec WHERE rowid IN (SELECT rowid FROM chunks WHERE "+where+")", args...)
+	if err != nil {
+		return fmt.Errorf("updating vector index: %w", err)
+	}
+	return nil
+}
+
+// nativeSearch runs a KNN query against sqlite-vec. ok is false when the
+// index cannot answer (disabled, absent, or a query of the wrong width)
+// and the caller should fall back to brute force.
+func (s *LanceDBStore) nativeSearch(ctx context.Context, vec vecIndex, embedding []float32, topK int) (results []entities.QueryResult, ok bool, err error) {
+	if !vec.available || !vec.enabled || vec.dims == 0 || len(embedding) != vec.dims {
+		return nil, false, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.id, c.document_id, c.content, c.chunk_index, c.page, c.embedding, c.encoding, COALESCE(d.name, c.source_doc), v.distance
+		FROM chunks_vec v
+		JOIN chunks c ON c.rowid = v.rowid
+		LEFT JOIN documents d ON d.collection = c.collection AND d.id = c.document_id
+		WHERE v.embedding MATCH ? AND v.k = ? AND v.collection = ?
+		ORDER BY v.distance
+	`, encodeEmbedding(embedding), topK, s.collection)
+	if err != nil {
+		return nil, true, fmt.Errorf("querying vector index: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chunk entities.Chunk
+		var embeddingBlob []byte
+		var encoding int
+		var sourceDoc string
+		var distance float64
+
+		err := rows.Scan(&chunk.ID, &chunk.DocumentID, &chunk.Content, &chunk.Index, &chunk.Page, &embeddingBlob, &encoding, &sourceDoc, &distance)
+		if err != nil {
+			return nil, true, fmt.Errorf("scanning row: %w", err)
+		}
+		chunk.Embedding, err = decodeStored(embeddingBlob, encoding)
+		if err != nil {
+			continue // Skip corrupted embeddings
+		}
+
+		// vec0 cosine distance is 1 - cosine similarity
+		results = append(results, entities.QueryResult{
+			Chunk:     chunk,
+			Score:     1 - distance,
+			SourceDoc: sourceDoc,
+		})
+	}
+	return results, true, rows.Err()
+}
@@ -0,0 +1,92 @@
+package vectordb
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestLanceDBStore_FallsBackWithoutVecExtension(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewLanceDBStoreWithOptions(dir, LanceDBOptions{VecExtension: "/nonexistent/vec0"})
+	if err != nil {
+		t.Fatalf("a missing extension should not fail open: %v", err)
+	}
+	defer store.Close()
+	if store.vec.available {
+		t.Skip("sqlite-vec is linked statically")
+	}
+	if store.NativeIndex() {
+		t.Error("native index should be off without sqlite-vec")
+	}
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc1", Content: "b", Embedding: []float32{0, 1, 0}},
+	})
+	results, err := store.Search(ctx, []float32{1, 0, 0}, 1)
+	if err != nil || len(results) != 1 || results[0].Chunk.ID != "c1" {
+		t.Errorf("brute-force search failed: %v, %v", results, err)
+	}
+}
+
+func TestLanceDBStore_NativeIndexMatchesBruteForce(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStore(dir)
+	defer store.Close()
+	if !store.NativeIndex() {
+		t.Skip("sqlite-vec extension not available")
+	}
+
+	ctx := context.Background()
+	work := store.Collection("work")
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc1", Content: "b", Embedding: []float32{0.6, 0.8, 0}},
+	})
+	work.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "w", Embedding: []float32{1, 0, 0}}})
+
+	native, err := store.Search(ctx, []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("native search failed: %v", err)
+	}
+	store.SetNativeIndex(false)
+	brute, _ := store.Search(ctx, []float32{1, 0, 0}, 2)
+
+	if len(native) != len(brute) {
+		t.Fatalf("expected %d results, got %d", len(brute), len(native))
+	}
+	for i := range native {
+		if native[i].Chunk.ID != brute[i].Chunk.ID || native[i].Score-brute[i].Score > 1e-5 || brute[i].Score-native[i].Score > 1e-5 {
+			t.Errorf("result %d differs: native %v, brute %v", i, native[i], brute[i])
+		}
+	}
+
+	// Deleted chunks leave the index
+	store.SetNativeIndex(true)
+	store.Delete(ctx, "doc1")
+	if results, _ := store.Search(ctx, []float32{1, 0, 0}, 10); len(results) != 0 {
+		t.Errorf("expected empty default collection, got %v", results)
+	}
+	if results, _ := work.Search(ctx, []float32{1, 0, 0}, 10); len(results) != 1 {
+		t.Errorf("work collection should be untouched, got %v", results)
+	}
+}
+
+func TestLanceDBStore_DisableNativeIndex(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lancedb-test-*")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewLanceDBStoreWithOptions(dir, LanceDBOptions{VecExtension: DefaultVecExtension, DisableNativeIndex: true})
+	defer store.Close()
+	if store.NativeIndex() {
+		t.Error("DisableNativeIndex should keep Search on brute force")
+	}
+}
@@ -5,59 +5,242 @@ package vectordb
 
 import (
 	"context"
+	"os"
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 )
 
 // InMemoryStore is a simple in-memory vector store for MVP.
 // Open-Closed: Can be replaced with LanceDB adapter without changing usecases.
 type InMemoryStore struct {
 	mu     sync.RWMutex
-	chunks map[string]entities.Chunk // chunkID -> chunk
-	docs   map[string][]string       // docID -> []chunkID
+	chunks map[string]entities.Chunk        // chunkID -> chunk
+	docs   map[string][]string              // docID -> []chunkID
+	infos  map[string]entities.DocumentInfo // docID -> registry entry
+	model  ports.EmbeddingModel             // Recorded embedding model; zero when none
+
+	colMu       sync.Mutex
+	collections map[string]*InMemoryStore // name -> store; default collection is the receiver
+
+	path string         // Snapshot file saved on Close; empty when not persisted
+	root *InMemoryStore // Store owning this collection; nil for the default collection
+
+	view atomic.Pointer[memoryView] // Copy scanned by searches; nil after a write (see memory_view.go)
 }
 
 // NewInMemoryStore creates a new in-memory vector store.
 func NewInMemoryStore() *InMemoryStore {
 	return &InMemoryStore{
-		chunks: make(map[string]entities.Chunk),
-		docs:   make(map[string][]string),
+		chunks:      make(map[string]entities.Chunk),
+		docs:        make(map[string][]string),
+		infos:       make(map[string]entities.DocumentInfo),
+		collections: make(map[string]*InMemoryStore),
+	}
+}
+
+// Collection returns a store scoped to the named collection.
+func (s *InMemoryStore) Collection(name string) ports.VectorStore {
+	if name == "" || name == entities.DefaultCollection {
+		return s
+	}
+
+	s.colMu.Lock()
+	defer s.colMu.Unlock()
+
+	c, ok := s.collections[name]
+	if !ok {
+		c = &InMemoryStore{
+			chunks: make(map[string]entities.Chunk),
+			docs:   make(map[string][]string),
+			infos:  make(map[string]entities.DocumentInfo),
+			root:   s,
+		}
+		s.collections[name] = c
+	}
+	return c
+}
+
+// Collections lists collections that hold data.
+func (s *InMemoryStore) Collections(ctx context.Context) ([]string, error) {
+	s.colMu.Lock()
+	defer s.colMu.Unlock()
+
+	names := []string{entities.DefaultCollection}
+	for name, c := range s.collections {
+		c.mu.RLock()
+		if len(c.chunks) > 0 {
+			names = append(names, name)
+		}
+		c.mu.RUnlock()
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+// SwapCollections exchanges the contents of two collections. Both are
+// locked throughout, in name order so concurrent swaps cannot deadlock.
+func (s *InMemoryStore) SwapCollections(ctx context.Context, a, b string) error {
+	if a == "" {
+		a = entities.DefaultCollection
+	}
+	if b == "" {
+		b = entities.DefaultCollection
 	}
+	if a == b {
+		return nil
+	}
+	if b < a {
+		a, b = b, a
+	}
+	x, y := s.Collection(a).(*InMemoryStore), s.Collection(b).(*InMemoryStore)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	x.chunks, y.chunks = y.chunks, x.chunks
+	x.docs, y.docs = y.docs, x.docs
+	x.infos, y.infos = y.infos, x.infos
+	x.model, y.model = y.model, x.model
+	x.view.Store(nil)
+	y.view.Store(nil)
+	return nil
 }
 
 // Store saves chunks with their embeddings.
 func (s *InMemoryStore) Store(ctx context.Context, chunks []entities.Chunk) error {
+	return s.Upsert(ctx, chunks, ports.ConflictVersion)
+}
+
+// Upsert is Store with an explicit policy for chunks already stored.
+func (s *InMemoryStore) Upsert(ctx context.Context, chunks []entities.Chunk, policy ports.ConflictPolicy) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := checkChunkDimensions(s.dimension(), chunks); err != nil {
+		return err
+	}
+	s.view.Store(nil)
+
 	for _, chunk := range chunks {
+		old, exists := s.chunks[chunk.ID]
+		if exists && keepStored(policy, old.Hash, chunk) {
+			continue
+		}
 		s.chunks[chunk.ID] = chunk
-		s.docs[chunk.DocumentID] = append(s.docs[chunk.DocumentID], chunk.ID)
+		moved := exists && old.DocumentID != chunk.DocumentID
+		if moved {
+			s.unlinkChunk(old.DocumentID, chunk.ID)
+		}
+		if !exists || moved {
+			s.docs[chunk.DocumentID] = append(s.docs[chunk.DocumentID], chunk.ID)
+		}
 	}
 	return nil
 }
 
-// Search finds the most similar chunks to a query embedding.
-func (s *InMemoryStore) Search(ctx context.Context, embedding []float32, topK int) ([]entities.QueryResult, error) {
+// unlinkChunk removes a chunk from its document's index, dropping the
+// document once it has no chunks. Caller must hold s.mu.
+func (s *InMemoryStore) unlinkChunk(documentID, chunkID string) {
+	ids := s.docs[documentID]
+	for i, id := range ids {
+		if id == chunkID {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(s.docs, documentID)
+		delete(s.infos, documentID)
+		return
+	}
+	s.docs[documentID] = ids
+}
+
+// ChunkHashes returns the content hash of each stored chunk of a document.
+func (s *InMemoryStore) ChunkHashes(ctx context.Context, documentID string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hashes := make(map[string]string)
+	for _, id := range s.docs[documentID] {
+		if chunk, ok := s.chunks[id]; ok {
+			hashes[id] = chunk.Hash
+		}
+	}
+	return hashes, nil
+}
+
+// DocumentChunks returns a document's stored chunks in order, without
+// their embeddings.
+func (s *InMemoryStore) DocumentChunks(ctx context.Context, documentID string) ([]entities.Chunk, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	chunks := make([]entities.Chunk, 0, len(s.docs[documentID]))
+	for _, id := range s.docs[documentID] {
+		if chunk, ok := s.chunks[id]; ok {
+			chunk.Embedding = nil
+			chunks = append(chunks, chunk)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Index < chunks[j].Index })
+	return chunks, nil
+}
+
+// dimension returns the width of stored embeddings, or 0 when empty.
+// Caller must hold s.mu.
+func (s *InMemoryStore) dimension() int {
+	for _, chunk := range s.chunks {
+		return len(chunk.Embedding)
+	}
+	return 0
+}
+
+// Search finds the most similar chunks to a query embedding.
+func (s *InMemoryStore) Search(ctx context.Context, embedding []float32, topK int) ([]entities.QueryResult, error) {
+	return s.search(embedding, topK, nil)
+}
+
+// SearchDocuments is Search restricted to chunks of the given documents.
+func (s *InMemoryStore) SearchDocuments(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	allowed := make(map[string]bool, len(documentIDs))
+	for _, id := range documentIDs {
+		allowed[id] = true
+	}
+	return s.search(embedding, topK, allowed)
+}
+
+// search ranks stored chunks, only those of allowed documents when
+// allowed is non-nil.
+func (s *InMemoryStore) search(embedding []float32, topK int, allowed map[string]bool) ([]entities.QueryResult, error) {
+	view := s.searchView()
+	if err := checkQueryDimension(view.dimension, len(embedding)); err != nil {
+		return nil, err
+	}
+
 	type scored struct {
 		chunk entities.Chunk
 		score float64
 	}
 
 	var results []scored
-	for _, chunk := range s.chunks {
+	for _, chunk := range view.chunks {
+		if allowed != nil && !allowed[chunk.DocumentID] {
+			continue
+		}
 		score := cosineSimilarity(embedding, chunk.Embedding)
 		results = append(results, scored{chunk: chunk, score: score})
 	}
 
 	// Sort by score descending
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].score > results[j].score
+		return ranksBefore(results[i].score, results[j].score, results[i].chunk.ID, results[j].chunk.ID)
 	})
 
 	// Take top K
@@ -71,7 +254,7 @@ func (s *InMemoryStore) Search(ctx context.Context, embedding []float32, topK in
 		queryResults[i] = entities.QueryResult{
 			Chunk:     r.chunk,
 			Score:     r.score,
-			SourceDoc: r.chunk.DocumentID, // Could be enhanced with actual doc name
+			SourceDoc: view.sourceName(r.chunk.DocumentID),
 		}
 	}
 
@@ -80,18 +263,22 @@ func (s *InMemoryStore) Search(ctx context.Context, embedding []float32, topK in
 
 // Delete removes all chunks for a document.
 func (s *InMemoryStore) Delete(ctx context.Context, documentID string) error {
+	return s.DeleteMany(ctx, []string{documentID})
+}
+
+// DeleteMany removes all chunks of several documents at once.
+func (s *InMemoryStore) DeleteMany(ctx context.Context, documentIDs []string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.view.Store(nil)
 
-	chunkIDs, ok := s.docs[documentID]
-	if !ok {
-		return nil
-	}
-
-	for _, id := range chunkIDs {
-		delete(s.chunks, id)
+	for _, documentID := range documentIDs {
+		for _, id := range s.docs[documentID] {
+			delete(s.chunks, id)
+		}
+		delete(s.docs, documentID)
+		delete(s.infos, documentID)
 	}
-	delete(s.docs, documentID)
 	return nil
 }
 
@@ -99,9 +286,77 @@ func (s *InMemoryStore) Delete(ctx context.Context, documentID string) error {
 func (s *InMemoryStore) Clear(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.view.Store(nil)
 
 	s.chunks = make(map[string]entities.Chunk)
 	s.docs = make(map[string][]string)
+	s.infos = make(map[string]entities.DocumentInfo)
+	s.model = ports.EmbeddingModel{}
 	return nil
 }
 
+// EmbeddingModel returns the model recorded for the collection.
+func (s *InMemoryStore) EmbeddingModel(ctx context.Context) (ports.EmbeddingModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.model, nil
+}
+
+// RecordEmbeddingModel records the model the collection is embedded with.
+func (s *InMemoryStore) RecordEmbeddingModel(ctx context.Context, model ports.EmbeddingModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.model = model
+	return nil
+}
+
+// Stats reports the collection's contents. SizeBytes is the size of the
+// snapshot file as last saved, or 0 when the store is not persisted.
+func (s *InMemoryStore) Stats(ctx context.Context) (ports.StoreStats, error) {
+	s.mu.RLock()
+	stats := ports.StoreStats{
+		Chunks:    len(s.chunks),
+		Documents: len(s.docs),
+		Dimension: s.dimension(),
+	}
+	s.mu.RUnlock()
+
+	path := s.path
+	if s.root != nil {
+		path = s.root.path
+	}
+	if path != "" {
+		if fi, err := os.Stat(path); err == nil {
+			stats.SizeBytes = fi.Size()
+		}
+	}
+	return stats, nil
+}
+
+// RegisterDocument records or updates a document's metadata.
+func (s *InMemoryStore) RegisterDocument(ctx context.Context, info entities.DocumentInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.view.Store(nil)
+
+	s.infos[info.ID] = info
+	return nil
+}
+
+// ListDocuments returns the documents that have stored chunks and match
+// filter, one page at a time.
+func (s *InMemoryStore) ListDocuments(ctx context.Context, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs := make([]entities.DocumentInfo, 0, len(s.docs))
+	for id, chunkIDs := range s.docs {
+		info, ok := s.infos[id]
+		if !ok {
+			info = entities.DocumentInfo{ID: id, Name: id}
+		}
+		info.ChunkCount = len(chunkIDs)
+		docs = append(docs, info)
+	}
+	return pageDocuments(docs, filter, page), nil
+}
@@ -0,0 +1,183 @@
+package vectordb
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// memorySnapshotVersion is bumped when memorySnapshot changes shape.
+const memorySnapshotVersion = 1
+
+// memorySnapshot is the gob file layout for InMemoryStore persistence.
+type memorySnapshot struct {
+	Version     int
+	Collections map[string]memoryCollection // Name -> data, including the default
+}
+
+// memoryCollection holds one collection's chunks in document order.
+type memoryCollection struct {
+	Chunks []entities.Chunk
+	Infos  map[string]entities.DocumentInfo
+	Model  ports.EmbeddingModel // Zero in snapshots that predate it
+}
+
+// OpenInMemoryStore creates an in-memory store backed by a single file:
+// the file is loaded now if it exists and rewritten on Close. It keeps
+// quick setups' index across restarts without SQLite or CGO.
+func OpenInMemoryStore(path string) (*InMemoryStore, error) {
+	s := NewInMemoryStore()
+	s.path = path
+	if err := s.load(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close saves the store to its file when opened with OpenInMemoryStore.
+func (s *InMemoryStore) Close() error {
+	if s.path == "" {
+		return nil
+	}
+	return s.save(s.path)
+}
+
+// Backup writes all collections to path as a gob snapshot.
+func (s *InMemoryStore) Backup(ctx context.Context, path string) error {
+	return s.save(path)
+}
+
+// Restore replaces the store contents with a snapshot written by Backup.
+// A snapshot that fails to decode leaves the current data untouched.
+func (s *InMemoryStore) Restore(ctx context.Context, path string) error {
+	return s.load(path)
+}
+
+// OpenSnapshot loads a snapshot written by Backup into a store of its
+// own, which is not saved on Close.
+func (s *InMemoryStore) OpenSnapshot(ctx context.Context, path string) (ports.SnapshotStore, error) {
+	snap := NewInMemoryStore()
+	if err := snap.load(path); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// save writes a snapshot to a temporary file and renames it over path,
+// so a crash mid-write never leaves a truncated file behind.
+func (s *InMemoryStore) save(path string) error {
+	snap := memorySnapshot{Version: memorySnapshotVersion, Collections: make(map[string]memoryCollection)}
+	snap.Collections[entities.DefaultCollection] = s.export()
+
+	s.colMu.Lock()
+	for name, c := range s.collections {
+		snap.Collections[name] = c.export()
+	}
+	s.colMu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(&snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing snapshot file: %w", err)
+	}
+	return nil
+}
+
+// load decodes a snapshot and swaps it in for every collection.
+func (s *InMemoryStore) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer f.Close()
+
+	var snap memorySnapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("%s is not a LocalRAG snapshot: %w", path, err)
+	}
+	if snap.Version != memorySnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	s.colMu.Lock()
+	defer s.colMu.Unlock()
+
+	s.restore(snap.Collections[entities.DefaultCollection])
+	for name, c := range s.collections {
+		if _, ok := snap.Collections[name]; !ok {
+			c.restore(memoryCollection{})
+		}
+	}
+	for name, data := range snap.Collections {
+		if name == entities.DefaultCollection {
+			continue
+		}
+		c, ok := s.collections[name]
+		if !ok {
+			c = NewInMemoryStore()
+			c.root = s
+			s.collections[name] = c
+		}
+		c.restore(data)
+	}
+	return nil
+}
+
+// export copies the collection's contents for saving.
+func (s *InMemoryStore) export() memoryCollection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data := memoryCollection{
+		Chunks: make([]entities.Chunk, 0, len(s.chunks)),
+		Infos:  make(map[string]entities.DocumentInfo, len(s.infos)),
+		Model:  s.model,
+	}
+	for _, chunkIDs := range s.docs {
+		for _, id := range chunkIDs {
+			if chunk, ok := s.chunks[id]; ok {
+				data.Chunks = append(data.Chunks, chunk)
+			}
+		}
+	}
+	for id, info := range s.infos {
+		data.Infos[id] = info
+	}
+	return data
+}
+
+// restore replaces the collection's contents with data.
+func (s *InMemoryStore) restore(data memoryCollection) {
+	chunks := make(map[string]entities.Chunk, len(data.Chunks))
+	docs := make(map[string][]string)
+	for _, chunk := range data.Chunks {
+		chunks[chunk.ID] = chunk
+		docs[chunk.DocumentID] = append(docs[chunk.DocumentID], chunk.ID)
+	}
+	infos := data.Infos
+	if infos == nil {
+		infos = make(map[string]entities.DocumentInfo)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunks, s.docs, s.infos, s.model = chunks, docs, infos, data.Model
+	s.view.Store(nil)
+}
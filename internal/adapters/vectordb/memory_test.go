@@ -0,0 +1,250 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+func TestInMemoryStore_StoreAndSearch(t *testing.T) {
+	store := NewInMemoryStore()
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "hello", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc1", Content: "world", Embedding: []float32{0, 1, 0}},
+	})
+
+	results, err := store.Search(ctx, []float32{1, 0, 0}, 1)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Chunk.ID != "c1" {
+		t.Errorf("c1 should be top result, got %v", results)
+	}
+}
+
+func TestInMemoryStore_Collections(t *testing.T) {
+	store := NewInMemoryStore()
+	ctx := context.Background()
+
+	if store.Collection(entities.DefaultCollection) != store {
+		t.Error("default collection should be the store itself")
+	}
+
+	work := store.Collection("work")
+	work.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}}})
+
+	results, _ := store.Search(ctx, []float32{1, 0, 0}, 10)
+	if len(results) != 0 {
+		t.Error("default collection should not see work chunks")
+	}
+
+	names, _ := store.Collections(ctx)
+	if len(names) != 2 || names[1] != "work" {
+		t.Errorf("unexpected collections: %v", names)
+	}
+}
+
+func TestInMemoryStore_DimensionMismatch(t *testing.T) {
+	store := NewInMemoryStore()
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}}})
+
+	err := store.Store(ctx, []entities.Chunk{{ID: "c2", DocumentID: "doc2", Embedding: []float32{1, 0}}})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("expected ErrDimensionMismatch on store, got %v", err)
+	}
+	if _, err := store.Search(ctx, []float32{1, 0}, 1); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("expected ErrDimensionMismatch on search, got %v", err)
+	}
+
+	// A cleared store accepts a new embedding model
+	store.Clear(ctx)
+	if err := store.Store(ctx, []entities.Chunk{{ID: "c2", DocumentID: "doc2", Embedding: []float32{1, 0}}}); err != nil {
+		t.Errorf("empty store should accept any dimension: %v", err)
+	}
+}
+
+func TestInMemoryStore_SkipsUnchangedChunks(t *testing.T) {
+	testSkipsUnchangedChunks(t, NewInMemoryStore())
+}
+
+func TestInMemoryStore_SearchDocuments(t *testing.T) {
+	testSearchDocuments(t, NewInMemoryStore())
+}
+
+func TestInMemoryStore_DeleteMany(t *testing.T) {
+	testDeleteMany(t, NewInMemoryStore())
+}
+
+func TestInMemoryStore_Upsert(t *testing.T) {
+	testUpsert(t, NewInMemoryStore())
+
+	// Rewriting chunks must not list them twice for their document
+	store := NewInMemoryStore()
+	ctx := context.Background()
+	chunks := []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "one", Embedding: []float32{1, 0}}}
+	store.Store(ctx, chunks)
+	store.Upsert(ctx, chunks, ports.ConflictReplace)
+	page, _ := store.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+	if len(page.Documents) != 1 || page.Documents[0].ChunkCount != 1 {
+		t.Errorf("expected one document with one chunk, got %+v", page.Documents)
+	}
+}
+
+func TestInMemoryStore_ListDocuments(t *testing.T) {
+	testListDocuments(t, NewInMemoryStore())
+}
+
+func TestInMemoryStore_EmbeddingModel(t *testing.T) {
+	testEmbeddingModel(t, NewInMemoryStore())
+}
+
+func TestInMemoryStore_SwapCollections(t *testing.T) {
+	testSwapCollections(t, NewInMemoryStore())
+}
+
+func TestInMemoryStore_ChunkPages(t *testing.T) {
+	testChunkPages(t, NewInMemoryStore())
+}
+
+func TestInMemoryStore_Stats(t *testing.T) {
+	if stats := testStats(t, NewInMemoryStore()); stats.SizeBytes != 0 {
+		t.Errorf("unpersisted store should report no size, got %d", stats.SizeBytes)
+	}
+}
+
+func TestInMemoryStore_StatsOfPersistedCollection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.gob")
+	ctx := context.Background()
+
+	store, err := OpenInMemoryStore(path)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	store.Collection("work").Store(ctx, []entities.Chunk{{ID: "w1", DocumentID: "doc1", Embedding: []float32{0, 1}}})
+	if err := store.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("snapshot not saved: %v", err)
+	}
+
+	stats, err := store.Collection("work").Stats(ctx)
+	if err != nil || stats.Chunks != 1 || stats.SizeBytes != fi.Size() {
+		t.Errorf("expected 1 chunk and the snapshot's %d bytes, got %+v, %v", fi.Size(), stats, err)
+	}
+
+	// Collections restored from the snapshot report it too
+	reopened, err := OpenInMemoryStore(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if stats, _ := reopened.Collection("work").Stats(ctx); stats.SizeBytes != fi.Size() {
+		t.Errorf("restored collection should report the snapshot's size, got %d", stats.SizeBytes)
+	}
+}
+
+func TestInMemoryStore_OpenSnapshot(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "memory-test-*")
+	defer os.RemoveAll(dir)
+	testOpenSnapshot(t, NewInMemoryStore(), filepath.Join(dir, "index.snapshot"))
+}
+
+func TestInMemoryStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.gob")
+	ctx := context.Background()
+
+	store, err := OpenInMemoryStore(path)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Content: "hello", Embedding: []float32{1, 0, 0}}})
+	store.RegisterDocument(ctx, entities.DocumentInfo{ID: "doc1", Name: "notes.md"})
+	store.Collection("work").Store(ctx, []entities.Chunk{{ID: "w1", DocumentID: "doc2", Embedding: []float32{0, 1}}})
+	if err := store.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	reopened, err := OpenInMemoryStore(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	results, err := reopened.Search(ctx, []float32{1, 0, 0}, 1)
+	if err != nil || len(results) != 1 || results[0].SourceDoc != "notes.md" {
+		t.Errorf("default collection not restored: %v, %v", results, err)
+	}
+	if results, _ := reopened.Collection("work").Search(ctx, []float32{0, 1}, 1); len(results) != 1 {
+		t.Errorf("work collection not restored: %v", results)
+	}
+
+	// A file that isn't a snapshot leaves the store untouched
+	bogus := filepath.Join(t.TempDir(), "bogus")
+	os.WriteFile(bogus, []byte("not a snapshot"), 0600)
+	if err := reopened.Restore(ctx, bogus); err == nil {
+		t.Error("expected error restoring a bogus file")
+	}
+	if page, _ := reopened.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{}); page.Total != 1 {
+		t.Errorf("failed restore changed the store: %v", page.Documents)
+	}
+}
+
+func TestInMemoryStore_ConcurrentWritesDuringSearch(t *testing.T) {
+	store := NewInMemoryStore()
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{{ID: "seed", DocumentID: "seed", Content: "seed", Embedding: []float32{1, 0}}})
+
+	view := store.searchView()
+	store.Clear(ctx)
+	if len(view.chunks) != 1 {
+		t.Errorf("a view taken before Clear should keep its chunks, got %d", len(view.chunks))
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				doc := fmt.Sprintf("doc%d-%d", w, i%5)
+				store.Store(ctx, []entities.Chunk{{ID: doc + "-c", DocumentID: doc, Content: "x", Embedding: []float32{float32(i), 1}}})
+				store.RegisterDocument(ctx, entities.DocumentInfo{ID: doc, Name: doc + ".md"})
+				switch i % 20 {
+				case 7:
+					store.Delete(ctx, doc)
+				case 19:
+					store.Clear(ctx)
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				results, err := store.Search(ctx, []float32{1, 1}, 5)
+				if err != nil {
+					t.Errorf("search failed: %v", err)
+					return
+				}
+				for _, r := range results {
+					if r.Chunk.Embedding == nil {
+						t.Errorf("search returned a torn chunk: %+v", r)
+					}
+				}
+				store.SearchDocuments(ctx, []float32{1, 1}, 5, []string{"doc0-1"})
+				store.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+			}
+		}()
+	}
+	wg.Wait()
+}
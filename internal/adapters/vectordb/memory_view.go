@@ -0,0 +1,55 @@
+package vectordb
+
+import "github.com/0xcro3dile/localrag-go/internal/domain/entities"
+
+// memoryView is an immutable copy of an InMemoryStore collection that
+// searches scan without holding the store's lock, so a long search
+// neither blocks nor races with Store, Delete or Clear. Writers drop the
+// current view and the next search builds a new one; searches already
+// running keep ranking the view they started with.
+//
+// Chunks are shared with the store rather than copied. That is safe
+// because writers replace map entries and never modify a stored chunk
+// or its embedding in place.
+type memoryView struct {
+	chunks    []entities.Chunk
+	names     map[string]string // Document ID -> registered name
+	dimension int
+}
+
+// searchView returns the current view, building it if a write dropped it.
+func (s *InMemoryStore) searchView() *memoryView {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if view := s.view.Load(); view != nil {
+		return view
+	}
+
+	// Writers are excluded while the read lock is held, so the view
+	// installed here is current; concurrent searches may each build one.
+	view := &memoryView{
+		chunks:    make([]entities.Chunk, 0, len(s.chunks)),
+		names:     make(map[string]string, len(s.infos)),
+		dimension: s.dimension(),
+	}
+	for _, chunk := range s.chunks {
+		view.chunks = append(view.chunks, chunk)
+	}
+	for id, info := range s.infos {
+		if info.Name != "" {
+			view.names[id] = info.Name
+		}
+	}
+	s.view.Store(view)
+	return view
+}
+
+// sourceName returns the registered document name for citations,
+// falling back to the ID.
+func (v *memoryView) sourceName(documentID string) string {
+	if name, ok := v.names[documentID]; ok {
+		return name
+	}
+	return documentID
+}
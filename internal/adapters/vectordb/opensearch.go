@@ -0,0 +1,900 @@
+package vectordb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// SearchFlavor selects the kNN dialect of the search backend.
+type SearchFlavor string
+
+const (
+	FlavorOpenSearch    SearchFlavor = "opensearch"
+	FlavorElasticsearch SearchFlavor = "elasticsearch"
+)
+
+// OpenSearchStore implements ports.VectorStore on OpenSearch or Elasticsearch.
+// One index holds dense vectors and BM25-analyzed text; document_id,
+// collection and chunk_index are mapped as fields for filtering.
+// Document registry entries live in a companion "<index>-documents" index.
+type OpenSearchStore struct {
+	state      *openSearchState // Shared by all collection views
+	baseURL    string
+	index      string
+	flavor     SearchFlavor
+	username   string
+	password   string
+	collection string
+	client     *http.Client
+	health     *resilience.Tracker
+	backoff    resilience.Backoff
+}
+
+// openSearchState tracks lazy index creation and fusion weight.
+type openSearchState struct {
+	mu          sync.Mutex
+	hasIndex    bool
+	hasRegistry bool
+	hybridAlpha float64
+}
+
+// NewOpenSearchStore creates a store backed by an OpenSearch/Elasticsearch index.
+// The index is created lazily on the first Store, once the embedding
+// dimension is known.
+func NewOpenSearchStore(baseURL, index string, flavor SearchFlavor, username, password string) *OpenSearchStore {
+	if baseURL == "" {
+		baseURL = "http://localhost:9200"
+	}
+	if index == "" {
+		index = "localrag"
+	}
+	if flavor == "" {
+		flavor = FlavorOpenSearch
+	}
+	return &OpenSearchStore{
+		state:      &openSearchState{hybridAlpha: defaultHybridAlpha},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		index:      index,
+		flavor:     flavor,
+		username:   username,
+		password:   password,
+		collection: entities.DefaultCollection,
+		client:     resilience.NewHTTPClient(60 * time.Second),
+		health:     resilience.NewTracker(string(flavor)),
+		backoff:    resilience.DefaultBackoff,
+	}
+}
+
+// openSearchDoc is the indexed document for a chunk.
+type openSearchDoc struct {
+	ChunkID    string    `json:"chunk_id"`
+	Collection string    `json:"collection"`
+	DocumentID string    `json:"document_id"`
+	Content    string    `json:"content"`
+	ChunkIndex int       `json:"chunk_index"`
+	Embedding  []float32 `json:"embedding"`
+	Hash       string    `json:"content_hash,omitempty"`
+	Page       int       `json:"page,omitempty"`
+}
+
+// openSearchDocument is a document's registry entry, kept in a separate
+// index so it never shows up in chunk searches.
+type openSearchDocument struct {
+	Collection string               `json:"collection"`
+	DocumentID string               `json:"document_id"`
+	Name       string               `json:"name"`
+	Path       string               `json:"path"`
+	IngestedAt time.Time            `json:"ingested_at"`
+	Metadata   map[string]string    `json:"metadata,omitempty"`
+	Provenance []transformationJSON `json:"provenance,omitempty"`
+}
+
+// openSearchHits is the subset of a _search response we use.
+type openSearchHits struct {
+	Hits struct {
+		Hits []struct {
+			Score  float64       `json:"_score"`
+			Source openSearchDoc `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// Collection returns a store scoped to the named collection.
+func (s *OpenSearchStore) Collection(name string) ports.VectorStore {
+	if name == "" {
+		name = entities.DefaultCollection
+	}
+	view := *s
+	view.collection = name
+	return &view
+}
+
+// Collections lists collections that hold data.
+func (s *OpenSearchStore) Collections(ctx context.Context) ([]string, error) {
+	body := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			"collections": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "collection", "size": 1000},
+			},
+		},
+	}
+	var resp struct {
+		Aggregations struct {
+			Collections struct {
+				Buckets []struct {
+					Key string `json:"key"`
+				} `json:"buckets"`
+			} `json:"collections"`
+		} `json:"aggregations"`
+	}
+	status, err := s.do(ctx, http.MethodPost, "/"+s.index+"/_search", body, &resp)
+	if err != nil && status != http.StatusNotFound {
+		return nil, err
+	}
+
+	names := []string{entities.DefaultCollection}
+	for _, b := range resp.Aggregations.Collections.Buckets {
+		if b.Key != entities.DefaultCollection {
+			names = append(names, b.Key)
+		}
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+// SetHybridWeight sets the vector weight (0..1) used by HybridSearch.
+func (s *OpenSearchStore) SetHybridWeight(alpha float64) {
+	if alpha < 0 {
+		alpha = 0
+	}
+	if alpha > 1 {
+		alpha = 1
+	}
+	s.state.mu.Lock()
+	s.state.hybridAlpha = alpha
+	s.state.mu.Unlock()
+}
+
+// Store indexes chunks with a single _bulk request.
+func (s *OpenSearchStore) Store(ctx context.Context, chunks []entities.Chunk) error {
+	return s.Upsert(ctx, chunks, ports.ConflictVersion)
+}
+
+// Upsert is Store with an explicit policy for chunks already stored.
+// ConflictSkip uses create actions, which the cluster refuses for
+// existing IDs; ConflictVersion first fetches the stored hashes.
+func (s *OpenSearchStore) Upsert(ctx context.Context, chunks []entities.Chunk, policy ports.ConflictPolicy) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	if err := s.ensureIndex(ctx, len(chunks[0].Embedding)); err != nil {
+		return err
+	}
+
+	op := "index"
+	switch policy {
+	case ports.ConflictSkip:
+		op = "create"
+	case ports.ConflictVersion:
+		stored, err := s.storedHashes(ctx, chunks)
+		if err != nil {
+			return err
+		}
+		var changed []entities.Chunk
+		for _, chunk := range chunks {
+			if hash, ok := stored[chunk.ID]; !ok || !keepStored(policy, hash, chunk) {
+				changed = append(changed, chunk)
+			}
+		}
+		if chunks = changed; len(chunks) == 0 {
+			return nil
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, chunk := range chunks {
+		action := map[string]interface{}{
+			op: map[string]string{"_index": s.index, "_id": s.docID(chunk.ID)},
+		}
+		if err := enc.Encode(action); err != nil {
+			return fmt.Errorf("encoding bulk action: %w", err)
+		}
+		err := enc.Encode(openSearchDoc{
+			ChunkID:    chunk.ID,
+			Collection: s.collection,
+			DocumentID: chunk.DocumentID,
+			Content:    chunk.Content,
+			ChunkIndex: chunk.Index,
+			Embedding:  chunk.Embedding,
+			Hash:       chunk.Hash,
+			Page:       chunk.Page,
+		})
+		if err != nil {
+			return fmt.Errorf("encoding chunk: %w", err)
+		}
+	}
+
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if _, err := s.doRaw(ctx, http.MethodPost, "/_bulk?refresh=wait_for", "application/x-ndjson", buf.Bytes(), &resp); err != nil {
+		return fmt.Errorf("bulk indexing: %w", err)
+	}
+	if resp.Errors {
+		skipped := false
+		for _, item := range resp.Items {
+			for _, result := range item {
+				if op == "create" && result.Status == http.StatusConflict {
+					skipped = true // Already stored; kept by policy
+					continue
+				}
+				if len(result.Error) > 0 {
+					return fmt.Errorf("bulk indexing: %s", result.Error)
+				}
+			}
+		}
+		if !skipped {
+			return fmt.Errorf("bulk indexing failed")
+		}
+	}
+	return nil
+}
+
+// storedHashes returns the content hash of each of chunks already in the
+// index, keyed by chunk ID.
+func (s *OpenSearchStore) storedHashes(ctx context.Context, chunks []entities.Chunk) (map[string]string, error) {
+	ids := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = s.docID(chunk.ID)
+	}
+	body := map[string]interface{}{"ids": ids}
+
+	var resp struct {
+		Docs []struct {
+			Found  bool `json:"found"`
+			Source struct {
+				ChunkID string `json:"chunk_id"`
+				Hash    string `json:"content_hash"`
+			} `json:"_source"`
+		} `json:"docs"`
+	}
+	if _, err := s.do(ctx, http.MethodPost, "/"+s.index+"/_mget?_source=chunk_id,content_hash", body, &resp); err != nil {
+		return nil, fmt.Errorf("reading chunk hashes: %w", err)
+	}
+	hashes := make(map[string]string)
+	for _, doc := range resp.Docs {
+		if doc.Found {
+			hashes[doc.Source.ChunkID] = doc.Source.Hash
+		}
+	}
+	return hashes, nil
+}
+
+// Search runs an approximate kNN query restricted to this collection.
+func (s *OpenSearchStore) Search(ctx context.Context, embedding []float32, topK int) ([]entities.QueryResult, error) {
+	return s.search(ctx, embedding, topK, nil)
+}
+
+// SearchDocuments is Search restricted to chunks of the given documents,
+// applied as a kNN filter so k counts only matching chunks.
+func (s *OpenSearchStore) SearchDocuments(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	if len(documentIDs) == 0 {
+		return nil, nil
+	}
+	return s.search(ctx, embedding, topK, documentIDs)
+}
+
+// search runs the kNN query, limited to documentIDs when non-nil.
+func (s *OpenSearchStore) search(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	var resp openSearchHits
+	status, err := s.do(ctx, http.MethodPost, "/"+s.index+"/_search", s.knnQuery(embedding, topK, documentIDs), &resp)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return nil, nil // Nothing stored yet
+		}
+		return nil, fmt.Errorf("searching index: %w", err)
+	}
+
+	results := make([]entities.QueryResult, 0, len(resp.Hits.Hits))
+	for _, h := range resp.Hits.Hits {
+		r := h.Source.toResult()
+		// Both engines report cosine as (1 + cos) / 2
+		r.Score = 2*h.Score - 1
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// HybridSearch fuses BM25 keyword scores with kNN similarity, using the
+// same 0..1 scaling and weighting as LanceDBStore.HybridSearch.
+func (s *OpenSearchStore) HybridSearch(ctx context.Context, query string, embedding []float32, topK int) ([]entities.QueryResult, error) {
+	s.state.mu.Lock()
+	alpha := s.state.hybridAlpha
+	s.state.mu.Unlock()
+
+	if strings.TrimSpace(query) == "" {
+		return s.Search(ctx, embedding, topK)
+	}
+
+	pool := topK * 4
+	vectorHits, err := s.Search(ctx, embedding, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"size": pool,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":   map[string]interface{}{"match": map[string]interface{}{"content": query}},
+				"filter": s.collectionFilter(),
+			},
+		},
+	}
+	var resp openSearchHits
+	status, err := s.do(ctx, http.MethodPost, "/"+s.index+"/_search", body, &resp)
+	if err != nil && status != http.StatusNotFound {
+		return nil, fmt.Errorf("keyword search: %w", err)
+	}
+
+	type fused struct {
+		result  entities.QueryResult
+		vector  float64
+		keyword float64
+	}
+	byID := make(map[string]*fused)
+	for _, r := range vectorHits {
+		byID[r.Chunk.ID] = &fused{result: r, vector: r.Score}
+	}
+
+	var maxKeyword float64
+	for _, h := range resp.Hits.Hits {
+		if h.Score > maxKeyword {
+			maxKeyword = h.Score
+		}
+		f, ok := byID[h.Source.ChunkID]
+		if !ok {
+			r := h.Source.toResult()
+			f = &fused{result: r, vector: cosineSimilarity(embedding, h.Source.Embedding)}
+			byID[h.Source.ChunkID] = f
+		}
+		f.keyword = h.Score
+	}
+
+	results := make([]entities.QueryResult, 0, len(byID))
+	for _, f := range byID {
+		vector := f.vector
+		if vector < 0 {
+			vector = 0
+		}
+		keyword := 0.0
+		if maxKeyword > 0 {
+			keyword = f.keyword / maxKeyword
+		}
+		r := f.result
+		r.Score = alpha*vector + (1-alpha)*keyword
+		results = append(results, r)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// Delete removes all chunks for a document.
+func (s *OpenSearchStore) Delete(ctx context.Context, documentID string) error {
+	filter := append(s.collectionFilter(), map[string]interface{}{
+		"term": map[string]interface{}{"document_id": documentID},
+	})
+	return s.deleteByQuery(ctx, filter)
+}
+
+// DeleteMany removes all chunks of several documents in one
+// delete-by-query request.
+func (s *OpenSearchStore) DeleteMany(ctx context.Context, documentIDs []string) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	filter := append(s.collectionFilter(), map[string]interface{}{
+		"terms": map[string]interface{}{"document_id": documentIDs},
+	})
+	return s.deleteByQuery(ctx, filter)
+}
+
+// Clear removes all chunks in this collection and its recorded
+// embedding model.
+func (s *OpenSearchStore) Clear(ctx context.Context) error {
+	if err := s.deleteByQuery(ctx, s.collectionFilter()); err != nil {
+		return err
+	}
+	status, err := s.do(ctx, http.MethodDelete, s.modelPath()+"?refresh=true", nil, nil)
+	if err != nil && status != http.StatusNotFound {
+		return fmt.Errorf("clearing embedding model: %w", err)
+	}
+	return nil
+}
+
+// openSearchModel is a collection's embedding model as recorded in the
+// models index.
+type openSearchModel struct {
+	Name      string `json:"name"`
+	Dimension int    `json:"dimension"`
+}
+
+// EmbeddingModel returns the model recorded for the collection.
+func (s *OpenSearchStore) EmbeddingModel(ctx context.Context) (ports.EmbeddingModel, error) {
+	var resp struct {
+		Source openSearchModel `json:"_source"`
+	}
+	status, err := s.do(ctx, http.MethodGet, s.modelPath(), nil, &resp)
+	if status == http.StatusNotFound {
+		return ports.EmbeddingModel{}, nil
+	}
+	if err != nil {
+		return ports.EmbeddingModel{}, fmt.Errorf("reading embedding model: %w", err)
+	}
+	return ports.EmbeddingModel{Name: resp.Source.Name, Dimension: resp.Source.Dimension}, nil
+}
+
+// RecordEmbeddingModel records the model the collection is embedded with.
+func (s *OpenSearchStore) RecordEmbeddingModel(ctx context.Context, model ports.EmbeddingModel) error {
+	doc := openSearchModel{Name: model.Name, Dimension: model.Dimension}
+	if _, err := s.do(ctx, http.MethodPut, s.modelPath()+"?refresh=true", doc, nil); err != nil {
+		return fmt.Errorf("recording embedding model: %w", err)
+	}
+	return nil
+}
+
+// modelPath addresses the collection's entry in the models index, which
+// is created with the first entry.
+func (s *OpenSearchStore) modelPath() string {
+	return "/" + s.index + "-models/_doc/" + url.PathEscape(s.collection)
+}
+
+// Stats reports the collection's chunk and document counts, and the
+// primary store size of the whole index. The document count comes from a
+// cardinality aggregation, which is approximate above a few thousand.
+func (s *OpenSearchStore) Stats(ctx context.Context) (ports.StoreStats, error) {
+	body := map[string]interface{}{
+		"size":             1,
+		"track_total_hits": true,
+		"_source":          []string{"embedding"},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": s.collectionFilter()},
+		},
+		"aggs": map[string]interface{}{
+			"documents": map[string]interface{}{
+				"cardinality": map[string]interface{}{"field": "document_id"},
+			},
+		},
+	}
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source openSearchDoc `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations struct {
+			Documents struct {
+				Value int `json:"value"`
+			} `json:"documents"`
+		} `json:"aggregations"`
+	}
+	var stats ports.StoreStats
+	status, err := s.do(ctx, http.MethodPost, "/"+s.index+"/_search", body, &resp)
+	if status == http.StatusNotFound {
+		return stats, nil // Nothing stored yet
+	}
+	if err != nil {
+		return stats, fmt.Errorf("reading stats: %w", err)
+	}
+	stats.Chunks = resp.Hits.Total.Value
+	stats.Documents = resp.Aggregations.Documents.Value
+	if len(resp.Hits.Hits) > 0 {
+		stats.Dimension = len(resp.Hits.Hits[0].Source.Embedding)
+	}
+
+	var size struct {
+		All struct {
+			Primaries struct {
+				Store struct {
+					SizeInBytes int64 `json:"size_in_bytes"`
+				} `json:"store"`
+			} `json:"primaries"`
+		} `json:"_all"`
+	}
+	if _, err := s.do(ctx, http.MethodGet, "/"+s.index+"/_stats/store", nil, &size); err != nil {
+		return stats, fmt.Errorf("reading index size: %w", err)
+	}
+	stats.SizeBytes = size.All.Primaries.Store.SizeInBytes
+	return stats, nil
+}
+
+// RegisterDocument records a document's name, path and ingest time in
+// the registry index.
+func (s *OpenSearchStore) RegisterDocument(ctx context.Context, info entities.DocumentInfo) error {
+	if err := s.ensureRegistry(ctx); err != nil {
+		return err
+	}
+	doc := openSearchDocument{
+		Collection: s.collection,
+		DocumentID: info.ID,
+		Name:       info.Name,
+		Path:       info.Path,
+		IngestedAt: info.IngestedAt,
+		Metadata:   info.Metadata,
+		Provenance: provenanceToJSON(info.Provenance),
+	}
+	path := "/" + s.registryIndex() + "/_doc/" + url.PathEscape(s.docID(info.ID)) + "?refresh=wait_for"
+	if _, err := s.do(ctx, http.MethodPut, path, doc, nil); err != nil {
+		return fmt.Errorf("registering document: %w", err)
+	}
+	return nil
+}
+
+// ListDocuments returns the documents that have stored chunks and match
+// filter, one page at a time. Documents and their chunk counts come from
+// a composite aggregation over the chunks, read in full unless the
+// filter names documents, so the filter and page are applied after
+// joining the registry entries.
+func (s *OpenSearchStore) ListDocuments(ctx context.Context, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error) {
+	counts, err := s.chunkCounts(ctx, filter.IDs)
+	if err != nil {
+		return entities.DocumentPage{}, err
+	}
+	if len(counts) == 0 {
+		return entities.DocumentPage{}, nil
+	}
+
+	ids := make([]string, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, s.docID(id))
+	}
+	var resp struct {
+		Docs []struct {
+			Found  bool               `json:"found"`
+			Source openSearchDocument `json:"_source"`
+		} `json:"docs"`
+	}
+	status, err := s.do(ctx, http.MethodPost, "/"+s.registryIndex()+"/_mget", map[string]interface{}{"ids": ids}, &resp)
+	if err != nil && status != http.StatusNotFound {
+		return entities.DocumentPage{}, fmt.Errorf("reading documents: %w", err)
+	}
+	registered := make(map[string]openSearchDocument, len(resp.Docs))
+	for _, doc := range resp.Docs {
+		if doc.Found {
+			registered[doc.Source.DocumentID] = doc.Source
+		}
+	}
+
+	docs := make([]entities.DocumentInfo, 0, len(counts))
+	for id, count := range counts {
+		info := entities.DocumentInfo{ID: id, Name: id, ChunkCount: count}
+		if rec, ok := registered[id]; ok {
+			info.Name = rec.Name
+			info.Path = rec.Path
+			info.IngestedAt = rec.IngestedAt
+			info.Metadata = rec.Metadata
+			info.Provenance = provenanceFromJSON(rec.Provenance)
+		}
+		docs = append(docs, info)
+	}
+	return pageDocuments(docs, filter, page), nil
+}
+
+// chunkCounts returns the number of chunks of each document in the
+// collection, or of those in documentIDs when it is non-empty, paging
+// through a composite aggregation.
+func (s *OpenSearchStore) chunkCounts(ctx context.Context, documentIDs []string) (map[string]int, error) {
+	filters := s.collectionFilter()
+	if len(documentIDs) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"document_id": documentIDs}})
+	}
+	counts := make(map[string]int)
+	var after map[string]interface{}
+	for {
+		composite := map[string]interface{}{
+			"size": 1000,
+			"sources": []interface{}{
+				map[string]interface{}{"document_id": map[string]interface{}{"terms": map[string]string{"field": "document_id"}}},
+			},
+		}
+		if after != nil {
+			composite["after"] = after
+		}
+		body := map[string]interface{}{
+			"size": 0,
+			"query": map[string]interface{}{
+				"bool": map[string]interface{}{"filter": filters},
+			},
+			"aggs": map[string]interface{}{
+				"per_document": map[string]interface{}{"composite": composite},
+			},
+		}
+		var resp struct {
+			Aggregations struct {
+				PerDocument struct {
+					AfterKey map[string]interface{} `json:"after_key"`
+					Buckets  []struct {
+						Key struct {
+							DocumentID string `json:"document_id"`
+						} `json:"key"`
+						DocCount int `json:"doc_count"`
+					} `json:"buckets"`
+				} `json:"per_document"`
+			} `json:"aggregations"`
+		}
+		status, err := s.do(ctx, http.MethodPost, "/"+s.index+"/_search", body, &resp)
+		if status == http.StatusNotFound {
+			return counts, nil // Nothing stored yet
+		}
+		if err != nil {
+			return nil, fmt.Errorf("counting chunks: %w", err)
+		}
+		agg := resp.Aggregations.PerDocument
+		for _, b := range agg.Buckets {
+			counts[b.Key.DocumentID] = b.DocCount
+		}
+		if len(agg.Buckets) == 0 || agg.AfterKey == nil {
+			return counts, nil
+		}
+		after = agg.AfterKey
+	}
+}
+
+// deleteByQuery removes the chunks and registry entries matching filter.
+func (s *OpenSearchStore) deleteByQuery(ctx context.Context, filter []interface{}) error {
+	body := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filter},
+		},
+	}
+	status, err := s.do(ctx, http.MethodPost, "/"+s.index+"/_delete_by_query?refresh=true", body, nil)
+	if err != nil && status != http.StatusNotFound {
+		return fmt.Errorf("deleting chunks: %w", err)
+	}
+	status, err = s.do(ctx, http.MethodPost, "/"+s.registryIndex()+"/_delete_by_query?refresh=true", body, nil)
+	if err != nil && status != http.StatusNotFound {
+		return fmt.Errorf("deleting documents: %w", err)
+	}
+	return nil
+}
+
+// knnQuery builds the flavor-specific kNN request body.
+func (s *OpenSearchStore) knnQuery(embedding []float32, topK int, documentIDs []string) map[string]interface{} {
+	clauses := s.collectionFilter()
+	if documentIDs != nil {
+		clauses = append(clauses, map[string]interface{}{
+			"terms": map[string]interface{}{"document_id": documentIDs},
+		})
+	}
+	filter := map[string]interface{}{
+		"bool": map[string]interface{}{"filter": clauses},
+	}
+	if s.flavor == FlavorElasticsearch {
+		return map[string]interface{}{
+			"size": topK,
+			"knn": map[string]interface{}{
+				"field":          "embedding",
+				"query_vector":   embedding,
+				"k":              topK,
+				"num_candidates": topK * 10,
+				"filter":         filter,
+			},
+		}
+	}
+	return map[string]interface{}{
+		"size": topK,
+		"query": map[string]interface{}{
+			"knn": map[string]interface{}{
+				"embedding": map[string]interface{}{
+					"vector": embedding,
+					"k":      topK,
+					"filter": filter,
+				},
+			},
+		},
+	}
+}
+
+func (s *OpenSearchStore) collectionFilter() []interface{} {
+	return []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{"collection": s.collection}},
+	}
+}
+
+// docID namespaces chunk IDs so collections don't overwrite each other.
+func (s *OpenSearchStore) docID(chunkID string) string {
+	return s.collection + ":" + chunkID
+}
+
+// ensureIndex creates the index with vector and keyword mappings.
+func (s *OpenSearchStore) ensureIndex(ctx context.Context, dim int) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if s.state.hasIndex {
+		return nil
+	}
+
+	status, err := s.do(ctx, http.MethodHead, "/"+s.index, nil, nil)
+	if err == nil {
+		s.state.hasIndex = true
+		return nil
+	}
+	if status != http.StatusNotFound {
+		return fmt.Errorf("checking index: %w", err)
+	}
+
+	properties := map[string]interface{}{
+		"chunk_id":     map[string]string{"type": "keyword"},
+		"collection":   map[string]string{"type": "keyword"},
+		"document_id":  map[string]string{"type": "keyword"},
+		"chunk_index":  map[string]string{"type": "integer"},
+		"content":      map[string]string{"type": "text"},
+		"content_hash": map[string]string{"type": "keyword"},
+		"page":         map[string]string{"type": "integer"},
+	}
+	body := map[string]interface{}{}
+	if s.flavor == FlavorElasticsearch {
+		properties["embedding"] = map[string]interface{}{
+			"type": "dense_vector", "dims": dim, "index": true, "similarity": "cosine",
+		}
+	} else {
+		properties["embedding"] = map[string]interface{}{
+			"type":      "knn_vector",
+			"dimension": dim,
+			"method": map[string]interface{}{
+				"name": "hnsw", "space_type": "cosinesimil", "engine": "lucene",
+			},
+		}
+		body["settings"] = map[string]interface{}{"index": map[string]interface{}{"knn": true}}
+	}
+	body["mappings"] = map[string]interface{}{"properties": properties}
+
+	if _, err := s.do(ctx, http.MethodPut, "/"+s.index, body, nil); err != nil {
+		return fmt.Errorf("creating index: %w", err)
+	}
+	s.state.hasIndex = true
+	return nil
+}
+
+// registryIndex names the index holding document registry entries.
+func (s *OpenSearchStore) registryIndex() string {
+	return s.index + "-documents"
+}
+
+// ensureRegistry creates the registry index with keyword mappings, so
+// entries can be filtered by collection and document like chunks.
+func (s *OpenSearchStore) ensureRegistry(ctx context.Context) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if s.state.hasRegistry {
+		return nil
+	}
+
+	status, err := s.do(ctx, http.MethodHead, "/"+s.registryIndex(), nil, nil)
+	if err == nil {
+		s.state.hasRegistry = true
+		return nil
+	}
+	if status != http.StatusNotFound {
+		return fmt.Errorf("checking registry index: %w", err)
+	}
+
+	body := map[string]interface{}{
+		"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"collection":  map[string]string{"type": "keyword"},
+				"document_id": map[string]string{"type": "keyword"},
+				"name":        map[string]string{"type": "keyword"},
+				"path":        map[string]string{"type": "keyword"},
+				"ingested_at": map[string]string{"type": "date"},
+				// Stored only; metadata filters are applied to listings
+				"metadata":   map[string]interface{}{"type": "object", "enabled": false},
+				"provenance": map[string]interface{}{"type": "object", "enabled": false},
+			},
+		},
+	}
+	if _, err := s.do(ctx, http.MethodPut, "/"+s.registryIndex(), body, nil); err != nil {
+		return fmt.Errorf("creating registry index: %w", err)
+	}
+	s.state.hasRegistry = true
+	return nil
+}
+
+// do sends a JSON request and decodes the JSON response into out.
+// The HTTP status is returned alongside errors so callers can treat
+// a missing index (404) as empty.
+func (s *OpenSearchStore) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	var jsonData []byte
+	if body != nil {
+		var err error
+		jsonData, err = json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("marshaling request: %w", err)
+		}
+	}
+	return s.doRaw(ctx, method, path, "application/json", jsonData, out)
+}
+
+// doRaw sends body as-is, retrying while the cluster is unreachable or
+// restarting. A nil body sends no payload.
+func (s *OpenSearchStore) doRaw(ctx context.Context, method, path, contentType string, body []byte, out interface{}) (int, error) {
+	resp, err := resilience.DoHTTP(ctx, s.client, s.health, s.backoff, func() (*http.Request, error) {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if s.username != "" {
+			req.SetBasicAuth(s.username, s.password)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("calling %s: %w", s.flavor, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("%s returned status %d: %s", s.flavor, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("decoding response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// Health reports the cluster connection state.
+func (s *OpenSearchStore) Health() ports.BackendHealth {
+	return s.health.Health()
+}
+
+func (d openSearchDoc) toResult() entities.QueryResult {
+	return entities.QueryResult{
+		Chunk: entities.Chunk{
+			ID:         d.ChunkID,
+			DocumentID: d.DocumentID,
+			Content:    d.Content,
+			Index:      d.ChunkIndex,
+			Embedding:  d.Embedding,
+			Page:       d.Page,
+		},
+		SourceDoc: d.DocumentID,
+	}
+}
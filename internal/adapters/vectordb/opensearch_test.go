@@ -0,0 +1,445 @@
+package vectordb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// fakeOpenSearch keeps indexed docs in memory and answers kNN queries by
+// brute-force cosine and match queries by term counting.
+type fakeOpenSearch struct {
+	mu       sync.Mutex
+	docs     map[string]openSearchDoc
+	created  bool
+	mappings map[string]interface{}
+
+	// Registry index, addressed by any path under /<index>-documents
+	registry        map[string]openSearchDocument
+	registryCreated bool
+
+	// Models index, addressed by /<index>-models/_doc/<collection>
+	models map[string]json.RawMessage
+}
+
+func newFakeOpenSearch(t *testing.T) *httptest.Server {
+	f := &fakeOpenSearch{docs: map[string]openSearchDoc{}, registry: map[string]openSearchDocument{}, models: map[string]json.RawMessage{}}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (f *fakeOpenSearch) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if index, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/"); strings.HasSuffix(index, "-documents") {
+		f.serveRegistry(w, r)
+		return
+	}
+	if index, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/_doc/"); strings.HasSuffix(index, "-models") {
+		switch r.Method {
+		case http.MethodPut:
+			var doc json.RawMessage
+			json.NewDecoder(r.Body).Decode(&doc)
+			f.models[id] = doc
+		case http.MethodGet:
+			if doc, ok := f.models[id]; ok {
+				json.NewEncoder(w).Encode(map[string]interface{}{"found": true, "_source": doc})
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case http.MethodDelete:
+			if _, ok := f.models[id]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			delete(f.models, id)
+		}
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodHead:
+		if !f.created {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPut:
+		json.NewDecoder(r.Body).Decode(&f.mappings)
+		f.created = true
+	case r.URL.Path == "/_bulk":
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 1<<20), 1<<20)
+		failed := false
+		var items []map[string]map[string]interface{}
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			json.Unmarshal(scanner.Bytes(), &action)
+			scanner.Scan()
+			var doc openSearchDoc
+			json.Unmarshal(scanner.Bytes(), &doc)
+			if create, ok := action["create"]; ok {
+				if _, exists := f.docs[create["_id"]]; exists {
+					failed = true
+					items = append(items, map[string]map[string]interface{}{"create": {
+						"status": 409, "error": map[string]string{"type": "version_conflict_engine_exception"},
+					}})
+					continue
+				}
+				f.docs[create["_id"]] = doc
+				items = append(items, map[string]map[string]interface{}{"create": {"status": 201}})
+				continue
+			}
+			f.docs[action["index"]["_id"]] = doc
+			items = append(items, map[string]map[string]interface{}{"index": {"status": 200}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": failed, "items": items})
+	case !f.created:
+		w.WriteHeader(http.StatusNotFound)
+	case strings.HasSuffix(r.URL.Path, "/_delete_by_query"):
+		var body struct {
+			Query struct {
+				Bool struct {
+					Filter []map[string]map[string]interface{} `json:"filter"`
+				} `json:"bool"`
+			} `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for id, doc := range f.docs {
+			if f.matches(doc, body.Query.Bool.Filter) {
+				delete(f.docs, id)
+			}
+		}
+		w.Write([]byte(`{}`))
+	case strings.HasSuffix(r.URL.Path, "/_mget"):
+		var body struct {
+			IDs []string `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		var docs []map[string]interface{}
+		for _, id := range body.IDs {
+			doc, ok := f.docs[id]
+			docs = append(docs, map[string]interface{}{"_id": id, "found": ok, "_source": doc})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"docs": docs})
+	case strings.HasSuffix(r.URL.Path, "/_stats/store"):
+		w.Write([]byte(`{"_all":{"primaries":{"store":{"size_in_bytes":4096}}}}`))
+	case strings.HasSuffix(r.URL.Path, "/_search"):
+		f.search(w, r)
+	}
+}
+
+func (f *fakeOpenSearch) serveRegistry(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodHead:
+		if !f.registryCreated {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/_doc/"):
+		var doc openSearchDocument
+		json.NewDecoder(r.Body).Decode(&doc)
+		f.registry[r.URL.Path[strings.Index(r.URL.Path, "/_doc/")+len("/_doc/"):]] = doc
+	case r.Method == http.MethodPut:
+		f.registryCreated = true
+	case !f.registryCreated:
+		w.WriteHeader(http.StatusNotFound)
+	case strings.HasSuffix(r.URL.Path, "/_delete_by_query"):
+		var body struct {
+			Query struct {
+				Bool struct {
+					Filter []map[string]map[string]interface{} `json:"filter"`
+				} `json:"bool"`
+			} `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for id, doc := range f.registry {
+			if f.matches(openSearchDoc{Collection: doc.Collection, DocumentID: doc.DocumentID}, body.Query.Bool.Filter) {
+				delete(f.registry, id)
+			}
+		}
+		w.Write([]byte(`{}`))
+	case strings.HasSuffix(r.URL.Path, "/_mget"):
+		var body struct {
+			IDs []string `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		var docs []map[string]interface{}
+		for _, id := range body.IDs {
+			doc, ok := f.registry[id]
+			docs = append(docs, map[string]interface{}{"_id": id, "found": ok, "_source": doc})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"docs": docs})
+	}
+}
+
+func (f *fakeOpenSearch) matches(doc openSearchDoc, filter []map[string]map[string]interface{}) bool {
+	for _, clause := range filter {
+		for field, value := range clause["term"] {
+			if (field == "collection" && doc.Collection != value) || (field == "document_id" && doc.DocumentID != value) {
+				return false
+			}
+		}
+		if values, ok := clause["terms"]["document_id"].([]interface{}); ok {
+			found := false
+			for _, v := range values {
+				found = found || v == doc.DocumentID
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *fakeOpenSearch) search(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	raw, _ := json.Marshal(body)
+
+	type hit struct {
+		Score  float64       `json:"_score"`
+		Source openSearchDoc `json:"_source"`
+	}
+	var hits []hit
+	collection := entities.DefaultCollection
+	if i := strings.Index(string(raw), `"collection":"`); i >= 0 {
+		rest := string(raw)[i+len(`"collection":"`):]
+		collection = rest[:strings.Index(rest, `"`)]
+	}
+
+	if aggs, ok := body["aggs"].(map[string]interface{}); ok && aggs["documents"] != nil {
+		documents := map[string]bool{}
+		for _, d := range f.docs {
+			if d.Collection == collection {
+				documents[d.DocumentID] = true
+				hits = append(hits, hit{1, d})
+			}
+		}
+		resp := map[string]interface{}{
+			"hits":         map[string]interface{}{"total": map[string]int{"value": len(hits)}, "hits": hits[:min(len(hits), 1)]},
+			"aggregations": map[string]interface{}{"documents": map[string]int{"value": len(documents)}},
+		}
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	if aggs, ok := body["aggs"].(map[string]interface{}); ok && aggs["per_document"] != nil {
+		counts := map[string]int{}
+		for _, d := range f.docs {
+			if d.Collection == collection {
+				counts[d.DocumentID]++
+			}
+		}
+		var buckets []map[string]interface{}
+		for id, n := range counts {
+			buckets = append(buckets, map[string]interface{}{"key": map[string]string{"document_id": id}, "doc_count": n})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"aggregations": map[string]interface{}{"per_document": map[string]interface{}{"buckets": buckets}},
+		})
+		return
+	}
+
+	if _, ok := body["aggs"]; ok {
+		seen := map[string]bool{}
+		var buckets []map[string]string
+		for _, d := range f.docs {
+			if !seen[d.Collection] {
+				seen[d.Collection] = true
+				buckets = append(buckets, map[string]string{"key": d.Collection})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"aggregations": map[string]interface{}{"collections": map[string]interface{}{"buckets": buckets}},
+		})
+		return
+	}
+
+	var vector []float32
+	var text string
+	var q struct {
+		Query struct {
+			Knn map[string]struct {
+				Vector []float32 `json:"vector"`
+			} `json:"knn"`
+			Bool struct {
+				Must struct {
+					Match map[string]string `json:"match"`
+				} `json:"must"`
+			} `json:"bool"`
+		} `json:"query"`
+	}
+	json.Unmarshal(raw, &q)
+	vector = q.Query.Knn["embedding"].Vector
+
+	// Honour a kNN "terms" filter on document_id
+	var allowed map[string]bool
+	if i := strings.Index(string(raw), `"document_id":[`); i >= 0 {
+		var ids []string
+		rest := string(raw)[i+len(`"document_id":`):]
+		json.Unmarshal([]byte(rest[:strings.Index(rest, "]")+1]), &ids)
+		allowed = map[string]bool{}
+		for _, id := range ids {
+			allowed[id] = true
+		}
+	}
+	text = q.Query.Bool.Must.Match["content"]
+
+	for _, d := range f.docs {
+		if d.Collection != collection || (allowed != nil && !allowed[d.DocumentID]) {
+			continue
+		}
+		if vector != nil {
+			hits = append(hits, hit{(1 + cosineSimilarity(vector, d.Embedding)) / 2, d})
+		} else if text != "" {
+			var score float64
+			for _, term := range strings.Fields(strings.ToLower(text)) {
+				score += float64(strings.Count(strings.ToLower(d.Content), term))
+			}
+			if score > 0 {
+				hits = append(hits, hit{score, d})
+			}
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	var resp struct {
+		Hits struct {
+			Hits []hit `json:"hits"`
+		} `json:"hits"`
+	}
+	resp.Hits.Hits = hits
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestOpenSearchStore_StoreAndSearch(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	store := NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", "")
+	ctx := context.Background()
+
+	results, err := store.Search(ctx, []float32{1, 0, 0}, 2)
+	if err != nil || len(results) != 0 {
+		t.Fatalf("search before any store should be empty, got %v, %v", results, err)
+	}
+
+	err = store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "hello", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc1", Content: "world", Embedding: []float32{0, 1, 0}},
+	})
+	if err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	results, err = store.Search(ctx, []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Chunk.ID != "c1" {
+		t.Fatalf("c1 should be top result, got %v", results)
+	}
+	if results[0].Score < 0.99 {
+		t.Errorf("expected cosine ~1.0, got %f", results[0].Score)
+	}
+}
+
+func TestOpenSearchStore_HybridSearch(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	store := NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", "")
+	ctx := context.Background()
+
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "general notes", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc2", Content: "ERR_DISK_FULL handling", Embedding: []float32{0, 1, 0}},
+	})
+
+	store.SetHybridWeight(0.3)
+	results, err := store.HybridSearch(ctx, "err_disk_full", []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("hybrid search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].Chunk.ID != "c2" {
+		t.Errorf("keyword match should rank first, got %v", results)
+	}
+}
+
+func TestOpenSearchStore_CollectionsAndDelete(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	store := NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", "")
+	ctx := context.Background()
+
+	work := store.Collection("work")
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}}})
+	work.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}}})
+
+	names, _ := store.Collections(ctx)
+	if len(names) != 2 || names[1] != "work" {
+		t.Errorf("unexpected collections: %v", names)
+	}
+
+	work.Delete(ctx, "doc1")
+	if results, _ := work.Search(ctx, []float32{1, 0, 0}, 10); len(results) != 0 {
+		t.Error("work chunks should be deleted")
+	}
+	if results, _ := store.Search(ctx, []float32{1, 0, 0}, 10); len(results) != 1 {
+		t.Error("default collection should be untouched")
+	}
+}
+
+func TestOpenSearchStore_ElasticsearchQueryShape(t *testing.T) {
+	store := NewOpenSearchStore("", "", FlavorElasticsearch, "", "")
+	q := store.knnQuery([]float32{1, 0}, 3, nil)
+
+	knn, ok := q["knn"].(map[string]interface{})
+	if !ok {
+		t.Fatal("elasticsearch should use top-level knn")
+	}
+	if knn["field"] != "embedding" || knn["k"] != 3 || knn["num_candidates"] != 30 {
+		t.Errorf("unexpected knn clause: %v", knn)
+	}
+}
+
+func TestOpenSearchStore_SearchDocuments(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	testSearchDocuments(t, NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", ""))
+}
+
+func TestOpenSearchStore_DeleteMany(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	testDeleteMany(t, NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", ""))
+}
+
+func TestOpenSearchStore_Upsert(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	testUpsert(t, NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", ""))
+}
+
+func TestOpenSearchStore_ChunkPages(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	testChunkPages(t, NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", ""))
+}
+
+func TestOpenSearchStore_Stats(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	store := NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", "")
+	if stats := testStats(t, store); stats.SizeBytes != 4096 {
+		t.Errorf("expected index size 4096, got %d", stats.SizeBytes)
+	}
+}
+
+func TestOpenSearchStore_ListDocuments(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	testListDocuments(t, NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", ""))
+}
+
+func TestOpenSearchStore_EmbeddingModel(t *testing.T) {
+	server := newFakeOpenSearch(t)
+	testEmbeddingModel(t, NewOpenSearchStore(server.URL, "test", FlavorOpenSearch, "", ""))
+}
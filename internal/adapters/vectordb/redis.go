@@ -0,0 +1,854 @@
+package vectordb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// RedisStore implements ports.VectorStore on Redis Stack / Valkey vector search.
+// Chunks are stored as hashes and indexed with an HNSW vector field, so
+// search and persistence are handled by Redis rather than scanned in Go.
+type RedisStore struct {
+	client   *redisClient // Shared by all collection views
+	base     string       // Index name of the default collection
+	index    string
+	prefix   string // Key prefix for chunk hashes, e.g. "localrag:chunk:"
+	hasIndex bool   // Guarded by client.indexMu
+}
+
+// defaultRedisPoolSize is the number of connections a RedisStore opens
+// at most, shared by all its collections.
+const defaultRedisPoolSize = 8
+
+// redisClient is a bounded pool of RESP connections. Each command, or
+// pipeline of commands, checks a connection out for its round trip, so
+// commands from different goroutines run side by side. A connection an
+// error leaves in an unknown state is dropped, and a new one is dialed
+// with jittered backoff while Redis is unreachable.
+type redisClient struct {
+	addr      string
+	password  string
+	dialer    net.Dialer
+	opTimeout time.Duration
+	backoff   resilience.Backoff
+	health    *resilience.Tracker
+	slots     chan struct{} // A token per connection checked out or being dialed
+
+	mu      sync.Mutex
+	idle    []*redisConn // Connections ready for a command
+	dropped bool         // A connection was lost, so the next dial is a reconnect
+
+	indexMu sync.Mutex // Serializes index checks and creation
+}
+
+// redisConn is a connection of the pool.
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisStore creates a Redis-backed vector store.
+// The search index is created lazily on the first Store, once the
+// embedding dimension is known.
+func NewRedisStore(addr, password, index string) *RedisStore {
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	if index == "" {
+		index = "localrag"
+	}
+	return &RedisStore{
+		client: &redisClient{
+			addr:      addr,
+			password:  password,
+			dialer:    net.Dialer{Timeout: 5 * time.Second},
+			opTimeout: 30 * time.Second,
+			backoff:   resilience.DefaultBackoff,
+			health:    resilience.NewTracker("redis"),
+			slots:     make(chan struct{}, defaultRedisPoolSize),
+		},
+		base:   index,
+		index:  index,
+		prefix: index + ":chunk:",
+	}
+}
+
+// SetPoolSize sets the number of connections the store opens at most,
+// 8 by default, shared by all its collections. Call it before the first
+// command.
+func (s *RedisStore) SetPoolSize(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.client.slots = make(chan struct{}, n)
+}
+
+// Collection returns a store scoped to the named collection.
+// Each collection gets its own search index and key prefix.
+func (s *RedisStore) Collection(name string) ports.VectorStore {
+	if name == "" || name == entities.DefaultCollection {
+		return &RedisStore{client: s.client, base: s.base, index: s.base, prefix: s.base + ":chunk:"}
+	}
+	index := s.collectionIndexPrefix() + name
+	return &RedisStore{client: s.client, base: s.base, index: index, prefix: index + ":chunk:"}
+}
+
+// Collections lists collections that hold data.
+func (s *RedisStore) Collections(ctx context.Context) ([]string, error) {
+	reply, err := s.client.do(ctx, "FT._LIST")
+	if err != nil {
+		return nil, fmt.Errorf("listing indexes: %w", err)
+	}
+
+	names := []string{entities.DefaultCollection}
+	indexes, _ := reply.([]interface{})
+	var named []string
+	for _, idx := range indexes {
+		if name, ok := idx.(string); ok && strings.HasPrefix(name, s.collectionIndexPrefix()) {
+			named = append(named, strings.TrimPrefix(name, s.collectionIndexPrefix()))
+		}
+	}
+	sort.Strings(named)
+	return append(names, named...), nil
+}
+
+func (s *RedisStore) collectionIndexPrefix() string {
+	return s.base + ":col:"
+}
+
+// Store saves chunks with their embeddings.
+func (s *RedisStore) Store(ctx context.Context, chunks []entities.Chunk) error {
+	return s.Upsert(ctx, chunks, ports.ConflictVersion)
+}
+
+// Upsert is Store with an explicit policy for chunks already stored.
+// The stored chunks are read back first, in one pipeline, to apply the
+// policy and to move them out of their old documents' sets; the writes
+// then follow in a second.
+func (s *RedisStore) Upsert(ctx context.Context, chunks []entities.Chunk, policy ports.ConflictPolicy) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	if err := s.ensureIndex(ctx, len(chunks[0].Embedding)); err != nil {
+		return err
+	}
+
+	reads := make([][]string, len(chunks))
+	for i, chunk := range chunks {
+		reads[i] = []string{"HMGET", s.prefix + chunk.ID, "document_id", "content_hash"}
+	}
+	stored, err := s.client.pipeline(ctx, reads)
+	if err != nil {
+		return fmt.Errorf("reading chunks: %w", err)
+	}
+
+	var writes [][]string
+	for i, chunk := range chunks {
+		key := s.prefix + chunk.ID
+		if rerr, ok := stored[i].(redisError); ok {
+			return fmt.Errorf("reading chunk: %w", rerr)
+		}
+		if fields, _ := stored[i].([]interface{}); len(fields) == 2 && fields[0] != nil {
+			oldDoc, _ := fields[0].(string)
+			oldHash, _ := fields[1].(string)
+			if keepStored(policy, oldHash, chunk) {
+				continue
+			}
+			if oldDoc != chunk.DocumentID {
+				writes = append(writes, []string{"SREM", s.docKey(oldDoc), key})
+			}
+		}
+		writes = append(writes, []string{"HSET", key,
+			"document_id", chunk.DocumentID,
+			"content", chunk.Content,
+			"chunk_index", strconv.Itoa(chunk.Index),
+			"embedding", string(encodeEmbedding(chunk.Embedding)),
+			"content_hash", chunk.Hash,
+			"page", strconv.Itoa(chunk.Page),
+		}, []string{"SADD", s.docKey(chunk.DocumentID), key})
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	replies, err := s.client.pipeline(ctx, writes)
+	if err != nil {
+		return fmt.Errorf("inserting chunks: %w", err)
+	}
+	for _, reply := range replies {
+		if rerr, ok := reply.(redisError); ok {
+			return fmt.Errorf("inserting chunk: %w", rerr)
+		}
+	}
+	return nil
+}
+
+// Search finds the most similar chunks via an HNSW KNN query.
+func (s *RedisStore) Search(ctx context.Context, embedding []float32, topK int) ([]entities.QueryResult, error) {
+	return s.search(ctx, embedding, topK, "*")
+}
+
+// SearchDocuments is Search restricted to chunks of the given documents,
+// using a TAG pre-filter on document_id.
+func (s *RedisStore) SearchDocuments(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	if len(documentIDs) == 0 {
+		return nil, nil
+	}
+	tags := make([]string, len(documentIDs))
+	for i, id := range documentIDs {
+		tags[i] = escapeTag(id)
+	}
+	return s.search(ctx, embedding, topK, "(@document_id:{"+strings.Join(tags, "|")+"})")
+}
+
+// search runs a KNN query over chunks matching the pre-filter expression.
+func (s *RedisStore) search(ctx context.Context, embedding []float32, topK int, filter string) ([]entities.QueryResult, error) {
+	query := fmt.Sprintf("%s=>[KNN %d @embedding $vec AS vector_distance]", filter, topK)
+	reply, err := s.client.do(ctx, "FT.SEARCH", s.index, query,
+		"PARAMS", "2", "vec", string(encodeEmbedding(embedding)),
+		"SORTBY", "vector_distance",
+		"RETURN", "5", "document_id", "content", "chunk_index", "page", "vector_distance",
+		"LIMIT", "0", strconv.Itoa(topK),
+		"DIALECT", "2",
+	)
+	if err != nil {
+		if isUnknownIndex(err) {
+			return nil, nil // Nothing stored yet
+		}
+		return nil, fmt.Errorf("searching index: %w", err)
+	}
+
+	return s.parseSearchReply(reply)
+}
+
+// escapeTag backslash-escapes RediSearch TAG syntax in a value.
+func escapeTag(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// parseSearchReply converts an FT.SEARCH reply into query results.
+// Reply layout: [total, key1, [field, value, ...], key2, [...], ...]
+func (s *RedisStore) parseSearchReply(reply interface{}) ([]entities.QueryResult, error) {
+	items, ok := reply.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("unexpected search reply: %v", reply)
+	}
+
+	var results []entities.QueryResult
+	for i := 1; i+1 < len(items); i += 2 {
+		key, _ := items[i].(string)
+		fields, _ := items[i+1].([]interface{})
+
+		chunk := entities.Chunk{ID: strings.TrimPrefix(key, s.prefix)}
+		var distance float64
+		for j := 0; j+1 < len(fields); j += 2 {
+			name, _ := fields[j].(string)
+			value, _ := fields[j+1].(string)
+			switch name {
+			case "document_id":
+				chunk.DocumentID = value
+			case "content":
+				chunk.Content = value
+			case "chunk_index":
+				chunk.Index, _ = strconv.Atoi(value)
+			case "page":
+				chunk.Page, _ = strconv.Atoi(value)
+			case "vector_distance":
+				distance, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+
+		results = append(results, entities.QueryResult{
+			Chunk:     chunk,
+			Score:     1 - distance, // COSINE metric returns 1 - similarity
+			SourceDoc: chunk.DocumentID,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, nil
+}
+
+// Delete removes all chunks for a document.
+func (s *RedisStore) Delete(ctx context.Context, documentID string) error {
+	return s.DeleteMany(ctx, []string{documentID})
+}
+
+// DeleteMany removes all chunks of several documents with a single DEL,
+// which Redis applies atomically.
+func (s *RedisStore) DeleteMany(ctx context.Context, documentIDs []string) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+
+	var keys []string
+	for _, documentID := range documentIDs {
+		docKey := s.docKey(documentID)
+		reply, err := s.client.do(ctx, "SMEMBERS", docKey)
+		if err != nil {
+			return fmt.Errorf("listing chunks: %w", err)
+		}
+
+		keys = append(keys, docKey, s.docInfoKey(documentID))
+		members, _ := reply.([]interface{})
+		for _, m := range members {
+			if k, ok := m.(string); ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	_, err := s.client.do(ctx, "DEL", keys...)
+	return err
+}
+
+// Clear removes all data from the store.
+func (s *RedisStore) Clear(ctx context.Context) error {
+	// DD drops the indexed chunk hashes along with the index
+	s.client.indexMu.Lock()
+	_, err := s.client.do(ctx, "FT.DROPINDEX", s.index, "DD")
+	if err == nil || isUnknownIndex(err) {
+		s.hasIndex = false
+	}
+	s.client.indexMu.Unlock()
+	if err != nil && !isUnknownIndex(err) {
+		return fmt.Errorf("dropping index: %w", err)
+	}
+
+	// Document sets and records are not part of the index; remove them
+	// by pattern
+	for _, pattern := range []string{s.index + ":doc:*", s.index + ":docinfo:*"} {
+		err := s.scanKeys(ctx, pattern, func(keys []string) error {
+			_, err := s.client.do(ctx, "DEL", keys...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := s.client.do(ctx, "DEL", s.modelKey()); err != nil {
+		return fmt.Errorf("clearing embedding model: %w", err)
+	}
+	return nil
+}
+
+// EmbeddingModel returns the model recorded for the collection.
+func (s *RedisStore) EmbeddingModel(ctx context.Context) (ports.EmbeddingModel, error) {
+	var model ports.EmbeddingModel
+	reply, err := s.client.do(ctx, "HMGET", s.modelKey(), "name", "dimension")
+	if err != nil {
+		return model, fmt.Errorf("reading embedding model: %w", err)
+	}
+	if fields, _ := reply.([]interface{}); len(fields) == 2 && fields[1] != nil {
+		model.Name, _ = fields[0].(string)
+		dimension, _ := fields[1].(string)
+		if model.Dimension, err = strconv.Atoi(dimension); err != nil {
+			return ports.EmbeddingModel{}, fmt.Errorf("reading embedding model: bad dimension %q", dimension)
+		}
+	}
+	return model, nil
+}
+
+// RecordEmbeddingModel records the model the collection is embedded with.
+func (s *RedisStore) RecordEmbeddingModel(ctx context.Context, model ports.EmbeddingModel) error {
+	_, err := s.client.do(ctx, "HSET", s.modelKey(), "name", model.Name, "dimension", strconv.Itoa(model.Dimension))
+	if err != nil {
+		return fmt.Errorf("recording embedding model: %w", err)
+	}
+	return nil
+}
+
+// scanKeys calls fn with each non-empty batch of keys matching pattern.
+func (s *RedisStore) scanKeys(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	cursor := "0"
+	for {
+		reply, err := s.client.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", "500")
+		if err != nil {
+			return fmt.Errorf("scanning keys: %w", err)
+		}
+		parts, _ := reply.([]interface{})
+		if len(parts) != 2 {
+			return fmt.Errorf("unexpected scan reply: %v", reply)
+		}
+		cursor, _ = parts[0].(string)
+		keys, _ := parts[1].([]interface{})
+		if len(keys) > 0 {
+			batch := make([]string, len(keys))
+			for i, k := range keys {
+				batch[i], _ = k.(string)
+			}
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// Stats reports the collection's contents from FT.INFO. Storage is
+// managed by Redis, so SizeBytes is not reported.
+func (s *RedisStore) Stats(ctx context.Context) (ports.StoreStats, error) {
+	var stats ports.StoreStats
+	reply, err := s.client.do(ctx, "FT.INFO", s.index)
+	if isUnknownIndex(err) {
+		return stats, nil // Nothing stored yet
+	}
+	if err != nil {
+		return stats, fmt.Errorf("reading index info: %w", err)
+	}
+
+	// Reply layout: [name, value, name, value, ...]
+	info, _ := reply.([]interface{})
+	for i := 0; i+1 < len(info); i += 2 {
+		switch name, _ := info[i].(string); name {
+		case "num_docs":
+			stats.Chunks = replyInt(info[i+1])
+		case "attributes":
+			stats.Dimension = vectorDim(info[i+1])
+		}
+	}
+
+	err = s.scanKeys(ctx, s.index+":doc:*", func(keys []string) error {
+		stats.Documents += len(keys)
+		return nil
+	})
+	return stats, err
+}
+
+// vectorDim finds the DIM of the vector field in FT.INFO attributes,
+// given as [[identifier, embedding, ..., dim, 768, ...], ...].
+func vectorDim(attributes interface{}) int {
+	attrs, _ := attributes.([]interface{})
+	for _, a := range attrs {
+		fields, _ := a.([]interface{})
+		for j := 0; j+1 < len(fields); j++ {
+			if name, _ := fields[j].(string); strings.EqualFold(name, "dim") {
+				return replyInt(fields[j+1])
+			}
+		}
+	}
+	return 0
+}
+
+// replyInt reads an integer that Redis may send as an integer or a string.
+func replyInt(v interface{}) int {
+	switch v := v.(type) {
+	case int64:
+		return int(v)
+	case string:
+		n, _ := strconv.ParseFloat(v, 64) // num_docs may be formatted as "12.0"
+		return int(n)
+	}
+	return 0
+}
+
+// Close closes the idle connections to Redis. Connections in use close
+// once their commands finish; a later command dials again.
+func (s *RedisStore) Close() error {
+	return s.client.close()
+}
+
+// ensureIndex creates the vector index if it doesn't exist yet.
+func (s *RedisStore) ensureIndex(ctx context.Context, dim int) error {
+	s.client.indexMu.Lock()
+	defer s.client.indexMu.Unlock()
+	if s.hasIndex {
+		return nil
+	}
+	if _, err := s.client.do(ctx, "FT.INFO", s.index); err == nil {
+		s.hasIndex = true
+		return nil
+	} else if !isUnknownIndex(err) {
+		return fmt.Errorf("checking index: %w", err)
+	}
+
+	_, err := s.client.do(ctx, "FT.CREATE", s.index, "ON", "HASH", "PREFIX", "1", s.prefix,
+		"SCHEMA",
+		"document_id", "TAG",
+		"content", "TEXT",
+		"chunk_index", "NUMERIC",
+		"embedding", "VECTOR", "HNSW", "6",
+		"TYPE", "FLOAT32", "DIM", strconv.Itoa(dim), "DISTANCE_METRIC", "COSINE",
+	)
+	if err != nil {
+		return fmt.Errorf("creating index: %w", err)
+	}
+	s.hasIndex = true
+	return nil
+}
+
+func (s *RedisStore) docKey(documentID string) string {
+	return s.index + ":doc:" + documentID
+}
+
+func (s *RedisStore) docInfoKey(documentID string) string {
+	return s.index + ":docinfo:" + documentID
+}
+
+func (s *RedisStore) modelKey() string {
+	return s.index + ":model"
+}
+
+// RegisterDocument records a document's name, path, ingest time,
+// metadata and provenance in a hash beside its chunk set.
+func (s *RedisStore) RegisterDocument(ctx context.Context, info entities.DocumentInfo) error {
+	ingestedAt := ""
+	if !info.IngestedAt.IsZero() {
+		ingestedAt = info.IngestedAt.UTC().Format(time.RFC3339Nano)
+	}
+	metadata, err := json.Marshal(info.Metadata)
+	if err != nil {
+		return fmt.Errorf("encoding metadata: %w", err)
+	}
+	provenance, err := json.Marshal(provenanceToJSON(info.Provenance))
+	if err != nil {
+		return fmt.Errorf("encoding provenance: %w", err)
+	}
+	_, err = s.client.do(ctx, "HSET", s.docInfoKey(info.ID),
+		"name", info.Name,
+		"path", info.Path,
+		"ingested_at", ingestedAt,
+		"metadata", string(metadata),
+		"provenance", string(provenance),
+	)
+	if err != nil {
+		return fmt.Errorf("registering document: %w", err)
+	}
+	return nil
+}
+
+// ListDocuments returns the documents that have stored chunks and match
+// filter, one page at a time. Documents are found by their chunk sets,
+// so the filter and page are applied after reading every record, or
+// only those of the documents the filter names.
+func (s *RedisStore) ListDocuments(ctx context.Context, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error) {
+	var docs []entities.DocumentInfo
+	if len(filter.IDs) > 0 {
+		for _, id := range filter.IDs {
+			info, err := s.documentInfo(ctx, id)
+			if err != nil {
+				return entities.DocumentPage{}, err
+			}
+			if info.ChunkCount > 0 {
+				docs = append(docs, info)
+			}
+		}
+		return pageDocuments(docs, filter, page), nil
+	}
+	err := s.scanKeys(ctx, s.index+":doc:*", func(keys []string) error {
+		for _, key := range keys {
+			info, err := s.documentInfo(ctx, strings.TrimPrefix(key, s.index+":doc:"))
+			if err != nil {
+				return err
+			}
+			docs = append(docs, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return entities.DocumentPage{}, err
+	}
+	return pageDocuments(docs, filter, page), nil
+}
+
+// documentInfo reads a document's chunk count and registered record,
+// naming it by ID if it was never registered.
+func (s *RedisStore) documentInfo(ctx context.Context, documentID string) (entities.DocumentInfo, error) {
+	info := entities.DocumentInfo{ID: documentID}
+	reply, err := s.client.do(ctx, "SCARD", s.docKey(documentID))
+	if err != nil {
+		return info, fmt.Errorf("counting chunks: %w", err)
+	}
+	info.ChunkCount = replyInt(reply)
+
+	reply, err = s.client.do(ctx, "HMGET", s.docInfoKey(documentID), "name", "path", "ingested_at", "metadata", "provenance")
+	if err != nil {
+		return info, fmt.Errorf("reading document: %w", err)
+	}
+	if fields, _ := reply.([]interface{}); len(fields) == 5 && fields[0] != nil {
+		info.Name, _ = fields[0].(string)
+		info.Path, _ = fields[1].(string)
+		if at, _ := fields[2].(string); at != "" {
+			info.IngestedAt, _ = time.Parse(time.RFC3339Nano, at)
+		}
+		if metadata, _ := fields[3].(string); metadata != "" {
+			json.Unmarshal([]byte(metadata), &info.Metadata)
+		}
+		if provenance, _ := fields[4].(string); provenance != "" {
+			var steps []transformationJSON
+			json.Unmarshal([]byte(provenance), &steps)
+			info.Provenance = provenanceFromJSON(steps)
+		}
+	}
+	if info.Name == "" {
+		info.Name = documentID
+	}
+	return info, nil
+}
+
+// isUnknownIndex reports whether Redis rejected a command for a missing index.
+func isUnknownIndex(err error) bool {
+	var rerr redisError
+	if !errors.As(err, &rerr) {
+		return false
+	}
+	msg := strings.ToLower(string(rerr))
+	return strings.Contains(msg, "unknown index") || strings.Contains(msg, "no such index")
+}
+
+// redisError is an error reply (-ERR ...) returned by the server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// do sends a command and reads its reply, reconnecting with backoff
+// while Redis is unreachable. Error replies are not retried.
+func (c *redisClient) do(ctx context.Context, cmd string, args ...string) (interface{}, error) {
+	replies, err := c.pipeline(ctx, [][]string{append([]string{cmd}, args...)})
+	if err != nil {
+		return nil, err
+	}
+	if rerr, ok := replies[0].(redisError); ok {
+		return nil, rerr
+	}
+	return replies[0], nil
+}
+
+// pipeline sends commands on one connection in a single write and reads
+// their replies, in order, reconnecting with backoff while Redis is
+// unreachable. Error replies are returned as redisError values, so one
+// rejected command does not hide the others' replies. A pipeline cut
+// off by a dropped connection is sent again whole, so its commands must
+// be safe to repeat.
+func (c *redisClient) pipeline(ctx context.Context, cmds [][]string) ([]interface{}, error) {
+	var replies []interface{}
+	err := resilience.Retry(ctx, c.backoff, func() error {
+		rc, err := c.get(ctx)
+		if err == nil {
+			replies, err = rc.roundTrip(ctx, c.opTimeout, cmds)
+			c.put(rc, err != nil)
+		}
+		var rerr redisError
+		if errors.As(err, &rerr) {
+			c.health.Success() // Redis answered; the password was rejected
+			return resilience.Permanent(err)
+		}
+		if err != nil && ctx.Err() != nil {
+			return resilience.Permanent(err)
+		}
+		return c.health.Record(err)
+	})
+	return replies, err
+}
+
+// get checks a connection out of the pool, dialing one when none is
+// idle, and waits while the pool is at its size.
+func (c *redisClient) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		rc := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return rc, nil
+	}
+	c.mu.Unlock()
+
+	rc, err := c.dial(ctx)
+	if err != nil {
+		<-c.slots
+		return nil, err
+	}
+	c.mu.Lock()
+	reconnect := c.dropped
+	c.dropped = false
+	c.mu.Unlock()
+	if reconnect {
+		c.health.Reconnect()
+	}
+	return rc, nil
+}
+
+// put returns a connection to the pool, or closes it when broken.
+func (c *redisClient) put(rc *redisConn, broken bool) {
+	c.mu.Lock()
+	if broken {
+		rc.conn.Close()
+		c.dropped = true
+	} else {
+		c.idle = append(c.idle, rc)
+	}
+	c.mu.Unlock()
+	<-c.slots
+}
+
+// dial connects to Redis and authenticates if a password is set.
+func (c *redisClient) dial(ctx context.Context) (*redisConn, error) {
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to Redis: %w", err)
+	}
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if c.password != "" {
+		replies, err := rc.roundTrip(ctx, c.opTimeout, [][]string{{"AUTH", c.password}})
+		if err == nil {
+			err, _ = replies[0].(redisError)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	return rc, nil
+}
+
+// close closes the idle connections.
+func (c *redisClient) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for _, rc := range c.idle {
+		if cerr := rc.conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	c.idle = nil
+	return err
+}
+
+// roundTrip writes cmds in one go and reads a reply for each. An error
+// means the connection is in an unknown state and must be dropped.
+func (rc *redisConn) roundTrip(ctx context.Context, timeout time.Duration, cmds [][]string) ([]interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	rc.conn.SetDeadline(deadline)
+
+	var buf bytes.Buffer
+	for _, cmd := range cmds {
+		writeCommand(&buf, cmd[0], cmd[1:]...)
+	}
+	if _, err := rc.conn.Write(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("writing command: %w", err)
+	}
+	replies := make([]interface{}, len(cmds))
+	for i := range replies {
+		reply, err := readReply(rc.reader)
+		var rerr redisError
+		if errors.As(err, &rerr) {
+			reply, err = rerr, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// Health reports the Redis connection state.
+func (s *RedisStore) Health() ports.BackendHealth {
+	return s.client.health.Health()
+}
+
+// writeCommand encodes a command as a RESP array of bulk strings.
+func writeCommand(w io.Writer, cmd string, args ...string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n$%d\r\n%s\r\n", len(args)+1, len(cmd), cmd)
+	for _, a := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(a), a)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// readReply decodes one RESP2 reply. Bulk and simple strings become
+// string, integers int64, arrays []interface{}, nil bulk/array nil.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, fmt.Errorf("empty reply line")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("bad bulk length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("bad array length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readReply(r)
+			if err != nil {
+				var rerr redisError
+				if !errors.As(err, &rerr) {
+					return nil, err
+				}
+				item = rerr // Nested errors are values, not failures
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", line[0])
+	}
+}
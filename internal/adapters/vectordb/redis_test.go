@@ -0,0 +1,499 @@
+package vectordb
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/resilience"
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// fakeRedis emulates the subset of Redis Stack used by RedisStore.
+// FT.SEARCH is answered by brute-force cosine over stored hashes.
+type fakeRedis struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+	sets   map[string]map[string]bool
+	index  bool
+	prefix string // Hashes the index covers
+	dim    int
+
+	delay      time.Duration // Added to every command, outside mu
+	conns      int           // Connections accepted
+	active     int           // Commands being served
+	maxActive  int
+	roundTrips int // Batches of commands read before replying
+}
+
+// indexed reports whether a hash key is covered by the search index.
+func (f *fakeRedis) indexed(key string) bool {
+	return f.index && strings.HasPrefix(key, f.prefix)
+}
+
+func startFakeRedis(t *testing.T) string {
+	_, addr := newFakeRedis(t)
+	return addr
+}
+
+func newFakeRedis(t *testing.T) (*fakeRedis, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeRedis{hashes: map[string]map[string]string{}, sets: map[string]map[string]bool{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns++
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		req, err := readReply(r)
+		if err != nil {
+			return
+		}
+		parts := req.([]interface{})
+		args := make([]string, len(parts))
+		for i, p := range parts {
+			args[i] = p.(string)
+		}
+		f.mu.Lock()
+		f.active++
+		if f.active > f.maxActive {
+			f.maxActive = f.active
+		}
+		if r.Buffered() == 0 {
+			f.roundTrips++
+		}
+		f.mu.Unlock()
+		time.Sleep(f.delay)
+		f.mu.Lock()
+		reply := f.handle(args)
+		f.active--
+		f.mu.Unlock()
+		writeReply(conn, reply)
+	}
+}
+
+func (f *fakeRedis) handle(args []string) interface{} {
+	switch strings.ToUpper(args[0]) {
+	case "FT.INFO":
+		if !f.index {
+			return redisError("Unknown index name")
+		}
+		numDocs := 0
+		for k := range f.hashes {
+			if f.indexed(k) {
+				numDocs++
+			}
+		}
+		return []interface{}{
+			"index_name", args[1],
+			"attributes", []interface{}{
+				[]interface{}{"identifier", "embedding", "type", "VECTOR", "dim", int64(f.dim)},
+			},
+			"num_docs", strconv.Itoa(numDocs),
+		}
+	case "FT.CREATE":
+		f.index = true
+		for i, a := range args {
+			if a == "DIM" {
+				f.dim, _ = strconv.Atoi(args[i+1])
+			}
+			if a == "PREFIX" {
+				f.prefix = args[i+2]
+			}
+		}
+		return "OK"
+	case "FT.DROPINDEX":
+		if !f.index {
+			return redisError("Unknown Index name")
+		}
+		for k := range f.hashes {
+			if f.indexed(k) {
+				delete(f.hashes, k)
+			}
+		}
+		f.index = false
+		return "OK"
+	case "HSET":
+		h := map[string]string{}
+		for i := 2; i+1 < len(args); i += 2 {
+			h[args[i]] = args[i+1]
+		}
+		f.hashes[args[1]] = h
+		return int64(len(h))
+	case "HMGET":
+		out := make([]interface{}, len(args)-2)
+		if h, ok := f.hashes[args[1]]; ok {
+			for i, field := range args[2:] {
+				if v, ok := h[field]; ok {
+					out[i] = v
+				}
+			}
+		}
+		return out
+	case "SREM":
+		delete(f.sets[args[1]], args[2])
+		if len(f.sets[args[1]]) == 0 {
+			delete(f.sets, args[1])
+		}
+		return int64(1)
+	case "SADD":
+		if f.sets[args[1]] == nil {
+			f.sets[args[1]] = map[string]bool{}
+		}
+		f.sets[args[1]][args[2]] = true
+		return int64(1)
+	case "SCARD":
+		return int64(len(f.sets[args[1]]))
+	case "SMEMBERS":
+		var out []interface{}
+		for m := range f.sets[args[1]] {
+			out = append(out, m)
+		}
+		return out
+	case "DEL":
+		for _, k := range args[1:] {
+			delete(f.hashes, k)
+			delete(f.sets, k)
+		}
+		return int64(len(args) - 1)
+	case "SCAN":
+		var pattern string
+		for i, a := range args {
+			if a == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		var keys []interface{}
+		for k := range f.sets {
+			if strings.HasPrefix(k, strings.TrimSuffix(pattern, "*")) {
+				keys = append(keys, k)
+			}
+		}
+		for k := range f.hashes {
+			if strings.HasPrefix(k, strings.TrimSuffix(pattern, "*")) {
+				keys = append(keys, k)
+			}
+		}
+		return []interface{}{"0", keys}
+	case "FT.SEARCH":
+		if !f.index {
+			return redisError("no such index")
+		}
+		var query []float32
+		for i, a := range args {
+			if a == "vec" {
+				query, _ = decodeEmbedding([]byte(args[i+1]))
+			}
+		}
+		k, _ := strconv.Atoi(strings.Fields(args[2])[1])
+		// Honour a "(@document_id:{a|b})" pre-filter
+		var allowed map[string]bool
+		if tags, ok := strings.CutPrefix(args[2], "(@document_id:{"); ok {
+			allowed = map[string]bool{}
+			for _, tag := range strings.Split(tags[:strings.Index(tags, "})")], "|") {
+				allowed[strings.ReplaceAll(tag, "\\", "")] = true
+			}
+		}
+		type hit struct {
+			key  string
+			dist float64
+		}
+		var hits []hit
+		for key, h := range f.hashes {
+			if !f.indexed(key) {
+				continue
+			}
+			if allowed != nil && !allowed[h["document_id"]] {
+				continue
+			}
+			emb, _ := decodeEmbedding([]byte(h["embedding"]))
+			hits = append(hits, hit{key, 1 - cosineSimilarity(query, emb)})
+		}
+		sort.Slice(hits, func(i, j int) bool { return hits[i].dist < hits[j].dist })
+		if len(hits) > k {
+			hits = hits[:k]
+		}
+		out := []interface{}{int64(len(hits))}
+		for _, h := range hits {
+			fields := f.hashes[h.key]
+			out = append(out, h.key, []interface{}{
+				"document_id", fields["document_id"],
+				"content", fields["content"],
+				"chunk_index", fields["chunk_index"],
+				"page", fields["page"],
+				"vector_distance", strconv.FormatFloat(h.dist, 'f', -1, 64),
+			})
+		}
+		return out
+	}
+	return redisError("ERR unknown command " + args[0])
+}
+
+func writeReply(w io.Writer, v interface{}) {
+	switch v := v.(type) {
+	case redisError:
+		fmt.Fprintf(w, "-%s\r\n", string(v))
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case nil:
+		fmt.Fprint(w, "$-1\r\n")
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	}
+}
+
+func TestRedisStore_StoreAndSearch(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+
+	ctx := context.Background()
+	results, err := store.Search(ctx, []float32{1, 0, 0}, 2)
+	if err != nil || len(results) != 0 {
+		t.Fatalf("search before any store should be empty, got %v, %v", results, err)
+	}
+
+	err = store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Content: "hello", Index: 0, Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc1", Content: "world", Index: 1, Embedding: []float32{0, 1, 0}},
+	})
+	if err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	results, err = store.Search(ctx, []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Chunk.ID != "c1" || results[0].Chunk.Content != "hello" {
+		t.Errorf("c1 should be top result, got %+v", results[0].Chunk)
+	}
+	if results[0].Score < 0.99 {
+		t.Errorf("expected score ~1.0, got %f", results[0].Score)
+	}
+}
+
+func TestRedisStore_DeleteAndClear(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0, 0}},
+		{ID: "c2", DocumentID: "doc2", Embedding: []float32{0, 1, 0}},
+	})
+
+	if err := store.Delete(ctx, "doc1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	results, _ := store.Search(ctx, []float32{1, 0, 0}, 10)
+	if len(results) != 1 || results[0].Chunk.ID != "c2" {
+		t.Errorf("only c2 should remain, got %v", results)
+	}
+
+	if err := store.Clear(ctx); err != nil {
+		t.Fatalf("clear failed: %v", err)
+	}
+	results, _ = store.Search(ctx, []float32{1, 0, 0}, 10)
+	if len(results) != 0 {
+		t.Error("store should be empty after clear")
+	}
+}
+
+func TestRedisStore_ConnectionError(t *testing.T) {
+	store := NewRedisStore("127.0.0.1:1", "", "test")
+	store.client.backoff = resilience.Backoff{Initial: time.Millisecond, Max: time.Millisecond, Attempts: 2}
+	_, err := store.Search(context.Background(), []float32{1}, 1)
+	if err == nil {
+		t.Error("should error when Redis is unreachable")
+	}
+	if h := store.Health(); h.Healthy || h.ConsecutiveFailures != 2 {
+		t.Errorf("unexpected health: %+v", h)
+	}
+}
+
+func TestRedisStore_ReconnectsAfterDrop(t *testing.T) {
+	addr := startFakeRedis(t)
+	store := NewRedisStore(addr, "", "test")
+	ctx := context.Background()
+
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0}}})
+
+	// Simulate a server restart dropping the connection
+	store.client.mu.Lock()
+	for _, rc := range store.client.idle {
+		rc.conn.Close()
+	}
+	store.client.mu.Unlock()
+
+	results, err := store.Search(ctx, []float32{1, 0}, 1)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("search after drop: %v, %v", results, err)
+	}
+	if h := store.Health(); !h.Healthy || h.Reconnects != 1 {
+		t.Errorf("unexpected health: %+v", h)
+	}
+}
+
+func TestRedisStore_PoolsConnections(t *testing.T) {
+	f, addr := newFakeRedis(t)
+	store := NewRedisStore(addr, "", "test")
+	store.SetPoolSize(3)
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{{ID: "c1", DocumentID: "doc1", Embedding: []float32{1, 0}}})
+
+	f.mu.Lock()
+	f.delay = 20 * time.Millisecond
+	f.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Search(ctx, []float32{1, 0}, 1); err != nil {
+				t.Errorf("search failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.maxActive < 2 {
+		t.Errorf("searches should run side by side, max in flight %d", f.maxActive)
+	}
+	if f.conns > 3 {
+		t.Errorf("pool of 3 opened %d connections", f.conns)
+	}
+}
+
+func TestRedisStore_UpsertPipelines(t *testing.T) {
+	f, addr := newFakeRedis(t)
+	store := NewRedisStore(addr, "", "test")
+	ctx := context.Background()
+
+	var chunks []entities.Chunk
+	for i := 0; i < 20; i++ {
+		chunks = append(chunks, entities.Chunk{ID: fmt.Sprintf("c%d", i), DocumentID: "doc1", Index: i, Embedding: []float32{1, 0}})
+	}
+	store.Store(ctx, chunks)
+	f.mu.Lock()
+	f.roundTrips = 0
+	f.mu.Unlock()
+
+	// Moving every chunk needs an HMGET, SREM, HSET and SADD each
+	for i := range chunks {
+		chunks[i].DocumentID = "doc2"
+		chunks[i].Hash = "changed"
+	}
+	if err := store.Store(ctx, chunks); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.roundTrips > 3 {
+		t.Errorf("upserting 20 chunks took %d round trips", f.roundTrips)
+	}
+	if n := len(f.sets[store.docKey("doc2")]); n != 20 {
+		t.Errorf("expected 20 chunks moved to doc2, got %d", n)
+	}
+	if n := len(f.sets[store.docKey("doc1")]); n != 0 {
+		t.Errorf("doc1 should have no chunks left, got %d", n)
+	}
+}
+
+func TestRedisStore_CollectionKeys(t *testing.T) {
+	store := NewRedisStore("localhost:6379", "", "localrag")
+	work := store.Collection("work").(*RedisStore)
+
+	if work.index != "localrag:col:work" || work.prefix != "localrag:col:work:chunk:" {
+		t.Errorf("unexpected collection naming: %s %s", work.index, work.prefix)
+	}
+	if work.client != store.client {
+		t.Error("collections should share the connection")
+	}
+	if def := store.Collection("").(*RedisStore); def.index != "localrag" {
+		t.Errorf("default collection should use base index, got %s", def.index)
+	}
+}
+
+func TestRedisStore_DeleteMany(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+	testDeleteMany(t, store)
+}
+
+func TestRedisStore_Upsert(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+	testUpsert(t, store)
+}
+
+func TestRedisStore_ChunkPages(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+	testChunkPages(t, store)
+}
+
+func TestRedisStore_Stats(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+	testStats(t, store)
+}
+
+func TestRedisStore_SearchDocuments(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+	testSearchDocuments(t, store)
+
+	if got := escapeTag("handbook.pdf-v2"); got != `handbook\.pdf\-v2` {
+		t.Errorf("escapeTag = %q", got)
+	}
+}
+
+func TestRedisStore_ListDocuments(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+	testListDocuments(t, store)
+}
+
+func TestRedisStore_EmbeddingModel(t *testing.T) {
+	store := NewRedisStore(startFakeRedis(t), "", "test")
+	defer store.Close()
+	testEmbeddingModel(t, store)
+}
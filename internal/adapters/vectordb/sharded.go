@@ -0,0 +1,380 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// Shard is a store ShardedStore can spread documents over.
+// InMemoryStore, LanceDBStore and BoltStore all qualify.
+type Shard interface {
+	ports.CollectionStore
+	ports.DocumentRegistry
+	ports.DocumentSearcher
+	ports.ChunkHasher
+	ports.ChunkReader
+	ports.CollectionSwapper
+	ports.BatchDeleter
+	ports.Upserter
+	ports.ModelRecorder
+}
+
+// ShardedStore spreads documents over several stores by a hash of the
+// document ID and fans searches out to all of them in parallel, merging
+// the per-shard top K. Brute-force search over one SQLite file runs on
+// a single core; shards let a large corpus use one core per shard.
+//
+// A document's chunks always live in one shard, so deletes, hashes and
+// registry entries go to that shard alone. Writes spanning shards are
+// not atomic across them. Keyword and hybrid search and snapshots are
+// not offered; usecases fall back to vector search.
+type ShardedStore struct {
+	shards []Shard
+}
+
+// NewShardedStore creates a store over shards. Documents are routed by
+// position, so reopening existing data needs the same shards in the
+// same order.
+func NewShardedStore(shards ...Shard) *ShardedStore {
+	return &ShardedStore{shards: shards}
+}
+
+// NewShardedLanceDBStore opens shardCount LanceDB stores, one SQLite
+// file each, under dataPath/shard-NN. The count is fixed once data
+// exists; changing it means re-ingesting into a new path.
+func NewShardedLanceDBStore(dataPath string, shardCount int, opts LanceDBOptions) (*ShardedStore, error) {
+	if dataPath == "" {
+		dataPath = "./data"
+	}
+	if shardCount < 1 {
+		return nil, fmt.Errorf("shard count must be at least 1, got %d", shardCount)
+	}
+	existing, err := filepath.Glob(filepath.Join(dataPath, "shard-*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 && len(existing) != shardCount {
+		return nil, fmt.Errorf("%s holds %d shards, not %d; re-ingest into a new path to change the shard count",
+			dataPath, len(existing), shardCount)
+	}
+
+	shards := make([]Shard, 0, shardCount)
+	for i := 0; i < shardCount; i++ {
+		path := filepath.Join(dataPath, fmt.Sprintf("shard-%02d", i))
+		if err := os.MkdirAll(path, 0755); err != nil {
+			closeShards(shards)
+			return nil, fmt.Errorf("creating shard directory: %w", err)
+		}
+		store, err := NewLanceDBStoreWithOptions(path, opts)
+		if err != nil {
+			closeShards(shards)
+			return nil, fmt.Errorf("opening shard %d: %w", i, err)
+		}
+		shards = append(shards, store)
+	}
+	return NewShardedStore(shards...), nil
+}
+
+// shardFor returns the shard holding a document.
+func (s *ShardedStore) shardFor(documentID string) Shard {
+	h := fnv.New32a()
+	h.Write([]byte(documentID))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// eachShard runs fn on every shard concurrently and returns the first
+// error.
+func (s *ShardedStore) eachShard(fn func(i int, shard Shard) error) error {
+	errs := make([]error, len(s.shards))
+	var wg sync.WaitGroup
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func(i int, shard Shard) {
+			defer wg.Done()
+			errs[i] = fn(i, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Collection returns a sharded store over each shard's named collection.
+func (s *ShardedStore) Collection(name string) ports.VectorStore {
+	shards := make([]Shard, len(s.shards))
+	for i, shard := range s.shards {
+		shards[i] = shard.Collection(name).(Shard)
+	}
+	return NewShardedStore(shards...)
+}
+
+// Collections lists collections that hold data in any shard.
+func (s *ShardedStore) Collections(ctx context.Context) ([]string, error) {
+	lists := make([][]string, len(s.shards))
+	err := s.eachShard(func(i int, shard Shard) error {
+		var err error
+		lists[i], err = shard.Collections(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{entities.DefaultCollection: true}
+	names := []string{entities.DefaultCollection}
+	for _, list := range lists {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+// Store saves each document's chunks in its shard.
+func (s *ShardedStore) Store(ctx context.Context, chunks []entities.Chunk) error {
+	return s.Upsert(ctx, chunks, ports.ConflictVersion)
+}
+
+// Upsert is Store with an explicit policy for chunks already stored.
+func (s *ShardedStore) Upsert(ctx context.Context, chunks []entities.Chunk, policy ports.ConflictPolicy) error {
+	groups := make(map[Shard][]entities.Chunk)
+	for _, chunk := range chunks {
+		shard := s.shardFor(chunk.DocumentID)
+		groups[shard] = append(groups[shard], chunk)
+	}
+	return s.eachShard(func(i int, shard Shard) error {
+		if len(groups[shard]) == 0 {
+			return nil
+		}
+		return shard.Upsert(ctx, groups[shard], policy)
+	})
+}
+
+// Search searches all shards in parallel and merges their best matches.
+func (s *ShardedStore) Search(ctx context.Context, embedding []float32, topK int) ([]entities.QueryResult, error) {
+	results := make([][]entities.QueryResult, len(s.shards))
+	err := s.eachShard(func(i int, shard Shard) error {
+		var err error
+		results[i], err = shard.Search(ctx, embedding, topK)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergeResults(results, topK), nil
+}
+
+// SearchDocuments is Search restricted to chunks of the given documents,
+// asking only the shards that hold them.
+func (s *ShardedStore) SearchDocuments(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	groups := s.groupDocuments(documentIDs)
+	results := make([][]entities.QueryResult, len(s.shards))
+	err := s.eachShard(func(i int, shard Shard) error {
+		if len(groups[shard]) == 0 {
+			return nil
+		}
+		var err error
+		results[i], err = shard.SearchDocuments(ctx, embedding, topK, groups[shard])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergeResults(results, topK), nil
+}
+
+// mergeResults combines per-shard rankings into the overall top K.
+func mergeResults(results [][]entities.QueryResult, topK int) []entities.QueryResult {
+	var merged []entities.QueryResult
+	for _, r := range results {
+		merged = append(merged, r...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+	if len(merged) > topK {
+		merged = merged[:topK]
+	}
+	return merged
+}
+
+// groupDocuments splits document IDs by the shard holding them.
+func (s *ShardedStore) groupDocuments(documentIDs []string) map[Shard][]string {
+	groups := make(map[Shard][]string)
+	for _, id := range documentIDs {
+		shard := s.shardFor(id)
+		groups[shard] = append(groups[shard], id)
+	}
+	return groups
+}
+
+// Delete removes all chunks for a document.
+func (s *ShardedStore) Delete(ctx context.Context, documentID string) error {
+	return s.shardFor(documentID).Delete(ctx, documentID)
+}
+
+// DeleteMany removes several documents, in one operation per shard.
+func (s *ShardedStore) DeleteMany(ctx context.Context, documentIDs []string) error {
+	groups := s.groupDocuments(documentIDs)
+	return s.eachShard(func(i int, shard Shard) error {
+		if len(groups[shard]) == 0 {
+			return nil
+		}
+		return shard.DeleteMany(ctx, groups[shard])
+	})
+}
+
+// Clear removes all data from every shard.
+func (s *ShardedStore) Clear(ctx context.Context) error {
+	return s.eachShard(func(i int, shard Shard) error {
+		return shard.Clear(ctx)
+	})
+}
+
+// Stats sums the shards' contents.
+func (s *ShardedStore) Stats(ctx context.Context) (ports.StoreStats, error) {
+	stats := make([]ports.StoreStats, len(s.shards))
+	err := s.eachShard(func(i int, shard Shard) error {
+		var err error
+		stats[i], err = shard.Stats(ctx)
+		return err
+	})
+	if err != nil {
+		return ports.StoreStats{}, err
+	}
+
+	var total ports.StoreStats
+	for _, st := range stats {
+		total.Chunks += st.Chunks
+		total.Documents += st.Documents
+		total.SizeBytes += st.SizeBytes
+		if total.Dimension == 0 {
+			total.Dimension = st.Dimension
+		}
+	}
+	return total, nil
+}
+
+// EmbeddingModel returns the model recorded by the first shard that has
+// one.
+func (s *ShardedStore) EmbeddingModel(ctx context.Context) (ports.EmbeddingModel, error) {
+	for _, shard := range s.shards {
+		model, err := shard.EmbeddingModel(ctx)
+		if err != nil || model.Dimension != 0 {
+			return model, err
+		}
+	}
+	return ports.EmbeddingModel{}, nil
+}
+
+// RecordEmbeddingModel records the model in every shard.
+func (s *ShardedStore) RecordEmbeddingModel(ctx context.Context, model ports.EmbeddingModel) error {
+	return s.eachShard(func(i int, shard Shard) error {
+		return shard.RecordEmbeddingModel(ctx, model)
+	})
+}
+
+// RegisterDocument records a document in its shard.
+func (s *ShardedStore) RegisterDocument(ctx context.Context, info entities.DocumentInfo) error {
+	return s.shardFor(info.ID).RegisterDocument(ctx, info)
+}
+
+// ListDocuments filters every shard's documents and pages the merged
+// listing.
+func (s *ShardedStore) ListDocuments(ctx context.Context, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error) {
+	lists := make([][]entities.DocumentInfo, len(s.shards))
+	err := s.eachShard(func(i int, shard Shard) error {
+		matched, err := shard.ListDocuments(ctx, filter, entities.Page{})
+		lists[i] = matched.Documents
+		return err
+	})
+	if err != nil {
+		return entities.DocumentPage{}, err
+	}
+
+	var docs []entities.DocumentInfo
+	for _, list := range lists {
+		docs = append(docs, list...)
+	}
+	return pageDocuments(docs, entities.DocumentFilter{}, page), nil
+}
+
+// ChunkHashes returns the content hash of each stored chunk of a document.
+func (s *ShardedStore) ChunkHashes(ctx context.Context, documentID string) (map[string]string, error) {
+	return s.shardFor(documentID).ChunkHashes(ctx, documentID)
+}
+
+// DocumentChunks returns a document's stored chunks from its shard.
+func (s *ShardedStore) DocumentChunks(ctx context.Context, documentID string) ([]entities.Chunk, error) {
+	return s.shardFor(documentID).DocumentChunks(ctx, documentID)
+}
+
+// SwapCollections swaps the collections in every shard. Each shard swaps
+// atomically, but not all shards at the same instant.
+func (s *ShardedStore) SwapCollections(ctx context.Context, a, b string) error {
+	return s.eachShard(func(i int, shard Shard) error {
+		return shard.SwapCollections(ctx, a, b)
+	})
+}
+
+// Maintain runs maintenance on every shard that supports it and adds up
+// the reports.
+func (s *ShardedStore) Maintain(ctx context.Context) (ports.MaintenanceReport, error) {
+	reports := make([]ports.MaintenanceReport, len(s.shards))
+	err := s.eachShard(func(i int, shard Shard) error {
+		m, ok := shard.(ports.Maintainer)
+		if !ok {
+			return nil
+		}
+		var err error
+		reports[i], err = m.Maintain(ctx)
+		return err
+	})
+
+	var total ports.MaintenanceReport
+	rebuilt := make(map[string]bool)
+	for _, r := range reports {
+		total.OrphansRemoved += r.OrphansRemoved
+		total.ReclaimedBytes += r.ReclaimedBytes
+		for _, name := range r.IndexesRebuilt {
+			if !rebuilt[name] {
+				rebuilt[name] = true
+				total.IndexesRebuilt = append(total.IndexesRebuilt, name)
+			}
+		}
+	}
+	return total, err
+}
+
+// Close closes every shard.
+func (s *ShardedStore) Close() error {
+	return closeShards(s.shards)
+}
+
+// closeShards closes the shards that need it and returns the first error.
+func closeShards(shards []Shard) error {
+	var first error
+	for _, shard := range shards {
+		if c, ok := shard.(io.Closer); ok {
+			if err := c.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
@@ -0,0 +1,133 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func newShardedMemoryStore(n int) *ShardedStore {
+	shards := make([]Shard, n)
+	for i := range shards {
+		shards[i] = NewInMemoryStore()
+	}
+	return NewShardedStore(shards...)
+}
+
+func TestShardedStore_SearchMatchesSingleStore(t *testing.T) {
+	ctx := context.Background()
+	sharded := newShardedMemoryStore(4)
+	single := NewInMemoryStore()
+
+	var chunks []entities.Chunk
+	for i := 0; i < 40; i++ {
+		chunks = append(chunks, entities.Chunk{
+			ID:         fmt.Sprintf("c%d", i),
+			DocumentID: fmt.Sprintf("doc%d", i%10),
+			Content:    fmt.Sprintf("chunk %d", i),
+			Embedding:  []float32{float32(i), float32(40 - i), 1},
+		})
+	}
+	if err := sharded.Store(ctx, chunks); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	single.Store(ctx, chunks)
+
+	// Each document lives wholly in one shard, and documents are spread
+	used := 0
+	for _, shard := range sharded.shards {
+		page, _ := shard.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+		for _, doc := range page.Documents {
+			if sharded.shardFor(doc.ID) != shard || doc.ChunkCount != 4 {
+				t.Errorf("document %s misplaced or split: %+v", doc.ID, doc)
+			}
+		}
+		if page.Total > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Errorf("expected documents spread over shards, %d used", used)
+	}
+
+	query := []float32{30, 10, 1}
+	want, _ := single.Search(ctx, query, 5)
+	got, err := sharded.Search(ctx, query, 5)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Chunk.ID != want[i].Chunk.ID {
+			t.Errorf("result %d: got %s, want %s", i, got[i].Chunk.ID, want[i].Chunk.ID)
+		}
+	}
+
+	stats, err := sharded.Stats(ctx)
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if stats.Chunks != 40 || stats.Documents != 10 || stats.Dimension != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if page, _ := sharded.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{}); page.Total != 10 {
+		t.Errorf("expected 10 documents, got %d", page.Total)
+	}
+}
+
+func TestShardedStore_Capabilities(t *testing.T) {
+	testSearchDocuments(t, newShardedMemoryStore(3))
+	testDeleteMany(t, newShardedMemoryStore(3))
+	testUpsert(t, newShardedMemoryStore(3))
+	testSkipsUnchangedChunks(t, newShardedMemoryStore(3))
+	testStats(t, newShardedMemoryStore(3))
+	testListDocuments(t, newShardedMemoryStore(3))
+	testEmbeddingModel(t, newShardedMemoryStore(3))
+	testSwapCollections(t, newShardedMemoryStore(3))
+
+	ctx := context.Background()
+	store := newShardedMemoryStore(3)
+	store.Collection("work").Store(ctx, []entities.Chunk{{ID: "w1", DocumentID: "w", Content: "w", Embedding: []float32{1, 0}}})
+	names, err := store.Collections(ctx)
+	if err != nil || len(names) != 2 || names[1] != "work" {
+		t.Errorf("expected default and work collections, got %v, %v", names, err)
+	}
+	if results, _ := store.Search(ctx, []float32{1, 0}, 5); len(results) != 0 {
+		t.Errorf("collections should stay separate, got %+v", results)
+	}
+}
+
+func TestNewShardedLanceDBStore(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "sharded-test-*")
+	defer os.RemoveAll(dir)
+
+	store, err := NewShardedLanceDBStore(dir, 3, LanceDBOptions{DisableNativeIndex: true})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	ctx := context.Background()
+	store.Store(ctx, []entities.Chunk{
+		{ID: "a1", DocumentID: "a", Content: "a1", Embedding: []float32{1, 0}},
+		{ID: "b1", DocumentID: "b", Content: "b1", Embedding: []float32{0, 1}},
+	})
+	store.Close()
+
+	if _, err := NewShardedLanceDBStore(dir, 2, LanceDBOptions{DisableNativeIndex: true}); err == nil {
+		t.Error("reopening with a different shard count should fail")
+	}
+
+	store, err = NewShardedLanceDBStore(dir, 3, LanceDBOptions{DisableNativeIndex: true})
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	defer store.Close()
+	results, err := store.Search(ctx, []float32{0, 1}, 1)
+	if err != nil || len(results) != 1 || results[0].Chunk.ID != "b1" {
+		t.Errorf("expected b1 after reopening, got %+v, %v", results, err)
+	}
+}
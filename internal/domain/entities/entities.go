@@ -2,17 +2,142 @@
 // These are the enterprise business rules - pure domain objects with no external dependencies.
 package entities
 
-import "time"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCollection is the collection used when none is specified.
+const DefaultCollection = "default"
+
+// sessionCollectionPrefix names the ephemeral collections holding
+// documents attached to a single chat session.
+const sessionCollectionPrefix = "session-"
+
+// SessionCollection returns the collection holding a session's documents.
+func SessionCollection(sessionID string) string {
+	return sessionCollectionPrefix + sessionID
+}
+
+// IsSessionCollection reports whether a collection belongs to a chat
+// session rather than the corpus.
+func IsSessionCollection(name string) bool {
+	return strings.HasPrefix(name, sessionCollectionPrefix)
+}
+
+const (
+	// stagingCollectionPrefix names the collection a re-embed writes
+	// new vectors to before switching.
+	stagingCollectionPrefix = "reembed-"
+
+	// previousCollectionPrefix names the collection holding what a
+	// re-embed replaced, until it is rolled back to or discarded.
+	previousCollectionPrefix = "previous-"
+)
+
+// StagingCollection returns the collection a re-embed of name builds
+// the new vectors in.
+func StagingCollection(name string) string {
+	return stagingCollectionPrefix + name
+}
+
+// PreviousCollection returns the collection keeping name's contents from
+// before its last re-embed.
+func PreviousCollection(name string) string {
+	return previousCollectionPrefix + name
+}
+
+// IsReembedCollection reports whether a collection is kept by a re-embed
+// beside the corpus, as its staging collection or previous version.
+func IsReembedCollection(name string) bool {
+	return strings.HasPrefix(name, stagingCollectionPrefix) || strings.HasPrefix(name, previousCollectionPrefix)
+}
 
 // Document represents a source document (PDF, TXT, MD).
 // This is a core entity - no knowledge of storage or external systems.
 type Document struct {
-	ID        string
-	Name      string
-	Path      string
-	Content   string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         string
+	Name       string
+	Path       string
+	Content    string
+	Collection string            // Target collection; empty means DefaultCollection
+	Metadata   map[string]string // User-defined fields, checked against the collection's MetadataSchema
+	Provenance Provenance        // Machine transformations that produced Content, if any
+	Sections   []Section         // Parts chunked separately, such as slides; nil chunks Content as a whole
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// DocumentInfo describes an ingested document without its content.
+type DocumentInfo struct {
+	ID         string
+	Name       string
+	Path       string
+	ChunkCount int
+	IngestedAt time.Time
+	Metadata   map[string]string
+	Provenance Provenance
+}
+
+// DocumentFilter narrows a document listing. Zero fields match everything.
+type DocumentFilter struct {
+	IDs            []string            // Only these documents
+	Query          string              // Case-insensitive substring of the name or path
+	PathPrefix     string              // Source path starts with this
+	IngestedAfter  time.Time           // Ingested at or after this time
+	IngestedBefore time.Time           // Ingested before this time
+	Metadata       []MetadataCondition // All must match
+	Expr           FilterExpr          // Filter expression; nil matches all
+}
+
+// Matches reports whether doc passes the filter.
+func (f DocumentFilter) Matches(doc DocumentInfo) bool {
+	if len(f.IDs) > 0 {
+		found := false
+		for _, id := range f.IDs {
+			found = found || id == doc.ID
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(doc.Name), q) && !strings.Contains(strings.ToLower(doc.Path), q) {
+			return false
+		}
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(doc.Path, f.PathPrefix) {
+		return false
+	}
+	if (!f.IngestedAfter.IsZero() || !f.IngestedBefore.IsZero()) && doc.IngestedAt.IsZero() {
+		return false // Unknown ingest time matches no time range
+	}
+	if !f.IngestedAfter.IsZero() && doc.IngestedAt.Before(f.IngestedAfter) {
+		return false
+	}
+	if !f.IngestedBefore.IsZero() && !doc.IngestedAt.Before(f.IngestedBefore) {
+		return false
+	}
+	for _, c := range f.Metadata {
+		if !c.Matches(doc.Metadata) {
+			return false
+		}
+	}
+	return f.Expr == nil || f.Expr.Matches(doc)
+}
+
+// Page selects part of a listing.
+type Page struct {
+	Limit  int // Items per page; 0 means all
+	Offset int // Items to skip
+}
+
+// DocumentPage is one page of a document listing.
+type DocumentPage struct {
+	Documents []DocumentInfo
+	Total     int // Documents matching the filter across all pages
 }
 
 // Chunk represents a piece of a document for embedding.
@@ -21,31 +146,161 @@ type Chunk struct {
 	ID         string
 	DocumentID string
 	Content    string
-	Index      int      // Position in document
+	Index      int       // Position in document
 	Embedding  []float32 // Vector representation (populated by adapter)
+	Hash       string    // SHA-256 of Content; stores skip rewriting unchanged chunks
+	Page       int       // Page of a paginated source, such as a PDF, it comes from; 0 when unknown
 }
 
 // QueryResult represents a search result with relevance.
 type QueryResult struct {
 	Chunk      Chunk
-	Score      float64 // Similarity score
-	SourceDoc  string  // Document name for citation
+	Score      float64    // Similarity score
+	SourceDoc  string     // Document name for citation
+	SourcePath string     // Source file path, when the store's registry records one
+	Collection string     // Collection searched, set by federated searches
+	Provenance Provenance // How the source document's text was derived, for citation
+	IngestedAt time.Time  // When the source document was ingested, when the registry records it
+}
+
+// Citation names the result's source for an answer to cite, with the
+// page its chunk comes from when known, such as "report.pdf, p. 12".
+func (r QueryResult) Citation() string {
+	if r.Chunk.Page > 0 {
+		return r.SourceDoc + ", p. " + strconv.Itoa(r.Chunk.Page)
+	}
+	return r.SourceDoc
+}
+
+// Snippet is a short preview of a chunk around its best match.
+type Snippet struct {
+	Text       string
+	Highlights []Highlight // Query term matches in Text, in order
+}
+
+// Highlight marks Text[Start:End], in bytes.
+type Highlight struct {
+	Start int
+	End   int
+}
+
+// SearchOptions narrows and pages a search.
+type SearchOptions struct {
+	Limit       int                 // Results per page; 0 means the configured topK
+	Offset      int                 // Ranked results to skip
+	MinScore    float64             // Drop results scoring below this; 0 means the configured default
+	DocumentIDs []string            // Search only these documents; empty means all
+	SessionID   string              // Also search documents attached to this session
+	Metadata    []MetadataCondition // Search only documents whose metadata matches
+	Filter      FilterExpr          // Search only documents matching this expression
+	Collections []CollectionWeight  // Search these together instead of the given collection
+}
+
+// CollectionWeight names a collection of a federated search and scales
+// its scores, so one corpus can outrank another. Zero weighs 1.
+type CollectionWeight struct {
+	Name   string
+	Weight float64
 }
 
 // ChatMessage represents a conversation turn.
 type ChatMessage struct {
-	Role    string // "user" or "assistant"
+	Role    string // "system", "user" or "assistant"
 	Content string
 }
 
 // ChatRequest represents a query with conversation context.
 type ChatRequest struct {
-	Query   string
-	History []ChatMessage
+	Query       string
+	History     []ChatMessage
+	Collection  string              // Corpus to search; empty means DefaultCollection
+	Collections []CollectionWeight  // Search these together instead of Collection
+	MinScore    float64             // Drop context scoring below this; 0 means the configured default
+	DocumentIDs []string            // Answer only from these documents; empty means all
+	SessionID   string              // Also answer from documents attached to this session
+	Metadata    []MetadataCondition // Answer only from documents whose metadata matches
+	Filter      FilterExpr          // Answer only from documents matching this expression
+	Overrides   LLMOverrides
+}
+
+// LLMOverrides adjusts generation for a single request. Zero values keep
+// the server configuration; the query usecase enforces an allowlist.
+type LLMOverrides struct {
+	Model          string
+	Temperature    *float64
+	PromptTemplate string // text/template over .Context, .Question and .History, see QueryUseCase.SetPromptTemplate
+	Seed           *int64 // Sampling seed; with Deterministic, the configured seed when nil
+	Deterministic  bool   // Temperature 0, a pinned seed and tie-broken retrieval order
+	TopP           *float64
+	TopK           *int
+	NumCtx         *int // Context window in tokens
+	RepeatPenalty  *float64
+	Stop           []string // Sequences that end the answer, such as "\nQuestion:"
+	NumPredict     *int     // Most tokens the answer may take
+}
+
+// AnswerParams records the settings an answer was produced with, so an
+// eval run or bug report can reproduce it.
+type AnswerParams struct {
+	Deterministic  bool
+	Seed           *int64   // nil when the LLM picked its own
+	Temperature    *float64 // nil for the LLM's configured temperature
+	TopP           *float64 // nil, like the settings below, for the LLM's configured value
+	SamplingTopK   *int     // LLM top_k; TopK is the number of passages retrieved
+	NumCtx         *int
+	RepeatPenalty  *float64
+	Stop           []string
+	NumPredict     *int
+	Model          string // LLM model override, or the fallback's model; empty for the configured model
+	Fallback       string // Fallback backend that answered because the LLM failed; empty when it did not
+	PromptTemplate string // Prompt template override; empty for the built-in prompt
+	EmbeddingModel string // Named embedding model; empty for the default embedder
+	Collection     string
+	Collections    []CollectionWeight // Collections of a federated query, searched instead of Collection
+	TopK           int
+	MinScore       float64 // Cutoff applied, request or configured
+	Hybrid         bool
 }
 
 // ChatResponse represents the LLM's answer with sources.
 type ChatResponse struct {
-	Answer  string
-	Sources []QueryResult
+	Answer     string
+	Sources    []QueryResult
+	FollowUps  []string // Suggested next questions; empty unless enabled
+	Extractive bool     // Answer quotes the sources because the LLM timed out
+	Citations  []int    // Sources cited by [n] markers in Answer, numbered from 1; empty unless enabled
+	Params     AnswerParams
+}
+
+// IngestReport summarizes an ingestion run, so partial failures are
+// reported rather than lost.
+type IngestReport struct {
+	FilesProcessed   int           // Files ingested, including ones already up to date
+	ChunksCreated    int           // Chunks embedded and stored
+	ChunksUnchanged  int           // Chunks skipped because the stored copy matched
+	ChunksFailed     int           // Chunks left out after failing to embed; each is listed in Errors
+	DocumentsEvicted int           // Older documents removed by the retention policy
+	Skipped          []IngestIssue // Files passed over, e.g. unsupported or empty
+	Errors           []IngestIssue // Files that failed to load, embed or store
+	EmbedDuration    time.Duration // Time spent embedding
+	StoreDuration    time.Duration // Time spent writing to the vector store
+	Duration         time.Duration // Whole run
+}
+
+// IngestProgress is a snapshot of a running ingestion, with an estimate
+// of the time left based on the throughput so far.
+type IngestProgress struct {
+	FilesDone       int           // Files ingested, skipped or failed
+	FilesTotal      int           // Files in the run
+	ChunksDone      int           // Chunks created, unchanged or failed
+	ChunksPerSecond float64       // Throughput so far
+	Elapsed         time.Duration // Time since the run started
+	Remaining       time.Duration // Estimated time left; 0 until a file is done or once all are
+	Current         string        // File being ingested; empty between files
+}
+
+// IngestIssue records why a file was skipped or failed.
+type IngestIssue struct {
+	Path   string
+	Reason string
 }
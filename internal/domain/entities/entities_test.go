@@ -86,3 +86,126 @@ func TestChatResponse_WithSources(t *testing.T) {
 		t.Error("sources should not be empty")
 	}
 }
+
+func TestDocumentFilter_Matches(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	doc := DocumentInfo{ID: "doc-1", Name: "Handbook.md", Path: "/docs/handbook.md", IngestedAt: at}
+
+	cases := []struct {
+		filter DocumentFilter
+		want   bool
+	}{
+		{DocumentFilter{}, true},
+		{DocumentFilter{Query: "handbook"}, true},
+		{DocumentFilter{Query: "DOCS/"}, true},
+		{DocumentFilter{Query: "roadmap"}, false},
+		{DocumentFilter{PathPrefix: "/docs/"}, true},
+		{DocumentFilter{PathPrefix: "/notes/"}, false},
+		{DocumentFilter{IngestedAfter: at}, true},
+		{DocumentFilter{IngestedBefore: at}, false},
+		{DocumentFilter{IngestedAfter: at.Add(-time.Hour), IngestedBefore: at.Add(time.Hour)}, true},
+	}
+	for _, tc := range cases {
+		if got := tc.filter.Matches(doc); got != tc.want {
+			t.Errorf("%+v: got %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+
+	if (DocumentFilter{IngestedBefore: at}).Matches(DocumentInfo{ID: "doc-2"}) {
+		t.Error("a document without an ingest time should not match a time range")
+	}
+}
+
+func TestMetadataSchema(t *testing.T) {
+	schema := MetadataSchema{
+		{Name: "published", Type: FieldDate, Required: true},
+		{Name: "pages", Type: FieldNumber},
+		{Name: "kind", Type: FieldEnum, Values: []string{"policy", "memo"}},
+	}
+	if err := schema.Validate(); err != nil {
+		t.Fatalf("valid schema rejected: %v", err)
+	}
+	if err := (MetadataSchema{{Name: "kind", Type: FieldEnum}}).Validate(); err == nil {
+		t.Error("an enum without values should be rejected")
+	}
+
+	meta, err := schema.Normalize(map[string]string{"published": "2024-03-01", "pages": "012.50", "kind": "memo"})
+	if err != nil {
+		t.Fatalf("valid metadata rejected: %v", err)
+	}
+	if meta["published"] != "2024-03-01T00:00:00Z" || meta["pages"] != "12.5" {
+		t.Errorf("values not canonical: %v", meta)
+	}
+	for _, bad := range []map[string]string{
+		{"pages": "3"}, // Missing required field
+		{"published": "March", "pages": "3"},
+		{"published": "2024-03-01", "pages": "many"},
+		{"published": "2024-03-01", "kind": "novel"},
+		{"published": "2024-03-01", "author": "ann"},
+	} {
+		if _, err := schema.Normalize(bad); err == nil {
+			t.Errorf("%v should be rejected", bad)
+		}
+	}
+
+	// Typed comparison: 9 < 12.5 as numbers, though not as text
+	bound, err := schema.Bind([]MetadataCondition{
+		{Field: "pages", Min: "9"},
+		{Field: "published", Max: "2024-06-30"},
+		{Field: "kind", In: []string{"policy", "memo"}},
+	})
+	if err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+	filter := DocumentFilter{Metadata: bound}
+	if !filter.Matches(DocumentInfo{Metadata: meta}) {
+		t.Errorf("%+v should match %v", bound, meta)
+	}
+	if filter.Matches(DocumentInfo{}) {
+		t.Error("a document without the fields should not match")
+	}
+	if _, err := schema.Bind([]MetadataCondition{{Field: "kind", Min: "a"}}); err == nil {
+		t.Error("ranges over enums should be rejected")
+	}
+	if _, err := schema.Bind([]MetadataCondition{{Field: "pages", In: []string{"x"}}}); err == nil {
+		t.Error("non-numeric values for a number field should be rejected")
+	}
+}
+
+func TestMetadataSchema_BindFilter(t *testing.T) {
+	schema := MetadataSchema{
+		{Name: "pages", Type: FieldNumber},
+		{Name: "kind", Type: FieldEnum, Values: []string{"policy", "memo"}},
+	}
+	expr := FilterAnd{
+		FilterTerm{Field: "pages", Op: FilterGreater, Value: "9.0"},
+		FilterNot{Expr: FilterTerm{Field: "kind", Value: "memo"}},
+		FilterTerm{Field: FilterFieldDoc, Value: "hand*"},
+	}
+	bound, err := schema.BindFilter(expr)
+	if err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+	if got := bound.String(); got != "(pages>9 AND NOT kind:memo AND doc:hand*)" {
+		t.Errorf("unexpected bound filter %s", got)
+	}
+
+	// 12 > 9 only when compared as numbers
+	doc := DocumentInfo{Name: "handbook", Metadata: map[string]string{"pages": "12", "kind": "policy"}}
+	if !(DocumentFilter{Expr: bound}).Matches(doc) {
+		t.Errorf("%s should match %+v", bound, doc)
+	}
+	if (DocumentFilter{Expr: expr}).Matches(doc) {
+		t.Errorf("unbound %s compares as text and should not match", expr)
+	}
+
+	for _, bad := range []FilterExpr{
+		FilterTerm{Field: "kind", Op: FilterLess, Value: "policy"},
+		FilterOr{FilterTerm{Field: "pages", Value: "many"}},
+		FilterTerm{Field: "author", Value: "ann"},
+	} {
+		if _, err := schema.BindFilter(bad); err == nil {
+			t.Errorf("%s should be rejected", bad)
+		}
+	}
+}
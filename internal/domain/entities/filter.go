@@ -0,0 +1,269 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fields a filter term can name besides metadata fields.
+const (
+	FilterFieldDoc      = "doc"      // Document name or ID
+	FilterFieldPath     = "path"     // Source path
+	FilterFieldIngested = "ingested" // Ingest time
+)
+
+// FilterExpr is a boolean expression over documents, as parsed from the
+// filter syntax by package filter. It is one of FilterAnd, FilterOr,
+// FilterNot and FilterTerm; String returns it in that syntax.
+type FilterExpr interface {
+	Matches(doc DocumentInfo) bool
+	String() string
+}
+
+// FilterOp compares a field with a term's value.
+type FilterOp int
+
+const (
+	FilterEqual        FilterOp = iota // field:value, with * matching any text
+	FilterLess                         // field<value
+	FilterLessEqual                    // field<=value
+	FilterGreater                      // field>value
+	FilterGreaterEqual                 // field>=value
+)
+
+// String returns the operator as written in filters.
+func (op FilterOp) String() string {
+	switch op {
+	case FilterLess:
+		return "<"
+	case FilterLessEqual:
+		return "<="
+	case FilterGreater:
+		return ">"
+	case FilterGreaterEqual:
+		return ">="
+	default:
+		return ":"
+	}
+}
+
+// FilterAnd matches documents matching every operand.
+type FilterAnd []FilterExpr
+
+// FilterOr matches documents matching any operand.
+type FilterOr []FilterExpr
+
+// FilterNot matches documents its operand does not match.
+type FilterNot struct {
+	Expr FilterExpr
+}
+
+// FilterTerm compares one field of a document with a value.
+//
+// doc and path match *-wildcard patterns, case-sensitively; doc matches
+// the name or the ID. ingested compares the ingest time with a date or
+// RFC 3339 timestamp, where a date stands for its whole UTC day, so
+// ingested>2024-01-01 starts on January 2. Any other field is a
+// metadata field: documents without it never match, patterns match its
+// text, and comparisons follow Type.
+type FilterTerm struct {
+	Field string
+	Op    FilterOp
+	Value string
+	Type  FieldType // Metadata field type; set by MetadataSchema.BindFilter
+}
+
+// Matches reports whether doc matches every operand.
+func (e FilterAnd) Matches(doc DocumentInfo) bool {
+	for _, x := range e {
+		if !x.Matches(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e FilterAnd) String() string { return joinFilters(e, " AND ") }
+
+// Matches reports whether doc matches any operand.
+func (e FilterOr) Matches(doc DocumentInfo) bool {
+	for _, x := range e {
+		if x.Matches(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e FilterOr) String() string { return joinFilters(e, " OR ") }
+
+// Matches reports whether doc does not match the operand.
+func (e FilterNot) Matches(doc DocumentInfo) bool { return !e.Expr.Matches(doc) }
+
+func (e FilterNot) String() string { return "NOT " + e.Expr.String() }
+
+func joinFilters(exprs []FilterExpr, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, x := range exprs {
+		parts[i] = x.String()
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+// Matches reports whether doc's field compares with the value as the
+// operator requires.
+func (t FilterTerm) Matches(doc DocumentInfo) bool {
+	switch t.Field {
+	case FilterFieldDoc:
+		return wildcardMatch(t.Value, doc.Name) || wildcardMatch(t.Value, doc.ID)
+	case FilterFieldPath:
+		return wildcardMatch(t.Value, doc.Path)
+	case FilterFieldIngested:
+		if doc.IngestedAt.IsZero() {
+			return false // Unknown ingest time matches no time range
+		}
+		from, to, err := FilterTimeRange(t.Value)
+		if err != nil {
+			return false
+		}
+		at := doc.IngestedAt
+		switch t.Op {
+		case FilterLess:
+			return at.Before(from)
+		case FilterLessEqual:
+			return !at.After(to)
+		case FilterGreater:
+			return at.After(to)
+		case FilterGreaterEqual:
+			return !at.Before(from)
+		default:
+			return !at.Before(from) && !at.After(to)
+		}
+	}
+
+	value, ok := doc.Metadata[t.Field]
+	if !ok {
+		return false
+	}
+	if t.Op == FilterEqual && strings.Contains(t.Value, "*") {
+		return wildcardMatch(t.Value, value)
+	}
+	c := compareMetadata(value, t.Value, t.Type)
+	switch t.Op {
+	case FilterLess:
+		return c < 0
+	case FilterLessEqual:
+		return c <= 0
+	case FilterGreater:
+		return c > 0
+	case FilterGreaterEqual:
+		return c >= 0
+	default:
+		return c == 0
+	}
+}
+
+// String returns the term in filter syntax, quoting the value when
+// needed.
+func (t FilterTerm) String() string {
+	value := t.Value
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"()\\") {
+		value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+	return t.Field + t.Op.String() + value
+}
+
+// FilterTimeRange returns the first and last instant a date or RFC 3339
+// timestamp stands for: a whole UTC day for a date, a single instant
+// for a timestamp.
+func FilterTimeRange(value string) (from, to time.Time, err error) {
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return day, day.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%q is not a date or RFC 3339 timestamp", value)
+	}
+	return at, at, nil
+}
+
+// wildcardMatch reports whether s matches pattern, in which * matches
+// any text, including none, and every other character only itself.
+func wildcardMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
+
+// BindFilter checks an expression's metadata terms against the schema,
+// setting each one's type and putting its value in canonical form.
+// Patterns are kept as written, and comparisons are rejected on enum
+// fields. Without a schema metadata terms compare as text.
+func (s MetadataSchema) BindFilter(expr FilterExpr) (FilterExpr, error) {
+	switch e := expr.(type) {
+	case nil:
+		return nil, nil
+	case FilterAnd:
+		return s.bindFilters(e, func(xs []FilterExpr) FilterExpr { return FilterAnd(xs) })
+	case FilterOr:
+		return s.bindFilters(e, func(xs []FilterExpr) FilterExpr { return FilterOr(xs) })
+	case FilterNot:
+		x, err := s.BindFilter(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return FilterNot{Expr: x}, nil
+	case FilterTerm:
+		switch e.Field {
+		case FilterFieldDoc, FilterFieldPath, FilterFieldIngested:
+			return e, nil
+		}
+		f := MetadataField{Name: e.Field}
+		if len(s) > 0 {
+			var ok bool
+			if f, ok = s.Field(e.Field); !ok {
+				return nil, fmt.Errorf("undeclared metadata field %q", e.Field)
+			}
+		}
+		if f.Type == FieldEnum && e.Op != FilterEqual {
+			return nil, fmt.Errorf("enum field %q cannot be compared by order", e.Field)
+		}
+		e.Type = f.Type
+		if e.Op != FilterEqual || !strings.Contains(e.Value, "*") {
+			v, err := f.normalize(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			e.Value = v
+		}
+		return e, nil
+	default:
+		return nil, fmt.Errorf("unknown filter expression %T", expr)
+	}
+}
+
+func (s MetadataSchema) bindFilters(exprs []FilterExpr, build func([]FilterExpr) FilterExpr) (FilterExpr, error) {
+	bound := make([]FilterExpr, len(exprs))
+	for i, x := range exprs {
+		var err error
+		if bound[i], err = s.BindFilter(x); err != nil {
+			return nil, err
+		}
+	}
+	return build(bound), nil
+}
@@ -0,0 +1,245 @@
+package entities
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType is the type of a declared metadata field. It decides how
+// values are validated at ingest and compared in filters.
+type FieldType int
+
+const (
+	FieldString FieldType = iota // Free text, compared as text
+	FieldNumber                  // Decimal number
+	FieldDate                    // Date (2006-01-02) or RFC 3339 timestamp
+	FieldEnum                    // One of the field's declared values
+)
+
+// String returns the type's name as used in schemas.
+func (t FieldType) String() string {
+	switch t {
+	case FieldNumber:
+		return "number"
+	case FieldDate:
+		return "date"
+	case FieldEnum:
+		return "enum"
+	default:
+		return "string"
+	}
+}
+
+// MetadataField declares a metadata field of a collection.
+type MetadataField struct {
+	Name     string
+	Type     FieldType
+	Values   []string // Allowed values of an enum
+	Required bool     // Documents without the field are rejected
+}
+
+// MetadataSchema declares the metadata fields documents of a collection
+// may carry. Documents ingested into a collection with a schema may
+// only use its fields; without one, any field holds free text.
+type MetadataSchema []MetadataField
+
+// Field returns the declared field with the given name.
+func (s MetadataSchema) Field(name string) (MetadataField, bool) {
+	for _, f := range s {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return MetadataField{}, false
+}
+
+// Validate checks that the schema itself is well formed.
+func (s MetadataSchema) Validate() error {
+	seen := make(map[string]bool, len(s))
+	for _, f := range s {
+		if f.Name == "" {
+			return fmt.Errorf("metadata field without a name")
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("metadata field %q declared twice", f.Name)
+		}
+		seen[f.Name] = true
+		if f.Type == FieldEnum && len(f.Values) == 0 {
+			return fmt.Errorf("enum field %q has no values", f.Name)
+		}
+	}
+	return nil
+}
+
+// Normalize validates a document's metadata against the schema and
+// returns it with numbers and dates in canonical form, so stored values
+// compare consistently. A nil schema accepts any metadata as is.
+func (s MetadataSchema) Normalize(metadata map[string]string) (map[string]string, error) {
+	if len(s) == 0 {
+		return metadata, nil
+	}
+	out := make(map[string]string, len(metadata))
+	for name, value := range metadata {
+		f, ok := s.Field(name)
+		if !ok {
+			return nil, fmt.Errorf("undeclared metadata field %q", name)
+		}
+		v, err := f.normalize(value)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = v
+	}
+	for _, f := range s {
+		if _, ok := out[f.Name]; f.Required && !ok {
+			return nil, fmt.Errorf("missing required metadata field %q", f.Name)
+		}
+	}
+	return out, nil
+}
+
+// normalize checks that value fits the field and returns its canonical
+// form.
+func (f MetadataField) normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch f.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("metadata field %q: %q is not a number", f.Name, value)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case FieldDate:
+		t, err := parseMetadataDate(value)
+		if err != nil {
+			return "", fmt.Errorf("metadata field %q: %q is not a date", f.Name, value)
+		}
+		return t.Format(time.RFC3339), nil
+	case FieldEnum:
+		for _, allowed := range f.Values {
+			if value == allowed {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("metadata field %q: %q is not one of %s", f.Name, value, strings.Join(f.Values, ", "))
+	default:
+		return value, nil
+	}
+}
+
+// parseMetadataDate reads a date or an RFC 3339 timestamp.
+func parseMetadataDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.UTC(), err
+}
+
+// MetadataCondition restricts one metadata field. A document matches
+// when it has the field and its value is in In (if set) and within
+// [Min, Max] (where set). Type decides how values compare; Bind sets it
+// from the collection's schema.
+type MetadataCondition struct {
+	Field string
+	In    []string // Allowed values; empty allows any
+	Min   string   // Inclusive lower bound; empty means none
+	Max   string   // Inclusive upper bound; empty means none
+	Type  FieldType
+}
+
+// Bind checks conditions against the schema, setting each one's type
+// and putting its values in canonical form. Ranges are rejected on enum
+// fields, whose values have no order. Without a schema conditions
+// compare as text.
+func (s MetadataSchema) Bind(conditions []MetadataCondition) ([]MetadataCondition, error) {
+	bound := make([]MetadataCondition, len(conditions))
+	for i, c := range conditions {
+		if c.Field == "" {
+			return nil, fmt.Errorf("metadata condition without a field")
+		}
+		f := MetadataField{Name: c.Field}
+		if len(s) > 0 {
+			var ok bool
+			if f, ok = s.Field(c.Field); !ok {
+				return nil, fmt.Errorf("undeclared metadata field %q", c.Field)
+			}
+		}
+		if f.Type == FieldEnum && (c.Min != "" || c.Max != "") {
+			return nil, fmt.Errorf("enum field %q cannot be compared by range", c.Field)
+		}
+
+		b := MetadataCondition{Field: c.Field, Type: f.Type}
+		var err error
+		for _, v := range c.In {
+			if v, err = f.normalize(v); err != nil {
+				return nil, err
+			}
+			b.In = append(b.In, v)
+		}
+		if c.Min != "" {
+			if b.Min, err = f.normalize(c.Min); err != nil {
+				return nil, err
+			}
+		}
+		if c.Max != "" {
+			if b.Max, err = f.normalize(c.Max); err != nil {
+				return nil, err
+			}
+		}
+		bound[i] = b
+	}
+	return bound, nil
+}
+
+// Matches reports whether a document's metadata satisfies the condition.
+func (c MetadataCondition) Matches(metadata map[string]string) bool {
+	value, ok := metadata[c.Field]
+	if !ok {
+		return false
+	}
+	if len(c.In) > 0 {
+		found := false
+		for _, v := range c.In {
+			found = found || compareMetadata(value, v, c.Type) == 0
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.Min != "" && compareMetadata(value, c.Min, c.Type) < 0 {
+		return false
+	}
+	if c.Max != "" && compareMetadata(value, c.Max, c.Type) > 0 {
+		return false
+	}
+	return true
+}
+
+// compareMetadata orders two values of a field type. Values that fail
+// to parse compare as text.
+func compareMetadata(a, b string, t FieldType) int {
+	switch t {
+	case FieldNumber:
+		x, errA := strconv.ParseFloat(a, 64)
+		y, errB := strconv.ParseFloat(b, 64)
+		if errA == nil && errB == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	case FieldDate:
+		x, errA := parseMetadataDate(a)
+		y, errB := parseMetadataDate(b)
+		if errA == nil && errB == nil {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,56 @@
+package entities
+
+import "strings"
+
+// Kinds of machine transformation a document's text may have gone
+// through. Other kinds may be recorded as well.
+const (
+	TransformOCR           = "ocr"
+	TransformTranscription = "transcription"
+	TransformSummarization = "summarization"
+	TransformTranslation   = "translation"
+	TransformCaptioning    = "captioning"
+)
+
+// Transformation is one machine step between a document's source and
+// the text that was ingested, such as OCR of a scan or the translation
+// of that OCR output.
+type Transformation struct {
+	Kind   string // One of the Transform kinds, or another name
+	Tool   string // Engine or model that performed it, e.g. "tesseract 5.3"
+	Detail string // Optional specifics, e.g. "de to en"
+}
+
+// String describes the step, as in "translation (nllb-200, de to en)".
+func (t Transformation) String() string {
+	var details []string
+	for _, s := range []string{t.Tool, t.Detail} {
+		if s != "" {
+			details = append(details, s)
+		}
+	}
+	if len(details) == 0 {
+		return t.Kind
+	}
+	return t.Kind + " (" + strings.Join(details, ", ") + ")"
+}
+
+// Provenance is the chain of transformations that produced a document's
+// text, from the source onwards. It is empty for text taken from the
+// source as is.
+type Provenance []Transformation
+
+// MachineDerived reports whether the text did not come from the source
+// as is.
+func (p Provenance) MachineDerived() bool {
+	return len(p) > 0
+}
+
+// String describes the chain, as in "ocr (tesseract) -> translation".
+func (p Provenance) String() string {
+	steps := make([]string, len(p))
+	for i, t := range p {
+		steps[i] = t.String()
+	}
+	return strings.Join(steps, " -> ")
+}
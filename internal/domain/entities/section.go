@@ -0,0 +1,33 @@
+package entities
+
+import "strings"
+
+// Section is a part of a document that answers cite on its own, such as
+// a slide. Chunks never span two sections, and each chunk of a labeled
+// section starts with its label in brackets, so the label reaches the
+// prompt and the cited passage whichever store keeps the chunk.
+type Section struct {
+	Label   string // Such as "Slide 3: Roadmap"; empty leaves the chunks unlabeled
+	Content string
+	Records bool // Content holds one record per line, such as a spreadsheet row, which chunks keep whole
+	Page    int  // Page of a paginated source, such as a PDF, it comes from; 0 when unknown
+}
+
+// SectionHeader is the line that starts each chunk of a section
+// labeled label, empty when it has no label.
+func SectionHeader(label string) string {
+	if label == "" {
+		return ""
+	}
+	return "[" + label + "]\n"
+}
+
+// JoinSections renders sections as a document's content: each under its
+// header, separated by blank lines.
+func JoinSections(sections []Section) string {
+	parts := make([]string, 0, len(sections))
+	for _, s := range sections {
+		parts = append(parts, SectionHeader(s.Label)+strings.TrimSpace(s.Content))
+	}
+	return strings.Join(parts, "\n\n")
+}
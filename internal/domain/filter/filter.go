@@ -0,0 +1,291 @@
+// Package filter parses document filter expressions such as
+//
+//	tag:policy AND ingested>2024-01-01 AND doc:"handbook*"
+//
+// into an entities.FilterExpr. A term is a field, an operator (: for
+// equality or a * pattern, <, <=, >, >=) and a value, quoted when it
+// holds spaces or parentheses. Terms combine with AND, OR and NOT
+// (upper case) and parentheses; AND binds tighter than OR, and terms
+// side by side are ANDed. See entities.FilterTerm for what each field
+// matches.
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// maxDepth bounds nesting, so hostile input cannot exhaust the stack.
+const maxDepth = 32
+
+// SyntaxError reports a malformed expression.
+type SyntaxError struct {
+	Offset int // Byte offset of the problem in the expression
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Parse parses a filter expression. Empty expressions are rejected.
+func Parse(expr string) (entities.FilterExpr, error) {
+	p := &parser{src: expr}
+	p.next()
+	if p.tok.kind == tokEOF {
+		return nil, &SyntaxError{Offset: 0, Msg: "empty expression"}
+	}
+	e, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokError
+)
+
+type token struct {
+	kind tokenKind
+	text string // Word, unquoted string, operator or error message
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+type parser struct {
+	src string
+	off int
+	tok token
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Offset: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// next reads the following token into p.tok.
+func (p *parser) next() {
+	for p.off < len(p.src) && unicode.IsSpace(rune(p.src[p.off])) {
+		p.off++
+	}
+	start := p.off
+	if p.off == len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	switch c := p.src[p.off]; {
+	case c == '(':
+		p.off++
+		p.tok = token{kind: tokLParen, text: "(", pos: start}
+	case c == ')':
+		p.off++
+		p.tok = token{kind: tokRParen, text: ")", pos: start}
+	case c == ':' || c == '<' || c == '>':
+		p.off++
+		if c != ':' && p.off < len(p.src) && p.src[p.off] == '=' {
+			p.off++
+		}
+		p.tok = token{kind: tokOp, text: p.src[start:p.off], pos: start}
+	case c == '"':
+		p.tok = p.readString(start)
+	default:
+		for p.off < len(p.src) && !strings.ContainsRune(" \t\r\n():<>\"", rune(p.src[p.off])) {
+			p.off++
+		}
+		word := p.src[start:p.off]
+		kind := tokWord
+		switch word {
+		case "AND":
+			kind = tokAnd
+		case "OR":
+			kind = tokOr
+		case "NOT":
+			kind = tokNot
+		}
+		p.tok = token{kind: kind, text: word, pos: start}
+	}
+}
+
+// nextValue reads the value after an operator into p.tok. Unquoted
+// values run to the next space or parenthesis, so they may hold colons
+// (as timestamps do) and the words AND, OR and NOT.
+func (p *parser) nextValue() {
+	for p.off < len(p.src) && unicode.IsSpace(rune(p.src[p.off])) {
+		p.off++
+	}
+	start := p.off
+	if p.off == len(p.src) || strings.ContainsRune("()\"", rune(p.src[p.off])) {
+		p.next()
+		return
+	}
+	for p.off < len(p.src) && !unicode.IsSpace(rune(p.src[p.off])) && !strings.ContainsRune("()", rune(p.src[p.off])) {
+		p.off++
+	}
+	p.tok = token{kind: tokWord, text: p.src[start:p.off], pos: start}
+}
+
+// readString reads a double-quoted string with backslash escapes.
+func (p *parser) readString(start int) token {
+	var b strings.Builder
+	for p.off++; p.off < len(p.src); p.off++ {
+		switch c := p.src[p.off]; c {
+		case '"':
+			p.off++
+			return token{kind: tokString, text: b.String(), pos: start}
+		case '\\':
+			if p.off+1 < len(p.src) {
+				p.off++
+				c = p.src[p.off]
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return token{kind: tokError, text: "unterminated string", pos: start}
+}
+
+// parseOr parses operands separated by OR.
+func (p *parser) parseOr(depth int) (entities.FilterExpr, error) {
+	var operands entities.FilterOr
+	for {
+		e, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, e)
+		if p.tok.kind != tokOr {
+			break
+		}
+		p.next()
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return operands, nil
+}
+
+// parseAnd parses operands separated by AND or written side by side.
+func (p *parser) parseAnd(depth int) (entities.FilterExpr, error) {
+	var operands entities.FilterAnd
+	for {
+		e, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, e)
+		if p.tok.kind == tokAnd {
+			p.next()
+			continue
+		}
+		if p.tok.kind != tokWord && p.tok.kind != tokNot && p.tok.kind != tokLParen {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return operands, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a term.
+func (p *parser) parseUnary(depth int) (entities.FilterExpr, error) {
+	if depth > maxDepth {
+		return nil, p.errorf("expression nested too deeply")
+	}
+	switch p.tok.kind {
+	case tokNot:
+		p.next()
+		e, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return entities.FilterNot{Expr: e}, nil
+	case tokLParen:
+		p.next()
+		e, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ) but found %s", p.tok)
+		}
+		p.next()
+		return e, nil
+	case tokWord:
+		return p.parseTerm()
+	case tokError:
+		return nil, p.errorf("%s", p.tok.text)
+	default:
+		return nil, p.errorf("expected a term but found %s", p.tok)
+	}
+}
+
+// parseTerm parses field, operator and value.
+func (p *parser) parseTerm() (entities.FilterExpr, error) {
+	field := p.tok
+	p.next()
+	if p.tok.kind != tokOp {
+		return nil, p.errorf("expected an operator after %s", field)
+	}
+	op := map[string]entities.FilterOp{
+		":":  entities.FilterEqual,
+		"<":  entities.FilterLess,
+		"<=": entities.FilterLessEqual,
+		">":  entities.FilterGreater,
+		">=": entities.FilterGreaterEqual,
+	}[p.tok.text]
+	p.nextValue()
+
+	value := p.tok
+	switch value.kind {
+	case tokWord, tokString:
+	case tokError:
+		return nil, p.errorf("%s", value.text)
+	default:
+		return nil, p.errorf("expected a value after %s but found %s", field, value)
+	}
+	term := entities.FilterTerm{Field: field.text, Op: op, Value: value.text}
+
+	switch term.Field {
+	case entities.FilterFieldDoc, entities.FilterFieldPath:
+		if term.Op != entities.FilterEqual {
+			return nil, p.errorf("%s only supports :", term.Field)
+		}
+	case entities.FilterFieldIngested:
+		if _, _, err := entities.FilterTimeRange(term.Value); err != nil {
+			return nil, p.errorf("%v", err)
+		}
+	}
+	p.next()
+	return term, nil
+}
@@ -0,0 +1,101 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`tag:policy`, `tag:policy`},
+		{`tag:policy AND ingested>2024-01-01 AND doc:"handbook*"`, `(tag:policy AND ingested>2024-01-01 AND doc:handbook*)`},
+		{`tag:a OR tag:b tag:c`, `(tag:a OR (tag:b AND tag:c))`},
+		{`NOT (tag:a OR tag:b)`, `NOT (tag:a OR tag:b)`},
+		{`pages >= 10 year<2020`, `(pages>=10 AND year<2020)`},
+		{`ingested<=2024-03-01T12:00:00Z`, `ingested<=2024-03-01T12:00:00Z`},
+		{`author:"Ada \"the\" Countess"`, `author:"Ada \"the\" Countess"`},
+		{`tag:OR`, `tag:OR`},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+		// The printed form parses back to the same expression
+		again, err := Parse(got.String())
+		if err != nil || again.String() != got.String() {
+			t.Errorf("round trip of %s: %v, %v", got, again, err)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`tag`,
+		`tag:`,
+		`(tag:a`,
+		`tag:a)`,
+		`tag:a AND`,
+		`doc>handbook`,
+		`ingested>yesterday`,
+		`tag:"open`,
+		`OR tag:a`,
+	} {
+		_, err := Parse(expr)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q): expected a SyntaxError, got %v", expr, err)
+		}
+	}
+}
+
+func TestParse_Matches(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d.Add(12 * time.Hour)
+	}
+	docs := []entities.DocumentInfo{
+		{ID: "a", Name: "handbook-2024.pdf", Path: "/docs/hr/handbook-2024.pdf", IngestedAt: day("2024-02-01"), Metadata: map[string]string{"tag": "policy"}},
+		{ID: "b", Name: "handbook-2019.pdf", Path: "/docs/hr/handbook-2019.pdf", IngestedAt: day("2024-01-01"), Metadata: map[string]string{"tag": "policy"}},
+		{ID: "c", Name: "minutes.md", Path: "/docs/board/minutes.md", IngestedAt: day("2024-02-01"), Metadata: map[string]string{"tag": "notes"}},
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`tag:policy AND ingested>2024-01-01 AND doc:"handbook*"`, "a"},
+		{`ingested:2024-01-01`, "b"},
+		{`ingested>=2024-01-01 NOT tag:policy`, "c"},
+		{`path:/docs/hr/* OR tag:notes`, "abc"},
+		{`doc:*2019*`, "b"},
+		{`doc:c`, "c"},
+		{`tag:*o*`, "abc"},
+		{`missing:x`, ""},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		var got string
+		for _, doc := range docs {
+			if expr.Matches(doc) {
+				got += doc.ID
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s matched %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,10 @@ package ports
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
 )
@@ -17,9 +21,51 @@ type EmbeddingService interface {
 	Embed(ctx context.Context, text string) ([]float32, error)
 
 	// EmbedBatch generates embeddings for multiple texts efficiently.
+	// When only some texts fail it may return a *BatchEmbedError along
+	// with the embeddings of the others.
 	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
 }
 
+// RoleEmbedder is an optional EmbeddingService capability for models
+// that embed search queries and the passages they are matched against
+// differently, such as nomic-embed-text, bge and e5 with their
+// instruction prefixes ("search_query: " and "search_document: " for
+// nomic). Usecases type-assert for it; Embed and EmbedBatch embed texts
+// as they are.
+type RoleEmbedder interface {
+	// EmbedQueries embeds search queries, as EmbedBatch does.
+	EmbedQueries(ctx context.Context, texts []string) ([][]float32, error)
+
+	// EmbedPassages embeds stored passages such as chunks, as EmbedBatch
+	// does, including partial failures.
+	EmbedPassages(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// BatchEmbedError reports the texts of an EmbedBatch call that failed
+// while the rest were embedded. The embeddings returned with it are nil
+// at the failed indexes.
+type BatchEmbedError struct {
+	Failed map[int]error // Index into texts -> cause
+}
+
+func (e *BatchEmbedError) Error() string {
+	indexes := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return fmt.Sprintf("%d texts failed to embed, first text %d: %v", len(indexes), indexes[0], e.Failed[indexes[0]])
+}
+
+// Unwrap returns the causes for errors.Is and errors.As.
+func (e *BatchEmbedError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, err := range e.Failed {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 // LLMService generates text responses from a language model.
 // Single Responsibility: Only LLM inference, no embedding logic.
 type LLMService interface {
@@ -31,10 +77,93 @@ type LLMService interface {
 	GenerateStream(ctx context.Context, prompt string, context []string) (<-chan StreamToken, error)
 }
 
+// TunableLLM is an optional LLMService capability for per-request
+// generation settings. Usecases type-assert for it.
+type TunableLLM interface {
+	// GenerateWith is Generate with opts applied to this call only.
+	GenerateWith(ctx context.Context, prompt string, context []string, opts GenerateOptions) (string, error)
+
+	// GenerateStreamWith is GenerateStream with opts applied to this call only.
+	GenerateStreamWith(ctx context.Context, prompt string, context []string, opts GenerateOptions) (<-chan StreamToken, error)
+}
+
+// ChatLLM is an optional LLMService capability for chat models that take
+// a system message and the conversation so far as separate messages
+// instead of one concatenated prompt. Usecases type-assert for it.
+type ChatLLM interface {
+	// Chat answers the last message given the ones before it, with opts
+	// applied to this call only.
+	Chat(ctx context.Context, messages []entities.ChatMessage, opts GenerateOptions) (string, error)
+
+	// ChatStream is Chat streamed token by token.
+	ChatStream(ctx context.Context, messages []entities.ChatMessage, opts GenerateOptions) (<-chan StreamToken, error)
+}
+
+// GenerateOptions overrides an adapter's configured generation settings.
+// Zero values keep the configuration.
+type GenerateOptions struct {
+	Model         string
+	Temperature   *float64
+	Seed          *int64   // Sampling seed, for reproducible output
+	TopP          *float64 // Nucleus sampling: the probability mass sampled from
+	TopK          *int     // Sample from this many most likely tokens
+	NumCtx        *int     // Context window in tokens, where the runtime takes one per request
+	RepeatPenalty *float64 // Above 1 discourages repeating tokens
+	Stop          []string // Generation ends before any of these
+	NumPredict    *int     // Most tokens to generate
+}
+
+// Or returns o with its unset settings taken from defaults, such as an
+// adapter's configuration.
+func (o GenerateOptions) Or(defaults GenerateOptions) GenerateOptions {
+	if o.Model == "" {
+		o.Model = defaults.Model
+	}
+	if o.Temperature == nil {
+		o.Temperature = defaults.Temperature
+	}
+	if o.Seed == nil {
+		o.Seed = defaults.Seed
+	}
+	if o.TopP == nil {
+		o.TopP = defaults.TopP
+	}
+	if o.TopK == nil {
+		o.TopK = defaults.TopK
+	}
+	if o.NumCtx == nil {
+		o.NumCtx = defaults.NumCtx
+	}
+	if o.RepeatPenalty == nil {
+		o.RepeatPenalty = defaults.RepeatPenalty
+	}
+	if o.Stop == nil {
+		o.Stop = defaults.Stop
+	}
+	if o.NumPredict == nil {
+		o.NumPredict = defaults.NumPredict
+	}
+	return o
+}
+
+// IsZero reports whether o changes no setting.
+func (o GenerateOptions) IsZero() bool {
+	return o.Model == "" && o.Temperature == nil && o.Seed == nil && o.TopP == nil && o.TopK == nil &&
+		o.NumCtx == nil && o.RepeatPenalty == nil && len(o.Stop) == 0 && o.NumPredict == nil
+}
+
+// Tokenizer counts the tokens a language model reads text as, so
+// prompts can be fitted into its context window.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
 // VectorStore persists and queries document embeddings.
 // Dependency Inversion: Usecases depend on this abstraction, not LanceDB directly.
 type VectorStore interface {
-	// Store saves chunks with their embeddings.
+	// Store saves chunks with their embeddings, upserting by chunk ID
+	// under ConflictVersion: a stored chunk is replaced unless both
+	// copies carry the same Hash. See Upserter for other policies.
 	Store(ctx context.Context, chunks []entities.Chunk) error
 
 	// Search finds the most similar chunks to a query embedding.
@@ -45,6 +174,256 @@ type VectorStore interface {
 
 	// Clear removes all data from the store.
 	Clear(ctx context.Context) error
+
+	// Stats reports the size of the store's contents.
+	Stats(ctx context.Context) (StoreStats, error)
+}
+
+// StoreStats describes a VectorStore's contents. Counts and dimension
+// cover one collection; SizeBytes is the whole store's storage.
+type StoreStats struct {
+	Chunks    int
+	Documents int
+	Dimension int   // Embedding width; 0 when empty
+	SizeBytes int64 // On-disk size; 0 when not persisted or not reported
+}
+
+// EmbeddingModel identifies the model a collection's embeddings come from.
+type EmbeddingModel struct {
+	Name      string // Registered model name; empty for an unnamed embedder
+	Dimension int
+}
+
+// ModelRecorder is an optional VectorStore capability for remembering
+// which embedding model a collection was built with, so queries and
+// ingests using another model can be refused instead of comparing
+// unrelated vectors. Usecases type-assert for it. Clear forgets the
+// record along with the data.
+type ModelRecorder interface {
+	// EmbeddingModel returns the recorded model, the zero value if none.
+	EmbeddingModel(ctx context.Context) (EmbeddingModel, error)
+
+	// RecordEmbeddingModel records the model, replacing any earlier record.
+	RecordEmbeddingModel(ctx context.Context, model EmbeddingModel) error
+}
+
+// CollectionStore is an optional VectorStore capability for hosting several
+// independent corpora (e.g. work notes vs. personal notes) in one store.
+// The embedded VectorStore methods operate on entities.DefaultCollection.
+type CollectionStore interface {
+	VectorStore
+
+	// Collection returns a VectorStore scoped to the named collection.
+	// Collections are created implicitly on first Store.
+	Collection(name string) VectorStore
+
+	// Collections lists collections that hold data; the default
+	// collection is always included.
+	Collections(ctx context.Context) ([]string, error)
+}
+
+// DocumentRegistry is an optional VectorStore capability that records
+// which documents have been ingested. Delete and Clear on the store
+// also remove registry entries.
+type DocumentRegistry interface {
+	// RegisterDocument records or updates a document's metadata.
+	RegisterDocument(ctx context.Context, info entities.DocumentInfo) error
+
+	// ListDocuments returns the documents that have stored chunks and
+	// match filter, ordered by name then ID, cut to page.
+	ListDocuments(ctx context.Context, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error)
+}
+
+// DocumentSearcher is an optional VectorStore capability for scoping a
+// search to chosen documents, e.g. "answer only from handbook.pdf".
+type DocumentSearcher interface {
+	// SearchDocuments is Search restricted to chunks of the given documents.
+	SearchDocuments(ctx context.Context, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error)
+}
+
+// BatchDeleter is an optional VectorStore capability for deleting
+// several documents in one operation, such as a removed directory.
+type BatchDeleter interface {
+	// DeleteMany removes all chunks of the given documents atomically
+	// where the backend allows, so a failure leaves all of them in place.
+	DeleteMany(ctx context.Context, documentIDs []string) error
+}
+
+// ConflictPolicy says what a store does with a chunk whose ID it
+// already holds.
+type ConflictPolicy int
+
+const (
+	// ConflictVersion replaces the stored chunk unless both carry the
+	// same content Hash. Chunks without a hash always replace. Store
+	// uses this policy.
+	ConflictVersion ConflictPolicy = iota
+	// ConflictReplace always overwrites the stored chunk.
+	ConflictReplace
+	// ConflictSkip keeps the stored chunk and drops the new one.
+	ConflictSkip
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ConflictVersion:
+		return "version"
+	case ConflictReplace:
+		return "replace"
+	case ConflictSkip:
+		return "skip"
+	}
+	return fmt.Sprintf("ConflictPolicy(%d)", int(p))
+}
+
+// Upserter is an optional VectorStore capability for storing chunks
+// under an explicit conflict policy.
+type Upserter interface {
+	// Upsert is Store with the given policy for chunks already stored.
+	// A replaced chunk moves to its new DocumentID.
+	Upsert(ctx context.Context, chunks []entities.Chunk, policy ConflictPolicy) error
+}
+
+// ChunkHasher is an optional VectorStore capability for incremental
+// re-ingestion. Stores keep each chunk's Hash, so unchanged chunks need
+// not be embedded again, and Store skips them.
+type ChunkHasher interface {
+	// ChunkHashes returns the stored content hash of each chunk of a
+	// document, keyed by chunk ID.
+	ChunkHashes(ctx context.Context, documentID string) (map[string]string, error)
+}
+
+// ChunkReader is an optional VectorStore capability for reading stored
+// chunks back, such as to re-embed them with another model.
+type ChunkReader interface {
+	// DocumentChunks returns a document's stored chunks in Index order,
+	// without their embeddings.
+	DocumentChunks(ctx context.Context, documentID string) ([]entities.Chunk, error)
+}
+
+// CollectionSwapper is an optional CollectionStore capability for
+// replacing a collection with one prepared alongside it.
+type CollectionSwapper interface {
+	// SwapCollections exchanges the contents of two collections, with
+	// their document registries and recorded models, in one step where
+	// the backend allows, so searches see one collection or the other
+	// and never a mix.
+	SwapCollections(ctx context.Context, a, b string) error
+}
+
+// HybridSearcher is an optional VectorStore capability that combines
+// keyword (BM25) and vector relevance. Usecases type-assert for it.
+type HybridSearcher interface {
+	// HybridSearch ranks chunks by a fusion of lexical match on query
+	// and similarity to embedding.
+	HybridSearch(ctx context.Context, query string, embedding []float32, topK int) ([]entities.QueryResult, error)
+}
+
+// KeywordSearcher is an optional VectorStore capability for lexical-only
+// search. It needs no embedding, so callers can show likely sources
+// before the query has been embedded.
+type KeywordSearcher interface {
+	// KeywordSearch ranks chunks by lexical match on query. Scores are
+	// scaled to 0..1 relative to the best hit.
+	KeywordSearch(ctx context.Context, query string, topK int) ([]entities.QueryResult, error)
+}
+
+// Snapshotter is an optional VectorStore capability for point-in-time
+// backups of the whole store, across all collections.
+type Snapshotter interface {
+	// Backup writes a consistent snapshot to path, replacing any file there.
+	Backup(ctx context.Context, path string) error
+
+	// Restore replaces the store contents with a snapshot from Backup.
+	Restore(ctx context.Context, path string) error
+}
+
+// SnapshotReader is an optional Snapshotter capability for reading a
+// snapshot without restoring it, such as to compare two.
+type SnapshotReader interface {
+	// OpenSnapshot opens a snapshot written by Backup as a store of its
+	// own, with the same optional capabilities. The live store is not
+	// touched.
+	OpenSnapshot(ctx context.Context, path string) (SnapshotStore, error)
+}
+
+// SnapshotStore is a snapshot opened by SnapshotReader. Close releases it.
+type SnapshotStore interface {
+	VectorStore
+	io.Closer
+}
+
+// Maintainer is an optional VectorStore capability for housekeeping of
+// the whole store, across all collections.
+type Maintainer interface {
+	// Maintain removes orphaned index and registry entries, rebuilds
+	// secondary indexes and compacts storage.
+	Maintain(ctx context.Context) (MaintenanceReport, error)
+}
+
+// MaintenanceReport summarizes what a Maintain run changed.
+type MaintenanceReport struct {
+	OrphansRemoved int      // Index and registry entries with no stored chunk
+	IndexesRebuilt []string // Secondary indexes rebuilt, e.g. "keyword", "vector"
+	ReclaimedBytes int64    // Storage returned to the filesystem
+}
+
+// HealthReporter is an optional capability of adapters that talk to a
+// remote backend. The HTTP layer surfaces reports at /api/health and /metrics.
+type HealthReporter interface {
+	// Health returns the current connection state of the backend.
+	Health() BackendHealth
+}
+
+// ModelPreloader is an optional capability of adapters whose backend
+// loads models on demand, such as Ollama, taking seconds on the first
+// request. The HTTP layer can warm them up at startup.
+type ModelPreloader interface {
+	// Preload loads the model into memory and returns once it is ready.
+	Preload(ctx context.Context) error
+}
+
+// ModelLister is an optional capability of adapters whose backend can
+// list the models it serves, for a model picker.
+type ModelLister interface {
+	// Models lists the models the backend serves, sorted.
+	Models(ctx context.Context) ([]string, error)
+
+	// Model returns the model the adapter uses, named as Models names it.
+	Model() string
+}
+
+// ModelPuller is an optional capability of adapters whose backend can
+// download the model they use, such as Ollama, so a missing model is
+// pulled instead of failing the first query with a 404.
+type ModelPuller interface {
+	// ModelInstalled reports whether the model is on the backend.
+	ModelInstalled(ctx context.Context) (bool, error)
+
+	// PullModel downloads the model, calling progress as it goes, and
+	// returns once it is installed.
+	PullModel(ctx context.Context, progress func(PullProgress)) error
+}
+
+// PullProgress is a step of a model download.
+type PullProgress struct {
+	Model     string
+	Status    string // The backend's status, such as "pulling manifest" or "success"
+	Completed int64  // Bytes of the current layer downloaded
+	Total     int64  // Size of the current layer; 0 when it has none
+}
+
+// BackendHealth is a snapshot of a remote backend's connection state.
+type BackendHealth struct {
+	Name                string
+	Healthy             bool
+	ConsecutiveFailures int
+	TotalFailures       uint64
+	Reconnects          uint64
+	LastError           string
+	LastSuccess         time.Time
+	LastFailure         time.Time
+	CircuitOpen         bool // Calls are held back after repeated failures
 }
 
 // DocumentLoader reads and parses documents from various formats.
@@ -59,18 +438,115 @@ type DocumentLoader interface {
 // DocumentParser extracts text from binary document formats (PDF, DOCX, etc).
 // Interface Segregation: Separate from DocumentLoader for different responsibilities.
 type DocumentParser interface {
-	// Parse extracts text content from document bytes.
-	Parse(ctx context.Context, data []byte, filename string) (string, error)
+	// Parse extracts the text of each page from document bytes, in
+	// order, so chunks can cite their page. Pages without text are empty
+	// strings, keeping the numbering; formats without pages return one.
+	Parse(ctx context.Context, data []byte, filename string) ([]string, error)
 
 	// SupportedFormats returns formats this parser handles (e.g., "pdf", "docx").
 	SupportedFormats() []string
 }
 
+// OCREngine recognizes the text of scanned pages, so image-only PDFs
+// and photos of documents are ingested as text.
+type OCREngine interface {
+	// Recognize returns the text of an image, or of every page of a PDF
+	// when filename ends in .pdf.
+	Recognize(ctx context.Context, data []byte, filename string) (string, error)
+
+	// Tool names the engine, as recorded in the provenance of the text
+	// it produced, e.g. "tesseract".
+	Tool() string
+}
+
+// ImageDescriber describes images in words with a multimodal model, so
+// diagrams, charts and photos are ingested as searchable text.
+type ImageDescriber interface {
+	// Describe returns a description of the image in data, named
+	// filename.
+	Describe(ctx context.Context, data []byte, filename string) (string, error)
+
+	// Tool names the model, as recorded in the provenance of the text
+	// it produced, e.g. "llava:latest".
+	Tool() string
+}
+
+// TranscriptSegment is a stretch of speech and when it was said.
+type TranscriptSegment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// Transcriber turns speech into text with timestamps, so recorded
+// talks are ingested as transcripts.
+type Transcriber interface {
+	// Transcribe returns the segments of speech in the audio or video
+	// file at path, in order.
+	Transcribe(ctx context.Context, path string) ([]TranscriptSegment, error)
+
+	// Tool names the engine, as recorded in the provenance of the text
+	// it produced, e.g. "whisper.cpp".
+	Tool() string
+}
+
+// CrawlLimits bounds a website crawl. Zero values take the crawler's
+// defaults, except MaxDepth.
+type CrawlLimits struct {
+	MaxDepth int           // Links followed away from the start pages; 0 takes them alone
+	MaxPages int           // Pages fetched at most
+	Domains  []string      // Hosts pages may come from, with their subdomains; the start URL's when empty
+	Delay    time.Duration // Least pause between two requests to a host
+}
+
+// SiteCrawler fetches the pages of a website, so a whole site, such as
+// a product's documentation, is ingested with one request.
+type SiteCrawler interface {
+	// Crawl fetches the pages reachable from start, or listed by it when
+	// it is a sitemap, within limits. It returns the URLs of the pages
+	// in the order they were found, and a loader serving each of them
+	// as a document, or the error fetching it.
+	Crawl(ctx context.Context, start string, limits CrawlLimits) (DocumentLoader, []string, error)
+}
+
+// FeedReader fetches syndication feeds, such as RSS and Atom, so blogs
+// and newsletters are ingested as they post.
+type FeedReader interface {
+	// Fetch reads the feed at url. It returns the paths of its entries,
+	// such as their links, and a loader serving each of them as a
+	// document.
+	Fetch(ctx context.Context, url string) (DocumentLoader, []string, error)
+}
+
+// GitCheckout is a repository ready for ingestion at its current
+// commit.
+type GitCheckout struct {
+	Loader DocumentLoader // Loads the files, labeling each with its path and commit
+	Root   string         // Directory of the working tree, under which all paths lie
+	Head   string         // Commit checked out
+	Paths  []string       // Tracked text files
+}
+
+// GitReader reads Git repositories, so a codebase is ingested as its
+// tracked files and re-ingested as the commits since change them.
+type GitReader interface {
+	// Checkout opens the repository at source, a local directory or a
+	// URL to clone or pull, and lists its tracked text files.
+	Checkout(ctx context.Context, source string) (GitCheckout, error)
+
+	// Changes lists the files of a checkout changed between since and
+	// its HEAD, and the files deleted. ok is false when since is not in
+	// the history, as after a force push, and every file must be read
+	// again.
+	Changes(ctx context.Context, co GitCheckout, since string) (changed, removed []string, ok bool, err error)
+}
+
 // StreamToken represents a single token in a streaming LLM response.
 type StreamToken struct {
-	Content string
-	Done    bool
-	Error   error
+	Content  string
+	Done     bool
+	Error    error
+	Fallback string // Fallback backend streaming instead of the configured LLM; empty from the LLM itself
 }
 
 // FileWatcher monitors a directory for changes.
@@ -95,4 +571,16 @@ const (
 	FileCreated FileOperation = iota
 	FileModified
 	FileDeleted
+	DirectoryDeleted // A subdirectory was removed along with any files in it
 )
+
+// ResourceMonitor samples the host's CPU and memory use.
+type ResourceMonitor interface {
+	Usage(ctx context.Context) (ResourceUsage, error)
+}
+
+// ResourceUsage is a sample of the host's load.
+type ResourceUsage struct {
+	CPU    float64 // Fraction of all cores busy since the previous sample, 0 to 1
+	Memory float64 // Fraction of memory in use, not counting reclaimable caches
+}
@@ -0,0 +1,161 @@
+// Package usecases - budget.go fits retrieved context into the LLM's context window.
+package usecases
+
+import (
+	"regexp"
+	"sort"
+	"unicode"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+const (
+	// defaultAnswerTokens is kept free for the answer when the budget
+	// does not say.
+	defaultAnswerTokens = 512
+
+	// minPassageTokens is the shortest a passage is cut to; with less
+	// room left it is dropped instead.
+	minPassageTokens = 32
+
+	// messageTokens approximates what a chat template adds per message.
+	messageTokens = 4
+)
+
+// truncationMark ends a passage cut to fit.
+const truncationMark = " [...]"
+
+// wordRun matches a run of non-space characters; passages are cut
+// after one.
+var wordRun = regexp.MustCompile(`\S+`)
+
+// ContextBudget fits prompts into the LLM's context window. Runtimes
+// such as Ollama silently drop the start of a prompt that overflows it,
+// which loses the instructions and the best passages first.
+type ContextBudget struct {
+	NumCtx       int             // Context window in tokens; a request's num_ctx takes precedence. 0 disables budgeting
+	AnswerTokens int             // Kept free for the answer; 0 keeps 512
+	Tokenizer    ports.Tokenizer // The LLM's tokenizer; nil estimates counts from the text
+}
+
+// SetContextBudget counts the tokens of every prompt, with its
+// instructions and history, and keeps only the retrieved passages that
+// fit beside them and the answer, best ranked first. The passage that
+// crosses the limit is cut at a word, or dropped when less than a few
+// dozen tokens are left. Answers list only the sources kept, so
+// citation numbers still match. Without a Tokenizer, counts are
+// estimated at about four characters per token, which suits English
+// prose with common BPE vocabularies.
+func (uc *QueryUseCase) SetContextBudget(budget ContextBudget) {
+	uc.budget = budget
+}
+
+// fitContext formats results as context passages for req, keeping those
+// that fit its context window. It returns the kept results and their
+// passages; all of them when no window is known.
+func (uc *QueryUseCase) fitContext(req *entities.ChatRequest, results []entities.QueryResult) ([]entities.QueryResult, []string, error) {
+	contextParts := uc.contextFor(req.Collection, results)
+	numCtx := uc.budget.NumCtx
+	if req.Overrides.NumCtx != nil {
+		numCtx = *req.Overrides.NumCtx
+	}
+	if numCtx <= 0 || len(results) == 0 {
+		return results, contextParts, nil
+	}
+	tok := uc.budget.Tokenizer
+	if tok == nil {
+		tok = estimatedTokens{}
+	}
+	answer := uc.budget.AnswerTokens
+	if answer <= 0 {
+		answer = defaultAnswerTokens
+	}
+	fixed, err := uc.promptTokens(req, tok)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	left := numCtx - answer - fixed
+	separator := tok.CountTokens("\n\n")
+	for i, part := range contextParts {
+		cost := tok.CountTokens(part)
+		if i > 0 {
+			cost += separator
+		}
+		if cost <= left {
+			left -= cost
+			continue
+		}
+		if i > 0 {
+			left -= separator
+		}
+		if left < minPassageTokens {
+			return results[:i], contextParts[:i], nil
+		}
+		if contextParts[i] = truncateTokens(tok, part, left); contextParts[i] == "" {
+			return results[:i], contextParts[:i], nil
+		}
+		return results[:i+1], contextParts[:i+1], nil
+	}
+	return results, contextParts, nil
+}
+
+// promptTokens counts what req's prompt takes without any passages: the
+// instructions, question and, for chat LLMs, the history.
+func (uc *QueryUseCase) promptTokens(req *entities.ChatRequest, tok ports.Tokenizer) (int, error) {
+	prompt, err := uc.buildPrompt(req, nil)
+	if err != nil {
+		return 0, err
+	}
+	if _, chat := uc.llm.(ports.ChatLLM); !chat {
+		return tok.CountTokens(prompt), nil
+	}
+	n := 0
+	for _, m := range uc.chatMessages(req, prompt, nil) {
+		n += tok.CountTokens(m.Content) + messageTokens
+	}
+	return n, nil
+}
+
+// truncateTokens cuts text after its last word that keeps it, with
+// truncationMark, within max tokens.
+func truncateTokens(tok ports.Tokenizer, text string, max int) string {
+	words := wordRun.FindAllStringIndex(text, -1)
+	n := sort.Search(len(words), func(i int) bool {
+		return tok.CountTokens(text[:words[i][1]]+truncationMark) > max
+	})
+	if n == 0 {
+		return ""
+	}
+	return text[:words[n-1][1]] + truncationMark
+}
+
+// estimatedTokens approximates BPE token counts: a token per four
+// characters of each word, rounded up, and one per punctuation mark or
+// ideograph.
+type estimatedTokens struct{}
+
+func (estimatedTokens) CountTokens(text string) int {
+	tokens, run := 0, 0
+	flush := func() {
+		tokens += (run + 3) / 4
+		run = 0
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			run++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
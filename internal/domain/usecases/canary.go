@@ -0,0 +1,435 @@
+// Package usecases - canary.go re-asks known questions to catch regressions.
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+const (
+	// canaryDrift is the word overlap with the previous answer below
+	// which a changed answer counts as a regression.
+	canaryDrift = 0.5
+
+	// canaryExpectedDrop is how far the overlap with a canary's expected
+	// answer may fall from the previous run's before it is a regression.
+	canaryExpectedDrop = 0.1
+)
+
+// Canary is a question asked of a collection after every configuration
+// change, with the answer it got last time. Expected, when set, is a
+// reference answer new answers are also measured against.
+type Canary struct {
+	Collection string    `json:"collection"`
+	Question   string    `json:"question"`
+	Expected   string    `json:"expected,omitempty"`
+	Answer     string    `json:"answer,omitempty"`  // Answer of the last run
+	Sources    []string  `json:"sources,omitempty"` // Source documents of the last answer
+	AnsweredAt time.Time `json:"answered_at,omitempty"`
+}
+
+// Canaries holds canary questions per collection and their last answers,
+// kept in a JSON file when one is given so answers given before a
+// restart with new settings are compared with answers given after it.
+type Canaries struct {
+	mu     sync.Mutex
+	path   string // "" keeps canaries in memory only
+	config string // Deployment settings the usecase cannot see, see SetConfig
+	state  canaryState
+}
+
+// canaryState is the canaries file.
+type canaryState struct {
+	Fingerprint string   `json:"fingerprint,omitempty"` // Settings of the last run
+	Canaries    []Canary `json:"canaries"`
+}
+
+// NewCanaries creates a set of canaries kept in the JSON file at path,
+// loading the file when it exists. An empty path keeps them in memory.
+func NewCanaries(path string) (*Canaries, error) {
+	c := &Canaries{path: path}
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading canaries: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("parsing canaries %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// SetConfig describes settings outside the query usecase that change
+// answers, such as the LLM's model name or a prompt file's contents.
+// A change to it makes RunCanariesIfChanged run.
+func (c *Canaries) SetConfig(config string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = config
+}
+
+// Add registers a canary, replacing one with the same collection and
+// question but keeping its last answer.
+func (c *Canaries) Add(canary Canary) error {
+	canary.Question = strings.TrimSpace(canary.Question)
+	if canary.Question == "" {
+		return fmt.Errorf("canary question is empty")
+	}
+	if canary.Collection == "" {
+		canary.Collection = entities.DefaultCollection
+	}
+	canary.Answer, canary.Sources, canary.AnsweredAt = "", nil, time.Time{}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.find(canary.Collection, canary.Question); i >= 0 {
+		prev := c.state.Canaries[i]
+		canary.Answer, canary.Sources, canary.AnsweredAt = prev.Answer, prev.Sources, prev.AnsweredAt
+		c.state.Canaries[i] = canary
+	} else {
+		c.state.Canaries = append(c.state.Canaries, canary)
+	}
+	return c.save()
+}
+
+// Remove deletes a canary and reports whether it existed.
+func (c *Canaries) Remove(collection, question string) (bool, error) {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.find(collection, strings.TrimSpace(question))
+	if i < 0 {
+		return false, nil
+	}
+	c.state.Canaries = append(c.state.Canaries[:i], c.state.Canaries[i+1:]...)
+	return true, c.save()
+}
+
+// List returns the canaries ordered by collection, then question.
+func (c *Canaries) List() []Canary {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	list := append([]Canary(nil), c.state.Canaries...)
+	c.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Collection != list[j].Collection {
+			return list[i].Collection < list[j].Collection
+		}
+		return list[i].Question < list[j].Question
+	})
+	return list
+}
+
+// find returns the index of a canary, -1 if there is none. Caller must
+// hold c.mu.
+func (c *Canaries) find(collection, question string) int {
+	for i, canary := range c.state.Canaries {
+		if canary.Collection == collection && canary.Question == question {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes the canaries file, replacing it atomically. Caller must
+// hold c.mu.
+func (c *Canaries) save() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding canaries: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating canaries file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing canaries: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing canaries: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("replacing canaries file: %w", err)
+	}
+	return nil
+}
+
+// CanaryReport is the outcome of a canary run.
+type CanaryReport struct {
+	Results []CanaryResult
+}
+
+// CanaryResult compares a canary's new answer with its previous one.
+type CanaryResult struct {
+	Collection     string
+	Question       string
+	Previous       string  // Empty on a canary's first run
+	Answer         string  // Empty when the question failed
+	Err            error   // Why the question failed
+	Similarity     float64 // Word overlap of Answer with Previous, 0..1
+	ExpectedBefore float64 // Word overlap of Previous with Expected; 0 without one
+	ExpectedAfter  float64 // Word overlap of Answer with Expected; 0 without one
+	SourcesAdded   []string
+	SourcesRemoved []string
+	Regressed      bool // Failed, drifted from Previous or moved away from Expected
+}
+
+// Regressions returns the results flagged as regressions.
+func (r CanaryReport) Regressions() []CanaryResult {
+	var regressed []CanaryResult
+	for _, res := range r.Results {
+		if res.Regressed {
+			regressed = append(regressed, res)
+		}
+	}
+	return regressed
+}
+
+// Report renders the run for people: a summary line, then each changed
+// answer with its previous ("-") and new ("+") text.
+func (r CanaryReport) Report() string {
+	var sb strings.Builder
+	changed := 0
+	for _, res := range r.Results {
+		if res.Err != nil || res.Answer != res.Previous {
+			changed++
+		}
+	}
+	fmt.Fprintf(&sb, "%d canaries: %d changed, %d regressed\n", len(r.Results), changed, len(r.Regressions()))
+	for _, res := range r.Results {
+		if res.Err == nil && res.Answer == res.Previous {
+			continue
+		}
+		mark := "~"
+		if res.Regressed {
+			mark = "!"
+		}
+		fmt.Fprintf(&sb, "%s [%s] %s", mark, res.Collection, res.Question)
+		if res.Err != nil {
+			fmt.Fprintf(&sb, ": %v\n", res.Err)
+			continue
+		}
+		if res.Previous == "" {
+			sb.WriteString(": first answer\n")
+		} else {
+			fmt.Fprintf(&sb, ": %.0f%% similar\n", res.Similarity*100)
+			fmt.Fprintf(&sb, "  - %s\n", oneLine(res.Previous))
+		}
+		fmt.Fprintf(&sb, "  + %s\n", oneLine(res.Answer))
+		for _, src := range res.SourcesAdded {
+			fmt.Fprintf(&sb, "  + source %s\n", src)
+		}
+		for _, src := range res.SourcesRemoved {
+			fmt.Fprintf(&sb, "  - source %s\n", src)
+		}
+	}
+	return sb.String()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// SetCanaries sets the canaries RunCanaries asks.
+func (uc *QueryUseCase) SetCanaries(canaries *Canaries) {
+	uc.canaries = canaries
+}
+
+// Canaries returns the canaries set with SetCanaries, nil if none.
+func (uc *QueryUseCase) Canaries() *Canaries {
+	return uc.canaries
+}
+
+// RunCanaries asks every canary question, compares each answer with the
+// previous one and keeps the new answers for the next run. A question
+// that fails is reported and keeps its previous answer.
+func (uc *QueryUseCase) RunCanaries(ctx context.Context) (CanaryReport, error) {
+	var report CanaryReport
+	if uc.canaries == nil {
+		return report, nil
+	}
+	for _, canary := range uc.canaries.List() {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		res := CanaryResult{Collection: canary.Collection, Question: canary.Question, Previous: canary.Answer}
+		resp, err := uc.Query(ctx, &entities.ChatRequest{Query: canary.Question, Collection: canary.Collection})
+		if err != nil {
+			res.Err, res.Regressed = err, true
+			report.Results = append(report.Results, res)
+			continue
+		}
+
+		res.Answer = resp.Answer
+		sources := sourceNames(resp.Sources)
+		res.SourcesAdded, res.SourcesRemoved = setDelta(canary.Sources, sources)
+		res.Similarity = 1
+		if canary.Answer != "" {
+			res.Similarity = wordOverlap(canary.Answer, resp.Answer)
+			res.Regressed = res.Similarity < canaryDrift
+		}
+		if canary.Expected != "" {
+			res.ExpectedAfter = wordOverlap(canary.Expected, resp.Answer)
+			if canary.Answer != "" {
+				res.ExpectedBefore = wordOverlap(canary.Expected, canary.Answer)
+				res.Regressed = res.Regressed || res.ExpectedAfter < res.ExpectedBefore-canaryExpectedDrop
+			}
+		}
+		report.Results = append(report.Results, res)
+
+		canary.Answer, canary.Sources, canary.AnsweredAt = resp.Answer, sources, time.Now()
+		if err := uc.canaries.record(canary); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// RunCanariesIfChanged runs the canaries when the settings that shape
+// answers have changed since their last run: the retrieval settings, the
+// built-in prompt, the embedding models and the config given to
+// Canaries.SetConfig. ran is false when nothing changed.
+func (uc *QueryUseCase) RunCanariesIfChanged(ctx context.Context) (report CanaryReport, ran bool, err error) {
+	if uc.canaries == nil {
+		return report, false, nil
+	}
+	fingerprint := uc.settingsFingerprint()
+	uc.canaries.mu.Lock()
+	unchanged := uc.canaries.state.Fingerprint == fingerprint
+	uc.canaries.mu.Unlock()
+	if unchanged {
+		return report, false, nil
+	}
+
+	report, err = uc.RunCanaries(ctx)
+	if err != nil {
+		return report, true, err
+	}
+	uc.canaries.mu.Lock()
+	defer uc.canaries.mu.Unlock()
+	uc.canaries.state.Fingerprint = fingerprint
+	return report, true, uc.canaries.save()
+}
+
+// settingsFingerprint hashes what RunCanariesIfChanged watches.
+func (uc *QueryUseCase) settingsFingerprint() string {
+	prompt, _ := uc.buildPrompt(&entities.ChatRequest{}, nil)
+	uc.canaries.mu.Lock()
+	config := uc.canaries.config
+	uc.canaries.mu.Unlock()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "topk=%d hybrid=%t min=%g translate=%t timeout=%s followups=%d\n",
+		uc.topK, uc.hybrid, uc.minScore, uc.translate, uc.genTimeout, uc.followUps)
+	if uc.deterministic {
+		fmt.Fprintf(h, "seed=%d\n", uc.seed)
+	}
+	if uc.budget.NumCtx > 0 {
+		fmt.Fprintf(h, "numctx=%d answer=%d\n", uc.budget.NumCtx, uc.budget.AnswerTokens)
+	}
+	fmt.Fprintf(h, "prompt=%q\nconfig=%q\n", prompt, config)
+	for _, canary := range uc.canaries.List() {
+		model, _ := uc.models.Resolve(canary.Collection, uc.embedder)
+		fmt.Fprintf(h, "model[%s]=%s\n", canary.Collection, model)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// record stores a canary's latest answer, unless it was removed or
+// re-registered with another expected answer meanwhile.
+func (c *Canaries) record(canary Canary) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.find(canary.Collection, canary.Question)
+	if i < 0 || c.state.Canaries[i].Expected != canary.Expected {
+		return nil
+	}
+	c.state.Canaries[i] = canary
+	return c.save()
+}
+
+// sourceNames lists the distinct source documents of results in order.
+func sourceNames(results []entities.QueryResult) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, r := range results {
+		if r.SourceDoc != "" && !seen[r.SourceDoc] {
+			seen[r.SourceDoc] = true
+			names = append(names, r.SourceDoc)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setDelta returns the names in after but not before, and the reverse.
+func setDelta(before, after []string) (added, removed []string) {
+	in := func(list []string, s string) bool {
+		for _, x := range list {
+			if x == s {
+				return true
+			}
+		}
+		return false
+	}
+	for _, s := range after {
+		if !in(before, s) {
+			added = append(added, s)
+		}
+	}
+	for _, s := range before {
+		if !in(after, s) {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// wordOverlap is the Jaccard similarity of two texts' lowercase word
+// sets: 1 for the same words, 0 for none in common.
+func wordOverlap(a, b string) float64 {
+	words := func(s string) map[string]bool {
+		set := make(map[string]bool)
+		for _, w := range strings.FieldsFunc(strings.ToLower(s), notWordRune) {
+			set[w] = true
+		}
+		return set
+	}
+	x, y := words(a), words(b)
+	if len(x) == 0 && len(y) == 0 {
+		return 1
+	}
+	shared := 0
+	for w := range x {
+		if y[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(x)+len(y)-shared)
+}
@@ -0,0 +1,50 @@
+// Package usecases - chat.go builds structured conversations for chat LLMs.
+package usecases
+
+import (
+	"strings"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// systemPrompt is the built-in instruction; chat LLMs get it as the
+// system message, others at the top of the prompt.
+const systemPrompt = "You are a helpful assistant. Answer the question based on the provided context."
+
+// systemPromptFor returns the instruction for a collection.
+func (uc *QueryUseCase) systemPromptFor(collection string) string {
+	if uc.isCode(collection) {
+		return codeSystemPrompt
+	}
+	if uc.system != "" {
+		return uc.system
+	}
+	return systemPrompt
+}
+
+// chatMessages builds the conversation sent to a ports.ChatLLM: the
+// instructions as a system message, the request's history, then the
+// context and question as the last user message. A prompt template,
+// configured or the request's, renders the whole last message instead,
+// after the history, and no system message is sent. History turns other than user and assistant
+// are dropped, so a client cannot replace the instructions.
+func (uc *QueryUseCase) chatMessages(req *entities.ChatRequest, prompt string, context []string) []entities.ChatMessage {
+	var messages []entities.ChatMessage
+	if !uc.templated(req) {
+		system := uc.systemPromptFor(req.Collection)
+		if uc.citations {
+			system += " " + citationInstruction
+		}
+		messages = append(messages, entities.ChatMessage{Role: "system", Content: system})
+	}
+	for _, m := range req.History {
+		if m.Role == "user" || m.Role == "assistant" {
+			messages = append(messages, m)
+		}
+	}
+
+	if !uc.templated(req) {
+		prompt = "Context:\n" + strings.Join(context, "\n\n") + "\n\nQuestion: " + req.Query
+	}
+	return append(messages, entities.ChatMessage{Role: "user", Content: prompt})
+}
@@ -0,0 +1,150 @@
+// Package usecases - citations.go asks for inline citation markers and checks them against the sources.
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// citationInstruction asks the LLM for markers; it follows the context.
+const citationInstruction = "Cite the numbered context passages you use inline, like [1] or [2, 3], right after the statements they support. Only cite passages by their numbers."
+
+// citationMarker matches a marker such as [2] or [1, 3] with the spaces
+// before it, which go when the whole marker is dropped.
+var citationMarker = regexp.MustCompile(`[ \t]*\[\s*\d+(?:\s*,\s*\d+)*\s*\]`)
+
+// maxPendingMarker bounds how much streamed text is held back while it
+// may still turn out to be a marker.
+const maxPendingMarker = 32
+
+// SetCitations enables inline citation markers: context passages are
+// numbered, the built-in prompt asks the LLM to cite them like [1], and
+// answers keep only markers whose number matches one of the sources, so
+// every marker left can be checked against the passage it names.
+// ChatResponse.Citations lists the sources an answer cites. Custom
+// prompt templates see the numbered passages but must ask for markers
+// themselves.
+func (uc *QueryUseCase) SetCitations(enabled bool) {
+	uc.citations = enabled
+}
+
+// contextFor formats results from a collection as context passages,
+// numbered from 1 when citations are enabled. Results of code
+// collections, federated ones by their own, are shown as source files.
+func (uc *QueryUseCase) contextFor(collection string, results []entities.QueryResult) []string {
+	contextParts := buildContext(results)
+	for i, r := range results {
+		if r.Collection != "" && uc.isCode(r.Collection) || r.Collection == "" && uc.isCode(collection) {
+			contextParts[i] = codePassage(r)
+		}
+	}
+	if uc.citations {
+		for i := range contextParts {
+			contextParts[i] = fmt.Sprintf("[%d] %s", i+1, contextParts[i])
+		}
+	}
+	return contextParts
+}
+
+// ValidateCitations drops the numbers of citation markers in answer that
+// do not name one of sources sources, 1-based, and markers left with
+// none. It returns the answer and the source numbers it cites, in order
+// of first citation.
+func ValidateCitations(answer string, sources int) (string, []int) {
+	var cited []int
+	seen := make(map[int]bool)
+	validated := citationMarker.ReplaceAllStringFunc(answer, func(marker string) string {
+		open := strings.IndexByte(marker, '[')
+		var kept []string
+		for _, field := range strings.Split(marker[open+1:len(marker)-1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || n < 1 || n > sources {
+				continue
+			}
+			kept = append(kept, strconv.Itoa(n))
+			if !seen[n] {
+				seen[n] = true
+				cited = append(cited, n)
+			}
+		}
+		if len(kept) == 0 {
+			return ""
+		}
+		return marker[:open] + "[" + strings.Join(kept, ", ") + "]"
+	})
+	return validated, cited
+}
+
+// validateStream applies ValidateCitations to a token stream. Text that
+// may be the start of a marker is held back until the marker closes or
+// turns out to be ordinary text, so markers split across tokens are
+// checked whole.
+func validateStream(ctx context.Context, tokens <-chan ports.StreamToken, sources int) <-chan ports.StreamToken {
+	out := make(chan ports.StreamToken, cap(tokens))
+	go func() {
+		defer close(out)
+		var pending strings.Builder
+		for token := range tokens {
+			if token.Error == nil {
+				token.Content = holdMarkers(&pending, token.Content, sources)
+				if token.Done {
+					token.Content += pending.String()
+					pending.Reset()
+				}
+				if token.Content == "" && !token.Done {
+					continue
+				}
+			}
+			select {
+			case out <- token:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// holdMarkers appends text to pending and returns what can be released:
+// text that cannot start a marker, and complete markers, validated.
+// Trailing spaces are held too, as a dropped marker takes them along.
+func holdMarkers(pending *strings.Builder, text string, sources int) string {
+	var released strings.Builder
+	for _, r := range text {
+		held := pending.String()
+		inMarker := strings.Contains(held, "[")
+		switch {
+		case r == ']' && inMarker:
+			validated, _ := ValidateCitations(held+"]", sources)
+			released.WriteString(validated)
+			pending.Reset()
+			continue
+		case inMarker && (unicode.IsSpace(r) || '0' <= r && r <= '9' || r == ','), !inMarker && startsMarker(r):
+			if pending.Len() < maxPendingMarker {
+				pending.WriteRune(r)
+				continue
+			}
+		}
+		// Not a marker after all; a space or bracket may start the next
+		released.WriteString(held)
+		pending.Reset()
+		if startsMarker(r) {
+			pending.WriteRune(r)
+		} else {
+			released.WriteRune(r)
+		}
+	}
+	return released.String()
+}
+
+// startsMarker reports whether r may begin a marker or the spaces before it.
+func startsMarker(r rune) bool {
+	return r == ' ' || r == '\t' || r == '['
+}
@@ -0,0 +1,238 @@
+// Package usecases - code.go tunes retrieval and prompts for source code collections.
+package usecases
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+const (
+	// symbolBoost is added to a code result's score for each identifier
+	// of the query its chunk contains as a whole word, up to maxSymbolHits.
+	symbolBoost   = 0.05
+	maxSymbolHits = 3
+
+	// definitionBoost is added when the chunk defines one of the query's
+	// identifiers, so "where is X defined?" ranks the definition above
+	// its call sites.
+	definitionBoost = 0.15
+
+	// pathBoost is added when the document's path names a file or
+	// identifier of the query.
+	pathBoost = 0.1
+
+	// codeOverfetch widens retrieval in code collections, so a chunk just
+	// outside the top K can be boosted into it.
+	codeOverfetch = 3
+)
+
+// codeSystemPrompt replaces systemPrompt for code collections.
+const codeSystemPrompt = "You are a helpful assistant for a codebase. Answer the question based on the provided source files. Name the file path of every definition or usage you mention, and quote code in fenced blocks."
+
+// codeLanguages maps source file extensions to Markdown fence languages.
+var codeLanguages = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".mjs": "javascript",
+	".ts": "typescript", ".tsx": "tsx", ".jsx": "jsx", ".java": "java",
+	".kt": "kotlin", ".rs": "rust", ".c": "c", ".h": "c", ".cc": "cpp",
+	".cpp": "cpp", ".hpp": "cpp", ".cs": "csharp", ".rb": "ruby",
+	".php": "php", ".swift": "swift", ".scala": "scala", ".sh": "bash",
+	".sql": "sql", ".lua": "lua", ".yaml": "yaml", ".yml": "yaml",
+	".json": "json", ".toml": "toml", ".html": "html", ".css": "css",
+	".md": "markdown", ".proto": "protobuf",
+}
+
+// queryTerm matches a backquoted span or a possibly qualified name, with
+// call parentheses, such as `cfg.Load`, parse_args() or server.go.
+var queryTerm = regexp.MustCompile("`[^`]+`|[A-Za-z_][A-Za-z0-9_]*(?:(?:\\.|::)[A-Za-z_][A-Za-z0-9_]*)*(?:\\(\\))?")
+
+// symbolNouns are words of a question that name the identifier after
+// them, as in "where is function load defined?".
+var symbolNouns = map[string]bool{
+	"function": true, "func": true, "method": true, "class": true, "type": true,
+	"struct": true, "interface": true, "variable": true, "constant": true, "field": true,
+}
+
+// definitionKeywords introduce definitions in common languages.
+const definitionKeywords = `func|def|class|type|struct|interface|enum|trait|impl|fn|function|const|let|var|module|#define`
+
+// SetCodeCollection marks a collection as source code, or unmarks it.
+// Code collections rank chunks that contain the query's identifiers,
+// such as parseConfig, http.Server or `load`, above those that only
+// resemble it, and definitions of them highest. Documents whose path
+// names one of them, or a file the query mentions, rank higher too. The
+// built-in prompt shows each passage's file path and fences its code in
+// the file's language, so "where is function X defined?" can be
+// answered with the file. Boosted scores may exceed 1.
+func (uc *QueryUseCase) SetCodeCollection(collection string, enabled bool) {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	if uc.code == nil {
+		uc.code = make(map[string]bool)
+	}
+	if enabled {
+		uc.code[collection] = true
+	} else {
+		delete(uc.code, collection)
+	}
+}
+
+// isCode reports whether a collection holds source code.
+func (uc *QueryUseCase) isCode(collection string) bool {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	return uc.code[collection]
+}
+
+// queryIdentifiers extracts the terms of a query that look like code:
+// backquoted, called, qualified, snake_case or camelCase, or named as a
+// symbol by the word before them, such as "function". Qualified
+// names also yield their last part, so http.Server matches a type
+// declared as Server. File names such as server.go are returned as
+// files instead.
+func queryIdentifiers(query string) (identifiers, files []string) {
+	seen := make(map[string]bool)
+	add := func(list *[]string, term string) {
+		if term != "" && !seen[term] {
+			seen[term] = true
+			*list = append(*list, term)
+		}
+	}
+	named := false
+	for _, term := range queryTerm.FindAllString(query, -1) {
+		quoted := strings.HasPrefix(term, "`")
+		called := strings.HasSuffix(term, "()")
+		term = strings.TrimSuffix(strings.Trim(term, "`"), "()")
+		afterNoun := named
+		named = symbolNouns[strings.ToLower(term)]
+		if _, ok := codeLanguages[strings.ToLower(path.Ext(term))]; ok && !called {
+			add(&files, term)
+			continue
+		}
+		if !quoted && !called && !afterNoun && !codeLike(term) {
+			continue
+		}
+		add(&identifiers, term)
+		if i := strings.LastIndexAny(term, ".:"); i >= 0 {
+			add(&identifiers, term[i+1:])
+		}
+	}
+	return identifiers, files
+}
+
+// codeLike reports whether a word is shaped like an identifier rather
+// than prose: qualified, with an underscore, digit or inner capital.
+func codeLike(word string) bool {
+	if strings.ContainsAny(word, "._:0123456789") {
+		return true
+	}
+	for i, r := range word {
+		if i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// boostSymbols raises the scores of code results that contain or define
+// the query's identifiers, or whose path names them, then re-ranks and
+// keeps the top K.
+func boostSymbols(query string, results []entities.QueryResult, topK int) []entities.QueryResult {
+	identifiers, files := queryIdentifiers(query)
+	if len(identifiers) == 0 && len(files) == 0 {
+		if len(results) > topK {
+			results = results[:topK]
+		}
+		return results
+	}
+
+	words := make([]*regexp.Regexp, len(identifiers))
+	definitions := make([]*regexp.Regexp, len(identifiers))
+	for i, id := range identifiers {
+		quoted := regexp.QuoteMeta(id)
+		words[i] = regexp.MustCompile(`\b` + quoted + `\b`)
+		definitions[i] = regexp.MustCompile(`(?m)(?:^|\s)(?:` + definitionKeywords + `)\s+(?:\([^)]*\)\s*)?` + quoted + `\b|^\s*` + quoted + `\s*:?=`)
+	}
+
+	for i := range results {
+		r := &results[i]
+		hits, defined := 0, false
+		for j := range identifiers {
+			if words[j].MatchString(r.Chunk.Content) {
+				hits++
+				defined = defined || definitions[j].MatchString(r.Chunk.Content)
+			}
+		}
+		if hits > maxSymbolHits {
+			hits = maxSymbolHits
+		}
+		r.Score += float64(hits) * symbolBoost
+		if defined {
+			r.Score += definitionBoost
+		}
+		if pathNames(sourcePath(*r), identifiers, files) {
+			r.Score += pathBoost
+		}
+	}
+	sortResults(results)
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+// pathNames reports whether a source path ends in one of files, or its
+// file name, without extension, is one of identifiers, ignoring case.
+func pathNames(source string, identifiers, files []string) bool {
+	source = strings.ToLower(source)
+	for _, f := range files {
+		f = strings.ToLower(f)
+		if source == f || strings.HasSuffix(source, "/"+f) {
+			return true
+		}
+	}
+	base := path.Base(source)
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	for _, id := range identifiers {
+		if strings.EqualFold(stem, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourcePath returns a result's source file path, or its document name
+// when the store records no path.
+func sourcePath(r entities.QueryResult) string {
+	if r.SourcePath != "" {
+		return strings.ReplaceAll(r.SourcePath, "\\", "/")
+	}
+	return r.SourceDoc
+}
+
+// codePassage formats a result as a source file passage: the file path,
+// then the chunk in a fence tagged with the file's language. Chunks of
+// the code loader name their file, lines and language in a header,
+// which replaces the path.
+func codePassage(r entities.QueryResult) string {
+	source := sourcePath(r)
+	lang := codeLanguages[strings.ToLower(path.Ext(source))]
+	content := r.Chunk.Content
+	if header, rest, ok := strings.Cut(content, "\n"); ok && strings.HasPrefix(header, "[File: ") && strings.HasSuffix(header, "]") {
+		source, content = strings.TrimSuffix(strings.TrimPrefix(header, "[File: "), "]"), rest
+	}
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	if r.Collection != "" {
+		source += "; collection: " + r.Collection
+	}
+	return fmt.Sprintf("[File: %s]\n%s%s\n%s\n%s", source, fence, lang, content, fence)
+}
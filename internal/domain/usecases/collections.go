@@ -0,0 +1,26 @@
+// Package usecases - collections.go resolves collection-scoped stores.
+package usecases
+
+import (
+	"errors"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// ErrCollectionsUnsupported is returned when a named collection is
+// requested from a store that only holds a single corpus.
+var ErrCollectionsUnsupported = errors.New("vector store does not support collections")
+
+// storeFor returns the VectorStore scoped to collection.
+// Empty or default names use the store as-is, so single-corpus stores keep working.
+func storeFor(vs ports.VectorStore, collection string) (ports.VectorStore, error) {
+	if collection == "" || collection == entities.DefaultCollection {
+		return vs, nil
+	}
+	cs, ok := vs.(ports.CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.Collection(collection), nil
+}
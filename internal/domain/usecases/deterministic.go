@@ -0,0 +1,111 @@
+// Package usecases - deterministic.go pins generation settings so answers can be reproduced.
+package usecases
+
+import (
+	"sort"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// defaultSeed is the sampling seed of deterministic answers when neither
+// SetDeterministic nor the request picks one.
+const defaultSeed int64 = 42
+
+// SetDeterministic makes every answer deterministic, as if each request
+// set LLMOverrides.Deterministic: generation runs at temperature 0 with
+// seed, unless a request gives its own seed. The LLM must implement
+// ports.TunableLLM or ports.ChatLLM. Requests can opt in without it.
+func (uc *QueryUseCase) SetDeterministic(enabled bool, seed int64) {
+	uc.deterministic = enabled
+	uc.seed = seed
+}
+
+// deterministicFor reports whether answers to a request with overrides o
+// are deterministic.
+func (uc *QueryUseCase) deterministicFor(o entities.LLMOverrides) bool {
+	return uc.deterministic || o.Deterministic
+}
+
+// generateOptions extracts adapter settings from overrides, pinning
+// temperature and seed in deterministic mode; tuned is false when the
+// configured settings apply unchanged.
+func (uc *QueryUseCase) generateOptions(o entities.LLMOverrides) (opts ports.GenerateOptions, tuned bool) {
+	opts = ports.GenerateOptions{
+		Model:         o.Model,
+		Temperature:   o.Temperature,
+		Seed:          o.Seed,
+		TopP:          o.TopP,
+		TopK:          o.TopK,
+		NumCtx:        o.NumCtx,
+		RepeatPenalty: o.RepeatPenalty,
+		Stop:          o.Stop,
+		NumPredict:    o.NumPredict,
+	}
+	if uc.deterministicFor(o) {
+		zero := 0.0
+		opts.Temperature = &zero
+		if opts.Seed == nil {
+			seed := uc.seed
+			opts.Seed = &seed
+		}
+	}
+	return opts, !opts.IsZero()
+}
+
+// AnswerParams returns the settings an answer to req is produced with,
+// for recording next to it.
+func (uc *QueryUseCase) AnswerParams(req *entities.ChatRequest) entities.AnswerParams {
+	opts, _ := uc.generateOptions(req.Overrides)
+	collection := req.Collection
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	minScore := req.MinScore
+	if minScore == 0 {
+		minScore = uc.minScore
+	}
+	embeddingModel, _ := uc.models.Resolve(collection, uc.embedder)
+	return entities.AnswerParams{
+		Deterministic:  uc.deterministicFor(req.Overrides),
+		Seed:           opts.Seed,
+		Temperature:    opts.Temperature,
+		TopP:           opts.TopP,
+		SamplingTopK:   opts.TopK,
+		NumCtx:         opts.NumCtx,
+		RepeatPenalty:  opts.RepeatPenalty,
+		Stop:           opts.Stop,
+		NumPredict:     opts.NumPredict,
+		Model:          opts.Model,
+		PromptTemplate: req.Overrides.PromptTemplate,
+		EmbeddingModel: embeddingModel,
+		Collection:     collection,
+		Collections:    req.Collections,
+		TopK:           uc.topK,
+		MinScore:       minScore,
+		Hybrid:         uc.hybrid,
+	}
+}
+
+// sortResults orders results by score descending, breaking ties by
+// document, chunk index, chunk ID and collection, so the same corpus
+// yields the same context order however the store or a merge left equal
+// scores.
+func sortResults(results []entities.QueryResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Chunk.DocumentID != b.Chunk.DocumentID {
+			return a.Chunk.DocumentID < b.Chunk.DocumentID
+		}
+		if a.Chunk.Index != b.Chunk.Index {
+			return a.Chunk.Index < b.Chunk.Index
+		}
+		if a.Chunk.ID != b.Chunk.ID {
+			return a.Chunk.ID < b.Chunk.ID
+		}
+		return a.Collection < b.Collection
+	})
+}
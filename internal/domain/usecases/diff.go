@@ -0,0 +1,262 @@
+// Package usecases - diff.go compares two snapshots of the corpus.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// ErrDiffUnsupported is returned when the store cannot open snapshots or
+// read their chunks back.
+var ErrDiffUnsupported = errors.New("vector store cannot compare snapshots")
+
+// CorpusDiff describes how one snapshot of the corpus differs from an
+// earlier one.
+type CorpusDiff struct {
+	Collections []CollectionDiff // Collections with differences, by name
+}
+
+// CollectionDiff describes the differences within one collection.
+// Documents are ordered by name.
+type CollectionDiff struct {
+	Name         string
+	Added        []DocumentDiff
+	Removed      []DocumentDiff
+	Changed      []DocumentDiff
+	Unchanged    int // Documents identical in both snapshots
+	ChunksBefore int
+	ChunksAfter  int
+}
+
+// DocumentDiff counts a document's chunk deltas. A chunk is changed when
+// its ID is in both snapshots but its content differs.
+type DocumentDiff struct {
+	ID            string
+	Name          string
+	ChunksAdded   int
+	ChunksRemoved int
+	ChunksChanged int
+}
+
+// Empty reports whether the snapshots hold the same corpus.
+func (d CorpusDiff) Empty() bool {
+	return len(d.Collections) == 0
+}
+
+// Report renders the diff for people, one line per collection followed
+// by one per document: "+" added, "-" removed and "~" changed.
+func (d CorpusDiff) Report() string {
+	if d.Empty() {
+		return "No differences.\n"
+	}
+	var sb strings.Builder
+	for _, c := range d.Collections {
+		fmt.Fprintf(&sb, "%s: %d added, %d removed, %d changed, %d unchanged documents; chunks %d -> %d (%+d)\n",
+			c.Name, len(c.Added), len(c.Removed), len(c.Changed), c.Unchanged,
+			c.ChunksBefore, c.ChunksAfter, c.ChunksAfter-c.ChunksBefore)
+		for _, doc := range c.Added {
+			fmt.Fprintf(&sb, "  + %s (%s)\n", doc.Name, pluralChunks(doc.ChunksAdded))
+		}
+		for _, doc := range c.Removed {
+			fmt.Fprintf(&sb, "  - %s (%s)\n", doc.Name, pluralChunks(doc.ChunksRemoved))
+		}
+		for _, doc := range c.Changed {
+			var parts []string
+			for _, delta := range []struct {
+				n    int
+				verb string
+			}{{doc.ChunksChanged, "changed"}, {doc.ChunksAdded, "added"}, {doc.ChunksRemoved, "removed"}} {
+				if delta.n > 0 {
+					parts = append(parts, fmt.Sprintf("%s %s", pluralChunks(delta.n), delta.verb))
+				}
+			}
+			fmt.Fprintf(&sb, "  ~ %s: %s\n", doc.Name, strings.Join(parts, ", "))
+		}
+	}
+	return sb.String()
+}
+
+func pluralChunks(n int) string {
+	if n == 1 {
+		return "1 chunk"
+	}
+	return fmt.Sprintf("%d chunks", n)
+}
+
+// DiffSnapshots compares two snapshots written by Backup, such as ones
+// taken before and after a sync or bulk ingest, document by document
+// and chunk by chunk. An empty path stands for the live store. Chat
+// session collections are left out. The store must implement
+// ports.SnapshotReader, and its snapshots ports.CollectionStore,
+// ports.DocumentRegistry and ports.ChunkReader.
+func (uc *IngestUseCase) DiffSnapshots(ctx context.Context, before, after string) (CorpusDiff, error) {
+	var diff CorpusDiff
+	old, closeOld, err := uc.openSnapshot(ctx, before)
+	if err != nil {
+		return diff, err
+	}
+	defer closeOld()
+	cur, closeCur, err := uc.openSnapshot(ctx, after)
+	if err != nil {
+		return diff, err
+	}
+	defer closeCur()
+
+	names, err := collectionUnion(ctx, old, cur)
+	if err != nil {
+		return diff, err
+	}
+	for _, name := range names {
+		c, err := diffCollection(ctx, name, old.Collection(name), cur.Collection(name))
+		if err != nil {
+			return diff, fmt.Errorf("comparing %s: %w", name, err)
+		}
+		if len(c.Added)+len(c.Removed)+len(c.Changed) > 0 {
+			diff.Collections = append(diff.Collections, c)
+		}
+	}
+	return diff, nil
+}
+
+// openSnapshot opens the snapshot at path, or the live store when path
+// is empty, and returns how to release it.
+func (uc *IngestUseCase) openSnapshot(ctx context.Context, path string) (ports.CollectionStore, func(), error) {
+	var store ports.VectorStore = uc.vectorStore
+	release := func() {}
+	if path != "" {
+		sr, ok := uc.vectorStore.(ports.SnapshotReader)
+		if !ok {
+			return nil, nil, ErrDiffUnsupported
+		}
+		snap, err := sr.OpenSnapshot(ctx, path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening %s: %w", path, err)
+		}
+		store, release = snap, func() { snap.Close() }
+	}
+
+	cs, ok := store.(ports.CollectionStore)
+	if ok {
+		_, canRead := cs.(ports.ChunkReader)
+		_, canList := cs.(ports.DocumentRegistry)
+		ok = canRead && canList
+	}
+	if !ok {
+		release()
+		return nil, nil, ErrDiffUnsupported
+	}
+	return cs, release, nil
+}
+
+// collectionUnion lists the collections of either store, except chat
+// sessions and those a re-embed keeps, in order.
+func collectionUnion(ctx context.Context, stores ...ports.CollectionStore) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, store := range stores {
+		list, err := store.Collections(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing collections: %w", err)
+		}
+		for _, name := range list {
+			if !seen[name] && !entities.IsSessionCollection(name) && !entities.IsReembedCollection(name) {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// diffCollection compares one collection's documents and chunks.
+func diffCollection(ctx context.Context, name string, before, after ports.VectorStore) (CollectionDiff, error) {
+	c := CollectionDiff{Name: name}
+	oldDocs, err := before.(ports.DocumentRegistry).ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+	if err != nil {
+		return c, err
+	}
+	newDocs, err := after.(ports.DocumentRegistry).ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+	if err != nil {
+		return c, err
+	}
+
+	oldChunks := make(map[string]map[string]entities.Chunk, len(oldDocs.Documents))
+	for _, doc := range oldDocs.Documents {
+		if oldChunks[doc.ID], err = chunksByID(ctx, before, doc.ID); err != nil {
+			return c, err
+		}
+		c.ChunksBefore += len(oldChunks[doc.ID])
+	}
+
+	for _, doc := range newDocs.Documents {
+		newChunks, err := chunksByID(ctx, after, doc.ID)
+		if err != nil {
+			return c, err
+		}
+		c.ChunksAfter += len(newChunks)
+		prev, existed := oldChunks[doc.ID]
+		delete(oldChunks, doc.ID)
+		if !existed {
+			c.Added = append(c.Added, DocumentDiff{ID: doc.ID, Name: doc.Name, ChunksAdded: len(newChunks)})
+			continue
+		}
+
+		d := DocumentDiff{ID: doc.ID, Name: doc.Name}
+		for id, chunk := range newChunks {
+			old, ok := prev[id]
+			switch {
+			case !ok:
+				d.ChunksAdded++
+			case !sameContent(old, chunk):
+				d.ChunksChanged++
+			}
+		}
+		for id := range prev {
+			if _, ok := newChunks[id]; !ok {
+				d.ChunksRemoved++
+			}
+		}
+		if d.ChunksAdded+d.ChunksRemoved+d.ChunksChanged == 0 {
+			c.Unchanged++
+			continue
+		}
+		c.Changed = append(c.Changed, d)
+	}
+
+	// Documents still in oldChunks are gone; keep the listing's order
+	for _, doc := range oldDocs.Documents {
+		if prev, ok := oldChunks[doc.ID]; ok {
+			c.Removed = append(c.Removed, DocumentDiff{ID: doc.ID, Name: doc.Name, ChunksRemoved: len(prev)})
+		}
+	}
+	return c, nil
+}
+
+// chunksByID reads a document's chunks keyed by ID.
+func chunksByID(ctx context.Context, store ports.VectorStore, documentID string) (map[string]entities.Chunk, error) {
+	chunks, err := store.(ports.ChunkReader).DocumentChunks(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", documentID, err)
+	}
+	byID := make(map[string]entities.Chunk, len(chunks))
+	for _, chunk := range chunks {
+		byID[chunk.ID] = chunk
+	}
+	return byID, nil
+}
+
+// sameContent compares chunks by content hash, or by content when either
+// was stored before hashes were kept.
+func sameContent(a, b entities.Chunk) bool {
+	if a.Hash != "" && b.Hash != "" {
+		return a.Hash == b.Hash
+	}
+	return a.Content == b.Content
+}
@@ -0,0 +1,101 @@
+// Package usecases - embedpolicy.go decides what ingestion does when some chunks fail to embed.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// EmbedPolicy controls how ingestion handles chunks the embedder fails
+// on while others of the same document succeed. The zero value fails the
+// whole document without retrying.
+type EmbedPolicy struct {
+	// Retries re-embeds only the failed chunks up to this many times.
+	Retries int
+	// SkipFailed stores the chunks that embedded and records the rest,
+	// which are embedded again on the next ingest. When false, any
+	// failure after retries leaves the document unchanged.
+	SkipFailed bool
+}
+
+// SetEmbedPolicy sets how partial embedding failures are handled.
+func (uc *IngestUseCase) SetEmbedPolicy(policy EmbedPolicy) {
+	uc.embedPolicy = policy
+}
+
+// embedTexts embeds texts with embedder under the embed policy. It returns the
+// embeddings, nil at the indexes in failed, which is only non-empty
+// when failures are skipped. Errors that aren't per-text, such as an
+// unreachable embedder, always fail the call. Background work yields to
+// queries, see SetThrottle.
+func (uc *IngestUseCase) embedTexts(ctx context.Context, embedder ports.EmbeddingService, texts []string, background bool) (embeddings [][]float32, failed map[int]error, err error) {
+	embed := embedPassages
+	if background {
+		embed = uc.embedThrottled
+	}
+	embeddings, err = embed(ctx, embedder, texts)
+	failed, err = batchFailures(err)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(embeddings) != len(texts) {
+		return nil, nil, fmt.Errorf("embedder returned %d embeddings for %d texts", len(embeddings), len(texts))
+	}
+
+	for attempt := 0; attempt < uc.embedPolicy.Retries && len(failed) > 0; attempt++ {
+		indexes := make([]int, 0, len(failed))
+		retry := make([]string, 0, len(failed))
+		for i := range failed {
+			indexes = append(indexes, i)
+			retry = append(retry, texts[i])
+		}
+
+		retried, err := embed(ctx, embedder, retry)
+		stillFailed, err := batchFailures(err)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(retried) != len(retry) {
+			return nil, nil, fmt.Errorf("embedder returned %d embeddings for %d texts", len(retried), len(retry))
+		}
+		for j, i := range indexes {
+			if cause, ok := stillFailed[j]; ok {
+				failed[i] = cause
+				continue
+			}
+			embeddings[i] = retried[j]
+			delete(failed, i)
+		}
+	}
+
+	if len(failed) > 0 && !uc.embedPolicy.SkipFailed {
+		return nil, nil, &ports.BatchEmbedError{Failed: failed}
+	}
+	return embeddings, failed, nil
+}
+
+// batchFailures splits an EmbedBatch error into per-text failures and
+// an error that failed the whole batch.
+func batchFailures(err error) (map[int]error, error) {
+	var batchErr *ports.BatchEmbedError
+	if errors.As(err, &batchErr) {
+		failed := make(map[int]error, len(batchErr.Failed))
+		for i, cause := range batchErr.Failed {
+			failed[i] = cause
+		}
+		return failed, nil
+	}
+	return nil, err
+}
+
+// embedPassages embeds texts to be stored, as passages when the
+// embedder implements ports.RoleEmbedder.
+func embedPassages(ctx context.Context, embedder ports.EmbeddingService, texts []string) ([][]float32, error) {
+	if re, ok := embedder.(ports.RoleEmbedder); ok {
+		return re.EmbedPassages(ctx, texts)
+	}
+	return embedder.EmbedBatch(ctx, texts)
+}
@@ -0,0 +1,176 @@
+// Package usecases - extractive.go answers from retrieved passages when the LLM is too slow.
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// extractivePassages bounds the passages quoted in an extractive answer.
+const extractivePassages = 3
+
+// SetGenerationTimeout bounds the wait for the LLM. A Query whose
+// answer is not generated within d, or a StreamAnswer whose first token
+// does not arrive within d, moves on to the fallbacks, if any, and is
+// otherwise answered extractively: with the sentences of the best
+// passages that match the question, quoted with their sources. Small machines without a GPU can take minutes to
+// generate; this keeps them useful. 0, the default, waits for the LLM.
+func (uc *QueryUseCase) SetGenerationTimeout(d time.Duration) {
+	uc.genTimeout = d
+}
+
+// generate asks the LLM to answer req from prompt, then each fallback
+// in turn while they fail, falling back to an extractive answer over
+// results when one timed out. It returns the fallback that answered,
+// empty for the configured LLM, and whether the answer is extractive.
+func (uc *QueryUseCase) generate(ctx context.Context, req *entities.ChatRequest, prompt string, contextParts []string, results []entities.QueryResult) (string, string, bool, error) {
+	backends := uc.backends()
+	var errs []error
+	anyTimedOut := false
+	for _, b := range backends {
+		answer, timedOut, err := uc.answerWith(ctx, b, req, prompt, contextParts)
+		if err == nil {
+			return answer, b.Name, false, nil
+		}
+		if ctx.Err() != nil {
+			return "", "", false, err
+		}
+		anyTimedOut = anyTimedOut || timedOut
+		errs = append(errs, err)
+	}
+	if anyTimedOut {
+		return extractiveAnswer(req.Query, results), "", true, nil
+	}
+	return "", "", false, chainError(backends, errs)
+}
+
+// streamWithin relays the stream of the first backend that opens and
+// sends a first token within its timeout, trying the LLM and then each
+// fallback, or streams an extractive answer over results when one timed
+// out and none succeeded. Opening the stream counts towards the timeout,
+// as a backend loading a model may not respond until it is ready. A
+// first token carrying an error moves on too, except from the last
+// backend, whose error is relayed.
+func (uc *QueryUseCase) streamWithin(ctx context.Context, query string, results []entities.QueryResult, start func(ctx context.Context, b LLMBackend) (<-chan ports.StreamToken, error)) (<-chan ports.StreamToken, error) {
+	backends := uc.backends()
+	if len(backends) == 1 && uc.genTimeout <= 0 {
+		return start(ctx, backends[0])
+	}
+
+	var errs []error
+	anyTimedOut := false
+	for i, b := range backends {
+		tokens, timedOut, err := openStream(ctx, b, i == len(backends)-1, start)
+		if tokens != nil {
+			return tokens, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if timedOut {
+			anyTimedOut = true
+			continue
+		}
+		errs = append(errs, err)
+	}
+	if !anyTimedOut {
+		return nil, chainError(backends, errs)
+	}
+	out := make(chan ports.StreamToken, 1)
+	out <- ports.StreamToken{Content: extractiveAnswer(query, results), Done: true}
+	close(out)
+	return out, nil
+}
+
+// openStream opens b's stream with start and waits for its first token,
+// up to b's timeout. It returns the relayed stream, or whether b timed
+// out or the error it failed with.
+func openStream(ctx context.Context, b LLMBackend, last bool, start func(ctx context.Context, b LLMBackend) (<-chan ports.StreamToken, error)) (<-chan ports.StreamToken, bool, error) {
+	genCtx, cancel := context.WithCancel(ctx)
+	type opened struct {
+		tokens <-chan ports.StreamToken
+		err    error
+	}
+	openCh := make(chan opened, 1)
+	go func() {
+		tokens, err := start(genCtx, b)
+		openCh <- opened{tokens, err}
+	}()
+
+	var deadline <-chan time.Time
+	if b.Timeout > 0 {
+		timer := time.NewTimer(b.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	select {
+	case o := <-openCh:
+		if o.err != nil {
+			cancel()
+			return nil, false, o.err
+		}
+		select {
+		case first, ok := <-o.tokens:
+			if ok && first.Error != nil && !last {
+				cancel()
+				return nil, false, first.Error
+			}
+			return relayTokens(genCtx, cancel, b.Name, first, ok, o.tokens), false, nil
+		case <-deadline:
+		case <-ctx.Done():
+			cancel()
+			return nil, false, ctx.Err()
+		}
+	case <-deadline:
+	case <-ctx.Done():
+		cancel()
+		return nil, false, ctx.Err()
+	}
+
+	cancel() // The abandoned stream ends with its context
+	return nil, true, nil
+}
+
+// relayTokens forwards first, when ok, and the rest of tokens, marked
+// with the fallback streaming them, then cancels the stream's context.
+func relayTokens(ctx context.Context, cancel context.CancelFunc, fallback string, first ports.StreamToken, ok bool, tokens <-chan ports.StreamToken) <-chan ports.StreamToken {
+	out := make(chan ports.StreamToken, cap(tokens))
+	go func() {
+		defer close(out)
+		defer cancel()
+		for token := first; ok; token, ok = <-tokens {
+			token.Fallback = fallback
+			select {
+			case out <- token:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// extractiveAnswer quotes the sentences of the best results that match
+// the query most closely, citing their sources, and says why.
+func extractiveAnswer(query string, results []entities.QueryResult) string {
+	if len(results) == 0 {
+		return "The language model did not answer in time, and no passages matched the question."
+	}
+	var sb strings.Builder
+	sb.WriteString("The language model did not answer in time. The most relevant passages say:\n")
+	for i, r := range results {
+		if i == extractivePassages {
+			break
+		}
+		if r.SourceDoc == "" {
+			r.SourceDoc = r.Chunk.DocumentID
+		}
+		fmt.Fprintf(&sb, "\n- %s [Source: %s]", BuildSnippet(r.Chunk.Content, query).Text, r.Citation())
+	}
+	return sb.String()
+}
@@ -0,0 +1,213 @@
+// Package usecases - fallback.go retries answers with the next LLM backend when one fails.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// errUntunable rejects a fallback that cannot take a request's options.
+var errUntunable = errors.New("LLM does not support per-request options")
+
+// LLMBackend is a step of the LLM fallback chain.
+type LLMBackend struct {
+	Name    string           // Reported with the answers it gives; defaults to Model
+	LLM     ports.LLMService // nil uses the configured LLM, asking it for Model
+	Model   string           // Model to answer with; empty for the backend's configured one
+	Timeout time.Duration    // Wait for an answer, or for the first streamed token; 0 uses the generation timeout
+}
+
+// SetFallbacks sets the backends tried in order when the configured LLM
+// fails or times out, such as when its model is not pulled. Each takes
+// the request's options except its model; the answer names the backend
+// that gave it in AnswerParams.Fallback, and streamed tokens in
+// StreamToken.Fallback. Only once every backend has failed is the error
+// returned, or, when one timed out, an extractive answer given. A
+// backend that names a model needs an LLM implementing ports.TunableLLM
+// or ports.ChatLLM.
+func (uc *QueryUseCase) SetFallbacks(backends ...LLMBackend) error {
+	fallbacks := make([]LLMBackend, len(backends))
+	for i, b := range backends {
+		if b.LLM == nil {
+			b.LLM = uc.llm
+		}
+		if b.Name == "" {
+			b.Name = b.Model
+		}
+		if b.Name == "" {
+			b.Name = fmt.Sprintf("fallback %d", i+1)
+		}
+		if b.Model != "" && !tunable(b.LLM) {
+			return fmt.Errorf("fallback %s: %w", b.Name, errUntunable)
+		}
+		fallbacks[i] = b
+	}
+	uc.fallbacks = fallbacks
+	return nil
+}
+
+// backends lists the configured LLM, unnamed, then the fallbacks, each
+// with its timeout resolved.
+func (uc *QueryUseCase) backends() []LLMBackend {
+	backends := append([]LLMBackend{{LLM: uc.llm}}, uc.fallbacks...)
+	for i := range backends {
+		if backends[i].Timeout <= 0 {
+			backends[i].Timeout = uc.genTimeout
+		}
+	}
+	return backends
+}
+
+// tunable reports whether llm takes per-request options.
+func tunable(llm ports.LLMService) bool {
+	_, chat := llm.(ports.ChatLLM)
+	_, tuned := llm.(ports.TunableLLM)
+	return chat || tuned
+}
+
+// backendOptions returns the options b answers o with: a fallback asks
+// for its own model rather than the request's.
+func (uc *QueryUseCase) backendOptions(b LLMBackend, o entities.LLMOverrides) (ports.GenerateOptions, bool, error) {
+	opts, tuned := uc.generateOptions(o)
+	if b.Name != "" {
+		opts.Model = b.Model
+		tuned = !opts.IsZero()
+	}
+	if tuned && !tunable(b.LLM) {
+		return opts, false, errUntunable
+	}
+	return opts, tuned, nil
+}
+
+// backendPrompt returns the prompt for b, rendered again when b differs
+// from the configured LLM in taking the history as messages.
+func (uc *QueryUseCase) backendPrompt(b LLMBackend, req *entities.ChatRequest, prompt string, contextParts []string) (string, error) {
+	_, chat := b.LLM.(ports.ChatLLM)
+	_, primaryChat := uc.llm.(ports.ChatLLM)
+	if chat == primaryChat {
+		return prompt, nil
+	}
+	return uc.buildPromptFor(b.LLM, req, contextParts)
+}
+
+// answerWith asks b to answer req, within its timeout. It reports
+// whether the backend timed out.
+func (uc *QueryUseCase) answerWith(ctx context.Context, b LLMBackend, req *entities.ChatRequest, prompt string, contextParts []string) (string, bool, error) {
+	opts, tuned, err := uc.backendOptions(b, req.Overrides)
+	if err != nil {
+		return "", false, err
+	}
+	if prompt, err = uc.backendPrompt(b, req, prompt, contextParts); err != nil {
+		return "", false, err
+	}
+	genCtx := ctx
+	if b.Timeout > 0 {
+		var cancel context.CancelFunc
+		genCtx, cancel = context.WithTimeout(ctx, b.Timeout)
+		defer cancel()
+	}
+
+	var answer string
+	call := TraceCall{Purpose: "answer", Backend: b.Name, Options: opts, Prompt: prompt}
+	cl, chat := b.LLM.(ports.ChatLLM)
+	if chat {
+		call.Prompt, call.Messages = "", uc.chatMessages(req, prompt, contextParts)
+	}
+	key, caching := uc.responses.responseKey(b, opts, prompt, contextParts, call.Messages)
+	if caching {
+		if answer, ok := uc.responses.get(key); ok {
+			call.Output, call.Cached = answer, true
+			traceFrom(ctx).call(call)
+			return answer, false, nil
+		}
+	}
+	start := time.Now()
+	if chat {
+		answer, err = cl.Chat(genCtx, call.Messages, opts)
+	} else if tuned {
+		answer, err = b.LLM.(ports.TunableLLM).GenerateWith(genCtx, prompt, contextParts, opts)
+	} else {
+		answer, err = b.LLM.Generate(genCtx, prompt, contextParts)
+	}
+	timedOut := err != nil && ctx.Err() == nil && genCtx.Err() == context.DeadlineExceeded
+	call.Output, call.Error, call.TimedOut, call.Duration = answer, errorText(err), timedOut, time.Since(start)
+	traceFrom(ctx).call(call)
+	if caching && err == nil {
+		uc.responses.put(key, answer)
+	}
+	return answer, timedOut, err
+}
+
+// streamWith opens b's stream for req.
+func (uc *QueryUseCase) streamWith(ctx context.Context, b LLMBackend, req *entities.ChatRequest, prompt string, contextParts []string) (<-chan ports.StreamToken, error) {
+	opts, tuned, err := uc.backendOptions(b, req.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	if prompt, err = uc.backendPrompt(b, req, prompt, contextParts); err != nil {
+		return nil, err
+	}
+	cl, chat := b.LLM.(ports.ChatLLM)
+	var messages []entities.ChatMessage
+	if chat {
+		messages = uc.chatMessages(req, prompt, contextParts)
+	}
+	key, caching := uc.responses.responseKey(b, opts, prompt, contextParts, messages)
+	if caching {
+		if answer, ok := uc.responses.get(key); ok {
+			return cachedStream(answer), nil
+		}
+	}
+
+	var tokens <-chan ports.StreamToken
+	switch {
+	case chat:
+		tokens, err = cl.ChatStream(ctx, messages, opts)
+	case tuned:
+		tokens, err = b.LLM.(ports.TunableLLM).GenerateStreamWith(ctx, prompt, contextParts, opts)
+	default:
+		tokens, err = b.LLM.GenerateStream(ctx, prompt, contextParts)
+	}
+	if err != nil || !caching {
+		return tokens, err
+	}
+	return uc.responses.cacheStream(ctx, key, tokens), nil
+}
+
+// chainError combines the failures of every backend tried, the
+// configured LLM's alone when there are no fallbacks.
+func chainError(backends []LLMBackend, errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	for i, err := range errs {
+		name := backends[i].Name
+		if name == "" {
+			name = "primary"
+		}
+		errs[i] = fmt.Errorf("%s: %w", name, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Fallbacks returns the fallback chain set by SetFallbacks.
+func (uc *QueryUseCase) Fallbacks() []LLMBackend {
+	return append([]LLMBackend(nil), uc.fallbacks...)
+}
+
+// FallbackModel returns the model of the fallback named name, as
+// reported in StreamToken.Fallback; empty for its backend's configured
+// one.
+func (uc *QueryUseCase) FallbackModel(name string) string {
+	for _, b := range uc.fallbacks {
+		if b.Name == name {
+			return b.Model
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+// Package usecases - federated.go searches several collections as one.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// ErrInvalidCollections is returned for a federated query that names a
+// collection twice or weighs one below zero.
+var ErrInvalidCollections = errors.New("invalid collections")
+
+// searchFederated searches each of collections for its own top K,
+// embedding the query with each collection's model, scales the scores
+// by the collections' weights and keeps the overall top K, labelled
+// with their collection. A session's attached documents are merged in
+// once, after. The collections are searched in parallel, unless the
+// guardrails find the host overloaded.
+func (uc *QueryUseCase) searchFederated(ctx context.Context, collections []entities.CollectionWeight, query string, topK int, documentIDs []string, filter entities.DocumentFilter, sessionID string) ([]entities.QueryResult, error) {
+	if err := checkCollectionWeights(collections); err != nil {
+		return nil, err
+	}
+
+	type searched struct {
+		results   []entities.QueryResult
+		embedding []float32
+		model     ports.EmbeddingModel
+		err       error
+	}
+	found := make([]searched, len(collections))
+	var wg sync.WaitGroup
+	sequential := uc.guard != nil && uc.guard.overloaded()
+	for i, c := range collections {
+		wg.Add(1)
+		search := func(i int, c entities.CollectionWeight) {
+			defer wg.Done()
+			name := c.Name
+			if name == "" {
+				name = entities.DefaultCollection
+			}
+			store, err := storeFor(uc.vectorStore, name)
+			if err != nil {
+				found[i].err = err
+				return
+			}
+			embedding, model, err := uc.embed(ctx, name, query)
+			if err != nil {
+				found[i].err = fmt.Errorf("embedding query for %s: %w", name, err)
+				return
+			}
+			results, err := uc.search(ctx, store, name, query, embedding, model, topK, documentIDs, filter, "")
+			if err != nil {
+				found[i].err = fmt.Errorf("searching %s: %w", name, err)
+				return
+			}
+			weight := c.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			for j := range results {
+				results[j].Score *= weight
+				results[j].Collection = name
+			}
+			found[i] = searched{results: results, embedding: embedding, model: model}
+		}
+		if sequential {
+			search(i, c)
+		} else {
+			go search(i, c)
+		}
+	}
+	wg.Wait()
+
+	var merged []entities.QueryResult
+	for _, f := range found {
+		if f.err != nil {
+			return nil, f.err
+		}
+		merged = append(merged, f.results...)
+	}
+	sortResults(merged)
+	if len(merged) > topK {
+		merged = merged[:topK]
+	}
+	if sessionID == "" {
+		return merged, nil
+	}
+	return uc.withSession(ctx, sessionID, query, found[0].embedding, found[0].model, topK, merged)
+}
+
+// checkCollectionWeights rejects negative weights and collections named
+// twice in a federated query.
+func checkCollectionWeights(collections []entities.CollectionWeight) error {
+	seen := make(map[string]bool, len(collections))
+	for _, c := range collections {
+		name := c.Name
+		if name == "" {
+			name = entities.DefaultCollection
+		}
+		if c.Weight < 0 {
+			return fmt.Errorf("%w: weight %g for %s", ErrInvalidCollections, c.Weight, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%w: %s named twice", ErrInvalidCollections, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Package usecases - followups.go suggests follow-up questions and pre-embeds them.
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// embeddingCacheSize bounds the number of pre-embedded questions kept,
+// unless SetQueryCache sets another size.
+const embeddingCacheSize = 64
+
+// prewarmTimeout bounds a background pre-embedding run.
+const prewarmTimeout = time.Minute
+
+// SetFollowUps sets how many follow-up questions to suggest after each
+// answer; 0, the default, disables suggestions. Suggestions cost one
+// extra LLM call, and are embedded in the background so asking one
+// skips the embed step.
+func (uc *QueryUseCase) SetFollowUps(n int) {
+	uc.followUps = n
+}
+
+// FollowUps asks the LLM for follow-up questions to an answer and starts
+// pre-embedding them. It returns nil when suggestions are disabled.
+func (uc *QueryUseCase) FollowUps(ctx context.Context, query, answer string) ([]string, error) {
+	if uc.followUps <= 0 {
+		return nil, nil
+	}
+
+	prompt := fmt.Sprintf("Suggest %d short follow-up questions a reader might ask next, one per line, with no numbering or other text.\n\nQuestion: %s\n\nAnswer: %s\n\nFollow-up questions:", uc.followUps, query, answer)
+	start := time.Now()
+	out, err := uc.llm.Generate(ctx, prompt, nil)
+	traceFrom(ctx).call(TraceCall{Purpose: "follow_ups", Prompt: prompt, Output: out, Error: errorText(err), Duration: time.Since(start)})
+	if err != nil {
+		return nil, fmt.Errorf("suggesting follow-ups: %w", err)
+	}
+
+	questions := parseFollowUps(out, uc.followUps)
+	uc.Prewarm(questions)
+	return questions, nil
+}
+
+// parseFollowUps extracts up to n questions from LLM output, tolerating
+// the bullets and numbering models add anyway.
+func parseFollowUps(out string, n int) []string {
+	var questions []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "-*•0123456789.) ")
+		line = strings.Trim(line, `"`)
+		if line == "" {
+			continue
+		}
+		questions = append(questions, line)
+		if len(questions) == n {
+			break
+		}
+	}
+	return questions
+}
+
+// Prewarm embeds questions in the background and keeps the vectors, so
+// a later Query or Search for the same text skips the embedder. They
+// are embedded with the default collection's model.
+func (uc *QueryUseCase) Prewarm(questions []string) {
+	model, embedder := uc.models.Resolve(entities.DefaultCollection, uc.embedder)
+	var missing []string
+	for _, q := range questions {
+		if _, ok := uc.embeddings.get(cacheKey(model, q)); !ok {
+			missing = append(missing, q)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), prewarmTimeout)
+		defer cancel()
+		vectors, err := embedQueries(ctx, embedder, missing)
+		if err != nil || len(vectors) != len(missing) {
+			return // Best effort; the question is embedded when asked
+		}
+		for i, q := range missing {
+			uc.embeddings.put(cacheKey(model, q), vectors[i])
+		}
+	}()
+}
+
+// SetQueryCache keeps the embeddings of the last size questions asked,
+// not only of pre-embedded follow-ups, so repeated questions skip the
+// embedder. It suits slow embedders such as CPU-only machines; size 0
+// goes back to caching pre-embedded questions only.
+func (uc *QueryUseCase) SetQueryCache(size int) {
+	uc.embeddings.mu.Lock()
+	defer uc.embeddings.mu.Unlock()
+	uc.embeddings.limit = max(size, 0)
+}
+
+// embed returns the embedding of a query in a collection, pre-embedded
+// or cached when possible, and the model that made it.
+func (uc *QueryUseCase) embed(ctx context.Context, collection, text string) ([]float32, ports.EmbeddingModel, error) {
+	name, embedder := uc.models.Resolve(collection, uc.embedder)
+	key := cacheKey(name, text)
+	start := time.Now()
+	v, ok := uc.embeddings.get(key)
+	if !ok {
+		var err error
+		if v, err = embedQuery(ctx, embedder, text); err != nil {
+			return nil, ports.EmbeddingModel{}, err
+		}
+		if uc.embeddings.cachesQueries() {
+			uc.embeddings.put(key, v)
+		}
+	}
+	traceFrom(ctx).embedding(TraceEmbedding{Collection: collection, Model: name, Text: text, Dimension: len(v), Cached: ok, Duration: time.Since(start)})
+	return v, ports.EmbeddingModel{Name: name, Dimension: len(v)}, nil
+}
+
+// cacheKey keys a pre-embedded question by the model that embedded it.
+func cacheKey(model, text string) string {
+	return model + "\x00" + text
+}
+
+// embedQuery embeds a question, as a query when the embedder implements
+// ports.RoleEmbedder.
+func embedQuery(ctx context.Context, embedder ports.EmbeddingService, text string) ([]float32, error) {
+	if _, ok := embedder.(ports.RoleEmbedder); !ok {
+		return embedder.Embed(ctx, text)
+	}
+	vectors, err := embedQueries(ctx, embedder, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d embeddings for 1 text", len(vectors))
+	}
+	return vectors[0], nil
+}
+
+// embedQueries embeds questions, as queries when the embedder implements
+// ports.RoleEmbedder.
+func embedQueries(ctx context.Context, embedder ports.EmbeddingService, texts []string) ([][]float32, error) {
+	if re, ok := embedder.(ports.RoleEmbedder); ok {
+		return re.EmbedQueries(ctx, texts)
+	}
+	return embedder.EmbedBatch(ctx, texts)
+}
+
+// embeddingCache holds pre-embedded questions, oldest evicted first.
+type embeddingCache struct {
+	mu      sync.Mutex
+	vectors map[string][]float32
+	order   []string // Insertion order, oldest first
+	limit   int      // Size set by SetQueryCache, which caches every query; 0 when unset
+}
+
+func (c *embeddingCache) cachesQueries() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit > 0
+}
+
+func (c *embeddingCache) get(text string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.vectors[text]
+	return v, ok
+}
+
+func (c *embeddingCache) put(text string, v []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.vectors == nil {
+		c.vectors = make(map[string][]float32)
+	}
+	if _, ok := c.vectors[text]; ok {
+		return
+	}
+	limit := c.limit
+	if limit == 0 {
+		limit = embeddingCacheSize
+	}
+	for len(c.order) >= limit {
+		delete(c.vectors, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.vectors[text] = v
+	c.order = append(c.order, text)
+}
@@ -0,0 +1,79 @@
+// Package usecases - git.go re-ingests Git repositories as the commits since the last ingest changed them.
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// GitSync is what re-ingesting a repository's checkout takes.
+type GitSync struct {
+	Since   string   // Commit last ingested from the repository; empty when none
+	Paths   []string // Files to ingest
+	Removed int      // Documents of deleted or untracked files, dropped already
+}
+
+// SyncGit prepares ingesting a checkout into collection. The documents
+// of the repository record the commit they were read from, so the
+// commit of the latest ingest is known: only the files changed since
+// are listed, and those deleted since are dropped. Without such a
+// commit, when it left the history or when full is set, every tracked
+// file is listed, and documents of files no longer tracked are dropped.
+func (uc *IngestUseCase) SyncGit(ctx context.Context, collection string, reader ports.GitReader, co ports.GitCheckout, full bool) (GitSync, error) {
+	prefix := strings.TrimSuffix(filepath.Clean(co.Root), string(filepath.Separator)) + string(filepath.Separator)
+	page, err := uc.FindDocuments(ctx, collection, entities.DocumentFilter{PathPrefix: prefix}, entities.Page{})
+	if err != nil && err != ErrDocumentsUnsupported {
+		return GitSync{}, err
+	}
+	ids := make(map[string]string, len(page.Documents)) // By path
+	var latest entities.DocumentInfo
+	for _, doc := range page.Documents {
+		ids[doc.Path] = doc.ID
+		if doc.Metadata["commit"] != "" && doc.IngestedAt.After(latest.IngestedAt) {
+			latest = doc
+		}
+	}
+
+	var sync GitSync
+	var stale []string
+	if since := latest.Metadata["commit"]; since != "" && !full {
+		changed, removed, ok, err := reader.Changes(ctx, co, since)
+		if err != nil {
+			return GitSync{}, err
+		}
+		if ok {
+			sync.Since, sync.Paths, stale = since, changed, removed
+		}
+	}
+	if sync.Since == "" {
+		sync.Paths = co.Paths
+		tracked := make(map[string]bool, len(co.Paths))
+		for _, path := range co.Paths {
+			tracked[path] = true
+		}
+		for path := range ids {
+			if !tracked[path] {
+				stale = append(stale, path)
+			}
+		}
+	}
+
+	var drop []string
+	for _, path := range stale {
+		if id, ok := ids[path]; ok {
+			drop = append(drop, id)
+		}
+	}
+	sort.Strings(drop)
+	if err := uc.DeleteMany(ctx, collection, drop); err != nil {
+		return GitSync{}, fmt.Errorf("dropping removed files: %w", err)
+	}
+	sync.Removed = len(drop)
+	return sync, nil
+}
@@ -0,0 +1,260 @@
+// Package usecases - guardrails.go sheds load while the host's CPU or memory runs high.
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+const (
+	// defaultGuardMargin is how far below a watermark use must fall
+	// before the host counts as calm again.
+	defaultGuardMargin = 0.05
+
+	// defaultGuardInterval is how often the host is sampled.
+	defaultGuardInterval = 5 * time.Second
+
+	// defaultGuardBatch caps background embedding batches under
+	// guardrails, so ingestion pauses soon after the host gets busy.
+	defaultGuardBatch = 16
+)
+
+// ResourceLimits are the watermarks above which the host counts as
+// overloaded. A watermark of 0 ignores that resource.
+type ResourceLimits struct {
+	CPU        float64       // Fraction of all cores busy, 0 to 1
+	Memory     float64       // Fraction of memory in use, 0 to 1
+	Margin     float64       // How far below a watermark use must fall to end overload; 0 uses 0.05
+	Interval   time.Duration // Between samples; 0 samples every 5s
+	MaxQueries int           // Queries answered at once while overloaded, the rest queue; 0 allows 1
+	BatchSize  int           // Texts per background embedding batch; 0 embeds 16 at a time
+}
+
+// GuardrailState is the guardrails' view of the host.
+type GuardrailState struct {
+	Overloaded bool
+	Usage      ports.ResourceUsage
+	Reasons    []string  // The watermarks exceeded, such as "cpu at 93%, limit 90%"
+	Queued     int       // Queries waiting for a slot
+	Running    int       // Queries being answered
+	SampledAt  time.Time // Zero before the first sample
+	LastError  string    // Why the last sample failed; empty when it succeeded
+}
+
+// Guardrails watch the host's load and shed work while it runs above
+// its limits: queries beyond MaxQueries queue, background embedding
+// pauses between batches, and OnChange callbacks can lower concurrency
+// elsewhere, such as the embedder's. One Guardrails is shared by a
+// QueryUseCase and an IngestUseCase via their SetGuardrails methods and
+// sampled by Run.
+type Guardrails struct {
+	monitor ports.ResourceMonitor
+	limits  ResourceLimits
+
+	mu       sync.Mutex
+	state    GuardrailState
+	changed  chan struct{} // Closed when Overloaded flips or a query finishes
+	onChange []func(GuardrailState)
+}
+
+// NewGuardrails creates guardrails sampling monitor against limits.
+// Until the first sample the host counts as calm.
+func NewGuardrails(monitor ports.ResourceMonitor, limits ResourceLimits) *Guardrails {
+	if limits.Margin <= 0 {
+		limits.Margin = defaultGuardMargin
+	}
+	if limits.Interval <= 0 {
+		limits.Interval = defaultGuardInterval
+	}
+	if limits.MaxQueries <= 0 {
+		limits.MaxQueries = 1
+	}
+	if limits.BatchSize <= 0 {
+		limits.BatchSize = defaultGuardBatch
+	}
+	return &Guardrails{monitor: monitor, limits: limits, changed: make(chan struct{})}
+}
+
+// OnChange calls fn, in the sampling goroutine, each time the host
+// becomes overloaded or calm again. Register callbacks before Run.
+func (g *Guardrails) OnChange(fn func(GuardrailState)) {
+	g.mu.Lock()
+	g.onChange = append(g.onChange, fn)
+	g.mu.Unlock()
+}
+
+// Run samples the host every interval until ctx ends.
+func (g *Guardrails) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.limits.Interval)
+	defer ticker.Stop()
+	for {
+		g.Sample(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sample takes one sample of the host and updates the state. When the
+// monitor fails, the host keeps its previous state.
+func (g *Guardrails) Sample(ctx context.Context) error {
+	usage, err := g.monitor.Usage(ctx)
+
+	g.mu.Lock()
+	if err != nil {
+		g.state.LastError = err.Error()
+		g.mu.Unlock()
+		return err
+	}
+	was := g.state.Overloaded
+	g.state.Usage = usage
+	g.state.SampledAt = time.Now()
+	g.state.LastError = ""
+	g.state.Reasons = g.exceeded(usage, was)
+	g.state.Overloaded = len(g.state.Reasons) > 0
+	flipped := g.state.Overloaded != was
+	if flipped {
+		g.broadcast()
+	}
+	state := g.snapshot()
+	callbacks := g.onChange
+	g.mu.Unlock()
+
+	if flipped {
+		for _, fn := range callbacks {
+			fn(state)
+		}
+	}
+	return nil
+}
+
+// exceeded lists the watermarks usage is above. While overloaded, a
+// resource stays over its watermark until it falls below it by the
+// margin, so the state does not flap around the limit.
+func (g *Guardrails) exceeded(usage ports.ResourceUsage, overloaded bool) []string {
+	var reasons []string
+	check := func(name string, used, limit float64) {
+		if limit <= 0 {
+			return
+		}
+		if used >= limit || overloaded && used > limit-g.limits.Margin {
+			reasons = append(reasons, fmt.Sprintf("%s at %.0f%%, limit %.0f%%", name, used*100, limit*100))
+		}
+	}
+	check("cpu", usage.CPU, g.limits.CPU)
+	check("memory", usage.Memory, g.limits.Memory)
+	return reasons
+}
+
+// State returns the latest sample and the query counts.
+func (g *Guardrails) State() GuardrailState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.snapshot()
+}
+
+// snapshot copies the state; g.mu must be held.
+func (g *Guardrails) snapshot() GuardrailState {
+	state := g.state
+	state.Reasons = append([]string(nil), g.state.Reasons...)
+	return state
+}
+
+// broadcast wakes everything waiting on the state; g.mu must be held.
+func (g *Guardrails) broadcast() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// admit waits until a query may run: at once while the host is calm,
+// otherwise once fewer than MaxQueries are running. The returned
+// function ends the query; it is safe to call more than once.
+func (g *Guardrails) admit(ctx context.Context) (func(), error) {
+	g.mu.Lock()
+	for g.state.Overloaded && g.state.Running >= g.limits.MaxQueries {
+		changed := g.changed
+		g.state.Queued++
+		g.mu.Unlock()
+
+		var err error
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		g.mu.Lock()
+		g.state.Queued--
+		if err != nil {
+			g.mu.Unlock()
+			return nil, err
+		}
+	}
+	g.state.Running++
+	g.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			g.state.Running--
+			g.broadcast()
+		})
+	}, nil
+}
+
+// waitCalm blocks while the host is overloaded. It only fails when ctx
+// ends.
+func (g *Guardrails) waitCalm(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		overloaded, changed := g.state.Overloaded, g.changed
+		g.mu.Unlock()
+		if !overloaded {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// overloaded reports whether the host is over its limits.
+func (g *Guardrails) overloaded() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state.Overloaded
+}
+
+// SetGuardrails makes queries wait for a slot while g finds the host
+// overloaded, from retrieval until the answer is complete, and searches
+// federated collections one after another instead of in parallel.
+func (uc *QueryUseCase) SetGuardrails(g *Guardrails) {
+	uc.guard = g
+}
+
+// admit waits for the guardrails, if any, to let a query run.
+func (uc *QueryUseCase) admit(ctx context.Context) (func(), error) {
+	if uc.guard == nil {
+		return func() {}, nil
+	}
+	return uc.guard.admit(ctx)
+}
+
+// SetGuardrails pauses background embedding while g finds the host
+// overloaded: ingestion and re-embedding embed in batches of the
+// limits' BatchSize and wait for the host to calm down before each.
+// Documents attached to chat sessions are embedded regardless, as a
+// user is waiting for them.
+func (uc *IngestUseCase) SetGuardrails(g *Guardrails) {
+	uc.guard = g
+}
@@ -7,12 +7,24 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
 	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 )
 
+// ErrDocumentsUnsupported is returned when the store keeps no document registry.
+var ErrDocumentsUnsupported = errors.New("vector store does not track documents")
+
+// ErrConflictPolicyUnsupported is returned when a conflict policy is set
+// but the store cannot apply one.
+var ErrConflictPolicyUnsupported = errors.New("vector store does not support conflict policies")
+
 // IngestUseCase handles document ingestion into the vector store.
 // Single Responsibility: Only ingestion logic.
 type IngestUseCase struct {
@@ -20,6 +32,17 @@ type IngestUseCase struct {
 	vectorStore ports.VectorStore
 	chunkSize   int
 	chunkOverlap int
+	version     atomic.Uint64 // Corpus version, see CorpusVersion
+	embedPolicy EmbedPolicy
+	retention   RetentionPolicy
+	collectionRetention map[string]RetentionPolicy // Per-collection overrides of retention
+	conflict    ports.ConflictPolicy
+	sessions    sessionRegistry // Chat sessions with attached documents, see AttachToSession
+	schemas     *MetadataSchemas // Typed metadata fields per collection; nil when none
+	models      *EmbeddingModels // Per-collection embedding models; nil when none
+	throttle    *Throttle        // Yields background embedding to queries; nil when none
+	guard       *Guardrails      // Pauses background embedding while the host is overloaded; nil when none
+	writes      collectionWrites // Documents written during re-embeds, see ReembedCollection
 }
 
 // NewIngestUseCase creates an IngestUseCase with injected dependencies.
@@ -45,52 +68,476 @@ func NewIngestUseCase(
 
 // Ingest processes a document: chunks it, embeds it, stores it.
 func (uc *IngestUseCase) Ingest(ctx context.Context, doc *entities.Document) error {
+	_, err := uc.ingest(ctx, doc)
+	return err
+}
+
+// IngestFiles loads and ingests each path into a collection, carrying
+// on past files that are unsupported, empty or fail. The report says
+// what happened to each; an error is returned only when ctx ends, with
+// the report covering the files handled until then. A loader listing
+// no extensions, such as one fetching URLs, is given every path.
+func (uc *IngestUseCase) IngestFiles(ctx context.Context, loader ports.DocumentLoader, collection string, paths []string) (report entities.IngestReport, err error) {
+	return uc.IngestFilesWithProgress(ctx, loader, collection, paths, nil)
+}
+
+// IngestFilesWithProgress is IngestFiles that passes progress a snapshot
+// as each file starts and after the last one, so long runs can report
+// throughput and an estimated time left. A nil progress is not called.
+func (uc *IngestUseCase) IngestFilesWithProgress(ctx context.Context, loader ports.DocumentLoader, collection string, paths []string, progress func(entities.IngestProgress)) (report entities.IngestReport, err error) {
+	start := time.Now()
+	defer func() { report.Duration = time.Since(start) }()
+	if progress == nil {
+		progress = func(entities.IngestProgress) {}
+	}
+	filesDone := 0
+	defer func() {
+		progress(ingestProgress(start, filesDone, len(paths), chunksDone(report), ""))
+	}()
+
+	supported := make(map[string]bool)
+	for _, ext := range loader.SupportedExtensions() {
+		supported[strings.ToLower(ext)] = true
+	}
+
+	for i, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		filesDone = i
+		progress(ingestProgress(start, filesDone, len(paths), chunksDone(report), path))
+		if len(supported) > 0 && !supported[strings.ToLower(filepath.Ext(path))] {
+			report.Skipped = append(report.Skipped, entities.IngestIssue{Path: path, Reason: "unsupported file type"})
+			continue
+		}
+
+		doc, err := loader.Load(ctx, path)
+		if err != nil {
+			report.Errors = append(report.Errors, entities.IngestIssue{Path: path, Reason: "loading: " + err.Error()})
+			continue
+		}
+		doc.Collection = collection
+
+		res, err := uc.ingest(ctx, doc)
+		report.EmbedDuration += res.embedTime
+		report.StoreDuration += res.storeTime
+		if err != nil {
+			report.Errors = append(report.Errors, entities.IngestIssue{Path: path, Reason: err.Error()})
+			continue
+		}
+		if res.chunks == 0 {
+			report.Skipped = append(report.Skipped, entities.IngestIssue{Path: path, Reason: "no text content"})
+			continue
+		}
+		report.FilesProcessed++
+		report.ChunksCreated += res.stored
+		report.ChunksFailed += len(res.failed)
+		report.DocumentsEvicted += res.evicted
+		report.ChunksUnchanged += res.chunks - res.stored - len(res.failed)
+		for _, f := range res.failed {
+			reason := fmt.Sprintf("chunk %d skipped: %v", f.index, f.err)
+			report.Errors = append(report.Errors, entities.IngestIssue{Path: path, Reason: reason})
+		}
+	}
+	filesDone = len(paths)
+	return report, nil
+}
+
+// ingestProgress estimates the time left in a run from its throughput
+// so far: the remaining files are expected to take as long on average,
+// and so to hold as many chunks, as the done ones.
+func ingestProgress(start time.Time, filesDone, filesTotal, chunks int, current string) entities.IngestProgress {
+	p := entities.IngestProgress{
+		FilesDone:  filesDone,
+		FilesTotal: filesTotal,
+		ChunksDone: chunks,
+		Elapsed:    time.Since(start),
+		Current:    current,
+	}
+	if p.Elapsed > 0 {
+		p.ChunksPerSecond = float64(chunks) / p.Elapsed.Seconds()
+	}
+	if filesDone > 0 && filesDone < filesTotal {
+		p.Remaining = p.Elapsed / time.Duration(filesDone) * time.Duration(filesTotal-filesDone)
+	}
+	return p
+}
+
+// chunksDone counts the chunks a run has dealt with so far.
+func chunksDone(report entities.IngestReport) int {
+	return report.ChunksCreated + report.ChunksUnchanged + report.ChunksFailed
+}
+
+// ingestResult describes the work ingest did for one document.
+type ingestResult struct {
+	chunks    int            // Chunks the document split into
+	stored    int            // Chunks embedded and written
+	failed    []chunkFailure // Chunks skipped under EmbedPolicy.SkipFailed
+	evicted   int            // Other documents removed by the retention policy
+	embedTime time.Duration
+	storeTime time.Duration
+}
+
+// chunkFailure records a chunk that was skipped because it failed to embed.
+type chunkFailure struct {
+	index int // Chunk position in the document
+	err   error
+}
+
+// ingest chunks, embeds and stores a document. Its errors say which step
+// failed.
+func (uc *IngestUseCase) ingest(ctx context.Context, doc *entities.Document) (ingestResult, error) {
+	release := uc.writes.begin(doc.Collection, doc.ID)
+	res, err := uc.write(ctx, doc)
+	release()
+	if err != nil || res.chunks == 0 {
+		return res, err
+	}
+
+	// 8. Evict documents the retention policy no longer allows
+	res.evicted, err = uc.enforceRetention(ctx, doc.Collection, doc.ID)
+	if err != nil {
+		return res, fmt.Errorf("applying retention: %w", err)
+	}
+	return res, nil
+}
+
+// write does the first steps of ingest, up to recording the document.
+func (uc *IngestUseCase) write(ctx context.Context, doc *entities.Document) (ingestResult, error) {
+	var res ingestResult
+	store, err := storeFor(uc.vectorStore, doc.Collection)
+	if err != nil {
+		return res, err
+	}
+
+	upserter, canUpsert := store.(ports.Upserter)
+	if uc.conflict != ports.ConflictVersion && !canUpsert {
+		return res, ErrConflictPolicyUnsupported
+	}
+	metadata, err := uc.schemas.Get(doc.Collection).Normalize(doc.Metadata)
+	if err != nil {
+		return res, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
 	// 1. Chunk the document
 	chunks := uc.chunkDocument(doc)
 	if len(chunks) == 0 {
-		return nil // Empty document
+		return res, nil // Empty document
+	}
+	res.chunks = len(chunks)
+
+	// 2. Skip chunks the conflict policy would keep as stored
+	pending, err := changedChunks(ctx, store, doc.ID, chunks, uc.conflict)
+	if err != nil {
+		return res, fmt.Errorf("reading stored chunks: %w", err)
+	}
+
+	if len(pending) > 0 {
+		// 3. Extract text for embedding
+		texts := make([]string, len(pending))
+		for i, chunk := range pending {
+			texts[i] = chunk.Content
+		}
+
+		// 4. Generate embeddings via port (adapter), with the
+		// collection's model, which must match the stored vectors'
+		model, embedder := uc.models.Resolve(doc.Collection, uc.embedder)
+		started := time.Now()
+		embeddings, failed, err := uc.embedTexts(ctx, embedder, texts, !entities.IsSessionCollection(doc.Collection))
+		res.embedTime = time.Since(started)
+		if err != nil {
+			return res, fmt.Errorf("embedding: %w", err)
+		}
+		if dim := embeddingWidth(embeddings); dim > 0 {
+			if err := checkModel(ctx, store, doc.Collection, ports.EmbeddingModel{Name: model, Dimension: dim}, true); err != nil {
+				return res, err
+			}
+		}
+
+		// 5. Attach embeddings to chunks, dropping skipped failures
+		embedded := pending[:0]
+		for i, chunk := range pending {
+			if cause, ok := failed[i]; ok {
+				res.failed = append(res.failed, chunkFailure{index: chunk.Index, err: cause})
+				continue
+			}
+			chunk.Embedding = embeddings[i]
+			embedded = append(embedded, chunk)
+		}
+		pending = embedded
+
+		// 6. Store in vector DB via port
+		if len(pending) == 0 {
+			return res, fmt.Errorf("embedding: %w", &ports.BatchEmbedError{Failed: failed})
+		}
+		started = time.Now()
+		if canUpsert {
+			err = upserter.Upsert(ctx, pending, uc.conflict)
+		} else {
+			err = store.Store(ctx, pending)
+		}
+		res.storeTime = time.Since(started)
+		if err != nil {
+			return res, fmt.Errorf("storing: %w", err)
+		}
+		res.stored = len(pending)
+		uc.version.Add(1)
 	}
 
-	// 2. Extract text for embedding
-	texts := make([]string, len(chunks))
-	for i, chunk := range chunks {
-		texts[i] = chunk.Content
+	// 7. Record the document if the store keeps a registry
+	if reg, ok := store.(ports.DocumentRegistry); ok {
+		err := reg.RegisterDocument(ctx, entities.DocumentInfo{
+			ID:         doc.ID,
+			Name:       doc.Name,
+			Path:       doc.Path,
+			ChunkCount: len(chunks) - len(res.failed),
+			IngestedAt: time.Now(),
+			Metadata:   metadata,
+			Provenance: doc.Provenance,
+		})
+		if err != nil {
+			return res, fmt.Errorf("registering document: %w", err)
+		}
 	}
+	return res, nil
+}
 
-	// 3. Generate embeddings via port (adapter)
-	embeddings, err := uc.embedder.EmbedBatch(ctx, texts)
+// changedChunks drops chunks the store would keep under policy: those
+// whose content hash matches the stored copy, or under ConflictSkip any
+// stored chunk. Stores without ports.ChunkHasher get every chunk.
+func changedChunks(ctx context.Context, store ports.VectorStore, documentID string, chunks []entities.Chunk, policy ports.ConflictPolicy) ([]entities.Chunk, error) {
+	hasher, ok := store.(ports.ChunkHasher)
+	if !ok || policy == ports.ConflictReplace {
+		return chunks, nil
+	}
+	stored, err := hasher.ChunkHashes(ctx, documentID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var pending []entities.Chunk
+	for _, chunk := range chunks {
+		hash, exists := stored[chunk.ID]
+		if exists && (policy == ports.ConflictSkip || hash == chunk.Hash) {
+			continue
+		}
+		pending = append(pending, chunk)
+	}
+	return pending, nil
+}
+
+// SetConflictPolicy sets how ingest treats chunks the store already
+// holds: ConflictReplace re-embeds and rewrites every chunk, and
+// ConflictSkip only adds chunks the store lacks. Policies other than
+// the default ports.ConflictVersion need a store implementing
+// ports.Upserter; ingesting into others fails with
+// ErrConflictPolicyUnsupported.
+func (uc *IngestUseCase) SetConflictPolicy(policy ports.ConflictPolicy) {
+	uc.conflict = policy
+}
+
+// CorpusVersion returns a counter that changes whenever Ingest, Delete
+// or Invalidate modifies the stored corpus. Re-ingesting unchanged
+// content leaves it alone. It starts at zero in each process.
+func (uc *IngestUseCase) CorpusVersion() uint64 {
+	return uc.version.Load()
+}
+
+// Invalidate bumps the corpus version after changes made outside this
+// usecase, such as a snapshot restore.
+func (uc *IngestUseCase) Invalidate() {
+	uc.version.Add(1)
+}
+
+// ListDocuments returns all documents ingested into a collection.
+func (uc *IngestUseCase) ListDocuments(ctx context.Context, collection string) ([]entities.DocumentInfo, error) {
+	page, err := uc.FindDocuments(ctx, collection, entities.DocumentFilter{}, entities.Page{})
+	if err != nil {
+		return nil, err
 	}
+	return page.Documents, nil
+}
 
-	// 4. Attach embeddings to chunks
-	for i := range chunks {
-		chunks[i].Embedding = embeddings[i]
+// FindDocuments returns one page of the documents in a collection that
+// match filter, ordered by name. Metadata conditions and terms are typed
+// by the collection's schema.
+func (uc *IngestUseCase) FindDocuments(ctx context.Context, collection string, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error) {
+	store, err := storeFor(uc.vectorStore, collection)
+	if err != nil {
+		return entities.DocumentPage{}, err
 	}
+	if filter, err = bindFilter(uc.schemas, collection, filter); err != nil {
+		return entities.DocumentPage{}, err
+	}
+	reg, ok := store.(ports.DocumentRegistry)
+	if !ok {
+		return entities.DocumentPage{}, ErrDocumentsUnsupported
+	}
+	return reg.ListDocuments(ctx, filter, page)
+}
 
-	// 5. Store in vector DB via port
-	return uc.vectorStore.Store(ctx, chunks)
+// Stats reports the contents of a collection.
+func (uc *IngestUseCase) Stats(ctx context.Context, collection string) (ports.StoreStats, error) {
+	store, err := storeFor(uc.vectorStore, collection)
+	if err != nil {
+		return ports.StoreStats{}, err
+	}
+	return store.Stats(ctx)
 }
 
-// Delete removes a document from the store.
+// Delete removes a document from the default collection.
 func (uc *IngestUseCase) Delete(ctx context.Context, documentID string) error {
-	return uc.vectorStore.Delete(ctx, documentID)
+	return uc.DeleteFromCollection(ctx, entities.DefaultCollection, documentID)
 }
 
-// chunkDocument splits document content into overlapping chunks.
+// DeleteFromCollection removes a document from the named collection.
+func (uc *IngestUseCase) DeleteFromCollection(ctx context.Context, collection, documentID string) error {
+	store, err := storeFor(uc.vectorStore, collection)
+	if err != nil {
+		return err
+	}
+	defer uc.writes.begin(collection, documentID)()
+	if err := store.Delete(ctx, documentID); err != nil {
+		return err
+	}
+	uc.version.Add(1)
+	return nil
+}
+
+// DeleteMany removes several documents from the named collection, in one
+// transaction when the store implements ports.BatchDeleter and one by
+// one otherwise.
+func (uc *IngestUseCase) DeleteMany(ctx context.Context, collection string, documentIDs []string) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	store, err := storeFor(uc.vectorStore, collection)
+	if err != nil {
+		return err
+	}
+	defer uc.writes.begin(collection, documentIDs...)()
+	defer uc.version.Add(1) // Even a partial failure may have changed the corpus
+
+	if bd, ok := store.(ports.BatchDeleter); ok {
+		return bd.DeleteMany(ctx, documentIDs)
+	}
+	for _, id := range documentIDs {
+		if err := store.Delete(ctx, id); err != nil {
+			return fmt.Errorf("deleting %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// DeleteMatching removes every document of a collection that matches
+// filter. It returns how many documents were deleted.
+func (uc *IngestUseCase) DeleteMatching(ctx context.Context, collection string, filter entities.DocumentFilter) (int, error) {
+	page, err := uc.FindDocuments(ctx, collection, filter, entities.Page{})
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]string, len(page.Documents))
+	for i, doc := range page.Documents {
+		ids[i] = doc.ID
+	}
+	if err := uc.DeleteMany(ctx, collection, ids); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// DeleteUnder removes every document of a collection whose source path
+// lies in dir, as when the watcher reports a removed directory. It
+// returns how many documents were deleted.
+func (uc *IngestUseCase) DeleteUnder(ctx context.Context, collection, dir string) (int, error) {
+	docs, err := uc.ListDocuments(ctx, collection)
+	if err != nil {
+		return 0, err
+	}
+
+	prefix := strings.TrimSuffix(filepath.Clean(dir), string(filepath.Separator)) + string(filepath.Separator)
+	var ids []string
+	for _, doc := range docs {
+		if doc.Path != "" && strings.HasPrefix(filepath.Clean(doc.Path), prefix) {
+			ids = append(ids, doc.ID)
+		}
+	}
+	if err := uc.DeleteMany(ctx, collection, ids); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// UnseenPaths returns the paths, in order, from which no document of
+// collection was ingested, such as the new entries of a feed. Stores
+// without a document registry have every path returned.
+func (uc *IngestUseCase) UnseenPaths(ctx context.Context, collection string, paths []string) ([]string, error) {
+	docs, err := uc.ListDocuments(ctx, collection)
+	if err == ErrDocumentsUnsupported {
+		return paths, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		seen[doc.Path] = true
+	}
+	var unseen []string
+	for _, path := range paths {
+		if !seen[path] {
+			unseen = append(unseen, path)
+		}
+	}
+	return unseen, nil
+}
+
+// chunkDocument splits document content into overlapping chunks, or,
+// when the document has sections, each section on its own, starting
+// every chunk with the section's header and giving it the section's
+// page.
 // Pure business logic - no external dependencies.
 func (uc *IngestUseCase) chunkDocument(doc *entities.Document) []entities.Chunk {
-	content := strings.TrimSpace(doc.Content)
-	if len(content) == 0 {
-		return nil
+	sections := doc.Sections
+	if len(sections) == 0 {
+		sections = []entities.Section{{Content: doc.Content}}
 	}
 
 	var chunks []entities.Chunk
-	start := 0
 	index := 0
+	for _, section := range sections {
+		header := entities.SectionHeader(section.Label)
+		// The header counts towards the chunk size, up to half of it
+		size := max(uc.chunkSize-len(header), uc.chunkSize/2)
+		split := splitText
+		if section.Records {
+			split = packRecords
+		}
+		for _, text := range split(section.Content, size, uc.chunkOverlap) {
+			chunkContent := header + text
+			chunks = append(chunks, entities.Chunk{
+				ID:         generateChunkID(doc.ID, index),
+				DocumentID: doc.ID,
+				Content:    chunkContent,
+				Index:      index,
+				Hash:       contentHash(chunkContent),
+				Page:       section.Page,
+			})
+			index++
+		}
+	}
+	return chunks
+}
+
+// splitText splits content into chunks of up to size bytes, breaking at
+// word boundaries, each overlapping the previous one by overlap bytes.
+func splitText(content string, size, overlap int) []string {
+	content = strings.TrimSpace(content)
+	var parts []string
+	start := 0
 
 	for start < len(content) {
-		end := start + uc.chunkSize
+		end := start + size
 		if end > len(content) {
 			end = len(content)
 		}
@@ -103,18 +550,15 @@ func (uc *IngestUseCase) chunkDocument(doc *entities.Document) []entities.Chunk
 			}
 		}
 
-		chunkContent := strings.TrimSpace(content[start:end])
-		if len(chunkContent) > 0 {
-			chunks = append(chunks, entities.Chunk{
-				ID:         generateChunkID(doc.ID, index),
-				DocumentID: doc.ID,
-				Content:    chunkContent,
-				Index:      index,
-			})
-			index++
+		if part := strings.TrimSpace(content[start:end]); len(part) > 0 {
+			parts = append(parts, part)
+		}
+
+		if end >= len(content) {
+			break
 		}
 
-		start = end - uc.chunkOverlap
+		start = end - overlap
 		if start < 0 {
 			start = 0
 		}
@@ -123,7 +567,47 @@ func (uc *IngestUseCase) chunkDocument(doc *entities.Document) []entities.Chunk
 		}
 	}
 
-	return chunks
+	return parts
+}
+
+// packRecords splits content of one record per line into chunks of
+// whole records, as many as fit in size bytes. Records are independent,
+// so chunks do not overlap; a record longer than size is split with
+// splitText on its own.
+func packRecords(content string, size, overlap int) []string {
+	var parts []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+	for _, record := range strings.Split(content, "\n") {
+		record = strings.TrimSpace(record)
+		switch {
+		case record == "":
+			continue
+		case len(record) > size:
+			flush()
+			parts = append(parts, splitText(record, size, overlap)...)
+			continue
+		case current.Len() > 0 && current.Len()+1+len(record) > size:
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteByte('\n')
+		}
+		current.WriteString(record)
+	}
+	flush()
+	return parts
+}
+
+// contentHash fingerprints chunk text for change detection.
+func contentHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
 }
 
 // generateChunkID creates a deterministic ID for a chunk.
@@ -2,9 +2,14 @@ package usecases
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 )
 
 // mockEmbedder implements ports.EmbeddingService for testing
@@ -65,6 +70,10 @@ func (m *mockVectorStore) Clear(ctx context.Context) error {
 	return nil
 }
 
+func (m *mockVectorStore) Stats(ctx context.Context) (ports.StoreStats, error) {
+	return ports.StoreStats{Chunks: len(m.chunks)}, nil
+}
+
 func TestIngestUseCase_ChunksDocument(t *testing.T) {
 	embedder := &mockEmbedder{}
 	store := &mockVectorStore{}
@@ -86,6 +95,69 @@ func TestIngestUseCase_ChunksDocument(t *testing.T) {
 	}
 }
 
+func TestIngestUseCase_ChunksSections(t *testing.T) {
+	embedder := &mockEmbedder{}
+	store := &mockVectorStore{}
+	uc := NewIngestUseCase(embedder, store, 60, 10)
+
+	doc := &entities.Document{
+		ID:   "deck",
+		Name: "deck.pptx",
+		Sections: []entities.Section{
+			{Label: "Slide 1: Intro", Content: "Welcome"},
+			{Label: "Slide 2: Roadmap", Content: "Ship version two in the third quarter and hire two more engineers"},
+		},
+	}
+	doc.Content = entities.JoinSections(doc.Sections)
+
+	if err := uc.Ingest(context.Background(), doc); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if len(store.chunks) < 3 {
+		t.Fatalf("expected the second slide to span chunks, got %d chunks", len(store.chunks))
+	}
+	if store.chunks[0].Content != "[Slide 1: Intro]\nWelcome" {
+		t.Errorf("unexpected first chunk: %q", store.chunks[0].Content)
+	}
+	for i, c := range store.chunks {
+		if c.Index != i {
+			t.Errorf("chunk %d has index %d", i, c.Index)
+		}
+		if i > 0 && !strings.HasPrefix(c.Content, "[Slide 2: Roadmap]\n") {
+			t.Errorf("chunk %d lacks its slide header: %q", i, c.Content)
+		}
+		if strings.Contains(c.Content, "Welcome") && i > 0 {
+			t.Errorf("chunk %d spans two slides: %q", i, c.Content)
+		}
+	}
+}
+
+func TestIngestUseCase_ChunksWholeRecords(t *testing.T) {
+	embedder := &mockEmbedder{}
+	store := &mockVectorStore{}
+	uc := NewIngestUseCase(embedder, store, 80, 20)
+
+	rows := []string{
+		"Row 2: SKU: A-113; Name: Widget; Stock: 12",
+		"Row 3: SKU: B-7; Name: Gear; Stock: 3",
+		"Row 4: SKU: C-21; Name: Sprocket; Stock: 40",
+	}
+	section := entities.Section{Label: "Sheet: Stock", Content: strings.Join(rows, "\n"), Records: true}
+	doc := &entities.Document{ID: "stock", Sections: []entities.Section{section}}
+
+	if err := uc.Ingest(context.Background(), doc); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if len(store.chunks) != len(rows) {
+		t.Fatalf("expected a chunk per row, got %d", len(store.chunks))
+	}
+	for i, c := range store.chunks {
+		if want := "[Sheet: Stock]\n" + rows[i]; c.Content != want {
+			t.Errorf("chunk %d = %q, want %q", i, c.Content, want)
+		}
+	}
+}
+
 func TestIngestUseCase_EmptyDocument(t *testing.T) {
 	embedder := &mockEmbedder{}
 	store := &mockVectorStore{}
@@ -133,3 +205,1318 @@ func TestIngestUseCase_Delete(t *testing.T) {
 		t.Errorf("delete failed: %v", err)
 	}
 }
+
+// mockRegistryStore records registered documents and the last page
+// asked for
+type mockRegistryStore struct {
+	mockVectorStore
+	registered []entities.DocumentInfo
+	page       entities.Page
+}
+
+func (m *mockRegistryStore) RegisterDocument(ctx context.Context, info entities.DocumentInfo) error {
+	m.registered = append(m.registered, info)
+	return nil
+}
+
+func (m *mockRegistryStore) ListDocuments(ctx context.Context, filter entities.DocumentFilter, page entities.Page) (entities.DocumentPage, error) {
+	m.page = page
+	var docs []entities.DocumentInfo
+	for _, doc := range m.registered {
+		if filter.Matches(doc) {
+			docs = append(docs, doc)
+		}
+	}
+	return entities.DocumentPage{Documents: docs, Total: len(docs)}, nil
+}
+
+func TestIngestUseCase_RegistersDocument(t *testing.T) {
+	store := &mockRegistryStore{}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+
+	doc := &entities.Document{ID: "doc-1", Name: "a.txt", Path: "/docs/a.txt", Content: "some content"}
+	if err := uc.Ingest(context.Background(), doc); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+
+	docs, err := uc.ListDocuments(context.Background(), "")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Name != "a.txt" || docs[0].ChunkCount != len(store.chunks) {
+		t.Errorf("unexpected registry: %+v", docs)
+	}
+}
+
+func TestIngestUseCase_FindDocuments(t *testing.T) {
+	store := &mockRegistryStore{}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	ctx := context.Background()
+	uc.Ingest(ctx, &entities.Document{ID: "doc-1", Name: "a.txt", Path: "/docs/a.txt", Content: "some content"})
+	uc.Ingest(ctx, &entities.Document{ID: "doc-2", Name: "b.txt", Path: "/notes/b.txt", Content: "more content"})
+
+	page, err := uc.FindDocuments(ctx, "", entities.DocumentFilter{PathPrefix: "/notes/"}, entities.Page{Limit: 10, Offset: 5})
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if page.Total != 1 || page.Documents[0].ID != "doc-2" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+	if store.page != (entities.Page{Limit: 10, Offset: 5}) {
+		t.Errorf("page not passed to the store: %+v", store.page)
+	}
+}
+
+func TestIngestUseCase_ListDocumentsUnsupported(t *testing.T) {
+	uc := NewIngestUseCase(&mockEmbedder{}, &mockVectorStore{}, 100, 20)
+	if _, err := uc.ListDocuments(context.Background(), ""); err != ErrDocumentsUnsupported {
+		t.Errorf("expected ErrDocumentsUnsupported, got %v", err)
+	}
+}
+
+func TestIngestUseCase_ChangedSources(t *testing.T) {
+	answered := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &mockRegistryStore{registered: []entities.DocumentInfo{
+		{ID: "a", Name: "a.txt", IngestedAt: answered},
+		{ID: "b", Name: "b.txt", IngestedAt: answered},
+		{ID: "c", Name: "c.txt", IngestedAt: answered},
+	}}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	ctx := context.Background()
+
+	results := []entities.QueryResult{
+		{Chunk: entities.Chunk{DocumentID: "a"}, SourceDoc: "a.txt", IngestedAt: answered},
+		{Chunk: entities.Chunk{DocumentID: "b"}, SourceDoc: "b.txt", IngestedAt: answered},
+		{Chunk: entities.Chunk{DocumentID: "b"}, SourceDoc: "b.txt", IngestedAt: answered},
+		{Chunk: entities.Chunk{DocumentID: "c"}, SourceDoc: "c.txt", IngestedAt: answered},
+	}
+	req := &entities.ChatRequest{Query: "q"}
+	if sources := AnswerSources(req, results, nil); len(sources) != 3 {
+		t.Errorf("expected each document once, got %+v", sources)
+	}
+	sources := AnswerSources(req, results, []int{2, 3, 4})
+	if len(sources) != 2 || sources[0].DocumentID != "b" || sources[1].DocumentID != "c" {
+		t.Fatalf("expected the cited documents, got %+v", sources)
+	}
+
+	if changes, err := uc.ChangedSources(ctx, sources); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v, %v", changes, err)
+	}
+
+	// a changing is no concern of an answer that did not cite it
+	reingested := answered.Add(time.Hour)
+	store.registered = []entities.DocumentInfo{
+		{ID: "a", Name: "a.txt", IngestedAt: reingested},
+		{ID: "b", Name: "b.txt", IngestedAt: reingested},
+	}
+	changes, err := uc.ChangedSources(ctx, sources)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("expected two changes, got %+v, %v", changes, err)
+	}
+	if changes[0].DocumentID != "b" || changes[0].Deleted || !changes[0].Reingested.Equal(reingested) {
+		t.Errorf("expected b re-ingested, got %+v", changes[0])
+	}
+	if changes[1].DocumentID != "c" || !changes[1].Deleted {
+		t.Errorf("expected c deleted, got %+v", changes[1])
+	}
+
+	if _, err := NewIngestUseCase(&mockEmbedder{}, &mockVectorStore{}, 100, 20).ChangedSources(ctx, sources); err != ErrDocumentsUnsupported {
+		t.Errorf("expected ErrDocumentsUnsupported, got %v", err)
+	}
+}
+
+// mockBatchStore records DeleteMany calls and drops the deleted documents
+type mockBatchStore struct {
+	mockRegistryStore
+	batches [][]string
+}
+
+func (m *mockBatchStore) DeleteMany(ctx context.Context, documentIDs []string) error {
+	m.batches = append(m.batches, documentIDs)
+	deleted := make(map[string]bool)
+	for _, id := range documentIDs {
+		deleted[id] = true
+	}
+	kept := m.registered[:0]
+	for _, doc := range m.registered {
+		if !deleted[doc.ID] {
+			kept = append(kept, doc)
+		}
+	}
+	m.registered = kept
+	return nil
+}
+
+func TestIngestUseCase_DeleteUnder(t *testing.T) {
+	store := &mockBatchStore{}
+	store.registered = []entities.DocumentInfo{
+		{ID: "a", Path: "/docs/sub/a.txt"},
+		{ID: "b", Path: "/docs/sub/deeper/b.txt"},
+		{ID: "c", Path: "/docs/subway.txt"},
+		{ID: "d", Path: "/docs/d.txt"},
+		{ID: "upload"},
+	}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	before := uc.CorpusVersion()
+
+	n, err := uc.DeleteUnder(context.Background(), "", "/docs/sub/")
+	if err != nil {
+		t.Fatalf("delete under failed: %v", err)
+	}
+	if n != 2 || len(store.batches) != 1 || strings.Join(store.batches[0], ",") != "a,b" {
+		t.Errorf("expected one batch of a,b, got %d deleted in %v", n, store.batches)
+	}
+	if uc.CorpusVersion() == before {
+		t.Error("batch delete should bump the corpus version")
+	}
+
+	if n, _ := uc.DeleteUnder(context.Background(), "", "/elsewhere"); n != 0 || len(store.batches) != 1 {
+		t.Errorf("nothing should be deleted outside dir, got %d", n)
+	}
+}
+
+// mockHashingStore reports hashes of the chunks it holds
+type mockHashingStore struct {
+	mockVectorStore
+}
+
+func (m *mockHashingStore) ChunkHashes(ctx context.Context, docID string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	for _, c := range m.chunks {
+		if c.DocumentID == docID {
+			hashes[c.ID] = c.Hash
+		}
+	}
+	return hashes, nil
+}
+
+func TestIngestUseCase_SkipsUnchangedChunks(t *testing.T) {
+	embedded := 0
+	embedder := &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		embedded++
+		return []float32{0.1, 0.2, 0.3}, nil
+	}}
+	store := &mockHashingStore{}
+	uc := NewIngestUseCase(embedder, store, 20, 0)
+
+	doc := &entities.Document{ID: "doc-1", Content: "alpha beta gamma delta epsilon zeta eta theta"}
+	if err := uc.Ingest(context.Background(), doc); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	first := embedded
+	if first == 0 || store.chunks[0].Hash == "" {
+		t.Fatalf("expected hashed chunks to be embedded, got %d embeddings", first)
+	}
+
+	// Identical content re-embeds nothing
+	if err := uc.Ingest(context.Background(), doc); err != nil {
+		t.Fatalf("re-ingest failed: %v", err)
+	}
+	if embedded != first {
+		t.Errorf("re-ingest embedded %d chunks, want 0", embedded-first)
+	}
+
+	// Changing the first chunk re-embeds only that one
+	doc.Content = "ALPHA beta gamma delta epsilon zeta eta theta"
+	if err := uc.Ingest(context.Background(), doc); err != nil {
+		t.Fatalf("re-ingest failed: %v", err)
+	}
+	if embedded != first+1 {
+		t.Errorf("edit embedded %d chunks, want 1", embedded-first)
+	}
+}
+
+// mockUpsertStore records the policy of each Upsert
+type mockUpsertStore struct {
+	mockHashingStore
+	policies []ports.ConflictPolicy
+}
+
+func (m *mockUpsertStore) Upsert(ctx context.Context, chunks []entities.Chunk, policy ports.ConflictPolicy) error {
+	m.policies = append(m.policies, policy)
+	return m.Store(ctx, chunks)
+}
+
+func TestIngestUseCase_ConflictPolicy(t *testing.T) {
+	embedded := 0
+	embedder := &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		embedded++
+		return []float32{0.1, 0.2, 0.3}, nil
+	}}
+	store := &mockUpsertStore{}
+	uc := NewIngestUseCase(embedder, store, 20, 0)
+	ctx := context.Background()
+
+	doc := &entities.Document{ID: "doc-1", Content: "alpha beta gamma delta epsilon zeta eta theta"}
+	uc.Ingest(ctx, doc)
+	first := embedded
+
+	// Replace rewrites unchanged chunks too
+	uc.SetConflictPolicy(ports.ConflictReplace)
+	if err := uc.Ingest(ctx, doc); err != nil {
+		t.Fatalf("replace failed: %v", err)
+	}
+	if embedded != 2*first || store.policies[1] != ports.ConflictReplace {
+		t.Errorf("replace embedded %d chunks with %v, want %d", embedded-first, store.policies, first)
+	}
+
+	// Skip leaves edited chunks alone and adds only new ones
+	uc.SetConflictPolicy(ports.ConflictSkip)
+	doc.Content = "ALPHA beta gamma delta epsilon zeta eta theta iota kappa lambda"
+	before := embedded
+	if err := uc.Ingest(ctx, doc); err != nil {
+		t.Fatalf("skip failed: %v", err)
+	}
+	if embedded-before != 1 || store.policies[2] != ports.ConflictSkip {
+		t.Errorf("skip embedded %d chunks, want only the new one", embedded-before)
+	}
+
+	uc = NewIngestUseCase(embedder, &mockHashingStore{}, 20, 0)
+	uc.SetConflictPolicy(ports.ConflictSkip)
+	if err := uc.Ingest(ctx, doc); err != ErrConflictPolicyUnsupported {
+		t.Errorf("expected ErrConflictPolicyUnsupported, got %v", err)
+	}
+}
+
+// mockLoader serves documents from a map of path to content
+type mockLoader struct {
+	files map[string]string
+}
+
+func (m *mockLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return nil, errors.New("file not found")
+	}
+	return &entities.Document{ID: path, Name: path, Path: path, Content: content}, nil
+}
+
+func (m *mockLoader) SupportedExtensions() []string {
+	return []string{".txt", ".md"}
+}
+
+func TestIngestUseCase_IngestFilesReport(t *testing.T) {
+	embedder := &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		if strings.Contains(text, "poison") {
+			return nil, errors.New("model unavailable")
+		}
+		return []float32{0.1, 0.2, 0.3}, nil
+	}}
+	store := &mockHashingStore{}
+	uc := NewIngestUseCase(embedder, store, 100, 0)
+	loader := &mockLoader{files: map[string]string{
+		"a.txt":    "alpha beta gamma",
+		"b.md":     "delta epsilon",
+		"bad.txt":  "poison pill",
+		"empty.md": "   ",
+	}}
+
+	ctx := context.Background()
+	paths := []string{"a.txt", "b.md", "bad.txt", "empty.md", "missing.txt", "image.png"}
+	report, err := uc.IngestFiles(ctx, loader, "", paths)
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if report.FilesProcessed != 2 || report.ChunksCreated != 2 || report.ChunksUnchanged != 0 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if len(report.Skipped) != 2 || report.Skipped[0].Path != "empty.md" || report.Skipped[1].Path != "image.png" {
+		t.Errorf("unexpected skipped files: %+v", report.Skipped)
+	}
+	if len(report.Errors) != 2 || report.Errors[0].Path != "bad.txt" || !strings.Contains(report.Errors[0].Reason, "model unavailable") {
+		t.Errorf("unexpected errors: %+v", report.Errors)
+	}
+	if report.Duration <= 0 {
+		t.Error("duration should be recorded")
+	}
+
+	// Re-ingesting unchanged files counts their chunks as unchanged
+	report, _ = uc.IngestFiles(ctx, loader, "", []string{"a.txt"})
+	if report.FilesProcessed != 1 || report.ChunksCreated != 0 || report.ChunksUnchanged != 1 {
+		t.Errorf("unexpected re-ingest counts: %+v", report)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := uc.IngestFiles(cancelled, loader, "", paths); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// anyPathLoader is a mockLoader listing no extensions, as URL loaders do
+type anyPathLoader struct {
+	mockLoader
+}
+
+func (m *anyPathLoader) SupportedExtensions() []string {
+	return nil
+}
+
+func TestIngestUseCase_IngestFilesAnyPath(t *testing.T) {
+	uc := NewIngestUseCase(&mockEmbedder{}, &mockHashingStore{}, 100, 0)
+	loader := &anyPathLoader{mockLoader{files: map[string]string{"https://example.com/docs": "alpha beta"}}}
+
+	report, err := uc.IngestFiles(context.Background(), loader, "", []string{"https://example.com/docs"})
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if report.FilesProcessed != 1 || len(report.Skipped) != 0 {
+		t.Errorf("a loader listing no extensions should be given every path: %+v", report)
+	}
+}
+
+func TestIngestUseCase_IngestFilesProgress(t *testing.T) {
+	embedder := &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		time.Sleep(5 * time.Millisecond)
+		return []float32{0.1, 0.2, 0.3}, nil
+	}}
+	uc := NewIngestUseCase(embedder, &mockHashingStore{}, 100, 0)
+	loader := &mockLoader{files: map[string]string{"a.txt": "alpha", "b.txt": "beta", "c.txt": "gamma", "d.txt": "delta"}}
+
+	var updates []entities.IngestProgress
+	_, err := uc.IngestFilesWithProgress(context.Background(), loader, "", []string{"a.txt", "b.txt", "c.txt", "d.txt"}, func(p entities.IngestProgress) {
+		updates = append(updates, p)
+	})
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if len(updates) != 5 {
+		t.Fatalf("expected an update per file and a final one, got %d", len(updates))
+	}
+	if first := updates[0]; first.FilesDone != 0 || first.FilesTotal != 4 || first.Current != "a.txt" || first.Remaining != 0 {
+		t.Errorf("unexpected first update %+v", first)
+	}
+	// Half way, the remaining two files are expected to take as long as the first two
+	if half := updates[2]; half.FilesDone != 2 || half.ChunksDone != 2 || (half.Elapsed-half.Remaining).Abs() > time.Nanosecond {
+		t.Errorf("unexpected estimate half way: %+v", half)
+	}
+	if last := updates[4]; last.FilesDone != 4 || last.ChunksDone != 4 || last.Remaining != 0 || last.Current != "" || last.ChunksPerSecond <= 0 {
+		t.Errorf("unexpected final update %+v", last)
+	}
+}
+
+func TestIngestUseCase_CorpusVersion(t *testing.T) {
+	uc := NewIngestUseCase(&mockEmbedder{}, &mockHashingStore{}, 100, 0)
+	ctx := context.Background()
+	doc := &entities.Document{ID: "doc-1", Content: "some content"}
+
+	uc.Ingest(ctx, doc)
+	v := uc.CorpusVersion()
+	if v == 0 {
+		t.Fatal("ingest should bump the corpus version")
+	}
+
+	uc.Ingest(ctx, doc)
+	if uc.CorpusVersion() != v {
+		t.Error("re-ingesting unchanged content should keep the version")
+	}
+
+	uc.Delete(ctx, "doc-1")
+	if uc.CorpusVersion() == v {
+		t.Error("delete should bump the corpus version")
+	}
+}
+
+// mockMaintainedStore records pruned documents and maintenance runs
+type mockMaintainedStore struct {
+	mockRegistryStore
+	deleted     []string
+	maintenance int
+}
+
+func (m *mockMaintainedStore) Delete(ctx context.Context, docID string) error {
+	m.deleted = append(m.deleted, docID)
+	return nil
+}
+
+func (m *mockMaintainedStore) Maintain(ctx context.Context) (ports.MaintenanceReport, error) {
+	m.maintenance++
+	return ports.MaintenanceReport{OrphansRemoved: 2}, nil
+}
+
+func TestIngestUseCase_Maintain(t *testing.T) {
+	store := &mockMaintainedStore{}
+	store.registered = []entities.DocumentInfo{
+		{ID: "kept", Path: "/docs/kept.txt"},
+		{ID: "gone", Path: "/docs/gone.txt"},
+		{ID: "upload"},
+	}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+
+	report, err := uc.Maintain(context.Background(), func(path string) bool {
+		return path == "/docs/kept.txt"
+	})
+	if err != nil {
+		t.Fatalf("maintain failed: %v", err)
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != "gone" || report.PrunedDocuments != 1 {
+		t.Errorf("expected only the missing document pruned, got %v", store.deleted)
+	}
+	if store.maintenance != 1 || report.OrphansRemoved != 2 {
+		t.Errorf("store maintenance not reported: %+v", report)
+	}
+}
+
+// mockPartialEmbedder rejects texts containing "poison" per item, as the
+// embedding adapters do, until heal is set.
+type mockPartialEmbedder struct {
+	calls [][]string
+	heal  int // Retries after which poison texts embed
+}
+
+func (m *mockPartialEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func (m *mockPartialEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	m.calls = append(m.calls, texts)
+	result := make([][]float32, len(texts))
+	failed := make(map[int]error)
+	for i, text := range texts {
+		if strings.Contains(text, "poison") && len(m.calls) <= m.heal {
+			failed[i] = errors.New("input rejected")
+			continue
+		}
+		result[i] = []float32{0.1, 0.2, 0.3}
+	}
+	if len(failed) > 0 {
+		return result, &ports.BatchEmbedError{Failed: failed}
+	}
+	return result, nil
+}
+
+func TestIngestUseCase_EmbedPolicy(t *testing.T) {
+	ctx := context.Background()
+	doc := &entities.Document{ID: "doc-1", Content: "alpha beta. poison pill. gamma delta."}
+
+	// Fail-fast leaves the document unstored
+	store := &mockVectorStore{}
+	uc := NewIngestUseCase(&mockPartialEmbedder{heal: 100}, store, 12, 0)
+	err := uc.Ingest(ctx, doc)
+	var batchErr *ports.BatchEmbedError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 {
+		t.Fatalf("expected one failed text, got %v", err)
+	}
+	if len(store.chunks) != 0 {
+		t.Errorf("fail-fast should store nothing, got %d chunks", len(store.chunks))
+	}
+
+	// Skipping stores the rest
+	store = &mockVectorStore{}
+	uc = NewIngestUseCase(&mockPartialEmbedder{heal: 100}, store, 12, 0)
+	uc.SetEmbedPolicy(EmbedPolicy{SkipFailed: true})
+	if err := uc.Ingest(ctx, doc); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if len(store.chunks) < 2 {
+		t.Fatalf("expected the other chunks to be stored, got %d", len(store.chunks))
+	}
+	for _, c := range store.chunks {
+		if strings.Contains(c.Content, "poison") {
+			t.Errorf("failed chunk should not be stored: %q", c.Content)
+		}
+	}
+
+	// Retries resend only the failed text
+	embedder := &mockPartialEmbedder{heal: 1}
+	store = &mockVectorStore{}
+	uc = NewIngestUseCase(embedder, store, 12, 0)
+	uc.SetEmbedPolicy(EmbedPolicy{Retries: 2})
+	if err := uc.Ingest(ctx, doc); err != nil {
+		t.Fatalf("ingest with retry failed: %v", err)
+	}
+	if len(embedder.calls) != 2 || len(embedder.calls[1]) != 1 || !strings.Contains(embedder.calls[1][0], "poison") {
+		t.Errorf("retry should resend only the failed text, got %q", embedder.calls)
+	}
+	for _, c := range store.chunks {
+		if c.Embedding == nil {
+			t.Errorf("chunk %d stored without an embedding", c.Index)
+		}
+	}
+}
+
+func TestIngestUseCase_IngestFilesReportsFailedChunks(t *testing.T) {
+	uc := NewIngestUseCase(&mockPartialEmbedder{heal: 100}, &mockHashingStore{}, 12, 0)
+	uc.SetEmbedPolicy(EmbedPolicy{SkipFailed: true})
+	loader := &mockLoader{files: map[string]string{"a.txt": "alpha beta. poison pill. gamma delta."}}
+
+	report, err := uc.IngestFiles(context.Background(), loader, "", []string{"a.txt"})
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if report.FilesProcessed != 1 || report.ChunksFailed != 1 || report.ChunksCreated == 0 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Path != "a.txt" || !strings.Contains(report.Errors[0].Reason, "input rejected") {
+		t.Errorf("unexpected errors: %+v", report.Errors)
+	}
+}
+
+func TestIngestUseCase_DeleteMatching(t *testing.T) {
+	store := &mockBatchStore{}
+	store.registered = []entities.DocumentInfo{
+		{ID: "a", Name: "handbook.pdf", Metadata: map[string]string{"tag": "policy"}},
+		{ID: "b", Name: "handbook-draft.pdf", Metadata: map[string]string{"tag": "draft"}},
+		{ID: "c", Name: "minutes.md", Metadata: map[string]string{"tag": "policy"}},
+	}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	expr := entities.FilterAnd{
+		entities.FilterTerm{Field: entities.FilterFieldDoc, Value: "handbook*"},
+		entities.FilterNot{Expr: entities.FilterTerm{Field: "tag", Value: "policy"}},
+	}
+
+	n, err := uc.DeleteMatching(context.Background(), "", entities.DocumentFilter{Expr: expr})
+	if err != nil {
+		t.Fatalf("delete matching failed: %v", err)
+	}
+	if n != 1 || len(store.batches) != 1 || strings.Join(store.batches[0], ",") != "b" {
+		t.Errorf("expected only the draft deleted, got %d deleted in %v", n, store.batches)
+	}
+
+	schemas := NewMetadataSchemas()
+	schemas.Set("", entities.MetadataSchema{{Name: "pages", Type: entities.FieldNumber}})
+	uc.SetMetadataSchemas(schemas)
+	_, err = uc.DeleteMatching(context.Background(), "", entities.DocumentFilter{Expr: entities.FilterTerm{Field: "tag", Value: "policy"}})
+	if !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("expected ErrInvalidMetadata for an undeclared field, got %v", err)
+	}
+}
+
+func TestIngestUseCase_Retention(t *testing.T) {
+	now := time.Now()
+	store := &mockBatchStore{}
+	store.registered = []entities.DocumentInfo{
+		{ID: "old", IngestedAt: now.Add(-48 * time.Hour)},
+		{ID: "undated"},
+		{ID: "mid", IngestedAt: now.Add(-2 * time.Hour)},
+		{ID: "recent", IngestedAt: now.Add(-10 * time.Minute)},
+	}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	ctx := context.Background()
+
+	// Ingesting applies the policy to the collection
+	uc.SetRetention(RetentionPolicy{MaxAge: 24 * time.Hour})
+	if err := uc.Ingest(ctx, &entities.Document{ID: "new", Content: "fresh notes"}); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if len(store.batches) != 1 || strings.Join(store.batches[0], ",") != "old" {
+		t.Errorf("expected only the expired document evicted, got %v", store.batches)
+	}
+
+	// Count limits evict the oldest, undated documents first
+	uc.SetRetention(RetentionPolicy{MaxDocuments: 2})
+	n, err := uc.EnforceRetention(ctx, "")
+	if err != nil {
+		t.Fatalf("enforce failed: %v", err)
+	}
+	if n != 2 || strings.Join(store.batches[1], ",") != "mid,undated" {
+		t.Errorf("expected mid and undated evicted, got %d: %v", n, store.batches)
+	}
+
+	uc.SetRetention(RetentionPolicy{MaxDocuments: 1})
+	report, err := uc.Maintain(ctx, func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("maintain failed: %v", err)
+	}
+	if report.EvictedDocuments != 1 || len(store.registered) != 1 || store.registered[0].ID != "new" {
+		t.Errorf("maintain should evict down to the newest document, got %+v, %+v", report, store.registered)
+	}
+
+	uc.SetRetention(RetentionPolicy{})
+	if n, _ := uc.EnforceRetention(ctx, ""); n != 0 {
+		t.Errorf("the zero policy should keep everything, evicted %d", n)
+	}
+}
+
+func TestIngestUseCase_CollectionRetention(t *testing.T) {
+	now := time.Now()
+	store := &mockBatchStore{}
+	store.registered = []entities.DocumentInfo{
+		{ID: "oldest", ChunkCount: 4, IngestedAt: now.Add(-3 * time.Hour)},
+		{ID: "older", ChunkCount: 4, IngestedAt: now.Add(-2 * time.Hour)},
+		{ID: "newest", ChunkCount: 4, IngestedAt: now.Add(-time.Hour)},
+	}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	ctx := context.Background()
+
+	// The collection's own policy replaces the default one
+	uc.SetRetention(RetentionPolicy{MaxDocuments: 1})
+	uc.SetCollectionRetention("", RetentionPolicy{MaxChunks: 9})
+	n, err := uc.EnforceRetention(ctx, entities.DefaultCollection)
+	if err != nil {
+		t.Fatalf("enforce failed: %v", err)
+	}
+	if n != 1 || strings.Join(store.batches[0], ",") != "oldest" {
+		t.Errorf("expected the oldest evicted to fit 9 chunks, got %d: %v", n, store.batches)
+	}
+
+	// A zero collection policy exempts it from the default
+	uc.SetCollectionRetention(entities.DefaultCollection, RetentionPolicy{})
+	if n, _ := uc.EnforceRetention(ctx, ""); n != 0 {
+		t.Errorf("an exempt collection should keep everything, evicted %d", n)
+	}
+}
+
+// mockCollectionStore adds ports.CollectionStore, one mockVectorStore
+// per collection
+type mockCollectionStore struct {
+	mockVectorStore
+	collections map[string]*mockVectorStore
+}
+
+func (m *mockCollectionStore) Collection(name string) ports.VectorStore {
+	if m.collections == nil {
+		m.collections = make(map[string]*mockVectorStore)
+	}
+	if m.collections[name] == nil {
+		m.collections[name] = &mockVectorStore{}
+	}
+	return m.collections[name]
+}
+
+func (m *mockCollectionStore) Collections(ctx context.Context) ([]string, error) {
+	names := []string{entities.DefaultCollection}
+	for name, c := range m.collections {
+		if len(c.chunks) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func TestIngestUseCase_Sessions(t *testing.T) {
+	store := &mockCollectionStore{}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	ctx := context.Background()
+
+	session, err := uc.AttachToSession(ctx, "", &entities.Document{ID: "upload", Path: "/tmp/x.txt", Content: "private notes"})
+	if err != nil || !validSessionID(session) {
+		t.Fatalf("attach should start a session, got %q, %v", session, err)
+	}
+	attached := store.collections[entities.SessionCollection(session)]
+	if attached == nil || len(attached.chunks) == 0 || len(store.chunks) != 0 {
+		t.Fatalf("attached document should stay out of the corpus")
+	}
+
+	if _, err := uc.AttachToSession(ctx, "../etc", &entities.Document{ID: "x", Content: "text"}); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("expected ErrInvalidSession, got %v", err)
+	}
+	if _, err := uc.AttachToSession(ctx, session, &entities.Document{ID: "empty"}); !errors.Is(err, ErrEmptyDocument) {
+		t.Errorf("expected ErrEmptyDocument, got %v", err)
+	}
+
+	// Live sessions survive a sweep; orphans from a restart do not
+	store.Collection(entities.SessionCollection("orphan")).Store(ctx, []entities.Chunk{{ID: "c"}})
+	if n, err := uc.ExpireSessions(ctx); err != nil || n != 1 {
+		t.Fatalf("expected only the orphan ended, got %d, %v", n, err)
+	}
+	if len(attached.chunks) == 0 {
+		t.Error("a live session should keep its documents")
+	}
+
+	// Idle sessions expire, and Maintain sweeps them
+	uc.SetSessionTTL(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	report, err := uc.Maintain(ctx, func(string) bool { return false })
+	if err != nil || report.ExpiredSessions != 1 || report.PrunedDocuments != 0 {
+		t.Fatalf("maintain should end the idle session only, got %+v, %v", report, err)
+	}
+	if len(attached.chunks) != 0 {
+		t.Error("an ended session should lose its documents")
+	}
+}
+
+// mockReembedStore adds ports.ChunkReader, ports.ModelRecorder and, on
+// the root, ports.CollectionStore and ports.CollectionSwapper
+type mockReembedStore struct {
+	mockRegistryStore
+	model       ports.EmbeddingModel
+	collections map[string]*mockReembedStore
+}
+
+func (m *mockReembedStore) Clear(ctx context.Context) error {
+	m.mockRegistryStore = mockRegistryStore{}
+	m.model = ports.EmbeddingModel{}
+	return nil
+}
+
+func (m *mockReembedStore) Delete(ctx context.Context, documentID string) error {
+	kept := m.chunks[:0]
+	for _, c := range m.chunks {
+		if c.DocumentID != documentID {
+			kept = append(kept, c)
+		}
+	}
+	m.chunks = kept
+	return nil
+}
+
+func (m *mockReembedStore) DocumentChunks(ctx context.Context, documentID string) ([]entities.Chunk, error) {
+	var chunks []entities.Chunk
+	for _, c := range m.chunks {
+		if c.DocumentID == documentID {
+			c.Embedding = nil
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, nil
+}
+
+func (m *mockReembedStore) EmbeddingModel(ctx context.Context) (ports.EmbeddingModel, error) {
+	return m.model, nil
+}
+
+func (m *mockReembedStore) RecordEmbeddingModel(ctx context.Context, model ports.EmbeddingModel) error {
+	m.model = model
+	return nil
+}
+
+func (m *mockReembedStore) Collection(name string) ports.VectorStore {
+	if name == entities.DefaultCollection {
+		return m
+	}
+	if m.collections == nil {
+		m.collections = make(map[string]*mockReembedStore)
+	}
+	if m.collections[name] == nil {
+		m.collections[name] = &mockReembedStore{}
+	}
+	return m.collections[name]
+}
+
+func (m *mockReembedStore) Collections(ctx context.Context) ([]string, error) {
+	names := []string{entities.DefaultCollection}
+	for name, c := range m.collections {
+		if len(c.chunks) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (m *mockReembedStore) SwapCollections(ctx context.Context, a, b string) error {
+	x, y := m.Collection(a).(*mockReembedStore), m.Collection(b).(*mockReembedStore)
+	x.mockRegistryStore, y.mockRegistryStore = y.mockRegistryStore, x.mockRegistryStore
+	x.model, y.model = y.model, x.model
+	return nil
+}
+
+func TestIngestUseCase_ReembedCollection(t *testing.T) {
+	models := NewEmbeddingModels()
+	models.Register("wide", &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		return []float32{0.1, 0.2, 0.3, 0.4}, nil
+	}})
+	models.Register("broken", &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		return nil, errors.New("model not loaded")
+	}})
+
+	store := &mockReembedStore{}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 50, 10)
+	uc.SetEmbeddingModels(models)
+	ctx := context.Background()
+	for _, id := range []string{"a", "b"} {
+		doc := &entities.Document{ID: id, Name: id + ".md", Collection: "notes", Content: strings.Repeat("Some notes to embed again. ", 5)}
+		if err := uc.Ingest(ctx, doc); err != nil {
+			t.Fatalf("ingest failed: %v", err)
+		}
+	}
+	notes := store.collections["notes"]
+	stored := len(notes.chunks)
+	contents := notes.chunks[0].Content
+
+	if _, err := uc.ReembedCollection(ctx, "notes", "missing", nil); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("expected ErrUnknownModel, got %v", err)
+	}
+	unsupported := NewIngestUseCase(&mockEmbedder{}, &mockRegistryStore{}, 50, 10)
+	unsupported.SetEmbeddingModels(models)
+	if _, err := unsupported.ReembedCollection(ctx, "", "wide", nil); !errors.Is(err, ErrReembedUnsupported) {
+		t.Errorf("expected ErrReembedUnsupported, got %v", err)
+	}
+
+	// A failed run leaves the collection as it was
+	if _, err := uc.ReembedCollection(ctx, "notes", "broken", nil); err == nil {
+		t.Fatal("expected the broken model to fail the run")
+	}
+	if notes = store.collections["notes"]; len(notes.chunks) != stored || len(notes.chunks[0].Embedding) != 3 {
+		t.Errorf("expected the collection untouched after a failure, got %d chunks", len(notes.chunks))
+	}
+	if staging := store.collections[entities.StagingCollection("notes")]; len(staging.chunks) != 0 {
+		t.Errorf("expected the staging collection cleared, got %d chunks", len(staging.chunks))
+	}
+
+	var last entities.IngestProgress
+	before := uc.CorpusVersion()
+	report, err := uc.ReembedCollection(ctx, "notes", "wide", func(p entities.IngestProgress) { last = p })
+	if err != nil {
+		t.Fatalf("re-embed failed: %v", err)
+	}
+	if report.FilesProcessed != 2 || report.ChunksCreated != stored {
+		t.Errorf("expected 2 documents and %d chunks, got %+v", stored, report)
+	}
+	if last.FilesDone != 2 || last.FilesTotal != 2 || last.ChunksDone != stored {
+		t.Errorf("expected final progress for every document, got %+v", last)
+	}
+	notes = store.collections["notes"]
+	if len(notes.chunks) != stored || notes.chunks[0].Content != contents || len(notes.chunks[0].Embedding) != 4 {
+		t.Errorf("expected the same chunks with new embeddings, got %+v", notes.chunks[0])
+	}
+	if len(notes.registered) != 2 {
+		t.Errorf("expected the documents registered in the new collection, got %v", notes.registered)
+	}
+	if want := (ports.EmbeddingModel{Name: "wide", Dimension: 4}); notes.model != want {
+		t.Errorf("expected %+v recorded, got %+v", want, notes.model)
+	}
+	if name, _ := models.Resolve("notes", nil); name != "wide" {
+		t.Errorf("expected notes bound to wide, got %q", name)
+	}
+	if staging := store.collections[entities.StagingCollection("notes")]; len(staging.chunks) != 0 || staging.model != (ports.EmbeddingModel{}) {
+		t.Errorf("expected the staging collection emptied, got %d chunks", len(staging.chunks))
+	}
+	previous := store.collections[entities.PreviousCollection("notes")]
+	if len(previous.chunks) != stored || len(previous.chunks[0].Embedding) != 3 {
+		t.Errorf("expected the old vectors kept as the previous version, got %d chunks", len(previous.chunks))
+	}
+	if uc.CorpusVersion() == before {
+		t.Error("expected the corpus version to change")
+	}
+
+	// Rolling back restores the old vectors and binding, and rolling
+	// back again restores the new ones
+	if err := uc.RollbackCollection(ctx, "notes"); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	notes = store.collections["notes"]
+	if len(notes.chunks) != stored || len(notes.chunks[0].Embedding) != 3 {
+		t.Errorf("expected the old vectors back, got %+v", notes.chunks[0])
+	}
+	if name, _ := models.Resolve("notes", nil); name != "" {
+		t.Errorf("expected notes unbound again, got %q", name)
+	}
+	if err := uc.RollbackCollection(ctx, "notes"); err != nil {
+		t.Fatalf("second rollback failed: %v", err)
+	}
+	if notes = store.collections["notes"]; len(notes.chunks[0].Embedding) != 4 {
+		t.Errorf("expected the new vectors back, got %+v", notes.chunks[0])
+	}
+	if name, _ := models.Resolve("notes", nil); name != "wide" {
+		t.Errorf("expected notes bound to wide again, got %q", name)
+	}
+
+	// An empty model rebuilds with the collection's own model
+	if _, err := uc.ReembedCollection(ctx, "notes", "", nil); err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+	if notes = store.collections["notes"]; len(notes.chunks) != stored || len(notes.chunks[0].Embedding) != 4 {
+		t.Errorf("expected the collection rebuilt with wide, got %+v", notes.chunks[0])
+	}
+
+	if err := uc.DiscardRollback(ctx, "notes"); err != nil {
+		t.Fatalf("discard failed: %v", err)
+	}
+	if err := uc.RollbackCollection(ctx, "notes"); !errors.Is(err, ErrNoRollback) {
+		t.Errorf("expected ErrNoRollback after discarding, got %v", err)
+	}
+}
+
+func TestIngestUseCase_ReembedKeepsConcurrentWrites(t *testing.T) {
+	store := &mockReembedStore{}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 50, 10)
+	ctx := context.Background()
+	ingest := func(id string) {
+		doc := &entities.Document{ID: id, Name: id + ".md", Collection: "notes", Content: strings.Repeat("Notes about "+id+". ", 5)}
+		if err := uc.Ingest(ctx, doc); err != nil {
+			t.Fatalf("ingest failed: %v", err)
+		}
+	}
+	ingest("a")
+	ingest("b")
+
+	// The new model's first request stands for writes landing mid-run
+	written := false
+	models := NewEmbeddingModels()
+	models.Register("wide", &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		if !written {
+			written = true
+			ingest("c")
+			if err := uc.DeleteFromCollection(ctx, "notes", "b"); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+		}
+		return []float32{0.1, 0.2, 0.3, 0.4}, nil
+	}})
+	uc.SetEmbeddingModels(models)
+
+	if _, err := uc.ReembedCollection(ctx, "notes", "wide", nil); err != nil {
+		t.Fatalf("re-embed failed: %v", err)
+	}
+	docs := map[string]int{}
+	for _, c := range store.collections["notes"].chunks {
+		if len(c.Embedding) != 4 {
+			t.Errorf("chunk %s kept its old vector", c.ID)
+		}
+		docs[c.DocumentID]++
+	}
+	if docs["a"] == 0 || docs["c"] == 0 || docs["b"] != 0 {
+		t.Errorf("expected a and c carried over without b, got chunks per document %v", docs)
+	}
+
+	// Writes go through again once the run is over
+	ingest("d")
+	landed := false
+	for _, c := range store.collections["notes"].chunks {
+		landed = landed || c.DocumentID == "d"
+	}
+	if !landed {
+		t.Error("expected writes after the re-embed to land")
+	}
+}
+
+func TestIngestUseCase_MaintainSkipsReembedCollections(t *testing.T) {
+	store := &mockReembedStore{}
+	gone := entities.DocumentInfo{ID: "gone", Path: "/docs/gone.txt"}
+	for _, name := range []string{"notes", entities.StagingCollection("notes"), entities.PreviousCollection("notes")} {
+		c := store.Collection(name).(*mockReembedStore)
+		c.chunks = []entities.Chunk{{ID: "c1", DocumentID: "gone"}}
+		c.registered = []entities.DocumentInfo{gone}
+	}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+
+	report, err := uc.Maintain(context.Background(), func(string) bool { return false })
+	if err != nil {
+		t.Fatalf("maintain failed: %v", err)
+	}
+	if report.PrunedDocuments != 1 || len(store.collections["notes"].chunks) != 0 {
+		t.Errorf("expected the document pruned from notes only, got %+v", report)
+	}
+	if len(store.collections[entities.PreviousCollection("notes")].chunks) != 1 || len(store.collections[entities.StagingCollection("notes")].chunks) != 1 {
+		t.Error("re-embed collections should be left alone")
+	}
+}
+
+func TestIngestUseCase_ProbeEmbeddings(t *testing.T) {
+	models := NewEmbeddingModels()
+	models.Register("wide", &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		return []float32{0.1, 0.2, 0.3, 0.4}, nil
+	}})
+	models.Bind("notes", "wide")
+
+	store := &mockReembedStore{}
+	store.model = ports.EmbeddingModel{Dimension: 3}
+	notes := store.Collection("notes").(*mockReembedStore)
+	notes.chunks = []entities.Chunk{{ID: "c1"}}
+	notes.model = ports.EmbeddingModel{Name: "wide", Dimension: 4}
+
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 50, 10)
+	uc.SetEmbeddingModels(models)
+	ctx := context.Background()
+	dims, err := uc.ProbeEmbeddings(ctx)
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if len(dims) != 2 || dims[""] != 3 || dims["wide"] != 4 {
+		t.Errorf("expected widths 3 and 4, got %v", dims)
+	}
+
+	// A model swapped under a collection fails with both widths named
+	models.Register("wide", &mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		return make([]float32, 8), nil
+	}})
+	_, err = uc.ProbeEmbeddings(ctx)
+	if !errors.Is(err, ErrModelMismatch) || !strings.Contains(err.Error(), `"notes" holds wide (4 dimensions), not wide (8 dimensions)`) {
+		t.Errorf("expected a mismatch naming notes, got %v", err)
+	}
+
+	down := NewIngestUseCase(&mockEmbedder{embedFn: func(text string) ([]float32, error) {
+		return nil, errors.New("connection refused")
+	}}, store, 50, 10)
+	if _, err := down.ProbeEmbeddings(ctx); err == nil || errors.Is(err, ErrModelMismatch) {
+		t.Errorf("expected the embedding error, got %v", err)
+	}
+}
+
+// mockSnapshotStore adds ports.SnapshotReader over snapshots by path
+type mockSnapshotStore struct {
+	mockReembedStore
+	snapshots map[string]*mockReembedStore
+}
+
+type mockSnapshot struct {
+	*mockReembedStore
+}
+
+func (mockSnapshot) Close() error { return nil }
+
+func (m *mockSnapshotStore) OpenSnapshot(ctx context.Context, path string) (ports.SnapshotStore, error) {
+	snap, ok := m.snapshots[path]
+	if !ok {
+		return nil, errors.New("no such snapshot")
+	}
+	return mockSnapshot{snap}, nil
+}
+
+func TestIngestUseCase_DiffSnapshots(t *testing.T) {
+	chunk := func(id, doc, content string) entities.Chunk {
+		return entities.Chunk{ID: id, DocumentID: doc, Content: content, Hash: content}
+	}
+	docs := func(names ...string) []entities.DocumentInfo {
+		var infos []entities.DocumentInfo
+		for _, name := range names {
+			infos = append(infos, entities.DocumentInfo{ID: name, Name: name + ".md"})
+		}
+		return infos
+	}
+	before := &mockReembedStore{}
+	before.chunks = []entities.Chunk{
+		chunk("a0", "a", "intro"), chunk("a1", "a", "body"),
+		chunk("b0", "b", "gone"), chunk("c0", "c", "same"),
+	}
+	before.registered = docs("a", "b", "c")
+
+	store := &mockSnapshotStore{snapshots: map[string]*mockReembedStore{"before.snap": before}}
+	store.chunks = []entities.Chunk{
+		chunk("a0", "a", "intro"), chunk("a1", "a", "body, revised"), chunk("a2", "a", "appendix"),
+		chunk("c0", "c", "same"), chunk("d0", "d", "new"),
+	}
+	store.registered = docs("a", "c", "d")
+	papers := store.Collection("papers").(*mockReembedStore)
+	papers.chunks = []entities.Chunk{chunk("p0", "p", "paper"), chunk("p1", "p", "more")}
+	papers.registered = docs("p")
+
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	ctx := context.Background()
+	diff, err := uc.DiffSnapshots(ctx, "before.snap", "")
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if len(diff.Collections) != 2 || diff.Collections[0].Name != entities.DefaultCollection || diff.Collections[1].Name != "papers" {
+		t.Fatalf("expected default and papers to differ, got %+v", diff.Collections)
+	}
+	c := diff.Collections[0]
+	if len(c.Added) != 1 || c.Added[0].ID != "d" || len(c.Removed) != 1 || c.Removed[0].ChunksRemoved != 1 || c.Unchanged != 1 {
+		t.Errorf("expected d added, b removed and c unchanged, got %+v", c)
+	}
+	if len(c.Changed) != 1 || c.Changed[0] != (DocumentDiff{ID: "a", Name: "a.md", ChunksAdded: 1, ChunksChanged: 1}) {
+		t.Errorf("expected a with one chunk changed and one added, got %+v", c.Changed)
+	}
+	if c.ChunksBefore != 4 || c.ChunksAfter != 5 {
+		t.Errorf("expected 4 -> 5 chunks, got %d -> %d", c.ChunksBefore, c.ChunksAfter)
+	}
+
+	report := diff.Report()
+	for _, line := range []string{
+		"default: 1 added, 1 removed, 1 changed, 1 unchanged documents; chunks 4 -> 5 (+1)",
+		"  + d.md (1 chunk)",
+		"  - b.md (1 chunk)",
+		"  ~ a.md: 1 chunk changed, 1 chunk added",
+		"papers: 1 added, 0 removed, 0 changed, 0 unchanged documents; chunks 0 -> 2 (+2)",
+	} {
+		if !strings.Contains(report, line+"\n") {
+			t.Errorf("expected %q in the report:\n%s", line, report)
+		}
+	}
+
+	if diff, err := uc.DiffSnapshots(ctx, "before.snap", "before.snap"); err != nil || !diff.Empty() || diff.Report() != "No differences.\n" {
+		t.Errorf("expected a snapshot to match itself, got %+v, %v", diff, err)
+	}
+	if _, err := uc.DiffSnapshots(ctx, "missing.snap", ""); err == nil {
+		t.Error("expected an error for a missing snapshot")
+	}
+	plain := NewIngestUseCase(&mockEmbedder{}, &mockReembedStore{}, 100, 20)
+	if _, err := plain.DiffSnapshots(ctx, "before.snap", ""); !errors.Is(err, ErrDiffUnsupported) {
+		t.Errorf("expected ErrDiffUnsupported, got %v", err)
+	}
+}
+
+// batchRecordingEmbedder records the size of each EmbedBatch call.
+type batchRecordingEmbedder struct {
+	mockEmbedder
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (m *batchRecordingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	m.mu.Lock()
+	m.sizes = append(m.sizes, len(texts))
+	m.mu.Unlock()
+	return m.mockEmbedder.EmbedBatch(ctx, texts)
+}
+
+func TestIngestUseCase_Throttle(t *testing.T) {
+	embedder := &batchRecordingEmbedder{}
+	store := &mockVectorStore{}
+	uc := NewIngestUseCase(embedder, store, 50, 10)
+	throttle := NewThrottle(ThrottlePolicy{BatchSize: 2})
+	uc.SetThrottle(throttle)
+
+	doc := &entities.Document{
+		ID:      "big",
+		Content: "word word word word word word word word word word word word word word word word word word word word",
+	}
+	done := throttle.Interactive()
+	ingested := make(chan error, 1)
+	go func() { ingested <- uc.Ingest(context.Background(), doc) }()
+
+	select {
+	case err := <-ingested:
+		t.Fatalf("ingest finished during a query: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	embedder.mu.Lock()
+	if len(embedder.sizes) != 0 {
+		t.Errorf("embedded %v during a query", embedder.sizes)
+	}
+	embedder.mu.Unlock()
+
+	done()
+	if err := <-ingested; err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	total := 0
+	for _, n := range embedder.sizes {
+		if n > 2 {
+			t.Errorf("batch of %d texts, want at most 2", n)
+		}
+		total += n
+	}
+	if total != len(store.chunks) || total < 3 {
+		t.Errorf("embedded %d texts in %v for %d chunks", total, embedder.sizes, len(store.chunks))
+	}
+
+	// MaxPause lets ingestion continue under constant queries
+	throttle = NewThrottle(ThrottlePolicy{MaxPause: 10 * time.Millisecond})
+	uc.SetThrottle(throttle)
+	defer throttle.Interactive()()
+	doc.ID = "other"
+	if err := uc.Ingest(context.Background(), doc); err != nil {
+		t.Fatalf("ingest under MaxPause failed: %v", err)
+	}
+}
+
+// mockMonitor implements ports.ResourceMonitor with a settable sample.
+type mockMonitor struct {
+	mu    sync.Mutex
+	usage ports.ResourceUsage
+}
+
+func (m *mockMonitor) set(cpu, memory float64) {
+	m.mu.Lock()
+	m.usage = ports.ResourceUsage{CPU: cpu, Memory: memory}
+	m.mu.Unlock()
+}
+
+func (m *mockMonitor) Usage(ctx context.Context) (ports.ResourceUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage, nil
+}
+
+func TestIngestUseCase_Guardrails(t *testing.T) {
+	embedder := &batchRecordingEmbedder{}
+	store := &mockVectorStore{}
+	uc := NewIngestUseCase(embedder, store, 50, 10)
+	monitor := &mockMonitor{}
+	guard := NewGuardrails(monitor, ResourceLimits{Memory: 0.8, BatchSize: 2})
+	uc.SetGuardrails(guard)
+
+	monitor.set(0.1, 0.95)
+	if err := guard.Sample(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	doc := &entities.Document{
+		ID:      "big",
+		Content: "word word word word word word word word word word word word word word word word word word word word",
+	}
+	ingested := make(chan error, 1)
+	go func() { ingested <- uc.Ingest(context.Background(), doc) }()
+
+	select {
+	case err := <-ingested:
+		t.Fatalf("ingest finished while overloaded: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	embedder.mu.Lock()
+	if len(embedder.sizes) != 0 {
+		t.Errorf("embedded %v while overloaded", embedder.sizes)
+	}
+	embedder.mu.Unlock()
+
+	monitor.set(0.1, 0.5)
+	guard.Sample(context.Background())
+	if err := <-ingested; err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	for _, n := range embedder.sizes {
+		if n > 2 {
+			t.Errorf("batch of %d texts, want at most 2", n)
+		}
+	}
+}
+
+// mockGitReader reports fixed changes since one known commit
+type mockGitReader struct {
+	known            string
+	changed, removed []string
+}
+
+func (m *mockGitReader) Checkout(ctx context.Context, source string) (ports.GitCheckout, error) {
+	return ports.GitCheckout{}, errors.New("not used")
+}
+
+func (m *mockGitReader) Changes(ctx context.Context, co ports.GitCheckout, since string) ([]string, []string, bool, error) {
+	if since != m.known {
+		return nil, nil, false, nil
+	}
+	return m.changed, m.removed, true, nil
+}
+
+func TestIngestUseCase_SyncGit(t *testing.T) {
+	earlier := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	registered := []entities.DocumentInfo{
+		{ID: "a", Path: "/repo/a.go", IngestedAt: earlier, Metadata: map[string]string{"commit": "c1"}},
+		{ID: "b", Path: "/repo/b.go", IngestedAt: earlier.Add(time.Hour), Metadata: map[string]string{"commit": "c2"}},
+		{ID: "c", Path: "/repo/old.go", IngestedAt: earlier, Metadata: map[string]string{"commit": "c1"}},
+		{ID: "d", Path: "/elsewhere/d.txt", IngestedAt: earlier},
+	}
+	co := ports.GitCheckout{Root: "/repo", Head: "c3", Paths: []string{"/repo/a.go", "/repo/b.go", "/repo/new.go"}}
+	ctx := context.Background()
+
+	store := &mockBatchStore{}
+	store.registered = append([]entities.DocumentInfo(nil), registered...)
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	reader := &mockGitReader{known: "c2", changed: []string{"/repo/new.go"}, removed: []string{"/repo/old.go"}}
+	sync, err := uc.SyncGit(ctx, "", reader, co, false)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if sync.Since != "c2" || strings.Join(sync.Paths, ",") != "/repo/new.go" || sync.Removed != 1 || strings.Join(store.batches[0], ",") != "c" {
+		t.Errorf("expected the changes since the latest commit, got %+v, deleted %v", sync, store.batches)
+	}
+
+	// A commit outside the history takes every file, dropping untracked ones
+	store = &mockBatchStore{}
+	store.registered = append([]entities.DocumentInfo(nil), registered...)
+	uc = NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	reader.known = "gone"
+	if sync, err = uc.SyncGit(ctx, "", reader, co, false); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if sync.Since != "" || len(sync.Paths) != 3 || sync.Removed != 1 || strings.Join(store.batches[0], ",") != "c" {
+		t.Errorf("expected a full ingest, got %+v, deleted %v", sync, store.batches)
+	}
+}
+
+func TestIngestUseCase_UnseenPaths(t *testing.T) {
+	store := &mockRegistryStore{registered: []entities.DocumentInfo{{ID: "a", Path: "https://blog.example.com/a"}}}
+	uc := NewIngestUseCase(&mockEmbedder{}, store, 100, 20)
+	paths := []string{"https://blog.example.com/b", "https://blog.example.com/a", "https://blog.example.com/c"}
+
+	unseen, err := uc.UnseenPaths(context.Background(), "", paths)
+	if err != nil || strings.Join(unseen, ",") != "https://blog.example.com/b,https://blog.example.com/c" {
+		t.Errorf("expected the new paths in order, got %v, %v", unseen, err)
+	}
+
+	uc = NewIngestUseCase(&mockEmbedder{}, &mockVectorStore{}, 100, 20)
+	if unseen, err := uc.UnseenPaths(context.Background(), "", paths); err != nil || len(unseen) != 3 {
+		t.Errorf("without a registry every path is new, got %v, %v", unseen, err)
+	}
+}
@@ -0,0 +1,134 @@
+// Package usecases - language.go guesses the language of a text.
+package usecases
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageNames maps the codes detectLanguage returns to English names,
+// as used in translation prompts.
+var languageNames = map[string]string{
+	"en": "English",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+	"it": "Italian",
+	"pt": "Portuguese",
+	"nl": "Dutch",
+	"ru": "Russian",
+	"el": "Greek",
+	"ar": "Arabic",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"zh": "Chinese",
+	"ja": "Japanese",
+	"ko": "Korean",
+}
+
+// stopwords holds frequent words, including question words, of the
+// Latin-script languages detectLanguage tells apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "of", "to", "in", "that", "it", "with", "for", "what", "how", "why", "when", "which", "who", "does", "do", "this", "be", "on", "not"},
+	"de": {"der", "die", "das", "und", "ist", "sind", "nicht", "ein", "eine", "mit", "für", "von", "zu", "den", "dem", "wie", "was", "warum", "wann", "welche", "wer", "auf", "auch", "sich"},
+	"fr": {"le", "la", "les", "et", "est", "sont", "des", "un", "une", "du", "pour", "que", "qui", "dans", "pas", "avec", "quel", "quelle", "comment", "pourquoi", "quand", "ce", "sur", "au"},
+	"es": {"el", "la", "los", "las", "y", "es", "son", "de", "un", "una", "que", "en", "por", "para", "con", "no", "qué", "cómo", "cuál", "cuándo", "quién", "del", "se", "lo"},
+	"it": {"il", "lo", "la", "gli", "le", "e", "è", "sono", "di", "un", "una", "che", "per", "con", "non", "come", "cosa", "perché", "quando", "quale", "chi", "del", "della", "nel"},
+	"pt": {"o", "a", "os", "as", "e", "é", "são", "de", "um", "uma", "que", "em", "para", "com", "não", "do", "da", "como", "qual", "quando", "quem", "por", "se", "no"},
+	"nl": {"de", "het", "een", "en", "is", "zijn", "van", "te", "dat", "niet", "met", "voor", "op", "wat", "hoe", "waarom", "wanneer", "welke", "wie", "ook", "er", "aan", "bij", "naar"},
+}
+
+// stopwordSets indexes stopwords for lookup.
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwords))
+	for lang, words := range stopwords {
+		set := make(map[string]bool, len(words))
+		for _, w := range words {
+			set[w] = true
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// detectLanguage guesses the language of text, returning a key of
+// languageNames, or "" when it cannot tell. Texts in a non-Latin script
+// are identified by their script; Latin-script texts by the language
+// whose stopwords they use most, which needs at least two hits and a
+// clear lead, so short or mixed texts often stay undecided.
+func detectLanguage(text string) string {
+	if lang := scriptLanguage(text); lang != "" {
+		return lang
+	}
+
+	hits := make(map[string]int, len(stopwordSets))
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		for lang, set := range stopwordSets {
+			if set[w] {
+				hits[lang]++
+			}
+		}
+	}
+	best, first, second := "", 0, 0
+	for lang, n := range hits {
+		if n > first {
+			best, first, second = lang, n, first
+		} else if n > second {
+			second = n // Includes ties with first, which leave it undecided
+		}
+	}
+	if first < 2 || first < second+max(2, second/2) {
+		return ""
+	}
+	return best
+}
+
+// scriptLanguage identifies text mostly written in a script used by one
+// language, or "" for Latin script and undecided mixes.
+func scriptLanguage(text string) string {
+	scripts := []struct {
+		lang  string
+		table *unicode.RangeTable
+	}{
+		{"ru", unicode.Cyrillic},
+		{"el", unicode.Greek},
+		{"ar", unicode.Arabic},
+		{"he", unicode.Hebrew},
+		{"hi", unicode.Devanagari},
+		{"ko", unicode.Hangul},
+		{"ja", unicode.Hiragana},
+		{"ja", unicode.Katakana},
+		{"zh", unicode.Han},
+	}
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Japanese mixes kana with Han characters
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	for lang, n := range counts {
+		if n*2 > letters {
+			return lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+// Package usecases - maintenance.go prunes stale documents and compacts the store.
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// MaintenanceReport summarizes a Maintain run.
+type MaintenanceReport struct {
+	PrunedDocuments  int // Documents removed because their source file is gone
+	EvictedDocuments int // Documents removed by the retention policy
+	ExpiredSessions  int // Idle or orphaned chat sessions ended, see ExpireSessions
+	ports.MaintenanceReport
+}
+
+// Maintain removes documents whose source file no longer exists from
+// every collection, evicts those the retention policy no longer allows
+// and ends expired chat sessions, then lets the store clean up and
+// compact itself if it implements ports.Maintainer. sourceExists is injected so this layer
+// stays free of filesystem access; documents without a path, such as
+// uploads, are never pruned. The staging and previous collections of
+// re-embeds are left alone, so a rollback restores what was replaced.
+func (uc *IngestUseCase) Maintain(ctx context.Context, sourceExists func(path string) bool) (MaintenanceReport, error) {
+	var report MaintenanceReport
+
+	collections := []string{entities.DefaultCollection}
+	if cs, ok := uc.vectorStore.(ports.CollectionStore); ok {
+		names, err := cs.Collections(ctx)
+		if err != nil {
+			return report, fmt.Errorf("listing collections: %w", err)
+		}
+		collections = names
+	}
+
+	for _, collection := range collections {
+		if entities.IsSessionCollection(collection) {
+			continue // Ended by ExpireSessions below
+		}
+		if entities.IsReembedCollection(collection) {
+			continue // Left for ReembedCollection and RollbackCollection
+		}
+		docs, err := uc.ListDocuments(ctx, collection)
+		if err == ErrDocumentsUnsupported {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		for _, doc := range docs {
+			if doc.Path == "" || sourceExists(doc.Path) {
+				continue
+			}
+			if err := uc.DeleteFromCollection(ctx, collection, doc.ID); err != nil {
+				return report, fmt.Errorf("pruning %s: %w", doc.ID, err)
+			}
+			report.PrunedDocuments++
+		}
+
+		evicted, err := uc.EnforceRetention(ctx, collection)
+		report.EvictedDocuments += evicted
+		if err != nil {
+			return report, fmt.Errorf("applying retention to %s: %w", collection, err)
+		}
+	}
+
+	expired, err := uc.ExpireSessions(ctx)
+	report.ExpiredSessions = expired
+	if err != nil {
+		return report, fmt.Errorf("expiring sessions: %w", err)
+	}
+
+	if m, ok := uc.vectorStore.(ports.Maintainer); ok {
+		stats, err := m.Maintain(ctx)
+		report.MaintenanceReport = stats
+		if err != nil {
+			return report, fmt.Errorf("maintaining store: %w", err)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,129 @@
+// Package usecases - metadata.go declares typed metadata fields per collection.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// ErrInvalidMetadata is returned for documents whose metadata does not
+// fit their collection's schema, and for filters the schema rejects.
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// MetadataSchemas holds each collection's metadata schema. The ingest
+// usecase validates documents against it and the query usecase compares
+// filter values by its field types, so both are given the same value.
+// A nil *MetadataSchemas declares no schemas.
+type MetadataSchemas struct {
+	mu      sync.RWMutex
+	schemas map[string]entities.MetadataSchema
+}
+
+// NewMetadataSchemas creates an empty set of schemas.
+func NewMetadataSchemas() *MetadataSchemas {
+	return &MetadataSchemas{schemas: make(map[string]entities.MetadataSchema)}
+}
+
+// Set declares the metadata fields of a collection. Documents already
+// stored are not revalidated.
+func (m *MetadataSchemas) Set(collection string, schema entities.MetadataSchema) error {
+	if err := schema.Validate(); err != nil {
+		return err
+	}
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schemas[collection] = schema
+	return nil
+}
+
+// Get returns the schema of a collection, nil if it has none.
+func (m *MetadataSchemas) Get(collection string) entities.MetadataSchema {
+	if m == nil {
+		return nil
+	}
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.schemas[collection]
+}
+
+// SetMetadataSchemas sets the schemas ingested metadata is validated
+// against and listing filters are typed by.
+func (uc *IngestUseCase) SetMetadataSchemas(schemas *MetadataSchemas) {
+	uc.schemas = schemas
+}
+
+// SetMetadataSchemas sets the schemas metadata filters are typed by.
+func (uc *QueryUseCase) SetMetadataSchemas(schemas *MetadataSchemas) {
+	uc.schemas = schemas
+}
+
+// bindMetadata types a filter's conditions by the collection's schema.
+func bindMetadata(schemas *MetadataSchemas, collection string, conditions []entities.MetadataCondition) ([]entities.MetadataCondition, error) {
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+	bound, err := schemas.Get(collection).Bind(conditions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+	return bound, nil
+}
+
+// bindFilter types a filter's metadata conditions and the metadata
+// terms of its expression by the collection's schema.
+func bindFilter(schemas *MetadataSchemas, collection string, filter entities.DocumentFilter) (entities.DocumentFilter, error) {
+	var err error
+	if filter.Metadata, err = bindMetadata(schemas, collection, filter.Metadata); err != nil {
+		return filter, err
+	}
+	if filter.Expr, err = schemas.Get(collection).BindFilter(filter.Expr); err != nil {
+		return filter, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+	return filter, nil
+}
+
+// scopeToFilter narrows documentIDs, or the whole collection when there
+// are none, to the documents matching filter: metadata conditions and a
+// filter expression. ok is false when no document can match, so the
+// search can be skipped. Both are resolved against the document
+// registry, so they work on every store implementing
+// ports.DocumentRegistry and ports.DocumentSearcher.
+func (uc *QueryUseCase) scopeToFilter(ctx context.Context, store ports.VectorStore, collection string, documentIDs []string, filter entities.DocumentFilter) (ids []string, ok bool, err error) {
+	if len(filter.Metadata) == 0 && filter.Expr == nil {
+		return documentIDs, true, nil
+	}
+	bound, err := bindFilter(uc.schemas, collection, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	reg, isReg := store.(ports.DocumentRegistry)
+	if !isReg {
+		return nil, false, ErrDocumentsUnsupported
+	}
+	page, err := reg.ListDocuments(ctx, bound, entities.Page{})
+	if err != nil {
+		return nil, false, fmt.Errorf("matching filter: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(documentIDs))
+	for _, id := range documentIDs {
+		wanted[id] = true
+	}
+	for _, doc := range page.Documents {
+		if len(documentIDs) == 0 || wanted[doc.ID] {
+			ids = append(ids, doc.ID)
+		}
+	}
+	return ids, len(ids) > 0, nil
+}
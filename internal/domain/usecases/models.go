@@ -0,0 +1,224 @@
+// Package usecases - models.go binds collections to named embedding models.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+var (
+	// ErrUnknownModel is returned for bindings to unregistered models.
+	ErrUnknownModel = errors.New("unknown embedding model")
+
+	// ErrModelMismatch is returned when a collection's recorded
+	// embedding model differs from the one a query or ingest would use.
+	ErrModelMismatch = errors.New("embedding model does not match the collection")
+)
+
+// EmbeddingModels holds named embedding models and the collections bound
+// to them. The ingest and query usecases embed a bound collection's text
+// with its model and other collections' with the default model, or their
+// own embedder when there is none, so both are given the same value.
+// A nil *EmbeddingModels binds nothing.
+type EmbeddingModels struct {
+	mu         sync.RWMutex
+	models     map[string]ports.EmbeddingService
+	bindings   map[string]string // Collection -> model name
+	defaultFor string            // Model for unbound collections; empty uses the usecase's embedder
+}
+
+// NewEmbeddingModels creates an empty registry.
+func NewEmbeddingModels() *EmbeddingModels {
+	return &EmbeddingModels{
+		models:   make(map[string]ports.EmbeddingService),
+		bindings: make(map[string]string),
+	}
+}
+
+// Register adds a model under name, replacing any model of that name.
+func (m *EmbeddingModels) Register(name string, embedder ports.EmbeddingService) error {
+	if name == "" {
+		return fmt.Errorf("embedding model name is empty")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.models[name] = embedder
+	return nil
+}
+
+// Bind makes a collection's text embedded with the named model. An
+// empty model removes the binding.
+func (m *EmbeddingModels) Bind(collection, model string) error {
+	if m == nil {
+		if model == "" {
+			return nil
+		}
+		return fmt.Errorf("%w: %q", ErrUnknownModel, model)
+	}
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if model == "" {
+		delete(m.bindings, collection)
+		return nil
+	}
+	if _, ok := m.models[model]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownModel, model)
+	}
+	m.bindings[collection] = model
+	return nil
+}
+
+// binding returns the model a collection is bound to, "" when unbound.
+func (m *EmbeddingModels) binding(collection string) string {
+	if m == nil {
+		return ""
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.bindings[collection]
+}
+
+// SetDefault sets the model for collections without a binding. An empty
+// model leaves them to the usecases' own embedders.
+func (m *EmbeddingModels) SetDefault(model string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.models[model]; model != "" && !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownModel, model)
+	}
+	m.defaultFor = model
+	return nil
+}
+
+// Names lists the registered models in order.
+func (m *EmbeddingModels) Names() []string {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.models))
+	for name := range m.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the named model's embedder.
+func (m *EmbeddingModels) Get(name string) (ports.EmbeddingService, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	embedder, ok := m.models[name]
+	return embedder, ok
+}
+
+// Resolve returns the name of the model for a collection and its
+// embedder, or "" and fallback when no model applies.
+func (m *EmbeddingModels) Resolve(collection string, fallback ports.EmbeddingService) (string, ports.EmbeddingService) {
+	if m == nil {
+		return "", fallback
+	}
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name, ok := m.bindings[collection]
+	if !ok {
+		name = m.defaultFor
+	}
+	if name == "" {
+		return "", fallback
+	}
+	return name, m.models[name]
+}
+
+// SetEmbeddingModels sets the models collections are embedded with.
+func (uc *IngestUseCase) SetEmbeddingModels(models *EmbeddingModels) {
+	uc.models = models
+}
+
+// SetEmbeddingModels sets the models queries are embedded with, per
+// collection.
+func (uc *QueryUseCase) SetEmbeddingModels(models *EmbeddingModels) {
+	uc.models = models
+}
+
+// checkModel compares model with the one recorded for a collection by
+// stores implementing ports.ModelRecorder. Names are only compared when
+// both are known. When nothing is recorded and record is set, model is
+// recorded, unless the collection already holds embeddings of another
+// width, as corpora built before models were recorded may.
+func checkModel(ctx context.Context, store ports.VectorStore, collection string, model ports.EmbeddingModel, record bool) error {
+	mr, ok := store.(ports.ModelRecorder)
+	if !ok {
+		return nil
+	}
+	recorded, err := mr.EmbeddingModel(ctx)
+	if err != nil {
+		return fmt.Errorf("reading embedding model: %w", err)
+	}
+	if recorded.Dimension == 0 {
+		if !record {
+			return nil
+		}
+		stats, err := store.Stats(ctx)
+		if err != nil {
+			return fmt.Errorf("reading store stats: %w", err)
+		}
+		if stats.Dimension != 0 && stats.Dimension != model.Dimension {
+			recorded.Dimension = stats.Dimension
+			return modelMismatch(collection, recorded, model)
+		}
+		return mr.RecordEmbeddingModel(ctx, model)
+	}
+	if recorded.Dimension != model.Dimension || recorded.Name != "" && model.Name != "" && recorded.Name != model.Name {
+		return modelMismatch(collection, recorded, model)
+	}
+	if record && recorded.Name == "" && model.Name != "" {
+		return mr.RecordEmbeddingModel(ctx, model) // Name the model now that it is known
+	}
+	return nil
+}
+
+func modelMismatch(collection string, recorded, model ports.EmbeddingModel) error {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	return fmt.Errorf("%w: collection %q holds %s, not %s; re-ingest it to switch models",
+		ErrModelMismatch, collection, describeModel(recorded), describeModel(model))
+}
+
+// describeModel names a model and its width, as in
+// "nomic-embed-text (768 dimensions)".
+func describeModel(m ports.EmbeddingModel) string {
+	name := m.Name
+	if name == "" {
+		name = "embeddings"
+	}
+	return fmt.Sprintf("%s (%d dimensions)", name, m.Dimension)
+}
+
+// embeddingWidth returns the dimension of the first embedding present,
+// 0 when every text failed.
+func embeddingWidth(embeddings [][]float32) int {
+	for _, e := range embeddings {
+		if len(e) > 0 {
+			return len(e)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+// Package usecases - probe.go checks embedding widths against the store.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// probeText is embedded to learn a model's output width.
+const probeText = "dimension probe"
+
+// ProbeEmbeddings embeds a probe string with the model of every
+// collection and checks the width it returns against the collection's
+// recorded model, or the width of its stored embeddings when none is
+// recorded. Run at startup, it turns a model swapped under an existing
+// corpus into a clear error instead of searches that rank nonsense.
+//
+// It returns the width of each model probed, keyed by model name; ""
+// stands for the usecase's own embedder. Every mismatch is reported,
+// each wrapping ErrModelMismatch; a model that cannot be reached fails
+// the probe with its embedding error.
+func (uc *IngestUseCase) ProbeEmbeddings(ctx context.Context) (map[string]int, error) {
+	collections := []string{entities.DefaultCollection}
+	cs, isCollections := uc.vectorStore.(ports.CollectionStore)
+	if isCollections {
+		names, err := cs.Collections(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing collections: %w", err)
+		}
+		collections = append(collections, names...)
+	}
+
+	dims := make(map[string]int)
+	checked := make(map[string]bool)
+	var mismatches []error
+	for _, collection := range collections {
+		if checked[collection] {
+			continue
+		}
+		checked[collection] = true
+
+		model, embedder := uc.models.Resolve(collection, uc.embedder)
+		dim, probed := dims[model]
+		if !probed {
+			embedding, err := embedder.Embed(ctx, probeText)
+			if err != nil {
+				name := model
+				if name == "" {
+					name = "the default embedder"
+				}
+				return dims, fmt.Errorf("probing %s: %w", name, err)
+			}
+			dim = len(embedding)
+			dims[model] = dim
+		}
+
+		store, err := storeFor(uc.vectorStore, collection)
+		if err != nil {
+			return dims, err
+		}
+		if err := checkDimension(ctx, store, collection, ports.EmbeddingModel{Name: model, Dimension: dim}); err != nil {
+			if !errors.Is(err, ErrModelMismatch) {
+				return dims, err
+			}
+			mismatches = append(mismatches, err)
+		}
+	}
+	return dims, errors.Join(mismatches...)
+}
+
+// checkDimension is checkModel without recording, falling back to the
+// width of the stored embeddings when no model is recorded.
+func checkDimension(ctx context.Context, store ports.VectorStore, collection string, model ports.EmbeddingModel) error {
+	if err := checkModel(ctx, store, collection, model, false); err != nil {
+		return err
+	}
+	if mr, ok := store.(ports.ModelRecorder); ok {
+		recorded, err := mr.EmbeddingModel(ctx)
+		if err != nil {
+			return fmt.Errorf("reading embedding model: %w", err)
+		}
+		if recorded.Dimension != 0 {
+			return nil // Checked above
+		}
+	}
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("reading store stats: %w", err)
+	}
+	if stats.Dimension != 0 && stats.Dimension != model.Dimension {
+		return modelMismatch(collection, ports.EmbeddingModel{Dimension: stats.Dimension}, model)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Package usecases - prompt.go renders LLM prompts from configurable templates.
+package usecases
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// DefaultPromptTemplate is the built-in prompt, a starting point for
+// SetPromptTemplate.
+const DefaultPromptTemplate = `{{.System}}
+
+Context:
+{{.Context}}{{if .Citations}}
+
+{{.Citations}}{{end}}
+
+Question: {{.Question}}
+
+Answer:`
+
+var defaultPrompt = template.Must(template.New("prompt").Parse(DefaultPromptTemplate))
+
+// promptData is what prompt templates render.
+type promptData struct {
+	System    string // The collection's instruction, see SetSystemPrompt
+	Context   string // Passages joined by blank lines
+	Question  string
+	Query     string // Question, under its original name
+	History   string // Earlier turns, one "User: ..." or "Assistant: ..." each
+	Citations string // The request for citation markers, when enabled
+}
+
+// SetSystemPrompt replaces the built-in instruction, "You are a helpful
+// assistant...", which chat LLMs get as the system message and templates
+// as .System. Code collections keep their own. Empty restores it.
+func (uc *QueryUseCase) SetSystemPrompt(prompt string) {
+	uc.system = prompt
+}
+
+// SetPromptTemplate replaces the built-in prompt with a text/template.
+// It sees .System, .Context (the passages, joined by blank lines),
+// .Question, .History (the earlier turns, one per line, empty for chat
+// LLMs, which get them as messages) and .Citations (the request for
+// markers when SetCitations is on); .Query is .Question. For chat LLMs
+// the template renders the last user message and no system message is
+// sent, as with per-request templates, which take precedence. Empty
+// restores DefaultPromptTemplate.
+func (uc *QueryUseCase) SetPromptTemplate(tmpl string) error {
+	if tmpl == "" {
+		uc.prompt = nil
+		return nil
+	}
+	t, err := template.New("prompt").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parsing prompt template: %w", err)
+	}
+	uc.prompt = t
+	return nil
+}
+
+// LoadPromptTemplate is SetPromptTemplate with the template read from a
+// file.
+func (uc *QueryUseCase) LoadPromptTemplate(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading prompt template: %w", err)
+	}
+	return uc.SetPromptTemplate(string(data))
+}
+
+// templated reports whether the prompt for req comes from a template
+// rather than the built-in one.
+func (uc *QueryUseCase) templated(req *entities.ChatRequest) bool {
+	return req.Overrides.PromptTemplate != "" || uc.prompt != nil
+}
+
+// buildPrompt creates the LLM prompt for req with context, from the
+// request's template, the configured one or the built-in one.
+func (uc *QueryUseCase) buildPrompt(req *entities.ChatRequest, context []string) (string, error) {
+	return uc.buildPromptFor(uc.llm, req, context)
+}
+
+// buildPromptFor is buildPrompt for llm, which gets the history in the
+// prompt unless it is a ports.ChatLLM.
+func (uc *QueryUseCase) buildPromptFor(llm ports.LLMService, req *entities.ChatRequest, context []string) (string, error) {
+	t := uc.prompt
+	if req.Overrides.PromptTemplate != "" {
+		var err error
+		if t, err = template.New("prompt").Parse(req.Overrides.PromptTemplate); err != nil {
+			return "", fmt.Errorf("parsing prompt template: %w", err)
+		}
+	}
+	if t == nil {
+		t = defaultPrompt
+	}
+
+	data := promptData{
+		System:   uc.systemPromptFor(req.Collection),
+		Context:  strings.Join(context, "\n\n"),
+		Question: req.Query,
+		Query:    req.Query,
+	}
+	if _, chat := llm.(ports.ChatLLM); !chat {
+		data.History = historyText(req.History)
+	}
+	if uc.citations {
+		data.Citations = citationInstruction
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("rendering prompt template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// historyText formats the user and assistant turns of a conversation,
+// one per line.
+func historyText(history []entities.ChatMessage) string {
+	var sb strings.Builder
+	for _, m := range history {
+		switch m.Role {
+		case "user":
+			sb.WriteString("User: " + m.Content + "\n")
+		case "assistant":
+			sb.WriteString("Assistant: " + m.Content + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -3,8 +3,10 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"text/template"
+	"time"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
 	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
@@ -13,12 +15,63 @@ import (
 // QueryUseCase handles search and response generation.
 // Single Responsibility: Only query/response logic.
 type QueryUseCase struct {
-	embedder    ports.EmbeddingService
-	vectorStore ports.VectorStore
-	llm         ports.LLMService
-	topK        int
+	embedder      ports.EmbeddingService
+	vectorStore   ports.VectorStore
+	llm           ports.LLMService
+	topK          int
+	hybrid        bool    // Use keyword+vector fusion when the store supports it
+	minScore      float64 // Default relevance cutoff
+	overrides     OverridePolicy
+	followUps     int                // Follow-up questions to suggest, see SetFollowUps
+	embeddings    embeddingCache     // Pre-embedded questions, see Prewarm
+	schemas       *MetadataSchemas   // Types metadata filters; nil when none
+	translate     bool               // Translate passages into the query's language, see SetTranslation
+	models        *EmbeddingModels   // Per-collection embedding models; nil when none
+	genTimeout    time.Duration      // Wait for the LLM before answering extractively; 0 waits indefinitely
+	canaries      *Canaries          // Regression questions, see RunCanaries; nil when none
+	deterministic bool               // Pin temperature and seed for every answer, see SetDeterministic
+	seed          int64              // Seed of deterministic answers
+	citations     bool               // Number passages and keep valid [n] markers, see SetCitations
+	throttle      *Throttle          // Marks queries so background embedding yields; nil when none
+	code          map[string]bool    // Collections holding source code, see SetCodeCollection
+	system        string             // Instruction replacing systemPrompt, see SetSystemPrompt
+	prompt        *template.Template // Prompt replacing the built-in one; nil when none
+	budget        ContextBudget      // Fits context into the LLM's window, see SetContextBudget
+	guard         *Guardrails        // Queues queries while the host is overloaded; nil when none
+	fallbacks     []LLMBackend       // Tried in order when the LLM fails, see SetFallbacks
+	responses     *responseCache     // Answers to prompts asked before, see SetResponseCache; nil when off
 }
 
+// maxStopSequences is the most stop sequences a request may give, as
+// OpenAI-compatible servers take no more.
+const maxStopSequences = 4
+
+// OverridePolicy is the allowlist for per-request LLM overrides
+// (entities.LLMOverrides). The zero value rejects every override.
+type OverridePolicy struct {
+	Models         []string // Models a request may select; "*" allows any
+	Temperature    bool
+	PromptTemplate bool
+	Sampling       bool // top_p, top_k and repeat_penalty
+	MaxNumCtx      int  // Largest context window a request may ask for; 0 allows none
+	Stop           bool // Stop sequences
+	MaxNumPredict  int  // Largest answer length in tokens a request may ask for; 0 allows none
+}
+
+var (
+	// ErrOverrideNotAllowed is returned for overrides outside the policy.
+	ErrOverrideNotAllowed = errors.New("LLM override not allowed")
+
+	// ErrDocumentFilterUnsupported is returned for document-scoped
+	// searches when the store does not implement ports.DocumentSearcher.
+	ErrDocumentFilterUnsupported = errors.New("vector store does not support document filters")
+
+	// ErrOverrideUnsupported is returned for model or sampling overrides,
+	// or deterministic answers, when the LLM implements
+	// neither ports.TunableLLM nor ports.ChatLLM.
+	ErrOverrideUnsupported = errors.New("LLM does not support per-request settings")
+)
+
 // NewQueryUseCase creates a QueryUseCase with injected dependencies.
 func NewQueryUseCase(
 	embedder ports.EmbeddingService,
@@ -34,59 +87,488 @@ func NewQueryUseCase(
 		vectorStore: vectorStore,
 		llm:         llm,
 		topK:        topK,
+		seed:        defaultSeed,
+	}
+}
+
+// SetHybrid enables hybrid BM25 + vector retrieval.
+// Stores that don't implement ports.HybridSearcher fall back to pure vector search.
+func (uc *QueryUseCase) SetHybrid(enabled bool) {
+	uc.hybrid = enabled
+}
+
+// SetMinScore sets the default relevance cutoff applied when a request
+// does not give its own. Scores are cosine similarity, or the fused 0..1
+// score in hybrid mode.
+func (uc *QueryUseCase) SetMinScore(score float64) {
+	uc.minScore = score
+}
+
+// SetOverridePolicy sets which per-request LLM overrides are accepted.
+func (uc *QueryUseCase) SetOverridePolicy(policy OverridePolicy) {
+	uc.overrides = policy
+}
+
+// CheckOverrides validates overrides against the policy and the LLM's
+// capabilities, so callers can reject a request before doing any work.
+func (uc *QueryUseCase) CheckOverrides(o entities.LLMOverrides) error {
+	if o.Model != "" && !uc.modelAllowed(o.Model) {
+		return fmt.Errorf("%w: model %q", ErrOverrideNotAllowed, o.Model)
+	}
+	if o.Temperature != nil {
+		if !uc.overrides.Temperature {
+			return fmt.Errorf("%w: temperature", ErrOverrideNotAllowed)
+		}
+		if *o.Temperature < 0 {
+			return fmt.Errorf("invalid temperature %g", *o.Temperature)
+		}
+		if *o.Temperature != 0 && uc.deterministicFor(o) {
+			return fmt.Errorf("temperature %g in deterministic mode, which pins it to 0", *o.Temperature)
+		}
 	}
+	if o.TopP != nil || o.TopK != nil || o.RepeatPenalty != nil {
+		if !uc.overrides.Sampling {
+			return fmt.Errorf("%w: sampling settings", ErrOverrideNotAllowed)
+		}
+		if o.TopP != nil && (*o.TopP <= 0 || *o.TopP > 1) {
+			return fmt.Errorf("invalid top_p %g, want (0, 1]", *o.TopP)
+		}
+		if o.TopK != nil && *o.TopK < 1 {
+			return fmt.Errorf("invalid top_k %d", *o.TopK)
+		}
+		if o.RepeatPenalty != nil && *o.RepeatPenalty <= 0 {
+			return fmt.Errorf("invalid repeat_penalty %g", *o.RepeatPenalty)
+		}
+	}
+	if o.NumCtx != nil {
+		if *o.NumCtx > uc.overrides.MaxNumCtx {
+			return fmt.Errorf("%w: num_ctx %d above %d", ErrOverrideNotAllowed, *o.NumCtx, uc.overrides.MaxNumCtx)
+		}
+		if *o.NumCtx < 1 {
+			return fmt.Errorf("invalid num_ctx %d", *o.NumCtx)
+		}
+	}
+	if len(o.Stop) > 0 {
+		if !uc.overrides.Stop {
+			return fmt.Errorf("%w: stop sequences", ErrOverrideNotAllowed)
+		}
+		if len(o.Stop) > maxStopSequences {
+			return fmt.Errorf("%d stop sequences, at most %d allowed", len(o.Stop), maxStopSequences)
+		}
+		for _, stop := range o.Stop {
+			if stop == "" {
+				return errors.New("empty stop sequence")
+			}
+		}
+	}
+	if o.NumPredict != nil {
+		if *o.NumPredict > uc.overrides.MaxNumPredict {
+			return fmt.Errorf("%w: num_predict %d above %d", ErrOverrideNotAllowed, *o.NumPredict, uc.overrides.MaxNumPredict)
+		}
+		if *o.NumPredict < 1 {
+			return fmt.Errorf("invalid num_predict %d", *o.NumPredict)
+		}
+	}
+	if o.PromptTemplate != "" {
+		if !uc.overrides.PromptTemplate {
+			return fmt.Errorf("%w: prompt template", ErrOverrideNotAllowed)
+		}
+		if _, err := template.New("prompt").Parse(o.PromptTemplate); err != nil {
+			return fmt.Errorf("parsing prompt template: %w", err)
+		}
+	}
+	if _, tuned := uc.generateOptions(o); tuned {
+		_, tunable := uc.llm.(ports.TunableLLM)
+		_, chat := uc.llm.(ports.ChatLLM)
+		if !tunable && !chat {
+			return ErrOverrideUnsupported
+		}
+	}
+	return nil
+}
+
+// Models lists the models a request may select, in policy order, for a
+// model picker. A "*" entry allows any model and is left out, so name
+// the models to offer.
+func (uc *QueryUseCase) Models() []string {
+	models := []string{}
+	for _, m := range uc.overrides.Models {
+		if m != "*" {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// AllowsModel reports whether a request may select model.
+func (uc *QueryUseCase) AllowsModel(model string) bool {
+	return uc.modelAllowed(model)
+}
+
+func (uc *QueryUseCase) modelAllowed(model string) bool {
+	for _, m := range uc.overrides.Models {
+		if m == "*" || m == model {
+			return true
+		}
+	}
+	return false
 }
 
 // Query searches for relevant context and generates a response.
 func (uc *QueryUseCase) Query(ctx context.Context, req *entities.ChatRequest) (*entities.ChatResponse, error) {
-	// 1. Embed the query
-	queryEmbedding, err := uc.embedder.Embed(ctx, req.Query)
+	if err := uc.CheckOverrides(req.Overrides); err != nil {
+		return nil, err
+	}
+	release, err := uc.admit(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("embedding query: %w", err)
+		return nil, err
 	}
+	defer release()
+	defer uc.interactive()()
 
-	// 2. Search vector store
-	results, err := uc.vectorStore.Search(ctx, queryEmbedding, uc.topK)
+	// 1-2. Embed the query and search the vector store
+	results, err := uc.searchRequest(ctx, req.Collection, req.Collections, req.Query, uc.topK, req.DocumentIDs, entities.DocumentFilter{Metadata: req.Metadata, Expr: req.Filter}, req.SessionID)
 	if err != nil {
-		return nil, fmt.Errorf("searching vectors: %w", err)
+		return nil, err
+	}
+	tr := traceFrom(ctx)
+	tr.stage("retrieved", results)
+	results = uc.applyMinScore(results, req.MinScore)
+	tr.stage("min_score", results)
+	if uc.translate {
+		results = uc.translateResults(ctx, req.Query, results)
+		tr.stage("translated", results)
 	}
 
-	// 3. Build context from results
-	contextParts := make([]string, len(results))
-	for i, r := range results {
-		contextParts[i] = fmt.Sprintf("[Source: %s]\n%s", r.SourceDoc, r.Chunk.Content)
+	// 3. Build context from the results that fit the context window
+	results, contextParts, err := uc.fitContext(req, results)
+	if err != nil {
+		return nil, err
 	}
+	tr.stage("context_budget", results)
+	tr.context(contextParts)
 
 	// 4. Generate response via LLM
-	prompt := uc.buildPrompt(req.Query, contextParts)
-	answer, err := uc.llm.Generate(ctx, prompt, contextParts)
+	prompt, err := uc.buildPrompt(req, contextParts)
+	if err != nil {
+		return nil, err
+	}
+	answer, fallback, extractive, err := uc.generate(ctx, req, prompt, contextParts, results)
 	if err != nil {
 		return nil, fmt.Errorf("generating response: %w", err)
 	}
+	var citations []int
+	if uc.citations {
+		answer, citations = ValidateCitations(answer, len(results))
+	}
+
+	// 5. Suggest follow-ups; best effort, a failure keeps the answer.
+	// An LLM that just timed out would only time out again.
+	var followUps []string
+	if !extractive {
+		followUps, _ = uc.FollowUps(ctx, req.Query, answer)
+	}
 
+	params := uc.AnswerParams(req)
+	if fallback != "" {
+		params.Fallback = fallback
+		params.Model = uc.FallbackModel(fallback)
+	}
 	return &entities.ChatResponse{
-		Answer:  answer,
-		Sources: results,
+		Answer:     answer,
+		Sources:    results,
+		FollowUps:  followUps,
+		Extractive: extractive,
+		Citations:  citations,
+		Params:     params,
 	}, nil
 }
 
+// StreamAnswer streams an answer to req over already retrieved results,
+// applying the request's overrides and the generation timeout. With translation enabled, passages
+// are translated for the prompt only; results is left as it is. With
+// citations enabled, markers are validated as they stream.
+func (uc *QueryUseCase) StreamAnswer(ctx context.Context, req *entities.ChatRequest, results []entities.QueryResult) (<-chan ports.StreamToken, error) {
+	if err := uc.CheckOverrides(req.Overrides); err != nil {
+		return nil, err
+	}
+
+	release, err := uc.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results = uc.translateResults(ctx, req.Query, results)
+	results, contextParts, err := uc.fitContext(req, results)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	prompt, err := uc.buildPrompt(req, contextParts)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	tokens, err := uc.streamWithin(ctx, req.Query, results, func(ctx context.Context, b LLMBackend) (<-chan ports.StreamToken, error) {
+		return uc.streamWith(ctx, b, req, prompt, contextParts)
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if uc.citations {
+		tokens = validateStream(ctx, tokens, len(results))
+	}
+	if uc.throttle != nil {
+		tokens = untilClosed(ctx, tokens, uc.throttle.Interactive())
+	}
+	if uc.guard != nil {
+		tokens = untilClosed(ctx, tokens, release)
+	}
+	return tokens, nil
+}
+
 // Search only retrieves relevant chunks without LLM generation.
 func (uc *QueryUseCase) Search(ctx context.Context, query string) ([]entities.QueryResult, error) {
-	embedding, err := uc.embedder.Embed(ctx, query)
+	return uc.SearchCollection(ctx, entities.DefaultCollection, query)
+}
+
+// SearchCollection retrieves relevant chunks from the named collection.
+func (uc *QueryUseCase) SearchCollection(ctx context.Context, collection, query string) ([]entities.QueryResult, error) {
+	return uc.SearchPage(ctx, collection, query, entities.SearchOptions{})
+}
+
+// SearchPage retrieves one page of ranked chunks from the named collection,
+// dropping those below the score threshold. A page shorter than the limit
+// means there are no more relevant results.
+func (uc *QueryUseCase) SearchPage(ctx context.Context, collection, query string, opts entities.SearchOptions) ([]entities.QueryResult, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = uc.topK
+	}
+	offset := opts.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	defer uc.interactive()()
+
+	// Stores rank but do not page, so fetch everything up to the page end
+	results, err := uc.searchRequest(ctx, collection, opts.Collections, query, offset+limit, opts.DocumentIDs, entities.DocumentFilter{Metadata: opts.Metadata, Expr: opts.Filter}, opts.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	results = uc.applyMinScore(results, opts.MinScore)
+
+	if offset >= len(results) {
+		return []entities.QueryResult{}, nil
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// SearchProgressive is SearchPage that first passes lexical hits to
+// preview, before the query is embedded, so callers can show likely
+// sources while the slower stages run. The preview only happens in
+// hybrid mode on stores implementing ports.KeywordSearcher, and not
+// for federated queries. It is best-effort: its errors are ignored.
+// The final ranked page is returned.
+func (uc *QueryUseCase) SearchProgressive(ctx context.Context, collection, query string, opts entities.SearchOptions, preview func([]entities.QueryResult)) ([]entities.QueryResult, error) {
+	if uc.hybrid && len(opts.Collections) == 0 && len(opts.DocumentIDs) == 0 && len(opts.Metadata) == 0 && opts.Filter == nil {
+		if store, err := storeFor(uc.vectorStore, collection); err == nil {
+			if ks, ok := store.(ports.KeywordSearcher); ok {
+				limit := opts.Limit
+				if limit <= 0 {
+					limit = uc.topK
+				}
+				if hits, err := ks.KeywordSearch(ctx, query, limit); err == nil && len(hits) > 0 {
+					preview(hits)
+				}
+			}
+		}
+	}
+	return uc.SearchPage(ctx, collection, query, opts)
+}
+
+// searchRequest embeds a query and retrieves the topK chunks of a
+// collection, or of collections together when any are given.
+func (uc *QueryUseCase) searchRequest(ctx context.Context, collection string, collections []entities.CollectionWeight, query string, topK int, documentIDs []string, filter entities.DocumentFilter, sessionID string) ([]entities.QueryResult, error) {
+	if len(collections) > 0 {
+		return uc.searchFederated(ctx, collections, query, topK, documentIDs, filter, sessionID)
+	}
+	store, err := storeFor(uc.vectorStore, collection)
+	if err != nil {
+		return nil, err
+	}
+	embedding, model, err := uc.embed(ctx, collection, query)
+	if err != nil {
+		return nil, fmt.Errorf("embedding query: %w", err)
+	}
+	return uc.search(ctx, store, collection, query, embedding, model, topK, documentIDs, filter, sessionID)
+}
+
+// search retrieves a collection's topK chunks, narrowed to documentIDs
+// and to documents matching filter, and merges in the best matches among
+// a session's attached documents. The query's embedding must come from
+// the model recorded for the collection.
+func (uc *QueryUseCase) search(ctx context.Context, store ports.VectorStore, collection, query string, embedding []float32, model ports.EmbeddingModel, topK int, documentIDs []string, filter entities.DocumentFilter, sessionID string) ([]entities.QueryResult, error) {
+	if err := checkModel(ctx, store, collection, model, false); err != nil {
+		return nil, err
+	}
+	ids, ok, err := uc.scopeToFilter(ctx, store, collection, documentIDs, filter)
+	if err != nil {
+		return nil, err
+	}
+	var results []entities.QueryResult
+	if ok {
+		code := uc.isCode(collection)
+		fetch := topK
+		if code {
+			fetch *= codeOverfetch
+		}
+		if results, err = uc.retrieve(ctx, store, collection, query, embedding, fetch, ids); err != nil {
+			return nil, err
+		}
+		if code {
+			results = boostSymbols(query, results, topK)
+		} else {
+			sortResults(results)
+		}
+	}
+	return uc.withSession(ctx, sessionID, query, embedding, model, topK, results)
+}
+
+// retrieve runs hybrid or pure vector search depending on configuration,
+// and attaches each result's provenance. Searches scoped to documentIDs
+// are vector-only.
+func (uc *QueryUseCase) retrieve(ctx context.Context, store ports.VectorStore, collection, query string, embedding []float32, topK int, documentIDs []string) ([]entities.QueryResult, error) {
+	var results []entities.QueryResult
+	var err error
+	mode := "vector"
+	start := time.Now()
+	if len(documentIDs) > 0 {
+		ds, ok := store.(ports.DocumentSearcher)
+		if !ok {
+			return nil, ErrDocumentFilterUnsupported
+		}
+		mode = "documents"
+		results, err = ds.SearchDocuments(ctx, embedding, topK, documentIDs)
+	} else if hs, ok := store.(ports.HybridSearcher); ok && uc.hybrid {
+		mode = "hybrid"
+		results, err = hs.HybridSearch(ctx, query, embedding, topK)
+	} else {
+		results, err = store.Search(ctx, embedding, topK)
+	}
+	if err == nil {
+		results, err = withProvenance(ctx, store, results)
+	}
+	traceFrom(ctx).search(TraceSearch{Collection: collection, Mode: mode, Query: query, TopK: topK, DocumentIDs: documentIDs, Results: results, Error: errorText(err), Duration: time.Since(start)})
 	if err != nil {
 		return nil, err
 	}
-	return uc.vectorStore.Search(ctx, embedding, uc.topK)
+	return results, nil
 }
 
-// buildPrompt creates the LLM prompt with context.
-func (uc *QueryUseCase) buildPrompt(query string, context []string) string {
-	var sb strings.Builder
-	sb.WriteString("You are a helpful assistant. Answer the question based on the provided context.\n\n")
-	sb.WriteString("Context:\n")
-	sb.WriteString(strings.Join(context, "\n\n"))
-	sb.WriteString("\n\nQuestion: ")
-	sb.WriteString(query)
-	sb.WriteString("\n\nAnswer:")
-	return sb.String()
+// withProvenance copies the provenance, source path and ingest time of
+// each result's document from the registry of stores implementing
+// ports.DocumentRegistry, so citations can flag machine-derived text and
+// name source files, and answers can later be checked for staleness.
+func withProvenance(ctx context.Context, store ports.VectorStore, results []entities.QueryResult) ([]entities.QueryResult, error) {
+	reg, ok := store.(ports.DocumentRegistry)
+	if !ok || len(results) == 0 {
+		return results, nil
+	}
+	seen := make(map[string]bool, len(results))
+	var ids []string
+	for _, r := range results {
+		if !seen[r.Chunk.DocumentID] {
+			seen[r.Chunk.DocumentID] = true
+			ids = append(ids, r.Chunk.DocumentID)
+		}
+	}
+	page, err := reg.ListDocuments(ctx, entities.DocumentFilter{IDs: ids}, entities.Page{})
+	if err != nil {
+		return nil, fmt.Errorf("reading provenance: %w", err)
+	}
+	docs := make(map[string]entities.DocumentInfo, len(page.Documents))
+	for _, doc := range page.Documents {
+		docs[doc.ID] = doc
+	}
+	for i := range results {
+		doc := docs[results[i].Chunk.DocumentID]
+		results[i].Provenance, results[i].SourcePath, results[i].IngestedAt = doc.Provenance, doc.Path, doc.IngestedAt
+	}
+	return results, nil
+}
+
+// withSession merges the best matches among a session's attached
+// documents into results, keeping the overall top K. Document filters
+// narrow the corpus only; attached documents are always searched, with
+// the query embedded again if they use another model than model.
+func (uc *QueryUseCase) withSession(ctx context.Context, sessionID, query string, embedding []float32, model ports.EmbeddingModel, topK int, results []entities.QueryResult) ([]entities.QueryResult, error) {
+	if sessionID == "" {
+		return results, nil
+	}
+	if !validSessionID(sessionID) {
+		return nil, ErrInvalidSession
+	}
+	collection := entities.SessionCollection(sessionID)
+	store, err := storeFor(uc.vectorStore, collection)
+	if err != nil {
+		return nil, err
+	}
+	if name, _ := uc.models.Resolve(collection, uc.embedder); name != model.Name {
+		if embedding, _, err = uc.embed(ctx, collection, query); err != nil {
+			return nil, fmt.Errorf("embedding query for session documents: %w", err)
+		}
+	}
+	attached, err := uc.retrieve(ctx, store, collection, query, embedding, topK, nil)
+	if err != nil {
+		return nil, fmt.Errorf("searching session documents: %w", err)
+	}
+
+	merged := append(append([]entities.QueryResult(nil), results...), attached...)
+	sortResults(merged)
+	if len(merged) > topK {
+		merged = merged[:topK]
+	}
+	return merged, nil
+}
+
+// applyMinScore drops results below minScore, or below the configured
+// default when minScore is 0.
+func (uc *QueryUseCase) applyMinScore(results []entities.QueryResult, minScore float64) []entities.QueryResult {
+	if minScore == 0 {
+		minScore = uc.minScore
+	}
+	if minScore == 0 {
+		return results
+	}
+	kept := results[:0]
+	for _, r := range results {
+		if r.Score >= minScore {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// buildContext formats results as cited context passages, citing the
+// page of paginated sources such as PDFs. Citations of
+// machine-derived text name the transformations, so answers can say so,
+// and those of federated results name their collection.
+func buildContext(results []entities.QueryResult) []string {
+	contextParts := make([]string, len(results))
+	for i, r := range results {
+		source := r.Citation()
+		if r.Collection != "" {
+			source += "; collection: " + r.Collection
+		}
+		if r.Provenance.MachineDerived() {
+			source += "; machine-derived: " + r.Provenance.String()
+		}
+		contextParts[i] = fmt.Sprintf("[Source: %s]\n%s", source, r.Chunk.Content)
+	}
+	return contextParts
 }
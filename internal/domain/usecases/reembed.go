@@ -0,0 +1,348 @@
+// Package usecases - reembed.go moves a collection to another embedding model.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// ErrReembedUnsupported is returned when the store cannot read chunks
+// back or swap collections.
+var ErrReembedUnsupported = errors.New("vector store cannot re-embed collections")
+
+// ErrNoRollback is returned by RollbackCollection when no re-embed of the
+// collection has left its previous contents behind.
+var ErrNoRollback = errors.New("no previous version of the collection to roll back to")
+
+// ReembedCollection re-embeds the stored text of every chunk in a
+// collection with the named model and switches the collection over to
+// it, so changing models needs no re-ingestion from the source files.
+// The new vectors are written to a staging collection, which then swaps
+// contents with the collection in one step: searches keep running on
+// the old vectors until then, see the new ones after, never a mix, and
+// a failed run leaves the collection as it was. The collection is then
+// bound to model, and its old contents are kept as the collection's
+// previous version for RollbackCollection, replacing any older one.
+//
+// An empty model rebuilds the collection with the model it already
+// uses, such as to refresh its index after an embedder upgrade. Any
+// other model must be registered with the models given to
+// SetEmbeddingModels, and the store must implement ports.CollectionStore,
+// ports.DocumentRegistry, ports.ChunkReader and ports.CollectionSwapper.
+// Documents ingested into or deleted from the collection during the run
+// are carried over before the switch: writes to the collection then wait
+// while those documents are re-embedded, and resume once it is bound to
+// model. progress is called as by IngestFilesWithProgress, counting
+// documents as files.
+func (uc *IngestUseCase) ReembedCollection(ctx context.Context, collection, model string, progress func(entities.IngestProgress)) (report entities.IngestReport, err error) {
+	start := time.Now()
+	defer func() { report.Duration = time.Since(start) }()
+	if progress == nil {
+		progress = func(entities.IngestProgress) {}
+	}
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+
+	var embedder ports.EmbeddingService
+	if model == "" {
+		model, embedder = uc.models.Resolve(collection, uc.embedder)
+	} else {
+		var ok bool
+		if embedder, ok = uc.models.Get(model); !ok {
+			return report, fmt.Errorf("%w: %q", ErrUnknownModel, model)
+		}
+	}
+	cs, ok := uc.vectorStore.(ports.CollectionStore)
+	swapper, canSwap := uc.vectorStore.(ports.CollectionSwapper)
+	if !ok || !canSwap {
+		return report, ErrReembedUnsupported
+	}
+	source := cs.Collection(collection)
+	reader, canRead := source.(ports.ChunkReader)
+	reg, canList := source.(ports.DocumentRegistry)
+	if !canRead || !canList {
+		return report, ErrReembedUnsupported
+	}
+
+	// Track documents written from here on, before the listing, so none
+	// slips between it and the switch
+	uc.writes.track(collection)
+	defer uc.writes.untrack(collection)
+
+	stagingName := entities.StagingCollection(collection)
+	staging := cs.Collection(stagingName)
+	if err := staging.Clear(ctx); err != nil {
+		return report, fmt.Errorf("clearing staging collection: %w", err)
+	}
+	switched := false
+	defer func() {
+		if !switched {
+			staging.Clear(context.Background()) // Leave nothing half-built behind
+		}
+	}()
+
+	listing, err := reg.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{})
+	if err != nil {
+		return report, fmt.Errorf("listing documents: %w", err)
+	}
+	docs := listing.Documents
+	filesDone := 0
+	defer func() {
+		progress(ingestProgress(start, filesDone, len(docs), report.ChunksCreated, ""))
+	}()
+
+	dim := 0
+	for i, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		filesDone = i
+		progress(ingestProgress(start, filesDone, len(docs), report.ChunksCreated, doc.Name))
+
+		if err := uc.copyDocument(ctx, doc, reader, staging, embedder, &report, &dim); err != nil {
+			return report, err
+		}
+	}
+	filesDone = len(docs)
+
+	// Carry over what was written meanwhile, holding further writes
+	// until the collection is switched and bound to model
+	changed, release := uc.writes.exclusive(collection)
+	defer release()
+	if len(changed) > 0 {
+		for _, id := range changed {
+			if err := staging.Delete(ctx, id); err != nil {
+				return report, fmt.Errorf("replaying %s: %w", id, err)
+			}
+		}
+		listing, err := reg.ListDocuments(ctx, entities.DocumentFilter{IDs: changed}, entities.Page{})
+		if err != nil {
+			return report, fmt.Errorf("listing documents: %w", err)
+		}
+		for _, doc := range listing.Documents {
+			if err := uc.copyDocument(ctx, doc, reader, staging, embedder, &report, &dim); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if dim > 0 {
+		if err := checkModel(ctx, staging, stagingName, ports.EmbeddingModel{Name: model, Dimension: dim}, true); err != nil {
+			return report, err
+		}
+	}
+	previousName := entities.PreviousCollection(collection)
+	if err := cs.Collection(previousName).Clear(ctx); err != nil {
+		return report, fmt.Errorf("dropping previous version: %w", err)
+	}
+	if err := swapper.SwapCollections(ctx, collection, stagingName); err != nil {
+		return report, fmt.Errorf("switching collections: %w", err)
+	}
+	switched = true
+	uc.version.Add(1)
+	oldModel := uc.models.binding(collection)
+	if err := uc.models.Bind(collection, model); err != nil {
+		return report, err
+	}
+	release()
+
+	// The staging collection now holds the old contents; keep them
+	if err := swapper.SwapCollections(ctx, stagingName, previousName); err != nil {
+		return report, fmt.Errorf("keeping previous version: %w", err)
+	}
+	if err := uc.models.Bind(previousName, oldModel); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// copyDocument re-embeds the stored chunks of doc with embedder into
+// staging and registers it there, adding to report. dim is set to the
+// width of the new vectors. Documents without chunks are skipped.
+func (uc *IngestUseCase) copyDocument(ctx context.Context, doc entities.DocumentInfo, reader ports.ChunkReader, staging ports.VectorStore, embedder ports.EmbeddingService, report *entities.IngestReport, dim *int) error {
+	chunks, err := reader.DocumentChunks(ctx, doc.ID)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", doc.Name, err)
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	texts := make([]string, len(chunks))
+	for j, chunk := range chunks {
+		texts[j] = chunk.Content
+	}
+
+	// Every chunk must make it across, whatever the EmbedPolicy
+	started := time.Now()
+	embeddings, failed, err := uc.embedTexts(ctx, embedder, texts, true)
+	report.EmbedDuration += time.Since(started)
+	if err == nil && len(failed) > 0 {
+		err = &ports.BatchEmbedError{Failed: failed}
+	}
+	if err != nil {
+		return fmt.Errorf("embedding %s: %w", doc.Name, err)
+	}
+	for j := range chunks {
+		chunks[j].Embedding = embeddings[j]
+	}
+	*dim = len(embeddings[0])
+
+	started = time.Now()
+	err = staging.Store(ctx, chunks)
+	if err == nil {
+		err = staging.(ports.DocumentRegistry).RegisterDocument(ctx, doc)
+	}
+	report.StoreDuration += time.Since(started)
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", doc.Name, err)
+	}
+	report.FilesProcessed++
+	report.ChunksCreated += len(chunks)
+	return nil
+}
+
+// collectionWrites lets ReembedCollection see which documents of a
+// collection are written while it runs, and hold new writes back for its
+// switch. Ingest and deletes enter it for the documents they touch.
+type collectionWrites struct {
+	mu      sync.Mutex
+	locks   map[string]*sync.RWMutex
+	changed map[string]map[string]bool // Documents written per tracked collection
+}
+
+// lock returns the collection's lock. Caller must hold w.mu.
+func (w *collectionWrites) lock(collection string) *sync.RWMutex {
+	if w.locks == nil {
+		w.locks = make(map[string]*sync.RWMutex)
+	}
+	if w.locks[collection] == nil {
+		w.locks[collection] = &sync.RWMutex{}
+	}
+	return w.locks[collection]
+}
+
+// begin marks documentIDs as written to collection, waiting while a
+// re-embed switches it over, and returns the function ending the write.
+// Writes must not nest.
+func (w *collectionWrites) begin(collection string, documentIDs ...string) func() {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	w.mu.Lock()
+	l := w.lock(collection)
+	w.mu.Unlock()
+
+	l.RLock()
+	w.mu.Lock()
+	if changed, ok := w.changed[collection]; ok {
+		for _, id := range documentIDs {
+			changed[id] = true
+		}
+	}
+	w.mu.Unlock()
+	return l.RUnlock
+}
+
+// track starts recording the documents written to collection.
+func (w *collectionWrites) track(collection string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.changed == nil {
+		w.changed = make(map[string]map[string]bool)
+	}
+	w.changed[collection] = make(map[string]bool)
+}
+
+// untrack stops recording writes to collection.
+func (w *collectionWrites) untrack(collection string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.changed, collection)
+}
+
+// exclusive waits for the writes in progress to collection and holds
+// back new ones until release is called, which may be called again. It
+// returns the documents written since track, and restarts the record.
+func (w *collectionWrites) exclusive(collection string) (changed []string, release func()) {
+	w.mu.Lock()
+	l := w.lock(collection)
+	w.mu.Unlock()
+
+	l.Lock()
+	w.mu.Lock()
+	for id := range w.changed[collection] {
+		changed = append(changed, id)
+	}
+	sort.Strings(changed)
+	w.changed[collection] = make(map[string]bool)
+	w.mu.Unlock()
+
+	var once sync.Once
+	return changed, func() { once.Do(l.Unlock) }
+}
+
+// RollbackCollection switches a collection back to the contents and
+// model it had before its last ReembedCollection, in one step like the
+// re-embed's own switch. The replaced contents become the previous
+// version in turn, so rolling back again undoes the rollback. Documents
+// ingested since the re-embed are lost. It returns ErrNoRollback when
+// there is no previous version.
+func (uc *IngestUseCase) RollbackCollection(ctx context.Context, collection string) error {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	cs, ok := uc.vectorStore.(ports.CollectionStore)
+	swapper, canSwap := uc.vectorStore.(ports.CollectionSwapper)
+	if !ok || !canSwap {
+		return ErrReembedUnsupported
+	}
+	previousName := entities.PreviousCollection(collection)
+	reg, ok := cs.Collection(previousName).(ports.DocumentRegistry)
+	if !ok {
+		return ErrReembedUnsupported
+	}
+	listing, err := reg.ListDocuments(ctx, entities.DocumentFilter{}, entities.Page{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("listing documents: %w", err)
+	}
+	if len(listing.Documents) == 0 {
+		return fmt.Errorf("%w: %q", ErrNoRollback, collection)
+	}
+
+	if err := swapper.SwapCollections(ctx, collection, previousName); err != nil {
+		return fmt.Errorf("switching collections: %w", err)
+	}
+	uc.version.Add(1)
+	current, previous := uc.models.binding(collection), uc.models.binding(previousName)
+	if err := uc.models.Bind(collection, previous); err != nil {
+		return err
+	}
+	return uc.models.Bind(previousName, current)
+}
+
+// DiscardRollback drops the previous version ReembedCollection kept of a
+// collection, freeing its space; the re-embed can no longer be rolled
+// back.
+func (uc *IngestUseCase) DiscardRollback(ctx context.Context, collection string) error {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	cs, ok := uc.vectorStore.(ports.CollectionStore)
+	if !ok {
+		return ErrReembedUnsupported
+	}
+	previousName := entities.PreviousCollection(collection)
+	if err := cs.Collection(previousName).Clear(ctx); err != nil {
+		return fmt.Errorf("dropping previous version: %w", err)
+	}
+	uc.models.Bind(previousName, "")
+	return nil
+}
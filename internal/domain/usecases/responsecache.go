@@ -0,0 +1,126 @@
+// Package usecases - responsecache.go reuses LLM answers to prompts asked again.
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// responseCacheSize bounds the answers kept when SetResponseCache is
+// given no size.
+const responseCacheSize = 256
+
+// SetResponseCache keeps the LLM's answers for ttl, up to size of them
+// (256 when size is 0), keyed by the prompt or chat messages sent, the
+// backend and model, and the generation settings. An identical question
+// over the same passages, common in demos and tests, is then answered
+// without calling the LLM, streamed as one token. The prompt holds the
+// passages, so answers are not reused once the corpus changes what a
+// question retrieves. Sampled answers are reused as they are; pair it
+// with SetDeterministic where that matters. Extractive and failed
+// answers are never kept. A ttl of 0 turns the cache off.
+func (uc *QueryUseCase) SetResponseCache(ttl time.Duration, size int) {
+	if ttl <= 0 {
+		uc.responses = nil
+		return
+	}
+	if size <= 0 {
+		size = responseCacheSize
+	}
+	uc.responses = &responseCache{ttl: ttl, limit: size, entries: make(map[[sha256.Size]byte]cachedResponse)}
+}
+
+// responseCache holds LLM answers by responseKey, oldest evicted first.
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	limit   int
+	entries map[[sha256.Size]byte]cachedResponse
+	order   [][sha256.Size]byte // Insertion order, oldest first
+}
+
+type cachedResponse struct {
+	answer  string
+	expires time.Time
+}
+
+// responseKey identifies what b is asked: the prompt or messages and the
+// settings they are answered with. A nil cache has no keys.
+func (c *responseCache) responseKey(b LLMBackend, opts ports.GenerateOptions, prompt string, contextParts []string, messages []entities.ChatMessage) ([sha256.Size]byte, bool) {
+	if c == nil {
+		return [sha256.Size]byte{}, false
+	}
+	key, err := json.Marshal(struct {
+		Backend  string
+		Options  ports.GenerateOptions
+		Prompt   string
+		Context  []string
+		Messages []entities.ChatMessage
+	}{b.Name, opts, prompt, contextParts, messages})
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	return sha256.Sum256(key), true
+}
+
+func (c *responseCache) get(key [sha256.Size]byte) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return "", false
+	}
+	return e.answer, true
+}
+
+func (c *responseCache) put(key [sha256.Size]byte, answer string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		for len(c.order) >= c.limit {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = cachedResponse{answer: answer, expires: time.Now().Add(c.ttl)}
+}
+
+// cachedStream streams a cached answer as a single token.
+func cachedStream(answer string) <-chan ports.StreamToken {
+	out := make(chan ports.StreamToken, 1)
+	out <- ports.StreamToken{Content: answer, Done: true}
+	close(out)
+	return out
+}
+
+// cacheStream relays tokens and keeps the answer they spell under key
+// once the stream completes without an error.
+func (c *responseCache) cacheStream(ctx context.Context, key [sha256.Size]byte, tokens <-chan ports.StreamToken) <-chan ports.StreamToken {
+	out := make(chan ports.StreamToken, cap(tokens))
+	go func() {
+		defer close(out)
+		var sb strings.Builder
+		failed := false
+		for token := range tokens {
+			sb.WriteString(token.Content)
+			failed = failed || token.Error != nil
+			if token.Done && !failed {
+				c.put(key, sb.String())
+			}
+			select {
+			case out <- token:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,111 @@
+// Package usecases - retention.go evicts the oldest documents of rolling corpora.
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// RetentionPolicy bounds how long and how many documents a collection
+// keeps, for rolling data such as news, mail, meeting notes or logs. Age
+// is measured from the document's last ingest, and count limits evict
+// the least recently ingested documents first. The zero value keeps
+// everything.
+type RetentionPolicy struct {
+	// MaxAge evicts documents ingested longer ago than this. Documents
+	// without an ingest time are never evicted by age.
+	MaxAge time.Duration
+	// MaxDocuments evicts the oldest documents beyond this many.
+	MaxDocuments int
+	// MaxChunks evicts the oldest documents until the rest hold at most
+	// this many chunks in total.
+	MaxChunks int
+}
+
+func (p RetentionPolicy) enabled() bool {
+	return p.MaxAge > 0 || p.MaxDocuments > 0 || p.MaxChunks > 0
+}
+
+// SetRetention sets the policy applied to a collection after each ingest
+// into it and to every collection by Maintain. Session collections are
+// bounded by the session TTL instead. It needs a store that
+// implements ports.DocumentRegistry; with others it does nothing.
+func (uc *IngestUseCase) SetRetention(policy RetentionPolicy) {
+	uc.retention = policy
+}
+
+// SetCollectionRetention sets the policy for one collection, replacing
+// the one given to SetRetention there. A zero policy exempts the
+// collection. It suits feed-style collections kept alongside a corpus
+// that should keep everything.
+func (uc *IngestUseCase) SetCollectionRetention(collection string, policy RetentionPolicy) {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	if uc.collectionRetention == nil {
+		uc.collectionRetention = make(map[string]RetentionPolicy)
+	}
+	uc.collectionRetention[collection] = policy
+}
+
+// retentionFor returns the policy applied to a collection.
+func (uc *IngestUseCase) retentionFor(collection string) RetentionPolicy {
+	if collection == "" {
+		collection = entities.DefaultCollection
+	}
+	if policy, ok := uc.collectionRetention[collection]; ok {
+		return policy
+	}
+	return uc.retention
+}
+
+// EnforceRetention evicts the documents of a collection that the
+// retention policy no longer allows, oldest first, and returns how many
+// were removed.
+func (uc *IngestUseCase) EnforceRetention(ctx context.Context, collection string) (int, error) {
+	return uc.enforceRetention(ctx, collection, "")
+}
+
+// enforceRetention is EnforceRetention that never evicts the document
+// keep, so an ingest cannot remove what it just stored.
+func (uc *IngestUseCase) enforceRetention(ctx context.Context, collection, keep string) (int, error) {
+	policy := uc.retentionFor(collection)
+	if !policy.enabled() || entities.IsSessionCollection(collection) {
+		return 0, nil
+	}
+	docs, err := uc.ListDocuments(ctx, collection)
+	if err == ErrDocumentsUnsupported {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Newest first, so everything past MaxDocuments is the oldest
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].IngestedAt.After(docs[j].IngestedAt) })
+
+	cutoff := time.Now().Add(-policy.MaxAge)
+	var evict []string
+	chunks := 0
+	for i, doc := range docs {
+		chunks += doc.ChunkCount
+		if doc.ID == keep {
+			continue
+		}
+		expired := policy.MaxAge > 0 && !doc.IngestedAt.IsZero() && doc.IngestedAt.Before(cutoff)
+		overflow := policy.MaxDocuments > 0 && i >= policy.MaxDocuments
+		oversize := policy.MaxChunks > 0 && chunks > policy.MaxChunks
+		if expired || overflow || oversize {
+			evict = append(evict, doc.ID)
+			chunks -= doc.ChunkCount
+		}
+	}
+	if err := uc.DeleteMany(ctx, collection, evict); err != nil {
+		return 0, fmt.Errorf("evicting documents: %w", err)
+	}
+	return len(evict), nil
+}
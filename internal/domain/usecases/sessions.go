@@ -0,0 +1,170 @@
+// Package usecases - sessions.go keeps documents attached to a single chat session.
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+var (
+	// ErrInvalidSession is returned for malformed session IDs.
+	ErrInvalidSession = errors.New("session IDs are 1-64 lowercase letters, digits or dashes")
+
+	// ErrEmptyDocument is returned when an attached document has no text.
+	ErrEmptyDocument = errors.New("document has no text content")
+)
+
+// defaultSessionTTL is how long a session may sit idle before
+// ExpireSessions ends it.
+const defaultSessionTTL = time.Hour
+
+// sessionRegistry tracks when each live session was last used.
+type sessionRegistry struct {
+	mu       sync.Mutex
+	lastUsed map[string]time.Time
+	ttl      time.Duration
+}
+
+// validSessionID reports whether id is safe to embed in a collection
+// name on every store.
+func validSessionID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSessionTTL sets how long a session may sit idle before
+// ExpireSessions ends it; 0 means one hour.
+func (uc *IngestUseCase) SetSessionTTL(ttl time.Duration) {
+	uc.sessions.mu.Lock()
+	defer uc.sessions.mu.Unlock()
+	uc.sessions.ttl = ttl
+}
+
+// AttachToSession ingests doc into the ephemeral collection of a chat
+// session instead of the corpus, so only that session's queries see it.
+// An empty sessionID starts a new session. The session ID is returned.
+// Attached documents have no source path, so Maintain never prunes them;
+// they are removed when the session ends.
+func (uc *IngestUseCase) AttachToSession(ctx context.Context, sessionID string, doc *entities.Document) (string, error) {
+	if sessionID == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("generating session ID: %w", err)
+		}
+		sessionID = hex.EncodeToString(b)
+	}
+	if err := uc.TouchSession(sessionID); err != nil {
+		return "", err
+	}
+
+	doc.Collection = entities.SessionCollection(sessionID)
+	doc.Path = ""
+	res, err := uc.ingest(ctx, doc)
+	if err != nil {
+		return "", err
+	}
+	if res.chunks == 0 {
+		return "", ErrEmptyDocument
+	}
+	return sessionID, nil
+}
+
+// TouchSession marks a session as in use, keeping ExpireSessions from
+// ending it for another TTL.
+func (uc *IngestUseCase) TouchSession(sessionID string) error {
+	if !validSessionID(sessionID) {
+		return ErrInvalidSession
+	}
+	uc.sessions.mu.Lock()
+	defer uc.sessions.mu.Unlock()
+	if uc.sessions.lastUsed == nil {
+		uc.sessions.lastUsed = make(map[string]time.Time)
+	}
+	uc.sessions.lastUsed[sessionID] = time.Now()
+	return nil
+}
+
+// EndSession removes every document attached to a session.
+func (uc *IngestUseCase) EndSession(ctx context.Context, sessionID string) error {
+	if !validSessionID(sessionID) {
+		return ErrInvalidSession
+	}
+	uc.sessions.mu.Lock()
+	delete(uc.sessions.lastUsed, sessionID)
+	uc.sessions.mu.Unlock()
+
+	store, err := storeFor(uc.vectorStore, entities.SessionCollection(sessionID))
+	if errors.Is(err, ErrCollectionsUnsupported) {
+		return nil // Nothing can have been attached
+	}
+	if err != nil {
+		return err
+	}
+	if err := store.Clear(ctx); err != nil {
+		return fmt.Errorf("clearing session %s: %w", sessionID, err)
+	}
+	uc.version.Add(1)
+	return nil
+}
+
+// ExpireSessions ends sessions idle for longer than the session TTL, and
+// session collections no live session owns, such as those left behind
+// by a restart. It returns how many sessions were ended.
+func (uc *IngestUseCase) ExpireSessions(ctx context.Context) (int, error) {
+	uc.sessions.mu.Lock()
+	ttl := uc.sessions.ttl
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	cutoff := time.Now().Add(-ttl)
+	var expired []string
+	live := make(map[string]bool, len(uc.sessions.lastUsed))
+	for id, used := range uc.sessions.lastUsed {
+		if used.Before(cutoff) {
+			expired = append(expired, id)
+		} else {
+			live[entities.SessionCollection(id)] = true
+		}
+	}
+	uc.sessions.mu.Unlock()
+
+	if cs, ok := uc.vectorStore.(ports.CollectionStore); ok {
+		names, err := cs.Collections(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("listing collections: %w", err)
+		}
+		for _, name := range names {
+			if entities.IsSessionCollection(name) && !live[name] {
+				expired = append(expired, strings.TrimPrefix(name, entities.SessionCollection("")))
+			}
+		}
+	}
+
+	ended := make(map[string]bool, len(expired))
+	for _, id := range expired {
+		if ended[id] || !validSessionID(id) {
+			continue
+		}
+		if err := uc.EndSession(ctx, id); err != nil {
+			return len(ended), err
+		}
+		ended[id] = true
+	}
+	return len(ended), nil
+}
@@ -0,0 +1,211 @@
+// Package usecases - snippet.go builds highlighted previews of retrieved chunks.
+package usecases
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// snippetWidth is the approximate length of a snippet, in characters.
+const snippetWidth = 200
+
+// snippetStopWords are query words too common to be worth highlighting.
+var snippetStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "the": true, "to": true, "was": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true,
+	"why": true, "with": true,
+}
+
+// termMatch is one occurrence of a query term in the text.
+type termMatch struct {
+	start, end int
+	term       string
+}
+
+// BuildSnippet returns about 200 characters of content around the
+// sentence that matches the most query terms, with every term occurrence
+// highlighted. Whitespace is collapsed, and "…" marks text cut off at
+// either end. Content without a match yields its opening.
+func BuildSnippet(content, query string) entities.Snippet {
+	text := strings.Join(strings.Fields(content), " ")
+	matches := findTerms(text, snippetTerms(query))
+
+	start := 0
+	if len(matches) > 0 {
+		start = bestSentence(text, matches)
+	}
+	end := forwardRunes(text, start, snippetWidth)
+	if end == len(text) {
+		start = backRunes(text, end, snippetWidth) // Fill the window from before
+	} else if first := firstMatchFrom(matches, start); first != nil && first.end > end {
+		// A long sentence: center on its first match instead
+		start = backRunes(text, first.start, snippetWidth/3)
+		end = forwardRunes(text, start, snippetWidth)
+	}
+	start, end = wordBounds(text, start, end)
+
+	var snippet entities.Snippet
+	prefix := ""
+	if start > 0 {
+		prefix = "…"
+	}
+	snippet.Text = prefix + text[start:end]
+	if end < len(text) {
+		snippet.Text += "…"
+	}
+	for _, m := range matches {
+		if m.start >= start && m.end <= end {
+			shift := len(prefix) - start
+			snippet.Highlights = append(snippet.Highlights, entities.Highlight{Start: m.start + shift, End: m.end + shift})
+		}
+	}
+	return snippet
+}
+
+// snippetTerms returns the distinct lowercase words of query worth
+// highlighting. A plural "s" is dropped from longer words so that
+// singular forms match too.
+func snippetTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), notWordRune) {
+		if snippetStopWords[word] {
+			continue
+		}
+		if utf8.RuneCountInString(word) > 4 {
+			word = strings.TrimSuffix(word, "s")
+		}
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+// findTerms returns the words of text that match a term, in order. A
+// word matches a term equal to it or, for terms of four or more letters,
+// one it starts with, so "index" also finds "indexes".
+func findTerms(text string, terms []string) []termMatch {
+	if len(terms) == 0 {
+		return nil
+	}
+	var matches []termMatch
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if notWordRune(r) {
+			i += size
+			continue
+		}
+		j := i + strings.IndexFunc(text[i:], notWordRune)
+		if j < i {
+			j = len(text)
+		}
+		word := strings.ToLower(text[i:j])
+		for _, term := range terms {
+			if word == term || (utf8.RuneCountInString(term) >= 4 && strings.HasPrefix(word, term)) {
+				matches = append(matches, termMatch{start: i, end: j, term: term})
+				break
+			}
+		}
+		i = j
+	}
+	return matches
+}
+
+// bestSentence returns the start of the sentence with the most distinct
+// matched terms, preferring more matches and then earlier sentences.
+func bestSentence(text string, matches []termMatch) int {
+	starts := sentenceStarts(text)
+	best, bestTerms, bestHits := 0, 0, 0
+	for i, start := range starts {
+		end := len(text)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		terms := make(map[string]bool)
+		hits := 0
+		for _, m := range matches {
+			if m.start >= start && m.start < end {
+				terms[m.term] = true
+				hits++
+			}
+		}
+		if len(terms) > bestTerms || (len(terms) == bestTerms && hits > bestHits) {
+			best, bestTerms, bestHits = start, len(terms), hits
+		}
+	}
+	return best
+}
+
+// sentenceStarts returns the offset of each sentence, splitting after
+// '.', '!' or '?' followed by a space.
+func sentenceStarts(text string) []int {
+	starts := []int{0}
+	for i := 1; i+1 < len(text); i++ {
+		if text[i] == ' ' && strings.ContainsRune(".!?", rune(text[i-1])) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// firstMatchFrom returns the first match at or after offset.
+func firstMatchFrom(matches []termMatch, offset int) *termMatch {
+	i := sort.Search(len(matches), func(i int) bool { return matches[i].start >= offset })
+	if i == len(matches) {
+		return nil
+	}
+	return &matches[i]
+}
+
+// forwardRunes returns the offset n runes after from, or the end of text.
+func forwardRunes(text string, from, n int) int {
+	for i := from; i < len(text); n-- {
+		if n == 0 {
+			return i
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	return len(text)
+}
+
+// backRunes returns the offset n runes before from, or 0.
+func backRunes(text string, from, n int) int {
+	for i := from; i > 0; n-- {
+		if n == 0 {
+			return i
+		}
+		_, size := utf8.DecodeLastRuneInString(text[:i])
+		i -= size
+	}
+	return 0
+}
+
+// wordBounds moves a window's edges onto spaces so it doesn't cut words
+// in half, dropping a partial word at either end.
+func wordBounds(text string, start, end int) (int, int) {
+	if start > 0 && text[start-1] != ' ' {
+		if i := strings.IndexByte(text[start:end], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+	if end < len(text) && text[end] != ' ' {
+		if i := strings.LastIndexByte(text[start:end], ' '); i >= 0 {
+			end = start + i
+		}
+	}
+	return start, end
+}
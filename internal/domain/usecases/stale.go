@@ -0,0 +1,113 @@
+// Package usecases - stale.go tells whether the sources of an earlier answer have changed.
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// AnswerSource is a document an answer drew on, as it was when the
+// answer was given.
+type AnswerSource struct {
+	Collection string
+	DocumentID string
+	Name       string
+	IngestedAt time.Time // Zero when the store records no ingest times
+	SessionID  string    // Chat session whose attached documents were searched too
+}
+
+// SourceChange is an answer's source that has changed since the answer.
+type SourceChange struct {
+	AnswerSource
+	Deleted    bool
+	Reingested time.Time // When the document was ingested again; zero when deleted
+}
+
+// AnswerSources lists the documents behind an answer to req, once each:
+// the cited results when citations names any, otherwise all of them.
+func AnswerSources(req *entities.ChatRequest, results []entities.QueryResult, citations []int) []AnswerSource {
+	used := results
+	if len(citations) > 0 {
+		used = nil
+		for _, n := range citations {
+			if n >= 1 && n <= len(results) {
+				used = append(used, results[n-1])
+			}
+		}
+	}
+
+	var sources []AnswerSource
+	seen := make(map[AnswerSource]bool)
+	for _, r := range used {
+		collection := r.Collection
+		if collection == "" {
+			collection = req.Collection
+		}
+		if collection == "" {
+			collection = entities.DefaultCollection
+		}
+		src := AnswerSource{Collection: collection, DocumentID: r.Chunk.DocumentID, Name: r.SourceDoc, IngestedAt: r.IngestedAt, SessionID: req.SessionID}
+		if !seen[src] {
+			seen[src] = true
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// ChangedSources reports which of sources have since been deleted or
+// ingested again, such as after their file was edited, so the answer
+// they back may be stale. Documents missing from their collection are
+// looked up among their session's attached documents, which are deleted
+// once the session ends. Needs a store implementing
+// ports.DocumentRegistry; without recorded ingest times only deletions
+// are found.
+func (uc *IngestUseCase) ChangedSources(ctx context.Context, sources []AnswerSource) ([]SourceChange, error) {
+	byCollection := make(map[string][]string)
+	for _, src := range sources {
+		byCollection[src.Collection] = append(byCollection[src.Collection], src.DocumentID)
+		if src.SessionID != "" && validSessionID(src.SessionID) {
+			session := entities.SessionCollection(src.SessionID)
+			byCollection[session] = append(byCollection[session], src.DocumentID)
+		}
+	}
+
+	found := make(map[string]map[string]entities.DocumentInfo, len(byCollection))
+	for collection, ids := range byCollection {
+		store, err := storeFor(uc.vectorStore, collection)
+		if err != nil {
+			return nil, err
+		}
+		reg, ok := store.(ports.DocumentRegistry)
+		if !ok {
+			return nil, ErrDocumentsUnsupported
+		}
+		page, err := reg.ListDocuments(ctx, entities.DocumentFilter{IDs: ids}, entities.Page{})
+		if err != nil {
+			return nil, err
+		}
+		docs := make(map[string]entities.DocumentInfo, len(page.Documents))
+		for _, doc := range page.Documents {
+			docs[doc.ID] = doc
+		}
+		found[collection] = docs
+	}
+
+	var changes []SourceChange
+	for _, src := range sources {
+		doc, ok := found[src.Collection][src.DocumentID]
+		if !ok && src.SessionID != "" {
+			doc, ok = found[entities.SessionCollection(src.SessionID)][src.DocumentID]
+		}
+		switch {
+		case !ok:
+			changes = append(changes, SourceChange{AnswerSource: src, Deleted: true})
+		case !src.IngestedAt.IsZero() && !doc.IngestedAt.Equal(src.IngestedAt):
+			changes = append(changes, SourceChange{AnswerSource: src, Reingested: doc.IngestedAt})
+		}
+	}
+	return changes, nil
+}
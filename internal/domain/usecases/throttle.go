@@ -0,0 +1,211 @@
+// Package usecases - throttle.go yields background embedding to interactive queries.
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// ThrottlePolicy says how background embedding yields to queries. The
+// embedder is shared, so a large ingest would otherwise queue every
+// query embedding, and on CPU-only machines every answer, behind it.
+type ThrottlePolicy struct {
+	// Cooldown keeps background work throttled this long after the last
+	// query, so a conversation's next question finds the embedder free.
+	Cooldown time.Duration
+	// BatchSize caps the texts of each background embedding request, so
+	// a query arriving mid-ingest waits for at most one small batch; 0
+	// leaves batches whole.
+	BatchSize int
+	// MaxPause bounds how long background embedding waits for the
+	// queries to stop before embedding its next batch anyway, so a busy
+	// chat cannot stall ingestion for good; 0 waits until idle.
+	MaxPause time.Duration
+}
+
+// Throttle tracks interactive queries so background embedding, such as
+// ingesting a folder or re-embedding a collection, can pause while they
+// run. One Throttle is shared by a QueryUseCase and an IngestUseCase via
+// their SetThrottle methods.
+type Throttle struct {
+	policy ThrottlePolicy
+
+	mu         sync.Mutex
+	active     int           // Queries in progress
+	lastActive time.Time     // When the last query finished
+	idle       chan struct{} // Closed when active drops to 0
+}
+
+// NewThrottle creates a Throttle with the given policy.
+func NewThrottle(policy ThrottlePolicy) *Throttle {
+	idle := make(chan struct{})
+	close(idle)
+	return &Throttle{policy: policy, idle: idle}
+}
+
+// Interactive marks a query in progress until the returned function is
+// called. It is safe to call the function more than once.
+func (t *Throttle) Interactive() func() {
+	t.mu.Lock()
+	t.active++
+	if t.active == 1 {
+		t.idle = make(chan struct{})
+	}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.active--
+			t.lastActive = time.Now()
+			if t.active == 0 {
+				close(t.idle)
+			}
+		})
+	}
+}
+
+// Wait blocks while the throttle is busy, up to the policy's MaxPause.
+// It only fails when ctx ends.
+func (t *Throttle) Wait(ctx context.Context) error {
+	if t.policy.MaxPause > 0 {
+		waitCtx, cancel := context.WithTimeout(ctx, t.policy.MaxPause)
+		defer cancel()
+		if err := t.waitIdle(waitCtx); err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return nil
+	}
+	return t.waitIdle(ctx)
+}
+
+// waitIdle blocks until no query has run for the cooldown.
+func (t *Throttle) waitIdle(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		idle := t.idle
+		busy := t.active > 0
+		remaining := t.policy.Cooldown - time.Since(t.lastActive)
+		t.mu.Unlock()
+
+		if busy {
+			select {
+			case <-idle:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if remaining <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// SetThrottle makes queries mark themselves interactive on t, from
+// retrieval until the answer is complete.
+func (uc *QueryUseCase) SetThrottle(t *Throttle) {
+	uc.throttle = t
+}
+
+// interactive marks a query in progress on the throttle, if any.
+func (uc *QueryUseCase) interactive() func() {
+	if uc.throttle == nil {
+		return func() {}
+	}
+	return uc.throttle.Interactive()
+}
+
+// untilClosed relays tokens and calls done once the stream ends or its
+// consumer gives up.
+func untilClosed(ctx context.Context, tokens <-chan ports.StreamToken, done func()) <-chan ports.StreamToken {
+	out := make(chan ports.StreamToken, cap(tokens))
+	go func() {
+		defer close(out)
+		defer done()
+		for token := range tokens {
+			select {
+			case out <- token:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// SetThrottle makes background embedding yield to the queries marked on
+// t: while they run, and for the policy's cooldown after, ingestion and
+// re-embedding pause before each batch of at most BatchSize texts. Documents attached to chat sessions are embedded
+// unthrottled, as a user is waiting for them.
+func (uc *IngestUseCase) SetThrottle(t *Throttle) {
+	uc.throttle = t
+}
+
+// embedThrottled is embedPassages in batches that wait for the throttle
+// and the guardrails, whichever are set. Per-text
+// failures are reported like EmbedBatch does, indexed into texts.
+func (uc *IngestUseCase) embedThrottled(ctx context.Context, embedder ports.EmbeddingService, texts []string) ([][]float32, error) {
+	if uc.throttle == nil && uc.guard == nil {
+		return embedPassages(ctx, embedder, texts)
+	}
+
+	size := 0
+	if uc.throttle != nil {
+		size = uc.throttle.policy.BatchSize
+	}
+	if uc.guard != nil && (size == 0 || size > uc.guard.limits.BatchSize) {
+		size = uc.guard.limits.BatchSize
+	}
+	embeddings := make([][]float32, 0, len(texts))
+	failed := make(map[int]error)
+	for start := 0; start < len(texts); {
+		if uc.throttle != nil {
+			if err := uc.throttle.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if uc.guard != nil {
+			if err := uc.guard.waitCalm(ctx); err != nil {
+				return nil, err
+			}
+		}
+		end := len(texts)
+		if size > 0 && end-start > size {
+			end = start + size
+		}
+
+		batch, err := embedPassages(ctx, embedder, texts[start:end])
+		var batchErr *ports.BatchEmbedError
+		if errors.As(err, &batchErr) {
+			for i, cause := range batchErr.Failed {
+				failed[start+i] = cause
+			}
+		} else if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("embedder returned %d embeddings for %d texts", len(batch), end-start)
+		}
+		embeddings = append(embeddings, batch...)
+		start = end
+	}
+	if len(failed) > 0 {
+		return embeddings, &ports.BatchEmbedError{Failed: failed}
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,164 @@
+// Package usecases - trace.go records every step of a query for debugging and tuning.
+package usecases
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// QueryTrace is everything a traced query did, in order: the texts it
+// embedded, each search and its results, the candidates left after each
+// step, the passages and prompts sent to the LLM and what it replied.
+type QueryTrace struct {
+	Request    *entities.ChatRequest
+	Params     entities.AnswerParams
+	Embeddings []TraceEmbedding
+	Searches   []TraceSearch
+	Stages     []TraceStage
+	Context    []string    // Passages as they went into the prompt
+	Calls      []TraceCall // LLM calls, for the answer and for translation and follow-ups
+	Response   *entities.ChatResponse
+	Error      string // Why the query failed; empty when it answered
+	Duration   time.Duration
+}
+
+// TraceEmbedding is a text embedded as a query.
+type TraceEmbedding struct {
+	Collection string
+	Model      string // Named embedding model; empty for the default embedder
+	Text       string
+	Dimension  int
+	Cached     bool // Taken from the pre-embedded questions
+	Duration   time.Duration
+}
+
+// TraceSearch is one retrieval from a store.
+type TraceSearch struct {
+	Collection  string
+	Mode        string // "vector", "hybrid" or "documents", when scoped to DocumentIDs
+	Query       string
+	TopK        int
+	DocumentIDs []string // Documents the search was scoped to, by request or filter
+	Results     []entities.QueryResult
+	Error       string
+	Duration    time.Duration
+}
+
+// TraceStage is the candidate set after a step of the pipeline:
+// "retrieved", "min_score", "translated" or "context_budget".
+type TraceStage struct {
+	Name    string
+	Results []entities.QueryResult
+}
+
+// TraceCall is one LLM call.
+type TraceCall struct {
+	Purpose  string // "answer", "translation" or "follow_ups"
+	Backend  string // Fallback that was called; empty for the configured LLM
+	Options  ports.GenerateOptions
+	Prompt   string
+	Messages []entities.ChatMessage // The conversation sent to chat LLMs instead of Prompt
+	Output   string                 // The raw reply, before citation markers are checked
+	Cached   bool                   // Answered from the response cache without calling the LLM
+	Error    string
+	TimedOut bool
+	Duration time.Duration
+}
+
+// DebugQuery answers req like Query while tracing every step, for bug
+// reports and tuning. The trace is returned even when the query fails,
+// with the error recorded in it. Nothing is traced for other queries.
+func (uc *QueryUseCase) DebugQuery(ctx context.Context, req *entities.ChatRequest) (*QueryTrace, error) {
+	t := &tracer{trace: QueryTrace{Request: req, Params: uc.AnswerParams(req)}}
+	start := time.Now()
+	resp, err := uc.Query(context.WithValue(ctx, traceKey{}, t), req)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trace := t.trace
+	trace.Duration = time.Since(start)
+	trace.Response = resp
+	if resp != nil {
+		trace.Params = resp.Params
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	return &trace, err
+}
+
+// traceKey carries a query's tracer in its context.
+type traceKey struct{}
+
+// tracer collects a QueryTrace; federated searches record concurrently.
+// Its methods do nothing on a nil tracer, so untraced queries pay only
+// the context lookup.
+type tracer struct {
+	mu    sync.Mutex
+	trace QueryTrace
+}
+
+// traceFrom returns the tracer of ctx, nil when the query is not traced.
+func traceFrom(ctx context.Context) *tracer {
+	t, _ := ctx.Value(traceKey{}).(*tracer)
+	return t
+}
+
+func (t *tracer) embedding(e TraceEmbedding) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.trace.Embeddings = append(t.trace.Embeddings, e)
+	t.mu.Unlock()
+}
+
+func (t *tracer) search(s TraceSearch) {
+	if t == nil {
+		return
+	}
+	s.Results = append([]entities.QueryResult(nil), s.Results...)
+	t.mu.Lock()
+	t.trace.Searches = append(t.trace.Searches, s)
+	t.mu.Unlock()
+}
+
+// stage records a copy of results, as later steps filter in place.
+func (t *tracer) stage(name string, results []entities.QueryResult) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.trace.Stages = append(t.trace.Stages, TraceStage{Name: name, Results: append([]entities.QueryResult(nil), results...)})
+	t.mu.Unlock()
+}
+
+func (t *tracer) context(parts []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.trace.Context = append([]string(nil), parts...)
+	t.mu.Unlock()
+}
+
+func (t *tracer) call(c TraceCall) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.trace.Calls = append(t.trace.Calls, c)
+	t.mu.Unlock()
+}
+
+// errorText is err's message, empty for nil.
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,77 @@
+// Package usecases - translate.go translates retrieved context into the language of the question.
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// translationTool names the translator in provenance records.
+const translationTool = "local LLM"
+
+// SetTranslation enables translating retrieved passages into the
+// language of the question before prompting, so a corpus in one language
+// can answer questions asked in another. Each passage in a different
+// language costs one extra LLM call, and the translation is recorded in
+// its provenance, so the citation flags it. Passages or questions whose
+// language cannot be told are used as they are.
+func (uc *QueryUseCase) SetTranslation(enabled bool) {
+	uc.translate = enabled
+}
+
+// translateResults returns results with the passages that are not in the
+// query's language translated into it, when translation is enabled.
+// Translation is best effort: a passage that fails to translate is kept
+// as it is. results itself is not modified.
+func (uc *QueryUseCase) translateResults(ctx context.Context, query string, results []entities.QueryResult) []entities.QueryResult {
+	if !uc.translate || len(results) == 0 {
+		return results
+	}
+	target := detectLanguage(query)
+	if target == "" {
+		return results
+	}
+
+	translated := make([]entities.QueryResult, len(results))
+	copy(translated, results)
+	for i, r := range translated {
+		source := detectLanguage(r.Chunk.Content)
+		if source == "" || source == target {
+			continue
+		}
+		text, err := uc.translateText(ctx, r.Chunk.Content, source, target)
+		if err != nil {
+			log.Printf("[WARN] Translating chunk %s from %s to %s: %v", r.Chunk.ID, source, target, err)
+			continue
+		}
+		translated[i].Chunk.Content = text
+		translated[i].Provenance = append(append(entities.Provenance(nil), r.Provenance...), entities.Transformation{
+			Kind:   entities.TransformTranslation,
+			Tool:   translationTool,
+			Detail: source + " to " + target + " at query time",
+		})
+	}
+	return translated
+}
+
+// translateText asks the LLM to translate text between two languages
+// given as languageNames codes.
+func (uc *QueryUseCase) translateText(ctx context.Context, text, from, to string) (string, error) {
+	prompt := fmt.Sprintf("Translate the following text from %s to %s. Keep names, numbers and formatting, and reply with the translation only.\n\nText:\n%s\n\nTranslation:", languageNames[from], languageNames[to], text)
+	start := time.Now()
+	out, err := uc.llm.Generate(ctx, prompt, nil)
+	traceFrom(ctx).call(TraceCall{Purpose: "translation", Prompt: prompt, Output: out, Error: errorText(err), Duration: time.Since(start)})
+	if err != nil {
+		return "", err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return "", fmt.Errorf("empty translation")
+	}
+	return out, nil
+}
@@ -0,0 +1,224 @@
+// Package discovery probes well-known local ports for LLM and embedding
+// servers, so setup can suggest backends that are actually running
+// instead of relying on hand-typed URLs.
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind identifies a backend runtime.
+type Kind string
+
+const (
+	KindOllama   Kind = "ollama"
+	KindLMStudio Kind = "lmstudio"
+	KindLlamaCpp Kind = "llamacpp" // Also matches llamafile, which embeds llama.cpp's server
+	KindTEI      Kind = "tei"
+)
+
+// Candidate is an endpoint to probe.
+type Candidate struct {
+	Kind Kind
+	URL  string
+}
+
+// DefaultCandidates are the ports each runtime listens on out of the box.
+var DefaultCandidates = []Candidate{
+	{KindOllama, "http://localhost:11434"},
+	{KindLMStudio, "http://localhost:1234"},
+	{KindLlamaCpp, "http://localhost:8080"},
+	{KindTEI, "http://localhost:8081"},
+}
+
+// Backend is a responding endpoint.
+type Backend struct {
+	Kind   Kind     `json:"kind"`
+	URL    string   `json:"url"`
+	Roles  []string `json:"roles"`            // "llm" and/or "embedding"
+	Models []string `json:"models,omitempty"` // Models the server reports
+	// EmbeddingModels are the Models suited to embedding, for servers
+	// that tell them apart (LM Studio).
+	EmbeddingModels []string `json:"embedding_models,omitempty"`
+}
+
+// probeTimeout bounds each probe; local servers answer in milliseconds.
+const probeTimeout = 1500 * time.Millisecond
+
+// Discover probes candidates concurrently and returns those that answer
+// like the expected runtime, in candidate order. Candidates on the
+// excluded host:port (typically localrag's own listener) are skipped.
+func Discover(ctx context.Context, candidates []Candidate, exclude string) []Backend {
+	client := &http.Client{Timeout: probeTimeout}
+
+	found := make([]*Backend, len(candidates))
+	var wg sync.WaitGroup
+	for i, c := range candidates {
+		if u, err := url.Parse(c.URL); err == nil && exclude != "" && sameHostPort(u.Host, exclude) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, c Candidate) {
+			defer wg.Done()
+			if b, err := probe(ctx, client, c); err == nil {
+				found[i] = b
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	var backends []Backend
+	for _, b := range found {
+		if b != nil {
+			backends = append(backends, *b)
+		}
+	}
+	return backends
+}
+
+// Locate returns the first of candidates of the given kind that answers,
+// such as the LM Studio server among DefaultCandidates, so an adapter
+// can be pointed at it without a hand-typed URL.
+func Locate(ctx context.Context, candidates []Candidate, kind Kind) (*Backend, bool) {
+	var ofKind []Candidate
+	for _, c := range candidates {
+		if c.Kind == kind {
+			ofKind = append(ofKind, c)
+		}
+	}
+	backends := Discover(ctx, ofKind, "")
+	if len(backends) == 0 {
+		return nil, false
+	}
+	return &backends[0], true
+}
+
+// probe checks a runtime-specific endpoint so an unrelated service on
+// the same port is not mistaken for a backend.
+func probe(ctx context.Context, client *http.Client, c Candidate) (*Backend, error) {
+	b := &Backend{Kind: c.Kind, URL: c.URL}
+
+	switch c.Kind {
+	case KindOllama:
+		var tags struct {
+			Models []struct {
+				Name string `json:"name"`
+			} `json:"models"`
+		}
+		if err := getJSON(ctx, client, c.URL+"/api/tags", &tags); err != nil {
+			return nil, err
+		}
+		b.Roles = []string{"llm", "embedding"}
+		for _, m := range tags.Models {
+			b.Models = append(b.Models, m.Name)
+		}
+
+	case KindLMStudio, KindLlamaCpp:
+		if c.Kind == KindLlamaCpp {
+			var health struct {
+				Status string `json:"status"`
+			}
+			if err := getJSON(ctx, client, c.URL+"/health", &health); err != nil {
+				return nil, err
+			}
+		}
+		var models struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if err := getJSON(ctx, client, c.URL+"/v1/models", &models); err != nil {
+			return nil, err
+		}
+		b.Roles = []string{"llm", "embedding"}
+		for _, m := range models.Data {
+			b.Models = append(b.Models, m.ID)
+		}
+		if c.Kind == KindLMStudio {
+			b.EmbeddingModels = lmStudioEmbeddingModels(ctx, client, c.URL)
+		}
+
+	case KindTEI:
+		var info struct {
+			ModelID string `json:"model_id"`
+		}
+		if err := getJSON(ctx, client, c.URL+"/info", &info); err != nil {
+			return nil, err
+		}
+		if info.ModelID == "" {
+			return nil, fmt.Errorf("not a TEI server")
+		}
+		b.Roles = []string{"embedding"}
+		b.Models = []string{info.ModelID}
+
+	default:
+		return nil, fmt.Errorf("unknown backend kind %q", c.Kind)
+	}
+
+	sort.Strings(b.Models)
+	return b, nil
+}
+
+// lmStudioEmbeddingModels lists LM Studio's embedding models from its
+// typed model listing, nil when the version has none.
+func lmStudioEmbeddingModels(ctx context.Context, client *http.Client, baseURL string) []string {
+	var typed struct {
+		Data []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, client, baseURL+"/api/v0/models", &typed); err != nil {
+		return nil
+	}
+	var models []string
+	for _, m := range typed.Data {
+		if m.Type == "embeddings" {
+			models = append(models, m.ID)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// sameHostPort compares a candidate host with a listen address such as
+// ":8080", treating an empty or wildcard listen host as local.
+func sameHostPort(host, listen string) bool {
+	_, cport := splitHostPort(host)
+	lhost, lport := splitHostPort(listen)
+	if cport != lport {
+		return false
+	}
+	return lhost == "" || lhost == "0.0.0.0" || lhost == "::" || lhost == "localhost" || lhost == "127.0.0.1" || host == listen
+}
+
+func splitHostPort(hostport string) (host, port string) {
+	u, err := url.Parse("//" + hostport)
+	if err != nil {
+		return hostport, ""
+	}
+	return u.Hostname(), u.Port()
+}
@@ -0,0 +1,94 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			w.Write([]byte(`{"models":[{"name":"nomic-embed-text"},{"name":"llama3.2"}]}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ollama.Close()
+
+	tei := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.Write([]byte(`{"model_id":"BAAI/bge-small-en-v1.5"}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer tei.Close()
+
+	// An unrelated web server must not be reported as llama.cpp
+	other := httptest.NewServer(http.NotFoundHandler())
+	defer other.Close()
+
+	backends := Discover(context.Background(), []Candidate{
+		{KindOllama, ollama.URL},
+		{KindLlamaCpp, other.URL},
+		{KindTEI, tei.URL},
+		{KindLMStudio, "http://127.0.0.1:1"}, // Nothing listening
+	}, "")
+
+	if len(backends) != 2 {
+		t.Fatalf("expected 2 backends, got %+v", backends)
+	}
+	if backends[0].Kind != KindOllama || len(backends[0].Models) != 2 || backends[0].Models[0] != "llama3.2" {
+		t.Errorf("unexpected ollama backend: %+v", backends[0])
+	}
+	if backends[1].Kind != KindTEI || backends[1].Roles[0] != "embedding" {
+		t.Errorf("unexpected TEI backend: %+v", backends[1])
+	}
+}
+
+func TestDiscover_SkipsOwnListener(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	port := server.URL[strings.LastIndex(server.URL, ":"):]
+	Discover(context.Background(), []Candidate{{KindLlamaCpp, server.URL}}, port)
+	if calls != 0 {
+		t.Error("own listen address should not be probed")
+	}
+}
+
+func TestLocate_LMStudio(t *testing.T) {
+	lmstudio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"data":[{"id":"qwen2.5-7b-instruct"},{"id":"text-embedding-nomic-embed-text-v1.5"}]}`))
+		case "/api/v0/models":
+			w.Write([]byte(`{"data":[{"id":"qwen2.5-7b-instruct","type":"llm"},{"id":"text-embedding-nomic-embed-text-v1.5","type":"embeddings"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer lmstudio.Close()
+
+	b, ok := Locate(context.Background(), []Candidate{
+		{KindOllama, lmstudio.URL}, // Wrong kind, not probed as LM Studio
+		{KindLMStudio, "http://127.0.0.1:1"},
+		{KindLMStudio, lmstudio.URL},
+	}, KindLMStudio)
+	if !ok || b.URL != lmstudio.URL {
+		t.Fatalf("expected the LM Studio server found, got %+v", b)
+	}
+	if len(b.Models) != 2 || len(b.EmbeddingModels) != 1 || b.EmbeddingModels[0] != "text-embedding-nomic-embed-text-v1.5" {
+		t.Errorf("unexpected models: %+v", b)
+	}
+
+	if _, ok := Locate(context.Background(), []Candidate{{KindLMStudio, "http://127.0.0.1:1"}}, KindLMStudio); ok {
+		t.Error("expected nothing found")
+	}
+}
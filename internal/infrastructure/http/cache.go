@@ -0,0 +1,77 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// answerCacheSize bounds the number of cached /api/query responses.
+const answerCacheSize = 256
+
+// answerCache keeps recent /api/query responses by ETag, so repeating a
+// question against an unchanged corpus skips retrieval and generation.
+// Entries for older corpus versions are never hit again and age out.
+type answerCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+	order   []string // Insertion order, oldest first
+	limit   int
+}
+
+func newAnswerCache(limit int) *answerCache {
+	return &answerCache{entries: make(map[string][]byte), limit: limit}
+}
+
+func (c *answerCache) get(etag string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	body, ok := c.entries[etag]
+	return body, ok
+}
+
+func (c *answerCache) put(etag string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[etag]; ok {
+		return
+	}
+	if len(c.order) >= c.limit {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[etag] = body
+	c.order = append(c.order, etag)
+}
+
+// queryETag identifies the answer to req for the current corpus. epoch
+// distinguishes server processes, whose corpus versions restart at zero.
+func queryETag(epoch int64, corpusVersion uint64, req *entities.ChatRequest) string {
+	key, _ := json.Marshal(struct {
+		Epoch       int64
+		Version     uint64
+		Query       string
+		History     []entities.ChatMessage
+		Collection  string
+		Collections []entities.CollectionWeight
+		MinScore    float64
+		DocumentIDs []string
+		SessionID   string
+		Metadata    []entities.MetadataCondition
+		Filter      string
+		Overrides   entities.LLMOverrides
+	}{epoch, corpusVersion, req.Query, req.History, req.Collection, req.Collections, req.MinScore, req.DocumentIDs, req.SessionID, req.Metadata, filterString(req.Filter), req.Overrides})
+	sum := sha256.Sum256(key)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// filterString returns expr in filter syntax, empty when it is nil.
+func filterString(expr entities.FilterExpr) string {
+	if expr == nil {
+		return ""
+	}
+	return expr.String()
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"sync"
+)
+
+// coalescer runs one answer pipeline per distinct streamed query and
+// fans its events out to every client asking the same question at the
+// same time, e.g. a classroom hitting "ask" together.
+type coalescer struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{flights: make(map[string]*flight)}
+}
+
+// flight is one in-progress pipeline. Events are kept so that late
+// subscribers replay the stream from the start.
+type flight struct {
+	mu     sync.Mutex
+	events []map[string]interface{}
+	done   bool
+	wake   chan struct{} // Closed when events grow or the flight ends
+
+	subscribers int // Guarded by coalescer.mu
+	cancel      context.CancelFunc
+}
+
+// join subscribes to the flight for key, starting run when there is none.
+// run publishes events and must return once ctx is cancelled; it is not
+// tied to any single request, so the first client leaving doesn't cut
+// off the others. Callers must leave when done.
+func (c *coalescer) join(key string, run func(ctx context.Context, f *flight)) *flight {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, ok := c.flights[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &flight{wake: make(chan struct{}), cancel: cancel}
+		c.flights[key] = f
+		go func() {
+			defer cancel()
+			run(ctx, f)
+			f.finish()
+			c.forget(key, f)
+		}()
+	}
+	f.subscribers++
+	return f
+}
+
+// leave unsubscribes from f, cancelling its pipeline if nobody is left.
+func (c *coalescer) leave(key string, f *flight) {
+	c.mu.Lock()
+	f.subscribers--
+	last := f.subscribers == 0
+	c.mu.Unlock()
+
+	if last {
+		f.cancel()
+		c.forget(key, f)
+	}
+}
+
+// forget stops routing new subscribers for key to f.
+func (c *coalescer) forget(key string, f *flight) {
+	c.mu.Lock()
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+	c.mu.Unlock()
+}
+
+// publish appends an event and wakes subscribers.
+func (f *flight) publish(event map[string]interface{}) {
+	f.mu.Lock()
+	f.events = append(f.events, event)
+	close(f.wake)
+	f.wake = make(chan struct{})
+	f.mu.Unlock()
+}
+
+// finish marks the stream complete and wakes subscribers.
+func (f *flight) finish() {
+	f.mu.Lock()
+	f.done = true
+	close(f.wake)
+	f.wake = make(chan struct{})
+	f.mu.Unlock()
+}
+
+// next returns event i, waiting until it is published. ok is false once
+// the stream has ended or ctx is done.
+func (f *flight) next(ctx context.Context, i int) (event map[string]interface{}, ok bool) {
+	for {
+		f.mu.Lock()
+		if i < len(f.events) {
+			event = f.events[i]
+			f.mu.Unlock()
+			return event, true
+		}
+		if f.done {
+			f.mu.Unlock()
+			return nil, false
+		}
+		wake := f.wake
+		f.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// gatedRun publishes n events, each once gate lets it through, and
+// counts the runs started and cancelled.
+type gatedRun struct {
+	n         int
+	gate      chan struct{}
+	started   atomic.Int32
+	cancelled chan struct{}
+}
+
+func newGatedRun(n int) *gatedRun {
+	return &gatedRun{n: n, gate: make(chan struct{}), cancelled: make(chan struct{}, 1)}
+}
+
+func (g *gatedRun) run(ctx context.Context, f *flight) {
+	g.started.Add(1)
+	for i := 0; i < g.n; i++ {
+		select {
+		case <-g.gate:
+		case <-ctx.Done():
+			g.cancelled <- struct{}{}
+			return
+		}
+		f.publish(map[string]interface{}{"token": fmt.Sprint(i)})
+	}
+}
+
+// drain reads f's events until it ends.
+func drain(f *flight) []map[string]interface{} {
+	var events []map[string]interface{}
+	for i := 0; ; i++ {
+		event, ok := f.next(context.Background(), i)
+		if !ok {
+			return events
+		}
+		events = append(events, event)
+	}
+}
+
+func TestCoalescer_JoinersShareOneFlight(t *testing.T) {
+	c := newCoalescer()
+	g := newGatedRun(3)
+
+	first := c.join("q", g.run)
+	g.gate <- struct{}{} // The first event is out before the others join
+
+	var wg sync.WaitGroup
+	streams := make([][]map[string]interface{}, 4)
+	for i := range streams {
+		f := first
+		if i > 0 {
+			f = c.join("q", g.run)
+			if f != first {
+				t.Fatal("an identical request should join the running flight")
+			}
+		}
+		wg.Add(1)
+		go func(i int, f *flight) {
+			defer wg.Done()
+			defer c.leave("q", f)
+			streams[i] = drain(f)
+		}(i, f)
+	}
+	g.gate <- struct{}{}
+	g.gate <- struct{}{}
+	wg.Wait()
+
+	if n := g.started.Load(); n != 1 {
+		t.Errorf("expected one pipeline run, got %d", n)
+	}
+	want := []map[string]interface{}{{"token": "0"}, {"token": "1"}, {"token": "2"}}
+	for i, events := range streams {
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("joiner %d got %v, want the full sequence", i, events)
+		}
+	}
+}
+
+func TestCoalescer_CancelsWhenLastJoinerLeaves(t *testing.T) {
+	c := newCoalescer()
+	g := newGatedRun(2)
+
+	a := c.join("q", g.run)
+	b := c.join("q", g.run)
+
+	c.leave("q", a)
+	select {
+	case <-g.cancelled:
+		t.Fatal("the flight was cancelled while a joiner remained")
+	case <-time.After(50 * time.Millisecond):
+	}
+	g.gate <- struct{}{}
+	if event, ok := b.next(context.Background(), 0); !ok || event["token"] != "0" {
+		t.Fatalf("the remaining joiner should keep receiving events, got %v", event)
+	}
+
+	c.leave("q", b)
+	select {
+	case <-g.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the flight should be cancelled once the last joiner leaves")
+	}
+
+	// The next request starts afresh
+	if f := c.join("q", g.run); f == a {
+		t.Error("a cancelled flight should not be joined")
+	} else {
+		c.leave("q", f)
+	}
+}
+
+func TestCoalescer_CorpusVersionSeparatesFlights(t *testing.T) {
+	c := newCoalescer()
+	g := newGatedRun(1)
+	req := &entities.ChatRequest{Query: "What changed?"}
+
+	stale := c.join(queryETag(1, 7, req), g.run)
+	defer c.leave(queryETag(1, 7, req), stale)
+	fresh := c.join(queryETag(1, 8, req), g.run)
+	defer c.leave(queryETag(1, 8, req), fresh)
+
+	if fresh == stale {
+		t.Error("a request after an ingest should not join the flight answering from the old corpus")
+	}
+	if same := c.join(queryETag(1, 8, req), g.run); same != fresh {
+		t.Error("requests at the same corpus version should share a flight")
+	} else {
+		c.leave(queryETag(1, 8, req), same)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.flights) != 2 {
+		t.Errorf("expected a flight per corpus version, got %d", len(c.flights))
+	}
+}
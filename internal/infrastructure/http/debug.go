@@ -0,0 +1,167 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	"github.com/0xcro3dile/localrag-go/internal/domain/usecases"
+)
+
+// handleDebugQuery answers a question like /api/query, taking the same
+// JSON or form fields and headers, and returns a trace of every step as
+// one JSON document: the texts embedded, each search and its results,
+// the candidates left after each step, the passages, every prompt sent
+// to the LLM and its raw reply, and the answer. It never uses the answer
+// cache. A failed query still returns its trace, with the error and the
+// status /api/search would give it.
+func (s *Server) handleDebugQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	chatReq, ok := s.chatRequest(w, r)
+	if !ok {
+		return
+	}
+
+	trace, err := s.queryUseCase.DebugQuery(r.Context(), chatReq)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(filterStatus(err))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(traceJSON(trace))
+}
+
+// traceJSON renders a query trace.
+func traceJSON(t *usecases.QueryTrace) map[string]interface{} {
+	query := t.Request.Query
+
+	embeddings := make([]map[string]interface{}, len(t.Embeddings))
+	for i, e := range t.Embeddings {
+		embeddings[i] = map[string]interface{}{
+			"collection":  e.Collection,
+			"model":       e.Model,
+			"text":        e.Text,
+			"dimension":   e.Dimension,
+			"cached":      e.Cached,
+			"duration_ms": e.Duration.Milliseconds(),
+		}
+	}
+
+	searches := make([]map[string]interface{}, len(t.Searches))
+	for i, sr := range t.Searches {
+		search := map[string]interface{}{
+			"collection":  sr.Collection,
+			"mode":        sr.Mode,
+			"query":       sr.Query,
+			"top_k":       sr.TopK,
+			"results":     resultsJSON(query, sr.Results),
+			"duration_ms": sr.Duration.Milliseconds(),
+		}
+		if len(sr.DocumentIDs) > 0 {
+			search["document_ids"] = sr.DocumentIDs
+		}
+		if sr.Error != "" {
+			search["error"] = sr.Error
+		}
+		searches[i] = search
+	}
+
+	stages := make([]map[string]interface{}, len(t.Stages))
+	for i, st := range t.Stages {
+		stages[i] = map[string]interface{}{"name": st.Name, "results": resultsJSON(query, st.Results)}
+	}
+
+	calls := make([]map[string]interface{}, len(t.Calls))
+	for i, c := range t.Calls {
+		call := map[string]interface{}{
+			"purpose":     c.Purpose,
+			"options":     optionsJSON(c.Options),
+			"output":      c.Output,
+			"timed_out":   c.TimedOut,
+			"cached":      c.Cached,
+			"duration_ms": c.Duration.Milliseconds(),
+		}
+		if c.Backend != "" {
+			call["backend"] = c.Backend
+		}
+		if len(c.Messages) > 0 {
+			call["messages"] = messagesJSON(c.Messages)
+		} else {
+			call["prompt"] = c.Prompt
+		}
+		if c.Error != "" {
+			call["error"] = c.Error
+		}
+		calls[i] = call
+	}
+
+	out := map[string]interface{}{
+		"query":       query,
+		"params":      paramsJSON(t.Params),
+		"embeddings":  embeddings,
+		"searches":    searches,
+		"stages":      stages,
+		"context":     append([]string{}, t.Context...),
+		"calls":       calls,
+		"duration_ms": t.Duration.Milliseconds(),
+	}
+	if t.Error != "" {
+		out["error"] = t.Error
+	}
+	if resp := t.Response; resp != nil {
+		out["answer"] = resp.Answer
+		out["sources"] = resultsJSON(query, resp.Sources)
+		out["citations"] = append([]int{}, resp.Citations...)
+		out["follow_ups"] = append([]string{}, resp.FollowUps...)
+		out["extractive"] = resp.Extractive
+	}
+	return out
+}
+
+// optionsJSON renders the per-request settings of an LLM call, leaving
+// out those left to the LLM's configuration.
+func optionsJSON(o ports.GenerateOptions) map[string]interface{} {
+	out := map[string]interface{}{}
+	if o.Model != "" {
+		out["model"] = o.Model
+	}
+	if o.Temperature != nil {
+		out["temperature"] = *o.Temperature
+	}
+	if o.Seed != nil {
+		out["seed"] = *o.Seed
+	}
+	if o.TopP != nil {
+		out["top_p"] = *o.TopP
+	}
+	if o.TopK != nil {
+		out["top_k"] = *o.TopK
+	}
+	if o.NumCtx != nil {
+		out["num_ctx"] = *o.NumCtx
+	}
+	if o.RepeatPenalty != nil {
+		out["repeat_penalty"] = *o.RepeatPenalty
+	}
+	if len(o.Stop) > 0 {
+		out["stop"] = o.Stop
+	}
+	if o.NumPredict != nil {
+		out["num_predict"] = *o.NumPredict
+	}
+	return out
+}
+
+// messagesJSON renders a conversation sent to a chat LLM.
+func messagesJSON(messages []entities.ChatMessage) []map[string]string {
+	out := make([]map[string]string, len(messages))
+	for i, m := range messages {
+		out[i] = map[string]string{"role": m.Role, "content": m.Content}
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// defaultFeedInterval is how often subscribed feeds are polled unless
+// told.
+const defaultFeedInterval = time.Hour
+
+// Feed is a subscribed syndication feed and the collection its entries
+// are ingested into, the default one when empty.
+type Feed struct {
+	URL        string
+	Collection string
+}
+
+// SetFeeds subscribes to feeds, read with reader, such as a
+// loader.FeedLoader. While the server runs, they are polled at startup
+// and then every interval (an hour when 0), and their new entries are
+// ingested. Entries already in the collection are not read again.
+func (s *Server) SetFeeds(reader ports.FeedReader, feeds []Feed, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultFeedInterval
+	}
+	s.feedReader = reader
+	s.feeds = feeds
+	s.feedEvery = interval
+}
+
+// pollFeeds ingests the new entries of the subscribed feeds now and
+// every feedEvery until ctx ends. Entries are remembered once seen, so
+// those the retention policy evicts are not ingested again while they
+// stay in the feed.
+func (s *Server) pollFeeds(ctx context.Context) {
+	ticker := time.NewTicker(s.feedEvery)
+	defer ticker.Stop()
+	seen := make(map[string]bool) // Collection and path
+	for {
+		for _, feed := range s.feeds {
+			if err := s.pollFeed(ctx, feed, seen); err != nil && ctx.Err() == nil {
+				log.Printf("[WARN] Polling feed %s: %v", feed.URL, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollFeed ingests the entries of feed neither seen before nor in its
+// collection, and marks them seen unless they failed.
+func (s *Server) pollFeed(ctx context.Context, feed Feed, seen map[string]bool) error {
+	entries, all, err := s.feedReader.Fetch(ctx, feed.URL)
+	if err != nil {
+		return err
+	}
+	var fresh []string
+	for _, path := range all {
+		if !seen[feed.Collection+"\x00"+path] {
+			fresh = append(fresh, path)
+		}
+	}
+	paths, err := s.ingestUseCase.UnseenPaths(ctx, feed.Collection, fresh)
+	if err != nil {
+		return err
+	}
+	var report entities.IngestReport
+	if len(paths) > 0 {
+		if report, err = s.ingestUseCase.IngestFiles(ctx, entries, feed.Collection, paths); err != nil {
+			return err
+		}
+	}
+	for _, path := range fresh {
+		seen[feed.Collection+"\x00"+path] = true
+	}
+	for _, issue := range report.Errors {
+		delete(seen, feed.Collection+"\x00"+issue.Path) // Retried next time
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	log.Printf("[INFO] Ingested %d new entries of %s (%d failed)", report.FilesProcessed, feed.URL, len(report.Errors))
+	return nil
+}
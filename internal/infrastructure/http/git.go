@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// SetGitReader enables /api/ingest/git, which ingests Git repositories
+// with reader, such as a loader.GitLoader. The server reads any local
+// repository and clones any URL a client names, so enable it only for
+// trusted clients.
+func (s *Server) SetGitReader(reader ports.GitReader) {
+	s.git = reader
+}
+
+// handleIngestGit ingests the tracked text files of the repository at
+// repo, a directory on the server or a URL to clone, and reports as
+// /api/ingest does. Ingesting a repository again only reads the files
+// changed since the commit ingested last, and drops those deleted,
+// unless full is set.
+func (s *Server) handleIngestGit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.git == nil {
+		http.Error(w, "Git ingestion is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		Repo       string               `json:"repo"`
+		Full       bool                 `json:"full"`
+		Collection string               `json:"collection"`
+		Metadata   map[string]string    `json:"metadata"`
+		Provenance []transformationJSON `json:"provenance"`
+		Async      bool                 `json:"async"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Repo == "" {
+		http.Error(w, "repo required", http.StatusBadRequest)
+		return
+	}
+
+	s.ingestSources(w, r, "git", req.Collection, 0, req.Async, func(ctx context.Context) (ports.DocumentLoader, []string, error) {
+		co, err := s.git.Checkout(ctx, req.Repo)
+		if err != nil {
+			return nil, nil, err
+		}
+		sync, err := s.ingestUseCase.SyncGit(ctx, req.Collection, s.git, co, req.Full)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sync.Since != "" {
+			log.Printf("[INFO] Ingesting %s at %.7s: %d files changed since %.7s, %d removed", req.Repo, co.Head, len(sync.Paths), sync.Since, sync.Removed)
+		}
+		files := co.Loader
+		if len(req.Metadata) > 0 || len(req.Provenance) > 0 {
+			files = annotatingLoader{DocumentLoader: files, metadata: req.Metadata, provenance: provenanceFromJSON(req.Provenance)}
+		}
+		return files, sync.Paths, nil
+	})
+}
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// finishedJobsKept bounds the finished jobs kept for status queries;
+// running jobs are always kept.
+const finishedJobsKept = 32
+
+// jobRegistry tracks background jobs: ingestions started with
+// POST /api/ingest {"async": true} and re-embeds started with
+// POST /api/reembed.
+type jobRegistry struct {
+	mu    sync.Mutex
+	jobs  map[string]*ingestJob
+	order []string // Start order, oldest first
+}
+
+func newJobRegistry() *jobRegistry {
+	return &jobRegistry{jobs: make(map[string]*ingestJob)}
+}
+
+// ingestJob is one background run. Its latest progress replaces the
+// previous one, so subscribers always see the newest state.
+type ingestJob struct {
+	id         string
+	kind       string // "ingest" or "reembed"
+	collection string
+	startedAt  time.Time
+
+	mu       sync.Mutex
+	progress entities.IngestProgress
+	report   entities.IngestReport
+	err      error
+	done     bool
+	wake     chan struct{} // Closed when progress changes or the job ends
+}
+
+// start runs ingest in the background as a new job. ingest reports
+// progress through update; the job is not tied to the request that
+// started it, so it carries on after the client disconnects.
+func (r *jobRegistry) start(kind, collection string, filesTotal int, ingest func(ctx context.Context, update func(entities.IngestProgress)) (entities.IngestReport, error)) (*ingestJob, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generating job ID: %w", err)
+	}
+	j := &ingestJob{
+		id:         hex.EncodeToString(b),
+		kind:       kind,
+		collection: collection,
+		startedAt:  time.Now(),
+		progress:   entities.IngestProgress{FilesTotal: filesTotal},
+		wake:       make(chan struct{}),
+	}
+
+	r.mu.Lock()
+	r.jobs[j.id] = j
+	r.order = append(r.order, j.id)
+	r.evict()
+	r.mu.Unlock()
+
+	go func() {
+		report, err := ingest(context.Background(), j.update)
+		j.finish(report, err)
+	}()
+	return j, nil
+}
+
+// evict forgets the oldest finished jobs beyond finishedJobsKept.
+// Caller must hold r.mu.
+func (r *jobRegistry) evict() {
+	finished := 0
+	for _, id := range r.order {
+		if r.jobs[id].finished() {
+			finished++
+		}
+	}
+	kept := r.order[:0]
+	for _, id := range r.order {
+		if finished > finishedJobsKept && r.jobs[id].finished() {
+			delete(r.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	r.order = kept
+}
+
+func (r *jobRegistry) get(id string) (*ingestJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	return j, ok
+}
+
+// list returns the known jobs, newest first.
+func (r *jobRegistry) list() []*ingestJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	jobs := make([]*ingestJob, 0, len(r.order))
+	for i := len(r.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, r.jobs[r.order[i]])
+	}
+	return jobs
+}
+
+// update records progress and wakes subscribers.
+func (j *ingestJob) update(p entities.IngestProgress) {
+	j.mu.Lock()
+	j.progress = p
+	close(j.wake)
+	j.wake = make(chan struct{})
+	j.mu.Unlock()
+}
+
+// finish records the outcome and wakes subscribers.
+func (j *ingestJob) finish(report entities.IngestReport, err error) {
+	j.mu.Lock()
+	j.report, j.err, j.done = report, err, true
+	close(j.wake)
+	j.wake = make(chan struct{})
+	j.mu.Unlock()
+}
+
+func (j *ingestJob) finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.done
+}
+
+// status renders the job for the API. chunks_per_sec and eta_seconds
+// come from the throughput so far; eta_seconds is null until the first
+// file is done.
+func (j *ingestJob) status() (status map[string]interface{}, done bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	p := j.progress
+	var eta interface{}
+	if p.Remaining > 0 {
+		eta = math.Round(p.Remaining.Seconds())
+	}
+	status = map[string]interface{}{
+		"id":             j.id,
+		"kind":           j.kind,
+		"collection":     j.collection,
+		"state":          "running",
+		"started_at":     j.startedAt.UTC().Format(time.RFC3339),
+		"files_done":     p.FilesDone,
+		"files_total":    p.FilesTotal,
+		"chunks_done":    p.ChunksDone,
+		"chunks_per_sec": math.Round(p.ChunksPerSecond*10) / 10,
+		"elapsed_ms":     p.Elapsed.Milliseconds(),
+		"eta_seconds":    eta,
+		"current":        p.Current,
+	}
+	if j.done {
+		status["state"] = "done"
+		status["report"] = ingestReportJSON(j.report)
+		if j.err != nil {
+			status["state"] = "failed"
+			status["error"] = j.err.Error()
+		}
+	}
+	return status, j.done
+}
+
+// changed returns a channel closed at the job's next change.
+func (j *ingestJob) changed() <-chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.wake
+}
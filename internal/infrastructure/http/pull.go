@@ -0,0 +1,149 @@
+package http
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// SetAutoPull checks at startup that the LLM and embedding models are
+// installed, for backends implementing ports.ModelPuller, and pulls the
+// missing ones in the background, logging their progress, instead of
+// failing the first query with a 404. Warm-up, when set, follows the
+// pulls.
+func (s *Server) SetAutoPull(enabled bool) {
+	s.autoPull = enabled
+}
+
+// puller is a backend that can download its model, with the role it
+// plays.
+type puller struct {
+	role string // "embedding" or "llm"
+	ports.ModelPuller
+}
+
+// pullers lists the backends implementing ports.ModelPuller.
+func (s *Server) pullers() []puller {
+	var out []puller
+	for _, dep := range []struct {
+		role    string
+		service interface{}
+	}{{"embedding", s.embedder}, {"llm", s.llm}} {
+		if p, ok := dep.service.(ports.ModelPuller); ok {
+			out = append(out, puller{dep.role, p})
+		}
+	}
+	return out
+}
+
+// prepareModels pulls missing models when auto-pull is set, then warms
+// the models up when warm-up is set.
+func (s *Server) prepareModels(ctx context.Context) {
+	if s.autoPull {
+		last := ""
+		s.pullMissing(ctx, func(role string, p ports.PullProgress) {
+			if p.Status != last {
+				last = p.Status
+				log.Printf("[INFO] Pulling %s model %s: %s", role, p.Model, p.Status)
+			}
+		})
+	}
+	if s.warmUp {
+		s.warmUpModels(ctx)
+	}
+}
+
+// pullMissing pulls the models that are not installed, one after the
+// other, reporting their progress. It returns the first failure; the
+// other models are still pulled.
+func (s *Server) pullMissing(ctx context.Context, progress func(role string, p ports.PullProgress)) error {
+	var first error
+	for _, p := range s.pullers() {
+		installed, err := p.ModelInstalled(ctx)
+		if err != nil {
+			log.Printf("[WARN] Checking the %s model failed: %v", p.role, err)
+			first = firstError(first, err)
+			continue
+		}
+		if installed {
+			continue
+		}
+		start := time.Now()
+		err = p.PullModel(ctx, func(pp ports.PullProgress) {
+			progress(p.role, pp)
+		})
+		if err != nil {
+			log.Printf("[WARN] Pulling the %s model failed: %v", p.role, err)
+			first = firstError(first, err)
+			continue
+		}
+		log.Printf("[INFO] Pulled the %s model in %s", p.role, time.Since(start).Round(time.Second))
+	}
+	return first
+}
+
+func firstError(first, err error) error {
+	if first != nil {
+		return first
+	}
+	return err
+}
+
+// handlePullModels pulls the LLM and embedding models that are not
+// installed and streams the download as SSE events with the role, model,
+// status, and completed and total bytes of the current layer. The last
+// event has status "done", or "failed" with the error.
+func (s *Server) handlePullModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	// Downloads outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// Ollama reports every chunk it downloads; relay a change of status
+	// or of a whole percent
+	var last ports.PullProgress
+	err := s.pullMissing(r.Context(), func(role string, p ports.PullProgress) {
+		if p.Status == last.Status && percent(p) == percent(last) {
+			return
+		}
+		last = p
+		sendSSE(w, flusher, pullEvent(role, p))
+	})
+	if err != nil {
+		sendSSE(w, flusher, map[string]interface{}{"status": "failed", "error": err.Error()})
+		return
+	}
+	sendSSE(w, flusher, map[string]interface{}{"status": "done"})
+}
+
+// percent is how much of the current layer is downloaded, 0 to 100.
+func percent(p ports.PullProgress) int64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return p.Completed * 100 / p.Total
+}
+
+// pullEvent renders a step of a model download.
+func pullEvent(role string, p ports.PullProgress) map[string]interface{} {
+	event := map[string]interface{}{"role": role, "model": p.Model, "status": p.Status}
+	if p.Total > 0 {
+		event["completed"] = p.Completed
+		event["total"] = p.Total
+	}
+	return event
+}
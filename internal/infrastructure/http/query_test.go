@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/vectordb"
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+	"github.com/0xcro3dile/localrag-go/internal/domain/usecases"
+)
+
+// fixedEmbedder embeds every text as the same vector.
+type fixedEmbedder struct{}
+
+func (fixedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1, 0}, nil
+}
+
+func (fixedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i := range texts {
+		out[i] = []float32{1, 0}
+	}
+	return out, nil
+}
+
+// stallingLLM answers, or waits out the request while stalled is set.
+type stallingLLM struct {
+	stalled atomic.Bool
+	answer  string // Defaults to a fixed answer about refunds
+}
+
+func (l *stallingLLM) reply() string {
+	if l.answer != "" {
+		return l.answer
+	}
+	return "Refunds are accepted for thirty days."
+}
+
+func (l *stallingLLM) Generate(ctx context.Context, prompt string, context []string) (string, error) {
+	if l.stalled.Load() {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return l.reply(), nil
+}
+
+func (l *stallingLLM) GenerateStream(ctx context.Context, prompt string, context []string) (<-chan ports.StreamToken, error) {
+	ch := make(chan ports.StreamToken, 1)
+	ch <- ports.StreamToken{Content: l.reply(), Done: true}
+	close(ch)
+	return ch, nil
+}
+
+// postQuery asks /api/query a question as the UI's form does.
+func postQuery(s *Server, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(url.Values{"query": {query}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	s.handleQuery(w, r)
+	return w
+}
+
+// newQueryServer returns a server over one ingested document whose
+// query usecase stops waiting for the LLM after 20ms, with that usecase.
+func newQueryServer(t *testing.T, llm ports.LLMService) (*Server, *usecases.QueryUseCase) {
+	store := vectordb.NewInMemoryStore()
+	ingestUC := usecases.NewIngestUseCase(fixedEmbedder{}, store, 500, 0)
+	doc := &entities.Document{ID: "policy", Name: "policy.md", Content: "The refund window is thirty days from delivery."}
+	if err := ingestUC.Ingest(context.Background(), doc); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	queryUC := usecases.NewQueryUseCase(fixedEmbedder{}, store, llm, 3)
+	queryUC.SetGenerationTimeout(20 * time.Millisecond)
+	s, err := NewServer(queryUC, ingestUC, llm, fixedEmbedder{}, store, "")
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	return s, queryUC
+}
+
+func TestHandleQuery_ExtractiveAnswerNotReused(t *testing.T) {
+	llm := &stallingLLM{}
+	s, _ := newQueryServer(t, llm)
+
+	llm.stalled.Store(true)
+	first := postQuery(s, "What is the refund window?")
+	if !strings.Contains(first.Body.String(), "extractive") {
+		t.Fatalf("expected an extractive answer while the LLM stalls, got %s", first.Body)
+	}
+	if etag := first.Header().Get("ETag"); etag != "" {
+		t.Errorf("an extractive answer should not be sent with an ETag, got %q", etag)
+	}
+
+	llm.stalled.Store(false)
+	second := postQuery(s, "What is the refund window?")
+	body := second.Body.String()
+	if strings.Contains(body, "extractive") || !strings.Contains(body, "Refunds are accepted for thirty days.") {
+		t.Errorf("expected the LLM's answer once it is back, got %s", body)
+	}
+	if second.Header().Get("ETag") == "" {
+		t.Error("the LLM's answer should be sent with an ETag")
+	}
+}
+
+func TestHandleQuery_FallbackAnswerNotTagged(t *testing.T) {
+	llm := &stallingLLM{}
+	s, queryUC := newQueryServer(t, llm)
+	backup := &stallingLLM{answer: "The backup model says thirty days."}
+	if err := queryUC.SetFallbacks(usecases.LLMBackend{Name: "backup", LLM: backup}); err != nil {
+		t.Fatalf("set fallbacks: %v", err)
+	}
+
+	llm.stalled.Store(true)
+	first := postQuery(s, "What is the refund window?")
+	if !strings.Contains(first.Body.String(), "The backup model says thirty days.") {
+		t.Fatalf("expected the fallback's answer, got %s", first.Body)
+	}
+	if etag := first.Header().Get("ETag"); etag != "" {
+		t.Errorf("a fallback's answer should not be sent with an ETag, got %q", etag)
+	}
+
+	llm.stalled.Store(false)
+	if body := postQuery(s, "What is the refund window?").Body.String(); !strings.Contains(body, "Refunds are accepted for thirty days.") {
+		t.Errorf("expected the LLM's answer once it is back, got %s", body)
+	}
+}
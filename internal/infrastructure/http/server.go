@@ -4,19 +4,31 @@ package http
 
 import (
 	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/filter"
 	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
 	"github.com/0xcro3dile/localrag-go/internal/domain/usecases"
+	"github.com/0xcro3dile/localrag-go/internal/infrastructure/discovery"
 )
 
 //go:embed templates/*
@@ -25,6 +37,12 @@ var templatesFS embed.FS
 //go:embed static/*
 var staticFS embed.FS
 
+// sessionSweepInterval is how often idle chat sessions are looked for.
+const sessionSweepInterval = time.Minute
+
+// modelListTimeout bounds the backends' model listings in /api/models.
+const modelListTimeout = 5 * time.Second
+
 // Server is the HTTP server for the RAG API and UI.
 type Server struct {
 	queryUseCase  *usecases.QueryUseCase
@@ -32,8 +50,24 @@ type Server struct {
 	llm           ports.LLMService
 	embedder      ports.EmbeddingService
 	vectorStore   ports.VectorStore
+	loader        ports.DocumentLoader // Enables /api/ingest; nil when unset
+	urlLoader     ports.DocumentLoader // Enables /api/ingest/url; nil when unset
+	crawler       ports.SiteCrawler    // Enables /api/ingest/crawl; nil when unset
+	git           ports.GitReader      // Enables /api/ingest/git; nil when unset
+	feedReader    ports.FeedReader     // Reads the subscribed feeds; nil when none
+	feeds         []Feed               // Polled every feedEvery while the server runs
+	feedEvery     time.Duration
+	maintainEvery time.Duration // Scheduled maintenance interval; 0 when off
 	templates     *template.Template
 	addr          string
+	answers       *answerCache
+	flights       *coalescer
+	jobs          *jobRegistry
+	shares        *shareRegistry
+	guard         *usecases.Guardrails // Sampled while the server runs; nil when none
+	warmUp        bool                 // Load the models at startup, see SetWarmUp
+	autoPull      bool                 // Pull missing models at startup, see SetAutoPull
+	epoch         int64                // Start time; scopes ETags to this process
 }
 
 // NewServer creates a new HTTP server.
@@ -59,11 +93,57 @@ func NewServer(
 		vectorStore:   vectorStore,
 		templates:     tmpl,
 		addr:          addr,
+		answers:       newAnswerCache(answerCacheSize),
+		flights:       newCoalescer(),
+		jobs:          newJobRegistry(),
+		shares:        newShareRegistry(),
+		epoch:         time.Now().UnixNano(),
 	}, nil
 }
 
-// Start runs the HTTP server.
+// SetLoader enables /api/ingest, which loads files from the server's
+// filesystem with loader.
+func (s *Server) SetLoader(loader ports.DocumentLoader) {
+	s.loader = loader
+}
+
+// SetAnswerCacheSize sets how many /api/query answers are kept for
+// repeated questions, 256 by default. Sizes below 1 keep the default.
+func (s *Server) SetAnswerCacheSize(size int) {
+	if size > 0 {
+		s.answers = newAnswerCache(size)
+	}
+}
+
+// SetMaintenanceInterval runs maintenance, as POST /api/maintenance
+// does, every interval while the server runs, so retention policies and
+// pruning hold without an external cron job. 0 turns it off.
+func (s *Server) SetMaintenanceInterval(interval time.Duration) {
+	s.maintainEvery = interval
+}
+
+// SetGuardrails samples g while the server runs and reports its state
+// under "resources" in /api/health. Share g with the use cases via their
+// SetGuardrails methods so it sheds their load.
+func (s *Server) SetGuardrails(g *usecases.Guardrails) {
+	s.guard = g
+}
+
+// SetWarmUp loads the LLM and embedding models into memory as the
+// server starts, for backends implementing ports.ModelPreloader, so the
+// first query does not wait seconds for them to load. Pair it with the
+// adapters' SetKeepAlive to keep them loaded afterwards.
+func (s *Server) SetWarmUp(enabled bool) {
+	s.warmUp = enabled
+}
+
+// Start runs the HTTP server. It first checks the embedding models'
+// widths against the store and refuses to start on a mismatch.
 func (s *Server) Start(ctx context.Context) error {
+	if err := s.checkEmbeddings(ctx); err != nil {
+		return err
+	}
+
 	mux := http.NewServeMux()
 
 	// Static files
@@ -76,7 +156,34 @@ func (s *Server) Start(ctx context.Context) error {
 	// API
 	mux.HandleFunc("/api/query", s.handleQuery)
 	mux.HandleFunc("/api/query/stream", s.handleQueryStream) // SSE streaming
+	mux.HandleFunc("/api/debug/query", s.handleDebugQuery)   // Traced query for bug reports
+	mux.HandleFunc("/api/search", s.handleSearch)
 	mux.HandleFunc("/api/health", s.handleHealth)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/api/collections", s.handleCollections)
+	mux.HandleFunc("/api/models", s.handleModels)
+	mux.HandleFunc("/api/models/pull", s.handlePullModels)
+	mux.HandleFunc("/api/documents", s.handleDocuments)
+	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/api/backends", s.handleBackends)
+	mux.HandleFunc("/api/backup", s.handleBackup)
+	mux.HandleFunc("/api/restore", s.handleRestore)
+	mux.HandleFunc("/api/diff", s.handleDiff)
+	mux.HandleFunc("/api/maintenance", s.handleMaintenance)
+	mux.HandleFunc("/api/ingest", s.handleIngest)
+	mux.HandleFunc("/api/ingest/url", s.handleIngestURL)
+	mux.HandleFunc("/api/ingest/crawl", s.handleIngestCrawl)
+	mux.HandleFunc("/api/ingest/git", s.handleIngestGit)
+	mux.HandleFunc("/api/reembed", s.handleReembed)
+	mux.HandleFunc("/api/reembed/rollback", s.handleReembedRollback)
+	mux.HandleFunc("/api/jobs", s.handleJobs)
+	mux.HandleFunc("/api/jobs/events", s.handleJobEvents) // SSE progress
+	mux.HandleFunc("/api/sessions", s.handleSessions)
+	mux.HandleFunc("/api/share", s.handleShare)
+	mux.HandleFunc("/api/answers/stale", s.handleStaleAnswer)
+	mux.HandleFunc("/api/canaries", s.handleCanaries)
+	mux.HandleFunc("/api/canaries/run", s.handleCanaryRun)
+	mux.HandleFunc("/share", s.handleSharedAnswer) // Read-only shared answers
 
 	server := &http.Server{
 		Addr:         s.addr,
@@ -86,6 +193,21 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 
 	log.Printf("[INFO] LocalRAG server starting on %s", s.addr)
+	go s.logDiscoveredBackends(ctx)
+	go s.expireSessions(ctx)
+	go s.runCanaries(ctx)
+	if s.maintainEvery > 0 {
+		go s.maintainPeriodically(ctx)
+	}
+	if s.feedReader != nil && len(s.feeds) > 0 {
+		go s.pollFeeds(ctx)
+	}
+	if s.guard != nil {
+		go s.guard.Run(ctx)
+	}
+	if s.autoPull || s.warmUp {
+		go s.prepareModels(ctx)
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -128,6 +250,9 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
             
             <form id="query-form" onsubmit="sendQuery(event)">
                 <input type="text" id="query-input" name="query" placeholder="Ask about your documents..." autocomplete="off" required>
+                <select id="model-select" name="model" title="Model" hidden>
+                    <option value="">Default model</option>
+                </select>
                 <button type="submit" id="send-btn">Send</button>
             </form>
         </main>
@@ -160,15 +285,22 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
             container.scrollTop = container.scrollHeight;
             
             // Start SSE streaming
-            const eventSource = new EventSource('/api/query/stream?q=' + encodeURIComponent(query));
+            let url = '/api/query/stream?q=' + encodeURIComponent(query);
+            const model = document.getElementById('model-select').value;
+            if (model) url += '&model=' + encodeURIComponent(model);
+            const eventSource = new EventSource(url);
             const responseEl = document.getElementById(responseId);
             let fullResponse = '';
             
             eventSource.onmessage = function(event) {
                 const data = JSON.parse(event.data);
-                if (data.done) {
+                if (data.sources) {
+                    showSources(responseId, data.sources);
+                } else if (data.follow_ups) {
+                    showFollowUps(responseId, data.follow_ups);
+                } else if (data.done) {
                     eventSource.close();
-                    responseEl.innerHTML = fullResponse || 'No response';
+                    responseEl.innerHTML = fullResponse ? linkCitations(responseId, fullResponse) : 'No response';
                 } else if (data.content) {
                     fullResponse += data.content;
                     responseEl.innerHTML = fullResponse + '<span class="cursor">▊</span>';
@@ -186,6 +318,64 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
             };
         }
         
+        // Sources arrive before the answer; later stages replace earlier ones
+        function showSources(responseId, sources) {
+            let el = document.getElementById(responseId + '-sources');
+            if (!el) {
+                el = document.createElement('div');
+                el.id = responseId + '-sources';
+                el.className = 'sources';
+                document.getElementById(responseId).after(el);
+            }
+            const names = [...new Set(sources.map(s => s.page ? s.source + ', p. ' + s.page : s.source))];
+            el.innerHTML = names.length ? 'Sources: ' + names.map(escapeHtml).join(', ') : '';
+            // Snippet HTML is escaped server-side apart from its <mark> tags
+            sources.forEach((s, i) => {
+                const preview = document.createElement('div');
+                preview.className = 'snippet';
+                preview.id = responseId + '-source-' + (i + 1);
+                preview.innerHTML = '[' + (i + 1) + '] <strong>' + escapeHtml(s.source) + '</strong> ' + s.snippet.html;
+                el.appendChild(preview);
+            });
+        }
+
+        // Citation markers such as [1] or [1, 3] link to the numbered sources
+        function linkCitations(responseId, text) {
+            return text.replace(/\[(\d+(?:, \d+)*)\]/g, (marker, list) => {
+                const links = list.split(', ').map(n => {
+                    const id = responseId + '-source-' + n;
+                    return document.getElementById(id) ? '<a class="cite" href="#' + id + '">' + n + '</a>' : n;
+                });
+                return '<sup>[' + links.join(', ') + ']</sup>';
+            });
+        }
+        
+        // Suggested questions are pre-embedded server-side, so asking one is quick
+        function showFollowUps(responseId, questions) {
+            const el = document.createElement('div');
+            el.className = 'follow-ups';
+            questions.forEach(q => {
+                const btn = document.createElement('button');
+                btn.type = 'button';
+                btn.textContent = q;
+                btn.onclick = () => {
+                    document.getElementById('query-input').value = q;
+                    sendQuery(new Event('submit'));
+                };
+                el.appendChild(btn);
+            });
+            const sources = document.getElementById(responseId + '-sources');
+            (sources || document.getElementById(responseId)).after(el);
+        }
+        
+        // The picker only shows when the server allows choosing a model
+        fetch('/api/models').then(r => r.json()).then(data => {
+            const select = document.getElementById('model-select');
+            if (data.selected) select.options[0].text = 'Default (' + data.selected + ')';
+            (data.models || []).forEach(m => select.add(new Option(m, m)));
+            select.hidden = !data.models || data.models.length === 0;
+        });
+
         function escapeHtml(text) {
             const div = document.createElement('div');
             div.textContent = text;
@@ -201,12 +391,52 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 // handleQueryStream handles SSE streaming queries.
 func (s *Server) handleQueryStream(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
+	params := r.URL.Query()
+	query := params.Get("q")
 	if query == "" {
 		http.Error(w, "Query required", http.StatusBadRequest)
 		return
 	}
 
+	overrides, err := llmOverrides(r.Header, params.Get, stopValues(params["stop"]))
+	if err == nil {
+		err = s.queryUseCase.CheckOverrides(overrides)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), overrideStatus(err))
+		return
+	}
+	conditions, err := metadataConditions(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	expr, err := parseFilter(params.Get("filter"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	collections, err := collectionWeights(params["collections"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	minScore, _ := strconv.ParseFloat(params.Get("min_score"), 64)
+	chatReq := &entities.ChatRequest{
+		Query:       query,
+		Collection:  params.Get("collection"),
+		Collections: collections,
+		MinScore:    minScore,
+		DocumentIDs: documentIDs(params["document_id"]),
+		SessionID:   params.Get("session"),
+		Metadata:    conditions,
+		Filter:      expr,
+		Overrides:   overrides,
+	}
+	if !s.touchSession(w, chatReq.SessionID) {
+		return
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -218,42 +448,86 @@ func (s *Server) handleQueryStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
+	// Identical concurrent questions share one pipeline run
+	key := queryETag(s.epoch, s.ingestUseCase.CorpusVersion(), chatReq)
+	f := s.flights.join(key, func(ctx context.Context, f *flight) {
+		s.streamAnswer(ctx, chatReq, f)
+	})
+	defer s.flights.leave(key, f)
 
-	// Get relevant context from vector store
-	embedding, err := s.embedder.Embed(ctx, query)
-	if err != nil {
-		sendSSE(w, flusher, map[string]interface{}{"error": err.Error(), "done": true})
-		return
+	for i := 0; ; i++ {
+		event, ok := f.next(r.Context(), i)
+		if !ok {
+			return
+		}
+		sendSSE(w, flusher, event)
 	}
+}
 
-	results, err := s.vectorStore.Search(ctx, embedding, 5)
+// streamAnswer runs retrieval and generation for req, publishing SSE
+// events to f. Sources are published as soon as they are known: lexical
+// hits first (stage "lexical", hybrid mode only), then the ranked set
+// used as context (stage "ranked"), ahead of the first token. The
+// settings the answer was produced with follow it as a params event.
+func (s *Server) streamAnswer(ctx context.Context, req *entities.ChatRequest, f *flight) {
+	version := s.ingestUseCase.CorpusVersion()
+	// Get relevant context via the query usecase (respects hybrid mode)
+	opts := entities.SearchOptions{MinScore: req.MinScore, DocumentIDs: req.DocumentIDs, SessionID: req.SessionID, Metadata: req.Metadata, Filter: req.Filter, Collections: req.Collections}
+	results, err := s.queryUseCase.SearchProgressive(ctx, req.Collection, req.Query, opts, func(hits []entities.QueryResult) {
+		f.publish(map[string]interface{}{"sources": resultsJSON(req.Query, hits), "stage": "lexical"})
+	})
 	if err != nil {
-		sendSSE(w, flusher, map[string]interface{}{"error": err.Error(), "done": true})
+		f.publish(map[string]interface{}{"error": err.Error(), "done": true})
 		return
 	}
-
-	// Build prompt
-	var contextParts []string
-	for _, r := range results {
-		contextParts = append(contextParts, fmt.Sprintf("[Source: %s]\n%s", r.SourceDoc, r.Chunk.Content))
-	}
-
-	prompt := buildPrompt(query, contextParts)
+	f.publish(map[string]interface{}{"sources": resultsJSON(req.Query, results), "stage": "ranked"})
 
 	// Stream response
-	tokenCh, err := s.llm.GenerateStream(ctx, prompt, contextParts)
+	tokenCh, err := s.queryUseCase.StreamAnswer(ctx, req, results)
 	if err != nil {
-		sendSSE(w, flusher, map[string]interface{}{"error": err.Error(), "done": true})
+		f.publish(map[string]interface{}{"error": err.Error(), "done": true})
 		return
 	}
 
+	var answer strings.Builder
+	var fallback string
 	for token := range tokenCh {
 		if token.Error != nil {
-			sendSSE(w, flusher, map[string]interface{}{"error": token.Error.Error(), "done": true})
+			f.publish(map[string]interface{}{"error": token.Error.Error(), "done": true})
 			return
 		}
-		sendSSE(w, flusher, map[string]interface{}{"content": token.Content, "done": token.Done})
+		if token.Fallback != "" && fallback == "" {
+			fallback = token.Fallback
+			f.publish(map[string]interface{}{"fallback": fallback})
+		}
+		answer.WriteString(token.Content)
+		if !token.Done {
+			f.publish(map[string]interface{}{"content": token.Content, "done": false})
+			continue
+		}
+		if token.Content != "" {
+			f.publish(map[string]interface{}{"content": token.Content, "done": false})
+		}
+
+		// Suggestions go out before done; they are pre-embedded meanwhile
+		if followUps, err := s.queryUseCase.FollowUps(ctx, req.Query, answer.String()); err == nil && len(followUps) > 0 {
+			f.publish(map[string]interface{}{"follow_ups": followUps})
+		}
+		_, citations := usecases.ValidateCitations(answer.String(), len(results))
+		if len(citations) > 0 {
+			f.publish(map[string]interface{}{"citations": citations})
+		}
+		if id, err := s.shares.remember(req, &entities.ChatResponse{Answer: answer.String(), Sources: results, Citations: citations}, version); err == nil {
+			f.publish(map[string]interface{}{"answer_id": id})
+		}
+		params := s.queryUseCase.AnswerParams(req)
+		if fallback != "" {
+			params.Fallback = fallback
+			params.Model = s.queryUseCase.FallbackModel(fallback)
+		}
+		f.publish(map[string]interface{}{"params": paramsJSON(params)})
+		f.publish(map[string]interface{}{"content": "", "done": true})
+		return
 	}
 }
 
@@ -263,14 +537,295 @@ func sendSSE(w http.ResponseWriter, flusher http.Flusher, data map[string]interf
 	flusher.Flush()
 }
 
-func buildPrompt(query string, context []string) string {
-	var sb strings.Builder
-	sb.WriteString("You are a helpful assistant. Answer based on the context.\n\nContext:\n")
-	sb.WriteString(strings.Join(context, "\n\n"))
-	sb.WriteString("\n\nQuestion: ")
-	sb.WriteString(query)
-	sb.WriteString("\n\nAnswer:")
-	return sb.String()
+// documentIDs collects document filters given as repeated values or
+// comma-separated lists. It returns nil when there are none.
+func documentIDs(values []string) []string {
+	var ids []string
+	for _, v := range values {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// collectionWeights parses the collections of a federated query, given
+// as repeated values or comma-separated lists of names with an optional
+// weight, such as notes:2,papers. It returns nil when there are none.
+func collectionWeights(values []string) ([]entities.CollectionWeight, error) {
+	var collections []entities.CollectionWeight
+	for _, entry := range documentIDs(values) {
+		c := entities.CollectionWeight{Name: entry}
+		if i := strings.LastIndexByte(entry, ':'); i >= 0 {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(entry[i+1:]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight in collections entry %q", entry)
+			}
+			c = entities.CollectionWeight{Name: strings.TrimSpace(entry[:i]), Weight: weight}
+		}
+		collections = append(collections, c)
+	}
+	return collections, nil
+}
+
+// metadataConditions reads metadata filters: meta.<field>=a,b keeps
+// documents whose field is one of the listed values, and
+// meta.<field>.min and meta.<field>.max bound it. Values are compared by
+// the field's declared type.
+func metadataConditions(params url.Values) ([]entities.MetadataCondition, error) {
+	byField := make(map[string]*entities.MetadataCondition)
+	var fields []string
+	condition := func(field string) *entities.MetadataCondition {
+		c, ok := byField[field]
+		if !ok {
+			c = &entities.MetadataCondition{Field: field}
+			byField[field] = c
+			fields = append(fields, field)
+		}
+		return c
+	}
+	for key, values := range params {
+		field, ok := strings.CutPrefix(key, "meta.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(field, ".min"):
+			condition(strings.TrimSuffix(field, ".min")).Min = values[0]
+		case strings.HasSuffix(field, ".max"):
+			condition(strings.TrimSuffix(field, ".max")).Max = values[0]
+		default:
+			condition(field).In = documentIDs(values)
+		}
+	}
+
+	sort.Strings(fields)
+	conditions := make([]entities.MetadataCondition, 0, len(fields))
+	for _, field := range fields {
+		if field == "" {
+			return nil, fmt.Errorf("metadata filter without a field name")
+		}
+		conditions = append(conditions, *byField[field])
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+	return conditions, nil
+}
+
+// parseFilter parses a filter expression; empty means no filter.
+func parseFilter(expr string) (entities.FilterExpr, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	return filter.Parse(expr)
+}
+
+// metadataJSON is the JSON form of a filter on one metadata field.
+type metadataJSON struct {
+	In  []string `json:"in"`
+	Min string   `json:"min"`
+	Max string   `json:"max"`
+}
+
+// metadataFromJSON converts JSON metadata filters keyed by field name.
+func metadataFromJSON(in map[string]metadataJSON) []entities.MetadataCondition {
+	fields := make([]string, 0, len(in))
+	for field := range in {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var conditions []entities.MetadataCondition
+	for _, field := range fields {
+		c := in[field]
+		conditions = append(conditions, entities.MetadataCondition{Field: field, In: c.In, Min: c.Min, Max: c.Max})
+	}
+	return conditions
+}
+
+// filterStatus maps a failed filtered search or listing to an HTTP
+// status.
+func filterStatus(err error) int {
+	switch {
+	case errors.Is(err, usecases.ErrInvalidMetadata), errors.Is(err, usecases.ErrInvalidCollections):
+		return http.StatusBadRequest
+	case errors.Is(err, usecases.ErrModelMismatch):
+		return http.StatusConflict
+	case errors.Is(err, usecases.ErrDocumentFilterUnsupported), errors.Is(err, usecases.ErrDocumentsUnsupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// llmOverrideHeaders maps override fields to the headers that set them
+// when a request leaves the field out.
+var llmOverrideHeaders = map[string]string{
+	"model":           "X-LLM-Model",
+	"temperature":     "X-LLM-Temperature",
+	"prompt_template": "X-LLM-Prompt-Template",
+	"seed":            "X-LLM-Seed",
+	"deterministic":   "X-LLM-Deterministic",
+	"top_p":           "X-LLM-Top-P",
+	"top_k":           "X-LLM-Top-K",
+	"num_ctx":         "X-LLM-Num-Ctx",
+	"repeat_penalty":  "X-LLM-Repeat-Penalty",
+	"num_predict":     "X-LLM-Num-Predict",
+	"stop":            "X-LLM-Stop",
+}
+
+// stopEscapes lets form values and headers, where newlines are awkward
+// or barred, write them in stop sequences as \n.
+var stopEscapes = strings.NewReplacer(`\n`, "\n", `\t`, "\t")
+
+// llmOverrides reads per-request LLM overrides; field returns a request
+// field by name, "" when absent, and stop holds the request's stop
+// sequences. Request fields take precedence over the X-LLM-* headers of
+// llmOverrideHeaders, which let scripts reuse one body; X-LLM-Stop may
+// be repeated.
+func llmOverrides(h http.Header, field func(name string) string, stop []string) (entities.LLMOverrides, error) {
+	get := func(name string) string {
+		if v := field(name); v != "" {
+			return v
+		}
+		return h.Get(llmOverrideHeaders[name])
+	}
+	parseFloat := func(name string) (*float64, error) {
+		v := get(name)
+		if v == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", name, v)
+		}
+		return &f, nil
+	}
+	parseInt := func(name string) (*int, error) {
+		v := get(name)
+		if v == "" {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", name, v)
+		}
+		return &n, nil
+	}
+
+	o := entities.LLMOverrides{Model: get("model"), PromptTemplate: get("prompt_template")}
+	var err error
+	if o.Temperature, err = parseFloat("temperature"); err != nil {
+		return o, err
+	}
+	if o.TopP, err = parseFloat("top_p"); err != nil {
+		return o, err
+	}
+	if o.RepeatPenalty, err = parseFloat("repeat_penalty"); err != nil {
+		return o, err
+	}
+	if o.TopK, err = parseInt("top_k"); err != nil {
+		return o, err
+	}
+	if o.NumCtx, err = parseInt("num_ctx"); err != nil {
+		return o, err
+	}
+	if o.NumPredict, err = parseInt("num_predict"); err != nil {
+		return o, err
+	}
+	if len(stop) == 0 {
+		for _, v := range h.Values(llmOverrideHeaders["stop"]) {
+			stop = append(stop, stopEscapes.Replace(v))
+		}
+	}
+	o.Stop = stop
+	if seed := get("seed"); seed != "" {
+		n, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			return o, fmt.Errorf("invalid seed %q", seed)
+		}
+		o.Seed = &n
+	}
+	if deterministic := get("deterministic"); deterministic != "" {
+		d, err := strconv.ParseBool(deterministic)
+		if err != nil {
+			return o, fmt.Errorf("invalid deterministic flag %q", deterministic)
+		}
+		o.Deterministic = d
+	}
+	return o, nil
+}
+
+// stopValues reads stop sequences given as form or query values, with
+// \n for newlines. Empty values are kept, to be rejected.
+func stopValues(values []string) []string {
+	var stop []string
+	for _, v := range values {
+		stop = append(stop, stopEscapes.Replace(v))
+	}
+	return stop
+}
+
+// paramsJSON renders the settings an answer was produced with.
+func paramsJSON(p entities.AnswerParams) map[string]interface{} {
+	out := map[string]interface{}{
+		"deterministic":   p.Deterministic,
+		"collection":      p.Collection,
+		"embedding_model": p.EmbeddingModel,
+		"top_k":           p.TopK,
+		"min_score":       p.MinScore,
+		"hybrid":          p.Hybrid,
+	}
+	if p.Seed != nil {
+		out["seed"] = *p.Seed
+	}
+	if p.Temperature != nil {
+		out["temperature"] = *p.Temperature
+	}
+	if p.TopP != nil {
+		out["top_p"] = *p.TopP
+	}
+	if p.SamplingTopK != nil {
+		out["sampling_top_k"] = *p.SamplingTopK
+	}
+	if p.NumCtx != nil {
+		out["num_ctx"] = *p.NumCtx
+	}
+	if p.RepeatPenalty != nil {
+		out["repeat_penalty"] = *p.RepeatPenalty
+	}
+	if len(p.Collections) > 0 {
+		collections := make([]map[string]interface{}, len(p.Collections))
+		for i, c := range p.Collections {
+			collections[i] = map[string]interface{}{"name": c.Name, "weight": c.Weight}
+		}
+		out["collections"] = collections
+	}
+	if len(p.Stop) > 0 {
+		out["stop"] = p.Stop
+	}
+	if p.NumPredict != nil {
+		out["num_predict"] = *p.NumPredict
+	}
+	if p.Model != "" {
+		out["model"] = p.Model
+	}
+	if p.Fallback != "" {
+		out["fallback"] = p.Fallback
+	}
+	if p.PromptTemplate != "" {
+		out["prompt_template"] = p.PromptTemplate
+	}
+	return out
+}
+
+// overrideStatus maps a rejected override to an HTTP status.
+func overrideStatus(err error) int {
+	if errors.Is(err, usecases.ErrOverrideNotAllowed) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
 }
 
 // handleQuery processes a non-streaming query.
@@ -280,57 +835,1650 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var query string
+	chatReq, ok := s.chatRequest(w, r)
+	if !ok {
+		return
+	}
+	query := chatReq.Query
+
+	// Identical requests against an unchanged corpus reuse the answer
+	version := s.ingestUseCase.CorpusVersion()
+	etag := queryETag(s.epoch, version, chatReq)
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if body, ok := s.answers.get(etag); ok {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", etag)
+		w.Header().Set("X-Answer-Params", answerParamsHeader(s.queryUseCase.AnswerParams(chatReq)))
+		w.Write(body)
+		return
+	}
+
+	resp, err := s.queryUseCase.Query(r.Context(), chatReq)
+	if err != nil {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<div class="message error">Error: ` + err.Error() + `</div>`))
+		return
+	}
+
+	class := "message assistant"
+	if resp.Extractive {
+		class += " extractive"
+	}
+	var share string
+	if id, err := s.shares.remember(chatReq, resp, version); err == nil {
+		share = shareButtonHTML(id) + staleCheckHTML(id)
+	}
+	answer := resp.Answer
+	if len(resp.Citations) > 0 {
+		answer = citedAnswerHTML(answer, resp.Sources)
+	}
+	body := []byte(`<div class="message user">` + query + `</div><div class="` + class + `">` + answer + fallbackNoteHTML(resp.Params.Fallback) + share + `</div>` + followUpsHTML(resp.FollowUps))
+	// A fallback's answer, or one quoted from the passages after the LLM
+	// timed out, is not reused once the LLM is back, nor tagged for
+	// clients to revalidate
+	cached := resp.Params.Fallback == "" && !resp.Extractive
+	if cached {
+		s.answers.put(etag, body)
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if cached {
+		w.Header().Set("ETag", etag)
+	}
+	w.Header().Set("X-Answer-Params", answerParamsHeader(resp.Params))
+	w.Write(body)
+}
+
+// chatRequest reads the question and its options for /api/query and
+// /api/debug/query, from JSON or a form, and checks the overrides and
+// session. It answers the request itself when they are invalid.
+func (s *Server) chatRequest(w http.ResponseWriter, r *http.Request) (*entities.ChatRequest, bool) {
+	var query, collection, sessionID, filterExpr string
+	var minScore float64
+	var docIDs []string
+	var collections []entities.CollectionWeight
+	var conditions []entities.MetadataCondition
+	var overrides entities.LLMOverrides
+	var history []entities.ChatMessage
+	var err error
 	contentType := r.Header.Get("Content-Type")
 	if contentType == "application/json" {
-		var req struct{ Query string `json:"query"` }
+		var req struct {
+			Query          string                  `json:"query"`
+			Collection     string                  `json:"collection"`
+			MinScore       float64                 `json:"min_score"`
+			DocumentIDs    []string                `json:"document_ids"`
+			SessionID      string                  `json:"session"`
+			Metadata       map[string]metadataJSON `json:"metadata"`
+			Filter         string                  `json:"filter"`
+			Model          string                  `json:"model"`
+			Temperature    json.Number             `json:"temperature"`
+			PromptTemplate string                  `json:"prompt_template"`
+			Seed           json.Number             `json:"seed"`
+			Deterministic  bool                    `json:"deterministic"`
+			TopP           json.Number             `json:"top_p"`
+			TopK           json.Number             `json:"top_k"`
+			NumCtx         json.Number             `json:"num_ctx"`
+			RepeatPenalty  json.Number             `json:"repeat_penalty"`
+			NumPredict     json.Number             `json:"num_predict"`
+			Stop           []string                `json:"stop"`
+			Collections    []struct {
+				Name   string  `json:"name"`
+				Weight float64 `json:"weight"`
+			} `json:"collections"`
+			History []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"history"`
+		}
 		json.NewDecoder(r.Body).Decode(&req)
 		query = req.Query
+		collection = req.Collection
+		for _, c := range req.Collections {
+			collections = append(collections, entities.CollectionWeight{Name: c.Name, Weight: c.Weight})
+		}
+		minScore = req.MinScore
+		docIDs = documentIDs(req.DocumentIDs)
+		sessionID = req.SessionID
+		conditions = metadataFromJSON(req.Metadata)
+		filterExpr = req.Filter
+		for _, m := range req.History {
+			history = append(history, entities.ChatMessage{Role: m.Role, Content: m.Content})
+		}
+		fields := map[string]string{
+			"model":           req.Model,
+			"temperature":     string(req.Temperature),
+			"prompt_template": req.PromptTemplate,
+			"seed":            string(req.Seed),
+			"top_p":           string(req.TopP),
+			"top_k":           string(req.TopK),
+			"num_ctx":         string(req.NumCtx),
+			"repeat_penalty":  string(req.RepeatPenalty),
+			"num_predict":     string(req.NumPredict),
+		}
+		if req.Deterministic {
+			fields["deterministic"] = "true"
+		}
+		overrides, err = llmOverrides(r.Header, func(name string) string { return fields[name] }, req.Stop)
 	} else {
 		r.ParseForm()
 		query = r.FormValue("query")
+		collection = r.FormValue("collection")
+		minScore, _ = strconv.ParseFloat(r.FormValue("min_score"), 64)
+		docIDs = documentIDs(r.Form["document_id"])
+		sessionID = r.FormValue("session")
+		filterExpr = r.FormValue("filter")
+		if collections, err = collectionWeights(r.Form["collections"]); err == nil {
+			if conditions, err = metadataConditions(r.Form); err == nil {
+				overrides, err = llmOverrides(r.Header, r.FormValue, stopValues(r.Form["stop"]))
+			}
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
 	}
 
 	if query == "" {
 		http.Error(w, "Query required", http.StatusBadRequest)
+		return nil, false
+	}
+	expr, err := parseFilter(filterExpr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := s.queryUseCase.CheckOverrides(overrides); err != nil {
+		http.Error(w, err.Error(), overrideStatus(err))
+		return nil, false
+	}
+
+	chatReq := &entities.ChatRequest{
+		Query:       query,
+		History:     history,
+		Collection:  collection,
+		Collections: collections,
+		MinScore:    minScore,
+		DocumentIDs: docIDs,
+		SessionID:   sessionID,
+		Metadata:    conditions,
+		Filter:      expr,
+		Overrides:   overrides,
+	}
+	if !s.touchSession(w, sessionID) {
+		return nil, false
+	}
+	return chatReq, true
+}
+
+// answerParamsHeader renders answer settings for the X-Answer-Params
+// header, as JSON.
+func answerParamsHeader(p entities.AnswerParams) string {
+	b, _ := json.Marshal(paramsJSON(p))
+	return string(b)
+}
+
+// handleShare creates a read-only link to an answer, identified by the
+// ID behind the Share button /api/query renders or the answer_id event
+// of /api/query/stream, valid for ttl (24h by default, at most 30 days). JSON requests get the link
+// as JSON; form posts, such as the UI's Share button, get it as HTML.
+func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	chatReq := &entities.ChatRequest{Query: query}
-	resp, err := s.queryUseCase.Query(r.Context(), chatReq)
+	var id, ttlParam string
+	asJSON := r.Header.Get("Content-Type") == "application/json"
+	if asJSON {
+		var req struct {
+			Answer string `json:"answer"`
+			TTL    string `json:"ttl"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		id, ttlParam = req.Answer, req.TTL
+	} else {
+		r.ParseForm()
+		id, ttlParam = r.FormValue("answer"), r.FormValue("ttl")
+	}
+	ttl, err := shareTTL(ttlParam)
 	if err != nil {
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(`<div class="message error">Error: ` + err.Error() + `</div>`))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	link, expires, ok := s.shares.share(id, ttl)
+	if !ok {
+		http.Error(w, "Answer not found or no longer available", http.StatusNotFound)
+		return
+	}
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false) // Keep the link's & readable
+		enc.Encode(map[string]interface{}{
+			"url":        link,
+			"expires_at": expires.UTC().Format(time.RFC3339),
+		})
+		return
+	}
 	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(`<div class="message user">` + query + `</div><div class="message assistant">` + resp.Answer + `</div>`))
+	fmt.Fprintf(w, `<a class="share-link" href="%s" target="_blank">Shared link</a> <span class="share-expiry">valid until %s</span>`,
+		template.HTMLEscapeString(link), expires.Format("2006-01-02 15:04"))
 }
 
-// handleHealth returns server health status.
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+// handleStaleAnswer tells whether the documents an answer drew on, its
+// cited ones when it cites any, were deleted or re-ingested since it was
+// given (?answer=, the ID behind the Share button or the stream's
+// answer_id event). The UI's answers poll it and are flagged, with a
+// button asking again, once they are stale; other clients get JSON.
+func (s *Server) handleStaleAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("answer")
+	answer, ok := s.shares.get(id)
+	if !ok {
+		http.Error(w, "Answer not found or no longer available", http.StatusNotFound)
+		return
+	}
+
+	// Sources cannot have changed while the corpus has not
+	htmx := r.Header.Get("HX-Request") == "true"
+	var changes []usecases.SourceChange
+	if version := s.ingestUseCase.CorpusVersion(); version != answer.version {
+		var err error
+		if changes, err = s.ingestUseCase.ChangedSources(r.Context(), answer.sources); err != nil {
+			if htmx {
+				// Drop the placeholder; checking again would fail again
+				w.Header().Set("Content-Type", "text/html")
+				return
+			}
+			status := http.StatusInternalServerError
+			if errors.Is(err, usecases.ErrDocumentsUnsupported) || errors.Is(err, usecases.ErrCollectionsUnsupported) {
+				status = http.StatusNotImplemented
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if len(changes) == 0 {
+			s.shares.unchanged(id, version)
+		}
+	}
+
+	if htmx {
+		w.Header().Set("Content-Type", "text/html")
+		if len(changes) == 0 {
+			w.Write([]byte(staleCheckHTML(id)))
+		} else {
+			w.Write([]byte(staleNoticeHTML(answer, changes)))
+		}
+		return
+	}
+	changed := []map[string]interface{}{}
+	for _, c := range changes {
+		entry := map[string]interface{}{
+			"document_id": c.DocumentID,
+			"source":      c.Name,
+			"collection":  c.Collection,
+			"deleted":     c.Deleted,
+		}
+		if !c.Reingested.IsZero() {
+			entry["reingested_at"] = c.Reingested.UTC().Format(time.RFC3339)
+		}
+		changed = append(changed, entry)
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"answer_id": id, "stale": len(changes) > 0, "changed": changed})
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %v", r.Method, r.URL.Path, time.Since(start))
-	})
+// handleSharedAnswer renders a shared answer from a signed link. The
+// page has no scripts or forms and a policy forbidding them, so it gives
+// no way to query the corpus.
+func (s *Server) handleSharedAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	answer, expired := s.shares.open(params.Get("id"), params.Get("exp"), params.Get("sig"))
+	if expired {
+		http.Error(w, "This link has expired", http.StatusGone)
+		return
+	}
+	if answer == nil {
+		http.Error(w, "Shared answer not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'none'")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	sharePage.Execute(w, answer)
 }
 
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		if r.Method == "OPTIONS" {
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+// canaryJSON is a canary as /api/canaries lists it.
+func canaryJSON(c usecases.Canary) map[string]interface{} {
+	out := map[string]interface{}{
+		"collection": c.Collection,
+		"question":   c.Question,
+		"expected":   c.Expected,
+		"answer":     c.Answer,
+		"sources":    c.Sources,
+	}
+	if !c.AnsweredAt.IsZero() {
+		out["answered_at"] = c.AnsweredAt.UTC().Format(time.RFC3339)
+	}
+	return out
 }
 
+// handleCanaries lists canary questions with their last answers (GET),
+// registers one from {"collection", "question", "expected"} (POST), or
+// removes one (DELETE ?collection=&question=).
+func (s *Server) handleCanaries(w http.ResponseWriter, r *http.Request) {
+	canaries := s.queryUseCase.Canaries()
+	if canaries == nil {
+		http.Error(w, "Canaries are not configured", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list := []map[string]interface{}{}
+		for _, c := range canaries.List() {
+			list = append(list, canaryJSON(c))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"canaries": list})
+	case http.MethodPost:
+		var req struct {
+			Collection string `json:"collection"`
+			Question   string `json:"question"`
+			Expected   string `json:"expected"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Question) == "" {
+			http.Error(w, "Question required", http.StatusBadRequest)
+			return
+		}
+		canary := usecases.Canary{Collection: req.Collection, Question: req.Question, Expected: req.Expected}
+		if err := canaries.Add(canary); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		params := r.URL.Query()
+		removed, err := canaries.Remove(params.Get("collection"), params.Get("question"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			http.Error(w, "Canary not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCanaryRun asks every canary question now and reports how the
+// answers differ from the previous ones; ?format=text for the plain
+// report.
+func (s *Server) handleCanaryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.queryUseCase.Canaries() == nil {
+		http.Error(w, "Canaries are not configured", http.StatusNotImplemented)
+		return
+	}
+	report, err := s.queryUseCase.RunCanaries(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, report.Report())
+		return
+	}
+	results := make([]map[string]interface{}, len(report.Results))
+	for i, res := range report.Results {
+		out := map[string]interface{}{
+			"collection":      res.Collection,
+			"question":        res.Question,
+			"previous":        res.Previous,
+			"answer":          res.Answer,
+			"similarity":      res.Similarity,
+			"expected_before": res.ExpectedBefore,
+			"expected_after":  res.ExpectedAfter,
+			"sources_added":   res.SourcesAdded,
+			"sources_removed": res.SourcesRemoved,
+			"regressed":       res.Regressed,
+		}
+		if res.Err != nil {
+			out["error"] = res.Err.Error()
+		}
+		results[i] = out
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results":     results,
+		"regressions": len(report.Regressions()),
+		"report":      report.Report(),
+	})
+}
+
+// citationMarker matches the citation markers of a validated answer.
+var citationMarker = regexp.MustCompile(`\[(\d+(?:, \d+)*)\]`)
+
+// citedAnswerHTML renders the citation markers of an answer as
+// superscripts naming the sources they cite.
+func citedAnswerHTML(answer string, sources []entities.QueryResult) string {
+	return citationMarker.ReplaceAllStringFunc(answer, func(marker string) string {
+		var names []string
+		for _, field := range strings.Split(marker[1:len(marker)-1], ", ") {
+			if n, err := strconv.Atoi(field); err == nil && n >= 1 && n <= len(sources) {
+				names = append(names, sources[n-1].Citation())
+			}
+		}
+		return `<sup class="cite" title="` + template.HTMLEscapeString(strings.Join(names, "; ")) + `">` + marker + `</sup>`
+	})
+}
+
+// followUpsHTML renders suggested questions as htmx buttons that ask
+// them; empty when there are none.
+func followUpsHTML(questions []string) string {
+	if len(questions) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<div class="follow-ups">`)
+	for _, q := range questions {
+		vals, _ := json.Marshal(map[string]string{"query": q})
+		sb.WriteString(`<button type="button" hx-post="/api/query" hx-target="#messages" hx-swap="beforeend" hx-vals="` +
+			template.HTMLEscapeString(string(vals)) + `">` + template.HTMLEscapeString(q) + `</button>`)
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
+
+// fallbackNoteHTML says which fallback answered instead of the
+// configured LLM; empty when it answered itself.
+func fallbackNoteHTML(fallback string) string {
+	if fallback == "" {
+		return ""
+	}
+	return `<div class="fallback-note">Answered by ` + template.HTMLEscapeString(fallback) + ` because the configured model failed.</div>`
+}
+
+// checkEmbeddings probes each embedding model in use and logs its
+// width. A width that differs from a collection's stored embeddings is
+// fatal; an unreachable model is only logged, since it may still be
+// starting.
+func (s *Server) checkEmbeddings(ctx context.Context) error {
+	dims, err := s.ingestUseCase.ProbeEmbeddings(ctx)
+	if errors.Is(err, usecases.ErrModelMismatch) {
+		return fmt.Errorf("embedding dimension check failed: %w", err)
+	}
+	if err != nil {
+		log.Printf("[WARN] Embedding dimension check skipped: %v", err)
+		return nil
+	}
+	models := make([]string, 0, len(dims))
+	for model := range dims {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		name := model
+		if name == "" {
+			name = "default"
+		}
+		log.Printf("[INFO] Embedding model %s returns %d dimensions", name, dims[model])
+	}
+	return nil
+}
+
+// warmUpModels preloads the embedding model and the LLM, one after the
+// other so they do not compete for memory bandwidth while loading.
+func (s *Server) warmUpModels(ctx context.Context) {
+	for _, dep := range []struct {
+		name    string
+		service interface{}
+	}{{"embedding", s.embedder}, {"LLM", s.llm}} {
+		p, ok := dep.service.(ports.ModelPreloader)
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		if err := p.Preload(ctx); err != nil {
+			log.Printf("[WARN] Warming up the %s model failed: %v", dep.name, err)
+			continue
+		}
+		log.Printf("[INFO] Warmed up the %s model in %s", dep.name, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// runCanaries re-asks the canary questions when the settings that shape
+// answers changed since they were last asked, and logs what changed.
+func (s *Server) runCanaries(ctx context.Context) {
+	report, ran, err := s.queryUseCase.RunCanariesIfChanged(ctx)
+	if err != nil {
+		log.Printf("[WARN] Canary run failed: %v", err)
+		return
+	}
+	if !ran || len(report.Results) == 0 {
+		return
+	}
+	level := "[INFO]"
+	if len(report.Regressions()) > 0 {
+		level = "[WARN]"
+	}
+	log.Printf("%s Settings changed; canary results:\n%s", level, report.Report())
+}
+
+// logDiscoveredBackends reports local LLM/embedding servers at startup,
+// so a misconfigured URL is easy to spot.
+func (s *Server) logDiscoveredBackends(ctx context.Context) {
+	backends := discovery.Discover(ctx, discovery.DefaultCandidates, s.addr)
+	if len(backends) == 0 {
+		log.Printf("[WARN] No local LLM or embedding servers detected on default ports")
+		return
+	}
+	for _, b := range backends {
+		log.Printf("[INFO] Detected %s at %s (%s; %d models)", b.Kind, b.URL, strings.Join(b.Roles, ", "), len(b.Models))
+	}
+}
+
+// handleBackends probes default local ports and lists responding backends.
+func (s *Server) handleBackends(w http.ResponseWriter, r *http.Request) {
+	backends := discovery.Discover(r.Context(), discovery.DefaultCandidates, s.addr)
+	if backends == nil {
+		backends = []discovery.Backend{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"backends": backends})
+}
+
+// handleBackup streams a snapshot of the vector store as a download.
+// Backups hand out the whole corpus, as restores replace it and diffs
+// read snapshots anywhere on the server's filesystem, so expose these
+// endpoints only to trusted clients.
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	snap, ok := s.vectorStore.(ports.Snapshotter)
+	if !ok {
+		http.Error(w, "Vector store does not support backups", http.StatusNotImplemented)
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "localrag-backup-*")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot")
+	if err := snap.Backup(r.Context(), path); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := fmt.Sprintf("localrag-%s.snapshot", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeFile(w, r, path)
+}
+
+// handleDiff compares two snapshots on the server's filesystem, named
+// by ?before= and ?after=, and reports the documents and chunks added,
+// removed and changed. A missing path stands for the live store, so
+// ?before= alone shows what changed since that snapshot was taken.
+// ?format=text returns the human-readable report alone. Like backups,
+// it is for trusted clients only.
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	diff, err := s.ingestUseCase.DiffSnapshots(r.Context(), q.Get("before"), q.Get("after"))
+	if errors.Is(err, usecases.ErrDiffUnsupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if q.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, diff.Report())
+		return
+	}
+
+	type documentJSON struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		ChunksAdded   int    `json:"chunks_added"`
+		ChunksRemoved int    `json:"chunks_removed"`
+		ChunksChanged int    `json:"chunks_changed"`
+	}
+	documents := func(in []usecases.DocumentDiff) []documentJSON {
+		out := make([]documentJSON, len(in))
+		for i, d := range in {
+			out[i] = documentJSON{ID: d.ID, Name: d.Name, ChunksAdded: d.ChunksAdded, ChunksRemoved: d.ChunksRemoved, ChunksChanged: d.ChunksChanged}
+		}
+		return out
+	}
+	collections := []map[string]interface{}{}
+	for _, c := range diff.Collections {
+		collections = append(collections, map[string]interface{}{
+			"name":          c.Name,
+			"added":         documents(c.Added),
+			"removed":       documents(c.Removed),
+			"changed":       documents(c.Changed),
+			"unchanged":     c.Unchanged,
+			"chunks_before": c.ChunksBefore,
+			"chunks_after":  c.ChunksAfter,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"collections": collections,
+		"report":      diff.Report(),
+	})
+}
+
+// handleRestore replaces the vector store with an uploaded snapshot.
+// Like backups, it is for trusted clients only.
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	snap, ok := s.vectorStore.(ports.Snapshotter)
+	if !ok {
+		http.Error(w, "Vector store does not support backups", http.StatusNotImplemented)
+		return
+	}
+
+	file, err := os.CreateTemp("", "localrag-restore-*")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, r.Body)
+	file.Close()
+	if err != nil {
+		http.Error(w, "Reading upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := snap.Restore(r.Context(), file.Name()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.ingestUseCase.Invalidate()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "restored"})
+}
+
+// handleMaintenance prunes documents whose source file is gone (unless
+// prune=false) and compacts the vector store, reporting what changed.
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sourceExists := fileExists
+	if r.URL.Query().Get("prune") == "false" {
+		sourceExists = func(string) bool { return true }
+	}
+
+	report, err := s.ingestUseCase.Maintain(r.Context(), sourceExists)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rebuilt := report.IndexesRebuilt
+	if rebuilt == nil {
+		rebuilt = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pruned_documents":  report.PrunedDocuments,
+		"evicted_documents": report.EvictedDocuments,
+		"expired_sessions":  report.ExpiredSessions,
+		"orphans_removed":   report.OrphansRemoved,
+		"indexes_rebuilt":   rebuilt,
+		"reclaimed_bytes":   report.ReclaimedBytes,
+	})
+}
+
+// handleIngest ingests files and directories on the server's filesystem
+// and reports what was processed, skipped or failed, and why.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.loader == nil {
+		http.Error(w, "Ingestion is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		Paths      []string             `json:"paths"`
+		Collection string               `json:"collection"`
+		Metadata   map[string]string    `json:"metadata"`
+		Provenance []transformationJSON `json:"provenance"`
+		Async      bool                 `json:"async"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Paths) == 0 {
+		http.Error(w, "Paths required", http.StatusBadRequest)
+		return
+	}
+
+	// Expand directories; unreadable entries reach the report as load errors
+	var files []string
+	for _, root := range req.Paths {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+
+	loader := s.loader
+	if len(req.Metadata) > 0 || len(req.Provenance) > 0 {
+		loader = annotatingLoader{DocumentLoader: loader, metadata: req.Metadata, provenance: provenanceFromJSON(req.Provenance)}
+	}
+
+	// Long runs go to the background; clients follow them under /api/jobs
+	if req.Async {
+		job, err := s.jobs.start("ingest", req.Collection, len(files), func(ctx context.Context, update func(entities.IngestProgress)) (entities.IngestReport, error) {
+			return s.ingestUseCase.IngestFilesWithProgress(ctx, loader, req.Collection, files, update)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		acceptJob(w, job)
+		return
+	}
+
+	report, err := s.ingestUseCase.IngestFiles(r.Context(), loader, req.Collection, files)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ingestReportJSON(report))
+}
+
+// acceptJob answers 202 Accepted with where to follow a background job.
+func acceptJob(w http.ResponseWriter, job *ingestJob) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs?id="+job.id)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"job_id":     job.id,
+		"status_url": "/api/jobs?id=" + job.id,
+		"events_url": "/api/jobs/events?id=" + job.id,
+	})
+}
+
+// handleReembed re-embeds a collection's stored chunks with another
+// registered model, or its own when none is given, in the background and
+// switches the collection over to it. Progress counts documents as files.
+func (s *Server) handleReembed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Collection string `json:"collection"`
+		Model      string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	job, err := s.jobs.start("reembed", req.Collection, 0, func(ctx context.Context, update func(entities.IngestProgress)) (entities.IngestReport, error) {
+		return s.ingestUseCase.ReembedCollection(ctx, req.Collection, req.Model, update)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	acceptJob(w, job)
+}
+
+// handleReembedRollback switches a collection back to its contents
+// before the last re-embed (POST), or drops them (DELETE).
+func (s *Server) handleReembedRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	collection := r.URL.Query().Get("collection")
+
+	status := "rolled back"
+	var err error
+	if r.Method == http.MethodDelete {
+		status = "discarded"
+		err = s.ingestUseCase.DiscardRollback(r.Context(), collection)
+	} else {
+		err = s.ingestUseCase.RollbackCollection(r.Context(), collection)
+	}
+	switch {
+	case errors.Is(err, usecases.ErrNoRollback):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, usecases.ErrReembedUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// ingestReportJSON renders an ingestion report for the API.
+func ingestReportJSON(report entities.IngestReport) map[string]interface{} {
+	type issueJSON struct {
+		Path   string `json:"path"`
+		Reason string `json:"reason"`
+	}
+	issues := func(in []entities.IngestIssue) []issueJSON {
+		out := make([]issueJSON, len(in))
+		for i, is := range in {
+			out[i] = issueJSON{Path: is.Path, Reason: is.Reason}
+		}
+		return out
+	}
+	return map[string]interface{}{
+		"files_processed":  report.FilesProcessed,
+		"chunks_created":   report.ChunksCreated,
+		"chunks_unchanged": report.ChunksUnchanged,
+		"chunks_failed":    report.ChunksFailed,
+		"evicted":          report.DocumentsEvicted,
+		"skipped":          issues(report.Skipped),
+		"errors":           issues(report.Errors),
+		"embed_ms":         report.EmbedDuration.Milliseconds(),
+		"store_ms":         report.StoreDuration.Milliseconds(),
+		"duration_ms":      report.Duration.Milliseconds(),
+	}
+}
+
+// handleJobs reports a background job's progress, with its
+// throughput and estimated time left, or lists all known jobs when no
+// id is given.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		jobs := []map[string]interface{}{}
+		for _, j := range s.jobs.list() {
+			status, _ := j.status()
+			jobs = append(jobs, status)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jobs": jobs})
+		return
+	}
+	job, ok := s.jobs.get(id)
+	if !ok {
+		http.Error(w, "Unknown job", http.StatusNotFound)
+		return
+	}
+	status, _ := job.status()
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleJobEvents streams a job's status as SSE events, one per change,
+// ending with the event whose state is "done" or "failed".
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.get(r.URL.Query().Get("id"))
+	if !ok {
+		http.Error(w, "Unknown job", http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	// Ingestion can outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	for {
+		changed := job.changed()
+		status, done := job.status()
+		sendSSE(w, flusher, status)
+		if done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// annotatingLoader gives every document it loads the same metadata and
+// provenance. Its metadata overrides fields the loader read from the
+// file, such as an email's subject.
+type annotatingLoader struct {
+	ports.DocumentLoader
+	metadata   map[string]string
+	provenance entities.Provenance
+}
+
+func (l annotatingLoader) Load(ctx context.Context, path string) (*entities.Document, error) {
+	doc, err := l.DocumentLoader.Load(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(l.metadata) > 0 {
+		merged := make(map[string]string, len(doc.Metadata)+len(l.metadata))
+		for k, v := range doc.Metadata {
+			merged[k] = v
+		}
+		doc.Metadata = merged
+		for k, v := range l.metadata {
+			doc.Metadata[k] = v
+		}
+	}
+	doc.Provenance = append(doc.Provenance, l.provenance...)
+	return doc, nil
+}
+
+// handleSessions attaches the file in the request body, named by the
+// name parameter, to the chat session given by session on POST,
+// starting a new session when it is empty. DELETE ends the session and
+// discards its documents. Attached files answer only queries that pass
+// the session; they never join the corpus.
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session")
+	switch r.Method {
+	case http.MethodPost:
+		s.handleAttach(w, r, sessionID)
+	case http.MethodDelete:
+		err := s.ingestUseCase.EndSession(r.Context(), sessionID)
+		if errors.Is(err, usecases.ErrInvalidSession) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ended"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAttach loads an uploaded file with the configured loader and
+// attaches it to a session. Re-attaching a name replaces that file.
+func (s *Server) handleAttach(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if s.loader == nil {
+		http.Error(w, "Ingestion is not configured", http.StatusNotImplemented)
+		return
+	}
+	name := filepath.Base(r.URL.Query().Get("name"))
+	if name == "." || name == string(filepath.Separator) {
+		http.Error(w, "Name required", http.StatusBadRequest)
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	supported := false
+	for _, e := range s.loader.SupportedExtensions() {
+		supported = supported || strings.ToLower(e) == ext
+	}
+	if !supported {
+		http.Error(w, "Unsupported file type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	file, err := os.CreateTemp("", "localrag-upload-*"+ext)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, r.Body)
+	file.Close()
+	if err != nil {
+		http.Error(w, "Reading upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	doc, err := s.loader.Load(r.Context(), file.Name())
+	if err != nil {
+		http.Error(w, "Loading upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256([]byte(name))
+	doc.ID = hex.EncodeToString(sum[:8])
+	doc.Name = name
+
+	sessionID, err = s.ingestUseCase.AttachToSession(r.Context(), sessionID, doc)
+	switch {
+	case errors.Is(err, usecases.ErrInvalidSession), errors.Is(err, usecases.ErrEmptyDocument):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, usecases.ErrCollectionsUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"session": sessionID, "document_id": doc.ID, "name": name})
+}
+
+// touchSession keeps the session a query names alive. It answers 400
+// and returns false for a malformed ID; an empty ID is no session.
+func (s *Server) touchSession(w http.ResponseWriter, sessionID string) bool {
+	if sessionID == "" {
+		return true
+	}
+	if err := s.ingestUseCase.TouchSession(sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// fileExists reports whether a document's source path still exists.
+// Errors other than not-existing, such as an unmounted share, count as
+// existing so they never cause pruning, as do web pages' URLs, which are
+// refreshed by ingesting them again.
+func fileExists(path string) bool {
+	if webURL(path) {
+		return true
+	}
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// maintainPeriodically runs maintenance every maintainEvery until ctx
+// ends.
+func (s *Server) maintainPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(s.maintainEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.ingestUseCase.Maintain(ctx, fileExists)
+			if err != nil {
+				log.Printf("[WARN] Scheduled maintenance: %v", err)
+				continue
+			}
+			if report.PrunedDocuments+report.EvictedDocuments > 0 {
+				log.Printf("[INFO] Scheduled maintenance pruned %d and evicted %d documents",
+					report.PrunedDocuments, report.EvictedDocuments)
+			}
+		}
+	}
+}
+
+// expireSessions periodically ends idle chat sessions until ctx ends.
+func (s *Server) expireSessions(ctx context.Context) {
+	ticker := time.NewTicker(sessionSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.ingestUseCase.ExpireSessions(ctx); err != nil {
+				log.Printf("[WARN] Expiring sessions: %v", err)
+			} else if n > 0 {
+				log.Printf("[INFO] Ended %d idle chat sessions", n)
+			}
+		}
+	}
+}
+
+// handleSearch returns ranked chunks without generating an answer.
+// Supports limit/offset paging and a min_score relevance cutoff.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := params.Get("q")
+	if query == "" {
+		http.Error(w, "Query required", http.StatusBadRequest)
+		return
+	}
+
+	var opts entities.SearchOptions
+	var err error
+	if v := params.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("offset"); v != "" {
+		if opts.Offset, err = strconv.Atoi(v); err != nil || opts.Offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("min_score"); v != "" {
+		if opts.MinScore, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Invalid min_score", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if opts.Metadata, err = metadataConditions(params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Filter, err = parseFilter(params.Get("filter")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Collections, err = collectionWeights(params["collections"]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.DocumentIDs = documentIDs(params["document_id"])
+	opts.SessionID = params.Get("session")
+	if !s.touchSession(w, opts.SessionID) {
+		return
+	}
+
+	results, err := s.queryUseCase.SearchPage(r.Context(), params.Get("collection"), query, opts)
+	if err != nil {
+		http.Error(w, err.Error(), filterStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": resultsJSON(query, results), "offset": opts.Offset})
+}
+
+// resultJSON is the wire form of a retrieved chunk. Provenance lists the
+// machine transformations behind its text, oldest first, and is absent
+// when the text was taken from the source as is.
+type resultJSON struct {
+	ChunkID    string               `json:"chunk_id"`
+	DocumentID string               `json:"document_id"`
+	Source     string               `json:"source"`
+	Path       string               `json:"path,omitempty"`
+	Page       int                  `json:"page,omitempty"`
+	Collection string               `json:"collection,omitempty"`
+	Content    string               `json:"content"`
+	Score      float64              `json:"score"`
+	Snippet    snippetJSON          `json:"snippet"`
+	Provenance []transformationJSON `json:"provenance,omitempty"`
+}
+
+// transformationJSON is the wire form of a step of a document's
+// provenance.
+type transformationJSON struct {
+	Kind   string `json:"kind"`
+	Tool   string `json:"tool,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func provenanceJSON(p entities.Provenance) []transformationJSON {
+	var out []transformationJSON
+	for _, t := range p {
+		out = append(out, transformationJSON{Kind: t.Kind, Tool: t.Tool, Detail: t.Detail})
+	}
+	return out
+}
+
+func provenanceFromJSON(in []transformationJSON) entities.Provenance {
+	var out entities.Provenance
+	for _, t := range in {
+		out = append(out, entities.Transformation{Kind: t.Kind, Tool: t.Tool, Detail: t.Detail})
+	}
+	return out
+}
+
+// snippetJSON is a preview of a chunk. Highlights are byte offsets into
+// Text; HTML is Text escaped, with each highlight wrapped in <mark>.
+type snippetJSON struct {
+	Text       string          `json:"text"`
+	Highlights []highlightJSON `json:"highlights"`
+	HTML       string          `json:"html"`
+}
+
+type highlightJSON struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func resultsJSON(query string, results []entities.QueryResult) []resultJSON {
+	out := make([]resultJSON, len(results))
+	for i, res := range results {
+		out[i] = resultJSON{
+			ChunkID:    res.Chunk.ID,
+			DocumentID: res.Chunk.DocumentID,
+			Source:     res.SourceDoc,
+			Path:       res.SourcePath,
+			Page:       res.Chunk.Page,
+			Collection: res.Collection,
+			Content:    res.Chunk.Content,
+			Score:      res.Score,
+			Snippet:    newSnippetJSON(usecases.BuildSnippet(res.Chunk.Content, query)),
+			Provenance: provenanceJSON(res.Provenance),
+		}
+	}
+	return out
+}
+
+func newSnippetJSON(snippet entities.Snippet) snippetJSON {
+	out := snippetJSON{Text: snippet.Text, Highlights: []highlightJSON{}}
+	var html strings.Builder
+	last := 0
+	for _, h := range snippet.Highlights {
+		out.Highlights = append(out.Highlights, highlightJSON{Start: h.Start, End: h.End})
+		html.WriteString(template.HTMLEscapeString(snippet.Text[last:h.Start]))
+		html.WriteString("<mark>" + template.HTMLEscapeString(snippet.Text[h.Start:h.End]) + "</mark>")
+		last = h.End
+	}
+	html.WriteString(template.HTMLEscapeString(snippet.Text[last:]))
+	out.HTML = html.String()
+	return out
+}
+
+// backendHealth collects reports from adapters that talk to remote backends.
+func (s *Server) backendHealth() []ports.BackendHealth {
+	var reports []ports.BackendHealth
+	for _, dep := range []interface{}{s.llm, s.embedder, s.vectorStore} {
+		if hr, ok := dep.(ports.HealthReporter); ok {
+			reports = append(reports, hr.Health())
+		}
+	}
+	return reports
+}
+
+// handleHealth returns server health status. The server stays up while
+// a backend is down, so an unhealthy backend reports "degraded" rather
+// than failing the check. With guardrails set, "resources" reports the
+// host's load and a healthy server under too much of it "overloaded".
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	type backendJSON struct {
+		Name                string     `json:"name"`
+		Healthy             bool       `json:"healthy"`
+		ConsecutiveFailures int        `json:"consecutive_failures"`
+		TotalFailures       uint64     `json:"total_failures"`
+		Reconnects          uint64     `json:"reconnects"`
+		LastError           string     `json:"last_error,omitempty"`
+		LastSuccess         *time.Time `json:"last_success,omitempty"`
+		LastFailure         *time.Time `json:"last_failure,omitempty"`
+		CircuitOpen         bool       `json:"circuit_open,omitempty"`
+	}
+
+	status := "ok"
+	backends := []backendJSON{}
+	for _, h := range s.backendHealth() {
+		if !h.Healthy {
+			status = "degraded"
+		}
+		b := backendJSON{
+			Name:                h.Name,
+			Healthy:             h.Healthy,
+			ConsecutiveFailures: h.ConsecutiveFailures,
+			TotalFailures:       h.TotalFailures,
+			Reconnects:          h.Reconnects,
+			LastError:           h.LastError,
+			CircuitOpen:         h.CircuitOpen,
+		}
+		if !h.LastSuccess.IsZero() {
+			b.LastSuccess = &h.LastSuccess
+		}
+		if !h.LastFailure.IsZero() {
+			b.LastFailure = &h.LastFailure
+		}
+		backends = append(backends, b)
+	}
+
+	health := map[string]interface{}{"status": status, "backends": backends}
+	if s.guard != nil {
+		state := s.guard.State()
+		if state.Overloaded && status == "ok" {
+			status = "overloaded"
+		}
+		resources := map[string]interface{}{
+			"overloaded": state.Overloaded,
+			"cpu":        state.Usage.CPU,
+			"memory":     state.Usage.Memory,
+			"reasons":    append([]string{}, state.Reasons...),
+			"queued":     state.Queued,
+			"running":    state.Running,
+		}
+		if !state.SampledAt.IsZero() {
+			resources["sampled_at"] = state.SampledAt
+		}
+		if state.LastError != "" {
+			resources["last_error"] = state.LastError
+		}
+		health["status"] = status
+		health["resources"] = resources
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// handleMetrics exposes backend gauges in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	reports := s.backendHealth()
+
+	var sb strings.Builder
+	metric := func(name, kind, help string, value func(ports.BackendHealth) float64) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, h := range reports {
+			fmt.Fprintf(&sb, "%s{backend=%q} %g\n", name, h.Name, value(h))
+		}
+	}
+	metric("localrag_backend_up", "gauge", "Whether the last call to the backend succeeded.", func(h ports.BackendHealth) float64 {
+		if h.Healthy {
+			return 1
+		}
+		return 0
+	})
+	metric("localrag_backend_consecutive_failures", "gauge", "Failed calls since the last success.", func(h ports.BackendHealth) float64 {
+		return float64(h.ConsecutiveFailures)
+	})
+	metric("localrag_backend_failures_total", "counter", "Failed calls to the backend.", func(h ports.BackendHealth) float64 {
+		return float64(h.TotalFailures)
+	})
+	metric("localrag_backend_reconnects_total", "counter", "Connections re-established after a failure.", func(h ports.BackendHealth) float64 {
+		return float64(h.Reconnects)
+	})
+	metric("localrag_backend_circuit_open", "gauge", "Whether calls to the backend are held back after repeated failures.", func(h ports.BackendHealth) float64 {
+		if h.CircuitOpen {
+			return 1
+		}
+		return 0
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(sb.String()))
+}
+
+// handleCollections lists the collections in the vector store, leaving
+// out the ephemeral ones holding session uploads and those a re-embed
+// keeps beside the corpus.
+func (s *Server) handleCollections(w http.ResponseWriter, r *http.Request) {
+	names := []string{entities.DefaultCollection}
+	if cs, ok := s.vectorStore.(ports.CollectionStore); ok {
+		var err error
+		all, err := cs.Collections(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		names = names[:0]
+		for _, name := range all {
+			if !entities.IsSessionCollection(name) && !entities.IsReembedCollection(name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"collections": names})
+}
+
+// handleModels lists the LLM models a query may select with model, for
+// the UI's model picker: those the override policy names and, when it
+// allows them, those the LLM's backend serves. "selected" is the model
+// answering queries that select none. "backends" reports, for the LLM,
+// the embedder and each fallback implementing ports.ModelLister, the
+// models its backend serves and the one it uses; a backend that cannot
+// be reached reports its error instead.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), modelListTimeout)
+	defer cancel()
+
+	models := s.queryUseCase.Models()
+	out := map[string]interface{}{}
+	backends := []map[string]interface{}{}
+	list := func(role, name string, service interface{}) []string {
+		lister, ok := service.(ports.ModelLister)
+		if !ok {
+			return nil
+		}
+		backend := map[string]interface{}{"role": role, "selected": lister.Model()}
+		if name != "" {
+			backend["name"] = name
+		}
+		installed, err := lister.Models(ctx)
+		if err != nil {
+			backend["error"] = err.Error()
+		} else {
+			backend["installed"] = installed
+		}
+		backends = append(backends, backend)
+		return installed
+	}
+
+	if lister, ok := s.llm.(ports.ModelLister); ok {
+		out["selected"] = lister.Model()
+	}
+	offered := make(map[string]bool, len(models))
+	for _, m := range models {
+		offered[m] = true
+	}
+	for _, m := range list("llm", "", s.llm) {
+		if s.queryUseCase.AllowsModel(m) && !offered[m] {
+			models = append(models, m)
+		}
+	}
+	list("embedding", "", s.embedder)
+	for _, b := range s.queryUseCase.Fallbacks() {
+		if b.LLM != s.llm {
+			list("fallback", b.Name, b.LLM)
+		}
+	}
+
+	out["models"] = models
+	out["backends"] = backends
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// handleDocuments lists ingested documents in a collection, filtered by
+// q, path_prefix, ingested_after, ingested_before, meta.<field> and a
+// filter expression and paged by limit and offset, or deletes several
+// at once on DELETE.
+func (s *Server) handleDocuments(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		s.handleDeleteDocuments(w, r)
+		return
+	}
+
+	params := r.URL.Query()
+	filter := entities.DocumentFilter{
+		Query:      params.Get("q"),
+		PathPrefix: params.Get("path_prefix"),
+	}
+	var page entities.Page
+	var err error
+	if filter.Metadata, err = metadataConditions(params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Expr, err = parseFilter(params.Get("filter")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		if page.Limit, err = strconv.Atoi(v); err != nil || page.Limit < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("offset"); v != "" {
+		if page.Offset, err = strconv.Atoi(v); err != nil || page.Offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("ingested_after"); v != "" {
+		if filter.IngestedAfter, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid ingested_after", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("ingested_before"); v != "" {
+		if filter.IngestedBefore, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid ingested_before", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := s.ingestUseCase.FindDocuments(r.Context(), params.Get("collection"), filter, page)
+	if err != nil {
+		http.Error(w, err.Error(), filterStatus(err))
+		return
+	}
+	docs := result.Documents
+
+	type documentJSON struct {
+		ID         string               `json:"id"`
+		Name       string               `json:"name"`
+		Path       string               `json:"path"`
+		ChunkCount int                  `json:"chunk_count"`
+		IngestedAt time.Time            `json:"ingested_at"`
+		Metadata   map[string]string    `json:"metadata,omitempty"`
+		Provenance []transformationJSON `json:"provenance,omitempty"`
+	}
+	out := make([]documentJSON, len(docs))
+	for i, d := range docs {
+		out[i] = documentJSON{ID: d.ID, Name: d.Name, Path: d.Path, ChunkCount: d.ChunkCount, IngestedAt: d.IngestedAt, Metadata: d.Metadata, Provenance: provenanceJSON(d.Provenance)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"documents": out, "total": result.Total})
+}
+
+// handleStats reports the size of a collection and of the store.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.ingestUseCase.Stats(r.Context(), r.URL.Query().Get("collection"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"chunks":     stats.Chunks,
+		"documents":  stats.Documents,
+		"dimension":  stats.Dimension,
+		"size_bytes": stats.SizeBytes,
+	})
+}
+
+// handleDeleteDocuments deletes the documents named by repeated or
+// comma-separated id values, in one transaction where the store allows,
+// or every document matching a filter expression.
+func (s *Server) handleDeleteDocuments(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	ids := documentIDs(params["id"])
+	expr, err := parseFilter(params.Get("filter"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(ids) == 0 && expr == nil {
+		http.Error(w, "Document IDs or filter required", http.StatusBadRequest)
+		return
+	}
+	if len(ids) > 0 && expr != nil {
+		http.Error(w, "Pass either document IDs or a filter", http.StatusBadRequest)
+		return
+	}
+
+	deleted := len(ids)
+	if expr != nil {
+		deleted, err = s.ingestUseCase.DeleteMatching(r.Context(), params.Get("collection"), entities.DocumentFilter{Expr: expr})
+	} else {
+		err = s.ingestUseCase.DeleteMany(r.Context(), params.Get("collection"), ids)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), filterStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"deleted": deleted})
+}
+
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Printf("%s %s %v", r.Method, r.URL.Path, time.Since(start))
+	})
+}
+
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-None-Match, X-LLM-Model, X-LLM-Temperature, X-LLM-Prompt-Template, X-LLM-Seed, X-LLM-Deterministic, X-LLM-Top-P, X-LLM-Top-K, X-LLM-Num-Ctx, X-LLM-Repeat-Penalty, X-LLM-Num-Predict, X-LLM-Stop")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Location")
+		if r.Method == "OPTIONS" {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/vectordb"
+)
+
+func TestHandleBackup_RequiresGet(t *testing.T) {
+	store := vectordb.NewInMemoryStore()
+	s, err := NewServer(nil, nil, nil, nil, store, "")
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.handleBackup(w, httptest.NewRequest(http.MethodPost, "/api/backup", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for a POST, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.handleBackup(w, httptest.NewRequest(http.MethodGet, "/api/backup", nil))
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Errorf("expected a snapshot download, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+}
@@ -0,0 +1,298 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/usecases"
+)
+
+const (
+	// shareableAnswers bounds the recent answers that can still be
+	// shared; shared answers are kept until their link expires.
+	shareableAnswers = 256
+
+	// defaultShareTTL and maxShareTTL bound how long a link is valid.
+	defaultShareTTL = 24 * time.Hour
+	maxShareTTL     = 30 * 24 * time.Hour
+)
+
+// sharedAnswer is an answered question as a share link shows it.
+type sharedAnswer struct {
+	Query      string
+	Answer     string
+	Sources    []string // Source document names, without their text
+	Cited      []citedSource
+	Extractive bool
+	AnsweredAt time.Time
+	expires    time.Time // Latest expiry of a link to it; zero until shared
+
+	sources []usecases.AnswerSource // Documents it drew on, for staleness checks
+	version uint64                  // Corpus version its sources were last known unchanged at
+	reask   map[string]string       // Form values asking the question again
+}
+
+// citedSource names the source behind a citation marker of an answer.
+type citedSource struct {
+	N      int
+	Source string
+}
+
+// shareRegistry keeps recent answers so they can be shared, and signs
+// links to them. Links carry their expiry and an HMAC over the answer ID
+// and expiry under a per-process key, so they cannot be extended or
+// forged, and all of them lapse when the server restarts.
+type shareRegistry struct {
+	mu      sync.Mutex
+	key     []byte
+	answers map[string]*sharedAnswer
+	order   []string // Unshared answers, oldest first
+}
+
+func newShareRegistry() *shareRegistry {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return &shareRegistry{key: key, answers: make(map[string]*sharedAnswer)}
+}
+
+// remember records an answer to req, retrieved at corpus version
+// version, and returns the ID to share it by.
+func (r *shareRegistry) remember(req *entities.ChatRequest, resp *entities.ChatResponse, version uint64) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating answer ID: %w", err)
+	}
+	id := hex.EncodeToString(b)
+	a := &sharedAnswer{
+		Query:      req.Query,
+		Answer:     resp.Answer,
+		Extractive: resp.Extractive,
+		AnsweredAt: time.Now(),
+		sources:    usecases.AnswerSources(req, resp.Sources, resp.Citations),
+		version:    version,
+		reask:      reaskValues(req),
+	}
+	seen := make(map[string]bool)
+	for _, src := range resp.Sources {
+		if src.SourceDoc != "" && !seen[src.SourceDoc] {
+			seen[src.SourceDoc] = true
+			a.Sources = append(a.Sources, src.SourceDoc)
+		}
+	}
+	for _, n := range resp.Citations {
+		if n >= 1 && n <= len(resp.Sources) {
+			a.Cited = append(a.Cited, citedSource{N: n, Source: resp.Sources[n-1].Citation()})
+		}
+	}
+	sort.Slice(a.Cited, func(i, j int) bool { return a.Cited[i].N < a.Cited[j].N })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.answers[id] = a
+	r.order = append(r.order, id)
+	r.evict(time.Now())
+	return id, nil
+}
+
+// get returns a copy of a remembered answer. ok is false when it has
+// been forgotten.
+func (r *shareRegistry) get(id string) (a sharedAnswer, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if found, ok := r.answers[id]; ok {
+		return *found, true
+	}
+	return sharedAnswer{}, false
+}
+
+// unchanged records that an answer's sources were found unchanged at
+// corpus version version, so checks skip the store until it changes.
+func (r *shareRegistry) unchanged(id string, version uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if a, ok := r.answers[id]; ok {
+		a.version = version
+	}
+}
+
+// share signs a link to an answer valid for ttl and keeps the answer
+// until it expires. ok is false when the answer has been forgotten.
+func (r *shareRegistry) share(id string, ttl time.Duration) (link string, expires time.Time, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.answers[id]
+	if !ok {
+		return "", time.Time{}, false
+	}
+	expires = time.Now().Add(ttl).Truncate(time.Second)
+	if expires.After(a.expires) {
+		a.expires = expires
+	}
+	for i, queued := range r.order {
+		if queued == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{"id": {id}, "exp": {exp}, "sig": {r.sign(id, exp)}}
+	return "/share?" + q.Encode(), expires, true
+}
+
+// open checks a link's signature and expiry and returns its answer.
+// expired is set for well-signed links past their expiry.
+func (r *shareRegistry) open(id, exp, sig string) (a *sharedAnswer, expired bool) {
+	if !hmac.Equal([]byte(sig), []byte(r.sign(id, exp))) {
+		return nil, false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	now := time.Now()
+	if err != nil || now.Unix() >= unix {
+		return nil, true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.evict(now)
+	a, ok := r.answers[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *a
+	return &copied, false
+}
+
+func (r *shareRegistry) sign(id, exp string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(id + "\x00" + exp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// evict forgets the oldest unshared answers beyond shareableAnswers and
+// shared ones whose links have all expired. Caller must hold r.mu.
+func (r *shareRegistry) evict(now time.Time) {
+	for len(r.order) > shareableAnswers {
+		delete(r.answers, r.order[0])
+		r.order = r.order[1:]
+	}
+	for id, a := range r.answers {
+		if !a.expires.IsZero() && !now.Before(a.expires) {
+			delete(r.answers, id)
+		}
+	}
+}
+
+// shareTTL parses a requested link lifetime such as "72h", defaulting
+// to defaultShareTTL and capped at maxShareTTL.
+func shareTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultShareTTL, nil
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl %q", s)
+	}
+	if ttl > maxShareTTL {
+		ttl = maxShareTTL
+	}
+	return ttl, nil
+}
+
+// reaskValues are the form values that ask req's question again over
+// the same collections and documents.
+func reaskValues(req *entities.ChatRequest) map[string]string {
+	vals := map[string]string{"query": req.Query}
+	if req.Collection != "" {
+		vals["collection"] = req.Collection
+	}
+	if len(req.Collections) > 0 {
+		var collections []string
+		for _, c := range req.Collections {
+			entry := c.Name
+			if c.Weight != 0 {
+				entry += ":" + strconv.FormatFloat(c.Weight, 'g', -1, 64)
+			}
+			collections = append(collections, entry)
+		}
+		vals["collections"] = strings.Join(collections, ",")
+	}
+	if len(req.DocumentIDs) > 0 {
+		vals["document_id"] = strings.Join(req.DocumentIDs, ",")
+	}
+	return vals
+}
+
+// staleCheckHTML renders a placeholder that checks every 30 seconds
+// whether an answer's sources have changed, replacing itself with
+// staleNoticeHTML once they have.
+func staleCheckHTML(id string) string {
+	return `<span class="stale-check" hx-get="/api/answers/stale?answer=` + id + `" hx-trigger="every 30s" hx-swap="outerHTML"></span>`
+}
+
+// staleNoticeHTML flags an answer whose sources changed, naming them,
+// with a button asking the question again.
+func staleNoticeHTML(a sharedAnswer, changes []usecases.SourceChange) string {
+	var names []string
+	for _, c := range changes {
+		name := c.Name
+		if name == "" {
+			name = c.DocumentID
+		}
+		if c.Deleted {
+			name += " (deleted)"
+		} else {
+			name += " (updated)"
+		}
+		names = append(names, name)
+	}
+	vals, _ := json.Marshal(a.reask)
+	return `<div class="stale">This answer may be out of date; its sources changed since: ` + template.HTMLEscapeString(strings.Join(names, ", ")) +
+		`. <button type="button" hx-post="/api/query" hx-target="#messages" hx-swap="beforeend" hx-vals="` + template.HTMLEscapeString(string(vals)) + `">Ask again</button></div>`
+}
+
+// shareButtonHTML renders the button that turns an answer into a link.
+func shareButtonHTML(id string) string {
+	return `<button type="button" class="share" hx-post="/api/share" hx-vals='{"answer": "` + id + `"}' hx-swap="outerHTML">Share</button>`
+}
+
+// sharePage renders a shared answer on its own: no scripts, forms or
+// links back into the app, so a viewer can read it and nothing more.
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Shared answer - LocalRAG</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.question { font-weight: 600; }
+.answer { white-space: pre-wrap; line-height: 1.5; }
+.meta, .sources { color: #666; font-size: 0.9rem; }
+</style>
+</head>
+<body>
+<p class="question">{{.Query}}</p>
+<div class="answer">{{.Answer}}</div>
+{{if .Extractive}}<p class="meta">Quoted from the sources; the model did not answer in time.</p>{{end}}
+{{if .Cited}}<p class="sources">Cited:</p>
+<ol class="sources">{{range .Cited}}<li value="{{.N}}">{{.Source}}</li>{{end}}</ol>{{end}}
+{{if .Sources}}<p class="sources">Sources:</p>
+<ul class="sources">{{range .Sources}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<p class="meta">Answered {{.AnsweredAt.Format "2006-01-02 15:04"}}. Shared read-only from LocalRAG.</p>
+</body>
+</html>
+`))
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+)
+
+// newShareServer returns a server remembering one answer, and its ID.
+func newShareServer(t *testing.T) (*Server, string) {
+	s, err := NewServer(nil, nil, nil, nil, nil, "")
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	req := &entities.ChatRequest{Query: "What is the refund window?"}
+	resp := &entities.ChatResponse{
+		Answer:    "Thirty days [1].",
+		Sources:   []entities.QueryResult{{SourceDoc: "policy.md", Chunk: entities.Chunk{Page: 3}}},
+		Citations: []int{1},
+	}
+	id, err := s.shares.remember(req, resp, 0)
+	if err != nil {
+		t.Fatalf("remember: %v", err)
+	}
+	return s, id
+}
+
+// shareLink asks /api/share for a link to answer id.
+func shareLink(t *testing.T, s *Server, id string) string {
+	r := httptest.NewRequest(http.MethodPost, "/api/share", strings.NewReader(`{"answer": "`+id+`", "ttl": "1h"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.handleShare(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("share: status %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		URL       string `json:"url"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding share response: %v", err)
+	}
+	expires, err := time.Parse(time.RFC3339, body.ExpiresAt)
+	if err != nil || expires.Before(time.Now().Add(59*time.Minute)) {
+		t.Errorf("expected the link valid for an hour, got %q", body.ExpiresAt)
+	}
+	return body.URL
+}
+
+// openShare fetches a shared answer from link.
+func openShare(s *Server, link string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.handleSharedAnswer(w, httptest.NewRequest(http.MethodGet, link, nil))
+	return w
+}
+
+func TestShare_ValidLink(t *testing.T) {
+	s, id := newShareServer(t)
+	link := shareLink(t, s, id)
+
+	w := openShare(s, link)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the shared answer, got status %d: %s", w.Code, w.Body)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"What is the refund window?", "Thirty days [1].", "policy.md, p. 3"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("shared page lacks %q:\n%s", want, body)
+		}
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'none'") {
+		t.Errorf("shared page should forbid scripts, got CSP %q", csp)
+	}
+	if strings.Contains(body, "<script") || strings.Contains(body, "<form") {
+		t.Error("shared page should be read-only")
+	}
+}
+
+func TestShare_TamperedLink(t *testing.T) {
+	s, id := newShareServer(t)
+	other, err := s.shares.remember(&entities.ChatRequest{Query: "Private question"}, &entities.ChatResponse{Answer: "Private answer"}, 0)
+	if err != nil {
+		t.Fatalf("remember: %v", err)
+	}
+	link, _ := url.Parse(shareLink(t, s, id))
+	params := link.Query()
+
+	tampered := map[string]func(url.Values){
+		"id":  func(q url.Values) { q.Set("id", other) },
+		"exp": func(q url.Values) { q.Set("exp", "9999999999") },
+		"sig": func(q url.Values) {
+			sig := []byte(q.Get("sig"))
+			sig[0] ^= 1
+			q.Set("sig", string(sig))
+		},
+		"missing sig": func(q url.Values) { q.Del("sig") },
+	}
+	for name, tamper := range tampered {
+		q := url.Values{}
+		for k, v := range params {
+			q[k] = append([]string(nil), v...)
+		}
+		tamper(q)
+		w := openShare(s, "/share?"+q.Encode())
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s changed: expected 404, got %d", name, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "Private answer") {
+			t.Errorf("%s changed: another answer leaked", name)
+		}
+	}
+}
+
+func TestShare_ExpiredLink(t *testing.T) {
+	s, id := newShareServer(t)
+	// The handler only signs links that are still valid, so sign a lapsed one directly
+	link, _, ok := s.shares.share(id, -time.Minute)
+	if !ok {
+		t.Fatal("share failed")
+	}
+	if w := openShare(s, link); w.Code != http.StatusGone {
+		t.Errorf("expected 410 for an expired link, got %d", w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/share", strings.NewReader(`{"answer": "`+id+`", "ttl": "-1h"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.handleShare(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected a negative ttl rejected, got %d", w.Code)
+	}
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/domain/entities"
+	"github.com/0xcro3dile/localrag-go/internal/domain/ports"
+)
+
+// defaultCrawlDepth is how many links away from the start page
+// /api/ingest/crawl goes unless told.
+const defaultCrawlDepth = 3
+
+// SetURLLoader enables /api/ingest/url, which fetches web pages with
+// loader, such as a loader.URLLoader. The server fetches whatever URL
+// a client names, including ones on its own network, so enable it only
+// for trusted clients.
+func (s *Server) SetURLLoader(loader ports.DocumentLoader) {
+	s.urlLoader = loader
+}
+
+// SetCrawler enables /api/ingest/crawl, which fetches whole websites
+// with crawler, such as a loader.Crawler. As with SetURLLoader, enable
+// it only for trusted clients.
+func (s *Server) SetCrawler(crawler ports.SiteCrawler) {
+	s.crawler = crawler
+}
+
+// handleIngestURL fetches the web pages at url or urls and ingests their
+// main text, citing each by its URL, and reports as /api/ingest does.
+func (s *Server) handleIngestURL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.urlLoader == nil {
+		http.Error(w, "URL ingestion is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		URL        string               `json:"url"`
+		URLs       []string             `json:"urls"`
+		Collection string               `json:"collection"`
+		Metadata   map[string]string    `json:"metadata"`
+		Provenance []transformationJSON `json:"provenance"`
+		Async      bool                 `json:"async"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	urls := req.URLs
+	if req.URL != "" {
+		urls = append([]string{req.URL}, urls...)
+	}
+	if len(urls) == 0 {
+		http.Error(w, "URL required", http.StatusBadRequest)
+		return
+	}
+	for _, raw := range urls {
+		if !webURL(raw) {
+			http.Error(w, "Not an http(s) URL: "+raw, http.StatusBadRequest)
+			return
+		}
+	}
+
+	loader := s.urlLoader
+	if len(req.Metadata) > 0 || len(req.Provenance) > 0 {
+		loader = annotatingLoader{DocumentLoader: loader, metadata: req.Metadata, provenance: provenanceFromJSON(req.Provenance)}
+	}
+	s.ingestSources(w, r, "ingest", req.Collection, len(urls), req.Async, func(context.Context) (ports.DocumentLoader, []string, error) {
+		return loader, urls, nil
+	})
+}
+
+// handleIngestCrawl crawls the website at url, or the pages its sitemap
+// lists, and ingests every page as a document cited by its URL,
+// reporting as /api/ingest does. depth, max_pages, domains and delay_ms
+// bound the crawl; see ports.CrawlLimits.
+func (s *Server) handleIngestCrawl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.crawler == nil {
+		http.Error(w, "Crawling is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		URL        string               `json:"url"`
+		Depth      *int                 `json:"depth"`
+		MaxPages   int                  `json:"max_pages"`
+		Domains    []string             `json:"domains"`
+		DelayMS    int                  `json:"delay_ms"`
+		Collection string               `json:"collection"`
+		Metadata   map[string]string    `json:"metadata"`
+		Provenance []transformationJSON `json:"provenance"`
+		Async      bool                 `json:"async"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !webURL(req.URL) {
+		http.Error(w, "http(s) URL required", http.StatusBadRequest)
+		return
+	}
+	limits := ports.CrawlLimits{
+		MaxDepth: defaultCrawlDepth,
+		MaxPages: req.MaxPages,
+		Domains:  req.Domains,
+		Delay:    time.Duration(req.DelayMS) * time.Millisecond,
+	}
+	if req.Depth != nil {
+		limits.MaxDepth = *req.Depth
+	}
+
+	s.ingestSources(w, r, "crawl", req.Collection, 0, req.Async, func(ctx context.Context) (ports.DocumentLoader, []string, error) {
+		pages, urls, err := s.crawler.Crawl(ctx, req.URL, limits)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(req.Metadata) > 0 || len(req.Provenance) > 0 {
+			pages = annotatingLoader{DocumentLoader: pages, metadata: req.Metadata, provenance: provenanceFromJSON(req.Provenance)}
+		}
+		return pages, urls, nil
+	})
+}
+
+// ingestSources ingests the paths sources gives with its loader and
+// answers with the report, or, when async, starts a background job of
+// the given kind, expecting total paths, and answers with where to
+// follow it. Failing to get the sources is the upstream's fault, such as
+// a website that is down, and answers 502 Bad Gateway.
+func (s *Server) ingestSources(w http.ResponseWriter, r *http.Request, kind, collection string, total int, async bool, sources func(ctx context.Context) (ports.DocumentLoader, []string, error)) {
+	if async {
+		job, err := s.jobs.start(kind, collection, total, func(ctx context.Context, update func(entities.IngestProgress)) (entities.IngestReport, error) {
+			loader, paths, err := sources(ctx)
+			if err != nil {
+				return entities.IngestReport{}, err
+			}
+			return s.ingestUseCase.IngestFilesWithProgress(ctx, loader, collection, paths, update)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		acceptJob(w, job)
+		return
+	}
+
+	loader, paths, err := sources(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	report, err := s.ingestUseCase.IngestFiles(r.Context(), loader, collection, paths)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ingestReportJSON(report))
+}
+
+// webURL reports whether raw is an absolute http(s) URL.
+func webURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
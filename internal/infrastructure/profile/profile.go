@@ -0,0 +1,122 @@
+// Package profile bundles tuned settings for the kind of machine
+// LocalRAG runs on, so one --profile flag picks models, retrieval depth,
+// caching and timeouts together instead of a dozen separate flags.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/0xcro3dile/localrag-go/internal/adapters/embedding"
+	"github.com/0xcro3dile/localrag-go/internal/domain/usecases"
+	server "github.com/0xcro3dile/localrag-go/internal/infrastructure/http"
+)
+
+// ErrUnknownProfile is returned by Lookup for names not in Profiles.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// Profile is a set of defaults. Zero values leave the corresponding
+// setting at its own default.
+type Profile struct {
+	Name        string
+	Description string
+
+	EmbedModel       string // Ollama embedding model
+	LLMModel         string // Ollama generation model
+	EmbedConcurrency int    // Embedding requests in flight
+
+	TopK         int // Chunks retrieved per question
+	ChunkSize    int // Characters per chunk
+	ChunkOverlap int // Characters shared by neighbouring chunks
+	Hybrid       bool
+	FollowUps    int
+
+	GenerationTimeout time.Duration // Wait before answering extractively; 0 waits for the LLM
+	QueryCache        int           // Question embeddings kept; 0 keeps pre-embedded ones only
+	AnswerCache       int           // /api/query answers kept
+}
+
+// Standard suits a desktop or server, ideally with a GPU.
+var Standard = Profile{
+	Name:         "standard",
+	Description:  "default models and settings",
+	EmbedModel:   "nomic-embed-text",
+	LLMModel:     "llama3.2",
+	TopK:         5,
+	ChunkSize:    500,
+	ChunkOverlap: 50,
+}
+
+// Lite suits a Raspberry Pi or an old laptop without a GPU: the
+// smallest models that still answer usefully, fewer and shorter
+// passages for a shorter prompt, generous caches, and extractive
+// answers when generation takes longer than a minute. Keyword search is
+// cheap and makes up for some of what the small embedding model misses.
+// all-minilm embeddings are 384-wide, so corpora built with Standard
+// must be re-ingested.
+var Lite = Profile{
+	Name:              "lite",
+	Description:       "small models and aggressive caching for machines without a GPU",
+	EmbedModel:        "all-minilm",
+	LLMModel:          "qwen2.5:0.5b",
+	EmbedConcurrency:  1,
+	TopK:              3,
+	ChunkSize:         400,
+	ChunkOverlap:      40,
+	Hybrid:            true,
+	GenerationTimeout: time.Minute,
+	QueryCache:        512,
+	AnswerCache:       1024,
+}
+
+// Profiles are the selectable profiles by name.
+var Profiles = map[string]Profile{
+	Standard.Name: Standard,
+	Lite.Name:     Lite,
+}
+
+// Lookup returns the named profile; an empty name selects Standard.
+func Lookup(name string) (Profile, error) {
+	if name == "" {
+		return Standard, nil
+	}
+	p, ok := Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w %q (have %v)", ErrUnknownProfile, name, Names())
+	}
+	return p, nil
+}
+
+// Names lists the selectable profiles in order.
+func Names() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyEmbedder applies the embedding settings to an Ollama adapter
+// created with EmbedModel.
+func (p Profile) ApplyEmbedder(a *embedding.OllamaAdapter) {
+	if p.EmbedConcurrency > 0 {
+		a.SetConcurrency(p.EmbedConcurrency)
+	}
+}
+
+// ApplyQuery applies the query settings to a usecase created with TopK.
+// Settings made afterwards take precedence.
+func (p Profile) ApplyQuery(uc *usecases.QueryUseCase) {
+	uc.SetHybrid(p.Hybrid)
+	uc.SetFollowUps(p.FollowUps)
+	uc.SetGenerationTimeout(p.GenerationTimeout)
+	uc.SetQueryCache(p.QueryCache)
+}
+
+// ApplyServer applies the caching settings to the HTTP server.
+func (p Profile) ApplyServer(s *server.Server) {
+	s.SetAnswerCacheSize(p.AnswerCache)
+}
@@ -0,0 +1,23 @@
+package profile
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	p, err := Lookup("")
+	if err != nil || p.Name != Standard.Name {
+		t.Errorf("expected the standard profile by default, got %q, %v", p.Name, err)
+	}
+	p, err = Lookup("lite")
+	if err != nil || p.Name != "lite" {
+		t.Fatalf("expected the lite profile, got %q, %v", p.Name, err)
+	}
+	if p.TopK >= Standard.TopK || p.GenerationTimeout == 0 || p.QueryCache == 0 {
+		t.Errorf("lite should retrieve less, cache queries and time out generation, got %+v", p)
+	}
+	if _, err := Lookup("turbo"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("expected ErrUnknownProfile, got %v", err)
+	}
+}
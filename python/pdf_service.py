@@ -2,10 +2,13 @@
 PDF Parsing Service for LocalRAG
 Clean Architecture: This is a Framework/Driver - outermost layer.
 Provides HTTP API for PDF text extraction, called by Go adapter.
+Optionally serves sentence-transformers embeddings on /embed, for models
+not yet available in Ollama, and OCR of scanned PDFs and images on /ocr.
 """
 import io
 import json
 import logging
+import os
 from http.server import HTTPServer, BaseHTTPRequestHandler
 from urllib.parse import parse_qs, urlparse
 
@@ -24,53 +27,114 @@ except ImportError:
         PDF_LIBRARY = None
         logger.warning("No PDF library found. Install: pip install pypdf")
 
+# Embeddings are optional; the endpoint reports an error without the library
+try:
+    from sentence_transformers import SentenceTransformer
+except ImportError:
+    SentenceTransformer = None
+
+# OCR is optional; the endpoint reports an error without pytesseract.
+# PDFs are rendered to images with pdf2image, which needs poppler.
+try:
+    import pytesseract
+    from PIL import Image
+except ImportError:
+    pytesseract = None
+try:
+    from pdf2image import convert_from_bytes
+except ImportError:
+    convert_from_bytes = None
+
+# Tesseract languages, such as "eng+deu"
+OCR_LANGUAGES = os.environ.get("OCR_LANGUAGES", "eng")
+
+# Model used when a request names none
+EMBEDDING_MODEL = os.environ.get("EMBEDDING_MODEL", "sentence-transformers/all-MiniLM-L6-v2")
+
+_models = {}
+
 
-def extract_text_pypdf(pdf_bytes: bytes) -> tuple[str, int]:
-    """Extract text using pypdf."""
+def extract_text_pypdf(pdf_bytes: bytes) -> list[str]:
+    """Extract the text of each page using pypdf."""
     reader = pypdf.PdfReader(io.BytesIO(pdf_bytes))
-    pages = len(reader.pages)
-    text_parts = []
-    for page in reader.pages:
-        text = page.extract_text()
-        if text:
-            text_parts.append(text)
-    return "\n\n".join(text_parts), pages
+    return [(page.extract_text() or "").strip() for page in reader.pages]
 
 
-def extract_text_pdfplumber(pdf_bytes: bytes) -> tuple[str, int]:
-    """Extract text using pdfplumber."""
+def extract_text_pdfplumber(pdf_bytes: bytes) -> list[str]:
+    """Extract the text of each page using pdfplumber."""
     import pdfplumber
-    text_parts = []
-    pages = 0
     with pdfplumber.open(io.BytesIO(pdf_bytes)) as pdf:
-        pages = len(pdf.pages)
-        for page in pdf.pages:
-            text = page.extract_text()
-            if text:
-                text_parts.append(text)
-    return "\n\n".join(text_parts), pages
+        return [(page.extract_text() or "").strip() for page in pdf.pages]
 
 
 def extract_text(pdf_bytes: bytes) -> dict:
-    """Extract text from PDF bytes."""
+    """Extract text from PDF bytes, whole and per page."""
     if PDF_LIBRARY is None:
         return {"error": "No PDF library installed", "text": "", "pages": 0}
     
     try:
         if PDF_LIBRARY == "pypdf":
-            text, pages = extract_text_pypdf(pdf_bytes)
+            page_texts = extract_text_pypdf(pdf_bytes)
         else:
-            text, pages = extract_text_pdfplumber(pdf_bytes)
+            page_texts = extract_text_pdfplumber(pdf_bytes)
         
         return {
-            "text": text.strip(),
-            "pages": pages,
+            "text": "\n\n".join(text for text in page_texts if text),
+            "pages": len(page_texts),
+            "page_texts": page_texts,
             "library": PDF_LIBRARY
         }
     except Exception as e:
         return {"error": str(e), "text": "", "pages": 0}
 
 
+def load_model(name: str):
+    """Load a sentence-transformers model once and keep it for later requests."""
+    model = _models.get(name)
+    if model is None:
+        logger.info(f"Loading embedding model {name}")
+        model = SentenceTransformer(name)
+        _models[name] = model
+    return model
+
+
+def embed_texts(texts: list, model_name: str) -> dict:
+    """Embed texts with a sentence-transformers model."""
+    if SentenceTransformer is None:
+        return {"error": "sentence-transformers not installed"}
+    try:
+        model = load_model(model_name)
+        embeddings = model.encode(texts, convert_to_numpy=True)
+        return {
+            "embeddings": embeddings.tolist(),
+            "model": model_name,
+            "dimension": int(embeddings.shape[1]) if len(texts) else 0,
+        }
+    except Exception as e:
+        return {"error": str(e)}
+
+
+def ocr_document(data: bytes, filename: str) -> dict:
+    """Recognize the text of an image, or of every page of a PDF."""
+    if pytesseract is None:
+        return {"error": "pytesseract not installed", "text": "", "pages": 0}
+    try:
+        if filename.lower().endswith(".pdf"):
+            if convert_from_bytes is None:
+                return {"error": "pdf2image not installed", "text": "", "pages": 0}
+            images = convert_from_bytes(data, dpi=300)
+        else:
+            images = [Image.open(io.BytesIO(data))]
+        text_parts = []
+        for image in images:
+            text = pytesseract.image_to_string(image, lang=OCR_LANGUAGES).strip()
+            if text:
+                text_parts.append(text)
+        return {"text": "\n\n".join(text_parts), "pages": len(images), "library": "pytesseract"}
+    except Exception as e:
+        return {"error": str(e), "text": "", "pages": 0}
+
+
 class PDFHandler(BaseHTTPRequestHandler):
     """HTTP handler for PDF parsing requests."""
     
@@ -80,12 +144,24 @@ class PDFHandler(BaseHTTPRequestHandler):
     def do_GET(self):
         """Health check endpoint."""
         if self.path == "/health":
-            self._send_json({"status": "ok", "library": PDF_LIBRARY})
+            self._send_json({
+                "status": "ok",
+                "library": PDF_LIBRARY,
+                "embeddings": SentenceTransformer is not None,
+                "embedding_model": EMBEDDING_MODEL,
+                "ocr": pytesseract is not None,
+            })
         else:
             self._send_json({"error": "Use POST /parse with PDF data"}, 400)
     
     def do_POST(self):
-        """Parse PDF from request body."""
+        """Parse PDF from request body, or embed texts."""
+        if self.path == "/embed":
+            self._embed()
+            return
+        if urlparse(self.path).path == "/ocr":
+            self._ocr()
+            return
         if self.path != "/parse":
             self._send_json({"error": "Unknown endpoint"}, 404)
             return
@@ -104,6 +180,42 @@ class PDFHandler(BaseHTTPRequestHandler):
             logger.info(f"Parsed PDF: {result['pages']} pages, {len(result['text'])} chars")
             self._send_json(result)
     
+    def _embed(self):
+        """Embed {"texts": [...], "model": optional name} with sentence-transformers."""
+        content_length = int(self.headers.get('Content-Length', 0))
+        try:
+            req = json.loads(self.rfile.read(content_length) or b"{}")
+        except ValueError:
+            self._send_json({"error": "Invalid JSON"}, 400)
+            return
+        texts = req.get("texts")
+        if not isinstance(texts, list) or not all(isinstance(t, str) for t in texts):
+            self._send_json({"error": "texts must be a list of strings"}, 400)
+            return
+
+        result = embed_texts(texts, req.get("model") or EMBEDDING_MODEL)
+        if "error" in result:
+            status = 501 if SentenceTransformer is None else 500
+            self._send_json(result, status)
+        else:
+            logger.info(f"Embedded {len(texts)} texts with {result['model']}")
+            self._send_json(result)
+
+    def _ocr(self):
+        """OCR the image or PDF in the body, named by the filename parameter."""
+        content_length = int(self.headers.get('Content-Length', 0))
+        if content_length == 0:
+            self._send_json({"error": "No document data"}, 400)
+            return
+        filename = parse_qs(urlparse(self.path).query).get("filename", [""])[0]
+        result = ocr_document(self.rfile.read(content_length), filename)
+        if result.get("error"):
+            status = 501 if pytesseract is None else 500
+            self._send_json(result, status)
+        else:
+            logger.info(f"OCR of {filename or 'document'}: {result['pages']} pages, {len(result['text'])} chars")
+            self._send_json(result)
+
     def _send_json(self, data: dict, status: int = 200):
         self.send_response(status)
         self.send_header("Content-Type", "application/json")
@@ -116,6 +228,10 @@ def main():
     server = HTTPServer(("localhost", port), PDFHandler)
     logger.info(f"[INFO] PDF Service starting on http://localhost:{port}")
     logger.info(f"   Using library: {PDF_LIBRARY or 'NONE - install pypdf!'}")
+    if SentenceTransformer is not None:
+        logger.info(f"   Embeddings: sentence-transformers, default model {EMBEDDING_MODEL}")
+    if pytesseract is not None:
+        logger.info(f"   OCR: pytesseract, languages {OCR_LANGUAGES}")
     try:
         server.serve_forever()
     except KeyboardInterrupt: